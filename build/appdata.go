@@ -73,6 +73,16 @@ func ExchangeRate() string {
 	return os.Getenv(siaExchangeRate)
 }
 
+// S3AccessKey returns the siaS3AccessKey environment variable.
+func S3AccessKey() string {
+	return os.Getenv(siaS3AccessKey)
+}
+
+// S3SecretKey returns the siaS3SecretKey environment variable.
+func S3SecretKey() string {
+	return os.Getenv(siaS3SecretKey)
+}
+
 // apiPasswordFilePath returns the path to the API's password file. The password
 // file is stored in the Sia data directory.
 func apiPasswordFilePath() string {
@@ -3,6 +3,8 @@ package build
 import (
 	"os"
 	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
 )
 
 // TestAPIPassword tests getting and setting the API Password
@@ -146,3 +148,27 @@ func TestSiaExchangeRate(t *testing.T) {
 		t.Errorf("Expected exchange rate to be %v but was %v", newRate, rate)
 	}
 }
+
+// TestSiaS3Credentials tests getting and setting the S3 gateway credentials
+func TestSiaS3Credentials(t *testing.T) {
+	// Unset any defaults, this only affects in memory state. Any Env Vars will
+	// remain intact on disk
+	err := errors.Compose(os.Unsetenv(siaS3AccessKey), os.Unsetenv(siaS3SecretKey))
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Test Default
+	if S3AccessKey() != "" || S3SecretKey() != "" {
+		t.Errorf("Expected credentials to be blank but were %v and %v", S3AccessKey(), S3SecretKey())
+	}
+
+	// Test Env Variable
+	err = errors.Compose(os.Setenv(siaS3AccessKey, "access"), os.Setenv(siaS3SecretKey, "secret"))
+	if err != nil {
+		t.Error(err)
+	}
+	if S3AccessKey() != "access" || S3SecretKey() != "secret" {
+		t.Errorf("Expected credentials to be access and secret but were %v and %v", S3AccessKey(), S3SecretKey())
+	}
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -19,6 +22,15 @@ var (
 		Run:   wrap(alertscmd),
 	}
 
+	eventsCmd = &cobra.Command{
+		Use:   "events",
+		Short: "Follow the events of the daemon",
+		Long: `Follow the events of the daemon, like registered alerts, finished uploads and
+downloads or expired contracts. Use the --types flag to only print events of
+certain types.`,
+		Run: wrap(eventscmd),
+	}
+
 	stopCmd = &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the Sia daemon",
@@ -75,6 +87,34 @@ directory in the siad data directory.`,
 		Run:   wrap(stackcmd),
 	}
 
+	tokensCmd = &cobra.Command{
+		Use:   "tokens",
+		Short: "View and manage the daemon's API tokens",
+		Long: `View the API tokens of the daemon. API tokens grant access to a subset of the
+API, e.g. only reading the renter's state or uploading files, without handing
+out the API password.`,
+		Run: wrap(tokenscmd),
+	}
+
+	tokensCreateCmd = &cobra.Command{
+		Use:   "create [name] [scopes]",
+		Short: "Create an API token",
+		Long: `Create an API token with a comma separated list of scopes. Every module has a
+read scope like 'renter:read' and a write scope. The write scopes are
+'daemon:admin', 'host:admin', 'wallet:spend' and '<module>:write' for all other
+modules. Use the --validity flag to create a token which expires.
+
+The token is only shown once.`,
+		Run: wrap(tokenscreatecmd),
+	}
+
+	tokensRemoveCmd = &cobra.Command{
+		Use:   "remove [id]",
+		Short: "Remove an API token",
+		Long:  "Remove an API token.",
+		Run:   wrap(tokensremovecmd),
+	}
+
 	updateCmd = &cobra.Command{
 		Use:   "update",
 		Short: "Update Sia",
@@ -82,6 +122,28 @@ directory in the siad data directory.`,
 		Run:   wrap(updatecmd),
 	}
 
+	webhooksCmd = &cobra.Command{
+		Use:   "webhooks",
+		Short: "View and manage the daemon's webhooks",
+		Long:  "View the webhooks which the daemon posts events to.",
+		Run:   wrap(webhookscmd),
+	}
+
+	webhooksAddCmd = &cobra.Command{
+		Use:   "add [url]",
+		Short: "Add a webhook",
+		Long: `Add a webhook which the daemon posts events to. Use the --types flag to only
+post events of certain types.`,
+		Run: wrap(webhooksaddcmd),
+	}
+
+	webhooksRemoveCmd = &cobra.Command{
+		Use:   "remove [url]",
+		Short: "Remove a webhook",
+		Long:  "Remove a webhook.",
+		Run:   wrap(webhooksremovecmd),
+	}
+
 	versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
@@ -304,3 +366,127 @@ func printAlerts(alerts []modules.Alert, as modules.AlertSeverity) {
 	}
 	fmt.Printf("\n------------------\n\n")
 }
+
+// eventscmd prints the events of the daemon until siac is interrupted.
+func eventscmd() {
+	var eventTypes []string
+	if daemonEventTypes != "" {
+		eventTypes = strings.Split(daemonEventTypes, ",")
+	}
+	es, err := httpClient.DaemonEventsSubscribe(eventTypes)
+	if err != nil {
+		die("Could not subscribe to events:", err)
+	}
+	defer func() {
+		if err := es.Close(); err != nil {
+			die(err)
+		}
+	}()
+	for {
+		e, data, err := es.Next()
+		if err != nil {
+			die("Could not read event:", err)
+		}
+		fmt.Printf("%v  %-25v %s\n", e.Time.Format(time.RFC3339), e.Type, data)
+	}
+}
+
+// webhookscmd prints the webhooks of the daemon.
+func webhookscmd() {
+	dwg, err := httpClient.DaemonWebhooksGet()
+	if err != nil {
+		die("Could not get webhooks:", err)
+	}
+	if len(dwg.Webhooks) == 0 {
+		fmt.Println("There are no webhooks.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tEvents")
+	for _, wh := range dwg.Webhooks {
+		events := "all"
+		if len(wh.Events) > 0 {
+			events = strings.Join(wh.Events, ", ")
+		}
+		fmt.Fprintf(w, "%v\t%v\n", wh.URL, events)
+	}
+	if err := w.Flush(); err != nil {
+		die(err)
+	}
+}
+
+// webhooksaddcmd adds a webhook to the daemon.
+func webhooksaddcmd(url string) {
+	var eventTypes []string
+	if daemonEventTypes != "" {
+		eventTypes = strings.Split(daemonEventTypes, ",")
+	}
+	err := httpClient.DaemonWebhooksAddPost(modules.Webhook{
+		URL:    url,
+		Events: eventTypes,
+	})
+	if err != nil {
+		die("Could not add webhook:", err)
+	}
+	fmt.Println("Added webhook", url)
+}
+
+// webhooksremovecmd removes a webhook from the daemon.
+func webhooksremovecmd(url string) {
+	if err := httpClient.DaemonWebhooksRemovePost(url); err != nil {
+		die("Could not remove webhook:", err)
+	}
+	fmt.Println("Removed webhook", url)
+}
+
+// tokenscmd prints the API tokens of the daemon.
+func tokenscmd() {
+	dtg, err := httpClient.DaemonTokensGet()
+	if err != nil {
+		die("Could not get API tokens:", err)
+	}
+	if len(dtg.Tokens) == 0 {
+		fmt.Println("There are no API tokens.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tName\tScopes\tExpiry")
+	for _, t := range dtg.Tokens {
+		expiry := "never"
+		if t.Expired {
+			expiry = "expired"
+		} else if !t.Expiry.IsZero() {
+			expiry = t.Expiry.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", t.ID, t.Name, strings.Join(t.Scopes, ", "), expiry)
+	}
+	if err := w.Flush(); err != nil {
+		die(err)
+	}
+}
+
+// tokenscreatecmd creates an API token.
+func tokenscreatecmd(name, scopes string) {
+	var validity time.Duration
+	if daemonTokenValidity != "" {
+		var err error
+		validity, err = time.ParseDuration(daemonTokenValidity)
+		if err != nil {
+			die("Could not parse validity:", err)
+		}
+	}
+	dtp, err := httpClient.DaemonTokensCreatePost(name, strings.Split(scopes, ","), validity)
+	if err != nil {
+		die("Could not create API token:", err)
+	}
+	fmt.Printf("Created API token %v: %v\n", dtp.ID, dtp.Token)
+	fmt.Println("Store the token securely, it can't be shown again.")
+}
+
+// tokensremovecmd removes an API token.
+func tokensremovecmd(id string) {
+	if err := httpClient.DaemonTokensRemovePost(id); err != nil {
+		die("Could not remove API token:", err)
+	}
+	fmt.Println("Removed API token", id)
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -70,6 +72,10 @@ Available settings:
      registrysize:       filesize
      customregistrypath: string
 
+     maxconcurrentprograms: programs per account
+     peerbandwidthlimit:    bandwidth per peer, e.g. 10MB/s
+     peerrpcratelimit:      RPCs per second per peer and account
+
 Currency units can be specified, e.g. 10SC; run 'siac help wallet' for details.
 
 Durations (maxduration and windowsize) must be specified in either blocks (b),
@@ -100,6 +106,17 @@ Available output types:
 		Run: wrap(hostcontractcmd),
 	}
 
+	hostContractExportCmd = &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export host contracts",
+		Long: `Export the host's contracts with a breakdown of their revenue to a file.
+All amounts are in hastings. The contracts can be filtered with the same flags
+as 'siac host contracts', e.g. to export the contracts negotiated within a
+range of block heights:
+	siac host contracts export contracts.csv --min-negotiation 200000 --max-negotiation 252560`,
+		Run: wrap(hostcontractexportcmd),
+	}
+
 	hostFolderAddCmd = &cobra.Command{
 		Use:   "add [path] [size]",
 		Short: "Add a storage folder to the host",
@@ -109,8 +126,8 @@ Available output types:
 
 	hostFolderCmd = &cobra.Command{
 		Use:   "folder",
-		Short: "Add, remove, or resize a storage folder",
-		Long:  "Add, remove, or resize a storage folder.",
+		Short: "Add, remove, resize, or tier a storage folder",
+		Long:  "Add, remove, resize, or tier a storage folder.",
 	}
 
 	hostFolderRemoveCmd = &cobra.Command{
@@ -131,6 +148,68 @@ other storage folders.`,
 		Run: wrap(hostfolderresizecmd),
 	}
 
+	hostFolderTierCmd = &cobra.Command{
+		Use:   "tier [path] [tier]",
+		Short: "Set the tier of a storage folder",
+		Long: `Set the tier of a storage folder to either 'fast' or 'slow'. New sectors are
+placed in slow storage folders and frequently read sectors are moved to fast
+storage folders in the background.`,
+		Example: "siac host folder tier /mnt/ssd/sia fast",
+		Run:     wrap(hostfoldertiercmd),
+	}
+
+	hostPricingCmd = &cobra.Command{
+		Use:   "pricing",
+		Short: "Show the host's pricing policy",
+		Long:  "Show the policy of the host's pricing engine and its recent price changes.",
+		Run:   wrap(hostpricingcmd),
+	}
+
+	hostPricingSetCmd = &cobra.Command{
+		Use:   "set [setting] [value]",
+		Short: "Modify the host's pricing policy",
+		Long: `Modify the policy of the host's pricing engine. If enabled, the engine
+periodically updates the host's contract, storage, bandwidth and collateral
+prices according to the policy.
+
+Available settings:
+     enabled:        boolean
+     source:         fiat or network
+     updateinterval: duration, e.g. 1h
+
+     exchangerate:      price of 1 SC, e.g. "0.01 USD"
+     fiatcontractprice: fiat
+     fiatdownloadprice: fiat / TB
+     fiatstorageprice:  fiat / TB / Month
+     fiatuploadprice:   fiat / TB
+
+     networkpercentile: percentile of the prices of the network's active hosts
+
+     targetutilization:     fraction of the storage in use, 0 to disable
+     utilizationadjustment: factor the storage price is adjusted by
+     collateralratio:       collateral as a multiple of the storage price
+
+     minstorageprice: currency / TB / Month
+     maxstorageprice: currency / TB / Month
+
+The network source requires the renter module to observe the network's prices.
+
+To price the host at the median of the network:
+	siac host pricing set source network
+	siac host pricing set networkpercentile 50
+	siac host pricing set enabled true
+`,
+		Run: wrap(hostpricingsetcmd),
+	}
+
+	hostScrubCmd = &cobra.Command{
+		Use:   "scrub",
+		Short: "Show the status of the host's scrubber",
+		Long: `Show the status of the host's scrubber. The scrubber periodically reads every
+sector stored by the host and verifies its data to detect silent corruption.`,
+		Run: wrap(hostscrubcmd),
+	}
+
 	hostSectorCmd = &cobra.Command{
 		Use:   "sector",
 		Short: "Add or delete a sector (add not supported)",
@@ -238,6 +317,10 @@ Host Internal Settings:
 	registrysize:       %v
 	customregistrypath: %v
 
+	maxconcurrentprograms: %v
+	peerbandwidthlimit:    %v
+	peerrpcratelimit:      %v
+
 Host Financials:
 	Contract Count:               %v
 	Transaction Fee Compensation: %v
@@ -264,6 +347,9 @@ RPC Stats:
 	Revise Calls:       %v
 	Settings Calls:     %v
 	FormContract Calls: %v
+
+	Rate Limited Calls:    %v
+	Program Limited Calls: %v
 `,
 			connectabilityString,
 			es.Version,
@@ -292,6 +378,10 @@ RPC Stats:
 			modules.FilesizeUnits(is.RegistrySize),
 			is.CustomRegistryPath,
 
+			limitString(is.MaxConcurrentPrograms, fmt.Sprint(is.MaxConcurrentPrograms)),
+			limitString(is.PeerBandwidthLimit, ratelimitUnits(int64(is.PeerBandwidthLimit))),
+			limitString(is.PeerRPCRateLimit, fmt.Sprintf("%v / s", is.PeerRPCRateLimit)),
+
 			fm.ContractCount, currencyUnits(fm.ContractCompensation),
 			currencyUnits(fm.PotentialContractCompensation),
 			currencyUnits(fm.TransactionFeeExpenses),
@@ -310,7 +400,9 @@ RPC Stats:
 
 			nm.ErrorCalls, nm.UnrecognizedCalls, nm.DownloadCalls,
 			nm.RenewCalls, nm.ReviseCalls, nm.SettingsCalls,
-			nm.FormContractCalls)
+			nm.FormContractCalls,
+
+			nm.RateLimitedCalls, nm.ProgramLimitedCalls)
 	} else {
 		fmt.Printf(`Host info:
 	Connectability Status: %v
@@ -355,11 +447,11 @@ RPC Stats:
 		return
 	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
-	fmt.Fprintf(w, "\tUsed\tCapacity\t%% Used\tPath\n")
+	fmt.Fprintf(w, "\tUsed\tCapacity\t%% Used\tTier\tPath\n")
 	for _, folder := range sg.Folders {
 		curSize := int64(folder.Capacity - folder.CapacityRemaining)
 		pctUsed := 100 * (float64(curSize) / float64(folder.Capacity))
-		fmt.Fprintf(w, "\t%s\t%s\t%.2f\t%s\n", modules.FilesizeUnits(uint64(curSize)), modules.FilesizeUnits(folder.Capacity), pctUsed, folder.Path)
+		fmt.Fprintf(w, "\t%s\t%s\t%.2f\t%s\t%s\n", modules.FilesizeUnits(uint64(curSize)), modules.FilesizeUnits(folder.Capacity), pctUsed, folder.Tier, folder.Path)
 	}
 	if err := w.Flush(); err != nil {
 		die("failed to flush writer")
@@ -414,6 +506,14 @@ func hostconfigcmd(param, value string) {
 			die("Could not parse "+param+":", err)
 		}
 
+	// ratelimit (convert to bytes per second)
+	case "peerbandwidthlimit":
+		bps, err := parseRatelimit(value)
+		if err != nil {
+			die("Could not parse "+param+":", err)
+		}
+		value = fmt.Sprint(bps)
+
 	// filesize (convert to bytes)
 	case "registrysize":
 		value, err = parseFilesize(value)
@@ -429,7 +529,7 @@ func hostconfigcmd(param, value string) {
 		}
 
 	// other valid settings
-	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress", "customregistrypath":
+	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress", "customregistrypath", "maxconcurrentprograms", "peerrpcratelimit":
 
 	// invalid settings
 	default:
@@ -453,13 +553,144 @@ func hostconfigcmd(param, value string) {
 	fmt.Printf("Estimated conversion rate: %v%%\n", eg.ConversionRate)
 }
 
+// hostpricingcmd is the handler for the command `siac host pricing`.
+func hostpricingcmd() {
+	hpg, err := httpClient.HostPricingGet()
+	if err != nil {
+		die("Could not fetch host pricing policy:", err)
+	}
+	p := hpg.Policy
+	updateInterval := "default"
+	if p.UpdateInterval != 0 {
+		updateInterval = p.UpdateInterval.String()
+	}
+	maxStoragePrice := "none"
+	if !p.MaxStoragePrice.IsZero() {
+		maxStoragePrice = currencyUnits(p.MaxStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)) + " / TB / Month"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Pricing Policy:\n")
+	fmt.Fprintf(w, "  Enabled:\t%v\n", yesNo(p.Enabled))
+	fmt.Fprintf(w, "  Source:\t%v\n", p.Source)
+	fmt.Fprintf(w, "  Update Interval:\t%v\n", updateInterval)
+	switch p.Source {
+	case modules.HostPricingSourceFiat:
+		fmt.Fprintf(w, "  Exchange Rate:\t%v\n", p.ExchangeRate)
+		fmt.Fprintf(w, "  Contract Price:\t%v\n", p.FiatContractPrice)
+		fmt.Fprintf(w, "  Download Price:\t%v / TB\n", p.FiatDownloadPrice)
+		fmt.Fprintf(w, "  Storage Price:\t%v / TB / Month\n", p.FiatStoragePrice)
+		fmt.Fprintf(w, "  Upload Price:\t%v / TB\n", p.FiatUploadPrice)
+	case modules.HostPricingSourceNetwork:
+		fmt.Fprintf(w, "  Network Percentile:\t%v\n", p.NetworkPercentile)
+	}
+	fmt.Fprintf(w, "  Target Utilization:\t%v%%\n", p.TargetUtilization*100)
+	fmt.Fprintf(w, "  Utilization Adjustment:\t%v\n", p.UtilizationAdjustment)
+	fmt.Fprintf(w, "  Collateral Ratio:\t%v\n", p.CollateralRatio)
+	fmt.Fprintf(w, "  Min Storage Price:\t%v / TB / Month\n", currencyUnits(p.MinStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)))
+	fmt.Fprintf(w, "  Max Storage Price:\t%v\n", maxStoragePrice)
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+
+	if len(hpg.Changes) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Recent Price Changes:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Time\tSetting\tOld Price\tNew Price\tReason\n")
+	for _, c := range hpg.Changes {
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\n", c.Time.Format(time.RFC3339), c.Setting, c.OldPrice.HumanString(), c.NewPrice.HumanString(), c.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
+// hostpricingsetcmd is the handler for the command `siac host pricing set
+// [setting] [value]`.
+func hostpricingsetcmd(param, value string) {
+	switch param {
+	// currency/TB/month (convert to hastings/byte/block)
+	case "minstorageprice", "maxstorageprice":
+		hastings, err := types.ParseCurrency(value)
+		if err != nil {
+			die("Could not parse "+param+":", err)
+		}
+		i, _ := new(big.Int).SetString(hastings, 10)
+		value = types.NewCurrency(i).Div(modules.BlockBytesPerMonthTerabyte).String()
+
+	// bool (allow "yes" and "no")
+	case "enabled":
+		switch strings.ToLower(value) {
+		case "yes":
+			value = "true"
+		case "no":
+			value = "false"
+		}
+
+	// other valid settings
+	case "source", "updateinterval", "exchangerate", "fiatcontractprice", "fiatdownloadprice", "fiatstorageprice",
+		"fiatuploadprice", "networkpercentile", "targetutilization", "utilizationadjustment", "collateralratio":
+
+	// invalid settings
+	default:
+		die("\"" + param + "\" is not a pricing policy setting")
+	}
+	err := httpClient.HostPricingPost(param, value)
+	if err != nil {
+		die("Failed to update host pricing policy:", err)
+	}
+	fmt.Println("Host pricing policy updated.")
+}
+
+// hostContractQuery returns the storage obligation query described by the
+// host contract flags.
+func hostContractQuery() modules.HostStorageObligationQuery {
+	query := modules.HostStorageObligationQuery{
+		MinExpirationHeight:  types.BlockHeight(hostContractMinExpiration),
+		MaxExpirationHeight:  types.BlockHeight(hostContractMaxExpiration),
+		MinNegotiationHeight: types.BlockHeight(hostContractMinNegotiated),
+		MaxNegotiationHeight: types.BlockHeight(hostContractMaxNegotiated),
+		ProofOutcome:         hostContractProofOutcome,
+		SortBy:               hostContractSortBy,
+		SortDesc:             hostContractSortDesc,
+		Offset:               hostContractOffset,
+		Limit:                hostContractLimit,
+	}
+	if hostContractStatus != "" {
+		query.Statuses = strings.Split(hostContractStatus, ",")
+	}
+	for _, revenue := range []struct {
+		flag  string
+		value *types.Currency
+	}{
+		{hostContractMinRevenue, &query.MinRevenue},
+		{hostContractMaxRevenue, &query.MaxRevenue},
+	} {
+		if revenue.flag == "" {
+			continue
+		}
+		hastings, err := types.ParseCurrency(revenue.flag)
+		if err != nil {
+			die("Could not parse revenue:", err)
+		}
+		if _, err := fmt.Sscan(hastings, revenue.value); err != nil {
+			die("Could not parse revenue:", err)
+		}
+	}
+	if err := query.Validate(); err != nil {
+		die(err)
+	}
+	return query
+}
+
 // hostcontractcmd is the handler for the command `siac host contracts [type]`.
 func hostcontractcmd() {
-	cg, err := httpClient.HostContractInfoGet()
+	cg, err := httpClient.HostContractInfoQueryGet(hostContractQuery())
 	if err != nil {
 		die("Could not fetch host contract info:", err)
 	}
-	sort.Slice(cg.Contracts, func(i, j int) bool { return cg.Contracts[i].ExpirationHeight < cg.Contracts[j].ExpirationHeight })
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
 	switch hostContractOutputType {
 	case "value":
@@ -481,6 +712,25 @@ func hostcontractcmd() {
 	if err := w.Flush(); err != nil {
 		die("failed to flush writer")
 	}
+	if uint64(len(cg.Contracts)) < cg.Total {
+		fmt.Printf("\nShowing %v of %v contracts.\n", len(cg.Contracts), cg.Total)
+	}
+}
+
+// hostcontractexportcmd is the handler for the command `siac host contracts
+// export [file]`.
+func hostcontractexportcmd(path string) {
+	if hostContractExportFormat != "csv" && hostContractExportFormat != "json" {
+		die("\"" + hostContractExportFormat + "\" is not an export format")
+	}
+	data, err := httpClient.HostContractsExportGet(hostContractQuery(), hostContractExportFormat)
+	if err != nil {
+		die("Could not export host contracts:", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		die("Could not write export:", err)
+	}
+	fmt.Printf("Exported host contracts to %v.\n", path)
 }
 
 // hostannouncecmd is the handler for the command `siac host announce`.
@@ -569,6 +819,55 @@ func hostfolderresizecmd(path, newsize string) {
 	fmt.Printf("Resized folder %v to %v\n", path, newsize)
 }
 
+// hostfoldertiercmd sets the tier of a folder in the host.
+func hostfoldertiercmd(path, tier string) {
+	err := httpClient.HostStorageFoldersTierPost(abs(path), tier)
+	if err != nil {
+		die("Could not set folder tier:", err)
+	}
+	fmt.Printf("Set tier of folder %v to %v\n", path, tier)
+}
+
+// hostscrubcmd is the handler for the command `siac host scrub`. It shows the
+// status of the host's scrubber.
+func hostscrubcmd() {
+	ssg, err := httpClient.HostStorageScrubGet()
+	if err != nil {
+		die("Could not fetch scrub status:", err)
+	}
+	status := "idle"
+	if ssg.Running {
+		status = "running"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Scrubber:\n")
+	fmt.Fprintf(w, "  Status:\t%v\n", status)
+	fmt.Fprintf(w, "  Last Started:\t%v\n", sanitizeTime(ssg.LastStarted, !ssg.LastStarted.IsZero()))
+	fmt.Fprintf(w, "  Last Completed:\t%v\n", sanitizeTime(ssg.LastCompleted, !ssg.LastCompleted.IsZero()))
+	fmt.Fprintf(w, "  Next Scrub:\t%v\n", sanitizeTime(ssg.NextScrub, !ssg.NextScrub.IsZero()))
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+
+	fmt.Println("\nStorage Folders:")
+	if len(ssg.Folders) == 0 {
+		fmt.Println("No storage folders configured")
+		return
+	}
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintf(w, "\tScrubbed\tTotal\t%% Done\tCorrupt\tPath\n")
+	for _, folder := range ssg.Folders {
+		pctDone := 100.0
+		if folder.TotalSectors > 0 {
+			pctDone = 100 * float64(folder.ScrubbedSectors) / float64(folder.TotalSectors)
+		}
+		fmt.Fprintf(w, "\t%v\t%v\t%.2f\t%v\t%v\n", folder.ScrubbedSectors, folder.TotalSectors, pctDone, folder.CorruptSectors, folder.Path)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
 // hostsectordeletecmd deletes a sector from the host.
 func hostsectordeletecmd(root string) {
 	var hash crypto.Hash
@@ -18,6 +18,10 @@ import (
 var (
 	// General Flags
 	alertSuppress bool
+	apiTLS        bool   // Connect to the API over TLS
+	apiTLSCA      string // Path to the CA certificates of the API's TLS certificate
+	apiTLSCert    string // Path to the client certificate for the API
+	apiTLSKey     string // Path to the key of the client certificate for the API
 	siaDir        string // Path to sia data dir
 	verbose       bool   // Display additional information
 
@@ -29,25 +33,51 @@ var (
 	daemonMemoryProfile    bool   // Indicates that the Memory profile should be started
 	daemonProfileDirectory string // The Directory where the profile logs are saved
 	daemonTraceProfile     bool   // Indicates that the Trace profile should be started
+	daemonEventTypes       string // Comma separated list of event types to subscribe to
+	daemonTokenValidity    string // Duration for which a new API token is valid
 
 	// Host Flags
-	hostContractOutputType string // output type for host contracts
-	hostFolderRemoveForce  bool   // force folder remove
+	hostContractExportFormat  string // format of the host contracts export
+	hostContractLimit         uint64 // maximum number of host contracts to show
+	hostContractMaxExpiration uint64 // maximum expiration height of host contracts
+	hostContractMaxNegotiated uint64 // maximum negotiation height of host contracts
+	hostContractMaxRevenue    string // maximum potential revenue of host contracts
+	hostContractMinExpiration uint64 // minimum expiration height of host contracts
+	hostContractMinNegotiated uint64 // minimum negotiation height of host contracts
+	hostContractMinRevenue    string // minimum potential revenue of host contracts
+	hostContractOffset        uint64 // number of host contracts to skip
+	hostContractOutputType    string // output type for host contracts
+	hostContractProofOutcome  string // proof outcome of host contracts
+	hostContractSortBy        string // field to sort host contracts by
+	hostContractSortDesc      bool   // sort host contracts in descending order
+	hostContractStatus        string // comma separated statuses of host contracts
+	hostFolderRemoveForce     bool   // force folder remove
 
 	// Renter Flags
-	dataPieces                string // the number of data pieces a file should be uploaded with
-	parityPieces              string // the number of parity pieces a file should be uploaded with
-	renterAllContracts        bool   // Show all active and expired contracts
-	renterBubbleAll           bool   // Bubble the entire directory tree
-	renterDeleteRoot          bool   // Delete path start from root instead of the UserFolder.
-	renterDownloadAsync       bool   // Downloads files asynchronously
-	renterDownloadRecursive   bool   // Downloads folders recursively.
-	renterDownloadRoot        bool   // Download path start from root instead of the UserFolder.
-	renterFuseMountAllowOther bool   // Mount fuse with 'AllowOther' set to true.
-	renterListRecursive       bool   // List files of folder recursively.
-	renterListRoot            bool   // List path start from root instead of the UserFolder.
-	renterRenameRoot          bool   // Rename files relative to root instead of the UserFolder.
-	renterShowHistory         bool   // Show download history in addition to download queue.
+	dataPieces                  string // the number of data pieces a file should be uploaded with
+	parityPieces                string // the number of parity pieces a file should be uploaded with
+	renterAllContracts          bool   // Show all active and expired contracts
+	renterBackupRoot            bool   // Backup siapath start from root instead of the UserFolder.
+	renterBackupSiaPath         string // Directory to back up, restore to or list backups of.
+	renterBubbleAll             bool   // Bubble the entire directory tree
+	renterDeleteRoot            bool   // Delete path start from root instead of the UserFolder.
+	renterDownloadAsync         bool   // Downloads files asynchronously
+	renterDownloadRecursive     bool   // Downloads folders recursively.
+	renterDownloadResumable     bool   // Resume downloads after a restart of siad.
+	renterDownloadRoot          bool   // Download path start from root instead of the UserFolder.
+	renterFuseMountAllowOther   bool   // Mount fuse with 'AllowOther' set to true.
+	renterFuseMountReadOnly     bool   // Mount fuse with 'ReadOnly' set to true.
+	renterListRecursive         bool   // List files of folder recursively.
+	renterListRoot              bool   // List path start from root instead of the UserFolder.
+	renterPolicyCipherType      string // Default cipher type of a directory's policy.
+	renterPolicyRepairThresh    string // Repair threshold of a directory's policy.
+	renterQuotaMaxDownloadSpend string // Max download spending of a directory's quota.
+	renterQuotaMaxStored        string // Max stored bytes of a directory's quota.
+	renterQuotaMaxUploadSpend   string // Max upload spending of a directory's quota.
+	renterRegistryRevision      uint64 // Revision of an updated registry entry.
+	renterRenameRoot            bool   // Rename files relative to root instead of the UserFolder.
+	renterShowHistory           bool   // Show download history in addition to download queue.
+	renterVersionsRoot          bool   // File versions path start from root instead of the UserFolder.
 
 	// Renter Allowance Flags
 	allowanceFunds       string // amount of money to be used within a period
@@ -254,6 +284,9 @@ func main() {
 		// set API password if it was not set
 		setAPIPasswordIfNotSet()
 
+		// set the TLS config if the API is served over TLS
+		setAPITLSConfig()
+
 		// Check if the siaDir is set.
 		if siaDir == "" {
 			// No siaDir passed in, fetch the siaDir
@@ -297,10 +330,27 @@ func initCmds() *cobra.Command {
 	gatewayBlocklistCmd.AddCommand(gatewayBlocklistAppendCmd, gatewayBlocklistClearCmd, gatewayBlocklistRemoveCmd, gatewayBlocklistSetCmd)
 
 	root.AddCommand(hostCmd)
-	hostCmd.AddCommand(hostAnnounceCmd, hostConfigCmd, hostContractCmd, hostFolderCmd, hostSectorCmd)
-	hostFolderCmd.AddCommand(hostFolderAddCmd, hostFolderRemoveCmd, hostFolderResizeCmd)
+	hostCmd.AddCommand(hostAnnounceCmd, hostConfigCmd, hostContractCmd, hostFolderCmd, hostPricingCmd, hostScrubCmd, hostSectorCmd)
+	hostPricingCmd.AddCommand(hostPricingSetCmd)
+	hostFolderCmd.AddCommand(hostFolderAddCmd, hostFolderRemoveCmd, hostFolderResizeCmd, hostFolderTierCmd)
 	hostSectorCmd.AddCommand(hostSectorDeleteCmd)
+	hostContractCmd.AddCommand(hostContractExportCmd)
 	hostContractCmd.Flags().StringVarP(&hostContractOutputType, "type", "t", "value", "Select output type")
+	hostContractExportCmd.Flags().StringVar(&hostContractExportFormat, "format", "csv", "Export format, either csv or json")
+	for _, cmd := range []*cobra.Command{hostContractCmd, hostContractExportCmd} {
+		cmd.Flags().StringVar(&hostContractStatus, "status", "", "Comma separated list of obligation statuses to show, e.g. succeeded,failed")
+		cmd.Flags().Uint64Var(&hostContractMinExpiration, "min-expiration", 0, "Minimum expiration height")
+		cmd.Flags().Uint64Var(&hostContractMaxExpiration, "max-expiration", 0, "Maximum expiration height")
+		cmd.Flags().Uint64Var(&hostContractMinNegotiated, "min-negotiation", 0, "Minimum negotiation height")
+		cmd.Flags().Uint64Var(&hostContractMaxNegotiated, "max-negotiation", 0, "Maximum negotiation height")
+		cmd.Flags().StringVar(&hostContractMinRevenue, "min-revenue", "", "Minimum potential revenue, e.g. 10SC")
+		cmd.Flags().StringVar(&hostContractMaxRevenue, "max-revenue", "", "Maximum potential revenue, e.g. 100SC")
+		cmd.Flags().StringVar(&hostContractProofOutcome, "proof", "", "Proof outcome, one of confirmed, missed or pending")
+		cmd.Flags().StringVar(&hostContractSortBy, "sort", modules.ObligationSortByExpiration, "Sort by datasize, expiration, id, negotiation or revenue")
+		cmd.Flags().BoolVar(&hostContractSortDesc, "desc", false, "Sort in descending order")
+		cmd.Flags().Uint64Var(&hostContractOffset, "offset", 0, "Number of contracts to skip")
+		cmd.Flags().Uint64Var(&hostContractLimit, "limit", 0, "Maximum number of contracts to show, 0 for no limit")
+	}
 	hostFolderRemoveCmd.Flags().BoolVarP(&hostFolderRemoveForce, "force", "f", false, "Force the removal of the folder and its data")
 
 	root.AddCommand(hostdbCmd)
@@ -314,29 +364,49 @@ func initCmds() *cobra.Command {
 	renterCmd.AddCommand(renterAllowanceCmd, renterBubbleCmd, renterBackupCreateCmd, renterBackupListCmd, renterBackupLoadCmd,
 		renterCleanCmd, renterContractsCmd, renterContractsRecoveryScanProgressCmd, renterDownloadCancelCmd,
 		renterDownloadsCmd, renterExportCmd, renterFilesDeleteCmd, renterFilesDownloadCmd,
-		renterFilesListCmd, renterFilesRenameCmd, renterFilesUnstuckCmd, renterFilesUploadCmd,
-		renterFuseCmd, renterLostCmd, renterPricesCmd, renterRatelimitCmd, renterSetAllowanceCmd,
-		renterSetLocalPathCmd, renterTriggerContractRecoveryScanCmd, renterUploadsCmd, renterWorkersCmd,
-		renterHealthSummaryCmd)
+		renterDirPolicyCmd, renterFilesListCmd, renterFilesRenameCmd, renterFilesRestoreVersionCmd, renterFilesUnstuckCmd,
+		renterFilesUploadCmd, renterFilesVersionsCmd, renterFuseCmd, renterLostCmd, renterPricesCmd,
+		renterRatelimitCmd, renterRegistryCmd, renterScrubCmd, renterSetAllowanceCmd, renterSetDirPolicyCmd, renterSetDirQuotaCmd, renterSetLocalPathCmd, renterSetMaxVersionsCmd,
+		renterSetPriorityWeightsCmd, renterTriggerContractRecoveryScanCmd, renterUploadsCmd, renterWorkersCmd, renterHealthSummaryCmd)
 	renterWorkersCmd.AddCommand(renterWorkersAccountsCmd, renterWorkersDownloadsCmd, renterWorkersPriceTableCmd, renterWorkersReadJobsCmd, renterWorkersHasSectorJobSCmd, renterWorkersUploadsCmd, renterWorkersReadRegistryCmd, renterWorkersUpdateRegistryCmd)
 
 	renterAllowanceCmd.AddCommand(renterAllowanceCancelCmd)
+	renterRegistryCmd.AddCommand(renterRegistryGetCmd, renterRegistrySetCmd, renterRegistryWatchCmd)
+	renterScrubCmd.AddCommand(renterScrubCancelCmd, renterScrubResultsCmd, renterScrubStartCmd)
+	renterRegistrySetCmd.Flags().Uint64Var(&renterRegistryRevision, "revision", 0, "the revision number of the updated entry")
 	renterBubbleCmd.Flags().BoolVarP(&renterBubbleAll, "all", "A", false, "Bubble the entire directory tree")
 	renterContractsCmd.AddCommand(renterContractsViewCmd)
+	renterDownloadsCmd.AddCommand(renterDownloadsResumeCmd)
 	renterFilesUploadCmd.AddCommand(renterFilesUploadPauseCmd, renterFilesUploadResumeCmd)
 
+	renterBackupCreateCmd.Flags().StringVar(&renterBackupSiaPath, "siapath", "", "Only back up the directory at the siapath")
+	renterBackupCreateCmd.Flags().BoolVar(&renterBackupRoot, "root", false, "Treat the siapath as relative to root instead of the user homedir")
+	renterBackupListCmd.Flags().StringVar(&renterBackupSiaPath, "siapath", "", "Only list the backups of the directory at the siapath")
+	renterBackupListCmd.Flags().BoolVar(&renterBackupRoot, "root", false, "Treat the siapath as relative to root instead of the user homedir")
+	renterBackupLoadCmd.Flags().StringVar(&renterBackupSiaPath, "siapath", "", "Restore the backup to the directory at the siapath")
+	renterBackupLoadCmd.Flags().BoolVar(&renterBackupRoot, "root", false, "Treat the siapath as relative to root instead of the user homedir")
 	renterContractsCmd.Flags().BoolVarP(&renterAllContracts, "all", "A", false, "Show all expired contracts in addition to active contracts")
 	renterDownloadsCmd.Flags().BoolVarP(&renterShowHistory, "history", "H", false, "Show download history in addition to the download queue")
 	renterFilesDeleteCmd.Flags().BoolVar(&renterDeleteRoot, "root", false, "Delete files and folders from root instead of from the user home directory")
 	renterFilesDownloadCmd.Flags().BoolVarP(&renterDownloadAsync, "async", "A", false, "Download file asynchronously")
 	renterFilesDownloadCmd.Flags().BoolVarP(&renterDownloadRecursive, "recursive", "R", false, "Download folder recursively")
+	renterFilesDownloadCmd.Flags().BoolVar(&renterDownloadResumable, "resumable", false, "Resume the download after a restart of siad")
 	renterFilesDownloadCmd.Flags().BoolVar(&renterDownloadRoot, "root", false, "Download files and folders from root instead of from the user home directory")
 	renterFilesListCmd.Flags().BoolVarP(&renterListRecursive, "recursive", "R", false, "Recursively list files and folders")
 	renterFilesListCmd.Flags().BoolVar(&renterListRoot, "root", false, "List files and folders from root instead of from the user home directory")
 	renterFilesUploadCmd.Flags().StringVar(&dataPieces, "data-pieces", "", "the number of data pieces a files should be uploaded with")
 	renterFilesUploadCmd.Flags().StringVar(&parityPieces, "parity-pieces", "", "the number of parity pieces a files should be uploaded with")
+	renterSetDirPolicyCmd.Flags().StringVar(&dataPieces, "data-pieces", "", "the number of data pieces new files in the directory should be uploaded with")
+	renterSetDirPolicyCmd.Flags().StringVar(&parityPieces, "parity-pieces", "", "the number of parity pieces new files in the directory should be uploaded with")
+	renterSetDirPolicyCmd.Flags().StringVar(&renterPolicyCipherType, "cipher-type", "", "the cipher type new files in the directory should be encrypted with")
+	renterSetDirPolicyCmd.Flags().StringVar(&renterPolicyRepairThresh, "repair-threshold", "", "the health at which files in the directory are repaired")
+	renterSetDirQuotaCmd.Flags().StringVar(&renterQuotaMaxStored, "max-stored", "", "the maximum size of the files in the directory, e.g. 500GB")
+	renterSetDirQuotaCmd.Flags().StringVar(&renterQuotaMaxUploadSpend, "max-upload-spend", "", "the maximum spending on uploads per period, e.g. 100SC")
+	renterSetDirQuotaCmd.Flags().StringVar(&renterQuotaMaxDownloadSpend, "max-download-spend", "", "the maximum spending on downloads per period, e.g. 100SC")
 	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
 	renterFilesRenameCmd.Flags().BoolVar(&renterRenameRoot, "root", false, "Rename files relative to root instead of the user homedir")
+	renterFilesRestoreVersionCmd.Flags().BoolVar(&renterVersionsRoot, "root", false, "Restore versions of files relative to root instead of the user homedir")
+	renterFilesVersionsCmd.Flags().BoolVar(&renterVersionsRoot, "root", false, "List versions of files relative to root instead of the user homedir")
 
 	renterSetAllowanceCmd.Flags().StringVar(&allowanceFunds, "amount", "", "amount of money in allowance, specified in currency units")
 	renterSetAllowanceCmd.Flags().StringVar(&allowancePeriod, "period", "", "period of allowance in blocks (b), hours (h), days (d) or weeks (w)")
@@ -355,9 +425,15 @@ func initCmds() *cobra.Command {
 
 	renterFuseCmd.AddCommand(renterFuseMountCmd, renterFuseUnmountCmd)
 	renterFuseMountCmd.Flags().BoolVarP(&renterFuseMountAllowOther, "allow-other", "", false, "Allow users other than the user that mounted the fuse directory to access and use the fuse directory")
+	renterFuseMountCmd.Flags().BoolVarP(&renterFuseMountReadOnly, "read-only", "", false, "Mount the fuse directory in read-only mode")
 
 	// Daemon Commands
-	root.AddCommand(alertsCmd, globalRatelimitCmd, profileCmd, stackCmd, stopCmd, updateCmd, versionCmd)
+	root.AddCommand(alertsCmd, eventsCmd, globalRatelimitCmd, profileCmd, stackCmd, stopCmd, tokensCmd, updateCmd, versionCmd, webhooksCmd)
+	eventsCmd.Flags().StringVar(&daemonEventTypes, "types", "", "Comma separated list of event types to subscribe to")
+	webhooksCmd.AddCommand(webhooksAddCmd, webhooksRemoveCmd)
+	tokensCmd.AddCommand(tokensCreateCmd, tokensRemoveCmd)
+	tokensCreateCmd.Flags().StringVar(&daemonTokenValidity, "validity", "", "Duration for which the token is valid, e.g. 720h. Tokens are valid forever by default")
+	webhooksAddCmd.Flags().StringVar(&daemonEventTypes, "types", "", "Comma separated list of event types posted to the webhook")
 	profileCmd.AddCommand(profileStartCmd, profileStopCmd)
 	profileStartCmd.Flags().BoolVarP(&daemonCPUProfile, "cpu", "c", false, "Start the CPU profile")
 	profileStartCmd.Flags().BoolVarP(&daemonMemoryProfile, "memory", "m", false, "Start the Memory profile")
@@ -398,15 +474,35 @@ func initClient(root *cobra.Command, verbose *bool, client *client.Client, siaDi
 	root.PersistentFlags().BoolVarP(verbose, "verbose", "v", false, "Display additional information")
 	root.PersistentFlags().StringVarP(&client.Address, "addr", "a", "localhost:9980", "which host/port to communicate with (i.e. the host/port siad is listening on)")
 	root.PersistentFlags().StringVarP(&client.Password, "apipassword", "", "", "the password for the API's http authentication")
+	root.PersistentFlags().StringVarP(&client.APIToken, "apitoken", "", "", "an API token to authenticate with instead of the API password")
 	root.PersistentFlags().StringVarP(siaDir, "sia-directory", "d", "", "location of the sia directory")
 	root.PersistentFlags().StringVarP(&client.UserAgent, "useragent", "", "Sia-Agent", "the useragent used by siac to connect to the daemon's API")
+	root.PersistentFlags().StringVarP(&client.UnixSocket, "api-unix-socket", "", "", "the unix socket of the daemon's API, used instead of --addr if set")
+	root.PersistentFlags().BoolVarP(&apiTLS, "api-tls", "", false, "connect to the daemon's API over TLS")
+	root.PersistentFlags().StringVarP(&apiTLSCA, "api-tls-ca", "", "", "CA certificates to verify the API's TLS certificate with, implies --api-tls")
+	root.PersistentFlags().StringVarP(&apiTLSCert, "api-tls-cert", "", "", "client certificate to present to the API, implies --api-tls")
+	root.PersistentFlags().StringVarP(&apiTLSKey, "api-tls-key", "", "", "key of the client certificate presented to the API")
 	root.PersistentFlags().BoolVarP(alertSuppress, "alert-suppress", "s", false, "suppress siac alerts")
 }
 
+// setAPITLSConfig sets the TLS config of the client if the API is served over
+// TLS.
+func setAPITLSConfig() {
+	if !apiTLS && apiTLSCA == "" && apiTLSCert == "" {
+		return
+	}
+	tlsConfig, err := client.NewTLSConfig(apiTLSCA, apiTLSCert, apiTLSKey)
+	if err != nil {
+		fmt.Println("Exiting: Error loading TLS config:", err)
+		os.Exit(exitCodeGeneral)
+	}
+	httpClient.TLSConfig = tlsConfig
+}
+
 // setAPIPasswordIfNotSet sets API password if it was not set
 func setAPIPasswordIfNotSet() {
-	// Check if the API Password is set
-	if httpClient.Password == "" {
+	// Check if the API Password or an API token is set
+	if httpClient.Password == "" && httpClient.APIToken == "" {
 		// No password passed in, fetch the API Password
 		pw, err := build.APIPassword()
 		if err != nil {
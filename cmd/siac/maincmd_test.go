@@ -172,8 +172,8 @@ func getCmdUsage(t *testing.T, cmd *cobra.Command) string {
 	nl := `
 `
 	usage = strings.ReplaceAll(usage, beforeHelpCommand, beforeHelpCommand+nl+helpCommand)
-	beforeHelpFlag := "the password for the API's http authentication"
-	helpFlag := `  -h, --help                   help for .*siac(\.test|)`
+	beforeHelpFlag := "an API token to authenticate with instead of the API password"
+	helpFlag := `  -h, --help +help for .*siac(\.test|)`
 	cmdUsagePattern := strings.ReplaceAll(usage, beforeHelpFlag, beforeHelpFlag+nl+helpFlag)
 
 	return cmdUsagePattern
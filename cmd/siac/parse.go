@@ -290,6 +290,15 @@ func parseRatelimit(rateLimitStr string) (int64, error) {
 	return 0, ErrParseRateLimitUnits
 }
 
+// limitString returns "unlimited" for a limit of 0 and the formatted limit
+// otherwise.
+func limitString(limit uint64, formatted string) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return formatted
+}
+
 // ratelimitUnits converts an int64 to a string with human-readable ratelimit
 // units. The unit used will be the largest unit that results in a value greater
 // than 1. The value is rounded to 4 significant digits.
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+var (
+	renterRegistryCmd = &cobra.Command{
+		Use:   "registry",
+		Short: "Read, update and watch registry entries",
+		Long: `Read, update and watch entries of the registry. An entry is identified by
+the public key of its owner and a data key.`,
+		// Run field not provided; registry requires a subcommand.
+	}
+
+	renterRegistryGetCmd = &cobra.Command{
+		Use:   "get [publickey] [datakey]",
+		Short: "Read a registry entry",
+		Long: `Read the entry with the highest revision number for the public key and
+data key from the hosts. The public key is expected in the format
+'ed25519:<hex>' and the data key as a hex encoded hash.`,
+		Run: wrap(renterregistrygetcmd),
+	}
+
+	renterRegistrySetCmd = &cobra.Command{
+		Use:   "set [datakey] [data]",
+		Short: "Update a registry entry",
+		Long: `Update the entry for the data key with the provided data. The hex
+encoded ed25519 secret key which signs the entry is read from stdin. Unless
+--revision is specified, the revision number of the current entry is
+incremented by one.`,
+		Run: wrap(renterregistrysetcmd),
+	}
+
+	renterRegistryWatchCmd = &cobra.Command{
+		Use:   "watch [publickey] [datakey]",
+		Short: "Watch a registry entry for updates",
+		Long: `Subscribe to the entry for the public key and data key and print every
+update until the command is interrupted.`,
+		Run: wrap(renterregistrywatchcmd),
+	}
+)
+
+// parseRegistryEntryID parses the public key and data key which identify a
+// registry entry.
+func parseRegistryEntryID(pubKeyStr, dataKeyStr string) (types.SiaPublicKey, crypto.Hash, error) {
+	var spk types.SiaPublicKey
+	if err := spk.LoadString(pubKeyStr); err != nil {
+		return types.SiaPublicKey{}, crypto.Hash{}, errors.AddContext(err, "could not parse public key")
+	}
+	var dataKey crypto.Hash
+	if err := dataKey.LoadString(dataKeyStr); err != nil {
+		return types.SiaPublicKey{}, crypto.Hash{}, errors.AddContext(err, "could not parse data key")
+	}
+	return spk, dataKey, nil
+}
+
+// printRegistryValue prints a registry value.
+func printRegistryValue(srv modules.SignedRegistryValue) {
+	fmt.Printf("Revision:  %v\n", srv.Revision)
+	fmt.Printf("Type:      %v\n", srv.Type)
+	fmt.Printf("Data:      %x\n", srv.Data)
+	fmt.Printf("Signature: %x\n", srv.Signature)
+}
+
+// renterregistrygetcmd is the handler for the command `siac renter registry
+// get [publickey] [datakey]`. It reads a registry entry.
+func renterregistrygetcmd(pubKeyStr, dataKeyStr string) {
+	spk, dataKey, err := parseRegistryEntryID(pubKeyStr, dataKeyStr)
+	if err != nil {
+		die(err)
+	}
+	srv, err := httpClient.RegistryRead(spk, dataKey)
+	if err != nil {
+		die("Could not read registry entry:", err)
+	}
+	printRegistryValue(srv)
+}
+
+// renterregistrysetcmd is the handler for the command `siac renter registry
+// set [datakey] [data]`. It signs and updates a registry entry.
+func renterregistrysetcmd(dataKeyStr, data string) {
+	var dataKey crypto.Hash
+	if err := dataKey.LoadString(dataKeyStr); err != nil {
+		die("Could not parse data key:", err)
+	}
+	if len(data) > modules.RegistryDataSize {
+		die(fmt.Sprintf("Data can't be larger than %v bytes", modules.RegistryDataSize))
+	}
+
+	// Read the secret key.
+	skStr, err := passwordPrompt("Secret key: ")
+	if err != nil {
+		die("Could not read secret key:", err)
+	}
+	skBytes, err := hex.DecodeString(strings.TrimPrefix(skStr, "ed25519:"))
+	if err != nil {
+		die("Could not decode secret key:", err)
+	}
+	var sk crypto.SecretKey
+	if len(skBytes) != len(sk) {
+		die(fmt.Sprintf("Secret key needs to be %v bytes", len(sk)))
+	}
+	copy(sk[:], skBytes)
+	spk := types.Ed25519PublicKey(sk.PublicKey())
+
+	// Determine the revision.
+	revision := renterRegistryRevision
+	if revision == 0 {
+		srv, err := httpClient.RegistryRead(spk, dataKey)
+		if err == nil {
+			revision = srv.Revision + 1
+		} else if !strings.Contains(err.Error(), "not found") {
+			die("Could not read current registry entry:", err)
+		}
+	}
+
+	srv := modules.NewRegistryValue(dataKey, []byte(data), revision, modules.RegistryTypeWithoutPubkey).Sign(sk)
+	err = httpClient.RegistryUpdate(spk, srv)
+	if err != nil {
+		die("Could not update registry entry:", err)
+	}
+	fmt.Printf("Updated entry %v of %v to revision %v\n", dataKey, spk, revision)
+}
+
+// renterregistrywatchcmd is the handler for the command `siac renter registry
+// watch [publickey] [datakey]`. It prints every update of a registry entry.
+func renterregistrywatchcmd(pubKeyStr, dataKeyStr string) {
+	spk, dataKey, err := parseRegistryEntryID(pubKeyStr, dataKeyStr)
+	if err != nil {
+		die(err)
+	}
+	sub, err := httpClient.RegistrySubscribe(spk, dataKey)
+	if err != nil {
+		die("Could not subscribe to registry entry:", err)
+	}
+	defer sub.Close()
+	fmt.Println("Watching for updates...")
+	for {
+		_, srv, err := sub.Next()
+		if err != nil {
+			die("Subscription failed:", err)
+		}
+		fmt.Println()
+		printRegistryValue(srv)
+	}
+}
@@ -67,22 +67,27 @@ To bubble the root directory pass in '.' as the directory.`,
 	renterBackupCreateCmd = &cobra.Command{
 		Use:   "createbackup [name]",
 		Short: "Create a backup of the renter's siafiles",
-		Long:  "Create a backup of the renter's siafiles, using the specified name.",
-		Run:   wrap(renterbackupcreatecmd),
+		Long: `Create a backup of the renter's siafiles, using the specified name.
+Use the --siapath flag to only back up a single directory.`,
+		Run: wrap(renterbackupcreatecmd),
 	}
 
 	renterBackupLoadCmd = &cobra.Command{
 		Use:   "restorebackup [name]",
 		Short: "Restore a backup of the renter's siafiles",
-		Long:  "Restore the backup of the renter's siafiles with the given name.",
-		Run:   wrap(renterbackuprestorecmd),
+		Long: `Restore the backup of the renter's siafiles with the given name.
+Backups of a single directory are restored to their original directory. Use the
+--siapath flag to restore a backup to a different directory instead. The
+directory must not exist yet.`,
+		Run: wrap(renterbackuprestorecmd),
 	}
 
 	renterBackupListCmd = &cobra.Command{
 		Use:   "listbackups",
 		Short: "List backups stored on hosts",
-		Long:  "List backups stored on hosts",
-		Run:   wrap(renterbackuplistcmd),
+		Long: `List backups stored on hosts. Use the --siapath flag to only list the
+backups of a single directory.`,
+		Run: wrap(renterbackuplistcmd),
 	}
 
 	renterCleanCmd = &cobra.Command{
@@ -131,6 +136,15 @@ the command 'siac renter lost' to see the renter's lost files.`,
 		Run:   wrap(renterdownloadscmd),
 	}
 
+	renterDownloadsResumeCmd = &cobra.Command{
+		Use:   "resume",
+		Short: "Resume persisted downloads",
+		Long: `Resume the resumable downloads which are not in progress. Resumable
+downloads are resumed automatically after siad starts. Chunks which were
+already written to the destination are skipped.`,
+		Run: wrap(renterdownloadsresumecmd),
+	}
+
 	renterDownloadCancelCmd = &cobra.Command{
 		Use:   "canceldownload [cancelID]",
 		Short: "Cancel async download",
@@ -168,6 +182,31 @@ the command 'siac renter lost' to see the renter's lost files.`,
 		Run:     wrap(renterfilesrenamecmd),
 	}
 
+	renterFilesRestoreVersionCmd = &cobra.Command{
+		Use:   "restore-version [path] [version]",
+		Short: "Restore a prior version of a file",
+		Long: `Replace a file with one of its prior versions. The replaced file is kept
+as the newest version of the file.`,
+		Run: wrap(renterfilesrestoreversioncmd),
+	}
+
+	renterFilesVersionsCmd = &cobra.Command{
+		Use:   "versions [path]",
+		Short: "List the prior versions of a file",
+		Long: `List the prior versions of a file which were kept when the file was
+overwritten. Use 'siac renter setmaxversions' to enable keeping versions.`,
+		Run: wrap(renterfilesversionscmd),
+	}
+
+	renterDirPolicyCmd = &cobra.Command{
+		Use:   "dirpolicy [path]",
+		Short: "Show the policy of a directory",
+		Long: `Show the upload and repair policy of a directory. The policy of the
+directory only contains the fields which were set for it. The effective policy
+also contains the fields inherited from its parent directories.`,
+		Run: wrap(renterdirpolicycmd),
+	}
+
 	renterFuseCmd = &cobra.Command{
 		Use:   "fuse",
 		Short: "Perform fuse actions.",
@@ -179,10 +218,10 @@ the command 'siac renter lost' to see the renter's lost files.`,
 		Use:   "mount [path] [siapath]",
 		Short: "Mount a Sia folder to your disk",
 		Long: `Mount a Sia folder to your disk. Applications will be able to see this folder
-as though it is a normal part of your filesystem.  Currently experimental, and
-read-only. When Sia is ready to support read-write fuse mounting, siac will be
-updated to mount in read-write mode as the default. If you must guarantee that
-read-only mode is used, you must use the API.`,
+as though it is a normal part of your filesystem.  Currently experimental. By
+default the folder is mounted in read-write mode. Files written to the folder
+are staged on disk and uploaded when they are closed. Use --read-only to mount
+the folder in read-only mode.`,
 		Run: wrap(renterfusemountcmd),
 	}
 
@@ -194,6 +233,28 @@ local path where the Sia folder is mounted.`,
 		Run: wrap(renterfuseunmountcmd),
 	}
 
+	renterSetDirPolicyCmd = &cobra.Command{
+		Use:   "setdirpolicy [path]",
+		Short: "Set the policy of a directory",
+		Long: `Set the upload and repair policy of a directory. New files within the
+directory and its subdirectories are uploaded with the erasure coding
+parameters and cipher type of the policy unless they are specified for the
+upload. Files are repaired once their health reaches the repair threshold.
+Fields which are not specified are inherited from the parent directory.`,
+		Run: wrap(rentersetdirpolicycmd),
+	}
+
+	renterSetDirQuotaCmd = &cobra.Command{
+		Use:   "setdirquota [path]",
+		Short: "Set the quota of a directory",
+		Long: `Set the quota of a directory. Uploads and downloads of files within the
+directory and its subdirectories fail if they would exceed the maximum stored
+bytes or the maximum spending on uploads and downloads in the current
+allowance period. Limits which are not specified are not enforced. Setting a
+quota without any limits removes it.`,
+		Run: wrap(rentersetdirquotacmd),
+	}
+
 	renterSetLocalPathCmd = &cobra.Command{
 		Use:   "setlocalpath [siapath] [newlocalpath]",
 		Short: "Changes the local path of the file",
@@ -201,6 +262,23 @@ local path where the Sia folder is mounted.`,
 		Run:   wrap(rentersetlocalpathcmd),
 	}
 
+	renterSetMaxVersionsCmd = &cobra.Command{
+		Use:   "setmaxversions [number]",
+		Short: "Set the number of prior file versions to keep",
+		Long: `Set the number of prior versions the renter keeps when a file is
+overwritten. Set it to 0 to disable keeping versions.`,
+		Run: wrap(rentersetmaxversionscmd),
+	}
+
+	renterSetPriorityWeightsCmd = &cobra.Command{
+		Use:   "setpriorityweights [interactive] [user] [background]",
+		Short: "Set the weights of the priority classes",
+		Long: `Set the weights the renter uses to share its workers and memory between
+the interactive, user and background priority classes. While more than one class
+has work queued, every class receives a share proportional to its weight.`,
+		Run: wrap(rentersetpriorityweightscmd),
+	}
+
 	renterFilesUnstuckCmd = &cobra.Command{
 		Use:   "unstuckall",
 		Short: "Set all files to unstuck",
@@ -284,7 +362,7 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 	renterUploadsCmd = &cobra.Command{
 		Use:   "uploads",
 		Short: "View the upload queue",
-		Long:  "View the list of files currently uploading.",
+		Long:  "View the list of files currently uploading and the stats of the upload priority classes.",
 		Run:   wrap(renteruploadscmd),
 	}
 
@@ -525,11 +603,30 @@ func renteruploadscmd() {
 	}
 	if len(filteredFiles) == 0 {
 		fmt.Println("No files are uploading.")
-		return
+	} else {
+		fmt.Println("Uploading", len(filteredFiles), "files:")
+		for _, file := range filteredFiles {
+			fmt.Printf("%13s  %s (uploading, %0.2f%%)\n", modules.FilesizeUnits(file.Filesize), file.SiaPath, file.UploadProgress)
+		}
 	}
-	fmt.Println("Uploading", len(filteredFiles), "files:")
-	for _, file := range filteredFiles {
-		fmt.Printf("%13s  %s (uploading, %0.2f%%)\n", modules.FilesizeUnits(file.Filesize), file.SiaPath, file.UploadProgress)
+
+	// Print the stats of the priority classes.
+	rug, err := httpClient.RenterUploadsGet()
+	if err != nil {
+		die("Could not get upload stats:", err)
+	}
+	fmt.Println()
+	if rug.Paused {
+		fmt.Printf("Uploads and repairs are paused until %v\n", rug.PauseEndTime.Format(time.RFC822))
+	}
+	fmt.Println("Priority Classes:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Class\tWeight\tQueued Chunks\tActive Chunks\tFinished Chunks\tScheduled")
+	for _, pc := range rug.PriorityClasses {
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\t%v\n", pc.Class, pc.Weight, pc.QueuedChunks, pc.ActiveChunks, pc.FinishedChunks, modules.FilesizeUnits(pc.ScheduledBytes))
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
 	}
 }
 
@@ -577,6 +674,16 @@ func renterdownloadscmd() {
 	}
 }
 
+// renterdownloadsresumecmd is the handler for the command `siac renter
+// downloads resume`. Resumes the persisted downloads.
+func renterdownloadsresumecmd() {
+	err := httpClient.RenterDownloadsResumePost()
+	if err != nil {
+		die("Could not resume downloads:", err)
+	}
+	fmt.Println("Resumed downloads.")
+}
+
 // renterallowancecmd is the handler for the command `siac renter allowance`.
 // displays the current allowance.
 func renterallowancecmd() {
@@ -1361,7 +1468,16 @@ func renterbubblecmd(directory string) {
 // createbackup`.
 func renterbackupcreatecmd(name string) {
 	// Create backup.
-	err := httpClient.RenterCreateBackupPost(name)
+	var err error
+	if renterBackupSiaPath != "" {
+		siaPath, parseErr := modules.NewSiaPath(renterBackupSiaPath)
+		if parseErr != nil {
+			die("Couldn't parse SiaPath:", parseErr)
+		}
+		err = httpClient.RenterCreateDirBackupPost(name, siaPath, renterBackupRoot)
+	} else {
+		err = httpClient.RenterCreateBackupPost(name)
+	}
 	if err != nil {
 		die("Failed to create backup", err)
 	}
@@ -1371,7 +1487,16 @@ func renterbackupcreatecmd(name string) {
 // renterbackuprestorecmd is the handler for the command `siac renter
 // restorebackup`.
 func renterbackuprestorecmd(name string) {
-	err := httpClient.RenterRecoverBackupPost(name)
+	var err error
+	if renterBackupSiaPath != "" {
+		target, parseErr := modules.NewSiaPath(renterBackupSiaPath)
+		if parseErr != nil {
+			die("Couldn't parse SiaPath:", parseErr)
+		}
+		err = httpClient.RenterRecoverDirBackupPost(name, target, renterBackupRoot)
+	} else {
+		err = httpClient.RenterRecoverBackupPost(name)
+	}
 	if err != nil {
 		die("Failed to restore backup", err)
 	}
@@ -1379,7 +1504,17 @@ func renterbackuprestorecmd(name string) {
 
 // renterbackuplistcmd is the handler for the command `siac renter listbackups`.
 func renterbackuplistcmd() {
-	ubs, err := httpClient.RenterBackups()
+	var ubs api.RenterBackupsGET
+	var err error
+	if renterBackupSiaPath != "" {
+		siaPath, parseErr := modules.NewSiaPath(renterBackupSiaPath)
+		if parseErr != nil {
+			die("Couldn't parse SiaPath:", parseErr)
+		}
+		ubs, err = httpClient.RenterDirBackups(siaPath, renterBackupRoot)
+	} else {
+		ubs, err = httpClient.RenterBackups()
+	}
 	if err != nil {
 		die("Failed to retrieve backups", err)
 	} else if len(ubs.Backups) == 0 {
@@ -1387,10 +1522,14 @@ func renterbackuplistcmd() {
 		return
 	}
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "  Name\tCreation Date\tUpload Progress")
+	fmt.Fprintln(w, "  Name\tCreation Date\tUpload Progress\tDirectory")
 	for _, ub := range ubs.Backups {
 		date := time.Unix(int64(ub.CreationDate), 0)
-		fmt.Fprintf(w, "  %v\t%v\t%v\n", ub.Name, date.Format(time.ANSIC), ub.UploadProgress)
+		dir := "-"
+		if !ub.SiaPath.IsEmpty() {
+			dir = ub.SiaPath.String()
+		}
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\n", ub.Name, date.Format(time.ANSIC), ub.UploadProgress, dir)
 	}
 	if err := w.Flush(); err != nil {
 		die("failed to flush writer:", err)
@@ -1769,6 +1908,7 @@ func renterfileslistcmd(cmd *cobra.Command, args []string) {
 	if !verbose {
 		for _, dir := range dirs {
 			fmt.Printf("%v/\n", dir.dir.SiaPath)
+			printDirQuota(dir.dir)
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 			for _, subDir := range dir.subDirs {
 				name := subDir.SiaPath.Name() + "/"
@@ -1792,6 +1932,7 @@ func renterfileslistcmd(cmd *cobra.Command, args []string) {
 	// Handle the verbose output.
 	for _, dir := range dirs {
 		fmt.Println(dir.dir.SiaPath.String() + "/")
+		printDirQuota(dir.dir)
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintf(w, "  Name\tFile size\tAvailable\t Uploaded\tProgress\tRedundancy\tHealth\tStuck Health\tStuck\tRenewing\tOn Disk\tRecoverable\n")
 		for _, subDir := range dir.subDirs {
@@ -1852,6 +1993,110 @@ func renterfilesrenamecmd(path, newpath string) {
 	fmt.Printf("Renamed %s to %s\n", path, newpath)
 }
 
+// renterfilesrestoreversioncmd is the handler for the command `siac renter
+// restore-version [path] [version]`. It replaces a file with one of its prior
+// versions.
+func renterfilesrestoreversioncmd(path, versionStr string) {
+	siaPath, err := modules.NewSiaPath(path)
+	if err != nil {
+		die("Couldn't parse SiaPath:", err)
+	}
+	version, err := strconv.ParseUint(versionStr, 10, 64)
+	if err != nil {
+		die("Couldn't parse version:", err)
+	}
+	err = httpClient.RenterFileVersionRestorePost(siaPath, version, renterVersionsRoot)
+	if err != nil {
+		die("Could not restore version:", err)
+	}
+	fmt.Printf("Restored version %v of %s\n", version, path)
+}
+
+// renterfilesversionscmd is the handler for the command `siac renter versions
+// [path]`. It lists the prior versions of a file.
+func renterfilesversionscmd(path string) {
+	siaPath, err := modules.NewSiaPath(path)
+	if err != nil {
+		die("Couldn't parse SiaPath:", err)
+	}
+	rfv, err := httpClient.RenterFileVersionsGet(siaPath, renterVersionsRoot)
+	if err != nil {
+		die("Could not get file versions:", err)
+	}
+	if len(rfv.Versions) == 0 {
+		fmt.Println("No versions.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Version\tSize\tRedundancy\tModified")
+	for _, v := range rfv.Versions {
+		fmt.Fprintf(w, "  %v\t%v\t%.2f\t%v\n", v.Version, modules.FilesizeUnits(v.Filesize), v.Redundancy, v.ModificationTime.Format("2006-01-02 15:04:05"))
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
+// renterdirpolicycmd is the handler for the command `siac renter dirpolicy
+// [path]`. It shows the policy of a directory.
+func renterdirpolicycmd(path string) {
+	siaPath, err := modules.NewSiaPath(path)
+	if err != nil {
+		die("Couldn't parse SiaPath:", err)
+	}
+	rd, err := httpClient.RenterDirGet(siaPath)
+	if err != nil {
+		die("Could not get directory:", err)
+	}
+	if len(rd.Directories) == 0 {
+		die("Directory not found")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  \tData Pieces\tParity Pieces\tCipher Type\tRepair Threshold")
+	printPolicy := func(name string, p modules.DirPolicy) {
+		ec := "-\t-"
+		if p.DataPieces != 0 {
+			ec = fmt.Sprintf("%v\t%v", p.DataPieces, p.ParityPieces)
+		}
+		ct := p.CipherType.String()
+		if ct == "" {
+			ct = "-"
+		}
+		rt := "-"
+		if p.RepairThreshold != 0 {
+			rt = fmt.Sprint(p.RepairThreshold)
+		}
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\n", name, ec, ct, rt)
+	}
+	printPolicy("Directory", rd.Directories[0].Policy)
+	printPolicy("Effective", rd.Policy)
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
+// printDirQuota prints the quota of a directory together with its usage in
+// the current period. Nothing is printed for directories without a quota.
+func printDirQuota(dir modules.DirectoryInfo) {
+	quota := dir.Quota
+	if !quota.IsSet() {
+		return
+	}
+	stored := modules.FilesizeUnits(dir.AggregateSize) + " / -"
+	if quota.MaxStoredBytes != 0 {
+		stored = modules.FilesizeUnits(dir.AggregateSize) + " / " + modules.FilesizeUnits(quota.MaxStoredBytes)
+	}
+	spend := func(spent, max types.Currency) string {
+		if max.IsZero() {
+			return currencyUnits(spent) + " / -"
+		}
+		return currencyUnits(spent) + " / " + currencyUnits(max)
+	}
+	fmt.Printf("  Quota: stored %v, upload spend %v, download spend %v\n", stored,
+		spend(dir.QuotaUsage.UploadSpend, quota.MaxUploadSpend),
+		spend(dir.QuotaUsage.DownloadSpend, quota.MaxDownloadSpend))
+}
+
 // renterfusecmd displays the list of directories that are currently mounted via
 // fuse.
 func renterfusecmd() {
@@ -1893,10 +2138,6 @@ func renterfusecmd() {
 
 // renterfusemountcmd is the handler for the command `siac renter fuse mount [path] [siapath]`.
 func renterfusemountcmd(path, siaPathStr string) {
-	// TODO: Once read-write is supported on the backend, the 'true' flag can be
-	// set to 'false' - siac will support mounting read-write by default. Need
-	// to update the help string of the command to indicate that mounting will
-	// mount in read-write mode.
 	path = abs(path)
 	var siaPath modules.SiaPath
 	var err error
@@ -1909,7 +2150,7 @@ func renterfusemountcmd(path, siaPathStr string) {
 		}
 	}
 	opts := modules.MountOptions{
-		ReadOnly:   true,
+		ReadOnly:   renterFuseMountReadOnly,
 		AllowOther: renterFuseMountAllowOther,
 	}
 	err = httpClient.RenterFuseMount(path, siaPath, opts)
@@ -2191,6 +2432,121 @@ func renterratelimitcmd(downloadSpeedStr, uploadSpeedStr string) {
 	fmt.Println("Set renter maxdownloadspeed to ", downloadSpeedInt, " and maxuploadspeed to ", uploadSpeedInt)
 }
 
+// rentersetdirpolicycmd is the handler for the command `siac renter
+// setdirpolicy [path]`. It sets the policy of a directory.
+func rentersetdirpolicycmd(path string) {
+	siaPath, err := modules.NewSiaPath(path)
+	if err != nil {
+		die("Couldn't parse SiaPath:", err)
+	}
+	var policy modules.DirPolicy
+	policy.DataPieces, policy.ParityPieces, err = api.ParseDataAndParityPieces(dataPieces, parityPieces)
+	if err != nil {
+		die("Could not parse data and parity pieces:", err)
+	}
+	if renterPolicyCipherType != "" {
+		if err := policy.CipherType.FromString(renterPolicyCipherType); err != nil {
+			die("Could not parse cipher type:", err)
+		}
+	}
+	if renterPolicyRepairThresh != "" {
+		policy.RepairThreshold, err = strconv.ParseFloat(renterPolicyRepairThresh, 64)
+		if err != nil {
+			die("Could not parse repair threshold:", err)
+		}
+	}
+	err = httpClient.RenterDirSetPolicyPost(siaPath, policy)
+	if err != nil {
+		die("Could not set directory policy:", err)
+	}
+	fmt.Println("Set policy of", siaPath)
+}
+
+// rentersetdirquotacmd is the handler for the command `siac renter
+// setdirquota [path]`. It sets the quota of a directory.
+func rentersetdirquotacmd(path string) {
+	siaPath, err := modules.NewSiaPath(path)
+	if err != nil {
+		die("Couldn't parse SiaPath:", err)
+	}
+	var quota modules.DirQuota
+	if renterQuotaMaxStored != "" {
+		size, err := parseFilesize(renterQuotaMaxStored)
+		if err != nil {
+			die("Could not parse max stored bytes:", err)
+		}
+		quota.MaxStoredBytes, err = strconv.ParseUint(size, 10, 64)
+		if err != nil {
+			die("Could not parse max stored bytes:", err)
+		}
+	}
+	parseSpend := func(spend string) types.Currency {
+		if spend == "" {
+			return types.ZeroCurrency
+		}
+		hastings, err := types.ParseCurrency(spend)
+		if err != nil {
+			die("Could not parse spending limit:", err)
+		}
+		var c types.Currency
+		if _, err := fmt.Sscan(hastings, &c); err != nil {
+			die("Could not parse spending limit:", err)
+		}
+		return c
+	}
+	quota.MaxUploadSpend = parseSpend(renterQuotaMaxUploadSpend)
+	quota.MaxDownloadSpend = parseSpend(renterQuotaMaxDownloadSpend)
+	err = httpClient.RenterDirSetQuotaPost(siaPath, quota)
+	if err != nil {
+		die("Could not set directory quota:", err)
+	}
+	fmt.Println("Set quota of", siaPath)
+}
+
+// rentersetmaxversionscmd is the handler for the command `siac renter
+// setmaxversions [number]`. It sets the number of prior versions the renter
+// keeps when a file is overwritten.
+func rentersetmaxversionscmd(maxVersionsStr string) {
+	maxVersions, err := strconv.ParseUint(maxVersionsStr, 10, 64)
+	if err != nil {
+		die("Couldn't parse number of versions:", err)
+	}
+	err = httpClient.RenterMaxFileVersionsPost(maxVersions)
+	if err != nil {
+		die("Could not set max file versions:", err)
+	}
+	fmt.Println("Set max file versions to", maxVersions)
+}
+
+// rentersetpriorityweightscmd is the handler for the command `siac renter
+// setpriorityweights [interactive] [user] [background]`. It sets the weights
+// used to share the renter's capacity between the priority classes.
+func rentersetpriorityweightscmd(interactiveStr, userStr, backgroundStr string) {
+	var weights modules.PriorityWeights
+	for _, pw := range []struct {
+		str    string
+		weight *uint64
+	}{
+		{interactiveStr, &weights.Interactive},
+		{userStr, &weights.User},
+		{backgroundStr, &weights.Background},
+	} {
+		weight, err := strconv.ParseUint(pw.str, 10, 64)
+		if err != nil {
+			die("Couldn't parse weight:", err)
+		}
+		*pw.weight = weight
+	}
+	if err := weights.Validate(); err != nil {
+		die("Invalid weights:", err)
+	}
+	err := httpClient.RenterPriorityWeightsPost(weights)
+	if err != nil {
+		die("Could not set priority weights:", err)
+	}
+	fmt.Printf("Set priority weights to %v (interactive), %v (user) and %v (background)\n", weights.Interactive, weights.User, weights.Background)
+}
+
 // renterworkerscmd is the handler for the command `siac renter workers`.
 // It lists the Renter's workers.
 func renterworkerscmd() {
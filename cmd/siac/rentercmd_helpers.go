@@ -125,7 +125,7 @@ func downloadDir(siaPath modules.SiaPath, destination string) (tfs []trackedFile
 		}
 		// Download file.
 		totalSize += file.Filesize
-		_, err = httpClient.RenterDownloadFullGet(file.SiaPath, dst, true, true)
+		_, err = startFileDownload(file.SiaPath, dst)
 		if err != nil {
 			err = errors.AddContext(err, "Failed to start download")
 			return
@@ -527,7 +527,7 @@ func renterFilesDownload(path, destination string) {
 	// the call will return before the download has completed. The call is made
 	// as an async call.
 	start := time.Now()
-	cancelID, err := httpClient.RenterDownloadFullGet(siaPath, destination, true, true)
+	cancelID, err := startFileDownload(siaPath, destination)
 	if err != nil {
 		die("Download could not be started:", err)
 	}
@@ -710,3 +710,13 @@ func writeWorkerReadUpdateRegistryInfo(read bool, w *tabwriter.Writer, rw module
 		}
 	}
 }
+
+// startFileDownload starts an async download of the file at the siapath, which
+// is relative to root, to the destination. The download is resumable if the
+// --resumable flag is set.
+func startFileDownload(siaPath modules.SiaPath, destination string) (modules.DownloadID, error) {
+	if renterDownloadResumable {
+		return httpClient.RenterDownloadResumableGet(siaPath, destination, 0, 0, true)
+	}
+	return httpClient.RenterDownloadFullGet(siaPath, destination, true, true)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go.sia.tech/siad/modules"
+)
+
+var (
+	renterScrubCmd = &cobra.Command{
+		Use:   "scrub",
+		Short: "Show the status of the scrub",
+		Long: `Show the status of the current or last scrub. A scrub spot-checks the
+pieces of uploaded files on the hosts against the Merkle roots of the files.
+Pieces which are missing or fail verification are repaired.`,
+		Run: wrap(renterscrubcmd),
+	}
+
+	renterScrubCancelCmd = &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel the running scrub",
+		Long:  "Cancel the running scrub.",
+		Run:   wrap(renterscrubcancelcmd),
+	}
+
+	renterScrubResultsCmd = &cobra.Command{
+		Use:   "results [path]",
+		Short: "Show the results of the last scrubs of files",
+		Long: `Show the results of the last scrubs of the files within a directory. If no
+path is provided, the results of the files within the user's home directory are
+shown.`,
+		Run: renterscrubresultscmd,
+	}
+
+	renterScrubStartCmd = &cobra.Command{
+		Use:   "start [path]",
+		Short: "Start a scrub of a file or directory",
+		Long: `Start a scrub of the file or of all files within the directory at the path
+in the background. If no path is provided, all files within the user's home
+directory are scrubbed. Only one scrub can run at a time.`,
+		Run: renterscrubstartcmd,
+	}
+)
+
+// parseScrubArgs parses the optional siapath argument of the scrub commands.
+func parseScrubArgs(cmd *cobra.Command, args []string) modules.SiaPath {
+	switch len(args) {
+	case 0:
+		return modules.RootSiaPath()
+	case 1:
+	default:
+		_ = cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	if args[0] == "." || args[0] == "" || args[0] == "/" {
+		return modules.RootSiaPath()
+	}
+	siaPath, err := modules.NewSiaPath(args[0])
+	if err != nil {
+		die("Couldn't parse SiaPath:", err)
+	}
+	return siaPath
+}
+
+// renterscrubcmd is the handler for the command `siac renter scrub`. It shows
+// the status of the current or last scrub.
+func renterscrubcmd() {
+	ss, err := httpClient.RenterScrubGet()
+	if err != nil {
+		die("Could not get scrub status:", err)
+	}
+	if ss.StartTime.IsZero() {
+		fmt.Println("No scrub was started since siad was started.")
+		return
+	}
+	status := "Finished"
+	duration := ss.EndTime.Sub(ss.StartTime)
+	if ss.Running {
+		status = "Running"
+		duration = time.Since(ss.StartTime)
+	}
+	siaPath := ss.SiaPath.String()
+	if siaPath == "" {
+		siaPath = "{root}"
+	}
+	fmt.Printf(`Scrub Status:    %v
+Path:            %v
+Started:         %v
+Duration:        %v
+Files Scrubbed:  %v
+Files Failed:    %v
+Pieces Checked:  %v
+Pieces Failed:   %v
+Unverified:      %v
+`, status, siaPath, ss.StartTime.Format(time.RFC822), duration.Round(time.Second), ss.FilesScrubbed,
+		ss.FilesFailed, ss.PiecesChecked, ss.PiecesFailed, ss.PiecesUnverified)
+	if ss.Error != "" {
+		fmt.Println("Error:          ", ss.Error)
+	}
+}
+
+// renterscrubcancelcmd is the handler for the command `siac renter scrub
+// cancel`.
+func renterscrubcancelcmd() {
+	err := httpClient.RenterScrubCancelPost()
+	if err != nil {
+		die("Could not cancel scrub:", err)
+	}
+	fmt.Println("Scrub cancelled.")
+}
+
+// renterscrubresultscmd is the handler for the command `siac renter scrub
+// results [path]`.
+func renterscrubresultscmd(cmd *cobra.Command, args []string) {
+	siaPath := parseScrubArgs(cmd, args)
+	rsr, err := httpClient.RenterScrubResultsGet(siaPath, true, false)
+	if err != nil {
+		die("Could not get scrub results:", err)
+	}
+	if len(rsr.Results) == 0 {
+		fmt.Println("No scrubbed files.")
+		return
+	}
+	results := rsr.Results
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SiaPath.String() < results[j].SiaPath.String()
+	})
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Path\tLast Scrub\tChecked\tFailed\tUnverified")
+	for _, r := range results {
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\n", r.SiaPath, r.LastScrubTime.Format("2006-01-02 15:04:05"), r.PiecesChecked, r.PiecesFailed, r.PiecesUnverified)
+	}
+	if err := w.Flush(); err != nil {
+		die("failed to flush writer:", err)
+	}
+}
+
+// renterscrubstartcmd is the handler for the command `siac renter scrub start
+// [path]`.
+func renterscrubstartcmd(cmd *cobra.Command, args []string) {
+	siaPath := parseScrubArgs(cmd, args)
+	err := httpClient.RenterScrubStartPost(siaPath, false)
+	if err != nil {
+		die("Could not start scrub:", err)
+	}
+	fmt.Println("Scrub started. Use 'siac renter scrub' to check its progress.")
+}
@@ -31,6 +31,11 @@ func passwordPrompt(prompt string) (string, error) {
 // verifyAPISecurity checks that the security values are consistent with a
 // sane, secure system.
 func verifyAPISecurity(config Config) error {
+	// An empty address means the API only listens on a unix socket.
+	if config.Siad.APIaddr == "" {
+		return nil
+	}
+
 	// Make sure that only the loopback address is allowed unless the
 	// --disable-api-security flag has been used.
 	if !config.Siad.AllowAPIBind {
@@ -108,16 +113,22 @@ func processModules(modules string) (string, error) {
 // processConfig checks the configuration values and performs cleanup on
 // incorrect-but-allowed values.
 func processConfig(config Config) (Config, error) {
-	var err1, err2 error
+	var err1, err2, err3 error
 	config.Siad.APIaddr = processNetAddr(config.Siad.APIaddr)
+	if (config.Siad.APITLSCert == "") != (config.Siad.APITLSKey == "") {
+		err1 = errors.New("--api-tls-cert and --api-tls-key need to be set together")
+	}
 	config.Siad.RPCaddr = processNetAddr(config.Siad.RPCaddr)
 	config.Siad.HostAddr = processNetAddr(config.Siad.HostAddr)
-	config.Siad.Modules, err1 = processModules(config.Siad.Modules)
+	if config.Siad.S3Addr != "" {
+		config.Siad.S3Addr = processNetAddr(config.Siad.S3Addr)
+	}
+	config.Siad.Modules, err2 = processModules(config.Siad.Modules)
 	if config.Siad.Profile != "" {
-		config.Siad.Profile, err2 = profile.ProcessProfileFlags(config.Siad.Profile)
+		config.Siad.Profile, err3 = profile.ProcessProfileFlags(config.Siad.Profile)
 	}
-	err3 := verifyAPISecurity(config)
-	err := build.JoinErrors([]error{err1, err2, err3}, ", and ")
+	err4 := verifyAPISecurity(config)
+	err := build.JoinErrors([]error{err1, err2, err3, err4}, ", and ")
 	if err != nil {
 		return Config{}, err
 	}
@@ -220,7 +231,13 @@ func startDaemon(config Config) (err error) {
 	nodeParams := parseModules(config)
 
 	// Start and run the server.
-	srv, err := server.New(config.Siad.APIaddr, config.Siad.RequiredUserAgent, config.APIPassword, nodeParams, loadStart)
+	listenerOpts := server.ListenerOptions{
+		TLSCertFile:     config.Siad.APITLSCert,
+		TLSKeyFile:      config.Siad.APITLSKey,
+		TLSClientCAFile: config.Siad.APITLSClientCA,
+		UnixSocket:      config.Siad.APIUnixSocket,
+	}
+	srv, err := server.NewWithOptions(config.Siad.APIaddr, listenerOpts, config.Siad.RequiredUserAgent, config.APIPassword, nodeParams, loadStart)
 	if err != nil {
 		return err
 	}
@@ -228,6 +245,15 @@ func startDaemon(config Config) (err error) {
 	// Attempt to auto-unlock the wallet using the SIA_WALLET_PASSWORD env variable
 	tryAutoUnlock(srv)
 
+	// Start the S3 gateway if an address was provided.
+	if config.Siad.S3Addr != "" {
+		err = srv.ServeS3(config.Siad.S3Addr, build.S3AccessKey(), build.S3SecretKey())
+		if err != nil {
+			return errors.Compose(errors.AddContext(err, "failed to start s3 gateway"), srv.Close())
+		}
+		fmt.Println("S3 gateway listening on", srv.S3Address())
+	}
+
 	// listen for kill signals
 	sigChan := installKillSignalHandler()
 
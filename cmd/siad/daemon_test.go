@@ -206,4 +206,12 @@ func TestVerifyAPISecurity(t *testing.T) {
 	if err != nil {
 		t.Error("public + securityOff with authentication was rejected:", err)
 	}
+	// Check that an empty address is accepted since the API only listens on a
+	// unix socket then.
+	var securityOnUnixSocket Config
+	securityOnUnixSocket.Siad.APIUnixSocket = "siad.sock"
+	err = verifyAPISecurity(securityOnUnixSocket)
+	if err != nil {
+		t.Error("unix socket + securityOn was rejected:", err)
+	}
 }
@@ -37,8 +37,14 @@ type Config struct {
 		HostAddr      string
 		SiaMuxTCPAddr string
 		SiaMuxWSAddr  string
+		S3Addr        string
 		AllowAPIBind  bool
 
+		APITLSCert     string
+		APITLSKey      string
+		APITLSClientCA string
+		APIUnixSocket  string
+
 		Modules           string
 		NoBootstrap       bool
 		UseUPNP           bool
@@ -192,7 +198,11 @@ func main() {
 	root.Flags().StringVarP(&globalConfig.Siad.RequiredUserAgent, "agent", "", "Sia-Agent", "required substring for the user agent")
 	root.Flags().StringVarP(&globalConfig.Siad.HostAddr, "host-addr", "", ":9982", "which port the host listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.ProfileDir, "profile-directory", "", "profiles", "location of the profiling directory")
-	root.Flags().StringVarP(&globalConfig.Siad.APIaddr, "api-addr", "", "localhost:9980", "which host:port the API server listens on")
+	root.Flags().StringVarP(&globalConfig.Siad.APIaddr, "api-addr", "", "localhost:9980", "which host:port the API server listens on, disabled if empty")
+	root.Flags().StringVarP(&globalConfig.Siad.APITLSCert, "api-tls-cert", "", "", "path to a PEM encoded certificate to serve the API over TLS")
+	root.Flags().StringVarP(&globalConfig.Siad.APITLSKey, "api-tls-key", "", "", "path to the PEM encoded key of the API's TLS certificate")
+	root.Flags().StringVarP(&globalConfig.Siad.APITLSClientCA, "api-tls-client-ca", "", "", "path to PEM encoded CA certificates which API clients need to present a certificate of")
+	root.Flags().StringVarP(&globalConfig.Siad.APIUnixSocket, "api-unix-socket", "", "", "path of a unix socket the API server listens on, only accessible by the user running siad")
 	root.Flags().StringVarP(&globalConfig.Siad.SiaDir, "sia-directory", "d", "", "location of the sia directory")
 	root.Flags().BoolVarP(&globalConfig.Siad.NoBootstrap, "no-bootstrap", "", false, "disable bootstrapping on this run")
 	root.Flags().BoolVarP(&globalConfig.Siad.UseUPNP, "upnp", "", true, "use UPnP for port forwarding and external IP discovery")
@@ -200,6 +210,7 @@ func main() {
 	root.Flags().StringVarP(&globalConfig.Siad.RPCaddr, "rpc-addr", "", ":9981", "which port the gateway listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.SiaMuxTCPAddr, "siamux-addr", "", ":9983", "which port the SiaMux listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.SiaMuxWSAddr, "siamux-addr-ws", "", ":9984", "which port the SiaMux websocket listens on")
+	root.Flags().StringVarP(&globalConfig.Siad.S3Addr, "s3-addr", "", "", "which host:port the S3 gateway listens on, disabled if empty")
 	root.Flags().StringVarP(&globalConfig.Siad.Modules, "modules", "M", "gctwrhfa", "enabled modules, see 'siad modules' for more info")
 	root.Flags().BoolVarP(&globalConfig.Siad.AuthenticateAPI, "authenticate-api", "", true, "enable API password protection")
 	root.Flags().BoolVarP(&globalConfig.Siad.TempPassword, "temp-password", "", false, "enter a temporary API password during startup")
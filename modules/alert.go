@@ -49,6 +49,9 @@ const (
 	// call to 'gateway.Offline' if the value returned is 'false' and
 	// unregistered when it returns 'true'.
 	AlertIDGatewayOffline = "gateway-offline"
+	// AlertIDHostCorruptSectors is the id of the alert that is registered when
+	// the host's scrubber finds sectors whose data doesn't match their root.
+	AlertIDHostCorruptSectors = "host-corrupt-sectors"
 	// AlertIDHostDiskTrouble is the id of the alert that is registered when the
 	// host is encountering problems interacting with one or more of his disks
 	AlertIDHostDiskTrouble = "host-disk-trouble"
@@ -64,6 +67,18 @@ func AlertIDSiafileLowRedundancy(uid string) AlertID {
 	return AlertID(fmt.Sprintf("low-redundancy:%v", uid))
 }
 
+// AlertIDSiafileScrubFailed uses a Siafile's UID to create a unique AlertID
+// for an alert about pieces which failed verification during a scrub.
+func AlertIDSiafileScrubFailed(uid string) AlertID {
+	return AlertID(fmt.Sprintf("scrub-failed:%v", uid))
+}
+
+// AlertIDDirQuota uses a siadir's SiaPath to create a unique AlertID for an
+// alert about the siadir approaching its quota.
+func AlertIDDirQuota(siaPath SiaPath) AlertID {
+	return AlertID(fmt.Sprintf("dir-quota:%v", siaPath))
+}
+
 type (
 	// Alerter is the interface implemented by all top-level modules. It's an
 	// interface that allows for asking a module about potential issues.
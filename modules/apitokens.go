@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+)
+
+// APIToken is a token which grants access to a subset of the API. Only the
+// hash of the token is persisted, the token itself is only revealed once when
+// it is created.
+type APIToken struct {
+	ID     string      `json:"id"`
+	Name   string      `json:"name"`
+	Scopes []string    `json:"scopes"`
+	Expiry time.Time   `json:"expiry"`
+	Hash   crypto.Hash `json:"hash"`
+}
+
+var (
+	// apiScopeWriteLevels maps the modules which API tokens can be scoped to,
+	// to the name of the scope which grants write access to the module. Every
+	// module also has a "read" scope which grants read access.
+	apiScopeWriteLevels = map[string]string{
+		"accounting": "write",
+		"consensus":  "write",
+		"daemon":     "admin",
+		"explorer":   "write",
+		"gateway":    "write",
+		"host":       "admin",
+		"miner":      "write",
+		"renter":     "write",
+		"tpool":      "write",
+		"wallet":     "spend",
+	}
+
+	// ErrAPITokenExpired is returned when an expired API token is used.
+	ErrAPITokenExpired = errors.New("API token expired")
+
+	// ErrInvalidAPIToken is returned when an unknown API token is used.
+	ErrInvalidAPIToken = errors.New("invalid API token")
+
+	// ErrUnknownAPIToken is returned when removing an API token which doesn't
+	// exist.
+	ErrUnknownAPIToken = errors.New("API token not found")
+)
+
+// APITokenScope returns the scope which grants read or write access to the
+// given module.
+func APITokenScope(module string, write bool) string {
+	level := "read"
+	if write {
+		level = apiScopeWriteLevels[module]
+	}
+	return module + ":" + level
+}
+
+// IsValidAPITokenScope returns whether the scope is a known API token scope.
+func IsValidAPITokenScope(scope string) bool {
+	module := strings.Split(scope, ":")[0]
+	if _, exists := apiScopeWriteLevels[module]; !exists {
+		return false
+	}
+	return scope == APITokenScope(module, false) || scope == APITokenScope(module, true)
+}
+
+// Expired returns whether the token is expired. Tokens with a zero expiry
+// never expire.
+func (t APIToken) Expired(now time.Time) bool {
+	return !t.Expiry.IsZero() && !now.Before(t.Expiry)
+}
+
+// HasScope returns whether the token grants the given scope. The write scope
+// of a module implies its read scope.
+func (t APIToken) HasScope(scope string) bool {
+	module := strings.Split(scope, ":")[0]
+	for _, s := range t.Scopes {
+		if s == scope || (s == APITokenScope(module, true) && scope == APITokenScope(module, false)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAPIToken creates a new API token with the given scopes and persists its
+// hash to disk. A zero expiry creates a token which never expires. The token is
+// returned together with its persisted metadata.
+func (cfg *SiadConfig) AddAPIToken(name string, scopes []string, expiry time.Time) (string, APIToken, error) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	// Input validation.
+	if len(scopes) == 0 {
+		return "", APIToken{}, errors.New("API token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !IsValidAPITokenScope(scope) {
+			return "", APIToken{}, errors.New("unknown API token scope " + scope)
+		}
+	}
+	if !expiry.IsZero() && !expiry.After(time.Now()) {
+		return "", APIToken{}, errors.New("API token expiry needs to be in the future")
+	}
+	// Create the token.
+	token := hex.EncodeToString(fastrand.Bytes(32))
+	t := APIToken{
+		ID:     hex.EncodeToString(fastrand.Bytes(8)),
+		Name:   name,
+		Scopes: append([]string(nil), scopes...),
+		Expiry: expiry,
+		Hash:   crypto.HashBytes([]byte(token)),
+	}
+	cfg.APITokens = append(cfg.APITokens, t)
+	if err := cfg.save(); err != nil {
+		cfg.APITokens = cfg.APITokens[:len(cfg.APITokens)-1]
+		return "", APIToken{}, err
+	}
+	return token, t, nil
+}
+
+// RemoveAPIToken removes the API token with the given id from the config and
+// persists it to disk.
+func (cfg *SiadConfig) RemoveAPIToken(id string) error {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	tokens := make([]APIToken, 0, len(cfg.APITokens))
+	for _, t := range cfg.APITokens {
+		if t.ID != id {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == len(cfg.APITokens) {
+		return ErrUnknownAPIToken
+	}
+	cfg.APITokens = tokens
+	return cfg.save()
+}
+
+// APITokenList returns a copy of the API tokens.
+func (cfg *SiadConfig) APITokenList() []APIToken {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	tokens := make([]APIToken, len(cfg.APITokens))
+	copy(tokens, cfg.APITokens)
+	return tokens
+}
+
+// APITokenLookup returns the metadata of the given API token. It returns an
+// error if the token doesn't exist or is expired.
+func (cfg *SiadConfig) APITokenLookup(token string) (APIToken, error) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	hash := crypto.HashBytes([]byte(token))
+	for _, t := range cfg.APITokens {
+		if t.Hash != hash {
+			continue
+		}
+		if t.Expired(time.Now()) {
+			return APIToken{}, ErrAPITokenExpired
+		}
+		return t, nil
+	}
+	return APIToken{}, ErrInvalidAPIToken
+}
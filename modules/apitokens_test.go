@@ -0,0 +1,112 @@
+package modules
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/persist"
+)
+
+// TestAPITokenScopes is a unit test for the API token scopes.
+func TestAPITokenScopes(t *testing.T) {
+	t.Parallel()
+
+	for _, scope := range []string{"renter:read", "renter:write", "wallet:spend", "host:admin", "daemon:read"} {
+		if !IsValidAPITokenScope(scope) {
+			t.Error("scope should be valid", scope)
+		}
+	}
+	for _, scope := range []string{"", "renter", "renter:spend", "wallet:write", "unknown:read"} {
+		if IsValidAPITokenScope(scope) {
+			t.Error("scope shouldn't be valid", scope)
+		}
+	}
+
+	token := APIToken{Scopes: []string{"renter:write", "wallet:read"}}
+	for _, scope := range []string{"renter:read", "renter:write", "wallet:read"} {
+		if !token.HasScope(scope) {
+			t.Error("token should have scope", scope)
+		}
+	}
+	for _, scope := range []string{"wallet:spend", "host:read"} {
+		if token.HasScope(scope) {
+			t.Error("token shouldn't have scope", scope)
+		}
+	}
+}
+
+// TestSiadConfigAPITokens tests creating, looking up and removing API tokens.
+func TestSiadConfigAPITokens(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testDir := build.TempDir("siadconfig", t.Name())
+	if err := os.MkdirAll(testDir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(testDir, ConfigName)
+	sc, err := NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid tokens are rejected.
+	if _, _, err := sc.AddAPIToken("none", nil, time.Time{}); err == nil {
+		t.Fatal("expected error for token without scopes")
+	}
+	if _, _, err := sc.AddAPIToken("unknown", []string{"renter:spend"}, time.Time{}); err == nil {
+		t.Fatal("expected error for unknown scope")
+	}
+	if _, _, err := sc.AddAPIToken("expired", []string{"renter:read"}, time.Now().Add(-time.Second)); err == nil {
+		t.Fatal("expected error for expiry in the past")
+	}
+
+	// Create a token.
+	token, apiToken, err := sc.AddAPIToken("uploader", []string{"renter:write"}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token is persisted but only its hash.
+	sc, err = NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := sc.APITokenList()
+	if len(tokens) != 1 || tokens[0].ID != apiToken.ID || tokens[0].Hash != apiToken.Hash {
+		t.Fatal("wrong tokens after reload", tokens)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), token) {
+		t.Fatal("token was persisted in plaintext")
+	}
+
+	// Look up the token.
+	if lookedUp, err := sc.APITokenLookup(token); err != nil || lookedUp.ID != apiToken.ID {
+		t.Fatal("failed to look up token", lookedUp, err)
+	}
+	if _, err := sc.APITokenLookup("invalid"); err != ErrInvalidAPIToken {
+		t.Fatal("expected ErrInvalidAPIToken but got", err)
+	}
+
+	// Remove the token.
+	if err := sc.RemoveAPIToken(apiToken.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.RemoveAPIToken(apiToken.ID); err != ErrUnknownAPIToken {
+		t.Fatal("expected ErrUnknownAPIToken but got", err)
+	}
+	if _, err := sc.APITokenLookup(token); err != ErrInvalidAPIToken {
+		t.Fatal("expected ErrInvalidAPIToken but got", err)
+	}
+}
@@ -0,0 +1,121 @@
+package modules
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// CompressionType is the type of compression which is applied to the data of
+// a file before it is erasure coded and encrypted.
+type CompressionType uint8
+
+const (
+	// CompressionNone indicates that the data of a file is not compressed.
+	CompressionNone CompressionType = iota
+
+	// CompressionGzip compresses the data of a file using gzip.
+	CompressionGzip
+
+	// CompressionDeflate compresses the data of a file using raw deflate. It
+	// produces slightly smaller output than gzip since it omits the gzip
+	// header and checksum.
+	CompressionDeflate
+)
+
+var (
+	// ErrUnknownCompressionType is returned when trying to use an unknown
+	// compression type.
+	ErrUnknownCompressionType = errors.New("unknown compression type")
+)
+
+// String creates a string representation of a CompressionType that can be
+// converted into a type with FromString.
+func (ct CompressionType) String() string {
+	switch ct {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionDeflate:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+// FromString reads a CompressionType from a string.
+func (ct *CompressionType) FromString(s string) error {
+	switch s {
+	case "none":
+		*ct = CompressionNone
+	case "gzip":
+		*ct = CompressionGzip
+	case "deflate":
+		*ct = CompressionDeflate
+	default:
+		return ErrUnknownCompressionType
+	}
+	return nil
+}
+
+// Compress compresses the provided data. Every call produces an independent
+// frame which can be decompressed without access to any other frame.
+func (ct CompressionType) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch ct {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionDeflate:
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		w = fw
+	default:
+		return nil, ErrUnknownCompressionType
+	}
+	_, err := w.Write(data)
+	err = errors.Compose(err, w.Close())
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to compress data")
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses a frame which was created by Compress. maxSize is
+// the maximum size of the decompressed data and protects against frames which
+// decompress to an unexpected amount of data.
+func (ct CompressionType) Decompress(frame []byte, maxSize uint64) ([]byte, error) {
+	var r io.ReadCloser
+	switch ct {
+	case CompressionNone:
+		return frame, nil
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(frame))
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to decompress data")
+		}
+		r = gr
+	case CompressionDeflate:
+		r = flate.NewReader(bytes.NewReader(frame))
+	default:
+		return nil, ErrUnknownCompressionType
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	err = errors.Compose(err, r.Close())
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to decompress data")
+	}
+	if uint64(len(data)) > maxSize {
+		return nil, errors.New("decompressed frame exceeds the maximum size")
+	}
+	return data, nil
+}
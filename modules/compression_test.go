@@ -0,0 +1,53 @@
+package modules
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+)
+
+// TestCompressionType tests compressing and decompressing frames with the
+// supported compression types.
+func TestCompressionType(t *testing.T) {
+	// Compressible data with some randomness.
+	data := bytes.Repeat(fastrand.Bytes(64), 100)
+
+	for _, ct := range []CompressionType{CompressionNone, CompressionGzip, CompressionDeflate} {
+		// The string representation should round-trip.
+		var parsed CompressionType
+		if err := parsed.FromString(ct.String()); err != nil || parsed != ct {
+			t.Fatal("failed to parse compression type", ct)
+		}
+
+		frame, err := ct.Compress(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct != CompressionNone && len(frame) >= len(data) {
+			t.Fatalf("%v: data wasn't compressed %v >= %v", ct, len(frame), len(data))
+		}
+		decompressed, err := ct.Decompress(frame, uint64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Fatal("decompressed data doesn't match", ct)
+		}
+		// Frames which decompress to more than the max size should be
+		// rejected.
+		if _, err := ct.Decompress(frame, uint64(len(data)-1)); ct != CompressionNone && err == nil {
+			t.Fatal("expected error for oversized frame", ct)
+		}
+	}
+
+	// Unknown types should be rejected.
+	var ct CompressionType
+	if err := ct.FromString("zip"); !errors.Contains(err, ErrUnknownCompressionType) {
+		t.Fatal("expected ErrUnknownCompressionType", err)
+	}
+	if _, err := CompressionType(42).Compress(data); !errors.Contains(err, ErrUnknownCompressionType) {
+		t.Fatal("expected ErrUnknownCompressionType", err)
+	}
+}
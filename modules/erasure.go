@@ -63,6 +63,11 @@ type (
 		// recover the original data.
 		MinPieces() int
 
+		// DataPieces is the number of pieces the original data is split into
+		// by Encode. For codes which can recover the data from any DataPieces
+		// pieces it is equal to MinPieces.
+		DataPieces() int
+
 		// Encode splits data into equal-length pieces, with some pieces
 		// containing parity data.
 		Encode(data []byte) ([][]byte, error)
@@ -179,6 +184,9 @@ func (rs *RSCode) NumPieces() int { return rs.numPieces }
 // recover the original data.
 func (rs *RSCode) MinPieces() int { return rs.dataPieces }
 
+// DataPieces returns the number of pieces the original data is split into.
+func (rs *RSCode) DataPieces() int { return rs.dataPieces }
+
 // Encode splits data into equal-length pieces, some containing the original
 // data and some containing parity data.
 func (rs *RSCode) Encode(data []byte) ([][]byte, error) {
@@ -477,6 +485,12 @@ func (pec *PassthroughErasureCoder) MinPieces() int {
 	return 1
 }
 
+// DataPieces is the number of pieces the original data is split into. For the
+// passthrough this is hardcoded to 1.
+func (pec *PassthroughErasureCoder) DataPieces() int {
+	return 1
+}
+
 // Encode splits data into equal-length pieces, with some pieces containing
 // parity data. For the passthrough this is a no-op.
 func (pec *PassthroughErasureCoder) Encode(data []byte) ([][]byte, error) {
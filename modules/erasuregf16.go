@@ -0,0 +1,476 @@
+package modules
+
+// erasuregf16.go implements a Reed-Solomon erasure coder over GF(2^16). The
+// coders in erasure.go operate on GF(2^8) which limits them to 256 pieces in
+// total. Operating on 2-byte symbols raises that limit to 65536 pieces which
+// allows for very wide codes like 64-of-500.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.sia.tech/siad/crypto"
+)
+
+const (
+	// RSGF16MaxPieces is the maximum number of pieces supported by the
+	// GF(2^16) Reed-Solomon coder.
+	RSGF16MaxPieces = 1 << 16
+
+	// RSGF8MaxPieces is the maximum number of pieces supported by the GF(2^8)
+	// Reed-Solomon coders.
+	RSGF8MaxPieces = 1 << 8
+
+	// gf16Polynomial is the primitive polynomial x^16 + x^12 + x^3 + x + 1
+	// which is used to construct GF(2^16).
+	gf16Polynomial = 0x1100B
+
+	// gf16Order is the number of non-zero elements in GF(2^16).
+	gf16Order = RSGF16MaxPieces - 1
+)
+
+var (
+	// ECReedSolomonGF16 is the marshaled type of the reed solomon coder over
+	// GF(2^16) for files where every 64 bytes of an encoded piece can be
+	// decoded separately.
+	ECReedSolomonGF16 = ErasureCoderType{0, 0, 0, 4}
+)
+
+var (
+	// gf16Exp and gf16Log are the exponent and logarithm tables of GF(2^16).
+	// gf16Exp is twice the size of the field's order to avoid a modulo when
+	// adding logarithms.
+	gf16Exp     [2 * gf16Order]uint16
+	gf16Log     [RSGF16MaxPieces]uint16
+	gf16InitMu  sync.Once
+	errGF16Size = errors.New("pieceSize not divisible by segmentSize")
+)
+
+type (
+	// RSGF16Code is a Reed-Solomon encoder/decoder over GF(2^16). It
+	// implements the ErasureCoder interface. Like the RSSubCode, every
+	// crypto.SegmentSize bytes of encoded data can be recovered separately.
+	RSGF16Code struct {
+		// parityMatrix is the Cauchy matrix which is used to compute the
+		// parity pieces. parityMatrix[i][j] is the coefficient of data piece
+		// j for parity piece i.
+		parityMatrix [][]uint16
+
+		numPieces         int
+		dataPieces        int
+		staticSegmentSize uint64
+	}
+)
+
+// NewRSGF16Code creates a new Reed-Solomon encoder/decoder over GF(2^16) using
+// the supplied parameters.
+func NewRSGF16Code(nData, nParity int) (ErasureCoder, error) {
+	if nData <= 0 || nParity <= 0 {
+		return nil, fmt.Errorf("invalid number of data and parity pieces %v+%v", nData, nParity)
+	}
+	if nData+nParity > RSGF16MaxPieces {
+		return nil, fmt.Errorf("too many pieces %v > %v", nData+nParity, RSGF16MaxPieces)
+	}
+	gf16InitMu.Do(gf16Init)
+
+	// Build the Cauchy matrix. Every square submatrix of a Cauchy matrix is
+	// invertible which guarantees that any nData pieces can recover the data.
+	// The parity pieces use the elements [0, nParity) and the data pieces the
+	// elements [nParity, nParity+nData).
+	parityMatrix := make([][]uint16, nParity)
+	for i := range parityMatrix {
+		parityMatrix[i] = make([]uint16, nData)
+		for j := range parityMatrix[i] {
+			parityMatrix[i][j] = gf16Inv(uint16(i) ^ uint16(nParity+j))
+		}
+	}
+	return &RSGF16Code{
+		parityMatrix:      parityMatrix,
+		numPieces:         nData + nParity,
+		dataPieces:        nData,
+		staticSegmentSize: crypto.SegmentSize,
+	}, nil
+}
+
+// NewPartialEncodingRSCode creates a Reed-Solomon encoder/decoder which
+// supports partial encoding. Codes which fit into GF(2^8) use the RSSubCode
+// while wider codes use the RSGF16Code.
+func NewPartialEncodingRSCode(nData, nParity int) (ErasureCoder, error) {
+	if nData+nParity > RSGF8MaxPieces {
+		return NewRSGF16Code(nData, nParity)
+	}
+	return NewRSSubCode(nData, nParity, crypto.SegmentSize)
+}
+
+// NumPieces returns the number of pieces returned by Encode.
+func (rs *RSGF16Code) NumPieces() int { return rs.numPieces }
+
+// MinPieces return the minimum number of pieces that must be present to
+// recover the original data.
+func (rs *RSGF16Code) MinPieces() int { return rs.dataPieces }
+
+// DataPieces returns the number of pieces the original data is split into.
+func (rs *RSGF16Code) DataPieces() int { return rs.dataPieces }
+
+// Encode splits data into equal-length pieces, some containing the original
+// data and some containing parity data. The pieces are padded to a multiple of
+// the segment size.
+func (rs *RSGF16Code) Encode(data []byte) ([][]byte, error) {
+	pieces, err := splitDataPieces(data, rs.dataPieces, rs.staticSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return rs.EncodeShards(pieces)
+}
+
+// EncodeShards encodes data in a way that every segmentSize bytes of the
+// encoded data can be decoded independently.
+func (rs *RSGF16Code) EncodeShards(pieces [][]byte) ([][]byte, error) {
+	// Check that there are enough pieces.
+	if len(pieces) != rs.MinPieces() {
+		return nil, fmt.Errorf("not enough segments expected %v but was %v",
+			rs.MinPieces(), len(pieces))
+	}
+	encoded, err := stripeDataPieces(pieces, rs.NumPieces(), rs.staticSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	// Compute the parity pieces.
+	for i, row := range rs.parityMatrix {
+		for j, c := range row {
+			gf16MulSliceXor(c, encoded[j], encoded[rs.dataPieces+i])
+		}
+	}
+	return encoded, nil
+}
+
+// Identifier returns an identifier for an erasure coder which can be used to
+// identify erasure coders of the same type, dataPieces and parityPieces.
+func (rs *RSGF16Code) Identifier() ErasureCoderIdentifier {
+	t := rs.Type()
+	dataPieces := rs.MinPieces()
+	parityPieces := rs.NumPieces() - dataPieces
+	id := fmt.Sprintf("%v+%v+%v", binary.BigEndian.Uint32(t[:]), dataPieces, parityPieces)
+	return ErasureCoderIdentifier(id)
+}
+
+// Reconstruct recovers the full set of encoded shards from the provided
+// pieces, of which at least MinPieces must be non-nil.
+func (rs *RSGF16Code) Reconstruct(pieces [][]byte) error {
+	if err := rs.reconstructData(pieces); err != nil {
+		return err
+	}
+	pieceSize := len(pieces[0])
+	for i, row := range rs.parityMatrix {
+		if len(pieces[rs.dataPieces+i]) != 0 {
+			continue
+		}
+		parity := make([]byte, pieceSize)
+		for j, c := range row {
+			gf16MulSliceXor(c, pieces[j], parity)
+		}
+		pieces[rs.dataPieces+i] = parity
+	}
+	return nil
+}
+
+// Recover accepts encoded pieces and decodes the data. The pieces can either
+// be full pieces or segments extracted using ExtractSegment.
+func (rs *RSGF16Code) Recover(pieces [][]byte, n uint64, w io.Writer) error {
+	// Copy the pieces to avoid modifying the caller's slice.
+	cpy := make([][]byte, len(pieces))
+	copy(cpy, pieces)
+	if err := rs.reconstructData(cpy); err != nil {
+		return err
+	}
+	return writeStripedData(cpy[:rs.dataPieces], rs.staticSegmentSize, n, w)
+}
+
+// SupportsPartialEncoding returns true for the GF(2^16) reed-solomon encoder
+// and returns the segment size.
+func (rs *RSGF16Code) SupportsPartialEncoding() (uint64, bool) {
+	return rs.staticSegmentSize, true
+}
+
+// Type returns the erasure coders type identifier.
+func (rs *RSGF16Code) Type() ErasureCoderType {
+	return ECReedSolomonGF16
+}
+
+// reconstructData recovers the missing data pieces from the provided pieces.
+// The parity pieces are left untouched.
+func (rs *RSGF16Code) reconstructData(pieces [][]byte) error {
+	// Check the length of pieces.
+	if len(pieces) != rs.NumPieces() {
+		return fmt.Errorf("expected pieces to have len %v but was %v",
+			rs.NumPieces(), len(pieces))
+	}
+	pieceSize, err := stripedPieceSize(pieces, rs.staticSegmentSize)
+	if err != nil {
+		return err
+	}
+	// Collect the missing data pieces and the available parity pieces.
+	var missing, parity []int
+	for i, piece := range pieces {
+		if len(piece) == 0 && i < rs.dataPieces {
+			missing = append(missing, i)
+		} else if len(piece) != 0 && len(piece) != pieceSize {
+			return fmt.Errorf("pieces don't have right size expected %v but was %v",
+				pieceSize, len(piece))
+		} else if len(piece) != 0 && i >= rs.dataPieces && len(parity) < rs.dataPieces {
+			parity = append(parity, i-rs.dataPieces)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if len(parity) < len(missing) {
+		return fmt.Errorf("not enough pieces to recover data, need %v but have %v",
+			rs.dataPieces, rs.dataPieces-len(missing)+len(parity))
+	}
+	parity = parity[:len(missing)]
+
+	// Subtract the contribution of the available data pieces from the parity
+	// pieces. What remains is a linear combination of the missing data
+	// pieces only.
+	isMissing := make([]bool, rs.dataPieces)
+	for _, j := range missing {
+		isMissing[j] = true
+	}
+	syndromes := make([][]byte, len(parity))
+	for k, p := range parity {
+		syndromes[k] = make([]byte, pieceSize)
+		copy(syndromes[k], pieces[rs.dataPieces+p])
+		for j, c := range rs.parityMatrix[p] {
+			if !isMissing[j] {
+				gf16MulSliceXor(c, pieces[j], syndromes[k])
+			}
+		}
+	}
+	// Invert the square submatrix of the Cauchy matrix which maps the missing
+	// data pieces to the syndromes.
+	m := make([][]uint16, len(parity))
+	for k, p := range parity {
+		m[k] = make([]uint16, len(missing))
+		for l, j := range missing {
+			m[k][l] = rs.parityMatrix[p][j]
+		}
+	}
+	inv, err := gf16InvertMatrix(m)
+	if err != nil {
+		return err
+	}
+	// Compute the missing data pieces.
+	for l, j := range missing {
+		piece := make([]byte, pieceSize)
+		for k := range syndromes {
+			gf16MulSliceXor(inv[l][k], syndromes[k], piece)
+		}
+		pieces[j] = piece
+	}
+	return nil
+}
+
+// splitDataPieces splits data into dataPieces equal-length pieces. The pieces
+// are padded to a multiple of segmentSize.
+func splitDataPieces(data []byte, dataPieces int, segmentSize uint64) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("not enough data to encode")
+	}
+	// Compute the pieceSize by rounding up to the next multiple of the segment
+	// size.
+	pieceSize := (uint64(len(data)) + uint64(dataPieces) - 1) / uint64(dataPieces)
+	if mod := pieceSize % segmentSize; mod != 0 {
+		pieceSize += segmentSize - mod
+	}
+	pieces := make([][]byte, dataPieces)
+	for i := range pieces {
+		pieces[i] = make([]byte, pieceSize)
+		off := uint64(i) * pieceSize
+		if off < uint64(len(data)) {
+			copy(pieces[i], data[off:])
+		}
+	}
+	return pieces, nil
+}
+
+// stripeDataPieces distributes the data of the provided data pieces across
+// numPieces new pieces one segment at a time. That way the data of every
+// segment index across all pieces is contiguous. The data pieces are followed
+// by zeroed pieces for the parity data.
+func stripeDataPieces(pieces [][]byte, numPieces int, segmentSize uint64) ([][]byte, error) {
+	// Since all the pieces should have the same length, get the pieceSize from
+	// the first one.
+	pieceSize := uint64(len(pieces[0]))
+	// pieceSize must be divisible by segmentSize
+	if pieceSize%segmentSize != 0 {
+		return nil, errGF16Size
+	}
+	// Each piece should have pieceSize bytes.
+	for _, piece := range pieces {
+		if uint64(len(piece)) != pieceSize {
+			return nil, fmt.Errorf("pieces don't have right size expected %v but was %v",
+				pieceSize, len(piece))
+		}
+	}
+	// Flatten the pieces into a byte slice.
+	data := make([]byte, uint64(len(pieces))*pieceSize)
+	for i, piece := range pieces {
+		copy(data[uint64(i)*pieceSize:], piece)
+	}
+	// Distribute the data across the pieces.
+	encoded := make([][]byte, numPieces)
+	for i := range encoded {
+		encoded[i] = make([]byte, pieceSize)
+	}
+	stripeSize := segmentSize * uint64(len(pieces))
+	for off := uint64(0); off < uint64(len(data)); off += stripeSize {
+		segmentOff := off / uint64(len(pieces))
+		for i := range pieces {
+			segment := data[off+uint64(i)*segmentSize:][:segmentSize]
+			copy(encoded[i][segmentOff:], segment)
+		}
+	}
+	return encoded, nil
+}
+
+// stripedPieceSize returns the size of the pieces which are set and makes
+// sure it is a multiple of segmentSize.
+func stripedPieceSize(pieces [][]byte, segmentSize uint64) (int, error) {
+	// Since all the pieces should have the same length, get the pieceSize from
+	// the first piece that was set.
+	var pieceSize int
+	for _, piece := range pieces {
+		if len(piece) > 0 {
+			pieceSize = len(piece)
+			break
+		}
+	}
+	// pieceSize must be divisible by segmentSize
+	if uint64(pieceSize)%segmentSize != 0 {
+		return 0, errGF16Size
+	}
+	return pieceSize, nil
+}
+
+// writeStripedData writes n bytes of the data which was distributed across
+// the data pieces by stripeDataPieces to w.
+func writeStripedData(dataPieces [][]byte, segmentSize, n uint64, w io.Writer) error {
+	pieceSize := uint64(len(dataPieces[0]))
+	for off := uint64(0); off < pieceSize && n > 0; off += segmentSize {
+		for _, piece := range dataPieces {
+			if n == 0 {
+				break
+			}
+			segment := piece[off:][:segmentSize]
+			if n < uint64(len(segment)) {
+				segment = segment[:n]
+			}
+			if _, err := w.Write(segment); err != nil {
+				return err
+			}
+			n -= uint64(len(segment))
+		}
+	}
+	return nil
+}
+
+// gf16Init initializes the exponent and logarithm tables of GF(2^16).
+func gf16Init() {
+	x := 1
+	for i := 0; i < gf16Order; i++ {
+		gf16Exp[i] = uint16(x)
+		gf16Exp[i+gf16Order] = uint16(x)
+		gf16Log[x] = uint16(i)
+		x <<= 1
+		if x&RSGF16MaxPieces != 0 {
+			x ^= gf16Polynomial
+		}
+	}
+}
+
+// gf16Mul multiplies two elements of GF(2^16).
+func gf16Mul(a, b uint16) uint16 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf16Exp[int(gf16Log[a])+int(gf16Log[b])]
+}
+
+// gf16Inv returns the multiplicative inverse of a non-zero element of
+// GF(2^16).
+func gf16Inv(a uint16) uint16 {
+	return gf16Exp[gf16Order-int(gf16Log[a])]
+}
+
+// gf16MulSliceXor multiplies every 2-byte little-endian symbol of in with c
+// and adds the result to the corresponding symbol of out.
+func gf16MulSliceXor(c uint16, in, out []byte) {
+	if c == 0 {
+		return
+	}
+	// Precompute the products of c with all values of the low and high byte
+	// of a symbol. Since multiplication distributes over addition, the
+	// product of a symbol is the sum of the two.
+	var lo, hi [256]uint16
+	for b := 1; b < 256; b++ {
+		lo[b] = gf16Mul(c, uint16(b))
+		hi[b] = gf16Mul(c, uint16(b)<<8)
+	}
+	for i := 0; i+1 < len(in); i += 2 {
+		p := lo[in[i]] ^ hi[in[i+1]]
+		out[i] ^= byte(p)
+		out[i+1] ^= byte(p >> 8)
+	}
+}
+
+// gf16InvertMatrix inverts a square matrix over GF(2^16) using Gauss-Jordan
+// elimination.
+func gf16InvertMatrix(m [][]uint16) ([][]uint16, error) {
+	n := len(m)
+	// Create the augmented matrix [m | I].
+	a := make([][]uint16, n)
+	for i := range a {
+		a[i] = make([]uint16, 2*n)
+		copy(a[i], m[i])
+		a[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		// Find a pivot.
+		pivot := -1
+		for row := col; row < n; row++ {
+			if a[row][col] != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot == -1 {
+			return nil, errors.New("matrix is singular")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		// Normalize the pivot row.
+		if inv := gf16Inv(a[col][col]); inv != 1 {
+			for k := range a[col] {
+				a[col][k] = gf16Mul(a[col][k], inv)
+			}
+		}
+		// Eliminate the column from all other rows.
+		for row := 0; row < n; row++ {
+			if row == col || a[row][col] == 0 {
+				continue
+			}
+			f := a[row][col]
+			for k := range a[row] {
+				a[row][k] ^= gf16Mul(f, a[col][k])
+			}
+		}
+	}
+	inv := make([][]uint16, n)
+	for i := range inv {
+		inv[i] = a[i][n:]
+	}
+	return inv, nil
+}
@@ -0,0 +1,174 @@
+package modules
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+	"go.sia.tech/siad/crypto"
+)
+
+// TestGF16Field verifies that the polynomial used to construct GF(2^16) is
+// primitive and that multiplication and inversion are consistent.
+func TestGF16Field(t *testing.T) {
+	gf16InitMu.Do(gf16Init)
+
+	// A primitive polynomial generates every non-zero element exactly once.
+	seen := make([]bool, RSGF16MaxPieces)
+	for i := 0; i < gf16Order; i++ {
+		e := gf16Exp[i]
+		if e == 0 || seen[e] {
+			t.Fatalf("element %v generated twice or zero at power %v", e, i)
+		}
+		seen[e] = true
+	}
+	// Check some random inverses.
+	for i := 0; i < 1000; i++ {
+		a := uint16(fastrand.Intn(gf16Order) + 1)
+		if gf16Mul(a, gf16Inv(a)) != 1 {
+			t.Fatalf("a * a^-1 != 1 for a = %v", a)
+		}
+	}
+}
+
+// TestRSGF16Code tests the RSGF16Code EC.
+func TestRSGF16Code(t *testing.T) {
+	badParams := []struct {
+		data, parity int
+	}{
+		{-1, -1},
+		{0, 0},
+		{0, 1},
+		{1, 0},
+		{RSGF16MaxPieces, 1},
+	}
+	for _, ps := range badParams {
+		if _, err := NewRSGF16Code(ps.data, ps.parity); err == nil {
+			t.Error("expected bad parameter error, got nil")
+		}
+	}
+
+	// Use a code which is too wide for the GF(2^8) coders.
+	dataPieces := 64
+	parityPieces := 436
+	segmentSize := int(crypto.SegmentSize)
+	pieceSize := 4 * segmentSize
+	rsc, err := NewRSGF16Code(dataPieces, parityPieces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsc.Type() != ECReedSolomonGF16 {
+		t.Fatal("wrong type")
+	}
+	if rsc.Identifier() != "4+64+436" {
+		t.Fatal("wrong identifier", rsc.Identifier())
+	}
+	if size, supported := rsc.SupportsPartialEncoding(); !supported || size != crypto.SegmentSize {
+		t.Fatal("partial encoding should be supported")
+	}
+
+	data := fastrand.Bytes(pieceSize * dataPieces)
+	encodedPieces, err := rsc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encodedPieces) != rsc.NumPieces() {
+		t.Fatalf("encodedPieces should've length %v but was %v", rsc.NumPieces(), len(encodedPieces))
+	}
+	for _, piece := range encodedPieces {
+		if len(piece) != pieceSize {
+			t.Fatalf("expected len(piece) to be %v but was %v", pieceSize, len(piece))
+		}
+	}
+	// Delete as many random pieces as possible.
+	for _, i := range fastrand.Perm(len(encodedPieces))[:parityPieces] {
+		encodedPieces[i] = nil
+	}
+	// Recover every segment individually.
+	dataOffset := 0
+	decodedSegmentSize := segmentSize * dataPieces
+	for segmentIndex := 0; segmentIndex < pieceSize/segmentSize; segmentIndex++ {
+		buf := new(bytes.Buffer)
+		segment := ExtractSegment(encodedPieces, segmentIndex, uint64(segmentSize))
+		err = rsc.Recover(segment, uint64(decodedSegmentSize), buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data[dataOffset:dataOffset+decodedSegmentSize]) {
+			t.Fatal("decoded bytes don't equal original segment")
+		}
+		dataOffset += decodedSegmentSize
+	}
+	// Recover all segments at once.
+	buf := new(bytes.Buffer)
+	err = rsc.Recover(encodedPieces, uint64(len(data)), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("decoded bytes don't equal original data")
+	}
+	// Recovering with one piece less should fail.
+	for i := range encodedPieces {
+		if encodedPieces[i] != nil {
+			encodedPieces[i] = nil
+			break
+		}
+	}
+	if err := rsc.Recover(encodedPieces, uint64(len(data)), new(bytes.Buffer)); err == nil {
+		t.Fatal("expected recovery to fail")
+	}
+}
+
+// TestRSGF16CodeReconstruct tests reconstructing all pieces with the
+// RSGF16Code EC.
+func TestRSGF16CodeReconstruct(t *testing.T) {
+	rsc, err := NewRSGF16Code(10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(777)
+	pieces, err := rsc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := make([][]byte, len(pieces))
+	copy(original, pieces)
+	for _, i := range fastrand.Perm(len(pieces))[:20] {
+		pieces[i] = nil
+	}
+	if err := rsc.Reconstruct(pieces); err != nil {
+		t.Fatal(err)
+	}
+	for i := range pieces {
+		if !bytes.Equal(pieces[i], original[i]) {
+			t.Fatalf("piece %v wasn't reconstructed correctly", i)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := rsc.Recover(pieces, uint64(len(data)), buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("decoded bytes don't equal original data")
+	}
+}
+
+// TestNewPartialEncodingRSCode checks that the right coder is picked for the
+// number of pieces.
+func TestNewPartialEncodingRSCode(t *testing.T) {
+	ec, err := NewPartialEncodingRSCode(10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.Type() != ECReedSolomonSubShards64 {
+		t.Fatal("expected RSSubCode", ec.Type())
+	}
+	ec, err = NewPartialEncodingRSCode(64, 436)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.Type() != ECReedSolomonGF16 {
+		t.Fatal("expected RSGF16Code", ec.Type())
+	}
+}
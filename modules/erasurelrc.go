@@ -0,0 +1,436 @@
+package modules
+
+// erasurelrc.go implements a locally repairable erasure code. The data pieces
+// are split into local groups which each have their own parity piece. On top
+// of that there are global parity pieces which are computed over all data
+// pieces. A single missing piece within a group can be repaired by fetching
+// the other pieces of the group instead of MinPieces pieces.
+//
+// The code is constructed as a pyramid code on top of a Reed-Solomon code over
+// GF(2^16) with globalParity+1 parity pieces. The first parity piece of that
+// code is split up into the local parity pieces which means that the local
+// parity pieces of all groups sum up to it. Therefore the code can tolerate
+// the loss of any globalParity+1 pieces.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.sia.tech/siad/crypto"
+)
+
+var (
+	// ECLocallyRepairable is the marshaled type of the locally repairable
+	// code. Every 64 bytes of an encoded piece can be decoded separately.
+	ECLocallyRepairable = ErasureCoderType{0, 0, 0, 5}
+)
+
+var (
+	// errNotLocallyRepairable is returned if a piece can't be repaired using
+	// its local group.
+	errNotLocallyRepairable = errors.New("piece is not locally repairable")
+)
+
+type (
+	// LocallyRepairableCoder is an ErasureCoder which is able to repair a
+	// single missing piece from a small group of other pieces.
+	LocallyRepairableCoder interface {
+		ErasureCoder
+
+		// LocalRepairGroup returns the indices of the pieces which are
+		// required to repair the piece at pieceIndex. If the piece can't be
+		// repaired locally, nil is returned.
+		LocalRepairGroup(pieceIndex int) []int
+
+		// RepairLocal repairs the piece at pieceIndex using the pieces of its
+		// local repair group which need to be set.
+		RepairLocal(pieces [][]byte, pieceIndex int) error
+	}
+
+	// LRCode is a locally repairable encoder/decoder. It implements the
+	// LocallyRepairableCoder interface. Like the RSSubCode, every
+	// crypto.SegmentSize bytes of encoded data can be recovered separately.
+	//
+	// The pieces are ordered as follows: data pieces, local parity pieces,
+	// global parity pieces.
+	LRCode struct {
+		// parityMatrix contains the coefficients of the data pieces for every
+		// parity piece, starting with the local parity pieces.
+		parityMatrix [][]uint16
+
+		// groups contains the indices of the data pieces of every local
+		// group.
+		groups [][]int
+
+		dataPieces        int
+		localGroups       int
+		globalParity      int
+		staticSegmentSize uint64
+	}
+)
+
+// NewLRCode creates a new locally repairable encoder/decoder with nData data
+// pieces split into nLocalGroups groups and nGlobalParity global parity
+// pieces.
+func NewLRCode(nData, nLocalGroups, nGlobalParity int) (ErasureCoder, error) {
+	if nData <= 0 || nLocalGroups <= 0 || nGlobalParity < 0 {
+		return nil, fmt.Errorf("invalid lrc parameters %v+%v+%v", nData, nLocalGroups, nGlobalParity)
+	}
+	if nLocalGroups > nData {
+		return nil, fmt.Errorf("number of local groups %v exceeds number of data pieces %v", nLocalGroups, nData)
+	}
+	if nData+nLocalGroups+nGlobalParity > RSGF16MaxPieces {
+		return nil, fmt.Errorf("too many pieces %v > %v", nData+nLocalGroups+nGlobalParity, RSGF16MaxPieces)
+	}
+	gf16InitMu.Do(gf16Init)
+
+	// Assign the data pieces to groups of roughly equal size.
+	groups := make([][]int, nLocalGroups)
+	for j := 0; j < nData; j++ {
+		g := j * nLocalGroups / nData
+		groups[g] = append(groups[g], j)
+	}
+	// Build the Cauchy matrix of the underlying Reed-Solomon code with
+	// nGlobalParity+1 parity pieces.
+	cauchy := func(row, col int) uint16 {
+		return gf16Inv(uint16(row) ^ uint16(nGlobalParity+1+col))
+	}
+	parityMatrix := make([][]uint16, nLocalGroups+nGlobalParity)
+	for i, group := range groups {
+		parityMatrix[i] = make([]uint16, nData)
+		for _, j := range group {
+			parityMatrix[i][j] = cauchy(0, j)
+		}
+	}
+	for i := 0; i < nGlobalParity; i++ {
+		row := make([]uint16, nData)
+		for j := range row {
+			row[j] = cauchy(i+1, j)
+		}
+		parityMatrix[nLocalGroups+i] = row
+	}
+	return &LRCode{
+		parityMatrix:      parityMatrix,
+		groups:            groups,
+		dataPieces:        nData,
+		localGroups:       nLocalGroups,
+		globalParity:      nGlobalParity,
+		staticSegmentSize: crypto.SegmentSize,
+	}, nil
+}
+
+// NumPieces returns the number of pieces returned by Encode.
+func (lrc *LRCode) NumPieces() int { return lrc.dataPieces + lrc.localGroups + lrc.globalParity }
+
+// MinPieces return the minimum number of pieces that must be present to
+// recover the original data. Unlike for a Reed-Solomon code, not every set of
+// DataPieces pieces is sufficient. E.g. losing a data piece and the local
+// parity piece of its group leaves only the global parity pieces to recover
+// it. Only the loss of up to globalParity+1 pieces is tolerated in every
+// case.
+func (lrc *LRCode) MinPieces() int { return lrc.NumPieces() - lrc.globalParity - 1 }
+
+// DataPieces returns the number of pieces the original data is split into.
+func (lrc *LRCode) DataPieces() int { return lrc.dataPieces }
+
+// LocalGroups returns the number of local groups of the code.
+func (lrc *LRCode) LocalGroups() int { return lrc.localGroups }
+
+// Encode splits data into equal-length pieces, some containing the original
+// data and some containing parity data. The pieces are padded to a multiple of
+// the segment size.
+func (lrc *LRCode) Encode(data []byte) ([][]byte, error) {
+	pieces, err := splitDataPieces(data, lrc.dataPieces, lrc.staticSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return lrc.EncodeShards(pieces)
+}
+
+// EncodeShards encodes data in a way that every segmentSize bytes of the
+// encoded data can be decoded independently.
+func (lrc *LRCode) EncodeShards(pieces [][]byte) ([][]byte, error) {
+	// Check that there are enough pieces.
+	if len(pieces) != lrc.dataPieces {
+		return nil, fmt.Errorf("not enough segments expected %v but was %v",
+			lrc.dataPieces, len(pieces))
+	}
+	encoded, err := stripeDataPieces(pieces, lrc.NumPieces(), lrc.staticSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lrc.parityMatrix {
+		lrc.computeParity(encoded, i, encoded[lrc.dataPieces+i])
+	}
+	return encoded, nil
+}
+
+// Identifier returns an identifier for an erasure coder which can be used to
+// identify erasure coders of the same type, dataPieces, localGroups and
+// globalParity.
+func (lrc *LRCode) Identifier() ErasureCoderIdentifier {
+	t := lrc.Type()
+	id := fmt.Sprintf("%v+%v+%v+%v", binary.BigEndian.Uint32(t[:]), lrc.dataPieces, lrc.localGroups, lrc.globalParity)
+	return ErasureCoderIdentifier(id)
+}
+
+// Reconstruct recovers the full set of encoded shards from the provided
+// pieces, of which at least MinPieces must be non-nil. Pieces which can be
+// repaired locally are repaired first.
+func (lrc *LRCode) Reconstruct(pieces [][]byte) error {
+	// Check the length of pieces.
+	if len(pieces) != lrc.NumPieces() {
+		return fmt.Errorf("expected pieces to have len %v but was %v",
+			lrc.NumPieces(), len(pieces))
+	}
+	// Repair all pieces that are the only missing piece in their group.
+	for i := range lrc.groups {
+		missing := -1
+		for _, j := range lrc.groupMembers(i) {
+			if len(pieces[j]) != 0 {
+				continue
+			} else if missing != -1 {
+				missing = -1
+				break
+			}
+			missing = j
+		}
+		if missing == -1 {
+			continue
+		}
+		if err := lrc.RepairLocal(pieces, missing); err != nil {
+			return err
+		}
+	}
+	// Recover the remaining data pieces.
+	if err := lrc.reconstructData(pieces); err != nil {
+		return err
+	}
+	// Recompute the missing parity pieces.
+	pieceSize := len(pieces[0])
+	for i := range lrc.parityMatrix {
+		if len(pieces[lrc.dataPieces+i]) != 0 {
+			continue
+		}
+		parity := make([]byte, pieceSize)
+		lrc.computeParity(pieces, i, parity)
+		pieces[lrc.dataPieces+i] = parity
+	}
+	return nil
+}
+
+// Recover accepts encoded pieces and decodes the data. The pieces can either
+// be full pieces or segments extracted using ExtractSegment.
+func (lrc *LRCode) Recover(pieces [][]byte, n uint64, w io.Writer) error {
+	// Copy the pieces to avoid modifying the caller's slice.
+	cpy := make([][]byte, len(pieces))
+	copy(cpy, pieces)
+	if err := lrc.reconstructData(cpy); err != nil {
+		return err
+	}
+	return writeStripedData(cpy[:lrc.dataPieces], lrc.staticSegmentSize, n, w)
+}
+
+// SupportsPartialEncoding returns true for the locally repairable code and
+// returns the segment size.
+func (lrc *LRCode) SupportsPartialEncoding() (uint64, bool) {
+	return lrc.staticSegmentSize, true
+}
+
+// Type returns the erasure coders type identifier.
+func (lrc *LRCode) Type() ErasureCoderType {
+	return ECLocallyRepairable
+}
+
+// LocalRepairGroup returns the indices of the pieces which are required to
+// repair the piece at pieceIndex. Data pieces and local parity pieces are
+// repaired from the other pieces of their group. Global parity pieces can't be
+// repaired locally.
+func (lrc *LRCode) LocalRepairGroup(pieceIndex int) []int {
+	var group int
+	switch {
+	case pieceIndex < 0 || pieceIndex >= lrc.dataPieces+lrc.localGroups:
+		return nil
+	case pieceIndex < lrc.dataPieces:
+		group = pieceIndex * lrc.localGroups / lrc.dataPieces
+	default:
+		group = pieceIndex - lrc.dataPieces
+	}
+	var indices []int
+	for _, j := range lrc.groupMembers(group) {
+		if j != pieceIndex {
+			indices = append(indices, j)
+		}
+	}
+	return indices
+}
+
+// RepairLocal repairs the piece at pieceIndex using the pieces of its local
+// repair group. All of the pieces returned by LocalRepairGroup need to be set.
+func (lrc *LRCode) RepairLocal(pieces [][]byte, pieceIndex int) error {
+	// Check the length of pieces.
+	if len(pieces) != lrc.NumPieces() {
+		return fmt.Errorf("expected pieces to have len %v but was %v",
+			lrc.NumPieces(), len(pieces))
+	}
+	indices := lrc.LocalRepairGroup(pieceIndex)
+	if indices == nil {
+		return errNotLocallyRepairable
+	}
+	pieceSize := len(pieces[indices[0]])
+	for _, j := range indices {
+		if len(pieces[j]) == 0 {
+			return fmt.Errorf("piece %v of the local group is missing", j)
+		}
+		if len(pieces[j]) != pieceSize {
+			return fmt.Errorf("pieces don't have right size expected %v but was %v",
+				pieceSize, len(pieces[j]))
+		}
+	}
+	// The local parity piece is a linear combination of the group's data
+	// pieces.
+	if pieceIndex >= lrc.dataPieces {
+		parity := make([]byte, pieceSize)
+		lrc.computeParity(pieces, pieceIndex-lrc.dataPieces, parity)
+		pieces[pieceIndex] = parity
+		return nil
+	}
+	// A data piece is computed by subtracting the other data pieces from the
+	// local parity piece and dividing by the piece's coefficient.
+	group := pieceIndex * lrc.localGroups / lrc.dataPieces
+	row := lrc.parityMatrix[group]
+	sum := make([]byte, pieceSize)
+	copy(sum, pieces[lrc.dataPieces+group])
+	for _, j := range lrc.groups[group] {
+		if j != pieceIndex {
+			gf16MulSliceXor(row[j], pieces[j], sum)
+		}
+	}
+	piece := make([]byte, pieceSize)
+	gf16MulSliceXor(gf16Inv(row[pieceIndex]), sum, piece)
+	pieces[pieceIndex] = piece
+	return nil
+}
+
+// groupMembers returns the indices of the data pieces and the local parity
+// piece of a group.
+func (lrc *LRCode) groupMembers(group int) []int {
+	members := make([]int, 0, len(lrc.groups[group])+1)
+	members = append(members, lrc.groups[group]...)
+	return append(members, lrc.dataPieces+group)
+}
+
+// computeParity adds the parity piece at index i of the parity pieces to out.
+func (lrc *LRCode) computeParity(pieces [][]byte, i int, out []byte) {
+	for j, c := range lrc.parityMatrix[i] {
+		gf16MulSliceXor(c, pieces[j], out)
+	}
+}
+
+// reconstructData recovers the missing data pieces from the provided pieces.
+// The parity pieces are left untouched.
+func (lrc *LRCode) reconstructData(pieces [][]byte) error {
+	// Check the length of pieces.
+	if len(pieces) != lrc.NumPieces() {
+		return fmt.Errorf("expected pieces to have len %v but was %v",
+			lrc.NumPieces(), len(pieces))
+	}
+	pieceSize, err := stripedPieceSize(pieces, lrc.staticSegmentSize)
+	if err != nil {
+		return err
+	}
+	// Collect the missing data pieces.
+	var missing []int
+	column := make(map[int]int)
+	for i, piece := range pieces {
+		if len(piece) != 0 && len(piece) != pieceSize {
+			return fmt.Errorf("pieces don't have right size expected %v but was %v",
+				pieceSize, len(piece))
+		}
+		if len(piece) == 0 && i < lrc.dataPieces {
+			column[i] = len(missing)
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	// Select parity pieces which are linearly independent when restricted to
+	// the missing data pieces. The local parity pieces come first and only
+	// depend on a few data pieces which makes them cheaper to use.
+	var parity []int
+	var basis [][]uint16
+	var pivots []int
+	for p := range lrc.parityMatrix {
+		if len(parity) == len(missing) {
+			break
+		}
+		if len(pieces[lrc.dataPieces+p]) == 0 {
+			continue
+		}
+		v := make([]uint16, len(missing))
+		for l, j := range missing {
+			v[l] = lrc.parityMatrix[p][j]
+		}
+		for b, pivot := range pivots {
+			if f := v[pivot]; f != 0 {
+				for l := range v {
+					v[l] ^= gf16Mul(f, basis[b][l])
+				}
+			}
+		}
+		pivot := -1
+		for l := range v {
+			if v[l] != 0 {
+				pivot = l
+				break
+			}
+		}
+		if pivot == -1 {
+			continue
+		}
+		inv := gf16Inv(v[pivot])
+		for l := range v {
+			v[l] = gf16Mul(v[l], inv)
+		}
+		basis = append(basis, v)
+		pivots = append(pivots, pivot)
+		parity = append(parity, p)
+	}
+	if len(parity) < len(missing) {
+		return fmt.Errorf("not enough pieces to recover data, %v data pieces are missing but only %v parity pieces can be used",
+			len(missing), len(parity))
+	}
+	// Subtract the contribution of the available data pieces from the parity
+	// pieces. What remains is a linear combination of the missing data
+	// pieces only.
+	syndromes := make([][]byte, len(parity))
+	m := make([][]uint16, len(parity))
+	for k, p := range parity {
+		syndromes[k] = make([]byte, pieceSize)
+		copy(syndromes[k], pieces[lrc.dataPieces+p])
+		m[k] = make([]uint16, len(missing))
+		for j, c := range lrc.parityMatrix[p] {
+			if l, isMissing := column[j]; isMissing {
+				m[k][l] = c
+			} else {
+				gf16MulSliceXor(c, pieces[j], syndromes[k])
+			}
+		}
+	}
+	inv, err := gf16InvertMatrix(m)
+	if err != nil {
+		return err
+	}
+	// Compute the missing data pieces.
+	for l, j := range missing {
+		piece := make([]byte, pieceSize)
+		for k := range syndromes {
+			gf16MulSliceXor(inv[l][k], syndromes[k], piece)
+		}
+		pieces[j] = piece
+	}
+	return nil
+}
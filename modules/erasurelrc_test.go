@@ -0,0 +1,199 @@
+package modules
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+	"go.sia.tech/siad/crypto"
+)
+
+// TestLRCode tests encoding and recovering data with the LRCode EC.
+func TestLRCode(t *testing.T) {
+	badParams := []struct {
+		data, groups, global int
+	}{
+		{0, 1, 1},
+		{1, 0, 1},
+		{1, 1, -1},
+		{2, 3, 1},
+		{RSGF16MaxPieces, 1, 1},
+	}
+	for _, ps := range badParams {
+		if _, err := NewLRCode(ps.data, ps.groups, ps.global); err == nil {
+			t.Error("expected bad parameter error, got nil")
+		}
+	}
+
+	dataPieces, localGroups, globalParity := 12, 3, 4
+	segmentSize := int(crypto.SegmentSize)
+	pieceSize := 4 * segmentSize
+	lrc, err := NewLRCode(dataPieces, localGroups, globalParity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lrc.NumPieces() != 19 || lrc.MinPieces() != 14 || lrc.DataPieces() != 12 {
+		t.Fatal("wrong number of pieces", lrc.NumPieces(), lrc.MinPieces(), lrc.DataPieces())
+	}
+	if lrc.Identifier() != "5+12+3+4" {
+		t.Fatal("wrong identifier", lrc.Identifier())
+	}
+	if size, supported := lrc.SupportsPartialEncoding(); !supported || size != crypto.SegmentSize {
+		t.Fatal("partial encoding should be supported")
+	}
+
+	data := fastrand.Bytes(pieceSize * dataPieces)
+	encodedPieces, err := lrc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The code can tolerate the loss of any globalParity+1 pieces.
+	for _, i := range fastrand.Perm(len(encodedPieces))[:globalParity+1] {
+		encodedPieces[i] = nil
+	}
+	// Recover every segment individually.
+	dataOffset := 0
+	decodedSegmentSize := segmentSize * dataPieces
+	for segmentIndex := 0; segmentIndex < pieceSize/segmentSize; segmentIndex++ {
+		buf := new(bytes.Buffer)
+		segment := ExtractSegment(encodedPieces, segmentIndex, uint64(segmentSize))
+		err = lrc.Recover(segment, uint64(decodedSegmentSize), buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data[dataOffset:dataOffset+decodedSegmentSize]) {
+			t.Fatal("decoded bytes don't equal original segment")
+		}
+		dataOffset += decodedSegmentSize
+	}
+	// Recover all segments at once.
+	buf := new(bytes.Buffer)
+	err = lrc.Recover(encodedPieces, uint64(len(data)), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("decoded bytes don't equal original data")
+	}
+}
+
+// TestLRCodeMinPieces checks that the data can be recovered from every subset
+// of MinPieces pieces.
+func TestLRCodeMinPieces(t *testing.T) {
+	params := []struct {
+		data, groups, global int
+	}{
+		{6, 2, 1},
+		{10, 3, 2},
+		{4, 4, 0},
+	}
+	for _, ps := range params {
+		lrc, err := NewLRCode(ps.data, ps.groups, ps.global)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := fastrand.Bytes(ps.data * int(crypto.SegmentSize))
+		encodedPieces, err := lrc.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Try every subset of MinPieces pieces.
+		var subsets int
+		n := lrc.NumPieces()
+		for mask := 0; mask < 1<<uint(n); mask++ {
+			pieces := make([][]byte, n)
+			var count int
+			for i := range pieces {
+				if mask&(1<<uint(i)) != 0 {
+					pieces[i] = encodedPieces[i]
+					count++
+				}
+			}
+			if count != lrc.MinPieces() {
+				continue
+			}
+			subsets++
+			buf := new(bytes.Buffer)
+			if err := lrc.Recover(pieces, uint64(len(data)), buf); err != nil {
+				t.Fatalf("%v: failed to recover from pieces %b: %v", lrc.Identifier(), mask, err)
+			}
+			if !bytes.Equal(buf.Bytes(), data) {
+				t.Fatalf("%v: wrong data recovered from pieces %b", lrc.Identifier(), mask)
+			}
+		}
+		if subsets == 0 {
+			t.Fatal("no subsets were tried")
+		}
+	}
+}
+
+// TestLRCodeReconstruct tests reconstructing all pieces with the LRCode EC.
+func TestLRCodeReconstruct(t *testing.T) {
+	lrc, err := NewLRCode(10, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(777)
+	pieces, err := lrc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := make([][]byte, len(pieces))
+	copy(original, pieces)
+	for _, i := range fastrand.Perm(len(pieces))[:3] {
+		pieces[i] = nil
+	}
+	if err := lrc.Reconstruct(pieces); err != nil {
+		t.Fatal(err)
+	}
+	for i := range pieces {
+		if !bytes.Equal(pieces[i], original[i]) {
+			t.Fatalf("piece %v wasn't reconstructed correctly", i)
+		}
+	}
+}
+
+// TestLRCodeRepairLocal checks that every data piece and local parity piece
+// can be repaired from its local group alone.
+func TestLRCodeRepairLocal(t *testing.T) {
+	ec, err := NewLRCode(10, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lrc := ec.(LocallyRepairableCoder)
+	original, err := lrc.Encode(fastrand.Bytes(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pieceIndex := range original {
+		group := lrc.LocalRepairGroup(pieceIndex)
+		// Global parity pieces can't be repaired locally.
+		if pieceIndex >= 13 {
+			if group != nil {
+				t.Fatal("global parity piece shouldn't have a local group")
+			}
+			continue
+		}
+		if len(group) >= lrc.MinPieces() {
+			t.Fatalf("local group of piece %v is too large: %v", pieceIndex, len(group))
+		}
+		// Only provide the pieces of the group.
+		pieces := make([][]byte, len(original))
+		for _, j := range group {
+			pieces[j] = original[j]
+		}
+		if err := lrc.RepairLocal(pieces, pieceIndex); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pieces[pieceIndex], original[pieceIndex]) {
+			t.Fatalf("piece %v wasn't repaired correctly", pieceIndex)
+		}
+		// Without one of the group's pieces the repair should fail.
+		pieces[pieceIndex] = nil
+		pieces[group[0]] = nil
+		if err := lrc.RepairLocal(pieces, pieceIndex); err == nil {
+			t.Fatal("expected repair to fail")
+		}
+	}
+}
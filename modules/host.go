@@ -1,8 +1,11 @@
 package modules
 
 import (
+	"fmt"
 	"time"
 
+	"gitlab.com/NebulousLabs/errors"
+
 	"go.sia.tech/siad/build"
 	"go.sia.tech/siad/crypto"
 	"go.sia.tech/siad/persist"
@@ -26,6 +29,14 @@ const (
 	// HostRegistryFile is the name of the file the host's registry is stored
 	// in.
 	HostRegistryFile = "registry.dat"
+
+	// HostPricingSourceFiat prices the host according to fiat denominated
+	// targets.
+	HostPricingSourceFiat = "fiat"
+
+	// HostPricingSourceNetwork prices the host according to a percentile of
+	// the prices of the network's active hosts.
+	HostPricingSourceNetwork = "network"
 )
 
 var (
@@ -289,6 +300,69 @@ type (
 
 		CustomRegistryPath string `json:"customregistrypath"`
 		RegistrySize       uint64 `json:"registrysize"`
+
+		// Limits which protect the host from single peers and ephemeral
+		// accounts. The bandwidth limit is in bytes per second per peer or
+		// account and direction, the RPC rate limit in RPCs per second per
+		// peer, renter and account, and the program limit is the number of
+		// programs an account can execute concurrently. 0 disables a limit.
+		MaxConcurrentPrograms uint64 `json:"maxconcurrentprograms"`
+		PeerBandwidthLimit    uint64 `json:"peerbandwidthlimit"`
+		PeerRPCRateLimit      uint64 `json:"peerrpcratelimit"`
+	}
+
+	// HostPricingPolicy configures the host's pricing engine. If enabled, the
+	// engine periodically derives the host's prices from the policy's source
+	// and updates the host's internal settings accordingly.
+	HostPricingPolicy struct {
+		Enabled        bool          `json:"enabled"`
+		Source         string        `json:"source"`
+		UpdateInterval time.Duration `json:"updateinterval"`
+
+		// Fiat denominated targets of the fiat source. The exchange rate is
+		// the price of one siacoin, e.g. "0.01 USD". It isn't fetched from an
+		// exchange and needs to be updated manually to keep the prices in line
+		// with the targets. The storage price is per TB per month and the
+		// bandwidth prices are per TB.
+		ExchangeRate      string  `json:"exchangerate"`
+		FiatContractPrice float64 `json:"fiatcontractprice"`
+		FiatDownloadPrice float64 `json:"fiatdownloadprice"`
+		FiatStoragePrice  float64 `json:"fiatstorageprice"`
+		FiatUploadPrice   float64 `json:"fiatuploadprice"`
+
+		// NetworkPercentile is the percentile of the prices of the network's
+		// active hosts the network source prices the host at.
+		NetworkPercentile float64 `json:"networkpercentile"`
+
+		// The storage price is adjusted by UtilizationAdjustment times the
+		// difference between the host's storage utilization and the
+		// TargetUtilization. A TargetUtilization of 0 disables the adjustment.
+		TargetUtilization     float64 `json:"targetutilization"`
+		UtilizationAdjustment float64 `json:"utilizationadjustment"`
+
+		// CollateralRatio sets the collateral to a multiple of the storage
+		// price. A ratio of 0 leaves the collateral unchanged.
+		CollateralRatio float64 `json:"collateralratio"`
+
+		// Bounds of the storage price. A MaxStoragePrice of 0 means no upper
+		// bound.
+		MinStoragePrice types.Currency `json:"minstorageprice"`
+		MaxStoragePrice types.Currency `json:"maxstorageprice"`
+	}
+
+	// HostPricingChange is a change of a price by the host's pricing engine.
+	HostPricingChange struct {
+		Time     time.Time      `json:"time"`
+		Setting  string         `json:"setting"`
+		OldPrice types.Currency `json:"oldprice"`
+		NewPrice types.Currency `json:"newprice"`
+		Reason   string         `json:"reason"`
+	}
+
+	// HostPricingHostDB is the part of a hostdb the host's pricing engine uses
+	// to observe the prices of the network.
+	HostPricingHostDB interface {
+		ActiveHosts() ([]HostDBEntry, error)
 	}
 
 	// HostNetworkMetrics reports the quantity of each type of RPC call that
@@ -301,6 +375,11 @@ type (
 		ReviseCalls       uint64 `json:"revisecalls"`
 		SettingsCalls     uint64 `json:"settingscalls"`
 		UnrecognizedCalls uint64 `json:"unrecognizedcalls"`
+
+		// Calls which were rejected by the host's per peer and per account
+		// limits.
+		ProgramLimitedCalls uint64 `json:"programlimitedcalls"`
+		RateLimitedCalls    uint64 `json:"ratelimitedcalls"`
 	}
 
 	// StorageObligation contains information about a storage obligation that
@@ -428,6 +507,10 @@ type (
 		// PriceTable returns the host's current price table.
 		PriceTable() RPCPriceTable
 
+		// PricingPolicy returns the policy of the host's pricing engine and
+		// the most recent price changes it made.
+		PricingPolicy() (HostPricingPolicy, []HostPricingChange)
+
 		// PruneStaleStorageObligations will delete storage obligations from the
 		// host that, for whatever reason, did not make it on the block chain.
 		// As these stale storage obligations have an impact on the host
@@ -480,9 +563,27 @@ type (
 		// and the resize operation completed, meaning that data will be lost.
 		ResizeStorageFolder(index uint16, newSize uint64, force bool) error
 
+		// ScrubStatus returns the status of the background scrubber which
+		// periodically verifies the integrity of the sectors stored by the
+		// host.
+		ScrubStatus() StorageScrubStatus
+
 		// SetInternalSettings sets the hosting parameters of the host.
 		SetInternalSettings(HostInternalSettings) error
 
+		// SetPricingHostDB sets the hostdb the pricing engine observes the
+		// prices of the network with.
+		SetPricingHostDB(HostPricingHostDB)
+
+		// SetPricingPolicy sets the policy of the host's pricing engine. An
+		// enabled policy is applied right away.
+		SetPricingPolicy(HostPricingPolicy) error
+
+		// SetStorageFolderTier sets the tier of a storage folder on the host.
+		// Frequently read sectors are moved to storage folders of the fast tier
+		// in the background.
+		SetStorageFolderTier(index uint16, tier string) error
+
 		// StorageObligation returns the storage obligation matching the id or
 		// an error if it does not exist
 		StorageObligation(obligationID types.FileContractID) (StorageObligation, error)
@@ -491,6 +592,11 @@ type (
 		// the host.
 		StorageObligations() []StorageObligation
 
+		// StorageObligationsQuery returns the page of storage obligations
+		// matching the query together with the total number of matching
+		// obligations.
+		StorageObligationsQuery(HostStorageObligationQuery) ([]StorageObligation, uint64, error)
+
 		// StorageFolders will return a list of storage folders tracked by the
 		// host.
 		StorageFolders() []StorageFolderMetadata
@@ -501,6 +607,43 @@ type (
 	}
 )
 
+// Validate returns an error if the pricing policy is enabled but can't be
+// applied.
+func (p HostPricingPolicy) Validate() error {
+	if !p.Enabled {
+		return nil
+	}
+	switch p.Source {
+	case HostPricingSourceFiat:
+		rate, err := types.ParseExchangeRate(p.ExchangeRate)
+		if err != nil {
+			return errors.AddContext(err, "invalid exchange rate")
+		}
+		if rate == nil {
+			return errors.New("the fiat source requires an exchange rate")
+		}
+		if p.FiatContractPrice < 0 || p.FiatDownloadPrice < 0 || p.FiatStoragePrice < 0 || p.FiatUploadPrice < 0 {
+			return errors.New("fiat prices can't be negative")
+		}
+	case HostPricingSourceNetwork:
+		if p.NetworkPercentile <= 0 || p.NetworkPercentile > 100 {
+			return errors.New("network percentile needs to be in (0, 100]")
+		}
+	default:
+		return fmt.Errorf("unknown pricing source '%v'", p.Source)
+	}
+	if p.TargetUtilization < 0 || p.TargetUtilization >= 1 {
+		return errors.New("target utilization needs to be in [0, 1)")
+	}
+	if p.UtilizationAdjustment < 0 || p.CollateralRatio < 0 {
+		return errors.New("utilization adjustment and collateral ratio can't be negative")
+	}
+	if !p.MaxStoragePrice.IsZero() && p.MaxStoragePrice.Cmp(p.MinStoragePrice) < 0 {
+		return errors.New("max storage price can't be lower than min storage price")
+	}
+	return nil
+}
+
 // MaxBaseRPCPrice returns the maximum value for the MinBaseRPCPrice based on
 // the MinDownloadBandwidthPrice
 func (his HostInternalSettings) MaxBaseRPCPrice() types.Currency {
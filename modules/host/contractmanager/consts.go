@@ -12,6 +12,10 @@ const (
 	// AlertMSGHostDiskTrouble indicates that one or multiple of a host's disks
 	// are encountering problems
 	AlertMSGHostDiskTrouble = "disk problem detected"
+
+	// AlertMSGHostCorruptSectors indicates that the scrubber found sectors
+	// whose data doesn't match their root.
+	AlertMSGHostCorruptSectors = "corrupt sectors detected"
 )
 
 const (
@@ -144,3 +148,47 @@ var (
 		Testing:  time.Second * 8,
 	}).(time.Duration)
 )
+
+var (
+	// hotSectorMigrationInterval is the interval at which the contract manager
+	// moves frequently read sectors to the fast storage tier.
+	hotSectorMigrationInterval = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: time.Minute * 10,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// hotSectorReadThreshold is the number of recent reads after which a
+	// sector is considered hot and moved to the fast storage tier.
+	hotSectorReadThreshold = build.Select(build.Var{
+		Dev:      uint64(5),
+		Standard: uint64(10),
+		Testing:  uint64(3),
+	}).(uint64)
+
+	// maxHotSectorMigrations is the maximum number of sectors which are moved
+	// to the fast storage tier per interval.
+	maxHotSectorMigrations = build.Select(build.Var{
+		Dev:      100,
+		Standard: 1000,
+		Testing:  10,
+	}).(int)
+)
+
+var (
+	// scrubInterval is the interval at which the contract manager verifies
+	// the integrity of all stored sectors.
+	scrubInterval = build.Select(build.Var{
+		Dev:      time.Hour,
+		Standard: time.Hour * 24 * 14,
+		Testing:  time.Minute,
+	}).(time.Duration)
+
+	// scrubSectorDelay is the time the scrubber waits between sectors to
+	// limit its impact on the disks.
+	scrubSectorDelay = build.Select(build.Var{
+		Dev:      time.Millisecond * 10,
+		Standard: time.Millisecond * 50,
+		Testing:  time.Duration(0),
+	}).(time.Duration)
+)
@@ -29,6 +29,7 @@ import (
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"gitlab.com/NebulousLabs/errors"
 	"go.sia.tech/siad/crypto"
@@ -88,8 +89,9 @@ type ContractManager struct {
 	sectorSalt                   crypto.Hash
 	sectorLocationsCountOverflow *overflowMap
 
-	// sectorMu is a dedicated lock for lockedSectors, sectorLocations and
-	// storageFolders meant to be usable in combination with cm.wal.mu.
+	// sectorMu is a dedicated lock for lockedSectors, sectorLocations,
+	// sectorReads and storageFolders meant to be usable in combination with
+	// cm.wal.mu.
 	//
 	// The goal is to allow for reading these fields without locking wal.mu
 	// which is acquired and released every 500ms in threadedSyncLoop and
@@ -114,6 +116,18 @@ type ContractManager struct {
 	sectorLocations map[sectorID]sectorLocation
 	storageFolders  map[uint16]*storageFolder
 
+	// nextScrub is the time the next scrub of all sectors is scheduled for
+	// and is persisted in the settings. The other scrub fields describe the
+	// scrubs since startup. They are protected by sectorMu.
+	nextScrub          time.Time
+	lastScrubCompleted time.Time
+	lastScrubStarted   time.Time
+	scrubRunning       bool
+
+	// sectorReads counts the recent reads of each sector. It is used to find
+	// the frequently read sectors which are moved to the fast storage tier.
+	sectorReads map[sectorID]uint64
+
 	// sectors are removed from the store in a rate-limited queue to work around
 	// lock contention on extra large contracts.
 	sectorRemoval *sectorRemovalMap
@@ -138,6 +152,7 @@ func newContractManager(dependencies modules.Dependencies, persistDir string) (_
 	cm := &ContractManager{
 		storageFolders:  make(map[uint16]*storageFolder),
 		sectorLocations: make(map[sectorID]sectorLocation),
+		sectorReads:     make(map[sectorID]uint64),
 
 		lockedSectors: make(map[sectorID]*sectorLock),
 
@@ -254,6 +269,14 @@ func newContractManager(dependencies modules.Dependencies, persistDir string) (_
 	// and adds them if they are discovered.
 	go cm.threadedFolderRecheck()
 
+	// Spin up the thread that moves frequently read sectors to the fast
+	// storage tier.
+	go cm.threadedMigrateHotSectors()
+
+	// Spin up the thread that periodically verifies the integrity of all
+	// stored sectors.
+	go cm.threadedScrubSectors()
+
 	// the removal map is loaded last so that the WAL and metadata is loaded.
 	cm.sectorRemoval, err = newSectorRemovalMap(filepath.Join(persistDir, sectorRemovalQueueFile), cm)
 	if err != nil {
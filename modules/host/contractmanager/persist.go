@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sort"
 	"sync/atomic"
+	"time"
 
 	"gitlab.com/NebulousLabs/errors"
 	"gitlab.com/NebulousLabs/fastrand"
@@ -21,12 +22,14 @@ type (
 	savedStorageFolder struct {
 		Index uint16
 		Path  string
+		Tier  string
 		Usage []uint64
 	}
 
 	// savedSettings contains fields that are saved atomically to disk inside
 	// of the contract manager directory, alongside the WAL and log.
 	savedSettings struct {
+		NextScrub      time.Time
 		SectorSalt     crypto.Hash
 		StorageFolders []savedStorageFolder
 	}
@@ -34,14 +37,14 @@ type (
 
 // equals tests if all settings are equal between two savedSettings.
 func (s *savedSettings) equals(sb savedSettings) bool {
-	if s.SectorSalt != sb.SectorSalt || len(s.StorageFolders) != len(sb.StorageFolders) {
+	if s.SectorSalt != sb.SectorSalt || !s.NextScrub.Equal(sb.NextScrub) || len(s.StorageFolders) != len(sb.StorageFolders) {
 		return false
 	}
 
 	for i, sf := range s.StorageFolders {
 		sfb := sb.StorageFolders[i]
 
-		if sf.Index != sfb.Index || sf.Path != sfb.Path || len(sf.Usage) != len(sfb.Usage) {
+		if sf.Index != sfb.Index || sf.Path != sfb.Path || sf.Tier != sfb.Tier || len(sf.Usage) != len(sfb.Usage) {
 			return false
 		}
 
@@ -60,6 +63,7 @@ func (sf *storageFolder) savedStorageFolder() savedStorageFolder {
 	ssf := savedStorageFolder{
 		Index: sf.index,
 		Path:  sf.path,
+		Tier:  sf.tier,
 		Usage: make([]uint64, len(sf.usage)),
 	}
 	copy(ssf.Usage, sf.usage)
@@ -72,6 +76,9 @@ func (cm *ContractManager) initSettings() error {
 	// Initialize the sector salt to a random value.
 	fastrand.Read(cm.sectorSalt[:])
 
+	// Schedule the first scrub.
+	cm.nextScrub = time.Now().Add(scrubInterval)
+
 	// Ensure that the initialized defaults have stuck.
 	ss := cm.savedSettings()
 	err := persist.SaveJSON(settingsMetadata, &ss, filepath.Join(cm.persistDir, settingsFile))
@@ -95,12 +102,19 @@ func (cm *ContractManager) loadSettings() error {
 		return errors.AddContext(err, "error loading the contract manager settings file")
 	}
 
-	// Copy the saved settings into the contract manager.
+	// Copy the saved settings into the contract manager. Contract managers
+	// which were persisted before scrubbing existed schedule their first
+	// scrub.
 	cm.sectorSalt = ss.SectorSalt
+	cm.nextScrub = ss.NextScrub
+	if cm.nextScrub.IsZero() {
+		cm.nextScrub = time.Now().Add(scrubInterval)
+	}
 	for i := range ss.StorageFolders {
 		sf := new(storageFolder)
 		sf.index = ss.StorageFolders[i].Index
 		sf.path = ss.StorageFolders[i].Path
+		sf.tier = storageFolderTier(ss.StorageFolders[i].Tier)
 		sf.usage = ss.StorageFolders[i].Usage
 		sf.metadataFile, err = cm.dependencies.OpenFile(filepath.Join(ss.StorageFolders[i].Path, metadataFile), os.O_RDWR, 0700)
 		if err != nil {
@@ -175,6 +189,7 @@ func (cm *ContractManager) savedSettings() savedSettings {
 		SectorSalt: cm.sectorSalt,
 	}
 	cm.sectorMu.Lock()
+	ss.NextScrub = cm.nextScrub
 	for _, sf := range cm.storageFolders {
 		// Unset all of the usage bits in the storage folder for the queued sectors.
 		for _, sectorIndex := range sf.availableSectors {
@@ -0,0 +1,205 @@
+package contractmanager
+
+// scrub.go contains the scrubber, which periodically reads every sector stored
+// by the contract manager, recomputes its Merkle root and compares it with the
+// sector's id. Without the scrubber, silent corruption of a sector is only
+// noticed once the host fails to provide a storage proof for it. The scrubber
+// reads one sector at a time and waits scrubSectorDelay between sectors to
+// limit its impact on the disks.
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+)
+
+// managedScrubSector verifies the data of the sector with the provided id at
+// the provided index of the storage folder. It returns false if the sector is
+// corrupt or unreadable. Sectors which were moved or removed in the meantime
+// are skipped.
+func (cm *ContractManager) managedScrubSector(sf *storageFolder, index uint32, id sectorID) bool {
+	cm.wal.managedLockSector(id)
+	cm.sectorMu.Lock()
+	sl, exists := cm.sectorLocations[id]
+	cm.sectorMu.Unlock()
+	if !exists || sl.storageFolder != sf.index || sl.index != index {
+		cm.wal.managedUnlockSector(id)
+		return true
+	}
+	sectorData, err := readSector(sf.sectorFile, index)
+	cm.wal.managedUnlockSector(id)
+	if err != nil {
+		atomic.AddUint64(&sf.atomicFailedReads, 1)
+		cm.log.Printf("ERROR: unable to read sector %x of folder %v while scrubbing: %v\n", id, sf.path, err)
+		return false
+	}
+	atomic.AddUint64(&sf.atomicSuccessfulReads, 1)
+
+	// Compare the root of the data with the root the sector was stored under.
+	if cm.managedSectorID(crypto.MerkleRoot(sectorData)) != id {
+		cm.log.Printf("ERROR: sector %x of folder %v is corrupt\n", id, sf.path)
+		return false
+	}
+	return true
+}
+
+// managedScrubStorageFolder verifies the data of all sectors in the storage
+// folder.
+func (cm *ContractManager) managedScrubStorageFolder(sf *storageFolder) {
+	// Copy the usage, sectors might be added or removed while scrubbing.
+	cm.wal.mu.Lock()
+	cm.sectorMu.Lock()
+	usage := append([]uint64(nil), sf.usage...)
+	cm.sectorMu.Unlock()
+	cm.wal.mu.Unlock()
+	sectors := usageSectors(usage)
+
+	atomic.StoreUint64(&sf.atomicScrubCorruptSectors, 0)
+	atomic.StoreUint64(&sf.atomicScrubScrubbedSectors, 0)
+	atomic.StoreUint64(&sf.atomicScrubTotalSectors, uint64(len(sectors)))
+
+	// Read the sector lookup bytes to figure out which sectors are in which
+	// locations.
+	if err := cm.tg.Add(); err != nil {
+		return
+	}
+	sectorLookupBytes, err := readFullMetadata(sf.metadataFile, len(usage)*storageFolderGranularity)
+	cm.tg.Done()
+	if err != nil {
+		atomic.AddUint64(&sf.atomicFailedReads, 1)
+		cm.log.Printf("ERROR: unable to read sector metadata of folder %v while scrubbing: %v\n", sf.path, err)
+		return
+	}
+	atomic.AddUint64(&sf.atomicSuccessfulReads, 1)
+
+	for _, index := range sectors {
+		select {
+		case <-cm.tg.StopChan():
+			return
+		case <-time.After(scrubSectorDelay):
+		}
+		if atomic.LoadUint64(&sf.atomicUnavailable) == 1 {
+			return
+		}
+
+		var id sectorID
+		readHead := index * sectorMetadataDiskSize
+		copy(id[:], sectorLookupBytes[readHead:readHead+12])
+		if err := cm.tg.Add(); err != nil {
+			return
+		}
+		valid := cm.managedScrubSector(sf, index, id)
+		cm.tg.Done()
+		if !valid {
+			atomic.AddUint64(&sf.atomicScrubCorruptSectors, 1)
+			cm.managedUpdateCorruptSectorsAlert()
+		}
+		atomic.AddUint64(&sf.atomicScrubScrubbedSectors, 1)
+	}
+}
+
+// managedUpdateCorruptSectorsAlert registers the corrupt sectors alert if the
+// current or last scrub of a storage folder found corrupt sectors and
+// unregisters it otherwise.
+func (cm *ContractManager) managedUpdateCorruptSectorsAlert() {
+	var corrupt uint64
+	cm.sectorMu.Lock()
+	for _, sf := range cm.storageFolders {
+		corrupt += atomic.LoadUint64(&sf.atomicScrubCorruptSectors)
+	}
+	cm.sectorMu.Unlock()
+	if corrupt == 0 {
+		cm.staticAlerter.UnregisterAlert(modules.AlertIDHostCorruptSectors)
+		return
+	}
+	cause := fmt.Sprintf("%v sectors are corrupt or unreadable", corrupt)
+	cm.staticAlerter.RegisterAlert(modules.AlertIDHostCorruptSectors, AlertMSGHostCorruptSectors, cause, modules.SeverityCritical)
+}
+
+// managedScrub verifies the data of all sectors in the available storage
+// folders and schedules the next scrub.
+func (cm *ContractManager) managedScrub() {
+	cm.sectorMu.Lock()
+	cm.scrubRunning = true
+	cm.lastScrubStarted = time.Now()
+	cm.sectorMu.Unlock()
+	defer func() {
+		cm.sectorMu.Lock()
+		cm.scrubRunning = false
+		cm.sectorMu.Unlock()
+	}()
+
+	sfs := cm.availableStorageFolders()
+	sort.Slice(sfs, func(i, j int) bool {
+		return sfs[i].index < sfs[j].index
+	})
+	for _, sf := range sfs {
+		cm.managedScrubStorageFolder(sf)
+	}
+	cm.managedUpdateCorruptSectorsAlert()
+
+	// Don't schedule the next scrub if the scrub was interrupted by shutdown,
+	// it is restarted after the next startup instead.
+	select {
+	case <-cm.tg.StopChan():
+		return
+	default:
+	}
+	cm.sectorMu.Lock()
+	cm.lastScrubCompleted = time.Now()
+	cm.nextScrub = cm.lastScrubCompleted.Add(scrubInterval)
+	cm.sectorMu.Unlock()
+}
+
+// threadedScrubSectors periodically verifies the data of all sectors.
+func (cm *ContractManager) threadedScrubSectors() {
+	for {
+		cm.sectorMu.Lock()
+		nextScrub := cm.nextScrub
+		cm.sectorMu.Unlock()
+
+		select {
+		case <-cm.tg.StopChan():
+			return
+		case <-time.After(time.Until(nextScrub)):
+		}
+		cm.managedScrub()
+	}
+}
+
+// ScrubStatus returns the status of the scrubber and the scrub progress of
+// each storage folder.
+func (cm *ContractManager) ScrubStatus() modules.StorageScrubStatus {
+	err := cm.tg.Add()
+	if err != nil {
+		return modules.StorageScrubStatus{}
+	}
+	defer cm.tg.Done()
+	cm.sectorMu.Lock()
+	defer cm.sectorMu.Unlock()
+
+	status := modules.StorageScrubStatus{
+		Running:       cm.scrubRunning,
+		LastStarted:   cm.lastScrubStarted,
+		LastCompleted: cm.lastScrubCompleted,
+		NextScrub:     cm.nextScrub,
+	}
+	for _, sf := range cm.storageFolders {
+		status.Folders = append(status.Folders, modules.StorageFolderScrubStatus{
+			Index: sf.index,
+			Path:  sf.path,
+
+			ScrubbedSectors: atomic.LoadUint64(&sf.atomicScrubScrubbedSectors),
+			TotalSectors:    atomic.LoadUint64(&sf.atomicScrubTotalSectors),
+			CorruptSectors:  atomic.LoadUint64(&sf.atomicScrubCorruptSectors),
+		})
+	}
+	sort.Slice(status.Folders, func(i, j int) bool {
+		return status.Folders[i].Index < status.Folders[j].Index
+	})
+	return status
+}
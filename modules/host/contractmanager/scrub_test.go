@@ -0,0 +1,99 @@
+package contractmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/modules"
+)
+
+// TestScrub checks that the scrubber finds corrupt sectors and reports them
+// through the scrub status and an alert.
+func TestScrub(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cmt, err := newContractManagerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmt.panicClose()
+
+	// Add a storage folder with a few sectors.
+	storageFolderDir := filepath.Join(cmt.persistDir, "storageFolderOne")
+	if err := os.MkdirAll(storageFolderDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmt.cm.AddStorageFolder(storageFolderDir, modules.SectorSize*64); err != nil {
+		t.Fatal(err)
+	}
+	root, data := randSector()
+	if err := cmt.cm.AddSector(root, data); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		r, d := randSector()
+		if err := cmt.cm.AddSector(r, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// hasAlert returns whether the corrupt sectors alert is registered.
+	hasAlert := func() bool {
+		crit, _, _, _ := cmt.cm.Alerts()
+		for _, alert := range crit {
+			if alert.Msg == AlertMSGHostCorruptSectors {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A scrub of intact sectors doesn't find any corruption.
+	cmt.cm.managedScrub()
+	status := cmt.cm.ScrubStatus()
+	if status.Running || status.LastCompleted.IsZero() || !status.NextScrub.After(status.LastCompleted) {
+		t.Fatal("unexpected scrub status", status)
+	}
+	if len(status.Folders) != 1 {
+		t.Fatal("expected 1 folder but got", len(status.Folders))
+	}
+	if sf := status.Folders[0]; sf.Path != storageFolderDir || sf.ScrubbedSectors != 3 || sf.TotalSectors != 3 || sf.CorruptSectors != 0 {
+		t.Fatal("unexpected folder scrub status", sf)
+	}
+	if hasAlert() {
+		t.Fatal("alert registered for intact sectors")
+	}
+
+	// Corrupt a sector on disk.
+	cmt.cm.sectorMu.Lock()
+	sl := cmt.cm.sectorLocations[cmt.cm.managedSectorID(root)]
+	sf := cmt.cm.storageFolders[sl.storageFolder]
+	cmt.cm.sectorMu.Unlock()
+	if err := writeSector(sf.sectorFile, sl.index, fastrand.Bytes(int(modules.SectorSize))); err != nil {
+		t.Fatal(err)
+	}
+
+	// The scrub finds the corrupt sector.
+	cmt.cm.managedScrub()
+	status = cmt.cm.ScrubStatus()
+	if sf := status.Folders[0]; sf.ScrubbedSectors != 3 || sf.CorruptSectors != 1 {
+		t.Fatal("unexpected folder scrub status", sf)
+	}
+	if !hasAlert() {
+		t.Fatal("alert wasn't registered for corrupt sector")
+	}
+
+	// Once the sector is repaired the alert is unregistered.
+	if err := writeSector(sf.sectorFile, sl.index, data); err != nil {
+		t.Fatal(err)
+	}
+	cmt.cm.managedScrub()
+	if cmt.cm.ScrubStatus().Folders[0].CorruptSectors != 0 || hasAlert() {
+		t.Fatal("alert wasn't unregistered")
+	}
+}
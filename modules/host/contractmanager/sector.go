@@ -134,6 +134,9 @@ func (cm *ContractManager) ReadPartialSector(root crypto.Hash, offset, length ui
 	cm.sectorMu.Lock()
 	sl, exists1 := cm.sectorLocations[id]
 	sf, exists2 := cm.storageFolders[sl.storageFolder]
+	if exists1 {
+		cm.sectorReads[id]++
+	}
 	cm.sectorMu.Unlock()
 	if !exists1 {
 		return nil, ErrSectorNotFound
@@ -90,14 +90,20 @@ type storageFolder struct {
 	atomicSuccessfulReads  uint64
 	atomicSuccessfulWrites uint64
 
+	// Scrub statistics of the current or last scrub of the storage folder.
+	atomicScrubCorruptSectors  uint64
+	atomicScrubScrubbedSectors uint64
+	atomicScrubTotalSectors    uint64
+
 	// Atomic bool indicating whether or not the storage folder is available. If
 	// the storage folder is not available, it will still be loaded but return
 	// an error if it is queried.
 	atomicUnavailable uint64 // uint64 for alignment
 
-	// The index, path, and usage are all saved directly to disk.
+	// The index, path, tier and usage are all saved directly to disk.
 	index uint16
 	path  string
+	tier  string
 	usage []uint64
 
 	// availableSectors indicates sectors which are marked as consumed in the
@@ -200,36 +206,36 @@ func usageSectors(usage []uint64) (usageSectors []uint32) {
 // vacancyStorageFolder takes a set of storage folders and returns a storage
 // folder with vacancy for a sector along with its index. 'nil' and '-1' are
 // returned if none of the storage folders are available to accept a sector.
-// The returned storage folder will be holding an RLock on its mutex.
+// Storage folders of the slow tier are preferred, leaving room in the fast
+// tier for frequently read sectors. The returned storage folder will be
+// holding an RLock on its mutex.
 func vacancyStorageFolder(sfs []*storageFolder) (*storageFolder, int) {
-	enoughRoom := false
-	var winningIndex int
+	// Go through the folders in random order, the slow tier first.
+	perm := fastrand.Perm(len(sfs))
+	for _, fast := range []bool{false, true} {
+		for _, index := range perm {
+			sf := sfs[index]
+			if (sf.tier == modules.StorageTierFast) != fast {
+				continue
+			}
 
-	// Go through the folders in random order.
-	for _, index := range fastrand.Perm(len(sfs)) {
-		sf := sfs[index]
+			// Skip past this storage folder if there is not enough room for at
+			// least one sector.
+			if sf.sectors >= uint64(len(sf.usage))*storageFolderGranularity {
+				continue
+			}
 
-		// Skip past this storage folder if there is not enough room for at
-		// least one sector.
-		if sf.sectors >= uint64(len(sf.usage))*storageFolderGranularity {
-			continue
-		}
+			// Skip past this storage folder if it's not available to receive
+			// new data.
+			if !sf.mu.TryRLock() {
+				continue
+			}
 
-		// Skip past this storage folder if it's not available to receive new
-		// data.
-		if !sf.mu.TryRLock() {
-			continue
+			// Select this storage folder.
+			return sf, index
 		}
-
-		// Select this storage folder.
-		enoughRoom = true
-		winningIndex = index
-		break
-	}
-	if !enoughRoom {
-		return nil, -1
 	}
-	return sfs[winningIndex], winningIndex
+	return nil, -1
 }
 
 // clearUsage will unset the usage bit at the provided sector index for this
@@ -454,6 +460,7 @@ func (cm *ContractManager) StorageFolders() []modules.StorageFolderMetadata {
 			CapacityRemaining: ((64 * uint64(len(sf.usage))) - sf.sectors) * modules.SectorSize,
 			Index:             sf.index,
 			Path:              sf.path,
+			Tier:              sf.tier,
 		}
 
 		// Set some of the values to extreme numbers if the storage folder is
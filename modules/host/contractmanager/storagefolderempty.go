@@ -22,6 +22,18 @@ var (
 // managedMoveSector will move a sector from its current storage folder to
 // another.
 func (wal *writeAheadLog) managedMoveSector(id sectorID) error {
+	wal.mu.Lock()
+	storageFolders := wal.cm.availableStorageFolders()
+	wal.mu.Unlock()
+	return wal.managedMoveSectorToFolders(id, storageFolders)
+}
+
+// managedMoveSectorToFolders will move a sector from its current storage
+// folder to one of the provided storage folders.
+func (wal *writeAheadLog) managedMoveSectorToFolders(id sectorID, storageFolders []*storageFolder) error {
+	// Copy the storage folders, failing folders are removed from the slice.
+	storageFolders = append([]*storageFolder(nil), storageFolders...)
+
 	wal.managedLockSector(id)
 	defer wal.managedUnlockSector(id)
 
@@ -52,9 +64,6 @@ func (wal *writeAheadLog) managedMoveSector(id sectorID) error {
 	}
 
 	// Place the sector into its new folder and add the atomic move to the WAL.
-	wal.mu.Lock()
-	storageFolders := wal.cm.availableStorageFolders()
-	wal.mu.Unlock()
 	for len(storageFolders) >= 1 {
 		var storageFolderIndex int
 		err := func() error {
@@ -0,0 +1,192 @@
+package contractmanager
+
+// storagetier.go moves frequently read sectors to the storage folders of the
+// fast tier. The contract manager counts the reads of every sector and halves
+// the counts every hotSectorMigrationInterval, so they reflect recent reads.
+// Sectors outside the fast tier which were read at least
+// hotSectorReadThreshold times are moved to the fast tier using the same
+// machinery as emptying a storage folder. If the fast tier is full, sectors
+// which are no longer hot are moved back to the slow tier to make room.
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+)
+
+var (
+	// errUnknownStorageTier is returned if a storage folder is assigned to a
+	// tier that doesn't exist.
+	errUnknownStorageTier = errors.New("unknown storage tier")
+)
+
+// storageFolderTier returns the tier of a persisted storage folder. Storage
+// folders which were persisted before tiers existed belong to the slow tier.
+func storageFolderTier(tier string) string {
+	if tier == modules.StorageTierFast {
+		return modules.StorageTierFast
+	}
+	return modules.StorageTierSlow
+}
+
+// managedTierStorageFolders returns the available storage folders of the
+// provided tier.
+func (cm *ContractManager) managedTierStorageFolders(tier string) []*storageFolder {
+	var sfs []*storageFolder
+	cm.sectorMu.Lock()
+	defer cm.sectorMu.Unlock()
+	for _, sf := range cm.storageFolders {
+		if sf.tier == tier && atomic.LoadUint64(&sf.atomicUnavailable) == 0 {
+			sfs = append(sfs, sf)
+		}
+	}
+	return sfs
+}
+
+// managedHotSectors returns up to maxHotSectorMigrations sectors outside of
+// the fast tier which were read at least hotSectorReadThreshold times, the
+// most frequently read sectors first.
+func (cm *ContractManager) managedHotSectors() []sectorID {
+	cm.sectorMu.Lock()
+	defer cm.sectorMu.Unlock()
+	var hot []sectorID
+	for id, reads := range cm.sectorReads {
+		if reads < hotSectorReadThreshold {
+			continue
+		}
+		sl, exists := cm.sectorLocations[id]
+		if !exists {
+			continue
+		}
+		sf, exists := cm.storageFolders[sl.storageFolder]
+		if !exists || sf.tier == modules.StorageTierFast {
+			continue
+		}
+		hot = append(hot, id)
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		return cm.sectorReads[hot[i]] > cm.sectorReads[hot[j]]
+	})
+	if len(hot) > maxHotSectorMigrations {
+		hot = hot[:maxHotSectorMigrations]
+	}
+	return hot
+}
+
+// managedColdSectors returns up to n sectors of the fast tier which were read
+// less than hotSectorReadThreshold times.
+func (cm *ContractManager) managedColdSectors(n int) []sectorID {
+	cm.sectorMu.Lock()
+	defer cm.sectorMu.Unlock()
+	var cold []sectorID
+	for id, sl := range cm.sectorLocations {
+		if len(cold) >= n {
+			break
+		}
+		sf, exists := cm.storageFolders[sl.storageFolder]
+		if !exists || sf.tier != modules.StorageTierFast || cm.sectorReads[id] >= hotSectorReadThreshold {
+			continue
+		}
+		cold = append(cold, id)
+	}
+	return cold
+}
+
+// managedDecaySectorReads halves the read counts of all sectors and removes
+// the counts of sectors which are no longer read or stored.
+func (cm *ContractManager) managedDecaySectorReads() {
+	cm.sectorMu.Lock()
+	defer cm.sectorMu.Unlock()
+	for id, reads := range cm.sectorReads {
+		_, exists := cm.sectorLocations[id]
+		if !exists || reads/2 == 0 {
+			delete(cm.sectorReads, id)
+			continue
+		}
+		cm.sectorReads[id] = reads / 2
+	}
+}
+
+// managedMigrateHotSectors moves the hot sectors to the fast tier.
+func (cm *ContractManager) managedMigrateHotSectors() {
+	err := cm.tg.Add()
+	if err != nil {
+		return
+	}
+	defer cm.tg.Done()
+	defer cm.managedDecaySectorReads()
+
+	fastFolders := cm.managedTierStorageFolders(modules.StorageTierFast)
+	if len(fastFolders) == 0 {
+		return
+	}
+	hot := cm.managedHotSectors()
+	var cold []sectorID
+	for i, id := range hot {
+		err := cm.wal.managedMoveSectorToFolders(id, fastFolders)
+		if err != nil && err.Error() == modules.V1420HostOutOfStorageErrString {
+			// The fast tier is full. Make room by moving a sector which is no
+			// longer hot to the slow tier.
+			if cold == nil {
+				cold = cm.managedColdSectors(len(hot) - i)
+			}
+			if len(cold) == 0 {
+				return
+			}
+			slowFolders := cm.managedTierStorageFolders(modules.StorageTierSlow)
+			err = cm.wal.managedMoveSectorToFolders(cold[0], slowFolders)
+			cold = cold[1:]
+			if err == nil {
+				err = cm.wal.managedMoveSectorToFolders(id, fastFolders)
+			}
+		}
+		if errors.Contains(err, errDiskTrouble) {
+			cm.staticAlerter.RegisterAlert(modules.AlertIDHostDiskTrouble, AlertMSGHostDiskTrouble, "", modules.SeverityCritical)
+		}
+		if err != nil {
+			cm.log.Println("Unable to move hot sector to the fast storage tier:", err)
+		}
+		if err != nil && err.Error() == modules.V1420HostOutOfStorageErrString {
+			return
+		}
+	}
+}
+
+// threadedMigrateHotSectors periodically moves the hot sectors to the fast
+// tier.
+func (cm *ContractManager) threadedMigrateHotSectors() {
+	for {
+		select {
+		case <-cm.tg.StopChan():
+			return
+		case <-time.After(hotSectorMigrationInterval):
+		}
+		cm.managedMigrateHotSectors()
+	}
+}
+
+// SetStorageFolderTier sets the tier of the storage folder with the provided
+// index. The tier is persisted with the next sync of the WAL.
+func (cm *ContractManager) SetStorageFolderTier(index uint16, tier string) error {
+	err := cm.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cm.tg.Done()
+	if tier != modules.StorageTierFast && tier != modules.StorageTierSlow {
+		return errUnknownStorageTier
+	}
+
+	cm.sectorMu.Lock()
+	defer cm.sectorMu.Unlock()
+	sf, exists := cm.storageFolders[index]
+	if !exists {
+		return errStorageFolderNotFound
+	}
+	sf.tier = tier
+	return nil
+}
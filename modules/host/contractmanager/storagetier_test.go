@@ -0,0 +1,123 @@
+package contractmanager
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+)
+
+// TestStorageTiers checks that new sectors are placed in the slow tier, that
+// frequently read sectors are moved to the fast tier and that the tiers are
+// persisted.
+func TestStorageTiers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cmt, err := newContractManagerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmt.panicClose()
+
+	// Add a slow and a fast storage folder.
+	slowDir := filepath.Join(cmt.persistDir, "slow")
+	fastDir := filepath.Join(cmt.persistDir, "fast")
+	for _, dir := range []string{slowDir, fastDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := cmt.cm.AddStorageFolder(dir, modules.SectorSize*64); err != nil {
+			t.Fatal(err)
+		}
+	}
+	folderIndex := func(path string) uint16 {
+		for _, sf := range cmt.cm.StorageFolders() {
+			if sf.Path == path {
+				return sf.Index
+			}
+		}
+		t.Fatal("storage folder not found", path)
+		return 0
+	}
+	fastIndex := folderIndex(fastDir)
+	if err := cmt.cm.SetStorageFolderTier(fastIndex, "unknown"); err != errUnknownStorageTier {
+		t.Fatal("expected errUnknownStorageTier but got", err)
+	}
+	if err := cmt.cm.SetStorageFolderTier(fastIndex, modules.StorageTierFast); err != nil {
+		t.Fatal(err)
+	}
+
+	// New sectors are placed in the slow tier.
+	root, data := randSector()
+	if err := cmt.cm.AddSector(root, data); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		r, d := randSector()
+		if err := cmt.cm.AddSector(r, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sectorFolder := func() uint16 {
+		cmt.cm.sectorMu.Lock()
+		defer cmt.cm.sectorMu.Unlock()
+		return cmt.cm.sectorLocations[cmt.cm.managedSectorID(root)].storageFolder
+	}
+	if sectorFolder() == fastIndex {
+		t.Fatal("new sector was placed in the fast tier")
+	}
+
+	// Read the sector frequently, it should be moved to the fast tier.
+	for i := uint64(0); i < hotSectorReadThreshold; i++ {
+		if _, err := cmt.cm.ReadSector(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if sectorFolder() != fastIndex {
+			return errors.New("sector wasn't moved to the fast tier")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sf := range cmt.cm.StorageFolders() {
+		if sf.Index == fastIndex && (sf.Tier != modules.StorageTierFast || sf.CapacityRemaining != sf.Capacity-modules.SectorSize) {
+			t.Fatal("unexpected fast storage folder", sf)
+		} else if sf.Index != fastIndex && (sf.Tier != modules.StorageTierSlow || sf.CapacityRemaining != sf.Capacity-3*modules.SectorSize) {
+			t.Fatal("unexpected slow storage folder", sf)
+		}
+	}
+	readData, err := cmt.cm.ReadSector(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readData, data) {
+		t.Fatal("moved sector has the wrong data")
+	}
+
+	// The tiers are persisted.
+	if err := cmt.cm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cmt.cm, err = New(filepath.Join(cmt.persistDir, modules.ContractManagerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sf := range cmt.cm.StorageFolders() {
+		if (sf.Index == fastIndex) != (sf.Tier == modules.StorageTierFast) {
+			t.Fatal("storage folder tier wasn't persisted", sf)
+		}
+	}
+	if sectorFolder() != fastIndex {
+		t.Fatal("moved sector wasn't persisted")
+	}
+}
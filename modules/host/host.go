@@ -194,6 +194,15 @@ type Host struct {
 	// of such conditions are congestion, load, liquidity, etc.
 	staticPriceTables *hostPrices
 
+	// staticRateLimiter enforces the host's per peer and per account limits.
+	staticRateLimiter *hostRateLimiter
+
+	// Fields related to the pricing engine. The hostdb is only set if the node
+	// runs a renter.
+	pricingChanges []modules.HostPricingChange
+	pricingHostDB  modules.HostPricingHostDB
+	pricingPolicy  modules.HostPricingPolicy
+
 	// Fields related to RHP3 bandwidhth.
 	atomicStreamUpload   uint64
 	atomicStreamDownload uint64
@@ -350,6 +359,9 @@ func (h *Host) managedUpdatePriceTable() {
 		ReadBaseCost:   hes.SectorAccessPrice, // roughly equal to 64 kib download
 		ReadLengthCost: types.NewCurrency64(1),
 
+		// Proving a segment requires reading the whole sector from disk.
+		ProveSegmentBaseCost: hes.SectorAccessPrice,
+
 		// Write related costs.
 		WriteBaseCost:   hes.SectorAccessPrice, // roughly equal to 64 kib download
 		WriteLengthCost: types.NewCurrency64(1),
@@ -455,6 +467,7 @@ func newHost(dependencies modules.Dependencies, smDeps modules.Dependencies, cs
 				heap: make([]*hostRPCPriceTable, 0),
 			},
 		},
+		staticRateLimiter:           newHostRateLimiter(),
 		staticRegistrySubscriptions: newRegistrySubscriptions(),
 		persistDir:                  persistDir,
 	}
@@ -561,6 +574,9 @@ func newHost(dependencies modules.Dependencies, smDeps modules.Dependencies, cs
 	// Ensure the expired RPC tables get pruned as to not leak memory
 	go h.threadedPruneExpiredPriceTables()
 
+	// Periodically update the prices according to the pricing policy.
+	go h.threadedUpdatePrices()
+
 	return h, nil
 }
 
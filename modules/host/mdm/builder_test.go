@@ -82,6 +82,13 @@ func (tb *testProgramBuilder) AddHasSectorInstruction(merkleRoot crypto.Hash) {
 	tb.staticValues.AddHasSectorInstruction()
 }
 
+// AddProveSegmentInstruction adds a provesegment instruction to the builder,
+// keeping track of running values.
+func (tb *testProgramBuilder) AddProveSegmentInstruction(merkleRoot, seed crypto.Hash) {
+	tb.staticPB.AddProveSegmentInstruction(merkleRoot, seed)
+	tb.staticValues.AddProveSegmentInstruction()
+}
+
 // AddReadOffsetInstruction adds a readoffset instruction to the builder,
 // keeping track of running values.
 func (tb *testProgramBuilder) AddReadOffsetInstruction(length, offset uint64, merkleProof bool) {
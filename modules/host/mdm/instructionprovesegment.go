@@ -0,0 +1,116 @@
+package mdm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// instructionProveSegment is an instruction which returns a segment of a
+// sector together with a Merkle proof for the segment. The index of the
+// segment is derived from a seed chosen by the renter.
+type instructionProveSegment struct {
+	commonInstruction
+
+	merkleRootOffset uint64
+	seedOffset       uint64
+}
+
+// staticDecodeProveSegmentInstruction creates a new 'ProveSegment'
+// instruction from the provided generic instruction.
+func (p *program) staticDecodeProveSegmentInstruction(instruction modules.Instruction) (instruction, error) {
+	// Check specifier.
+	if instruction.Specifier != modules.SpecifierProveSegment {
+		return nil, fmt.Errorf("expected specifier %v but got %v",
+			modules.SpecifierProveSegment, instruction.Specifier)
+	}
+	// Check args.
+	if len(instruction.Args) != modules.RPCIProveSegmentLen {
+		return nil, fmt.Errorf("expected instruction to have len %v but was %v",
+			modules.RPCIProveSegmentLen, len(instruction.Args))
+	}
+	// Read args.
+	rootOffset := binary.LittleEndian.Uint64(instruction.Args[:8])
+	seedOffset := binary.LittleEndian.Uint64(instruction.Args[8:16])
+	return &instructionProveSegment{
+		commonInstruction: commonInstruction{
+			staticData:        p.staticData,
+			staticMerkleProof: true,
+			staticState:       p.staticProgramState,
+		},
+		merkleRootOffset: rootOffset,
+		seedOffset:       seedOffset,
+	}, nil
+}
+
+// Batch declares whether or not this instruction can be batched together with
+// the previous instruction.
+func (i instructionProveSegment) Batch() bool {
+	return false
+}
+
+// Collateral is zero for the ProveSegment instruction.
+func (i *instructionProveSegment) Collateral() types.Currency {
+	return modules.MDMProveSegmentCollateral()
+}
+
+// Cost returns the cost of executing this instruction.
+func (i *instructionProveSegment) Cost() (executionCost, _ types.Currency, err error) {
+	executionCost = modules.MDMProveSegmentCost(i.staticState.priceTable)
+	return
+}
+
+// Memory returns the memory allocated by this instruction beyond the end of its
+// lifetime.
+func (i *instructionProveSegment) Memory() uint64 {
+	return modules.MDMProveSegmentMemory()
+}
+
+// Execute executes the 'ProveSegment' instruction. If the host doesn't store
+// the sector, the output is empty.
+func (i *instructionProveSegment) Execute(prevOutput output) (output, types.Currency) {
+	// Fetch the operands.
+	sectorRoot, err := i.staticData.Hash(i.merkleRootOffset)
+	if err != nil {
+		return errOutput(err), types.ZeroCurrency
+	}
+	seed, err := i.staticData.Hash(i.seedOffset)
+	if err != nil {
+		return errOutput(err), types.ZeroCurrency
+	}
+
+	// Check if the sector exists.
+	ps := i.staticState
+	_, gained := ps.sectors.sectorsGained[sectorRoot]
+	if !gained && !ps.host.HasSector(sectorRoot) {
+		return output{
+			NewSize:       prevOutput.NewSize,       // size stays the same
+			NewMerkleRoot: prevOutput.NewMerkleRoot, // root stays the same
+		}, types.ZeroCurrency
+	}
+
+	// Read the sector and construct the proof.
+	sectorData, err := ps.sectors.readSector(ps.host, sectorRoot)
+	if err != nil {
+		return errOutput(err), types.ZeroCurrency
+	}
+	segmentIndex := modules.ProveSegmentIndex(seed, sectorRoot)
+	start := segmentIndex * crypto.SegmentSize
+	proof := crypto.MerkleRangeProof(sectorData, int(segmentIndex), int(segmentIndex)+1)
+
+	// Return the output.
+	return output{
+		NewSize:       prevOutput.NewSize,       // size stays the same
+		NewMerkleRoot: prevOutput.NewMerkleRoot, // root stays the same
+		Output:        sectorData[start : start+crypto.SegmentSize],
+		Proof:         proof,
+	}, types.ZeroCurrency
+}
+
+// Time returns the execution time of a 'ProveSegment' instruction.
+func (i *instructionProveSegment) Time() (uint64, error) {
+	return modules.MDMTimeProveSegment, nil
+}
@@ -0,0 +1,88 @@
+package mdm
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestInstructionProveSegment tests executing a program with a single
+// ProveSegmentInstruction.
+func TestInstructionProveSegment(t *testing.T) {
+	host := newTestHost()
+	mdm := New(host)
+	defer mdm.Stop()
+
+	// Add a sector to the host.
+	sectorData := fastrand.Bytes(int(modules.SectorSize))
+	sectorRoot := crypto.MerkleRoot(sectorData)
+	host.mu.Lock()
+	host.sectors[sectorRoot] = sectorData
+	host.mu.Unlock()
+
+	// Build the program.
+	so := host.newTestStorageObligation(true)
+	pt := newTestPriceTable()
+	duration := types.BlockHeight(fastrand.Uint64n(5))
+	var seed crypto.Hash
+	fastrand.Read(seed[:])
+	tb := newTestProgramBuilder(pt, duration)
+	tb.AddProveSegmentInstruction(sectorRoot, seed)
+
+	ics := so.ContractSize()
+	imr := so.MerkleRoot()
+
+	// Execute it.
+	outputs, err := mdm.ExecuteProgramWithBuilder(tb, so, duration, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert output.
+	index := modules.ProveSegmentIndex(seed, sectorRoot)
+	segment := sectorData[index*crypto.SegmentSize : (index+1)*crypto.SegmentSize]
+	proof := crypto.MerkleRangeProof(sectorData, int(index), int(index)+1)
+	err = outputs[0].assert(ics, imr, proof, segment, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The proof should be valid for the root.
+	if !crypto.VerifyRangeProof(outputs[0].Output, outputs[0].Proof, int(index), int(index)+1, sectorRoot) {
+		t.Fatal("proof verification failed")
+	}
+
+	// Try again for a sector the host doesn't store. The output should be
+	// empty.
+	tb = newTestProgramBuilder(pt, duration)
+	tb.AddProveSegmentInstruction(crypto.Hash{}, seed)
+	outputs, err = mdm.ExecuteProgramWithBuilder(tb, so, duration, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = outputs[0].assert(ics, imr, []crypto.Hash{}, []byte{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestProveSegmentIndex tests that ProveSegmentIndex is deterministic and
+// returns an index within the bounds of a sector.
+func TestProveSegmentIndex(t *testing.T) {
+	numSegments := modules.SectorSize / crypto.SegmentSize
+	for i := 0; i < 100; i++ {
+		var seed, root crypto.Hash
+		fastrand.Read(seed[:])
+		fastrand.Read(root[:])
+		index := modules.ProveSegmentIndex(seed, root)
+		if index >= numSegments {
+			t.Fatal("index out of bounds", index, numSegments)
+		}
+		if index != modules.ProveSegmentIndex(seed, root) {
+			t.Fatal("index isn't deterministic")
+		}
+	}
+}
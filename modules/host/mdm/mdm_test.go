@@ -231,15 +231,16 @@ func newTestPriceTable() *modules.RPCPriceTable {
 		CollateralCost:       types.NewCurrency64(1),
 
 		// Instruction costs
-		DropSectorsBaseCost: types.NewCurrency64(1),
-		DropSectorsUnitCost: types.NewCurrency64(1),
-		HasSectorBaseCost:   types.NewCurrency64(1),
-		ReadBaseCost:        types.NewCurrency64(1),
-		ReadLengthCost:      types.NewCurrency64(1),
-		SwapSectorCost:      types.NewCurrency64(1),
-		WriteBaseCost:       types.NewCurrency64(1),
-		WriteLengthCost:     types.NewCurrency64(1),
-		WriteStoreCost:      types.NewCurrency64(1),
+		DropSectorsBaseCost:  types.NewCurrency64(1),
+		DropSectorsUnitCost:  types.NewCurrency64(1),
+		HasSectorBaseCost:    types.NewCurrency64(1),
+		ProveSegmentBaseCost: types.NewCurrency64(1),
+		ReadBaseCost:         types.NewCurrency64(1),
+		ReadLengthCost:       types.NewCurrency64(1),
+		SwapSectorCost:       types.NewCurrency64(1),
+		WriteBaseCost:        types.NewCurrency64(1),
+		WriteLengthCost:      types.NewCurrency64(1),
+		WriteStoreCost:       types.NewCurrency64(1),
 
 		// Bandwidth costs
 		DownloadBandwidthCost: types.NewCurrency64(1),
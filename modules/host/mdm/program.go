@@ -93,6 +93,8 @@ func decodeInstruction(p *program, i modules.Instruction) (instruction, error) {
 		return p.staticDecodeDropSectorsInstruction(i)
 	case modules.SpecifierHasSector:
 		return p.staticDecodeHasSectorInstruction(i)
+	case modules.SpecifierProveSegment:
+		return p.staticDecodeProveSegmentInstruction(i)
 	case modules.SpecifierReadSector:
 		return p.staticDecodeReadSectorInstruction(i)
 	case modules.SpecifierReadOffset:
@@ -87,6 +87,19 @@ func (v *TestValues) AddHasSectorInstruction() {
 	v.addInstruction(collateral, cost, types.ZeroCurrency, types.ZeroCurrency, memory, time, newData, readonly, batch)
 }
 
+// AddProveSegmentInstruction adds a provesegment instruction to the builder,
+// keeping track of running values.
+func (v *TestValues) AddProveSegmentInstruction() {
+	collateral := modules.MDMProveSegmentCollateral()
+	cost := modules.MDMProveSegmentCost(v.staticPT)
+	memory := modules.MDMProveSegmentMemory()
+	time := uint64(modules.MDMTimeProveSegment)
+	newData := 2 * crypto.HashSize
+	readonly := true
+	batch := false
+	v.addInstruction(collateral, cost, types.ZeroCurrency, types.ZeroCurrency, memory, time, newData, readonly, batch)
+}
+
 // AddReadOffsetInstruction adds a readoffset instruction to the builder,
 // keeping track of running values.
 func (v *TestValues) AddReadOffsetInstruction(length uint64) {
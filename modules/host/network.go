@@ -281,6 +281,15 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 		conn.Close()
 	}()
 
+	// Reject peers which exceed the RPC rate limit and apply the peer's
+	// bandwidth limit.
+	if err := h.managedAllowRPC(peerKey(conn.RemoteAddr())); err != nil {
+		h.log.Debugf("WARN: incoming conn %v was rejected: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn, release := h.managedLimitConn(conn)
+	defer release()
+
 	// Set an initial duration that is generous, but finite. RPCs can extend
 	// this if desired.
 	err = conn.SetDeadline(time.Now().Add(defaultConnectionDeadline))
@@ -394,6 +403,17 @@ func (h *Host) threadedHandleStream(stream siamux.Stream) {
 		return
 	}
 
+	// Reject peers which exceed the RPC rate limit and apply the peer's
+	// bandwidth limit.
+	if err := h.managedAllowRPC(peerKey(stream.RemoteAddr())); err != nil {
+		if wErr := modules.RPCWriteError(stream, err); wErr != nil {
+			h.managedLogError(wErr)
+		}
+		return
+	}
+	stream, release := h.managedLimitStream(stream, peerKey(stream.RemoteAddr()))
+	defer release()
+
 	// read the RPC id
 	var rpcID types.Specifier
 	err = modules.RPCRead(stream, &rpcID)
@@ -483,5 +503,8 @@ func (h *Host) NetworkMetrics() modules.HostNetworkMetrics {
 		ReviseCalls:       atomic.LoadUint64(&h.atomicReviseCalls),
 		SettingsCalls:     atomic.LoadUint64(&h.atomicSettingsCalls),
 		UnrecognizedCalls: atomic.LoadUint64(&h.atomicUnrecognizedCalls),
+
+		ProgramLimitedCalls: atomic.LoadUint64(&h.staticRateLimiter.atomicProgramLimitedCalls),
+		RateLimitedCalls:    atomic.LoadUint64(&h.staticRateLimiter.atomicRateLimitedCalls),
 	}
 }
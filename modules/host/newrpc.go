@@ -97,6 +97,17 @@ func (h *Host) managedRPCLoopLock(s *rpcSession) error {
 		return err
 	}
 
+	// The renter is known once the challenge signature was verified. Charge
+	// the lock attempt to the renter, unless the RPC loop already did, and
+	// limit the following RPCs of the session per renter as well, even if the
+	// lock can't be acquired.
+	if key := renterKey(so); key != "" && key != s.renterKey {
+		if err := h.managedAllowRPC(key); err != nil {
+			return errors.Compose(err, s.writeError(err))
+		}
+		s.renterKey = key
+	}
+
 	// attempt to lock the storage obligation
 	lockErr := h.managedTryLockStorageObligation(req.ContractID, lockTimeout)
 	if lockErr == nil {
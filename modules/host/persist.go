@@ -25,6 +25,7 @@ type persistence struct {
 	Announced        bool                         `json:"announced"`
 	AutoAddress      modules.NetAddress           `json:"autoaddress"`
 	FinancialMetrics modules.HostFinancialMetrics `json:"financialmetrics"`
+	PricingPolicy    modules.HostPricingPolicy    `json:"pricingpolicy"`
 	PublicKey        types.SiaPublicKey           `json:"publickey"`
 	RevisionNumber   uint64                       `json:"revisionnumber"`
 	SecretKey        crypto.SecretKey             `json:"secretkey"`
@@ -43,6 +44,7 @@ func (h *Host) persistData() persistence {
 		Announced:        h.announced,
 		AutoAddress:      h.autoAddress,
 		FinancialMetrics: h.financialMetrics,
+		PricingPolicy:    h.pricingPolicy,
 		PublicKey:        h.publicKey,
 		RevisionNumber:   h.revisionNumber,
 		SecretKey:        h.secretKey,
@@ -112,6 +114,7 @@ func (h *Host) loadPersistObject(p *persistence) {
 		h.autoAddress = ""
 	}
 	h.financialMetrics = p.FinancialMetrics
+	h.pricingPolicy = p.PricingPolicy
 	h.publicKey = p.PublicKey
 	h.revisionNumber = p.RevisionNumber
 	h.secretKey = p.SecretKey
@@ -0,0 +1,338 @@
+package host
+
+// pricing.go contains the host's pricing engine. If enabled, the engine
+// periodically derives the host's prices from the pricing policy and updates
+// the host's internal settings. The prices are either derived from fiat
+// denominated targets or from a percentile of the prices of the network's
+// active hosts as observed by a local hostdb. The storage price can further be
+// adjusted according to the utilization of the host's storage. Every change is
+// logged together with the reason for it.
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+const (
+	// maxPricingChanges is the number of recent price changes the host keeps
+	// in memory.
+	maxPricingChanges = 100
+
+	// Names of the settings the pricing engine changes. They match the json
+	// tags of the host's internal settings.
+	pricingSettingCollateral        = "collateral"
+	pricingSettingContractPrice     = "mincontractprice"
+	pricingSettingDownloadBandwidth = "mindownloadbandwidthprice"
+	pricingSettingStoragePrice      = "minstorageprice"
+	pricingSettingUploadBandwidth   = "minuploadbandwidthprice"
+)
+
+var (
+	// defaultPricingUpdateInterval is the interval at which the pricing
+	// engine updates the host's prices if the policy doesn't specify one.
+	defaultPricingUpdateInterval = build.Select(build.Var{
+		Standard: time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// minPricingUpdateInterval is the minimum interval at which the pricing
+	// engine updates the host's prices.
+	minPricingUpdateInterval = build.Select(build.Var{
+		Standard: 10 * time.Minute,
+		Dev:      time.Minute,
+		Testing:  100 * time.Millisecond,
+	}).(time.Duration)
+
+	// minPricingNetworkHosts is the minimum number of active hosts the
+	// network source requires to derive prices from.
+	minPricingNetworkHosts = build.Select(build.Var{
+		Standard: 10,
+		Dev:      3,
+		Testing:  3,
+	}).(int)
+
+	// errNoPricingHostDB is returned when the network source is used without a
+	// hostdb.
+	errNoPricingHostDB = errors.New("the network pricing source requires a renter's hostdb")
+)
+
+// pricingUpdate is a price derived by the pricing engine.
+type pricingUpdate struct {
+	setting string
+	price   types.Currency
+	reason  string
+}
+
+// mulFactor multiplies the price with the factor. Unlike MulFloat, the factor
+// is converted using its shortest decimal representation to avoid adding
+// floating point noise to the price.
+func mulFactor(price types.Currency, factor float64) types.Currency {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(factor, 'f', -1, 64))
+	r.Mul(r, new(big.Rat).SetInt(price.Big()))
+	return types.NewCurrency(new(big.Int).Quo(r.Num(), r.Denom()))
+}
+
+// percentile returns the p-th percentile of the prices using the nearest-rank
+// method. The prices are sorted in place.
+func percentile(prices []types.Currency, p float64) types.Currency {
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Cmp(prices[j]) < 0
+	})
+	rank := int(math.Ceil(p / 100 * float64(len(prices))))
+	if rank < 1 {
+		rank = 1
+	}
+	return prices[rank-1]
+}
+
+// computePrices derives the host's prices from the pricing policy. hosts are
+// the network's active hosts and utilization is the fraction of the host's
+// storage in use.
+func computePrices(policy modules.HostPricingPolicy, hosts []modules.HostDBEntry, utilization float64) ([]pricingUpdate, error) {
+	var updates []pricingUpdate
+	switch policy.Source {
+	case modules.HostPricingSourceFiat:
+		rate, err := types.ParseExchangeRate(policy.ExchangeRate)
+		if err != nil || rate == nil {
+			return nil, errors.AddContext(err, "invalid exchange rate")
+		}
+		reason := func(amount float64, unit string) string {
+			return fmt.Sprintf("fiat target of %v %v%v at %v per SC", amount, rate.Symbol(), unit, policy.ExchangeRate)
+		}
+		updates = append(updates,
+			pricingUpdate{pricingSettingContractPrice, rate.ToCurrency(policy.FiatContractPrice), reason(policy.FiatContractPrice, "")},
+			pricingUpdate{pricingSettingDownloadBandwidth, rate.ToCurrency(policy.FiatDownloadPrice).Div(modules.BytesPerTerabyte), reason(policy.FiatDownloadPrice, "/TB")},
+			pricingUpdate{pricingSettingStoragePrice, rate.ToCurrency(policy.FiatStoragePrice).Div(modules.BlockBytesPerMonthTerabyte), reason(policy.FiatStoragePrice, "/TB/month")},
+			pricingUpdate{pricingSettingUploadBandwidth, rate.ToCurrency(policy.FiatUploadPrice).Div(modules.BytesPerTerabyte), reason(policy.FiatUploadPrice, "/TB")},
+		)
+	case modules.HostPricingSourceNetwork:
+		if len(hosts) < minPricingNetworkHosts {
+			return nil, fmt.Errorf("need at least %v active hosts to derive prices from but only %v are known", minPricingNetworkHosts, len(hosts))
+		}
+		reason := fmt.Sprintf("%v percentile of %v active hosts", policy.NetworkPercentile, len(hosts))
+		networkPrice := func(price func(modules.HostExternalSettings) types.Currency) types.Currency {
+			prices := make([]types.Currency, 0, len(hosts))
+			for _, host := range hosts {
+				prices = append(prices, price(host.HostExternalSettings))
+			}
+			return percentile(prices, policy.NetworkPercentile)
+		}
+		updates = append(updates,
+			pricingUpdate{pricingSettingContractPrice, networkPrice(func(s modules.HostExternalSettings) types.Currency { return s.ContractPrice }), reason},
+			pricingUpdate{pricingSettingDownloadBandwidth, networkPrice(func(s modules.HostExternalSettings) types.Currency { return s.DownloadBandwidthPrice }), reason},
+			pricingUpdate{pricingSettingStoragePrice, networkPrice(func(s modules.HostExternalSettings) types.Currency { return s.StoragePrice }), reason},
+			pricingUpdate{pricingSettingUploadBandwidth, networkPrice(func(s modules.HostExternalSettings) types.Currency { return s.UploadBandwidthPrice }), reason},
+		)
+	default:
+		return nil, fmt.Errorf("unknown pricing source '%v'", policy.Source)
+	}
+
+	// Adjust the storage price according to the utilization and bounds.
+	var storage *pricingUpdate
+	for i := range updates {
+		if updates[i].setting == pricingSettingStoragePrice {
+			storage = &updates[i]
+		}
+	}
+	if storage == nil {
+		return nil, errors.New("pricing source didn't derive a storage price")
+	}
+	if policy.TargetUtilization > 0 {
+		factor := math.Max(0, 1+policy.UtilizationAdjustment*(utilization-policy.TargetUtilization))
+		storage.price = mulFactor(storage.price, factor)
+		storage.reason += fmt.Sprintf(", adjusted by %.3f for a utilization of %.1f%% and a target of %.1f%%", factor, utilization*100, policy.TargetUtilization*100)
+	}
+	if storage.price.Cmp(policy.MinStoragePrice) < 0 {
+		storage.price = policy.MinStoragePrice
+		storage.reason += ", raised to the minimum storage price"
+	}
+	if !policy.MaxStoragePrice.IsZero() && storage.price.Cmp(policy.MaxStoragePrice) > 0 {
+		storage.price = policy.MaxStoragePrice
+		storage.reason += ", lowered to the maximum storage price"
+	}
+
+	// Derive the collateral from the storage price.
+	if policy.CollateralRatio > 0 {
+		updates = append(updates, pricingUpdate{
+			setting: pricingSettingCollateral,
+			price:   mulFactor(storage.price, policy.CollateralRatio),
+			reason:  fmt.Sprintf("%v times the storage price", policy.CollateralRatio),
+		})
+	}
+	return updates, nil
+}
+
+// pricingSetting returns a pointer to the setting with the given name.
+func pricingSetting(settings *modules.HostInternalSettings, setting string) *types.Currency {
+	switch setting {
+	case pricingSettingCollateral:
+		return &settings.Collateral
+	case pricingSettingContractPrice:
+		return &settings.MinContractPrice
+	case pricingSettingDownloadBandwidth:
+		return &settings.MinDownloadBandwidthPrice
+	case pricingSettingStoragePrice:
+		return &settings.MinStoragePrice
+	case pricingSettingUploadBandwidth:
+		return &settings.MinUploadBandwidthPrice
+	}
+	build.Critical("unknown pricing setting", setting)
+	return new(types.Currency)
+}
+
+// managedStorageUtilization returns the fraction of the host's storage which
+// is in use.
+func (h *Host) managedStorageUtilization() float64 {
+	var total, remaining uint64
+	for _, sf := range h.StorageFolders() {
+		total += sf.Capacity
+		remaining += sf.CapacityRemaining
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(total-remaining) / float64(total)
+}
+
+// managedApplyPricingPolicy derives the host's prices from the pricing policy
+// and updates the host's settings if the prices changed.
+func (h *Host) managedApplyPricingPolicy() error {
+	h.mu.RLock()
+	policy := h.pricingPolicy
+	hdb := h.pricingHostDB
+	h.mu.RUnlock()
+	if !policy.Enabled {
+		return nil
+	}
+
+	// Derive the prices.
+	var hosts []modules.HostDBEntry
+	if policy.Source == modules.HostPricingSourceNetwork {
+		if hdb == nil {
+			return errNoPricingHostDB
+		}
+		var err error
+		hosts, err = hdb.ActiveHosts()
+		if err != nil {
+			return errors.AddContext(err, "failed to get active hosts")
+		}
+	}
+	updates, err := computePrices(policy, hosts, h.managedStorageUtilization())
+	if err != nil {
+		return err
+	}
+
+	// Apply the changed prices.
+	h.mu.Lock()
+	var changed bool
+	for _, update := range updates {
+		price := pricingSetting(&h.settings, update.setting)
+		if price.Equals(update.price) {
+			continue
+		}
+		change := modules.HostPricingChange{
+			Time:     time.Now(),
+			Setting:  update.setting,
+			OldPrice: *price,
+			NewPrice: update.price,
+			Reason:   update.reason,
+		}
+		h.log.Printf("Pricing engine changed %v from %v to %v: %v", change.Setting, change.OldPrice, change.NewPrice, change.Reason)
+		h.pricingChanges = append(h.pricingChanges, change)
+		*price = update.price
+		changed = true
+	}
+	if len(h.pricingChanges) > maxPricingChanges {
+		h.pricingChanges = h.pricingChanges[len(h.pricingChanges)-maxPricingChanges:]
+	}
+	if !changed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.revisionNumber++
+	err = h.saveSync()
+	h.mu.Unlock()
+	h.managedUpdatePriceTable()
+	return errors.AddContext(err, "prices updated, but failed saving to disk")
+}
+
+// threadedUpdatePrices periodically applies the pricing policy.
+func (h *Host) threadedUpdatePrices() {
+	for {
+		h.mu.RLock()
+		interval := h.pricingPolicy.UpdateInterval
+		h.mu.RUnlock()
+		if interval == 0 {
+			interval = defaultPricingUpdateInterval
+		}
+
+		// Block until next cycle.
+		select {
+		case <-h.tg.StopChan():
+			return
+		case <-time.After(interval):
+		}
+
+		func() {
+			if err := h.tg.Add(); err != nil {
+				return
+			}
+			defer h.tg.Done()
+			if err := h.managedApplyPricingPolicy(); err != nil {
+				h.log.Println("WARN: failed to apply pricing policy:", err)
+			}
+		}()
+	}
+}
+
+// PricingPolicy returns the policy of the host's pricing engine and the most
+// recent price changes it made.
+func (h *Host) PricingPolicy() (modules.HostPricingPolicy, []modules.HostPricingChange) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	changes := make([]modules.HostPricingChange, len(h.pricingChanges))
+	copy(changes, h.pricingChanges)
+	return h.pricingPolicy, changes
+}
+
+// SetPricingHostDB sets the hostdb the pricing engine observes the prices of
+// the network with.
+func (h *Host) SetPricingHostDB(hdb modules.HostPricingHostDB) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pricingHostDB = hdb
+}
+
+// SetPricingPolicy sets the policy of the host's pricing engine. An enabled
+// policy is applied right away.
+func (h *Host) SetPricingPolicy(policy modules.HostPricingPolicy) error {
+	if err := h.tg.Add(); err != nil {
+		return err
+	}
+	defer h.tg.Done()
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if policy.UpdateInterval != 0 && policy.UpdateInterval < minPricingUpdateInterval {
+		return fmt.Errorf("update interval can't be lower than %v", minPricingUpdateInterval)
+	}
+	h.mu.Lock()
+	h.pricingPolicy = policy
+	err := h.saveSync()
+	h.mu.Unlock()
+	if err != nil {
+		return errors.AddContext(err, "failed to save pricing policy")
+	}
+	return h.managedApplyPricingPolicy()
+}
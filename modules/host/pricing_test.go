@@ -0,0 +1,205 @@
+package host
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// testPricingHostDB is a hostdb which returns a fixed set of hosts.
+type testPricingHostDB []modules.HostDBEntry
+
+// ActiveHosts implements modules.HostPricingHostDB.
+func (hdb testPricingHostDB) ActiveHosts() ([]modules.HostDBEntry, error) {
+	return hdb, nil
+}
+
+// testPricingHosts returns n hosts whose prices are i SC for i = 1..n.
+func testPricingHosts(n int) testPricingHostDB {
+	hosts := make(testPricingHostDB, n)
+	for i := range hosts {
+		price := types.SiacoinPrecision.Mul64(uint64(i + 1))
+		hosts[i].ContractPrice = price
+		hosts[i].DownloadBandwidthPrice = price
+		hosts[i].StoragePrice = price
+		hosts[i].UploadBandwidthPrice = price
+	}
+	return hosts
+}
+
+// updatePrice returns the price of the setting within the updates.
+func updatePrice(t *testing.T, updates []pricingUpdate, setting string) types.Currency {
+	t.Helper()
+	for _, update := range updates {
+		if update.setting == setting {
+			return update.price
+		}
+	}
+	t.Fatalf("no update for %v", setting)
+	return types.ZeroCurrency
+}
+
+// TestComputePrices is a unit test for computePrices.
+func TestComputePrices(t *testing.T) {
+	t.Parallel()
+
+	// Fiat source. At 0.01 USD per SC, 1 USD is 100 SC.
+	policy := modules.HostPricingPolicy{
+		Enabled:           true,
+		Source:            modules.HostPricingSourceFiat,
+		ExchangeRate:      "0.01 USD",
+		FiatContractPrice: 1,
+		FiatDownloadPrice: 2,
+		FiatStoragePrice:  3,
+		FiatUploadPrice:   4,
+	}
+	updates, err := computePrices(policy, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 4 {
+		t.Fatal("expected 4 updates but got", len(updates))
+	}
+	sc := types.SiacoinPrecision
+	if p := updatePrice(t, updates, pricingSettingContractPrice); !p.Equals(sc.Mul64(100)) {
+		t.Fatal("wrong contract price", p)
+	}
+	if p := updatePrice(t, updates, pricingSettingDownloadBandwidth); !p.Equals(sc.Mul64(200).Div(modules.BytesPerTerabyte)) {
+		t.Fatal("wrong download price", p)
+	}
+	if p := updatePrice(t, updates, pricingSettingStoragePrice); !p.Equals(sc.Mul64(300).Div(modules.BlockBytesPerMonthTerabyte)) {
+		t.Fatal("wrong storage price", p)
+	}
+	if p := updatePrice(t, updates, pricingSettingUploadBandwidth); !p.Equals(sc.Mul64(400).Div(modules.BytesPerTerabyte)) {
+		t.Fatal("wrong upload price", p)
+	}
+
+	// Network source.
+	policy = modules.HostPricingPolicy{
+		Enabled:           true,
+		Source:            modules.HostPricingSourceNetwork,
+		NetworkPercentile: 50,
+	}
+	if _, err := computePrices(policy, testPricingHosts(minPricingNetworkHosts-1), 0); err == nil {
+		t.Fatal("expected error for too few hosts")
+	}
+	updates, err = computePrices(policy, testPricingHosts(10), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := updatePrice(t, updates, pricingSettingContractPrice); !p.Equals(sc.Mul64(5)) {
+		t.Fatal("wrong contract price", p)
+	}
+
+	// Utilization adjustment, collateral and bounds. A utilization of 90% with
+	// a target of 50% and an adjustment of 0.5 increases the storage price by
+	// 20%.
+	policy.TargetUtilization = 0.5
+	policy.UtilizationAdjustment = 0.5
+	policy.CollateralRatio = 2
+	updates, err = computePrices(policy, testPricingHosts(10), 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := updatePrice(t, updates, pricingSettingStoragePrice); !p.Equals(sc.Mul64(6)) {
+		t.Fatal("wrong storage price", p)
+	}
+	if p := updatePrice(t, updates, pricingSettingCollateral); !p.Equals(sc.Mul64(12)) {
+		t.Fatal("wrong collateral", p)
+	}
+	policy.MaxStoragePrice = sc.Mul64(4)
+	updates, err = computePrices(policy, testPricingHosts(10), 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := updatePrice(t, updates, pricingSettingStoragePrice); !p.Equals(sc.Mul64(4)) {
+		t.Fatal("storage price wasn't capped", p)
+	}
+	policy.MaxStoragePrice = types.ZeroCurrency
+	policy.MinStoragePrice = sc.Mul64(8)
+	updates, err = computePrices(policy, testPricingHosts(10), 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := updatePrice(t, updates, pricingSettingStoragePrice); !p.Equals(sc.Mul64(8)) {
+		t.Fatal("storage price wasn't raised", p)
+	}
+}
+
+// TestHostPricingPolicy tests setting a pricing policy on the host.
+func TestHostPricingPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	ht, err := blankHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := ht.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Invalid policies are rejected.
+	policy := modules.HostPricingPolicy{
+		Enabled: true,
+		Source:  "unknown",
+	}
+	if err := ht.host.SetPricingPolicy(policy); err == nil {
+		t.Fatal("expected error for unknown source")
+	}
+
+	// The network source requires a hostdb.
+	policy.Source = modules.HostPricingSourceNetwork
+	policy.NetworkPercentile = 100
+	if err := ht.host.SetPricingPolicy(policy); err != errNoPricingHostDB {
+		t.Fatal("expected errNoPricingHostDB but got", err)
+	}
+
+	// With a hostdb the prices are updated right away.
+	ht.host.SetPricingHostDB(testPricingHosts(10))
+	if err := ht.host.SetPricingPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	expected := types.SiacoinPrecision.Mul64(10)
+	if is := ht.host.InternalSettings(); !is.MinContractPrice.Equals(expected) || !is.MinStoragePrice.Equals(expected) {
+		t.Fatal("prices weren't updated", is.MinContractPrice, is.MinStoragePrice)
+	}
+	p, changes := ht.host.PricingPolicy()
+	if p.Source != modules.HostPricingSourceNetwork || len(changes) != 4 {
+		t.Fatal("unexpected policy or changes", p, changes)
+	}
+
+	// The prices follow the network.
+	ht.host.SetPricingHostDB(testPricingHosts(20))
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if is := ht.host.InternalSettings(); !is.MinContractPrice.Equals(types.SiacoinPrecision.Mul64(20)) {
+			return errors.New("prices weren't updated")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The policy is persisted.
+	if err := ht.host.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ht.host, err = New(ht.cs, ht.gateway, ht.tpool, ht.wallet, ht.mux, "localhost:0", filepath.Join(ht.persistDir, modules.HostDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := ht.host.PricingPolicy(); p.Source != modules.HostPricingSourceNetwork || p.NetworkPercentile != 100 {
+		t.Fatal("policy wasn't persisted", p)
+	}
+}
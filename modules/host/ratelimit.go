@@ -0,0 +1,256 @@
+package host
+
+// ratelimit.go contains the host's per peer and per account limits. They
+// prevent single renters or ephemeral accounts from monopolising the host's
+// bandwidth, MDM execution time and RPC handling. Peers are identified by
+// their IP, renters by the public key of the contract they lock and accounts
+// by their id. RPCs are limited by a token bucket per identity. Bandwidth is
+// limited per peer and additionally per account for the streams of the
+// ExecuteProgram RPC.
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/ratelimit"
+	"gitlab.com/NebulousLabs/siamux"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+)
+
+const (
+	// bandwidthPacketSize is the packet size of the per peer and per account
+	// bandwidth limits.
+	bandwidthPacketSize = 4 * 4096
+)
+
+var (
+	// rateLimitPruneInterval is the interval at which idle token buckets are
+	// removed.
+	rateLimitPruneInterval = build.Select(build.Var{
+		Standard: time.Minute,
+		Dev:      time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// errProgramLimitReached is returned when an account tries to execute more
+	// programs concurrently than allowed.
+	errProgramLimitReached = errors.New("account reached the maximum number of concurrent programs")
+
+	// errRPCRateLimited is returned when a peer, renter or account exceeds the
+	// RPC rate limit.
+	errRPCRateLimited = errors.New("RPC rate limit exceeded")
+)
+
+type (
+	// hostRateLimiter tracks the usage of the host by peers and accounts.
+	hostRateLimiter struct {
+		atomicProgramLimitedCalls uint64
+		atomicRateLimitedCalls    uint64
+
+		bandwidths map[string]*sharedBandwidth
+		buckets    map[string]*tokenBucket
+		lastPrune  time.Time
+		programs   map[modules.AccountID]uint64
+		mu         sync.Mutex
+	}
+
+	// sharedBandwidth is the bandwidth limit shared by all connections and
+	// streams of a peer or account.
+	sharedBandwidth struct {
+		rl   *ratelimit.RateLimit
+		refs uint64
+	}
+
+	// tokenBucket is a token bucket which is refilled at a constant rate. Its
+	// capacity is the number of tokens added per second.
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+	}
+)
+
+// newHostRateLimiter creates a new hostRateLimiter.
+func newHostRateLimiter() *hostRateLimiter {
+	return &hostRateLimiter{
+		bandwidths: make(map[string]*sharedBandwidth),
+		buckets:    make(map[string]*tokenBucket),
+		lastPrune:  time.Now(),
+		programs:   make(map[modules.AccountID]uint64),
+	}
+}
+
+// refill adds the tokens which accumulated since the last refill to the
+// bucket.
+func (b *tokenBucket) refill(now time.Time, rate float64) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > rate {
+		b.tokens = rate
+	}
+	b.last = now
+}
+
+// peerKey returns the key which identifies the peer at the given address.
+func peerKey(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "peer:" + addr.String()
+	}
+	return "peer:" + host
+}
+
+// renterKey returns the key which identifies the renter of a storage
+// obligation. An empty key is returned if the obligation doesn't contain a
+// revision with the renter's public key.
+func renterKey(so storageObligation) string {
+	if len(so.RevisionTransactionSet) == 0 {
+		return ""
+	}
+	txn := so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1]
+	if len(txn.FileContractRevisions) == 0 || len(txn.FileContractRevisions[0].UnlockConditions.PublicKeys) == 0 {
+		return ""
+	}
+	return "renter:" + txn.FileContractRevisions[0].UnlockConditions.PublicKeys[0].String()
+}
+
+// accountKey returns the key which identifies an ephemeral account.
+func accountKey(id modules.AccountID) string {
+	return "account:" + id.SPK().String()
+}
+
+// allowRPC takes a token from the buckets of all keys. It returns false if one
+// of the buckets is empty. A rate of 0 disables the limit.
+func (rl *hostRateLimiter) allowRPC(rate uint64, keys ...string) bool {
+	if rate == 0 {
+		return true
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+
+	// Remove idle buckets. A bucket which wasn't used for a second is full and
+	// can be recreated when needed.
+	if now.Sub(rl.lastPrune) > rateLimitPruneInterval {
+		for key, b := range rl.buckets {
+			if now.Sub(b.last) > time.Second {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.lastPrune = now
+	}
+
+	// Only take tokens if all buckets contain one.
+	buckets := make([]*tokenBucket, 0, len(keys))
+	for _, key := range keys {
+		b, exists := rl.buckets[key]
+		if !exists {
+			b = &tokenBucket{tokens: float64(rate), last: now}
+			rl.buckets[key] = b
+		}
+		b.refill(now, float64(rate))
+		if b.tokens < 1 {
+			atomic.AddUint64(&rl.atomicRateLimitedCalls, 1)
+			return false
+		}
+		buckets = append(buckets, b)
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true
+}
+
+// startProgram reserves one of the account's program slots. The returned
+// function releases it. A limit of 0 disables the limit.
+func (rl *hostRateLimiter) startProgram(id modules.AccountID, limit uint64) (func(), error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if limit != 0 && rl.programs[id] >= limit {
+		atomic.AddUint64(&rl.atomicProgramLimitedCalls, 1)
+		return nil, errProgramLimitReached
+	}
+	rl.programs[id]++
+	return func() {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		rl.programs[id]--
+		if rl.programs[id] == 0 {
+			delete(rl.programs, id)
+		}
+	}, nil
+}
+
+// bandwidthLimit returns the bandwidth limit of the peer or account with the
+// given key set to the given bytes per second. The returned function releases
+// the limit once the connection or stream is closed.
+func (rl *hostRateLimiter) bandwidthLimit(key string, bps uint64) (*ratelimit.RateLimit, func()) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	sb, exists := rl.bandwidths[key]
+	if !exists {
+		sb = &sharedBandwidth{rl: ratelimit.NewRateLimit(0, 0, 0)}
+		rl.bandwidths[key] = sb
+	}
+	sb.rl.SetLimits(int64(bps), int64(bps), bandwidthPacketSize)
+	sb.refs++
+	return sb.rl, func() {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		sb.refs--
+		if sb.refs == 0 {
+			delete(rl.bandwidths, key)
+		}
+	}
+}
+
+// managedAllowRPC returns errRPCRateLimited if one of the given peers,
+// renters or accounts exceeded the RPC rate limit.
+func (h *Host) managedAllowRPC(keys ...string) error {
+	h.mu.RLock()
+	rate := h.settings.PeerRPCRateLimit
+	h.mu.RUnlock()
+	if !h.staticRateLimiter.allowRPC(rate, keys...) {
+		return errRPCRateLimited
+	}
+	return nil
+}
+
+// managedStartProgram reserves a program slot for the account. The returned
+// function needs to be called once the program is done.
+func (h *Host) managedStartProgram(id modules.AccountID) (func(), error) {
+	h.mu.RLock()
+	limit := h.settings.MaxConcurrentPrograms
+	h.mu.RUnlock()
+	return h.staticRateLimiter.startProgram(id, limit)
+}
+
+// managedLimitConn applies the peer's bandwidth limit to the connection. The
+// returned function needs to be called once the connection is closed.
+func (h *Host) managedLimitConn(conn net.Conn) (net.Conn, func()) {
+	h.mu.RLock()
+	bps := h.settings.PeerBandwidthLimit
+	h.mu.RUnlock()
+	if bps == 0 {
+		return conn, func() {}
+	}
+	rl, release := h.staticRateLimiter.bandwidthLimit(peerKey(conn.RemoteAddr()), bps)
+	return ratelimit.NewRLConn(conn, rl, h.tg.StopChan()), release
+}
+
+// managedLimitStream applies the bandwidth limit of the peer or account with
+// the given key to the stream. The returned function needs to be called once
+// the stream is closed.
+func (h *Host) managedLimitStream(stream siamux.Stream, key string) (siamux.Stream, func()) {
+	h.mu.RLock()
+	bps := h.settings.PeerBandwidthLimit
+	h.mu.RUnlock()
+	if bps == 0 {
+		return stream, func() {}
+	}
+	rl, release := h.staticRateLimiter.bandwidthLimit(key, bps)
+	return ratelimit.NewRLStream(stream, rl, h.tg.StopChan()), release
+}
@@ -0,0 +1,336 @@
+package host
+
+import (
+	"crypto/cipher"
+	"net"
+	"strings"
+	"testing"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestHostRateLimiterAllowRPC is a unit test for allowRPC.
+func TestHostRateLimiterAllowRPC(t *testing.T) {
+	t.Parallel()
+	rl := newHostRateLimiter()
+
+	// A rate of 0 disables the limit.
+	for i := 0; i < 10; i++ {
+		if !rl.allowRPC(0, "peer:a") {
+			t.Fatal("RPC was limited without a limit")
+		}
+	}
+
+	// A new bucket allows a burst of up to the rate.
+	for i := 0; i < 3; i++ {
+		if !rl.allowRPC(3, "peer:a") {
+			t.Fatal("RPC was limited within the burst", i)
+		}
+	}
+	if rl.allowRPC(3, "peer:a") {
+		t.Fatal("RPC wasn't limited after the burst")
+	}
+
+	// Other peers are not affected.
+	if !rl.allowRPC(3, "peer:b") {
+		t.Fatal("RPC of other peer was limited")
+	}
+
+	// If one bucket is empty, no tokens are taken from the others.
+	if rl.allowRPC(3, "peer:b", "peer:a") {
+		t.Fatal("RPC wasn't limited by empty bucket")
+	}
+	if tokens := rl.buckets["peer:b"].tokens; tokens < 2 {
+		t.Fatal("token was taken from bucket of rejected RPC", tokens)
+	}
+	if limited := rl.atomicRateLimitedCalls; limited != 2 {
+		t.Fatal("expected 2 rate limited calls but got", limited)
+	}
+}
+
+// TestRenterKey checks that renterKey doesn't panic for storage obligations
+// without a renter's public key.
+func TestRenterKey(t *testing.T) {
+	t.Parallel()
+	var so storageObligation
+	if key := renterKey(so); key != "" {
+		t.Fatal("expected empty key without revisions", key)
+	}
+	so.RevisionTransactionSet = []types.Transaction{{}}
+	if key := renterKey(so); key != "" {
+		t.Fatal("expected empty key without revision", key)
+	}
+	so.RevisionTransactionSet[0].FileContractRevisions = []types.FileContractRevision{{}}
+	if key := renterKey(so); key != "" {
+		t.Fatal("expected empty key without public keys", key)
+	}
+	_, pk := crypto.GenerateKeyPair()
+	spk := types.Ed25519PublicKey(pk)
+	so.RevisionTransactionSet[0].FileContractRevisions[0].UnlockConditions.PublicKeys = []types.SiaPublicKey{spk}
+	if key := renterKey(so); key != "renter:"+spk.String() {
+		t.Fatal("wrong key", key)
+	}
+}
+
+// TestHostRateLimiterStartProgram is a unit test for startProgram.
+func TestHostRateLimiterStartProgram(t *testing.T) {
+	t.Parallel()
+	rl := newHostRateLimiter()
+	var id modules.AccountID
+
+	finish1, err := rl.startProgram(id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish2, err := rl.startProgram(id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rl.startProgram(id, 2); err != errProgramLimitReached {
+		t.Fatal("expected errProgramLimitReached but got", err)
+	}
+	if limited := rl.atomicProgramLimitedCalls; limited != 1 {
+		t.Fatal("expected 1 program limited call but got", limited)
+	}
+
+	// Finishing a program frees a slot.
+	finish1()
+	finish3, err := rl.startProgram(id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish2()
+	finish3()
+	if len(rl.programs) != 0 {
+		t.Fatal("finished programs weren't removed", rl.programs)
+	}
+}
+
+// TestHostRateLimiterBandwidth is a unit test for bandwidthLimit.
+func TestHostRateLimiterBandwidth(t *testing.T) {
+	t.Parallel()
+	rl := newHostRateLimiter()
+
+	// Connections of the same peer share a limit.
+	rl1, release1 := rl.bandwidthLimit("peer:a", 1000)
+	rl2, release2 := rl.bandwidthLimit("peer:a", 1000)
+	if rl1 != rl2 {
+		t.Fatal("connections of the same peer don't share a limit")
+	}
+	rl3, release3 := rl.bandwidthLimit("peer:b", 1000)
+	if rl3 == rl1 {
+		t.Fatal("connections of different peers share a limit")
+	}
+
+	// Streams of an account share a limit which is separate from the limits
+	// of the peers.
+	aid, _ := modules.NewAccountID()
+	rl4, release4 := rl.bandwidthLimit(accountKey(aid), 1000)
+	rl5, release5 := rl.bandwidthLimit(accountKey(aid), 1000)
+	if rl4 != rl5 {
+		t.Fatal("streams of the same account don't share a limit")
+	}
+	if rl4 == rl1 || rl4 == rl3 {
+		t.Fatal("account shares a limit with a peer")
+	}
+
+	// The limit is removed once all connections are released.
+	release1()
+	if _, exists := rl.bandwidths["peer:a"]; !exists {
+		t.Fatal("limit was removed while still in use")
+	}
+	release2()
+	release3()
+	release4()
+	release5()
+	if len(rl.bandwidths) != 0 {
+		t.Fatal("released limits weren't removed", rl.bandwidths)
+	}
+}
+
+// addrConn is a net.Conn with a custom remote address.
+type addrConn struct {
+	net.Conn
+	staticAddr net.Addr
+}
+
+// RemoteAddr implements net.Conn.
+func (c addrConn) RemoteAddr() net.Addr { return c.staticAddr }
+
+// rpcLoopTestSession is a minimal renter side of an RPC loop session.
+type rpcLoopTestSession struct {
+	aead      cipher.AEAD
+	challenge [16]byte
+	conn      net.Conn
+	done      chan error
+}
+
+// newRPCLoopTestSession starts an RPC loop session with the host from the
+// provided peer address.
+func newRPCLoopTestSession(t *testing.T, h *Host, addr string) *rpcLoopTestSession {
+	t.Helper()
+	renterConn, hostConn := net.Pipe()
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &rpcLoopTestSession{
+		conn: renterConn,
+		done: make(chan error, 1),
+	}
+	go func() {
+		s.done <- h.managedRPCLoop(addrConn{Conn: hostConn, staticAddr: tcpAddr})
+		hostConn.Close()
+	}()
+
+	// Perform the key exchange and read the challenge.
+	xsk, xpk := crypto.GenerateX25519KeyPair()
+	req := modules.LoopKeyExchangeRequest{
+		PublicKey: xpk,
+		Ciphers:   []types.Specifier{modules.CipherChaCha20Poly1305},
+	}
+	if err := encoding.NewEncoder(renterConn).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	var resp modules.LoopKeyExchangeResponse
+	if err := encoding.NewDecoder(renterConn, encoding.DefaultAllocLimit).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	cipherKey := crypto.DeriveSharedSecret(xsk, resp.PublicKey)
+	s.aead, err = chacha20poly1305.New(cipherKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var challengeReq modules.LoopChallengeRequest
+	if err := modules.ReadRPCMessage(renterConn, s.aead, &challengeReq, modules.RPCMinLen); err != nil {
+		t.Fatal(err)
+	}
+	s.challenge = challengeReq.Challenge
+	return s
+}
+
+// call calls the RPC with the provided id.
+func (s *rpcLoopTestSession) call(id types.Specifier, req, resp interface{}) error {
+	if err := modules.WriteRPCRequest(s.conn, s.aead, id, req); err != nil {
+		return err
+	}
+	return modules.ReadRPCResponse(s.conn, s.aead, resp, modules.RPCMinLen)
+}
+
+// lock calls the Lock RPC for the provided contract.
+func (s *rpcLoopTestSession) lock(id types.FileContractID, sk crypto.SecretKey) error {
+	sig := crypto.SignHash(crypto.HashAll(modules.RPCChallengePrefix, s.challenge), sk)
+	req := modules.LoopLockRequest{
+		ContractID: id,
+		Signature:  sig[:],
+		Timeout:    100,
+	}
+	var resp modules.LoopLockResponse
+	if err := s.call(modules.RPCLoopLock, req, &resp); err != nil {
+		return err
+	}
+	s.challenge = resp.NewChallenge
+	if !resp.Acquired {
+		return errors.New("contract wasn't locked")
+	}
+	return nil
+}
+
+// settings calls the Settings RPC.
+func (s *rpcLoopTestSession) settings() error {
+	var resp modules.LoopSettingsResponse
+	return s.call(modules.RPCLoopSettings, nil, &resp)
+}
+
+// close exits the RPC loop and waits for the host to finish the session.
+func (s *rpcLoopTestSession) close() error {
+	err := modules.WriteRPCRequest(s.conn, s.aead, modules.RPCLoopExit, nil)
+	return errors.Compose(err, <-s.done, s.conn.Close())
+}
+
+// TestRPCLoopRateLimit checks that the RPC loop limits the RPCs of a session
+// both per peer and per renter.
+func TestRPCLoopRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rhp, err := newRenterHostPair(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rhp.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	h := rhp.staticHT.host
+	is := h.InternalSettings()
+	is.PeerRPCRateLimit = 4
+	if err := h.SetInternalSettings(is); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lock the contract from the first peer. The Lock RPC and the following
+	// RPC are charged to the peer and the renter.
+	s1 := newRPCLoopTestSession(t, h, "1.1.1.1:1")
+	if err := s1.lock(rhp.staticFCID, rhp.staticRenterSK); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.settings(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A Lock RPC with an invalid signature isn't charged to the renter.
+	s2 := newRPCLoopTestSession(t, h, "2.2.2.2:2")
+	otherSK, _ := crypto.GenerateKeyPair()
+	if err := s2.lock(rhp.staticFCID, otherSK); err == nil {
+		t.Fatal("expected lock with invalid signature to fail")
+	}
+	<-s2.done
+
+	// The renter's limit applies to the session of another peer. The renter
+	// has 2 tokens left, one for the Lock RPC and one for the following RPC.
+	s3 := newRPCLoopTestSession(t, h, "3.3.3.3:3")
+	if err := s3.lock(rhp.staticFCID, rhp.staticRenterSK); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.settings(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.settings(); err == nil || !strings.Contains(err.Error(), errRPCRateLimited.Error()) {
+		t.Fatal("expected the renter to be rate limited but got", err)
+	}
+	<-s3.done
+
+	// A renter which locks the contract again after being limited is limited
+	// before the contract is locked.
+	s4 := newRPCLoopTestSession(t, h, "4.4.4.4:4")
+	if err := s4.lock(rhp.staticFCID, rhp.staticRenterSK); err == nil || !strings.Contains(err.Error(), errRPCRateLimited.Error()) {
+		t.Fatal("expected the Lock RPC to be rate limited but got", err)
+	}
+	<-s4.done
+
+	// Sessions of the first peer which don't lock a contract are still
+	// limited per peer. The peer has 2 tokens left.
+	s5 := newRPCLoopTestSession(t, h, "1.1.1.1:5")
+	for i := 0; i < 2; i++ {
+		if err := s5.settings(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s5.settings(); err == nil || !strings.Contains(err.Error(), errRPCRateLimited.Error()) {
+		t.Fatal("expected the peer to be rate limited but got", err)
+	}
+	<-s5.done
+}
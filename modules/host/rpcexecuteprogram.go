@@ -70,6 +70,19 @@ func (h *Host) managedRPCExecuteProgram(stream siamux.Stream) error {
 		}()
 	}()
 
+	// Enforce the account's limits. This happens after the refund was
+	// scheduled to refund the payment of rejected programs.
+	if err := h.managedAllowRPC(accountKey(refundAccount)); err != nil {
+		return err
+	}
+	finishProgram, err := h.managedStartProgram(refundAccount)
+	if err != nil {
+		return err
+	}
+	defer finishProgram()
+	stream, releaseStream := h.managedLimitStream(stream, accountKey(refundAccount))
+	defer releaseStream()
+
 	// Read request
 	var epr modules.RPCExecuteProgramRequest
 	err = modules.RPCReadMaxLen(stream, &epr, maxRPCExecuteProgramRequestSize)
@@ -22,6 +22,11 @@ type rpcSession struct {
 	aead      cipher.AEAD
 	so        storageObligation
 	challenge [16]byte
+
+	// renterKey identifies the renter of the most recent Lock RPC with a
+	// valid challenge signature for the host's rate limits. RPCs before the
+	// first Lock RPC are only limited per peer.
+	renterKey string
 }
 
 // extendDeadline extends the read/write deadline on the underlying connection
@@ -140,9 +145,18 @@ func (h *Host) managedRPCLoop(conn net.Conn) error {
 		} else if id == modules.RPCLoopExit {
 			return nil
 		}
-		if rpcFn, ok := rpcs[id]; !ok {
+		rpcFn, ok := rpcs[id]
+		if !ok {
 			return errors.New("invalid or unknown RPC ID: " + id.String())
-		} else if err := rpcFn(s); err != nil {
+		}
+		keys := []string{peerKey(conn.RemoteAddr())}
+		if s.renterKey != "" {
+			keys = append(keys, s.renterKey)
+		}
+		if err := h.managedAllowRPC(keys...); err != nil {
+			return errors.Compose(err, s.writeError(err))
+		}
+		if err := rpcFn(s); err != nil {
 			return extendErr("incoming RPC"+id.String()+" failed: ", err)
 		}
 	}
@@ -1472,6 +1472,36 @@ func (h *Host) StorageObligations() (sos []modules.StorageObligation) {
 	return sos
 }
 
+// StorageObligationsQuery returns the page of storage obligations matching the
+// query together with the total number of matching obligations.
+func (h *Host) StorageObligationsQuery(query modules.HostStorageObligationQuery) ([]modules.StorageObligation, uint64, error) {
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sos := make([]modules.StorageObligation, 0)
+	err := h.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketStorageObligations)
+		return b.ForEach(func(idBytes, soBytes []byte) error {
+			var so storageObligation
+			err := json.Unmarshal(soBytes, &so)
+			if err != nil {
+				return build.ExtendErr("unable to unmarshal storage obligation:", err)
+			}
+			if mso := so.StorageObligation(); query.Matches(mso) {
+				sos = append(sos, mso)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, errors.AddContext(err, "database failed to provide storage obligations")
+	}
+	return query.Apply(sos), uint64(len(sos)), nil
+}
+
 // StorageObligation returns the storage obligation matching the id or
 // an error if it does not exist
 func (h *Host) StorageObligation(obligationID types.FileContractID) (modules.StorageObligation, error) {
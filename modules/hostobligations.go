@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.sia.tech/siad/types"
+)
+
+const (
+	// Proof outcomes a storage obligation query can filter by.
+	//
+	// ProofOutcomeConfirmed matches obligations whose storage proof was
+	// confirmed on the blockchain.
+	ProofOutcomeConfirmed = "confirmed"
+	// ProofOutcomeMissed matches obligations which failed because the host
+	// didn't submit a storage proof in time.
+	ProofOutcomeMissed = "missed"
+	// ProofOutcomePending matches unresolved obligations whose storage proof
+	// wasn't confirmed yet.
+	ProofOutcomePending = "pending"
+)
+
+const (
+	// Fields storage obligations can be sorted by.
+	ObligationSortByDataSize    = "datasize"
+	ObligationSortByExpiration  = "expiration"
+	ObligationSortByID          = "id"
+	ObligationSortByNegotiation = "negotiation"
+	ObligationSortByRevenue     = "revenue"
+)
+
+// HostStorageObligationQuery filters, sorts and pages the host's storage
+// obligations. The zero value matches all obligations in the order of their
+// ids.
+type HostStorageObligationQuery struct {
+	// Statuses are the obligation statuses to match, e.g. "succeeded" or
+	// "obligationSucceeded". An empty slice matches all statuses.
+	Statuses []string
+
+	// Block height ranges of the expiration and negotiation heights. A zero
+	// maximum means no upper bound.
+	MinExpirationHeight  types.BlockHeight
+	MaxExpirationHeight  types.BlockHeight
+	MinNegotiationHeight types.BlockHeight
+	MaxNegotiationHeight types.BlockHeight
+
+	// Bounds of the potential revenue from the renter. A zero maximum means no
+	// upper bound.
+	MinRevenue types.Currency
+	MaxRevenue types.Currency
+
+	// ProofOutcome is one of the ProofOutcome constants or empty to match all
+	// obligations.
+	ProofOutcome string
+
+	// SortBy is one of the ObligationSortBy constants. Obligations are sorted in
+	// ascending order unless SortDesc is set.
+	SortBy   string
+	SortDesc bool
+
+	// Offset is the number of matching obligations to skip and Limit the
+	// maximum number of obligations to return. A Limit of 0 means no limit.
+	Offset uint64
+	Limit  uint64
+}
+
+// PotentialRevenue returns the sum of the revenue the host expects from the
+// renter for the storage obligation.
+func (so StorageObligation) PotentialRevenue() types.Currency {
+	return so.PotentialAccountFunding.Add(so.PotentialDownloadRevenue).Add(so.PotentialStorageRevenue).Add(so.PotentialUploadRevenue)
+}
+
+// normalizeObligationStatus strips the "obligation" prefix of a status and
+// converts it to lower case.
+func normalizeObligationStatus(status string) string {
+	return strings.TrimPrefix(strings.ToLower(status), "obligation")
+}
+
+// Validate returns an error if the query contains unknown statuses, proof
+// outcomes or sort fields.
+func (q HostStorageObligationQuery) Validate() error {
+	for _, status := range q.Statuses {
+		switch normalizeObligationStatus(status) {
+		case "unresolved", "rejected", "succeeded", "failed":
+		default:
+			return fmt.Errorf("unknown obligation status '%v'", status)
+		}
+	}
+	switch q.ProofOutcome {
+	case "", ProofOutcomeConfirmed, ProofOutcomeMissed, ProofOutcomePending:
+	default:
+		return fmt.Errorf("unknown proof outcome '%v'", q.ProofOutcome)
+	}
+	switch q.SortBy {
+	case "", ObligationSortByDataSize, ObligationSortByExpiration, ObligationSortByID, ObligationSortByNegotiation, ObligationSortByRevenue:
+	default:
+		return fmt.Errorf("unknown sort field '%v'", q.SortBy)
+	}
+	return nil
+}
+
+// Matches returns whether the storage obligation matches the query's filters.
+func (q HostStorageObligationQuery) Matches(so StorageObligation) bool {
+	if len(q.Statuses) > 0 {
+		var match bool
+		for _, status := range q.Statuses {
+			match = match || normalizeObligationStatus(status) == normalizeObligationStatus(so.ObligationStatus)
+		}
+		if !match {
+			return false
+		}
+	}
+	if so.ExpirationHeight < q.MinExpirationHeight || (q.MaxExpirationHeight != 0 && so.ExpirationHeight > q.MaxExpirationHeight) {
+		return false
+	}
+	if so.NegotiationHeight < q.MinNegotiationHeight || (q.MaxNegotiationHeight != 0 && so.NegotiationHeight > q.MaxNegotiationHeight) {
+		return false
+	}
+	revenue := so.PotentialRevenue()
+	if revenue.Cmp(q.MinRevenue) < 0 || (!q.MaxRevenue.IsZero() && revenue.Cmp(q.MaxRevenue) > 0) {
+		return false
+	}
+	status := normalizeObligationStatus(so.ObligationStatus)
+	switch q.ProofOutcome {
+	case ProofOutcomeConfirmed:
+		return so.ProofConfirmed
+	case ProofOutcomeMissed:
+		return status == "failed" && !so.ProofConfirmed
+	case ProofOutcomePending:
+		return status == "unresolved" && !so.ProofConfirmed
+	}
+	return true
+}
+
+// Apply sorts the storage obligations according to the query and returns the
+// requested page. The obligations are expected to be filtered already.
+func (q HostStorageObligationQuery) Apply(sos []StorageObligation) []StorageObligation {
+	less := func(i, j int) bool {
+		switch q.SortBy {
+		case ObligationSortByDataSize:
+			return sos[i].DataSize < sos[j].DataSize
+		case ObligationSortByExpiration:
+			return sos[i].ExpirationHeight < sos[j].ExpirationHeight
+		case ObligationSortByNegotiation:
+			return sos[i].NegotiationHeight < sos[j].NegotiationHeight
+		case ObligationSortByRevenue:
+			return sos[i].PotentialRevenue().Cmp(sos[j].PotentialRevenue()) < 0
+		}
+		return bytes.Compare(sos[i].ObligationId[:], sos[j].ObligationId[:]) < 0
+	}
+	sort.SliceStable(sos, func(i, j int) bool {
+		if q.SortDesc {
+			return less(j, i)
+		}
+		return less(i, j)
+	})
+	if q.Offset >= uint64(len(sos)) {
+		return sos[:0]
+	}
+	sos = sos[q.Offset:]
+	if q.Limit != 0 && q.Limit < uint64(len(sos)) {
+		sos = sos[:q.Limit]
+	}
+	return sos
+}
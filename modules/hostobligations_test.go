@@ -0,0 +1,75 @@
+package modules
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestHostStorageObligationQuery is a unit test for filtering, sorting and
+// paging storage obligations with a HostStorageObligationQuery.
+func TestHostStorageObligationQuery(t *testing.T) {
+	t.Parallel()
+
+	sos := []StorageObligation{
+		{ObligationId: types.FileContractID{1}, ObligationStatus: "obligationSucceeded", ProofConfirmed: true, ExpirationHeight: 10, NegotiationHeight: 1, DataSize: 3, PotentialStorageRevenue: types.NewCurrency64(30)},
+		{ObligationId: types.FileContractID{2}, ObligationStatus: "obligationFailed", ExpirationHeight: 20, NegotiationHeight: 2, DataSize: 1, PotentialUploadRevenue: types.NewCurrency64(10)},
+		{ObligationId: types.FileContractID{3}, ObligationStatus: "obligationUnresolved", ExpirationHeight: 30, NegotiationHeight: 3, DataSize: 2, PotentialDownloadRevenue: types.NewCurrency64(15), PotentialAccountFunding: types.NewCurrency64(5)},
+	}
+
+	// query applies the query to a copy of the obligations and returns the ids
+	// of the result.
+	query := func(q HostStorageObligationQuery) []byte {
+		if err := q.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		var matches []StorageObligation
+		for _, so := range sos {
+			if q.Matches(so) {
+				matches = append(matches, so)
+			}
+		}
+		var ids []byte
+		for _, so := range q.Apply(matches) {
+			ids = append(ids, so.ObligationId[0])
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		query    HostStorageObligationQuery
+		expected []byte
+	}{
+		{"all", HostStorageObligationQuery{}, []byte{1, 2, 3}},
+		{"status", HostStorageObligationQuery{Statuses: []string{"succeeded", "obligationFailed"}}, []byte{1, 2}},
+		{"expiration", HostStorageObligationQuery{MinExpirationHeight: 15, MaxExpirationHeight: 25}, []byte{2}},
+		{"negotiation", HostStorageObligationQuery{MinNegotiationHeight: 2}, []byte{2, 3}},
+		{"revenue", HostStorageObligationQuery{MinRevenue: types.NewCurrency64(15), MaxRevenue: types.NewCurrency64(25)}, []byte{3}},
+		{"confirmed", HostStorageObligationQuery{ProofOutcome: ProofOutcomeConfirmed}, []byte{1}},
+		{"missed", HostStorageObligationQuery{ProofOutcome: ProofOutcomeMissed}, []byte{2}},
+		{"pending", HostStorageObligationQuery{ProofOutcome: ProofOutcomePending}, []byte{3}},
+		{"sort datasize", HostStorageObligationQuery{SortBy: ObligationSortByDataSize}, []byte{2, 3, 1}},
+		{"sort revenue desc", HostStorageObligationQuery{SortBy: ObligationSortByRevenue, SortDesc: true}, []byte{1, 3, 2}},
+		{"sort id desc", HostStorageObligationQuery{SortDesc: true}, []byte{3, 2, 1}},
+		{"page", HostStorageObligationQuery{Offset: 1, Limit: 1}, []byte{2}},
+		{"offset out of bounds", HostStorageObligationQuery{Offset: 3}, nil},
+	}
+	for _, test := range tests {
+		if ids := query(test.query); string(ids) != string(test.expected) {
+			t.Errorf("%v: expected %v but got %v", test.name, test.expected, ids)
+		}
+	}
+
+	// Invalid queries are rejected.
+	invalid := []HostStorageObligationQuery{
+		{Statuses: []string{"unknown"}},
+		{ProofOutcome: "unknown"},
+		{SortBy: "unknown"},
+	}
+	for _, q := range invalid {
+		if err := q.Validate(); err == nil {
+			t.Error("expected error for invalid query", q)
+		}
+	}
+}
@@ -82,6 +82,10 @@ const (
 	// instruction.
 	MDMTimeInitSingleInstruction = 1
 
+	// MDMTimeProveSegment is the time for executing a 'ProveSegment'
+	// instruction.
+	MDMTimeProveSegment = 1000
+
 	// MDMTimeReadOffset is the time for executing a 'ReadOffset' instruction.
 	MDMTimeReadOffset = 1000
 
@@ -117,6 +121,10 @@ const (
 	// instruction.
 	RPCIHasSectorLen = 8
 
+	// RPCIProveSegmentLen is the expected length of the 'Args' of a
+	// ProveSegment instruction.
+	RPCIProveSegmentLen = 16
+
 	// RPCIReadSectorLen is the expected length of the 'Args' of a ReadSector
 	// instruction.
 	RPCIReadSectorLen = 25
@@ -188,6 +196,9 @@ var (
 	// SpecifierHasSector is the specifier for the HasSector instruction.
 	SpecifierHasSector = InstructionSpecifier{'H', 'a', 's', 'S', 'e', 'c', 't', 'o', 'r'}
 
+	// SpecifierProveSegment is the specifier for the ProveSegment instruction.
+	SpecifierProveSegment = InstructionSpecifier{'P', 'r', 'o', 'v', 'e', 'S', 'e', 'g', 'm', 'e', 'n', 't'}
+
 	// SpecifierReadOffset is the specifier for the ReadOffset instruction.
 	SpecifierReadOffset = InstructionSpecifier{'R', 'e', 'a', 'd', 'O', 'f', 'f', 's', 'e', 't'}
 
@@ -254,6 +265,15 @@ func RPCHasSectorInstruction(merkleRootOffset uint64) Instruction {
 	return i
 }
 
+// ProveSegmentIndex returns the index of the segment of the sector with the
+// given root which a host needs to prove for a 'ProveSegment' instruction with
+// the given seed. Since the seed is chosen by the renter, the host can't know
+// in advance which segment it will need to prove.
+func ProveSegmentIndex(seed, sectorRoot crypto.Hash) uint64 {
+	h := crypto.HashAll(seed, sectorRoot)
+	return binary.LittleEndian.Uint64(h[:8]) % (SectorSize / crypto.SegmentSize)
+}
+
 // RPCIReadSector is a convenience method to create an Instruction of type 'ReadSector'.
 func RPCIReadSector(rootOff, offsetOff, lengthOff uint64, merkleProof bool) Instruction {
 	args := make([]byte, RPCIReadSectorLen)
@@ -302,6 +322,11 @@ func MDMHasSectorCost(pt *RPCPriceTable) types.Currency {
 	return cost
 }
 
+// MDMProveSegmentCost is the cost of executing a 'ProveSegment' instruction.
+func MDMProveSegmentCost(pt *RPCPriceTable) types.Currency {
+	return pt.ProveSegmentBaseCost
+}
+
 // MDMReadCost is the cost of executing a 'Read' instruction. It is defined as:
 // 'readBaseCost' + 'readLengthCost' * `readLength`
 func MDMReadCost(pt *RPCPriceTable, readLength uint64) types.Currency {
@@ -423,6 +448,12 @@ func MDMHasSectorMemory() uint64 {
 	return 0 // 'HasSector' doesn't hold on to any memory beyond the lifetime of the instruction.
 }
 
+// MDMProveSegmentMemory returns the additional memory consumption of a
+// 'ProveSegment' instruction.
+func MDMProveSegmentMemory() uint64 {
+	return 0 // 'ProveSegment' doesn't hold on to any memory beyond the lifetime of the instruction.
+}
+
 // MDMReadMemory returns the additional memory consumption of a 'Read' instruction.
 func MDMReadMemory() uint64 {
 	return 0 // 'Read' doesn't hold on to any memory beyond the lifetime of the instruction.
@@ -489,6 +520,12 @@ func MDMHasSectorCollateral() types.Currency {
 	return types.ZeroCurrency
 }
 
+// MDMProveSegmentCollateral returns the additional collateral a
+// 'ProveSegment' instruction requires the host to put up.
+func MDMProveSegmentCollateral() types.Currency {
+	return types.ZeroCurrency
+}
+
 // MDMReadCollateral returns the additional collateral a 'Read' instruction
 // requires the host to put up.
 func MDMReadCollateral() types.Currency {
@@ -528,6 +565,7 @@ func (p Program) ReadOnly() bool {
 		case SpecifierDropSectors:
 			return false
 		case SpecifierHasSector:
+		case SpecifierProveSegment:
 		case SpecifierReadOffset:
 		case SpecifierReadSector:
 		case SpecifierRevision:
@@ -556,6 +594,7 @@ func (p Program) RequiresSnapshot() bool {
 		case SpecifierDropSectors:
 			return true
 		case SpecifierHasSector:
+		case SpecifierProveSegment:
 		case SpecifierReadOffset:
 			return true
 		case SpecifierReadSector:
@@ -102,6 +102,26 @@ func (pb *ProgramBuilder) AddHasSectorInstruction(merkleRoot crypto.Hash) {
 	pb.addInstruction(collateral, cost, types.ZeroCurrency, memory, time)
 }
 
+// AddProveSegmentInstruction adds a ProveSegment instruction to the program.
+func (pb *ProgramBuilder) AddProveSegmentInstruction(merkleRoot, seed crypto.Hash) {
+	// Compute the argument offsets.
+	merkleRootOffset := uint64(pb.programData.Len())
+	seedOffset := merkleRootOffset + crypto.HashSize
+	// Extend the programData.
+	binary.Write(pb.programData, binary.LittleEndian, merkleRoot[:])
+	binary.Write(pb.programData, binary.LittleEndian, seed[:])
+	// Create the instruction.
+	i := NewProveSegmentInstruction(merkleRootOffset, seedOffset)
+	// Append instruction
+	pb.program = append(pb.program, i)
+	// Update cost, collateral and memory usage.
+	collateral := MDMProveSegmentCollateral()
+	cost := MDMProveSegmentCost(pb.staticPT)
+	memory := MDMProveSegmentMemory()
+	time := uint64(MDMTimeProveSegment)
+	pb.addInstruction(collateral, cost, types.ZeroCurrency, memory, time)
+}
+
 // AddReadOffsetInstruction adds a ReadOffset instruction to the program.
 func (pb *ProgramBuilder) AddReadOffsetInstruction(length, offset uint64, merkleProof bool) {
 	// Compute the argument offsets.
@@ -546,6 +566,17 @@ func NewHasSectorInstruction(merkleRootOffset uint64) Instruction {
 	return i
 }
 
+// NewProveSegmentInstruction creates a modules.Instruction from arguments.
+func NewProveSegmentInstruction(merkleRootOffset, seedOffset uint64) Instruction {
+	i := Instruction{
+		Specifier: SpecifierProveSegment,
+		Args:      make([]byte, RPCIProveSegmentLen),
+	}
+	binary.LittleEndian.PutUint64(i.Args[:8], merkleRootOffset)
+	binary.LittleEndian.PutUint64(i.Args[8:16], seedOffset)
+	return i
+}
+
 // NewReadOffsetInstruction creates a modules.Instruction from arguments.
 func NewReadOffsetInstruction(lengthOffset, offsetOffset uint64, merkleProof bool) Instruction {
 	i := Instruction{
@@ -23,7 +23,7 @@ import (
 const (
 	// RHPVersion is the version of the Sia renter-host protocol currently
 	// implemented by the host module.
-	RHPVersion = "1.5.9"
+	RHPVersion = "1.5.10"
 
 	// MinimumSupportedRenterHostProtocolVersion is the minimum version of Sia
 	// that supports the currently used version of the renter-host protocol.
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"time"
 
@@ -377,6 +378,15 @@ type DirectoryInfo struct {
 	StuckHealth         float64     `json:"stuckhealth"`
 	StuckSize           uint64      `json:"stucksize"`
 	UID                 uint64      `json:"uid"`
+
+	// Policy is the policy which was set for the siadir. Fields which are not
+	// set are inherited from the parent directories.
+	Policy DirPolicy `json:"policy"`
+
+	// Quota is the quota which was set for the siadir and QuotaUsage is the
+	// spending which was counted against it during the current period.
+	Quota      DirQuota      `json:"quota"`
+	QuotaUsage DirQuotaUsage `json:"quotausage"`
 }
 
 // Name implements os.FileInfo.
@@ -397,6 +407,183 @@ func (d DirectoryInfo) IsDir() bool { return true }
 // Sys implements os.FileInfo.
 func (d DirectoryInfo) Sys() interface{} { return nil }
 
+// DirPolicy is the policy of a siadir for uploading and repairing the files
+// within it. It is inherited by the subdirectories of the siadir. Fields which
+// are set to their zero value are inherited from the parent directory.
+type DirPolicy struct {
+	// DataPieces and ParityPieces are the default erasure coding parameters
+	// of new files. They are either both set or both unset.
+	DataPieces   int
+	ParityPieces int
+
+	// CipherType is the default cipher type of new files.
+	CipherType crypto.CipherType
+
+	// RepairThreshold is the health at which the renter starts repairing a
+	// file. If it is not set, the global RepairThreshold is used.
+	RepairThreshold float64
+}
+
+// dirPolicyJSON is the JSON representation of a DirPolicy. The cipher type is
+// encoded as a string to make it human readable.
+type dirPolicyJSON struct {
+	DataPieces      int     `json:"datapieces"`
+	ParityPieces    int     `json:"paritypieces"`
+	CipherType      string  `json:"ciphertype"`
+	RepairThreshold float64 `json:"repairthreshold"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p DirPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dirPolicyJSON{
+		DataPieces:      p.DataPieces,
+		ParityPieces:    p.ParityPieces,
+		CipherType:      p.CipherType.String(),
+		RepairThreshold: p.RepairThreshold,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *DirPolicy) UnmarshalJSON(b []byte) error {
+	var pj dirPolicyJSON
+	if err := json.Unmarshal(b, &pj); err != nil {
+		return err
+	}
+	var ct crypto.CipherType
+	if pj.CipherType != "" {
+		if err := ct.FromString(pj.CipherType); err != nil {
+			return err
+		}
+	}
+	*p = DirPolicy{
+		DataPieces:      pj.DataPieces,
+		ParityPieces:    pj.ParityPieces,
+		CipherType:      ct,
+		RepairThreshold: pj.RepairThreshold,
+	}
+	return nil
+}
+
+// ErasureCoder returns the erasure coder of the policy or the default erasure
+// coder if the policy doesn't specify one.
+func (p DirPolicy) ErasureCoder() (ErasureCoder, error) {
+	if p.DataPieces == 0 {
+		return NewRSSubCodeDefault(), nil
+	}
+	return NewPartialEncodingRSCode(p.DataPieces, p.ParityPieces)
+}
+
+// Cipher returns the cipher type of the policy or the default cipher type of
+// the renter if the policy doesn't specify one.
+func (p DirPolicy) Cipher() crypto.CipherType {
+	if p.CipherType == crypto.TypeInvalid {
+		return crypto.TypeDefaultRenter
+	}
+	return p.CipherType
+}
+
+// Inherit returns the policy with all the fields which are not set replaced
+// by the fields of the parent's policy.
+func (p DirPolicy) Inherit(parent DirPolicy) DirPolicy {
+	if p.DataPieces == 0 {
+		p.DataPieces, p.ParityPieces = parent.DataPieces, parent.ParityPieces
+	}
+	if p.CipherType == crypto.TypeInvalid {
+		p.CipherType = parent.CipherType
+	}
+	if p.RepairThreshold == 0 {
+		p.RepairThreshold = parent.RepairThreshold
+	}
+	return p
+}
+
+// NeedsRepair returns whether a file with the given health needs to be
+// repaired according to the policy.
+func (p DirPolicy) NeedsRepair(health float64) bool {
+	if p.RepairThreshold == 0 {
+		return NeedsRepair(health)
+	}
+	return health >= p.RepairThreshold
+}
+
+// Validate checks that the fields of the policy are valid.
+func (p DirPolicy) Validate() error {
+	if (p.DataPieces == 0) != (p.ParityPieces == 0) {
+		return errors.New("data pieces and parity pieces need to be set together")
+	}
+	if p.DataPieces < 0 || p.ParityPieces < 0 {
+		return errors.New("data pieces and parity pieces can't be negative")
+	}
+	if p.DataPieces > 0 {
+		if _, err := p.ErasureCoder(); err != nil {
+			return errors.AddContext(err, "invalid erasure coding parameters")
+		}
+	}
+	if p.CipherType != crypto.TypeInvalid && !crypto.IsValidCipherType(p.CipherType) {
+		return crypto.ErrInvalidCipherType
+	}
+	if p.RepairThreshold < 0 || p.RepairThreshold > 1 {
+		return fmt.Errorf("repair threshold needs to be between 0 and 1 but was %v", p.RepairThreshold)
+	}
+	return nil
+}
+
+// DirQuota limits the resources which the files within a siadir and its
+// subdirectories can consume. Limits which are set to their zero value are not
+// enforced. Unlike the fields of a DirPolicy, a quota isn't inherited. Instead
+// the quotas of the siadir and all of its parents need to be satisfied.
+type DirQuota struct {
+	// MaxStoredBytes is the maximum aggregate size of the files within the
+	// siadir.
+	MaxStoredBytes uint64 `json:"maxstoredbytes"`
+
+	// MaxUploadSpend and MaxDownloadSpend are the maximum amounts of money
+	// which may be spent on uploading and downloading the files within the
+	// siadir per allowance period.
+	MaxUploadSpend   types.Currency `json:"maxuploadspend"`
+	MaxDownloadSpend types.Currency `json:"maxdownloadspend"`
+}
+
+// DirQuotaUsage is the spending which was counted against the quota of a
+// siadir.
+type DirQuotaUsage struct {
+	// Period is the start height of the allowance period the spending belongs
+	// to. The spending is reset once a new period starts.
+	Period types.BlockHeight `json:"period"`
+
+	UploadSpend   types.Currency `json:"uploadspend"`
+	DownloadSpend types.Currency `json:"downloadspend"`
+}
+
+// IsSet returns whether any of the limits of the quota are set.
+func (q DirQuota) IsSet() bool {
+	return q.MaxStoredBytes != 0 || !q.MaxUploadSpend.IsZero() || !q.MaxDownloadSpend.IsZero()
+}
+
+// Utilization returns the highest fraction of any of the quota's limits which
+// is used by the given stored bytes and usage. A quota without limits has a
+// utilization of 0.
+func (q DirQuota) Utilization(storedBytes uint64, usage DirQuotaUsage) float64 {
+	var utilization float64
+	if q.MaxStoredBytes != 0 {
+		utilization = float64(storedBytes) / float64(q.MaxStoredBytes)
+	}
+	spendUtilization := func(spent, max types.Currency) float64 {
+		if max.IsZero() {
+			return 0
+		}
+		f, _ := new(big.Rat).SetFrac(spent.Big(), max.Big()).Float64()
+		return f
+	}
+	if u := spendUtilization(usage.UploadSpend, q.MaxUploadSpend); u > utilization {
+		utilization = u
+	}
+	if u := spendUtilization(usage.DownloadSpend, q.MaxDownloadSpend); u > utilization {
+		utilization = u
+	}
+	return utilization
+}
+
 // DownloadInfo provides information about a file that has been requested for
 // download.
 type DownloadInfo struct {
@@ -404,6 +591,7 @@ type DownloadInfo struct {
 	DestinationType string  `json:"destinationtype"` // Can be "file", "memory buffer", or "http stream".
 	Length          uint64  `json:"length"`          // The length requested for the download.
 	Offset          uint64  `json:"offset"`          // The offset within the siafile requested for the download.
+	Resumable       bool    `json:"resumable"`       // Whether the download is resumed after a restart.
 	SiaPath         SiaPath `json:"siapath"`         // The siapath of the file used for the download.
 
 	Completed            bool      `json:"completed"`            // Whether or not the download has completed.
@@ -433,6 +621,18 @@ type FileUploadParams struct {
 	// to create a CipherKey with the given CipherType. This value override
 	// CipherType if it is set.
 	CipherKey crypto.CipherKey
+
+	// Dedup enables the deduplication of the file's data. The data is split
+	// into blocks at content-defined boundaries. Blocks which were already
+	// uploaded by another deduplicated upload with the same erasure code and
+	// cipher type are referenced instead of being uploaded again. Only
+	// supported for streamed uploads.
+	Dedup bool
+
+	// Compression is the compression which is applied to the file's data
+	// before it is erasure coded and encrypted. Only supported for streamed
+	// uploads.
+	Compression CompressionType
 }
 
 // FileInfo provides information about a file.
@@ -441,6 +641,7 @@ type FileInfo struct {
 	Available        bool              `json:"available"`
 	ChangeTime       time.Time         `json:"changetime"`
 	CipherType       string            `json:"ciphertype"`
+	Compression      string            `json:"compression"`
 	CreateTime       time.Time         `json:"createtime"`
 	Expiration       types.BlockHeight `json:"expiration"`
 	Filesize         uint64            `json:"filesize"`
@@ -484,6 +685,46 @@ func (f FileInfo) IsDir() bool { return false }
 // Sys implements os.FileInfo.
 func (f FileInfo) Sys() interface{} { return nil }
 
+// FileVersion provides information about a prior version of a file which was
+// kept when the file was overwritten.
+type FileVersion struct {
+	Version          uint64    `json:"version"`
+	Filesize         uint64    `json:"filesize"`
+	ModificationTime time.Time `json:"modtime"`
+	Redundancy       float64   `json:"redundancy"`
+	SiaPath          SiaPath   `json:"siapath"`
+}
+
+// FileScrubResult contains the result of the last scrub of a file.
+type FileScrubResult struct {
+	SiaPath          SiaPath   `json:"siapath"`
+	LastScrubTime    time.Time `json:"lastscrubtime"`
+	PiecesChecked    uint64    `json:"pieceschecked"`
+	PiecesFailed     uint64    `json:"piecesfailed"`
+	PiecesUnverified uint64    `json:"piecesunverified"`
+}
+
+// ScrubStatus contains information about the current or last scrub of the
+// renter. A scrub spot-checks the pieces of the files within a directory
+// against their Merkle roots and queues the chunks of pieces which fail
+// verification for repair.
+type ScrubStatus struct {
+	SiaPath   SiaPath   `json:"siapath"`
+	Running   bool      `json:"running"`
+	StartTime time.Time `json:"starttime"`
+	EndTime   time.Time `json:"endtime"`
+
+	FilesScrubbed uint64 `json:"filesscrubbed"`
+	// FilesFailed is the number of scrubbed files with at least one piece
+	// which failed verification.
+	FilesFailed      uint64 `json:"filesfailed"`
+	PiecesChecked    uint64 `json:"pieceschecked"`
+	PiecesFailed     uint64 `json:"piecesfailed"`
+	PiecesUnverified uint64 `json:"piecesunverified"`
+
+	Error string `json:"error,omitempty"`
+}
+
 // A HostDBEntry represents one host entry in the Renter's host DB. It
 // aggregates the host's external settings and metrics with its public key.
 type HostDBEntry struct {
@@ -594,6 +835,133 @@ type MountInfo struct {
 	MountOptions MountOptions `json:"mountoptions"`
 }
 
+// PriorityClass describes how urgent an upload, repair or download is. The
+// renter shares its workers and memory between the classes according to the
+// PriorityWeights in its settings.
+type PriorityClass int
+
+// The priority classes of the renter. The zero value is the least urgent
+// class to make sure that work is never prioritized by accident.
+const (
+	// PriorityClassBackground is used for the work the renter schedules on
+	// its own, like the repair of files and the downloads needed for it.
+	PriorityClassBackground PriorityClass = iota
+
+	// PriorityClassUser is used for work which was requested by the user but
+	// which nobody is actively waiting for, like repairs from a stream and
+	// downloads to disk.
+	PriorityClassUser
+
+	// PriorityClassInteractive is used for work a user is waiting for, like
+	// new uploads and streamed downloads.
+	PriorityClassInteractive
+
+	// NumPriorityClasses is the number of priority classes.
+	NumPriorityClasses = 3
+)
+
+// DefaultPriorityWeights are the PriorityWeights the renter uses if none are
+// set.
+var DefaultPriorityWeights = PriorityWeights{
+	Background:  1,
+	User:        2,
+	Interactive: 8,
+}
+
+// PriorityClasses returns all the priority classes from the most to the least
+// urgent one.
+func PriorityClasses() []PriorityClass {
+	return []PriorityClass{PriorityClassInteractive, PriorityClassUser, PriorityClassBackground}
+}
+
+// String returns the string value for the PriorityClass.
+func (pc PriorityClass) String() string {
+	switch pc {
+	case PriorityClassBackground:
+		return "background"
+	case PriorityClassUser:
+		return "user"
+	case PriorityClassInteractive:
+		return "interactive"
+	default:
+		return "unknown"
+	}
+}
+
+// FromString assigns the PriorityClass from the provided string.
+func (pc *PriorityClass) FromString(s string) error {
+	switch s {
+	case "background":
+		*pc = PriorityClassBackground
+	case "user":
+		*pc = PriorityClassUser
+	case "interactive":
+		*pc = PriorityClassInteractive
+	default:
+		return fmt.Errorf("unknown priority class %v", s)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (pc PriorityClass) MarshalText() ([]byte, error) {
+	return []byte(pc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (pc *PriorityClass) UnmarshalText(b []byte) error {
+	return pc.FromString(string(b))
+}
+
+// PriorityWeights are the weights the renter uses to share its workers and
+// memory between the priority classes. While more than one class has work
+// queued, every class receives a share of the capacity which is proportional
+// to its weight.
+type PriorityWeights struct {
+	Background  uint64 `json:"background"`
+	User        uint64 `json:"user"`
+	Interactive uint64 `json:"interactive"`
+}
+
+// Validate checks that all the weights are set.
+func (pw PriorityWeights) Validate() error {
+	if pw.Background == 0 || pw.User == 0 || pw.Interactive == 0 {
+		return errors.New("priority weights need to be greater than 0")
+	}
+	return nil
+}
+
+// Weight returns the weight of a priority class.
+func (pw PriorityWeights) Weight(pc PriorityClass) uint64 {
+	switch pc {
+	case PriorityClassUser:
+		return pw.User
+	case PriorityClassInteractive:
+		return pw.Interactive
+	default:
+		return pw.Background
+	}
+}
+
+// PriorityClassStatus contains information about the uploads and repairs of a
+// single priority class.
+type PriorityClassStatus struct {
+	Class  PriorityClass `json:"class"`
+	Weight uint64        `json:"weight"`
+
+	// QueuedChunks is the number of chunks waiting in the upload heap.
+	// ActiveChunks is the number of chunks which are currently being
+	// uploaded. FinishedChunks is the number of chunks which finished since
+	// the renter started.
+	QueuedChunks   uint64 `json:"queuedchunks"`
+	ActiveChunks   uint64 `json:"activechunks"`
+	FinishedChunks uint64 `json:"finishedchunks"`
+
+	// ScheduledBytes is the amount of memory that was scheduled for the
+	// chunks of the class since the renter started.
+	ScheduledBytes uint64 `json:"scheduledbytes"`
+}
+
 // RenterPriceEstimation contains a bunch of files estimating the costs of
 // various operations on the network.
 type RenterPriceEstimation struct {
@@ -613,11 +981,13 @@ type RenterPriceEstimation struct {
 
 // RenterSettings control the behavior of the Renter.
 type RenterSettings struct {
-	Allowance        Allowance     `json:"allowance"`
-	IPViolationCheck bool          `json:"ipviolationcheck"`
-	MaxUploadSpeed   int64         `json:"maxuploadspeed"`
-	MaxDownloadSpeed int64         `json:"maxdownloadspeed"`
-	UploadsStatus    UploadsStatus `json:"uploadsstatus"`
+	Allowance        Allowance       `json:"allowance"`
+	IPViolationCheck bool            `json:"ipviolationcheck"`
+	MaxUploadSpeed   int64           `json:"maxuploadspeed"`
+	MaxDownloadSpeed int64           `json:"maxdownloadspeed"`
+	MaxFileVersions  uint64          `json:"maxfileversions"`
+	PriorityWeights  PriorityWeights `json:"priorityweights"`
+	UploadsStatus    UploadsStatus   `json:"uploadsstatus"`
 }
 
 // UploadsStatus contains information about the Renter's Uploads
@@ -862,6 +1232,7 @@ type UploadedBackup struct {
 	CreationDate   types.Timestamp
 	Size           uint64 // size of snapshot .sia file
 	UploadProgress float64
+	SiaPath        SiaPath // directory of a directory backup, empty for full backups
 }
 
 type (
@@ -1026,6 +1397,11 @@ type Renter interface {
 	// nil, the backup will be encrypted using the provided secret.
 	CreateBackup(dst string, secret []byte) error
 
+	// CreateDirBackup creates a backup of the siafiles within a single
+	// directory and its subdirectories. If a secret is not nil, the backup
+	// will be encrypted using the provided secret.
+	CreateDirBackup(dst string, siaPath SiaPath, secret []byte) error
+
 	// LoadBackup loads the siafiles of a previously created backup into the
 	// renter. If the backup is encrypted, secret will be used to decrypt it.
 	// Otherwise the argument is ignored.
@@ -1035,6 +1411,10 @@ type Renter interface {
 	// use.
 	LoadBackup(src string, secret []byte) error
 
+	// LoadDirBackup loads the siafiles of a previously created backup into
+	// the target directory. The target directory must not exist yet.
+	LoadDirBackup(src string, secret []byte, target SiaPath) error
+
 	// InitRecoveryScan starts scanning the whole blockchain for recoverable
 	// contracts within a separate thread.
 	InitRecoveryScan() error
@@ -1089,6 +1469,10 @@ type Renter interface {
 	// using only the seed.
 	UploadBackup(src string, name string) error
 
+	// UploadDirBackup uploads a backup of a single directory to hosts, such
+	// that it can be retrieved using only the seed.
+	UploadDirBackup(src string, name string, siaPath SiaPath) error
+
 	// DownloadBackup downloads a backup previously uploaded to hosts.
 	DownloadBackup(dst string, name string) error
 
@@ -1124,6 +1508,10 @@ type Renter interface {
 	// DownloadHistory lists all the files that have been scheduled for download.
 	DownloadHistory() []DownloadInfo
 
+	// ResumeDownloads resumes the persisted resumable downloads which are not
+	// in progress.
+	ResumeDownloads() error
+
 	// File returns information on specific file queried by user
 	File(siaPath SiaPath) (FileInfo, error)
 
@@ -1135,6 +1523,10 @@ type Renter interface {
 	// FileHosts returns a list of hosts that are storing the file data.
 	FileHosts(SiaPath) ([]HostDBEntry, error)
 
+	// FileVersions returns the prior versions of a file which were kept when
+	// the file was overwritten.
+	FileVersions(siaPath SiaPath) ([]FileVersion, error)
+
 	// Filter returns the renter's hostdb's filterMode and filteredHosts
 	Filter() (FilterMode, map[string]types.SiaPublicKey, []string, error)
 
@@ -1155,9 +1547,17 @@ type Renter interface {
 	// RenameFile changes the path of a file.
 	RenameFile(siaPath, newSiaPath SiaPath) error
 
+	// ReplaceFile changes the path of a file to the path of another file
+	// which is archived like a file overwritten by a forced upload.
+	ReplaceFile(siaPath, replacedSiaPath SiaPath) error
+
 	// RenameDir changes the path of a dir.
 	RenameDir(oldPath, newPath SiaPath) error
 
+	// RestoreFileVersion replaces a file with one of its prior versions. The
+	// replaced file is kept as a new version.
+	RestoreFileVersion(siaPath SiaPath, version uint64) error
+
 	// EstimateHostScore will return the score for a host with the provided
 	// settings, assuming perfect age and uptime adjustments
 	EstimateHostScore(entry HostDBEntry, allowance Allowance) (HostScoreBreakdown, error)
@@ -1168,6 +1568,20 @@ type Renter interface {
 	// used.
 	ReadRegistry(spk types.SiaPublicKey, tweak crypto.Hash, timeout time.Duration) (SignedRegistryValue, error)
 
+	// Scrub starts a scrub of the files within the directory or of the file at
+	// the given path in the background. Only one scrub can run at a time.
+	Scrub(siaPath SiaPath) error
+
+	// ScrubCancel cancels the running scrub.
+	ScrubCancel() error
+
+	// ScrubResults returns the results of the last scrubs of the files within
+	// the directory at the given path.
+	ScrubResults(siaPath SiaPath, recursive bool) ([]FileScrubResult, error)
+
+	// ScrubStatus returns the status of the current or last scrub.
+	ScrubStatus() ScrubStatus
+
 	// ScoreBreakdown will return the score for a host db entry using the
 	// hostdb's weighting algorithm.
 	ScoreBreakdown(entry HostDBEntry) (HostScoreBreakdown, error)
@@ -1175,6 +1589,12 @@ type Renter interface {
 	// Settings returns the Renter's current settings.
 	Settings() (RenterSettings, error)
 
+	// SubscribeRegistry subscribes to updates of a registry entry on all
+	// available workers. Updates with a higher revision than the previous one
+	// are sent on the returned channel. The returned function unsubscribes
+	// from the entry and needs to be called once the caller is done.
+	SubscribeRegistry(spk types.SiaPublicKey, tweak crypto.Hash) (<-chan SignedRegistryValue, func(), error)
+
 	// SetSettings sets the Renter's settings.
 	SetSettings(RenterSettings) error
 
@@ -1193,11 +1613,19 @@ type Renter interface {
 	// ResumeRepairsAndUploads resumes the renter's repairs and uploads
 	ResumeRepairsAndUploads() error
 
+	// UploadPriorityClasses returns the status of the renter's uploads and
+	// repairs for every priority class.
+	UploadPriorityClasses() ([]PriorityClassStatus, error)
+
 	// Streamer creates a io.ReadSeeker that can be used to stream downloads
 	// from the Sia network and also returns the fileName of the streamed
 	// resource.
 	Streamer(siapath SiaPath, disableLocalFetch bool) (string, Streamer, error)
 
+	// StreamInfo returns the information needed to serve a file over HTTP
+	// without creating a streamer or fetching any data from hosts.
+	StreamInfo(siapath SiaPath) (StreamInfo, error)
+
 	// Upload uploads a file using the input parameters.
 	Upload(FileUploadParams) error
 
@@ -1214,6 +1642,16 @@ type Renter interface {
 	// DirList lists the directories in a siadir
 	DirList(siaPath SiaPath) ([]DirectoryInfo, error)
 
+	// DirPolicy returns the effective policy of a siadir which includes the
+	// fields inherited from its parent directories.
+	DirPolicy(siaPath SiaPath) (DirPolicy, error)
+
+	// SetDirPolicy sets the policy of a siadir.
+	SetDirPolicy(siaPath SiaPath, policy DirPolicy) error
+
+	// SetDirQuota sets the quota of a siadir.
+	SetDirQuota(siaPath SiaPath, quota DirQuota) error
+
 	// WorkerPoolStatus returns the current status of the Renter's worker pool
 	WorkerPoolStatus() (WorkerPoolStatus, error)
 
@@ -1236,6 +1674,16 @@ type Streamer interface {
 	io.Closer
 }
 
+// StreamInfo contains the information about a file which is needed to serve
+// it over HTTP without fetching any of its data. The ETag only changes if the
+// content of the file changes.
+type StreamInfo struct {
+	Name    string
+	Size    uint64
+	ModTime time.Time
+	ETag    string
+}
+
 // SkyfileStreamer is the interface implemented by the Renter's skyfile type
 // which allows for streaming files uploaded to the Sia network.
 type SkyfileStreamer interface {
@@ -1253,6 +1701,11 @@ type RenterDownloadParameters struct {
 	SiaPath          SiaPath
 	Destination      string
 	DisableDiskFetch bool
+
+	// Resumable downloads persist their progress and are resumed after a
+	// restart. Only supported for downloads of uncompressed files to a file
+	// on disk.
+	Resumable bool
 }
 
 // HealthPercentage returns the health in a more human understandable format out
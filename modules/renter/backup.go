@@ -45,12 +45,31 @@ func (r *Renter) CreateBackup(dst string, secret []byte) error {
 		return err
 	}
 	defer r.tg.Done()
-	return r.managedCreateBackup(dst, secret)
+	return r.managedCreateBackup(dst, secret, modules.UserFolder)
 }
 
-// managedCreateBackup creates a backup of the renter's siafiles. If a secret is
-// not nil, the backup will be encrypted using the provided secret.
-func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
+// CreateDirBackup creates a backup of the siafiles within a single directory
+// and its subdirectories. If a secret is not nil, the backup will be encrypted
+// using the provided secret.
+func (r *Renter) CreateDirBackup(dst string, siaPath modules.SiaPath, secret []byte) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	exists, err := r.staticFileSystem.DirExists(siaPath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return filesystem.ErrNotExist
+	}
+	return r.managedCreateBackup(dst, secret, siaPath)
+}
+
+// managedCreateBackup creates a backup of the siafiles within the provided
+// folder. If a secret is not nil, the backup will be encrypted using the
+// provided secret.
+func (r *Renter) managedCreateBackup(dst string, secret []byte, folder modules.SiaPath) (err error) {
 	// Create the gzip file.
 	f, err := os.Create(dst)
 	if err != nil {
@@ -101,7 +120,7 @@ func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
 	// Wrap the gzip writer into a tar writer.
 	tw := tar.NewWriter(gzw)
 	// Add the files to the archive.
-	if err := r.managedTarSiaFiles(tw); err != nil {
+	if err := r.managedTarSiaFiles(tw, folder); err != nil {
 		twErr := tw.Close()
 		gzwErr := gzw.Close()
 		return errors.Compose(err, twErr, gzwErr)
@@ -144,10 +163,57 @@ func (r *Renter) LoadBackup(src string, secret []byte) (err error) {
 		err = errors.Compose(err, root.Close())
 	}()
 
+	allowance, err := r.managedLoadBackup(src, secret, modules.UserFolder)
+	if err != nil {
+		return err
+	}
+	// If the backup contained a valid allowance and we currently don't have an
+	// allowance set, import it.
+	if !reflect.DeepEqual(allowance, modules.Allowance{}) &&
+		reflect.DeepEqual(r.hostContractor.Allowance(), modules.Allowance{}) {
+		if err := r.hostContractor.SetAllowance(allowance); err != nil {
+			return errors.AddContext(err, "unable to set allowance from backup")
+		}
+	}
+	return nil
+}
+
+// LoadDirBackup loads the siafiles of a previously created backup into the
+// target directory. The target directory must not exist yet which guarantees
+// that no existing siafiles are touched. The allowance of the backup is
+// ignored.
+func (r *Renter) LoadDirBackup(src string, secret []byte, target modules.SiaPath) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	// Make sure the target doesn't exist yet.
+	if target.IsRoot() {
+		return errors.New("can't load a backup into the root directory")
+	}
+	dirExists, err := r.staticFileSystem.DirExists(target)
+	if err != nil {
+		return err
+	}
+	fileExists, err := r.staticFileSystem.FileExists(target)
+	if err != nil {
+		return err
+	}
+	if dirExists || fileExists {
+		return errors.AddContext(filesystem.ErrExists, "target of backup already exists")
+	}
+	_, err = r.managedLoadBackup(src, secret, target)
+	return err
+}
+
+// managedLoadBackup loads the siafiles of a previously created backup into
+// the provided folder and returns the allowance stored in the backup.
+func (r *Renter) managedLoadBackup(src string, secret []byte, folder modules.SiaPath) (_ modules.Allowance, err error) {
 	// Open the gzip file.
 	f, err := os.Open(src)
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	defer func() {
 		err = errors.Compose(err, f.Close())
@@ -158,53 +224,53 @@ func (r *Renter) LoadBackup(src string, secret []byte) (err error) {
 	var chks crypto.Hash
 	_, err = io.ReadFull(f, chks[:])
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	// Read the header.
 	dec := json.NewDecoder(archive)
 	var bh backupHeader
 	if err := dec.Decode(&bh); err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	// Check the version number.
 	if bh.Version != encryptionVersion {
-		return errors.New("unknown version")
+		return modules.Allowance{}, errors.New("unknown version")
 	}
 	// Wrap the file in the correct streamcipher. Consider the data remaining in
 	// the decoder's buffer by using a multireader.
 	archive = io.MultiReader(dec.Buffered(), archive)
 	_, err = archive.Read(make([]byte, 1)) // Ignore first byte of buffer to get to the body of the backup
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	archive, err = wrapReaderInCipher(io.MultiReader(archive, f), bh, secret)
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	// Pipe the remaining file into the hasher to verify that the hash is
 	// correct.
 	h := crypto.NewHash()
 	n, err := io.Copy(h, archive)
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	// Verify the hash.
 	if !bytes.Equal(h.Sum(nil), chks[:]) {
-		return errors.New("checksum doesn't match")
+		return modules.Allowance{}, errors.New("checksum doesn't match")
 	}
 	// Seek back to the beginning of the body.
 	if _, err := f.Seek(-n, io.SeekCurrent); err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	// Wrap the file again.
 	archive, err = wrapReaderInCipher(f, bh, secret)
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	// Wrap the potentially encrypted reader in a gzip reader.
 	gzr, err := gzip.NewReader(archive)
 	if err != nil {
-		return err
+		return modules.Allowance{}, err
 	}
 	defer func() {
 		err = errors.Compose(err, gzr.Close())
@@ -212,8 +278,8 @@ func (r *Renter) LoadBackup(src string, secret []byte) (err error) {
 	// Wrap the gzip reader in a tar reader.
 	tr := tar.NewReader(gzr)
 	// Untar the files.
-	if err := r.managedUntarDir(tr); err != nil {
-		return errors.AddContext(err, "failed to untar dir")
+	if err := r.managedUntarDir(tr, folder); err != nil {
+		return modules.Allowance{}, errors.AddContext(err, "failed to untar dir")
 	}
 	// Unmarshal the allowance if available. This needs to happen after adding
 	// decryption and confirming the hash but before adding decompression.
@@ -223,23 +289,15 @@ func (r *Renter) LoadBackup(src string, secret []byte) (err error) {
 		// legacy backup without allowance
 		r.log.Println("WARN: Decoding the backup's allowance failed: ", err)
 	}
-	// If the backup contained a valid allowance and we currently don't have an
-	// allowance set, import it.
-	if !reflect.DeepEqual(allowance, modules.Allowance{}) &&
-		reflect.DeepEqual(r.hostContractor.Allowance(), modules.Allowance{}) {
-		if err := r.hostContractor.SetAllowance(allowance); err != nil {
-			return errors.AddContext(err, "unable to set allowance from backup")
-		}
-	}
-	return nil
+	return allowance, nil
 }
 
-// managedTarSiaFiles creates a tarball from the renter's siafiles and writes
-// it to dst.
-func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
-	// Walk over all the siafiles in in the user's home and add them to the
+// managedTarSiaFiles creates a tarball from the siafiles within the provided
+// folder and writes it to dst.
+func (r *Renter) managedTarSiaFiles(tw *tar.Writer, folder modules.SiaPath) error {
+	// Walk over all the siafiles in in the folder and add them to the
 	// tarball.
-	return r.staticFileSystem.Walk(modules.UserFolder, func(path string, info os.FileInfo, statErr error) (err error) {
+	return r.staticFileSystem.Walk(folder, func(path string, info os.FileInfo, statErr error) (err error) {
 		// This error is non-nil if filepath.Walk couldn't stat a file or
 		// folder.
 		if statErr != nil {
@@ -255,7 +313,7 @@ func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
 		if err != nil {
 			return err
 		}
-		relPath := strings.TrimPrefix(path, r.staticFileSystem.DirPath(modules.UserFolder))
+		relPath := strings.TrimPrefix(path, r.staticFileSystem.DirPath(folder))
 		header.Name = relPath
 		// If the info is a dir there is nothing more to do besides writing the
 		// header.
@@ -266,7 +324,7 @@ func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
 		var file io.Reader
 		if filepath.Ext(path) == modules.SiaFileExtension {
 			// Get the siafile.
-			siaPath, err := modules.UserFolder.Join(strings.TrimSuffix(relPath, modules.SiaFileExtension))
+			siaPath, err := folder.Join(strings.TrimSuffix(relPath, modules.SiaFileExtension))
 			if err != nil {
 				return err
 			}
@@ -298,9 +356,9 @@ func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
 			var siaPath modules.SiaPath
 			siaPathStr := strings.TrimSuffix(relPath, modules.SiaDirExtension)
 			if siaPathStr == string(filepath.Separator) {
-				siaPath = modules.UserFolder
+				siaPath = folder
 			} else {
-				siaPath, err = modules.UserFolder.Join(siaPathStr)
+				siaPath, err = folder.Join(siaPathStr)
 				if err != nil {
 					return err
 				}
@@ -341,7 +399,7 @@ func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
 
 // managedUntarDir untars the archive from src and writes the contents to dstFolder
 // while preserving the relative paths within the archive.
-func (r *Renter) managedUntarDir(tr *tar.Reader) (err error) {
+func (r *Renter) managedUntarDir(tr *tar.Reader, dstFolder modules.SiaPath) (err error) {
 	// dirsToUpdate are all the directories that will need bubble to be called
 	// on them so that the renter's directory metadata from the back up is
 	// updated
@@ -351,7 +409,7 @@ func (r *Renter) managedUntarDir(tr *tar.Reader) (err error) {
 	}()
 
 	// Copy the files from the tarball to the new location.
-	dir := r.staticFileSystem.DirPath(modules.UserFolder)
+	dir := r.staticFileSystem.DirPath(dstFolder)
 	for {
 		header, err := tr.Next()
 		if errors.Contains(err, io.EOF) {
@@ -403,13 +461,17 @@ func (r *Renter) managedUntarDir(tr *tar.Reader) (err error) {
 			}
 			// Try creating a new SiaDir.
 			var siaPath modules.SiaPath
-			if err := siaPath.LoadSysPath(r.staticFileSystem.DirPath(modules.UserFolder), dst); err != nil {
+			if err := siaPath.LoadSysPath(r.staticFileSystem.DirPath(dstFolder), dst); err != nil {
 				return errors.AddContext(err, "could not load system path")
 			}
 			siaPath, err = siaPath.Dir()
 			if err != nil {
 				return errors.AddContext(err, "could not get directory")
 			}
+			siaPath, err = siaPath.Rebase(modules.RootSiaPath(), dstFolder)
+			if err != nil {
+				return errors.AddContext(err, "could not rebase directory")
+			}
 			err := r.staticFileSystem.NewSiaDir(siaPath, modules.DefaultDirPerm)
 			if errors.Contains(err, filesystem.ErrExists) {
 				// .siadir exists already
@@ -437,7 +499,7 @@ func (r *Renter) managedUntarDir(tr *tar.Reader) (err error) {
 		} else if filepath.Ext(info.Name()) == modules.SiaFileExtension {
 			// Add the file to the SiaFileSet.
 			reader := bytes.NewReader(b)
-			siaPath, err := modules.UserFolder.Join(strings.TrimSuffix(header.Name, modules.SiaFileExtension))
+			siaPath, err := dstFolder.Join(strings.TrimSuffix(header.Name, modules.SiaFileExtension))
 			if err != nil {
 				return errors.AddContext(err, "could not join folders")
 			}
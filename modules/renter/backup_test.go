@@ -0,0 +1,92 @@
+package renter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+	"go.sia.tech/siad/siatest/dependencies"
+)
+
+// TestDirBackup tests creating a backup of a single directory and restoring
+// it to a different directory.
+func TestDirBackup(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Create files within the directory to back up and a file outside of it.
+	for _, sp := range []string{"home/user/dir/file", "home/user/dir/sub/file", "home/user/other/file"} {
+		entry, err := r.createRenterTestFile(newSiaPath(sp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := entry.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Back up a directory that doesn't exist.
+	dst := filepath.Join(rt.dir, "dir.backup")
+	secret := fastrand.Bytes(32)
+	if err := r.CreateDirBackup(dst, newSiaPath("home/user/missing"), secret); !errors.Contains(err, filesystem.ErrNotExist) {
+		t.Fatal("expected ErrNotExist", err)
+	}
+	// Back up the directory.
+	if err := r.CreateDirBackup(dst, newSiaPath("home/user/dir"), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring the backup to an existing directory should fail.
+	for _, sp := range []string{"home/user/dir", "home/user/other/file"} {
+		if err := r.LoadDirBackup(dst, secret, newSiaPath(sp)); !errors.Contains(err, filesystem.ErrExists) {
+			t.Fatal("expected ErrExists", sp, err)
+		}
+	}
+	if err := r.LoadDirBackup(dst, secret, modules.RootSiaPath()); err == nil {
+		t.Fatal("shouldn't be able to restore backup to root")
+	}
+
+	// Restore the backup to a new directory.
+	if err := r.LoadDirBackup(dst, secret, newSiaPath("home/user/restored")); err != nil {
+		t.Fatal(err)
+	}
+	for _, sp := range []string{"home/user/restored/file", "home/user/restored/sub/file", "home/user/dir/file", "home/user/other/file"} {
+		exists, err := r.staticFileSystem.FileExists(newSiaPath(sp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Fatal("file doesn't exist", sp)
+		}
+	}
+	exists, err := r.staticFileSystem.DirExists(newSiaPath("home/user/restored/sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("restored subdirectory doesn't exist")
+	}
+	// The other directory shouldn't be part of the backup.
+	exists, err = r.staticFileSystem.DirExists(newSiaPath("home/user/restored/other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("backup contains files outside of the directory")
+	}
+}
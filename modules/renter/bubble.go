@@ -301,11 +301,21 @@ func (bs *bubbleScheduler) managedPerformBubbleUpdate(siaPath modules.SiaPath) (
 		defer func() {
 			err = errors.Compose(err, siaDir.Close())
 		}()
+		// The directory's own repair threshold is part of the aggregate.
+		if md, mdErr := siaDir.Metadata(); mdErr == nil {
+			metadata.AggregateMinRepairThreshold = minRepairThreshold(metadata.AggregateMinRepairThreshold, md.Policy.RepairThreshold)
+		}
 		err = siaDir.UpdateBubbledMetadata(metadata)
 		if err != nil {
 			e := fmt.Sprintf("could not update the metadata of the directory %v", siaPath.String())
 			err = errors.AddContext(err, e)
 		}
+		// Check whether the directory is about to reach its quota.
+		if md, mdErr := siaDir.Metadata(); mdErr == nil {
+			if md.Quota.IsSet() {
+				r.managedUpdateQuotaAlert(siaPath, md.Quota, md.AggregateSize, r.managedCurrentQuotaUsage(md.QuotaUsage))
+			}
+		}
 	}
 
 	// If we are at the root directory then check if any files were found in
@@ -314,7 +324,8 @@ func (bs *bubbleScheduler) managedPerformBubbleUpdate(siaPath modules.SiaPath) (
 	// loops start at the root directory so there is no point triggering them
 	// until the root directory is updated
 	if siaPath.IsRoot() {
-		if modules.NeedsRepair(metadata.AggregateHealth) {
+		r.managedSetMinRepairThreshold(metadata.AggregateMinRepairThreshold)
+		if metadata.AggregateHealth >= r.managedMinRepairThreshold() {
 			select {
 			case r.uploadHeap.repairNeeded <- struct{}{}:
 			default:
@@ -0,0 +1,268 @@
+package renter
+
+import (
+	"io"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+)
+
+// Compression Overview:
+// Uploads which set the Compression field of the FileUploadParams split the
+// stream into frames of ChunkSize bytes and compress every frame
+// independently. The compressed frames are concatenated and uploaded like
+// regular data. Once the upload is complete, the offsets of the compressed
+// frames are stored in the siafile's compression index.
+//
+// Readers translate an offset within the uncompressed data into the frame
+// which contains it, fetch the frame's compressed bytes using the index and
+// decompress the frame. Since frames are independent, seeking only requires
+// fetching and decompressing a single frame.
+
+var (
+	// errCompressionNotStreamed is returned if an upload from a local file
+	// sets the Compression field.
+	errCompressionNotStreamed = errors.New("compression is only supported for streamed uploads")
+
+	// errCompressionRepair is returned if a repair upload sets the
+	// Compression field. Repairs always use the compression of the existing
+	// file.
+	errCompressionRepair = errors.New("can't provide compression settings when doing repairs")
+)
+
+type (
+	// compressReader is an io.Reader which compresses the data of the
+	// underlying reader in independent frames and keeps track of the offsets
+	// of the compressed frames.
+	compressReader struct {
+		staticReader    io.Reader
+		staticType      modules.CompressionType
+		staticFrameSize uint64
+
+		buf   []byte
+		frame []byte
+		index []uint64
+		size  uint64
+		err   error
+	}
+
+	// decompressStreamer is a modules.Streamer which decompresses the data of
+	// an underlying streamer of a compressed file.
+	decompressStreamer struct {
+		staticStreamer    modules.Streamer
+		staticCompression siafile.CompressionInfo
+
+		offset     int64
+		frame      []byte
+		frameIndex uint64
+		mu         sync.Mutex
+	}
+
+	// decompressWriter is an io.Writer which receives consecutive compressed
+	// frames of a file and writes the part of the decompressed data between
+	// offset and offset+length to the underlying writer.
+	decompressWriter struct {
+		staticCompression siafile.CompressionInfo
+		staticWriter      io.Writer
+
+		buf        []byte
+		frameIndex uint64
+		offset     uint64
+		length     uint64
+	}
+)
+
+// newCompressReader creates a new compressReader.
+func newCompressReader(r io.Reader, ct modules.CompressionType, frameSize uint64) *compressReader {
+	return &compressReader{
+		staticReader:    r,
+		staticType:      ct,
+		staticFrameSize: frameSize,
+		frame:           make([]byte, frameSize),
+		index:           []uint64{0},
+	}
+}
+
+// Index returns the compression index and the number of uncompressed bytes
+// read so far.
+func (cr *compressReader) Index() ([]uint64, uint64) {
+	return cr.index, cr.size
+}
+
+// Read implements io.Reader.
+func (cr *compressReader) Read(b []byte) (int, error) {
+	for len(cr.buf) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		n, err := io.ReadFull(cr.staticReader, cr.frame)
+		if errors.Contains(err, io.EOF) || errors.Contains(err, io.ErrUnexpectedEOF) {
+			cr.err = io.EOF
+		} else if err != nil {
+			cr.err = err
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+		compressed, err := cr.staticType.Compress(cr.frame[:n])
+		if err != nil {
+			cr.err = err
+			return 0, err
+		}
+		cr.buf = compressed
+		cr.size += uint64(n)
+		cr.index = append(cr.index, cr.index[len(cr.index)-1]+uint64(len(compressed)))
+	}
+	n := copy(b, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
+
+// newDecompressStreamer wraps the streamer of a compressed file. The
+// compression index needs to be complete.
+func newDecompressStreamer(s modules.Streamer, ci siafile.CompressionInfo) *decompressStreamer {
+	return &decompressStreamer{
+		staticStreamer:    s,
+		staticCompression: ci,
+	}
+}
+
+// Close closes the underlying streamer.
+func (ds *decompressStreamer) Close() error {
+	return ds.staticStreamer.Close()
+}
+
+// Read reads from the decompressed data of the file. Only the frame
+// containing the current offset is fetched and decompressed.
+func (ds *decompressStreamer) Read(b []byte) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ci := ds.staticCompression
+	if ds.offset >= int64(ci.UncompressedSize) {
+		return 0, io.EOF
+	}
+	frameIndex := uint64(ds.offset) / ci.FrameSize
+	if ds.frame == nil || ds.frameIndex != frameIndex {
+		frame, err := ds.fetchFrame(frameIndex)
+		if err != nil {
+			return 0, err
+		}
+		ds.frame = frame
+		ds.frameIndex = frameIndex
+	}
+	n := copy(b, ds.frame[uint64(ds.offset)-frameIndex*ci.FrameSize:])
+	ds.offset += int64(n)
+	return n, nil
+}
+
+// Seek sets the offset within the decompressed data for the next Read.
+func (ds *decompressStreamer) Seek(offset int64, whence int) (int64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = 0
+	case io.SeekCurrent:
+		newOffset = ds.offset
+	case io.SeekEnd:
+		newOffset = int64(ds.staticCompression.UncompressedSize)
+	}
+	newOffset += offset
+	if newOffset < 0 {
+		return ds.offset, errors.New("cannot seek to negative offset")
+	}
+	ds.offset = newOffset
+	return newOffset, nil
+}
+
+// fetchFrame fetches a compressed frame from the underlying streamer
+// and decompresses it.
+func (ds *decompressStreamer) fetchFrame(frameIndex uint64) ([]byte, error) {
+	offset, length := ds.staticCompression.Frame(frameIndex)
+	if _, err := ds.staticStreamer.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, errors.AddContext(err, "failed to seek to compressed frame")
+	}
+	compressed := make([]byte, length)
+	if _, err := io.ReadFull(ds.staticStreamer, compressed); err != nil {
+		return nil, errors.AddContext(err, "failed to read compressed frame")
+	}
+	return decompressFrame(ds.staticCompression, frameIndex, compressed)
+}
+
+// newDecompressWriter creates a writer which expects the compressed frames
+// starting at the frame containing offset.
+func newDecompressWriter(w io.Writer, ci siafile.CompressionInfo, offset, length uint64) *decompressWriter {
+	return &decompressWriter{
+		staticCompression: ci,
+		staticWriter:      w,
+		frameIndex:        offset / ci.FrameSize,
+		offset:            offset,
+		length:            length,
+	}
+}
+
+// Write buffers the compressed data until a frame is complete, decompresses
+// the frame and writes the requested part of it to the underlying writer.
+func (dw *decompressWriter) Write(b []byte) (int, error) {
+	dw.buf = append(dw.buf, b...)
+	for dw.length > 0 && dw.frameIndex < dw.staticCompression.NumFrames() {
+		_, frameLen := dw.staticCompression.Frame(dw.frameIndex)
+		if uint64(len(dw.buf)) < frameLen {
+			break
+		}
+		frame, err := decompressFrame(dw.staticCompression, dw.frameIndex, dw.buf[:frameLen])
+		if err != nil {
+			return 0, err
+		}
+		dw.buf = dw.buf[frameLen:]
+
+		// Write the requested part of the frame.
+		start := dw.offset - dw.frameIndex*dw.staticCompression.FrameSize
+		end := uint64(len(frame))
+		if end-start > dw.length {
+			end = start + dw.length
+		}
+		if _, err := dw.staticWriter.Write(frame[start:end]); err != nil {
+			return 0, err
+		}
+		dw.offset += end - start
+		dw.length -= end - start
+		dw.frameIndex++
+	}
+	return len(b), nil
+}
+
+// compressedRange returns the range of the stored data which contains the
+// compressed frames of the uncompressed range [offset, offset+length).
+func compressedRange(ci siafile.CompressionInfo, offset, length uint64) (compressedOffset, compressedLength uint64) {
+	if length == 0 {
+		return 0, 0
+	}
+	firstFrame := offset / ci.FrameSize
+	lastFrame := (offset + length - 1) / ci.FrameSize
+	compressedOffset, _ = ci.Frame(firstFrame)
+	lastOffset, lastLength := ci.Frame(lastFrame)
+	return compressedOffset, lastOffset + lastLength - compressedOffset
+}
+
+// decompressFrame decompresses a frame of a compressed file and checks that
+// the frame has the expected size.
+func decompressFrame(ci siafile.CompressionInfo, frameIndex uint64, compressed []byte) ([]byte, error) {
+	frame, err := ci.Type.Decompress(compressed, ci.FrameSize)
+	if err != nil {
+		return nil, err
+	}
+	expectedSize := ci.FrameSize
+	if remaining := ci.UncompressedSize - frameIndex*ci.FrameSize; remaining < expectedSize {
+		expectedSize = remaining
+	}
+	if uint64(len(frame)) != expectedSize {
+		return nil, errors.New("decompressed frame has unexpected size")
+	}
+	return frame, nil
+}
@@ -0,0 +1,106 @@
+package renter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+)
+
+// bytesStreamer is a helper type which turns a bytes.Reader into a
+// modules.Streamer.
+type bytesStreamer struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer.
+func (bs bytesStreamer) Close() error { return nil }
+
+// TestCompression tests compressing a stream with a compressReader and
+// reading it back with a decompressStreamer and a decompressWriter.
+func TestCompression(t *testing.T) {
+	t.Parallel()
+
+	// Compress 3.5 frames of compressible data.
+	frameSize := uint64(1 << 12)
+	data := bytes.Repeat(fastrand.Bytes(32), int(frameSize*7/2/32))
+	cr := newCompressReader(bytes.NewReader(data), modules.CompressionGzip, frameSize)
+	compressed, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(data) {
+		t.Fatal("data wasn't compressed")
+	}
+	index, size := cr.Index()
+	if size != uint64(len(data)) {
+		t.Fatal("wrong uncompressed size", size, len(data))
+	}
+	if len(index) != 5 || index[4] != uint64(len(compressed)) {
+		t.Fatal("wrong index", index)
+	}
+	ci := siafile.CompressionInfo{
+		Type:             modules.CompressionGzip,
+		FrameSize:        frameSize,
+		Index:            index,
+		UncompressedSize: size,
+	}
+
+	// Read the whole file and a few random ranges from a decompressStreamer.
+	ds := newDecompressStreamer(bytesStreamer{bytes.NewReader(compressed)}, ci)
+	decompressed, err := ioutil.ReadAll(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Fatal("decompressed data doesn't match")
+	}
+	for i := 0; i < 10; i++ {
+		offset := fastrand.Uint64n(size)
+		length := fastrand.Uint64n(size-offset) + 1
+		if _, err := ds.Seek(int64(offset), io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, length)
+		if _, err := io.ReadFull(ds, b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data[offset:offset+length]) {
+			t.Fatal("wrong data after seek", offset, length)
+		}
+
+		// A decompressWriter which receives the compressed range should
+		// write the same data.
+		cOffset, cLength := compressedRange(ci, offset, length)
+		var buf bytes.Buffer
+		dw := newDecompressWriter(&buf, ci, offset, length)
+		if _, err := dw.Write(compressed[cOffset : cOffset+cLength]); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data[offset:offset+length]) {
+			t.Fatal("wrong data written by decompressWriter", offset, length)
+		}
+	}
+	if _, err := ds.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatal("expected EOF at end of file", err)
+	}
+
+	// Corrupted frames should be detected.
+	corrupted := append([]byte{}, compressed...)
+	corrupted[index[1]+10] ^= 0xff
+	ds = newDecompressStreamer(bytesStreamer{bytes.NewReader(corrupted)}, ci)
+	if _, err := ds.Seek(int64(frameSize), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.Read(make([]byte, 1)); err == nil {
+		t.Fatal("corrupted frame wasn't detected")
+	}
+}
@@ -21,6 +21,14 @@ const (
 	// AlertSiafileLowRedundancyThreshold is the health threshold at which we start
 	// registering the LowRedundancy alert for a Siafile.
 	AlertSiafileLowRedundancyThreshold = 0.75
+
+	// AlertMSGSiafileScrubFailed indicates that pieces of a file failed
+	// verification during a scrub.
+	AlertMSGSiafileScrubFailed = "Pieces of the SiaFile mentioned in the 'Cause' failed verification during a scrub"
+
+	// AlertMSGDirQuota indicates that a directory is about to reach its
+	// quota.
+	AlertMSGDirQuota = "The directory mentioned in the 'Cause' is about to reach its quota"
 )
 
 // AlertCauseSiafileLowRedundancy creates a customized "cause" for a siafile
@@ -29,6 +37,18 @@ func AlertCauseSiafileLowRedundancy(siaPath modules.SiaPath, health, redundancy
 	return fmt.Sprintf("Siafile '%v' has a health of %v and redundancy of %v", siaPath.String(), health, redundancy)
 }
 
+// AlertCauseSiafileScrubFailed creates a customized "cause" for a siafile
+// with a certain path of which pieces failed verification during a scrub.
+func AlertCauseSiafileScrubFailed(siaPath modules.SiaPath, failed, checked uint64) string {
+	return fmt.Sprintf("Siafile '%v' has %v of %v checked pieces which failed verification and were queued for repair", siaPath.String(), failed, checked)
+}
+
+// AlertCauseDirQuota creates a customized "cause" for a directory with a
+// certain path and quota utilization.
+func AlertCauseDirQuota(siaPath modules.SiaPath, utilization float64) string {
+	return fmt.Sprintf("Directory '%v' has used %.2f%% of its quota", siaPath.String(), utilization*100)
+}
+
 // Default redundancy parameters.
 var (
 	// syncCheckInterval is how often the repair heap checks the consensus code
@@ -142,6 +162,16 @@ var (
 		Standard: int64(1 << 25), // 32 MiB
 		Testing:  int64(1 << 13), // 8 KiB
 	}).(int64)
+
+	// streamerQuotaChargeSize is the minimum number of bytes a streamer
+	// charges against the quotas of the file's directories at once. Charging
+	// a quota updates the metadata of the directories, so it is done in
+	// batches instead of for every fill of the cache.
+	streamerQuotaChargeSize = build.Select(build.Var{
+		Dev:      int64(1 << 22), // 4 MiB
+		Standard: int64(1 << 26), // 64 MiB
+		Testing:  int64(1 << 15), // 32 KiB
+	}).(int64)
 )
 
 // Default bandwidth usage parameters.
@@ -0,0 +1,504 @@
+package renter
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/bits"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+	"go.sia.tech/siad/types"
+)
+
+// Deduplication Overview:
+// Uploads which set the Dedup flag of the FileUploadParams split the stream
+// into blocks using content-defined chunking. A rolling hash over the last 64
+// bytes of the stream is computed and a block ends wherever the hash matches a
+// pattern. Since the boundaries only depend on the surrounding content,
+// inserting or removing data only changes the blocks around the edit and the
+// following blocks line up with the blocks of the original data again. Blocks
+// are between 3/4 of a chunk and a whole chunk long.
+//
+// Every block is stored in its own chunk of the siafile, followed by zero
+// padding if it is shorter than the chunk. The DedupID of a block is computed
+// from its plaintext, the erasure code and the cipher type. The chunk is then
+// encrypted with a key derived from that ID instead of the file's masterkey
+// which results in the same pieces for the same data. If the index already
+// contains the block, its pieces are added to the new file without uploading
+// anything. Otherwise the chunk is uploaded as usual and added to the index
+// once it becomes available. Once the upload is complete, the offsets of the
+// blocks are stored in the siafile. Readers use them to translate offsets
+// within the file's data into offsets within the padded chunks.
+//
+// The index counts the references to every chunk. Deleting a file removes its
+// references and chunks without references are removed from the index. The
+// renter never deletes sectors from hosts, so the sectors of an unreferenced
+// chunk are dropped once the contracts storing them expire.
+
+const (
+	// dedupIndexFile is the name of the file which contains the dedup index.
+	dedupIndexFile = "dedup.json"
+
+	// dedupBoundaryDivisor determines how often the rolling hash finds a
+	// block boundary. Once the minimum block size is reached, a boundary is
+	// expected every chunkSize/dedupBoundaryDivisor bytes.
+	dedupBoundaryDivisor = 16
+)
+
+var (
+	// errDedupRepair is returned if an upload sets both the Dedup and Repair
+	// flags.
+	errDedupRepair = errors.New("'dedup' and 'repair' can't both be set")
+
+	// errDedupNotStreamed is returned if an upload from a local file sets the
+	// Dedup flag.
+	errDedupNotStreamed = errors.New("deduplication is only supported for streamed uploads")
+
+	// errDedupCompression is returned if an upload sets both the Dedup flag
+	// and the Compression field. Compressing the data would shift the block
+	// boundaries found in the uncompressed data.
+	errDedupCompression = errors.New("deduplicated uploads can't be compressed")
+
+	// cdcGearSpecifier is the specifier used when deriving the table of the
+	// rolling hash which determines the block boundaries.
+	cdcGearSpecifier = types.NewSpecifier("CDCGear")
+
+	// cdcGear maps every byte to the pseudorandom value which is added to the
+	// rolling hash. It is derived deterministically to make sure that all
+	// renters find the same boundaries.
+	cdcGear = func() (gear [256]uint64) {
+		for i := range gear {
+			h := crypto.HashAll(cdcGearSpecifier, uint64(i))
+			gear[i] = binary.LittleEndian.Uint64(h[:8])
+		}
+		return
+	}()
+)
+
+type (
+	// dedupStream keeps track of the deduplicated chunks of a streamed upload.
+	dedupStream struct {
+		// uploaded maps the ids of the chunks which were uploaded by the
+		// stream to the index of the uploaded chunk.
+		uploaded map[siafile.DedupID]uint64
+
+		// duplicates maps the index of a chunk which wasn't uploaded since an
+		// earlier chunk of the same stream has the same content to the index
+		// of that earlier chunk.
+		duplicates map[uint64]uint64
+
+		// offsets contains the offset of every block of the stream followed
+		// by the number of bytes read so far.
+		offsets []uint64
+	}
+
+	// cdcChunker splits a stream into blocks at content-defined boundaries.
+	// The boundaries are found with a gear hash, a rolling hash which only
+	// depends on the last 64 bytes.
+	cdcChunker struct {
+		staticReader  io.Reader
+		staticMinSize int
+		staticMaxSize int
+		staticMask    uint64
+
+		buf []byte
+		err error
+	}
+
+	// dedupStreamer is a modules.Streamer which returns the data of a
+	// deduplicated file without the padding of its chunks.
+	dedupStreamer struct {
+		staticStreamer modules.Streamer
+		staticDedup    siafile.DedupInfo
+
+		offset       int64
+		storedOffset int64
+		mu           sync.Mutex
+	}
+
+	// dedupWriter is an io.Writer which receives the consecutive stored data
+	// of a deduplicated file and writes the part of the file's data between
+	// offset and offset+length to the underlying writer.
+	dedupWriter struct {
+		staticDedup  siafile.DedupInfo
+		staticWriter io.Writer
+
+		storedOffset uint64
+		length       uint64
+	}
+)
+
+// newDedupStream creates a new dedupStream.
+func newDedupStream() *dedupStream {
+	return &dedupStream{
+		uploaded:   make(map[siafile.DedupID]uint64),
+		duplicates: make(map[uint64]uint64),
+		offsets:    []uint64{0},
+	}
+}
+
+// newCDCChunker creates a chunker which splits the stream into blocks of at
+// most chunkSize bytes.
+func newCDCChunker(r io.Reader, chunkSize uint64) *cdcChunker {
+	// The mask selects the topmost bits of the hash since those depend on
+	// the whole window of 64 bytes.
+	maskBits := bits.Len64(chunkSize/dedupBoundaryDivisor) - 1
+	var mask uint64
+	if maskBits > 0 {
+		mask = ^uint64(0) << uint(64-maskBits)
+	}
+	return &cdcChunker{
+		staticReader:  r,
+		staticMinSize: int(chunkSize - chunkSize/4),
+		staticMaxSize: int(chunkSize),
+		staticMask:    mask,
+		buf:           make([]byte, 0, chunkSize),
+	}
+}
+
+// Next returns the next block of the stream and whether there are more blocks
+// after it.
+func (c *cdcChunker) Next() (block []byte, more bool, err error) {
+	if err := c.fill(); err != nil {
+		return nil, false, err
+	}
+	boundary := cdcBoundary(c.buf, c.staticMinSize, c.staticMask)
+	block = append([]byte(nil), c.buf[:boundary]...)
+	c.buf = c.buf[:copy(c.buf, c.buf[boundary:])]
+	if err := c.fill(); err != nil {
+		return nil, false, err
+	}
+	return block, len(c.buf) > 0, nil
+}
+
+// fill reads from the stream until the buffer contains a block of the maximum
+// size or the end of the stream is reached.
+func (c *cdcChunker) fill() error {
+	if c.err != nil || len(c.buf) == c.staticMaxSize {
+		return nil
+	}
+	n := len(c.buf)
+	read, err := io.ReadFull(c.staticReader, c.buf[n:c.staticMaxSize])
+	c.buf = c.buf[:n+read]
+	if errors.Contains(err, io.EOF) || errors.Contains(err, io.ErrUnexpectedEOF) {
+		c.err = io.EOF
+	} else if err != nil {
+		c.err = err
+		return err
+	}
+	return nil
+}
+
+// cdcBoundary returns the length of the first block of data. The block ends
+// at the first position after minSize where the masked bits of the rolling
+// hash are zero or at the end of data.
+func cdcBoundary(data []byte, minSize int, mask uint64) int {
+	var h uint64
+	for i := minSize; i < len(data); i++ {
+		h = h<<1 + cdcGear[data[i]]
+		if h&mask == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// managedDedupStreamChunk marks a chunk of a streamed upload as deduplicated
+// and records the offset of its block. If the block was uploaded before,
+// either by another file or by the same stream, it doesn't need to be uploaded
+// again and false is returned.
+func (r *Renter) managedDedupStreamChunk(fileNode *filesystem.FileNode, ds *dedupStream, chunkIndex uint64, data []byte) (upload bool, err error) {
+	id := r.staticDedupIndex.ID(fileNode.ErasureCode(), fileNode.PieceSize(), fileNode.MasterKey().Type(), data)
+	if err := fileNode.SetChunkDedupID(chunkIndex, id); err != nil {
+		return false, errors.AddContext(err, "failed to set dedup id of chunk")
+	}
+	ds.offsets = append(ds.offsets, ds.offsets[len(ds.offsets)-1]+uint64(len(data)))
+
+	// If the stream is already uploading the same chunk, the pieces are
+	// copied once the upload is done.
+	if sourceIndex, exists := ds.uploaded[id]; exists {
+		ds.duplicates[chunkIndex] = sourceIndex
+		return false, nil
+	}
+
+	// If the chunk is in the index, reuse its pieces.
+	pieces, exists := r.staticDedupIndex.Reference(id)
+	if !exists {
+		ds.uploaded[id] = chunkIndex
+		return true, nil
+	}
+	err = addChunkPieces(fileNode, chunkIndex, pieces)
+	if err != nil {
+		r.staticDedupIndex.Release(id)
+		return false, errors.AddContext(err, "failed to add pieces of deduplicated chunk")
+	}
+	return false, nil
+}
+
+// managedRegisterDedupStream adds the chunks which were uploaded by a stream to
+// the dedup index and copies their pieces to the chunks which have the same
+// content. It needs to be called after all the chunks are available.
+func (r *Renter) managedRegisterDedupStream(fileNode *filesystem.FileNode, ds *dedupStream) error {
+	for id, chunkIndex := range ds.uploaded {
+		pieces, err := fileNode.Pieces(chunkIndex)
+		if err != nil {
+			return errors.AddContext(err, "failed to get pieces of uploaded chunk")
+		}
+		r.staticDedupIndex.Add(id, pieces)
+	}
+	for chunkIndex, sourceIndex := range ds.duplicates {
+		pieces, err := fileNode.Pieces(sourceIndex)
+		if err != nil {
+			return errors.AddContext(err, "failed to get pieces of uploaded chunk")
+		}
+		if err := addChunkPieces(fileNode, chunkIndex, pieces); err != nil {
+			return errors.AddContext(err, "failed to add pieces of deduplicated chunk")
+		}
+		id, _, err := fileNode.ChunkDedupID(chunkIndex)
+		if err != nil {
+			return errors.AddContext(err, "failed to get dedup id of chunk")
+		}
+		r.staticDedupIndex.Add(id, pieces)
+	}
+	return r.staticDedupIndex.Save()
+}
+
+// threadedUpdateDedupStream waits for the chunks uploaded by a stream to finish
+// uploading and then updates the pieces of the chunks in the dedup index and
+// of the duplicate chunks of the stream. Otherwise they would only contain the
+// pieces which were available when the stream returned.
+func (r *Renter) threadedUpdateDedupStream(fileNode *filesystem.FileNode, ds *dedupStream, chunks []*unfinishedUploadChunk) {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	defer func() {
+		if err := fileNode.Close(); err != nil {
+			r.log.Println("WARN: failed to close file after updating dedup index:", err)
+		}
+	}()
+
+	for _, chunk := range chunks {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-chunk.staticUploadCompletedChan:
+		}
+	}
+	for id, chunkIndex := range ds.uploaded {
+		pieces, err := fileNode.Pieces(chunkIndex)
+		if err != nil {
+			r.log.Println("WARN: failed to get pieces of deduplicated chunk:", err)
+			return
+		}
+		r.staticDedupIndex.UpdatePieces(id, pieces)
+	}
+	for chunkIndex, sourceIndex := range ds.duplicates {
+		pieces, err := fileNode.Pieces(sourceIndex)
+		if err != nil {
+			r.log.Println("WARN: failed to get pieces of deduplicated chunk:", err)
+			return
+		}
+		if err := addChunkPieces(fileNode, chunkIndex, pieces); err != nil {
+			r.log.Println("WARN: failed to add pieces of deduplicated chunk:", err)
+			return
+		}
+	}
+	if err := r.staticDedupIndex.Save(); err != nil {
+		r.log.Println("WARN: failed to save dedup index:", err)
+	}
+}
+
+// managedFileDedupIDs returns the ids of the deduplicated chunks of the
+// provided files. The references to those chunks need to be released when the
+// files are deleted.
+func (r *Renter) managedFileDedupIDs(siaPaths ...modules.SiaPath) ([]siafile.DedupID, error) {
+	if r.staticDedupIndex.Len() == 0 {
+		return nil, nil
+	}
+	var ids []siafile.DedupID
+	for _, siaPath := range siaPaths {
+		fileNode, err := r.staticFileSystem.OpenSiaFile(siaPath)
+		if err != nil {
+			return nil, err
+		}
+		fileIDs, err := fileNode.DedupIDs()
+		err = errors.Compose(err, fileNode.Close())
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to get dedup ids of file")
+		}
+		ids = append(ids, fileIDs...)
+	}
+	return ids, nil
+}
+
+// managedReleaseDedupIDs removes the references of a deleted file from the
+// dedup index.
+func (r *Renter) managedReleaseDedupIDs(ids []siafile.DedupID) {
+	if len(ids) == 0 {
+		return
+	}
+	r.staticDedupIndex.Release(ids...)
+	if err := r.staticDedupIndex.Save(); err != nil {
+		r.log.Println("WARN: failed to save dedup index:", err)
+	}
+}
+
+// managedDirFiles returns the siapaths of all the files within a directory and
+// its subdirectories.
+func (r *Renter) managedDirFiles(siaPath modules.SiaPath) ([]modules.SiaPath, error) {
+	var mu sync.Mutex
+	var siaPaths []modules.SiaPath
+	err := r.staticFileSystem.CachedList(siaPath, true, func(fi modules.FileInfo) {
+		mu.Lock()
+		siaPaths = append(siaPaths, fi.SiaPath)
+		mu.Unlock()
+	}, func(modules.DirectoryInfo) {})
+	return siaPaths, err
+}
+
+// addChunkPieces adds the pieces of a chunk to a file unless the file already
+// contains them.
+func addChunkPieces(fileNode *filesystem.FileNode, chunkIndex uint64, pieces [][]siafile.Piece) error {
+	existing, err := fileNode.Pieces(chunkIndex)
+	if err != nil {
+		return err
+	}
+	for pieceIndex, pieceSet := range pieces {
+	LOOP:
+		for _, piece := range pieceSet {
+			for _, p := range existing[pieceIndex] {
+				if p.HostPubKey.Equals(piece.HostPubKey) && p.MerkleRoot == piece.MerkleRoot {
+					continue LOOP
+				}
+			}
+			if err := fileNode.AddPiece(piece.HostPubKey, chunkIndex, uint64(pieceIndex), piece.MerkleRoot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newDedupStreamer wraps the streamer of a deduplicated file. The dedup offsets
+// need to be complete.
+func newDedupStreamer(s modules.Streamer, di siafile.DedupInfo) *dedupStreamer {
+	return &dedupStreamer{
+		staticStreamer: s,
+		staticDedup:    di,
+	}
+}
+
+// Close closes the underlying streamer.
+func (ds *dedupStreamer) Close() error {
+	return ds.staticStreamer.Close()
+}
+
+// Read reads from the file's data. A single Read never returns data of more
+// than one block.
+func (ds *dedupStreamer) Read(b []byte) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	di := ds.staticDedup
+	if ds.offset >= int64(di.Size()) {
+		return 0, io.EOF
+	}
+	blockOffset, blockLength := di.Block(di.BlockIndex(uint64(ds.offset)))
+	if remaining := blockOffset + blockLength - uint64(ds.offset); uint64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+
+	// Skip the padding between two blocks by reading it. Seeking would reset
+	// the cache of the underlying streamer.
+	storedOffset := int64(di.StoredOffset(uint64(ds.offset)))
+	if gap := storedOffset - ds.storedOffset; gap > 0 && gap < int64(di.ChunkSize) {
+		if _, err := io.CopyN(ioutil.Discard, ds.staticStreamer, gap); err != nil {
+			return 0, errors.AddContext(err, "failed to skip padding")
+		}
+	} else if gap != 0 {
+		if _, err := ds.staticStreamer.Seek(storedOffset, io.SeekStart); err != nil {
+			return 0, errors.AddContext(err, "failed to seek to block")
+		}
+	}
+	ds.storedOffset = storedOffset
+
+	n, err := ds.staticStreamer.Read(b)
+	ds.offset += int64(n)
+	ds.storedOffset += int64(n)
+	return n, err
+}
+
+// Seek sets the offset within the file's data for the next Read.
+func (ds *dedupStreamer) Seek(offset int64, whence int) (int64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = 0
+	case io.SeekCurrent:
+		newOffset = ds.offset
+	case io.SeekEnd:
+		newOffset = int64(ds.staticDedup.Size())
+	}
+	newOffset += offset
+	if newOffset < 0 {
+		return ds.offset, errors.New("cannot seek to negative offset")
+	}
+	ds.offset = newOffset
+	return newOffset, nil
+}
+
+// newDedupWriter creates a writer which expects the stored data starting at
+// the stored offset of the byte at offset.
+func newDedupWriter(w io.Writer, di siafile.DedupInfo, offset, length uint64) *dedupWriter {
+	storedOffset, _ := di.StoredRange(offset, length)
+	return &dedupWriter{
+		staticDedup:  di,
+		staticWriter: w,
+		storedOffset: storedOffset,
+		length:       length,
+	}
+}
+
+// Write writes the parts of the stored data which belong to a block to the
+// underlying writer and drops the padding.
+func (dw *dedupWriter) Write(b []byte) (int, error) {
+	written := len(b)
+	chunkSize := dw.staticDedup.ChunkSize
+	for len(b) > 0 && dw.length > 0 {
+		chunkIndex := dw.storedOffset / chunkSize
+		_, blockLength := dw.staticDedup.Block(chunkIndex)
+		offsetInChunk := dw.storedOffset % chunkSize
+		if offsetInChunk >= blockLength {
+			// Skip the padding.
+			n := chunkSize - offsetInChunk
+			if n > uint64(len(b)) {
+				n = uint64(len(b))
+			}
+			b = b[n:]
+			dw.storedOffset += n
+			continue
+		}
+		n := blockLength - offsetInChunk
+		if n > uint64(len(b)) {
+			n = uint64(len(b))
+		}
+		if n > dw.length {
+			n = dw.length
+		}
+		if _, err := dw.staticWriter.Write(b[:n]); err != nil {
+			return 0, err
+		}
+		b = b[n:]
+		dw.storedOffset += n
+		dw.length -= n
+	}
+	return written, nil
+}
@@ -0,0 +1,151 @@
+package renter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+)
+
+// cdcBlocks splits data into blocks using a cdcChunker.
+func cdcBlocks(t *testing.T, data []byte, chunkSize uint64) [][]byte {
+	t.Helper()
+	c := newCDCChunker(bytes.NewReader(data), chunkSize)
+	var blocks [][]byte
+	for more := true; more; {
+		var block []byte
+		var err error
+		block, more, err = c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// TestCDCChunker checks that the chunker respects the block sizes and that the
+// boundaries line up again after data was inserted into a stream.
+func TestCDCChunker(t *testing.T) {
+	t.Parallel()
+
+	chunkSize := uint64(1 << 14)
+	minSize := int(chunkSize - chunkSize/4)
+	data := fastrand.Bytes(int(40 * chunkSize))
+	blocks := cdcBlocks(t, data, chunkSize)
+	if !bytes.Equal(bytes.Join(blocks, nil), data) {
+		t.Fatal("blocks don't match the data")
+	}
+	var maxBlocks int
+	for i, block := range blocks {
+		if len(block) > int(chunkSize) || (len(block) < minSize && i != len(blocks)-1) {
+			t.Fatal("block has invalid size", i, len(block))
+		}
+		if len(block) == int(chunkSize) {
+			maxBlocks++
+		}
+	}
+	if maxBlocks > len(blocks)/2 {
+		t.Fatalf("%v of %v blocks have the maximum size", maxBlocks, len(blocks))
+	}
+
+	// Insert a few bytes after the first quarter of the data. Apart from the
+	// blocks around the insertion, the blocks should be the same.
+	insertAt := len(data) / 4
+	edited := append(append(append([]byte{}, data[:insertAt]...), fastrand.Bytes(100)...), data[insertAt:]...)
+	original := make(map[crypto.Hash]struct{})
+	for _, block := range blocks {
+		original[crypto.HashBytes(block)] = struct{}{}
+	}
+	var shared int
+	for _, block := range cdcBlocks(t, edited, chunkSize) {
+		if _, exists := original[crypto.HashBytes(block)]; exists {
+			shared++
+		}
+	}
+	if shared < len(blocks)*3/4 {
+		t.Fatalf("only %v of %v blocks are shared after an insertion", shared, len(blocks))
+	}
+
+	// Streams which are shorter than the minimum block size result in a
+	// single block.
+	short := fastrand.Bytes(minSize - 1)
+	if blocks := cdcBlocks(t, short, chunkSize); len(blocks) != 1 || !bytes.Equal(blocks[0], short) {
+		t.Fatal("short stream should result in a single block")
+	}
+}
+
+// TestDedupStreamer tests reading the data of a deduplicated file with a
+// dedupStreamer and a dedupWriter.
+func TestDedupStreamer(t *testing.T) {
+	t.Parallel()
+
+	// Store the blocks of some data in padded chunks.
+	chunkSize := uint64(1 << 12)
+	data := fastrand.Bytes(int(chunkSize*10 + 100))
+	di := siafile.DedupInfo{
+		Deduplicated: true,
+		ChunkSize:    chunkSize,
+		Offsets:      []uint64{0},
+	}
+	var stored []byte
+	for _, block := range cdcBlocks(t, data, chunkSize) {
+		di.Offsets = append(di.Offsets, di.Offsets[len(di.Offsets)-1]+uint64(len(block)))
+		padding := (chunkSize - uint64(len(stored))%chunkSize) % chunkSize
+		stored = append(stored, make([]byte, padding)...)
+		stored = append(stored, block...)
+	}
+	if di.Size() != uint64(len(data)) {
+		t.Fatal("wrong size", di.Size(), len(data))
+	}
+
+	// Read the whole file and a few random ranges from a dedupStreamer.
+	ds := newDedupStreamer(bytesStreamer{bytes.NewReader(stored)}, di)
+	read, err := ioutil.ReadAll(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Fatal("read data doesn't match")
+	}
+	size := di.Size()
+	for i := 0; i < 10; i++ {
+		offset := fastrand.Uint64n(size)
+		length := fastrand.Uint64n(size-offset) + 1
+		if _, err := ds.Seek(int64(offset), io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, length)
+		if _, err := io.ReadFull(ds, b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data[offset:offset+length]) {
+			t.Fatal("wrong data after seek", offset, length)
+		}
+
+		// A dedupWriter which receives the stored range should write the
+		// same data.
+		sOffset, sLength := di.StoredRange(offset, length)
+		var buf bytes.Buffer
+		dw := newDedupWriter(&buf, di, offset, length)
+		for _, b := range [][]byte{stored[sOffset : sOffset+sLength/2], stored[sOffset+sLength/2 : sOffset+sLength]} {
+			if _, err := dw.Write(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(buf.Bytes(), data[offset:offset+length]) {
+			t.Fatal("wrong data written by dedupWriter", offset, length)
+		}
+	}
+	if _, err := ds.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatal("expected EOF at end of file", err)
+	}
+}
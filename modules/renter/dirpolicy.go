@@ -0,0 +1,136 @@
+package renter
+
+import (
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+)
+
+// Directory Policy Overview:
+// Every siadir can have a policy which specifies the default erasure coding
+// parameters and cipher type of new files as well as the health at which the
+// files within the siadir are repaired. Fields of the policy which are not set
+// are inherited from the parent directory. Fields which aren't set in any of
+// the parents fall back to the renter's defaults.
+//
+// Uploads which don't specify erasure coding parameters or a cipher type use
+// the effective policy of the directory they are uploaded to. The repair loop
+// uses the repair threshold of the effective policy to decide whether a file
+// needs to be repaired.
+//
+// The lowest repair threshold of all policies is bubbled as the
+// AggregateMinRepairThreshold of the siadirs. Whenever the root is bubbled,
+// the renter's minRepairThreshold is replaced with the root's aggregate, so it
+// goes back up once policies with lower thresholds are changed or deleted.
+
+// DirPolicy returns the effective policy of a siadir which includes the fields
+// inherited from its parent directories.
+func (r *Renter) DirPolicy(siaPath modules.SiaPath) (modules.DirPolicy, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.DirPolicy{}, err
+	}
+	defer r.tg.Done()
+	return r.managedDirPolicy(siaPath)
+}
+
+// SetDirPolicy sets the policy of a siadir.
+func (r *Renter) SetDirPolicy(siaPath modules.SiaPath, policy modules.DirPolicy) (err error) {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if err := policy.Validate(); err != nil {
+		return errors.AddContext(err, "invalid policy")
+	}
+	dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Compose(err, dir.Close())
+	}()
+	if err := dir.SetPolicy(policy); err != nil {
+		return errors.AddContext(err, "unable to set policy")
+	}
+	r.managedTrackRepairThreshold(policy)
+
+	// Bubble the directory to make sure the repair loop picks up the new
+	// repair threshold.
+	_ = r.staticBubbleScheduler.callQueueBubble(siaPath)
+	return nil
+}
+
+// managedDirPolicy returns the effective policy of a siadir. Directories which
+// don't exist yet are skipped which allows for calling managedDirPolicy
+// before the directory of a new file is created.
+func (r *Renter) managedDirPolicy(siaPath modules.SiaPath) (modules.DirPolicy, error) {
+	var policy modules.DirPolicy
+	for {
+		dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+		if err == nil {
+			md, err := dir.Metadata()
+			err = errors.Compose(err, dir.Close())
+			if err != nil {
+				return modules.DirPolicy{}, errors.AddContext(err, "unable to get metadata of siadir")
+			}
+			policy = policy.Inherit(md.Policy)
+		} else if !errors.Contains(err, filesystem.ErrNotExist) {
+			return modules.DirPolicy{}, err
+		}
+		if siaPath.IsRoot() {
+			return policy, nil
+		}
+		siaPath, err = siaPath.Dir()
+		if err != nil {
+			return modules.DirPolicy{}, err
+		}
+	}
+}
+
+// managedFilePolicy returns the effective policy of the directory of a file.
+func (r *Renter) managedFilePolicy(siaPath modules.SiaPath) (modules.DirPolicy, error) {
+	dirSiaPath, err := siaPath.Dir()
+	if err != nil {
+		return modules.DirPolicy{}, err
+	}
+	return r.managedDirPolicy(dirSiaPath)
+}
+
+// managedMinRepairThreshold returns the lowest repair threshold of any
+// directory the renter knows about. A directory heap with a health below that
+// threshold doesn't contain any files which need to be repaired.
+func (r *Renter) managedMinRepairThreshold() float64 {
+	r.minRepairThresholdMu.Lock()
+	defer r.minRepairThresholdMu.Unlock()
+	if r.minRepairThreshold == 0 || r.minRepairThreshold > modules.RepairThreshold {
+		return modules.RepairThreshold
+	}
+	return r.minRepairThreshold
+}
+
+// managedSetMinRepairThreshold replaces the lowest known repair threshold. It
+// is called with the aggregate of the root directory after bubbling it.
+func (r *Renter) managedSetMinRepairThreshold(threshold float64) {
+	r.minRepairThresholdMu.Lock()
+	defer r.minRepairThresholdMu.Unlock()
+	r.minRepairThreshold = threshold
+}
+
+// managedTrackRepairThreshold lowers the lowest known repair threshold to the
+// threshold of a policy. It is called for every policy that is set, so that
+// the new threshold is used before the next bubble of the root.
+func (r *Renter) managedTrackRepairThreshold(policy modules.DirPolicy) {
+	r.minRepairThresholdMu.Lock()
+	defer r.minRepairThresholdMu.Unlock()
+	r.minRepairThreshold = minRepairThreshold(r.minRepairThreshold, policy.RepairThreshold)
+}
+
+// minRepairThreshold returns the lower of two repair thresholds. A threshold
+// of 0 isn't set and is ignored.
+func minRepairThreshold(a, b float64) float64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,130 @@
+package renter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/siatest/dependencies"
+)
+
+// TestDirPolicy tests that directory policies are inherited by subdirectories
+// and used for new uploads.
+func TestDirPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Create an archive dir with a hot subdir.
+	archive := newSiaPath("archive")
+	hot := newSiaPath("archive/hot")
+	if err := r.CreateDir(hot, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+	archivePolicy := modules.DirPolicy{
+		DataPieces:      10,
+		ParityPieces:    40,
+		CipherType:      crypto.TypeXChaCha20,
+		RepairThreshold: 0.1,
+	}
+	if err := r.SetDirPolicy(archive, archivePolicy); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetDirPolicy(hot, modules.DirPolicy{DataPieces: 10, ParityPieces: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetDirPolicy(hot, modules.DirPolicy{DataPieces: 10}); err == nil {
+		t.Fatal("invalid policy was accepted")
+	}
+	if r.managedMinRepairThreshold() != 0.1 {
+		t.Fatal("repair threshold wasn't tracked", r.managedMinRepairThreshold())
+	}
+
+	// Check the effective policies.
+	policy, err := r.DirPolicy(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != archivePolicy {
+		t.Fatal("wrong policy of archive dir", policy)
+	}
+	policy, err = r.DirPolicy(hot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := archivePolicy
+	expected.ParityPieces = 20
+	if policy != expected {
+		t.Fatal("wrong policy of hot dir", policy)
+	}
+	// Dirs that don't exist yet inherit the policy of their parents.
+	policy, err = r.DirPolicy(newSiaPath("archive/hot/new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != expected {
+		t.Fatal("wrong policy of new dir", policy)
+	}
+	// Dirs outside of the archive use the defaults.
+	policy, err = r.DirPolicy(modules.RootSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != (modules.DirPolicy{}) {
+		t.Fatal("root shouldn't have a policy", policy)
+	}
+
+	// Uploads without erasure coding parameters and cipher type should use
+	// the policy.
+	checkUpload := func(siaPath modules.SiaPath, dataPieces, parityPieces int, ct crypto.CipherType) {
+		t.Helper()
+		entry, err := r.managedInitUploadStream(modules.FileUploadParams{SiaPath: siaPath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := entry.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		ec := entry.ErasureCode()
+		if ec.MinPieces() != dataPieces || ec.NumPieces()-ec.MinPieces() != parityPieces {
+			t.Fatalf("expected %v-of-%v but got %v-of-%v", dataPieces, dataPieces+parityPieces, ec.MinPieces(), ec.NumPieces())
+		}
+		if entry.MasterKey().Type() != ct {
+			t.Fatal("wrong cipher type", entry.MasterKey().Type())
+		}
+	}
+	checkUpload(newSiaPath("archive/file"), 10, 40, crypto.TypeXChaCha20)
+	checkUpload(newSiaPath("archive/hot/new/file"), 10, 20, crypto.TypeXChaCha20)
+	checkUpload(newSiaPath("other/file"), modules.RenterDefaultDataPieces, modules.RenterDefaultParityPieces, crypto.TypeDefaultRenter)
+
+	// Removing the repair threshold raises the lowest known threshold again
+	// once the change was bubbled to the root.
+	archivePolicy.RepairThreshold = 0
+	if err := r.SetDirPolicy(archive, archivePolicy); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if threshold := r.managedMinRepairThreshold(); threshold != modules.RepairThreshold {
+			return fmt.Errorf("expected repair threshold %v but got %v", modules.RepairThreshold, threshold)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,271 @@
+package renter
+
+import (
+	"fmt"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+	"go.sia.tech/siad/types"
+)
+
+// Directory Quota Overview:
+// Every siadir can have a quota which limits the aggregate size of the files
+// within it as well as the money spent on uploading and downloading them
+// during an allowance period. In contrast to a directory policy, a quota isn't
+// inherited. An upload or download needs to satisfy the quotas of the
+// directory of the file and of all its parents. This allows for nesting quotas,
+// e.g. a quota for a team and smaller quotas for the team's projects.
+//
+// The renter doesn't know which contract pays for which file, so the spending
+// of an upload or download is estimated upfront from the average prices of
+// the workers' price tables. The estimate is counted against the quotas when
+// the upload or download is admitted. Stored bytes are taken from the
+// aggregate size of the directory which is updated by bubbling.
+//
+// Charging requires walking up the directory tree and updating the metadata
+// of every directory with a quota. To avoid that cost for renters which don't
+// use quotas, the renter persists whether a quota was ever set and skips the
+// walk until one is. Streams charge their downloads in batches of
+// streamerQuotaChargeSize rather than for every fill of their cache.
+
+const (
+	// quotaAlertThreshold is the utilization of a quota at which an alert is
+	// registered for the directory.
+	quotaAlertThreshold = 0.9
+)
+
+var (
+	// ErrQuotaExceeded is returned if an upload or download would exceed the
+	// quota of a directory.
+	ErrQuotaExceeded = errors.New("directory quota exceeded")
+)
+
+type (
+	// quotaCharge is the estimated resource usage of an upload or download
+	// which is counted against the quotas of a file's directories.
+	quotaCharge struct {
+		storedBytes   uint64
+		uploadSpend   types.Currency
+		downloadSpend types.Currency
+	}
+
+	// quotaPrices are the estimated costs of uploading and downloading a
+	// single byte.
+	quotaPrices struct {
+		uploadByte   types.Currency
+		downloadByte types.Currency
+	}
+)
+
+// exceeds returns an error if adding the charge to the usage would exceed a
+// limit of the quota. A limit which is already reached is exceeded by any
+// charge.
+func (c quotaCharge) exceeds(quota modules.DirQuota, storedBytes uint64, usage modules.DirQuotaUsage) error {
+	if quota.MaxStoredBytes != 0 && (storedBytes >= quota.MaxStoredBytes || storedBytes+c.storedBytes > quota.MaxStoredBytes) {
+		return fmt.Errorf("%v stored bytes exceed the limit of %v", storedBytes+c.storedBytes, quota.MaxStoredBytes)
+	}
+	exceedsSpend := func(spent, charge, max types.Currency) bool {
+		return !max.IsZero() && (spent.Cmp(max) >= 0 || spent.Add(charge).Cmp(max) > 0)
+	}
+	if exceedsSpend(usage.UploadSpend, c.uploadSpend, quota.MaxUploadSpend) {
+		return fmt.Errorf("upload spending of %v H exceeds the limit of %v H", usage.UploadSpend.Add(c.uploadSpend), quota.MaxUploadSpend)
+	}
+	if exceedsSpend(usage.DownloadSpend, c.downloadSpend, quota.MaxDownloadSpend) {
+		return fmt.Errorf("download spending of %v H exceeds the limit of %v H", usage.DownloadSpend.Add(c.downloadSpend), quota.MaxDownloadSpend)
+	}
+	return nil
+}
+
+// SetDirQuota sets the quota of a siadir.
+func (r *Renter) SetDirQuota(siaPath modules.SiaPath, quota modules.DirQuota) (err error) {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Compose(err, dir.Close())
+	}()
+
+	// Remember that a quota was set before setting it to make sure that it is
+	// charged right away.
+	if quota.IsSet() {
+		id := r.mu.Lock()
+		if !r.persist.QuotasSet {
+			r.persist.QuotasSet = true
+			err = r.saveSync()
+		}
+		r.mu.Unlock(id)
+		if err != nil {
+			return errors.AddContext(err, "unable to save renter persistence")
+		}
+	}
+	if err := dir.SetQuota(quota); err != nil {
+		return errors.AddContext(err, "unable to set quota")
+	}
+	md, err := dir.Metadata()
+	if err != nil {
+		return errors.AddContext(err, "unable to get metadata of siadir")
+	}
+	r.managedUpdateQuotaAlert(siaPath, md.Quota, md.AggregateSize, r.managedCurrentQuotaUsage(md.QuotaUsage))
+	return nil
+}
+
+// managedCurrentQuotaUsage returns the usage of a quota for the current
+// period. The spending is reset if the usage belongs to a prior period.
+func (r *Renter) managedCurrentQuotaUsage(usage modules.DirQuotaUsage) modules.DirQuotaUsage {
+	period := r.hostContractor.CurrentPeriod()
+	if usage.Period != period {
+		return modules.DirQuotaUsage{Period: period}
+	}
+	return usage
+}
+
+// managedQuotaPrices estimates the prices of uploading and downloading a byte
+// from the price tables of the workers. The price of uploading a byte
+// includes storing it until the end of the current period.
+func (r *Renter) managedQuotaPrices() quotaPrices {
+	var prices quotaPrices
+	var n uint64
+	allowance := r.hostContractor.Allowance()
+	periodEnd := r.hostContractor.CurrentPeriod() + allowance.Period
+	var remaining types.BlockHeight
+	if height := r.cs.Height(); periodEnd > height {
+		remaining = periodEnd - height
+	}
+	for _, w := range r.staticWorkerPool.callWorkers() {
+		wpt := w.staticPriceTable()
+		if !wpt.staticValid() {
+			continue
+		}
+		pt := wpt.staticPriceTable
+		ul := pt.UploadBandwidthCost.Add(pt.WriteLengthCost).Add(pt.WriteStoreCost.Mul64(uint64(remaining)))
+		dl := pt.DownloadBandwidthCost.Add(pt.ReadLengthCost)
+		prices.uploadByte = prices.uploadByte.Add(ul)
+		prices.downloadByte = prices.downloadByte.Add(dl)
+		n++
+	}
+	if n == 0 {
+		return quotaPrices{}
+	}
+	prices.uploadByte = prices.uploadByte.Div64(n)
+	prices.downloadByte = prices.downloadByte.Div64(n)
+	return prices
+}
+
+// managedUploadQuotaCharge estimates the charge of uploading a file of the
+// given size with the given erasure coder.
+func (r *Renter) managedUploadQuotaCharge(size uint64, ec modules.ErasureCoder) quotaCharge {
+	uploaded := size * uint64(ec.NumPieces()) / uint64(ec.DataPieces())
+	return quotaCharge{
+		storedBytes: size,
+		uploadSpend: r.managedQuotaPrices().uploadByte.Mul64(uploaded),
+	}
+}
+
+// managedDownloadQuotaCharge estimates the charge of downloading the given
+// number of bytes.
+func (r *Renter) managedDownloadQuotaCharge(length uint64) quotaCharge {
+	return quotaCharge{
+		downloadSpend: r.managedQuotaPrices().downloadByte.Mul64(length),
+	}
+}
+
+// managedChargeQuotas counts a charge against the quotas of a siadir and all of
+// its parents. If enforce is true, the charge is only counted if it doesn't
+// exceed any of the quotas. Otherwise ErrQuotaExceeded is returned. Directories
+// which don't exist yet are skipped.
+func (r *Renter) managedChargeQuotas(siaPath modules.SiaPath, charge quotaCharge, enforce bool) error {
+	// Nothing to charge if no quota was ever set.
+	id := r.mu.RLock()
+	quotasSet := r.persist.QuotasSet
+	r.mu.RUnlock(id)
+	if !quotasSet {
+		return nil
+	}
+
+	// Checking and charging the quotas needs to be atomic.
+	r.quotaMu.Lock()
+	defer r.quotaMu.Unlock()
+
+	// Open the directories with quotas.
+	var dirs []*filesystem.DirNode
+	var dirSiaPaths []modules.SiaPath
+	defer func() {
+		for _, dir := range dirs {
+			if err := dir.Close(); err != nil {
+				r.log.Println("Failed to close siadir after charging quota:", err)
+			}
+		}
+	}()
+	var quotas []modules.DirQuota
+	var usages []modules.DirQuotaUsage
+	var storedBytes []uint64
+	for {
+		dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+		if err == nil {
+			md, err := dir.Metadata()
+			if err != nil {
+				return errors.Compose(errors.AddContext(err, "unable to get metadata of siadir"), dir.Close())
+			}
+			if md.Quota.IsSet() {
+				dirs = append(dirs, dir)
+				dirSiaPaths = append(dirSiaPaths, siaPath)
+				quotas = append(quotas, md.Quota)
+				usages = append(usages, r.managedCurrentQuotaUsage(md.QuotaUsage))
+				storedBytes = append(storedBytes, md.AggregateSize)
+			} else if err := dir.Close(); err != nil {
+				return err
+			}
+		} else if !errors.Contains(err, filesystem.ErrNotExist) {
+			return err
+		}
+		if siaPath.IsRoot() {
+			break
+		}
+		siaPath, err = siaPath.Dir()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check the quotas.
+	if enforce {
+		for i := range dirs {
+			if err := charge.exceeds(quotas[i], storedBytes[i], usages[i]); err != nil {
+				return errors.Compose(ErrQuotaExceeded, fmt.Errorf("quota of '%v': %v", dirSiaPaths[i], err))
+			}
+		}
+	}
+
+	// Charge the quotas.
+	var errs error
+	for i, dir := range dirs {
+		usage := usages[i]
+		usage.UploadSpend = usage.UploadSpend.Add(charge.uploadSpend)
+		usage.DownloadSpend = usage.DownloadSpend.Add(charge.downloadSpend)
+		if err := dir.SetQuotaUsage(usage); err != nil {
+			errs = errors.Compose(errs, errors.AddContext(err, "unable to update quota usage"))
+			continue
+		}
+		r.managedUpdateQuotaAlert(dirSiaPaths[i], quotas[i], storedBytes[i]+charge.storedBytes, usage)
+	}
+	return errs
+}
+
+// managedUpdateQuotaAlert registers an alert for a directory which is about to
+// reach its quota and unregisters it otherwise.
+func (r *Renter) managedUpdateQuotaAlert(siaPath modules.SiaPath, quota modules.DirQuota, storedBytes uint64, usage modules.DirQuotaUsage) {
+	utilization := quota.Utilization(storedBytes, usage)
+	if utilization < quotaAlertThreshold {
+		r.staticAlerter.UnregisterAlert(modules.AlertIDDirQuota(siaPath))
+		return
+	}
+	r.staticAlerter.RegisterAlert(modules.AlertIDDirQuota(siaPath), AlertMSGDirQuota,
+		AlertCauseDirQuota(siaPath, utilization), modules.SeverityWarning)
+}
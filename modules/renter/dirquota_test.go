@@ -0,0 +1,184 @@
+package renter
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/siatest/dependencies"
+	"go.sia.tech/siad/types"
+)
+
+// TestQuotaChargeExceeds is a unit test for quotaCharge.exceeds.
+func TestQuotaChargeExceeds(t *testing.T) {
+	t.Parallel()
+
+	quota := modules.DirQuota{
+		MaxStoredBytes: 100,
+		MaxUploadSpend: types.NewCurrency64(1000),
+	}
+	usage := modules.DirQuotaUsage{
+		UploadSpend:   types.NewCurrency64(500),
+		DownloadSpend: types.NewCurrency64(1e9), // not limited
+	}
+	tests := []struct {
+		charge      quotaCharge
+		storedBytes uint64
+		exceeds     bool
+	}{
+		{quotaCharge{}, 0, false},
+		{quotaCharge{storedBytes: 100}, 0, false},
+		{quotaCharge{storedBytes: 101}, 0, true},
+		{quotaCharge{storedBytes: 50}, 60, true},
+		{quotaCharge{}, 100, true}, // limit already reached
+		{quotaCharge{uploadSpend: types.NewCurrency64(500)}, 0, false},
+		{quotaCharge{uploadSpend: types.NewCurrency64(501)}, 0, true},
+		{quotaCharge{downloadSpend: types.NewCurrency64(1e9)}, 0, false},
+	}
+	for i, test := range tests {
+		err := test.charge.exceeds(quota, test.storedBytes, usage)
+		if (err != nil) != test.exceeds {
+			t.Errorf("%v: expected exceeds to be %v but got %v", i, test.exceeds, err)
+		}
+	}
+
+	// A quota without limits is never exceeded.
+	if err := (quotaCharge{storedBytes: 1e9}).exceeds(modules.DirQuota{}, 1e9, usage); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDirQuota tests charging the quotas of nested directories.
+func TestDirQuota(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Create a team dir with a project subdir.
+	team := newSiaPath("team")
+	project := newSiaPath("team/project")
+	if err := r.CreateDir(project, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+
+	// Charging is skipped until a quota is set.
+	if r.persist.QuotasSet {
+		t.Fatal("no quota was set yet")
+	}
+	if err := r.managedChargeQuotas(project, quotaCharge{uploadSpend: types.NewCurrency64(1)}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetDirQuota(team, modules.DirQuota{MaxUploadSpend: types.NewCurrency64(1000)}); err != nil {
+		t.Fatal(err)
+	}
+	if !r.persist.QuotasSet {
+		t.Fatal("setting a quota should be remembered")
+	}
+	if err := r.SetDirQuota(project, modules.DirQuota{MaxDownloadSpend: types.NewCurrency64(100)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// usage is a helper to get the quota usage of a directory.
+	usage := func(siaPath modules.SiaPath) modules.DirQuotaUsage {
+		t.Helper()
+		dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := dir.Metadata()
+		if err := errors.Compose(err, dir.Close()); err != nil {
+			t.Fatal(err)
+		}
+		return md.QuotaUsage
+	}
+
+	// Uploads to dirs which don't exist yet are charged to the parents.
+	charge := quotaCharge{uploadSpend: types.NewCurrency64(600)}
+	if err := r.managedChargeQuotas(newSiaPath("team/project/new"), charge, true); err != nil {
+		t.Fatal(err)
+	}
+	if !usage(team).UploadSpend.Equals64(600) || !usage(project).UploadSpend.Equals64(600) {
+		t.Fatal("wrong upload spending", usage(team), usage(project))
+	}
+	if usage(team).Period != r.hostContractor.CurrentPeriod() {
+		t.Fatal("wrong period", usage(team).Period)
+	}
+
+	// Another upload of the same size exceeds the team's quota and isn't
+	// charged.
+	err = r.managedChargeQuotas(project, charge, true)
+	if !errors.Contains(err, ErrQuotaExceeded) {
+		t.Fatal("expected ErrQuotaExceeded but got", err)
+	}
+	if !usage(project).UploadSpend.Equals64(600) {
+		t.Fatal("upload shouldn't have been charged", usage(project))
+	}
+
+	// Uploads outside of the team aren't affected.
+	if err := r.managedChargeQuotas(newSiaPath("other"), charge, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Downloads are limited by the project's quota but the team dir can still
+	// be downloaded from.
+	charge = quotaCharge{downloadSpend: types.NewCurrency64(100)}
+	if err := r.managedChargeQuotas(project, charge, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.managedChargeQuotas(project, charge, true); !errors.Contains(err, ErrQuotaExceeded) {
+		t.Fatal("expected ErrQuotaExceeded but got", err)
+	}
+	if err := r.managedChargeQuotas(team, charge, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project reached its download quota which should register an alert.
+	_, _, warn, _ := r.staticAlerter.Alerts()
+	var found bool
+	for _, alert := range warn {
+		found = found || alert.Msg == AlertMSGDirQuota
+	}
+	if !found {
+		t.Fatal("quota alert wasn't registered")
+	}
+
+	// Charges which aren't enforced are counted even if they exceed the
+	// quota.
+	if err := r.managedChargeQuotas(project, charge, false); err != nil {
+		t.Fatal(err)
+	}
+	if !usage(project).DownloadSpend.Equals64(200) {
+		t.Fatal("wrong download spending", usage(project))
+	}
+
+	// Removing the quota removes the alert.
+	if err := r.SetDirQuota(project, modules.DirQuota{}); err != nil {
+		t.Fatal(err)
+	}
+	_, _, warn, _ = r.staticAlerter.Alerts()
+	for _, alert := range warn {
+		if alert.Msg == AlertMSGDirQuota {
+			t.Fatal("quota alert wasn't unregistered")
+		}
+	}
+
+	// The quota is reported by DirList.
+	dis, err := r.DirList(team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dis[0].Quota.MaxUploadSpend.Equals64(1000) || !dis[0].QuotaUsage.UploadSpend.Equals64(600) {
+		t.Fatal("wrong quota reported", dis[0].Quota, dis[0].QuotaUsage)
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	"gitlab.com/NebulousLabs/errors"
 	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
 )
 
 // CreateDir creates a directory for the renter
@@ -26,7 +28,37 @@ func (r *Renter) DeleteDir(siaPath modules.SiaPath) error {
 		return err
 	}
 	defer r.tg.Done()
-	return r.staticFileSystem.DeleteDir(siaPath)
+
+	err := r.managedDeleteDir(siaPath)
+	if err != nil {
+		return err
+	}
+	// Delete the prior versions of the files within the dir too.
+	return r.managedDeleteDirVersions(siaPath)
+}
+
+// managedDeleteDir removes a directory from the renter without touching the
+// prior versions of the files within it.
+func (r *Renter) managedDeleteDir(siaPath modules.SiaPath) error {
+	// Get the deduplicated chunks of the files within the dir before deleting
+	// it.
+	var dedupIDs []siafile.DedupID
+	if r.staticDedupIndex.Len() > 0 {
+		siaPaths, err := r.managedDirFiles(siaPath)
+		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+			return errors.AddContext(err, "unable to list files of siadir")
+		}
+		dedupIDs, err = r.managedFileDedupIDs(siaPaths...)
+		if err != nil {
+			return errors.AddContext(err, "unable to get deduplicated chunks of siadir")
+		}
+	}
+	err := r.staticFileSystem.DeleteDir(siaPath)
+	if err != nil {
+		return err
+	}
+	r.managedReleaseDedupIDs(dedupIDs)
+	return nil
 }
 
 // DirList lists the directories in a siadir
@@ -42,6 +74,8 @@ func (r *Renter) DirList(siaPath modules.SiaPath) (dis []modules.DirectoryInfo,
 func (r *Renter) managedDirList(siaPath modules.SiaPath) (dis []modules.DirectoryInfo, _ error) {
 	var mu sync.Mutex
 	dlf := func(di modules.DirectoryInfo) {
+		// Don't report the quota usage of a prior period.
+		di.QuotaUsage = r.managedCurrentQuotaUsage(di.QuotaUsage)
 		mu.Lock()
 		dis = append(dis, di)
 		mu.Unlock()
@@ -69,5 +103,10 @@ func (r *Renter) RenameDir(oldPath, newPath modules.SiaPath) error {
 	if newPath.IsRoot() {
 		return errors.New("cannot rename a file to the root directory")
 	}
-	return r.staticFileSystem.RenameDir(oldPath, newPath)
+	err := r.staticFileSystem.RenameDir(oldPath, newPath)
+	if err != nil {
+		return err
+	}
+	// The prior versions of the files within the dir move with it.
+	return r.managedRenameDirVersions(oldPath, newPath)
 }
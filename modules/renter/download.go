@@ -48,6 +48,7 @@ type (
 		staticDestinationType string             // "memory buffer", "http stream", "file", etc.
 		staticLength          uint64             // Length to download starting from the offset.
 		staticOffset          uint64             // Offset within the file to start the download.
+		staticResumable       bool               // Whether the progress of the download is persisted.
 		staticSiaPath         modules.SiaPath    // The path of the siafile at the time the download started.
 		staticUID             modules.DownloadID // unique identifier for the download
 
@@ -65,17 +66,20 @@ type (
 
 	// downloadParams is the set of parameters to use when downloading a file.
 	downloadParams struct {
-		destination       downloadDestination // The place to write the downloaded data.
-		destinationType   string              // "file", "buffer", "http stream", etc.
-		destinationString string              // The string to report to the user for the destination.
-		disableLocalFetch bool                // Whether or not the file can be fetched from disk if available.
-		file              *siafile.Snapshot   // The file to download.
-		latencyTarget     time.Duration       // Workers above this latency will be automatically put on standby initially.
-		length            uint64              // Length of download. Cannot be 0.
-		needsMemory       bool                // Whether new memory needs to be allocated to perform the download.
-		offset            uint64              // Offset within the file to start the download. Must be less than the total filesize.
-		overdrive         int                 // How many extra pieces to download to prevent slow hosts from being a bottleneck.
-		priority          uint64              // Files with a higher priority will be downloaded first.
+		destination       downloadDestination   // The place to write the downloaded data.
+		destinationType   string                // "file", "buffer", "http stream", etc.
+		destinationString string                // The string to report to the user for the destination.
+		disableLocalFetch bool                  // Whether or not the file can be fetched from disk if available.
+		file              *siafile.Snapshot     // The file to download.
+		latencyTarget     time.Duration         // Workers above this latency will be automatically put on standby initially.
+		length            uint64                // Length of download. Cannot be 0.
+		needsMemory       bool                  // Whether new memory needs to be allocated to perform the download.
+		offset            uint64                // Offset within the file to start the download. Must be less than the total filesize.
+		overdrive         int                   // How many extra pieces to download to prevent slow hosts from being a bottleneck.
+		priority          uint64                // Files with a higher priority will be downloaded first.
+		priorityClass     modules.PriorityClass // The class used to share the download capacity with other downloads.
+		resumable         bool                  // Whether the progress of the download is persisted to resume it after a restart.
+		uid               modules.DownloadID    // The ID of the download. A random ID is generated if it's empty.
 
 		staticMemoryManager *memoryManager
 
@@ -200,7 +204,7 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) (modules.DownloadI
 		return "", nil, err
 	}
 	defer r.tg.Done()
-	d, err := r.managedDownload(p)
+	d, err := r.managedDownload(p, nil)
 	if err != nil {
 		return "", nil, err
 	}
@@ -229,7 +233,7 @@ func (r *Renter) DownloadAsync(p modules.RenterDownloadParameters, f func(error)
 		return "", nil, nil, err
 	}
 	defer r.tg.Done()
-	d, err := r.managedDownload(p)
+	d, err := r.managedDownload(p, nil)
 	if err != nil {
 		return "", nil, nil, err
 	}
@@ -243,8 +247,8 @@ func (r *Renter) DownloadAsync(p modules.RenterDownloadParameters, f func(error)
 
 // managedDownload performs a file download using the passed parameters and
 // returns the download object and an error that indicates if the download
-// setup was successful.
-func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *download, err error) {
+// setup was successful. If resume is set, the persisted download is resumed.
+func (r *Renter) managedDownload(p modules.RenterDownloadParameters, resume *persistedDownload) (_ *download, err error) {
 	// Lookup the file associated with the nickname.
 	entry, err := r.staticFileSystem.OpenSiaFile(p.SiaPath)
 	if err != nil {
@@ -269,25 +273,83 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 	if p.Destination != "" && !filepath.IsAbs(p.Destination) {
 		return nil, errors.New("destination must be an absolute path")
 	}
-	if p.Offset == entry.Size() && entry.Size() != 0 {
+	if p.Resumable && isHTTPResp {
+		return nil, errResumableDestination
+	}
+	if p.Resumable && entry.Compression().Compressed() {
+		return nil, errResumableCompressed
+	}
+	if p.Resumable && entry.Dedup().Deduplicated {
+		return nil, errResumableDeduplicated
+	}
+	if resume != nil && resume.FileUID != entry.UID() {
+		return nil, errResumedFileChanged
+	}
+	// The offset and length of compressed files refer to the uncompressed
+	// data and those of deduplicated files to the data without padding.
+	fileSize := entry.Size()
+	compression := entry.Compression()
+	if compression.Compressed() {
+		if compression.Index == nil {
+			return nil, siafile.ErrCompressionIndexIncomplete
+		}
+		fileSize = compression.UncompressedSize
+	}
+	dedup := entry.Dedup()
+	if dedup.Deduplicated {
+		if dedup.Offsets == nil {
+			return nil, siafile.ErrDedupOffsetsIncomplete
+		}
+		fileSize = dedup.Size()
+	}
+	if p.Offset == fileSize && fileSize != 0 {
 		return nil, errors.New("offset equals filesize")
 	}
 	// Sentinel: if length == 0, download the entire file.
 	if p.Length == 0 {
-		if p.Offset > entry.Size() {
+		if p.Offset > fileSize {
 			return nil, errors.New("offset cannot be greater than file size")
 		}
-		p.Length = entry.Size() - p.Offset
+		p.Length = fileSize - p.Offset
 	}
 	// Check whether offset and length is valid.
-	if p.Offset < 0 || p.Offset+p.Length > entry.Size() {
-		return nil, fmt.Errorf("offset and length combination invalid, max byte is at index %d", entry.Size()-1)
+	if p.Offset < 0 || p.Offset+p.Length > fileSize {
+		return nil, fmt.Errorf("offset and length combination invalid, max byte is at index %d", fileSize-1)
+	}
+	// Count the download against the quotas of the file's directories.
+	// Resumed downloads were already counted when they were started.
+	if resume == nil {
+		dirSiaPath, err := p.SiaPath.Dir()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.managedChargeQuotas(dirSiaPath, r.managedDownloadQuotaCharge(p.Length), true); err != nil {
+			return nil, err
+		}
+	}
+
+	// The stored data of compressed and deduplicated files needs to be
+	// filtered before it is written to the destination.
+	var filter func(io.Writer) io.Writer
+	if compression.Compressed() {
+		filter = func(w io.Writer) io.Writer {
+			return newDecompressWriter(w, compression, p.Offset, p.Length)
+		}
+	} else if dedup.Deduplicated {
+		filter = func(w io.Writer) io.Writer {
+			return newDedupWriter(w, dedup, p.Offset, p.Length)
+		}
 	}
 
 	// Instantiate the correct downloadWriter implementation.
 	var dw downloadDestination
 	var destinationType string
-	if isHTTPResp {
+	if isHTTPResp && filter != nil {
+		dw = &downloadDestinationFilter{
+			downloadDestinationWriter: newDownloadDestinationWriter(filter(p.Httpwriter)),
+		}
+		destinationType = "http stream"
+	} else if isHTTPResp {
 		dw = newDownloadDestinationWriter(p.Httpwriter)
 		destinationType = "http stream"
 	} else {
@@ -295,10 +357,17 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 		if err != nil {
 			return nil, err
 		}
-		dw = &downloadDestinationFile{
-			deps:            r.deps,
-			f:               osFile,
-			staticChunkSize: int64(entry.ChunkSize()),
+		if filter != nil {
+			dw = &downloadDestinationFilter{
+				downloadDestinationWriter: newDownloadDestinationWriter(filter(osFile)),
+				staticCloser:              osFile,
+			}
+		} else {
+			dw = &downloadDestinationFile{
+				deps:            r.deps,
+				f:               osFile,
+				staticChunkSize: int64(entry.ChunkSize()),
+			}
 		}
 		destinationType = "file"
 	}
@@ -312,11 +381,26 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 		}
 	}
 
+	// Compressed files are downloaded by fetching the compressed frames which
+	// contain the requested range and deduplicated files by fetching the
+	// stored range including the padding between the blocks.
+	offset, length := p.Offset, p.Length
+	if compression.Compressed() {
+		offset, length = compressedRange(compression, p.Offset, p.Length)
+	} else if dedup.Deduplicated {
+		offset, length = dedup.StoredRange(p.Offset, p.Length)
+	}
+
 	// Prepare snapshot.
-	snap, err := entry.SnapshotRange(p.SiaPath, p.Offset, p.Length)
+	snap, err := entry.SnapshotRange(p.SiaPath, offset, length)
 	if err != nil {
 		return nil, err
 	}
+	// Resumed downloads keep their ID.
+	var uid modules.DownloadID
+	if resume != nil {
+		uid = resume.UID
+	}
 	// Create the download object.
 	d, err := r.managedNewDownload(downloadParams{
 		destination:       dw,
@@ -326,11 +410,14 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 		file:              snap,
 
 		latencyTarget: 25e3 * time.Millisecond, // TODO: high default until full latency support is added.
-		length:        p.Length,
+		length:        length,
 		needsMemory:   true,
-		offset:        p.Offset,
+		offset:        offset,
 		overdrive:     3, // TODO: moderate default until full overdrive support is added.
 		priority:      5, // TODO: moderate default until full priority support is added.
+		priorityClass: modules.PriorityClassUser,
+		resumable:     p.Resumable,
+		uid:           uid,
 
 		staticMemoryManager:    r.userDownloadMemoryManager, // user initiated download
 		staticSpendingCategory: categoryDownload,
@@ -342,6 +429,31 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (_ *downloa
 		return nil, err
 	}
 
+	// Persist resumable downloads. They are removed from the persister once
+	// they succeed or are cancelled.
+	if p.Resumable {
+		if resume == nil {
+			err = r.staticDownloadPersister.callAdd(persistedDownload{
+				UID:               d.UID(),
+				SiaPath:           p.SiaPath,
+				FileUID:           entry.UID(),
+				Destination:       p.Destination,
+				Offset:            p.Offset,
+				Length:            p.Length,
+				DisableLocalFetch: p.DisableDiskFetch,
+			})
+			if err != nil {
+				return nil, errors.Compose(err, dw.(io.Closer).Close())
+			}
+		}
+		d.OnComplete(func(err error) error {
+			if err == nil || errors.Contains(err, modules.ErrDownloadCancelled) {
+				return r.staticDownloadPersister.callRemove(d.UID())
+			}
+			return nil
+		})
+	}
+
 	// Register some cleanup for when the download is done.
 	d.OnComplete(func(_ error) error {
 		// close the destination if possible.
@@ -392,7 +504,8 @@ func (r *Renter) managedNewDownload(params downloadParams) (*download, error) {
 		destination:           params.destination,
 		destinationString:     params.destinationString,
 		staticDestinationType: params.destinationType,
-		staticUID:             modules.DownloadID(hex.EncodeToString(fastrand.Bytes(16))),
+		staticResumable:       params.resumable,
+		staticUID:             params.uid,
 		staticLatencyTarget:   params.latencyTarget,
 		staticLength:          params.length,
 		staticOffset:          params.offset,
@@ -403,6 +516,9 @@ func (r *Renter) managedNewDownload(params downloadParams) (*download, error) {
 		r:            r,
 		staticParams: params,
 	}
+	if d.staticUID == "" {
+		d.staticUID = modules.DownloadID(hex.EncodeToString(fastrand.Bytes(16)))
+	}
 
 	// Update the endTime of the download when it's done. Also nil out the
 	// destination pointer so that the garbage collector does not think any
@@ -468,16 +584,40 @@ func (d *download) Start() error {
 		}
 	}
 
+	// Resumed downloads skip the chunks which were already written to the
+	// destination.
+	skipChunk := make([]bool, maxChunk-minChunk+1)
+	numChunks := uint64(0)
+	for i := minChunk; i <= maxChunk; i++ {
+		skipChunk[i-minChunk] = d.staticResumable && d.r.staticDownloadPersister.callChunkComplete(d.staticUID, i)
+		if !skipChunk[i-minChunk] {
+			numChunks++
+		}
+	}
+	if numChunks == 0 {
+		atomic.StoreUint64(&d.atomicDataReceived, d.staticLength)
+		d.mu.Lock()
+		d.markComplete()
+		d.mu.Unlock()
+		return nil
+	}
+
 	// Queue the downloads for each chunk.
 	writeOffset := int64(0) // where to write a chunk within the download destination.
-	d.chunksRemaining += maxChunk - minChunk + 1
+	d.chunksRemaining += numChunks
 	for i := minChunk; i <= maxChunk; i++ {
+		// Deduplicated chunks are not encrypted with the file's masterkey.
+		key, keyIndex, err := params.file.ChunkCipherKey(i)
+		if err != nil {
+			return errors.AddContext(err, "failed to get cipher key of chunk")
+		}
 		udc := &unfinishedDownloadChunk{
 			destination: params.destination,
 			erasureCode: params.file.ErasureCode(),
-			masterKey:   params.file.MasterKey(),
+			masterKey:   key,
 
 			staticChunkIndex: i,
+			staticKeyIndex:   keyIndex,
 			staticCacheID:    fmt.Sprintf("%v:%v", d.staticSiaPath, i),
 			staticChunkMap:   chunkMaps[i-minChunk],
 			staticChunkSize:  params.file.ChunkSize(),
@@ -498,6 +638,7 @@ func (d *download) Start() error {
 			staticLatencyTarget:    d.staticLatencyTarget + (25 * time.Duration(i-minChunk)), // Increase target by 25ms per chunk.
 			staticNeedsMemory:      params.needsMemory,
 			staticPriority:         params.priority,
+			staticPriorityClass:    params.priorityClass,
 
 			completedPieces:   make([]bool, params.file.ErasureCode().NumPieces()),
 			physicalChunkData: make([][]byte, params.file.ErasureCode().NumPieces()),
@@ -526,6 +667,10 @@ func (d *download) Start() error {
 		// be written.
 		udc.staticWriteOffset = writeOffset
 		writeOffset += int64(udc.staticFetchLength)
+		if skipChunk[i-minChunk] {
+			atomic.AddUint64(&d.atomicDataReceived, udc.staticFetchLength)
+			continue
+		}
 
 		// TODO: Currently all chunks are given overdrive. This should probably
 		// be changed once the hostdb knows how to measure host speed/latency
@@ -558,6 +703,7 @@ func (r *Renter) DownloadByUID(uid modules.DownloadID) (modules.DownloadInfo, bo
 		DestinationType: d.staticDestinationType,
 		Length:          d.staticLength,
 		Offset:          d.staticOffset,
+		Resumable:       d.staticResumable,
 		SiaPath:         d.staticSiaPath,
 
 		Completed:            d.staticComplete(),
@@ -602,6 +748,7 @@ func (r *Renter) DownloadHistory() []modules.DownloadInfo {
 			DestinationType: d.staticDestinationType,
 			Length:          d.staticLength,
 			Offset:          d.staticOffset,
+			Resumable:       d.staticResumable,
 			SiaPath:         d.staticSiaPath,
 
 			Completed:            d.staticComplete(),
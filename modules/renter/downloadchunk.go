@@ -41,7 +41,8 @@ type unfinishedDownloadChunk struct {
 	masterKey   crypto.CipherKey
 
 	// Fetch + Write instructions - read only or otherwise thread safe.
-	staticChunkIndex  uint64                       // Required for deriving the encryption keys for each piece.
+	staticChunkIndex  uint64                       // Index of the chunk within the file.
+	staticKeyIndex    uint64                       // Required for deriving the encryption keys for each piece.
 	staticCacheID     string                       // Used to uniquely identify a chunk in the chunk cache.
 	staticChunkMap    map[string]downloadPieceInfo // Maps from host PubKey to the info for the piece associated with that host
 	staticChunkSize   uint64
@@ -60,6 +61,7 @@ type unfinishedDownloadChunk struct {
 	staticMemoryManager    *memoryManager
 	staticOverdrive        int
 	staticPriority         uint64
+	staticPriorityClass    modules.PriorityClass
 
 	// Download chunk state - need mutex to access.
 	completedPieces   []bool    // Which pieces were downloaded successfully.
@@ -190,6 +192,12 @@ func (udc *unfinishedDownloadChunk) markPieceCompleted(pieceIndex uint64) {
 	}
 }
 
+// staticMemoryRequired returns the amount of memory required to download the
+// chunk. It is equal to the minimum number of pieces plus the overdrive amount.
+func (udc *unfinishedDownloadChunk) staticMemoryRequired() uint64 {
+	return uint64(udc.staticOverdrive+udc.erasureCode.MinPieces()) * udc.staticPieceSize
+}
+
 // returnMemory will check on the status of all the workers and pieces, and
 // determine how much memory is safe to return to the renter. This should be
 // called each time a worker returns, and also after the chunk is recovered.
@@ -234,6 +242,10 @@ func (udc *unfinishedDownloadChunk) threadedRecoverLogicalData() error {
 		udc.mu.Unlock()
 		return errors.AddContext(err, "unable to write to download destination")
 	}
+	// Remember the completed chunk for resumable downloads.
+	if udc.download.staticResumable {
+		udc.managedPersistCompletion()
+	}
 	// finalize the chunk.
 	udc.managedFinalizeRecovery()
 	return nil
@@ -253,7 +265,7 @@ func bytesToRecover(chunkFetchOffset, chunkFetchLength, chunkSize uint64, rs mod
 		return chunkSize
 	}
 	// Else we need to calculate how much data we need to recover.
-	recoveredSegmentSize := uint64(rs.MinPieces()) * segmentSize
+	recoveredSegmentSize := uint64(rs.DataPieces()) * segmentSize
 	_, numSegments := segmentsForRecovery(chunkFetchOffset, chunkFetchLength, rs)
 	return numSegments * recoveredSegmentSize
 }
@@ -269,6 +281,6 @@ func recoveredDataOffset(chunkFetchOffset uint64, rs modules.ErasureCoder) uint6
 		return chunkFetchOffset
 	}
 	// Else we need to adjust the offset a bit.
-	recoveredSegmentSize := uint64(rs.MinPieces()) * segmentSize
+	recoveredSegmentSize := uint64(rs.DataPieces()) * segmentSize
 	return chunkFetchOffset % recoveredSegmentSize
 }
@@ -179,6 +179,16 @@ var (
 	errOffsetAlreadyWritten = errors.New("cannot write to that offset in stream, data already written")
 )
 
+// downloadDestinationFilter is the downloadDestination of a download whose
+// stored data needs to be transformed before it reaches the final destination,
+// e.g. the data of a compressed or deduplicated file. It writes the stored
+// data in order to a filtering writer and closes the final destination when
+// it is closed.
+type downloadDestinationFilter struct {
+	*downloadDestinationWriter
+	staticCloser io.Closer
+}
+
 // newDownloadDestinationWriter takes an io.Writer and converts it
 // into a downloadDestination.
 func newDownloadDestinationWriter(w io.Writer) *downloadDestinationWriter {
@@ -269,3 +279,13 @@ func (ddw *downloadDestinationWriter) WritePieces(ec modules.ErasureCoder, piece
 	ddw.mu.Unlock()
 	return err
 }
+
+// Close closes the writer and the final destination of the download if there
+// is one.
+func (ddf *downloadDestinationFilter) Close() error {
+	err := ddf.downloadDestinationWriter.Close()
+	if ddf.staticCloser != nil {
+		err = errors.Compose(err, ddf.staticCloser.Close())
+	}
+	return err
+}
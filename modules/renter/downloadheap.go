@@ -12,7 +12,6 @@ package renter
 // subsystem.
 
 import (
-	"container/heap"
 	"context"
 	"io"
 	"os"
@@ -69,7 +68,7 @@ func (r *Renter) managedAcquireMemoryForDownloadChunk(udc *unfinishedDownloadChu
 	// need extra memory to decode a bunch of pieces, though I do not believe
 	// our erasure coding has been optimized around this yet, so we may actually
 	// go over the memory limits when we decode pieces.
-	memoryRequired := uint64(udc.staticOverdrive+udc.erasureCode.MinPieces()) * udc.staticPieceSize
+	memoryRequired := udc.staticMemoryRequired()
 	udc.memoryAllocated = memoryRequired
 	return udc.staticMemoryManager.Request(context.Background(), memoryRequired, memoryPriorityHigh)
 }
@@ -103,7 +102,7 @@ func (r *Renter) managedAddChunkToDownloadHeap(udc *unfinishedDownloadChunk) {
 
 	// Put the chunk into the chunk heap.
 	r.downloadHeapMu.Lock()
-	r.downloadHeap.Push(udc)
+	r.downloadHeap.push(udc)
 	r.downloadHeapMu.Unlock()
 }
 
@@ -151,7 +150,7 @@ func (r *Renter) managedNextDownloadChunk() *unfinishedDownloadChunk {
 		if r.downloadHeap.Len() <= 0 {
 			return nil
 		}
-		nextChunk := heap.Pop(r.downloadHeap).(*unfinishedDownloadChunk)
+		nextChunk := r.downloadHeap.pop()
 		if !nextChunk.download.staticComplete() {
 			return nextChunk
 		}
@@ -227,6 +226,9 @@ func (r *Renter) managedTryFetchChunkFromDisk(chunk *unfinishedDownloadChunk) bo
 				// recovery.
 				atomic.AddUint64(&chunk.download.atomicDataReceived, chunk.staticFetchLength)
 				atomic.AddUint64(&chunk.download.atomicTotalDataTransferred, chunk.staticFetchLength)
+				if chunk.download.staticResumable {
+					chunk.managedPersistCompletion()
+				}
 				chunk.managedFinalizeRecovery()
 				chunk.returnMemory()
 			} else {
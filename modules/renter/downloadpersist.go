@@ -0,0 +1,276 @@
+package renter
+
+// downloadpersist.go persists resumable downloads. For every resumable
+// download the renter stores the parameters of the download and a bitmap of
+// the chunks which were already written to the destination. After a restart
+// the downloads are resumed and skip the chunks which are complete.
+//
+// A chunk is only marked as complete after its data was synced to the
+// destination file. Only downloads of uncompressed files to a file on disk
+// can be resumable since other destinations are written sequentially.
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+	"go.sia.tech/siad/persist"
+)
+
+const (
+	// downloadsPersistFilename is the name of the file which contains the
+	// resumable downloads.
+	downloadsPersistFilename = "downloads.json"
+)
+
+var (
+	// downloadsMetadata is the metadata of the persisted downloads.
+	downloadsMetadata = persist.Metadata{
+		Header:  "Renter Downloads",
+		Version: "1.0",
+	}
+
+	// resumeDownloadsDelay is the amount of time the renter waits after
+	// startup before resuming downloads. This gives the workers time to
+	// become ready.
+	resumeDownloadsDelay = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: time.Minute,
+		Testing:  3 * time.Second,
+	}).(time.Duration)
+
+	// errResumableDestination is returned if a resumable download doesn't
+	// download to a file.
+	errResumableDestination = errors.New("only downloads to a file can be resumable")
+
+	// errResumableCompressed is returned if a resumable download is started
+	// for a compressed file.
+	errResumableCompressed = errors.New("downloads of compressed files can't be resumable")
+
+	// errResumableDeduplicated is returned if a resumable download is started
+	// for a deduplicated file.
+	errResumableDeduplicated = errors.New("downloads of deduplicated files can't be resumable")
+
+	// errResumedFileChanged is returned if the file of a resumed download was
+	// replaced since the download was started.
+	errResumedFileChanged = errors.New("file changed since the download was started")
+)
+
+type (
+	// persistedDownload contains the information needed to resume a
+	// download.
+	persistedDownload struct {
+		UID               modules.DownloadID `json:"uid"`
+		SiaPath           modules.SiaPath    `json:"siapath"`
+		FileUID           siafile.SiafileUID `json:"fileuid"`
+		Destination       string             `json:"destination"`
+		Offset            uint64             `json:"offset"`
+		Length            uint64             `json:"length"`
+		DisableLocalFetch bool               `json:"disablelocalfetch"`
+
+		// CompletedChunks is a bitmap of the chunks which were written to
+		// the destination. The bit of a chunk is determined by its index
+		// within the file.
+		CompletedChunks []byte `json:"completedchunks"`
+	}
+
+	// downloadPersister keeps track of the resumable downloads and persists
+	// them to disk.
+	downloadPersister struct {
+		downloads map[modules.DownloadID]*persistedDownload
+
+		staticPath string
+		mu         sync.Mutex
+	}
+)
+
+// chunkComplete returns whether the chunk with the given index was written to
+// the destination.
+func (pd *persistedDownload) chunkComplete(chunkIndex uint64) bool {
+	if chunkIndex/8 >= uint64(len(pd.CompletedChunks)) {
+		return false
+	}
+	return pd.CompletedChunks[chunkIndex/8]&(1<<(chunkIndex%8)) != 0
+}
+
+// markChunkComplete marks the chunk with the given index as complete.
+func (pd *persistedDownload) markChunkComplete(chunkIndex uint64) {
+	for chunkIndex/8 >= uint64(len(pd.CompletedChunks)) {
+		pd.CompletedChunks = append(pd.CompletedChunks, 0)
+	}
+	pd.CompletedChunks[chunkIndex/8] |= 1 << (chunkIndex % 8)
+}
+
+// newDownloadPersister creates a new downloadPersister and loads the persisted
+// downloads from disk.
+func newDownloadPersister(persistDir string) (*downloadPersister, error) {
+	dp := &downloadPersister{
+		downloads:  make(map[modules.DownloadID]*persistedDownload),
+		staticPath: filepath.Join(persistDir, downloadsPersistFilename),
+	}
+	var downloads []*persistedDownload
+	err := persist.LoadJSON(downloadsMetadata, &downloads, dp.staticPath)
+	if os.IsNotExist(err) {
+		return dp, nil
+	}
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to load persisted downloads")
+	}
+	for _, pd := range downloads {
+		dp.downloads[pd.UID] = pd
+	}
+	return dp, nil
+}
+
+// save persists the downloads. The caller needs to hold the lock.
+func (dp *downloadPersister) save() error {
+	downloads := make([]*persistedDownload, 0, len(dp.downloads))
+	for _, pd := range dp.downloads {
+		downloads = append(downloads, pd)
+	}
+	return persist.SaveJSON(downloadsMetadata, downloads, dp.staticPath)
+}
+
+// callAdd adds a download to the persister.
+func (dp *downloadPersister) callAdd(pd persistedDownload) error {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	dp.downloads[pd.UID] = &pd
+	return dp.save()
+}
+
+// callChunkComplete returns whether the chunk with the given index of the
+// download was written to the destination.
+func (dp *downloadPersister) callChunkComplete(uid modules.DownloadID, chunkIndex uint64) bool {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	pd, exists := dp.downloads[uid]
+	return exists && pd.chunkComplete(chunkIndex)
+}
+
+// callDownloads returns copies of all persisted downloads.
+func (dp *downloadPersister) callDownloads() []persistedDownload {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	downloads := make([]persistedDownload, 0, len(dp.downloads))
+	for _, pd := range dp.downloads {
+		cpy := *pd
+		cpy.CompletedChunks = append([]byte{}, pd.CompletedChunks...)
+		downloads = append(downloads, cpy)
+	}
+	return downloads
+}
+
+// callMarkChunkComplete marks a chunk of the download as complete.
+func (dp *downloadPersister) callMarkChunkComplete(uid modules.DownloadID, chunkIndex uint64) error {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	pd, exists := dp.downloads[uid]
+	if !exists {
+		return nil
+	}
+	pd.markChunkComplete(chunkIndex)
+	return dp.save()
+}
+
+// callRemove removes a download from the persister.
+func (dp *downloadPersister) callRemove(uid modules.DownloadID) error {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	if _, exists := dp.downloads[uid]; !exists {
+		return nil
+	}
+	delete(dp.downloads, uid)
+	return dp.save()
+}
+
+// managedPersistCompletion syncs the data of a completed chunk of a resumable
+// download to the destination and marks the chunk as complete. Failing to do
+// so isn't fatal, the chunk is just downloaded again when the download is
+// resumed.
+func (udc *unfinishedDownloadChunk) managedPersistCompletion() {
+	d := udc.download
+	if ddf, ok := udc.destination.(*downloadDestinationFile); ok {
+		if err := ddf.f.Sync(); err != nil {
+			d.r.log.Printf("Failed to sync chunk %v of download %v: %v", udc.staticChunkIndex, d.staticUID, err)
+			return
+		}
+	}
+	err := d.r.staticDownloadPersister.callMarkChunkComplete(d.staticUID, udc.staticChunkIndex)
+	if err != nil {
+		d.r.log.Printf("Failed to persist completion of chunk %v of download %v: %v", udc.staticChunkIndex, d.staticUID, err)
+	}
+}
+
+// ResumeDownloads resumes all persisted downloads which are not in progress.
+func (r *Renter) ResumeDownloads() error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	var errs error
+	for _, pd := range r.staticDownloadPersister.callDownloads() {
+		// Skip downloads which are in progress.
+		r.downloadHistoryMu.Lock()
+		d, exists := r.downloadHistory[pd.UID]
+		r.downloadHistoryMu.Unlock()
+		if exists && !d.staticComplete() {
+			continue
+		}
+		err := r.managedResumeDownload(pd)
+		if err != nil {
+			errs = errors.Compose(errs, errors.AddContext(err, "failed to resume download of "+pd.SiaPath.String()))
+		}
+	}
+	return errs
+}
+
+// managedResumeDownload resumes a persisted download.
+func (r *Renter) managedResumeDownload(pd persistedDownload) error {
+	// If the destination was removed, the download starts from scratch.
+	if _, err := os.Stat(pd.Destination); os.IsNotExist(err) && len(pd.CompletedChunks) > 0 {
+		pd.CompletedChunks = nil
+		if err := r.staticDownloadPersister.callAdd(pd); err != nil {
+			return err
+		}
+	}
+	d, err := r.managedDownload(modules.RenterDownloadParameters{
+		Destination:      pd.Destination,
+		DisableDiskFetch: pd.DisableLocalFetch,
+		Length:           pd.Length,
+		Offset:           pd.Offset,
+		SiaPath:          pd.SiaPath,
+		Resumable:        true,
+	}, &pd)
+	if errors.Contains(err, errResumedFileChanged) || errors.Contains(err, filesystem.ErrNotExist) {
+		// The download can never be resumed.
+		return errors.Compose(err, r.staticDownloadPersister.callRemove(pd.UID))
+	}
+	if err != nil {
+		return err
+	}
+	return d.Start()
+}
+
+// threadedResumeDownloads resumes the persisted downloads after startup.
+func (r *Renter) threadedResumeDownloads() {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	select {
+	case <-r.tg.StopChan():
+		return
+	case <-time.After(resumeDownloadsDelay):
+	}
+	if err := r.ResumeDownloads(); err != nil {
+		r.log.Println("Failed to resume downloads:", err)
+	}
+}
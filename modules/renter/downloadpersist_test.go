@@ -0,0 +1,136 @@
+package renter
+
+import (
+	"os"
+	"testing"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+)
+
+// TestPersistedDownloadCompletedChunks is a unit test for the bitmap of
+// completed chunks of a persistedDownload.
+func TestPersistedDownloadCompletedChunks(t *testing.T) {
+	t.Parallel()
+
+	var pd persistedDownload
+	if pd.chunkComplete(0) || pd.chunkComplete(100) {
+		t.Fatal("chunks shouldn't be complete")
+	}
+	completed := []uint64{0, 7, 8, 63, 100}
+	for _, i := range completed {
+		pd.markChunkComplete(i)
+	}
+	if len(pd.CompletedChunks) != 13 {
+		t.Fatal("unexpected bitmap length", len(pd.CompletedChunks))
+	}
+	for i := uint64(0); i < 120; i++ {
+		expected := false
+		for _, j := range completed {
+			expected = expected || i == j
+		}
+		if pd.chunkComplete(i) != expected {
+			t.Fatalf("chunk %v: expected %v but was %v", i, expected, !expected)
+		}
+	}
+}
+
+// TestDownloadPersister is a unit test for the downloadPersister.
+func TestDownloadPersister(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testdir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(testdir, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a persister and add two downloads.
+	dp, err := newDownloadPersister(testdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dp.callDownloads()) != 0 {
+		t.Fatal("persister should be empty")
+	}
+	pd1 := persistedDownload{
+		UID:         "download1",
+		SiaPath:     modules.RandomSiaPath(),
+		FileUID:     "file1",
+		Destination: "/tmp/file1",
+		Length:      100,
+	}
+	pd2 := persistedDownload{
+		UID:         "download2",
+		SiaPath:     modules.RandomSiaPath(),
+		FileUID:     "file2",
+		Destination: "/tmp/file2",
+		Offset:      10,
+		Length:      200,
+	}
+	if err := dp.callAdd(pd1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dp.callAdd(pd2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mark some chunks as complete.
+	if err := dp.callMarkChunkComplete(pd1.UID, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := dp.callMarkChunkComplete(pd2.UID, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Marking the chunk of an unknown download is a no-op.
+	if err := dp.callMarkChunkComplete("unknown", 0); err != nil {
+		t.Fatal(err)
+	}
+	if !dp.callChunkComplete(pd1.UID, 3) || dp.callChunkComplete(pd1.UID, 0) {
+		t.Fatal("wrong chunks are complete")
+	}
+
+	// Reload the persister.
+	dp, err = newDownloadPersister(testdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads := dp.callDownloads()
+	if len(downloads) != 2 {
+		t.Fatal("expected 2 downloads but got", len(downloads))
+	}
+	for _, pd := range downloads {
+		expected := pd1
+		if pd.UID == pd2.UID {
+			expected = pd2
+		}
+		if pd.SiaPath != expected.SiaPath || pd.FileUID != expected.FileUID || pd.Destination != expected.Destination ||
+			pd.Offset != expected.Offset || pd.Length != expected.Length {
+			t.Fatal("download wasn't persisted correctly", pd, expected)
+		}
+	}
+	if !dp.callChunkComplete(pd1.UID, 3) || !dp.callChunkComplete(pd2.UID, 0) {
+		t.Fatal("completed chunks weren't persisted")
+	}
+
+	// Modifying a returned download doesn't modify the persister.
+	downloads[0].markChunkComplete(50)
+	if dp.callChunkComplete(downloads[0].UID, 50) {
+		t.Fatal("persister shouldn't be modified")
+	}
+
+	// Remove a download and reload again.
+	if err := dp.callRemove(pd1.UID); err != nil {
+		t.Fatal(err)
+	}
+	dp, err = newDownloadPersister(testdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads = dp.callDownloads()
+	if len(downloads) != 1 || downloads[0].UID != pd2.UID {
+		t.Fatal("wrong downloads after removal", downloads)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"gitlab.com/NebulousLabs/errors"
 
+	"go.sia.tech/siad/crypto"
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/modules/renter/filesystem"
 	"go.sia.tech/siad/modules/renter/filesystem/siafile"
@@ -63,6 +64,11 @@ type (
 		readErr                 error
 		targetCacheSize         int64
 
+		// quotaPrepaid is the number of bytes which were charged against the
+		// quotas of the file's directories but haven't been fetched yet. It
+		// is only accessed by threadedFillCache.
+		quotaPrepaid int64
+
 		// Mutex to protect the offset variable, and all of the cacheing
 		// variables.
 		mu sync.Mutex
@@ -166,6 +172,32 @@ func (s *streamer) managedFillCache() bool {
 		fetchLen = fileSize - fetchOffset
 	}
 
+	// Count the fetched data against the quotas of the file's directories.
+	// If the data wasn't charged yet, at least streamerQuotaChargeSize bytes
+	// are charged at once, without going beyond the end of the file.
+	if fetchLen > s.quotaPrepaid {
+		chargeLen := fetchLen - s.quotaPrepaid
+		if chargeLen < streamerQuotaChargeSize {
+			chargeLen = streamerQuotaChargeSize
+		}
+		if remaining := fileSize - fetchOffset - s.quotaPrepaid; chargeLen > remaining {
+			chargeLen = remaining
+		}
+		dirSiaPath, err := s.staticFile.SiaPath().Dir()
+		if err == nil {
+			err = s.r.managedChargeQuotas(dirSiaPath, s.r.managedDownloadQuotaCharge(uint64(chargeLen)), true)
+		}
+		if err != nil {
+			s.mu.Lock()
+			s.readErr = errors.Compose(s.readErr, err)
+			s.mu.Unlock()
+			s.r.log.Println("Error charging quotas for stream file:", err)
+			return false
+		}
+		s.quotaPrepaid += chargeLen
+	}
+	s.quotaPrepaid -= fetchLen
+
 	// Perform the actual download.
 	buffer := bytes.NewBuffer([]byte{})
 	ddw := newDownloadDestinationWriter(buffer)
@@ -182,6 +214,7 @@ func (s *streamer) managedFillCache() bool {
 		offset:        uint64(fetchOffset),
 		overdrive:     5,    // TODO: high default until full overdrive support is added.
 		priority:      1000, // TODO: high default until full priority support is added.
+		priorityClass: modules.PriorityClassInteractive,
 
 		staticMemoryManager:    s.r.userDownloadMemoryManager, // user initiated download
 		staticSpendingCategory: categoryDownload,
@@ -492,10 +525,76 @@ func (r *Renter) Streamer(siaPath modules.SiaPath, disableLocalFetch bool) (_ st
 	if err != nil {
 		return "", nil, err
 	}
-	s := r.managedStreamer(snap, disableLocalFetch)
+	s, err := r.managedStreamer(snap, disableLocalFetch)
+	if err != nil {
+		return "", nil, err
+	}
 	return siaPath.String(), s, nil
 }
 
+// StreamInfo returns the name, size, modification time and ETag of the file at
+// siaPath. It doesn't fetch any data from the hosts.
+func (r *Renter) StreamInfo(siaPath modules.SiaPath) (_ modules.StreamInfo, err error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.StreamInfo{}, err
+	}
+	defer r.tg.Done()
+
+	node, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return modules.StreamInfo{}, err
+	}
+	defer func() {
+		err = errors.Compose(err, node.Close())
+	}()
+	snap, err := node.Snapshot(siaPath)
+	if err != nil {
+		return modules.StreamInfo{}, err
+	}
+
+	// The streamer of a compressed file returns the decompressed data and the
+	// streamer of a deduplicated file the data without padding.
+	size := snap.Size()
+	if compression := snap.Compression(); compression.Compressed() {
+		if compression.Index == nil {
+			return modules.StreamInfo{}, siafile.ErrCompressionIndexIncomplete
+		}
+		size = compression.UncompressedSize
+	}
+	if dedup := snap.Dedup(); dedup.Deduplicated {
+		if dedup.Offsets == nil {
+			return modules.StreamInfo{}, siafile.ErrDedupOffsetsIncomplete
+		}
+		size = dedup.Size()
+	}
+	return modules.StreamInfo{
+		Name:    siaPath.String(),
+		Size:    size,
+		ModTime: node.ModTime(),
+		ETag:    streamETag(snap, size, node.ModTime()),
+	}, nil
+}
+
+// streamETag derives an ETag for the content of a file from its UID, its size,
+// its modification time and the Merkle roots of its pieces. Repairs upload the
+// same encrypted pieces again, so the root of a piece index doesn't change
+// when a host is replaced. Using one root per piece index in piece order, and
+// an empty root for piece indices without a piece, keeps the ETag stable
+// while the file is being repaired.
+func streamETag(snap *siafile.Snapshot, size uint64, modTime time.Time) string {
+	var roots []crypto.Hash
+	for chunkIndex := uint64(0); chunkIndex < snap.NumChunks(); chunkIndex++ {
+		for _, pieces := range snap.Pieces(chunkIndex) {
+			var root crypto.Hash
+			if len(pieces) > 0 {
+				root = pieces[0].MerkleRoot
+			}
+			roots = append(roots, root)
+		}
+	}
+	return crypto.HashAll(snap.UID(), size, modTime.UnixNano(), roots).String()
+}
+
 // StreamerByNode will open a streamer for the renter, taking a FileNode as
 // input instead of a siapath. This is important for fuse, which has filenodes
 // that could be getting renamed before the streams are opened.
@@ -511,13 +610,21 @@ func (r *Renter) StreamerByNode(node *filesystem.FileNode, disableLocalFetch boo
 	if err != nil {
 		return nil, err
 	}
-	s := r.managedStreamer(snap, disableLocalFetch)
-	return s, nil
+	return r.managedStreamer(snap, disableLocalFetch)
 }
 
 // managedStreamer creates a streamer from a siafile snapshot and starts filling
-// its cache.
-func (r *Renter) managedStreamer(snapshot *siafile.Snapshot, disableLocalFetch bool) modules.Streamer {
+// its cache. The streamer of a compressed file returns the decompressed data
+// and the streamer of a deduplicated file the data without padding.
+func (r *Renter) managedStreamer(snapshot *siafile.Snapshot, disableLocalFetch bool) (modules.Streamer, error) {
+	compression := snapshot.Compression()
+	if compression.Compressed() && compression.Index == nil {
+		return nil, siafile.ErrCompressionIndexIncomplete
+	}
+	dedup := snapshot.Dedup()
+	if dedup.Deduplicated && dedup.Offsets == nil {
+		return nil, siafile.ErrDedupOffsetsIncomplete
+	}
 	s := &streamer{
 		staticFile: snapshot,
 		r:          r,
@@ -528,5 +635,11 @@ func (r *Renter) managedStreamer(snapshot *siafile.Snapshot, disableLocalFetch b
 		targetCacheSize:         initialStreamerCacheSize,
 	}
 	go s.threadedFillCache()
-	return s
+	if compression.Compressed() {
+		return newDecompressStreamer(s, compression), nil
+	}
+	if dedup.Deduplicated {
+		return newDedupStreamer(s, dedup), nil
+	}
+	return s, nil
 }
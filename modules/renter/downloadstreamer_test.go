@@ -0,0 +1,119 @@
+package renter
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/siatest/dependencies"
+	"go.sia.tech/siad/types"
+)
+
+// TestStreamInfoETag checks that the ETag of a file changes when pieces are
+// uploaded for the first time but not when the file is repaired.
+func TestStreamInfoETag(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create a file with two chunks.
+	siaPath := modules.RandomSiaPath()
+	rsc, _ := modules.NewRSCode(1, 2)
+	err = rt.renter.staticFileSystem.NewSiaFile(siaPath, "", rsc, crypto.GenerateSiaKey(crypto.RandomCipherType()), 2*modules.SectorSize, persist.DefaultDiskPermissionsTest, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := rt.renter.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := node.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	etag := func() string {
+		t.Helper()
+		si, err := rt.renter.StreamInfo(siaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return si.ETag
+	}
+
+	// Upload the pieces of the file to one host per piece index.
+	roots := make([][]crypto.Hash, node.NumChunks())
+	for chunkIndex := range roots {
+		for pieceIndex := 0; pieceIndex < rsc.NumPieces(); pieceIndex++ {
+			root := crypto.HashBytes(fastrand.Bytes(16))
+			roots[chunkIndex] = append(roots[chunkIndex], root)
+			pk := types.SiaPublicKey{Key: fastrand.Bytes(crypto.PublicKeySize)}
+			if err := node.AddPiece(pk, uint64(chunkIndex), uint64(pieceIndex), root); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	uploaded := etag()
+	modTime := node.ModTime()
+
+	// Repair the file by uploading the same pieces to new hosts and removing
+	// the pieces of the old ones.
+	var oldPieces [][]siafile.Piece
+	for chunkIndex := range roots {
+		pieces, err := node.Pieces(uint64(chunkIndex))
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldPieces = append(oldPieces, nil)
+		for pieceIndex, root := range roots[chunkIndex] {
+			oldPieces[chunkIndex] = append(oldPieces[chunkIndex], pieces[pieceIndex][0])
+			pk := types.SiaPublicKey{Key: fastrand.Bytes(crypto.PublicKeySize)}
+			if err := node.AddPiece(pk, uint64(chunkIndex), uint64(pieceIndex), root); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if etag() != uploaded {
+		t.Fatal("ETag changed after uploading pieces to new hosts")
+	}
+	for chunkIndex, pieces := range oldPieces {
+		for pieceIndex, piece := range pieces {
+			if err := node.RemovePiece(piece.HostPubKey, uint64(chunkIndex), uint64(pieceIndex), piece.MerkleRoot); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if etag() != uploaded {
+		t.Fatal("ETag changed after removing the pieces of the old hosts")
+	}
+	if !node.ModTime().Equal(modTime) {
+		t.Fatal("ModTime changed by the repair")
+	}
+
+	// Replacing the file with a new upload changes the ETag.
+	if err := rt.renter.staticFileSystem.DeleteFile(siaPath); err != nil {
+		t.Fatal(err)
+	}
+	err = rt.renter.staticFileSystem.NewSiaFile(siaPath, "", rsc, crypto.GenerateSiaKey(crypto.RandomCipherType()), 2*modules.SectorSize, persist.DefaultDiskPermissionsTest, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag() == uploaded {
+		t.Fatal("ETag didn't change after replacing the file")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"go.sia.tech/siad/build"
 	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
 
 	"gitlab.com/NebulousLabs/errors"
 )
@@ -18,11 +19,30 @@ func (r *Renter) DeleteFile(siaPath modules.SiaPath) error {
 	}
 	defer r.tg.Done()
 
+	err = r.managedDeleteFile(siaPath)
+	if err != nil {
+		return err
+	}
+	// Delete the prior versions of the file too.
+	return r.managedDeleteFileVersions(siaPath)
+}
+
+// managedDeleteFile removes a file entry from the renter without touching the
+// prior versions of the file.
+func (r *Renter) managedDeleteFile(siaPath modules.SiaPath) error {
+	// Get the deduplicated chunks of the file before deleting it. If that
+	// fails, the delete operation will most likely fail too.
+	dedupIDs, err := r.managedFileDedupIDs(siaPath)
+	if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+		return errors.AddContext(err, "unable to get deduplicated chunks of siafile")
+	}
+
 	// Perform the delete operation.
 	err = r.staticFileSystem.DeleteFile(siaPath)
 	if err != nil {
 		return errors.AddContext(err, "unable to delete siafile from filesystem")
 	}
+	r.managedReleaseDedupIDs(dedupIDs)
 
 	// Update the filesystem metadata.
 	//
@@ -97,6 +117,29 @@ func (r *Renter) RenameFile(currentName, newName modules.SiaPath) error {
 	}
 	defer r.tg.Done()
 
+	err := r.managedRenameFile(currentName, newName)
+	if err != nil {
+		return err
+	}
+	// The prior versions of the file move with it.
+	return r.managedRenameFileVersions(currentName, newName)
+}
+
+// ReplaceFile renames a file to the siapath of another file. The replaced file
+// is archived like a file which is overwritten by a forced upload. If there is
+// no file at the new siapath, ReplaceFile behaves like RenameFile. If the
+// rename fails, the replaced file is left in place.
+func (r *Renter) ReplaceFile(siaPath, replacedSiaPath modules.SiaPath) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.managedReplaceFile(siaPath, replacedSiaPath)
+}
+
+// managedRenameFile renames a file without touching the prior versions of the
+// file.
+func (r *Renter) managedRenameFile(currentName, newName modules.SiaPath) error {
 	// Rename file.
 	err := r.staticFileSystem.RenameFile(currentName, newName)
 	if err != nil {
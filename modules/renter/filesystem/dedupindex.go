@@ -0,0 +1,196 @@
+package filesystem
+
+import (
+	"os"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+	"go.sia.tech/siad/persist"
+)
+
+const (
+	// dedupSecretSize is the size of the secret which is mixed into the
+	// DedupIDs of the chunks.
+	dedupSecretSize = 32
+)
+
+var (
+	// dedupIndexMetadata is the metadata of the dedup index's persist file.
+	dedupIndexMetadata = persist.Metadata{
+		Header:  "Dedup Index",
+		Version: "1.0",
+	}
+)
+
+type (
+	// DedupIndex keeps track of the chunks which were uploaded with
+	// deduplication enabled. For every chunk it stores the pieces which were
+	// uploaded for it and the number of chunks across all siafiles which
+	// reference it. A chunk is only removed from the index once no siafile
+	// references it any more.
+	//
+	// The index is kept in memory and only written to disk when Save is
+	// called. Losing updates is safe since a chunk which is missing from the
+	// index simply won't be deduplicated against.
+	DedupIndex struct {
+		entries      map[siafile.DedupID]*dedupEntry
+		staticSecret []byte
+		staticPath   string
+		dirty        bool
+		mu           sync.Mutex
+	}
+
+	// dedupEntry is an entry of the DedupIndex.
+	dedupEntry struct {
+		RefCount uint64            `json:"refcount"`
+		Pieces   [][]siafile.Piece `json:"pieces"`
+	}
+
+	// dedupIndexPersist is the on-disk representation of the DedupIndex.
+	dedupIndexPersist struct {
+		Secret  []byte                          `json:"secret"`
+		Entries map[siafile.DedupID]*dedupEntry `json:"entries"`
+	}
+)
+
+// NewDedupIndex loads the DedupIndex persisted at the given path or creates a
+// new one if the file doesn't exist yet.
+func NewDedupIndex(path string) (*DedupIndex, error) {
+	var data dedupIndexPersist
+	err := persist.LoadJSON(dedupIndexMetadata, &data, path)
+	if os.IsNotExist(err) {
+		data.Secret = fastrand.Bytes(dedupSecretSize)
+		data.Entries = make(map[siafile.DedupID]*dedupEntry)
+		err = persist.SaveJSON(dedupIndexMetadata, data, path)
+	}
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to load dedup index")
+	}
+	if len(data.Secret) != dedupSecretSize {
+		return nil, errors.New("dedup index contains invalid secret")
+	}
+	if data.Entries == nil {
+		data.Entries = make(map[siafile.DedupID]*dedupEntry)
+	}
+	return &DedupIndex{
+		entries:      data.Entries,
+		staticSecret: data.Secret,
+		staticPath:   path,
+	}, nil
+}
+
+// ID computes the DedupID of a chunk using the secret of the index.
+func (di *DedupIndex) ID(ec modules.ErasureCoder, pieceSize uint64, ct crypto.CipherType, data []byte) siafile.DedupID {
+	return siafile.NewDedupID(di.staticSecret, ec, pieceSize, ct, data)
+}
+
+// Add adds a reference to a chunk. If the chunk is not in the index yet, it is
+// added together with its pieces.
+func (di *DedupIndex) Add(id siafile.DedupID, pieces [][]siafile.Piece) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	di.dirty = true
+	if entry, exists := di.entries[id]; exists {
+		entry.RefCount++
+		return
+	}
+	di.entries[id] = &dedupEntry{
+		RefCount: 1,
+		Pieces:   copyPieces(pieces),
+	}
+}
+
+// Reference adds a reference to a chunk which is already in the index and
+// returns its pieces. If the chunk is not in the index, false is returned.
+func (di *DedupIndex) Reference(id siafile.DedupID) ([][]siafile.Piece, bool) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[id]
+	if !exists {
+		return nil, false
+	}
+	di.dirty = true
+	entry.RefCount++
+	return copyPieces(entry.Pieces), true
+}
+
+// Release removes a reference from each of the provided chunks. Chunks which
+// are no longer referenced are removed from the index.
+func (di *DedupIndex) Release(ids ...siafile.DedupID) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	for _, id := range ids {
+		entry, exists := di.entries[id]
+		if !exists {
+			continue
+		}
+		di.dirty = true
+		entry.RefCount--
+		if entry.RefCount == 0 {
+			delete(di.entries, id)
+		}
+	}
+}
+
+// Len returns the number of chunks in the index.
+func (di *DedupIndex) Len() int {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	return len(di.entries)
+}
+
+// RefCount returns the number of references to a chunk.
+func (di *DedupIndex) RefCount(id siafile.DedupID) uint64 {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[id]
+	if !exists {
+		return 0
+	}
+	return entry.RefCount
+}
+
+// UpdatePieces replaces the pieces of a chunk in the index. This is used to
+// keep the pieces up-to-date after more pieces of a chunk were uploaded.
+func (di *DedupIndex) UpdatePieces(id siafile.DedupID, pieces [][]siafile.Piece) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[id]
+	if !exists {
+		return
+	}
+	di.dirty = true
+	entry.Pieces = copyPieces(pieces)
+}
+
+// Save writes the index to disk if it was changed since the last call to Save.
+func (di *DedupIndex) Save() error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	if !di.dirty {
+		return nil
+	}
+	err := persist.SaveJSON(dedupIndexMetadata, dedupIndexPersist{
+		Secret:  di.staticSecret,
+		Entries: di.entries,
+	}, di.staticPath)
+	if err != nil {
+		return errors.AddContext(err, "failed to save dedup index")
+	}
+	di.dirty = false
+	return nil
+}
+
+// copyPieces returns a deep copy of a chunk's pieces.
+func copyPieces(pieces [][]siafile.Piece) [][]siafile.Piece {
+	c := make([][]siafile.Piece, len(pieces))
+	for i := range pieces {
+		c[i] = append([]siafile.Piece(nil), pieces[i]...)
+	}
+	return c
+}
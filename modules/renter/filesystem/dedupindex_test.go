@@ -0,0 +1,93 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+	"go.sia.tech/siad/types"
+)
+
+// TestDedupIndex tests the reference counting and persistence of the
+// DedupIndex.
+func TestDedupIndex(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	path := filepath.Join(testDir(t.Name()), "dedup.json")
+	di, err := NewDedupIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID of a chunk should only depend on its content and encoding.
+	ec := modules.NewRSSubCodeDefault()
+	data := fastrand.Bytes(100)
+	id := di.ID(ec, modules.SectorSize, crypto.TypeDefaultRenter, data)
+	if di.ID(ec, modules.SectorSize, crypto.TypeDefaultRenter, data) != id {
+		t.Fatal("same data should result in same id")
+	}
+	if di.ID(ec, modules.SectorSize, crypto.TypePlain, data) == id {
+		t.Fatal("different cipher type should result in different id")
+	}
+	if di.ID(ec, modules.SectorSize, crypto.TypeDefaultRenter, fastrand.Bytes(100)) == id {
+		t.Fatal("different data should result in different id")
+	}
+
+	// An unknown chunk can't be referenced.
+	if _, ok := di.Reference(id); ok {
+		t.Fatal("chunk shouldn't be in index")
+	}
+
+	// Add the chunk and reference it.
+	pieces := make([][]siafile.Piece, ec.NumPieces())
+	for i := range pieces {
+		pieces[i] = []siafile.Piece{{
+			HostPubKey: types.SiaPublicKey{Algorithm: types.SignatureEd25519, Key: fastrand.Bytes(32)},
+			MerkleRoot: crypto.HashBytes(fastrand.Bytes(10)),
+		}}
+	}
+	di.Add(id, pieces)
+	referenced, ok := di.Reference(id)
+	if !ok {
+		t.Fatal("chunk should be in index")
+	}
+	if !reflect.DeepEqual(referenced, pieces) {
+		t.Fatal("pieces don't match")
+	}
+	if rc := di.RefCount(id); rc != 2 {
+		t.Fatal("wrong refcount", rc)
+	}
+
+	// Reload the index. The secret and the entries should be persisted.
+	if err := di.Save(); err != nil {
+		t.Fatal(err)
+	}
+	di2, err := NewDedupIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di2.ID(ec, modules.SectorSize, crypto.TypeDefaultRenter, data) != id {
+		t.Fatal("secret wasn't persisted")
+	}
+	if rc := di2.RefCount(id); rc != 2 {
+		t.Fatal("wrong refcount after reload", rc)
+	}
+
+	// Release the chunk twice. It should be removed from the index.
+	di2.Release(id)
+	if rc := di2.RefCount(id); rc != 1 {
+		t.Fatal("wrong refcount", rc)
+	}
+	di2.Release(id)
+	if _, ok := di2.Reference(id); ok {
+		t.Fatal("chunk should have been removed from the index")
+	}
+}
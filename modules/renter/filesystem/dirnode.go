@@ -145,6 +145,61 @@ func (n *DirNode) UpdateBubbledMetadata(md siadir.Metadata) error {
 	return sd.UpdateBubbledMetadata(md)
 }
 
+// SetPolicy is a wrapper for SiaDir.SetPolicy.
+func (n *DirNode) SetPolicy(policy modules.DirPolicy) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.siaDir()
+	if err != nil {
+		return err
+	}
+	return sd.SetPolicy(policy)
+}
+
+// SetScrubResult is a wrapper for SiaDir.SetScrubResult.
+func (n *DirNode) SetScrubResult(name string, result siadir.ScrubResult) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.siaDir()
+	if err != nil {
+		return err
+	}
+	return sd.SetScrubResult(name, result)
+}
+
+// DeleteScrubResults is a wrapper for SiaDir.DeleteScrubResults.
+func (n *DirNode) DeleteScrubResults(names []string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.siaDir()
+	if err != nil {
+		return err
+	}
+	return sd.DeleteScrubResults(names)
+}
+
+// SetQuota is a wrapper for SiaDir.SetQuota.
+func (n *DirNode) SetQuota(quota modules.DirQuota) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.siaDir()
+	if err != nil {
+		return err
+	}
+	return sd.SetQuota(quota)
+}
+
+// SetQuotaUsage is a wrapper for SiaDir.SetQuotaUsage.
+func (n *DirNode) SetQuotaUsage(usage modules.DirQuotaUsage) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.siaDir()
+	if err != nil {
+		return err
+	}
+	return sd.SetQuotaUsage(usage)
+}
+
 // UpdateLastHealthCheckTime is a wrapper for SiaDir.UpdateLastHealthCheckTime.
 func (n *DirNode) UpdateLastHealthCheckTime(aggregateLastHealthCheckTime, lastHealthCheckTime time.Time) error {
 	n.mu.Lock()
@@ -609,6 +664,9 @@ func (n *DirNode) managedInfo(siaPath modules.SiaPath) (modules.DirectoryInfo, e
 		StuckSize:           metadata.StuckSize,
 		SiaPath:             siaPath,
 		UID:                 n.staticUID,
+		Policy:              metadata.Policy,
+		Quota:               metadata.Quota,
+		QuotaUsage:          metadata.QuotaUsage,
 	}, nil
 }
 
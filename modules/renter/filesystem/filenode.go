@@ -36,6 +36,19 @@ func (n *FileNode) AddPiece(pk types.SiaPublicKey, chunkIndex, pieceIndex uint64
 	return n.SiaFile.AddPiece(pk, chunkIndex, pieceIndex, merkleRoot)
 }
 
+// RemovePiece wraps siafile.RemovePiece to guarantee that it's not called when
+// the fileNode was already closed.
+func (n *FileNode) RemovePiece(pk types.SiaPublicKey, chunkIndex, pieceIndex uint64, merkleRoot crypto.Hash) (err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		err := errors.New("RemovePiece called on close FileNode")
+		build.Critical(err)
+		return err
+	}
+	return n.SiaFile.RemovePiece(pk, chunkIndex, pieceIndex, merkleRoot)
+}
+
 // close closes the file and removes it from the parent if it was the last open
 // instance.
 // NOTE: If the file has a parent, it needs to be already locked when this is
@@ -137,14 +150,16 @@ func (n *FileNode) managedFileInfo(siaPath modules.SiaPath, offline map[string]b
 		return modules.FileInfo{}, errors.AddContext(err, "failed to get upload progress and bytes")
 	}
 	maxHealth := math.Max(health, stuckHealth)
+	compression := n.Compression()
 	fileInfo := modules.FileInfo{
 		AccessTime:       n.AccessTime(),
 		Available:        redundancy >= 1,
 		ChangeTime:       n.ChangeTime(),
 		CipherType:       n.MasterKey().Type().String(),
+		Compression:      compression.Type.String(),
 		CreateTime:       n.CreateTime(),
 		Expiration:       n.Expiration(contracts),
-		Filesize:         n.Size(),
+		Filesize:         userFileSize(n.Size(), compression.Type, compression.Index, compression.UncompressedSize, n.Dedup().Offsets),
 		Health:           health,
 		LocalPath:        localPath,
 		MaxHealth:        maxHealth,
@@ -226,9 +241,10 @@ func (n *FileNode) staticCachedInfo(siaPath modules.SiaPath) (modules.FileInfo,
 		Available:        md.CachedUserRedundancy >= 1,
 		ChangeTime:       md.ChangeTime,
 		CipherType:       md.StaticMasterKeyType.String(),
+		Compression:      md.CompressionType.String(),
 		CreateTime:       md.CreateTime,
 		Expiration:       md.CachedExpiration,
-		Filesize:         uint64(md.FileSize),
+		Filesize:         userFileSize(uint64(md.FileSize), md.CompressionType, md.CompressionIndex, md.UncompressedSize, md.DedupOffsets),
 		Health:           md.CachedHealth,
 		LocalPath:        localPath,
 		MaxHealth:        maxHealth,
@@ -250,3 +266,16 @@ func (n *FileNode) staticCachedInfo(siaPath modules.SiaPath) (modules.FileInfo,
 	}
 	return fileInfo, nil
 }
+
+// userFileSize returns the size of a file as seen by the user. For compressed
+// files that is the uncompressed size and for deduplicated files the size
+// without the padding of the chunks once the upload is complete.
+func userFileSize(storedSize uint64, ct modules.CompressionType, index []uint64, uncompressedSize uint64, dedupOffsets []uint64) uint64 {
+	if len(dedupOffsets) > 0 {
+		return dedupOffsets[len(dedupOffsets)-1]
+	}
+	if ct == modules.CompressionNone || index == nil {
+		return storedSize
+	}
+	return uncompressedSize
+}
@@ -149,10 +149,79 @@ func (sd *SiaDir) UpdateBubbledMetadata(metadata Metadata) error {
 	sd.mu.Lock()
 	defer sd.mu.Unlock()
 	metadata.Mode = sd.metadata.Mode
+	metadata.Policy = sd.metadata.Policy
+	metadata.Quota = sd.metadata.Quota
+	metadata.QuotaUsage = sd.metadata.QuotaUsage
+	metadata.ScrubResults = sd.metadata.ScrubResults
 	metadata.Version = sd.metadata.Version
 	return sd.updateMetadata(metadata)
 }
 
+// SetPolicy sets the policy of the SiaDir and saves the changes to disk.
+func (sd *SiaDir) SetPolicy(policy modules.DirPolicy) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	md := sd.metadata
+	md.Policy = policy
+	return sd.updateMetadata(md)
+}
+
+// SetScrubResult sets the result of the most recent scrub of the siafile with
+// the given name and saves the changes to disk.
+func (sd *SiaDir) SetScrubResult(name string, result ScrubResult) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	// Copy the map since the metadata returned by Metadata shares it.
+	scrubResults := make(map[string]ScrubResult, len(sd.metadata.ScrubResults)+1)
+	for n, r := range sd.metadata.ScrubResults {
+		scrubResults[n] = r
+	}
+	scrubResults[name] = result
+	md := sd.metadata
+	md.ScrubResults = scrubResults
+	return sd.updateMetadata(md)
+}
+
+// DeleteScrubResults removes the results of the siafiles with the given names
+// and saves the changes to disk.
+func (sd *SiaDir) DeleteScrubResults(names []string) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	// Copy the map since the metadata returned by Metadata shares it.
+	scrubResults := make(map[string]ScrubResult, len(sd.metadata.ScrubResults))
+	for n, r := range sd.metadata.ScrubResults {
+		scrubResults[n] = r
+	}
+	for _, name := range names {
+		delete(scrubResults, name)
+	}
+	if len(scrubResults) == len(sd.metadata.ScrubResults) {
+		return nil // nothing to delete
+	}
+	md := sd.metadata
+	md.ScrubResults = scrubResults
+	return sd.updateMetadata(md)
+}
+
+// SetQuota sets the quota of the SiaDir and saves the changes to disk.
+func (sd *SiaDir) SetQuota(quota modules.DirQuota) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	md := sd.metadata
+	md.Quota = quota
+	return sd.updateMetadata(md)
+}
+
+// SetQuotaUsage sets the usage of the SiaDir's quota and saves the changes to
+// disk.
+func (sd *SiaDir) SetQuotaUsage(usage modules.DirQuotaUsage) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	md := sd.metadata
+	md.QuotaUsage = usage
+	return sd.updateMetadata(md)
+}
+
 // UpdateLastHealthCheckTime updates the SiaDir LastHealthCheckTime and
 // AggregateLastHealthCheckTime and saves the changes to disk
 func (sd *SiaDir) UpdateLastHealthCheckTime(aggregateLastHealthCheckTime, lastHealthCheckTime time.Time) error {
@@ -201,6 +270,7 @@ func (sd *SiaDir) updateMetadata(metadata Metadata) error {
 	sd.metadata.AggregateHealth = metadata.AggregateHealth
 	sd.metadata.AggregateLastHealthCheckTime = metadata.AggregateLastHealthCheckTime
 	sd.metadata.AggregateMinRedundancy = metadata.AggregateMinRedundancy
+	sd.metadata.AggregateMinRepairThreshold = metadata.AggregateMinRepairThreshold
 	sd.metadata.AggregateModTime = metadata.AggregateModTime
 	sd.metadata.AggregateNumFiles = metadata.AggregateNumFiles
 	sd.metadata.AggregateNumStuckChunks = metadata.AggregateNumStuckChunks
@@ -219,8 +289,12 @@ func (sd *SiaDir) updateMetadata(metadata Metadata) error {
 	sd.metadata.NumFiles = metadata.NumFiles
 	sd.metadata.NumStuckChunks = metadata.NumStuckChunks
 	sd.metadata.NumSubDirs = metadata.NumSubDirs
+	sd.metadata.Policy = metadata.Policy
 	sd.metadata.RemoteHealth = metadata.RemoteHealth
 	sd.metadata.RepairSize = metadata.RepairSize
+	sd.metadata.Quota = metadata.Quota
+	sd.metadata.QuotaUsage = metadata.QuotaUsage
+	sd.metadata.ScrubResults = metadata.ScrubResults
 	sd.metadata.Size = metadata.Size
 	sd.metadata.StuckHealth = metadata.StuckHealth
 	sd.metadata.StuckSize = metadata.StuckSize
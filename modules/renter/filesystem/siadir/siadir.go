@@ -40,6 +40,10 @@ type (
 		// MinRedundancy is the minimum redundancy of any of the siafiles in the
 		// siadir
 		//
+		// MinRepairThreshold is the lowest repair threshold of the policies of
+		// the siadir and its sub-siadirs. 0 means that none of the policies
+		// sets a repair threshold. It only exists as an aggregate value.
+		//
 		// ModTime is the last time any of the siafiles in the siadir was
 		// updated
 		//
@@ -50,6 +54,17 @@ type (
 		//
 		// NumSubDirs is the number of sub-siadirs in a siadir
 		//
+		// Policy is the upload and repair policy of the siadir. It is not
+		// bubbled and is inherited by the sub-siadirs.
+		//
+		// Quota limits the resources the siafiles within the siadir and its
+		// sub-siadirs can consume and QuotaUsage is the spending which was
+		// counted against it. They are not bubbled.
+		//
+		// ScrubResults contains the result of the most recent scrub of the
+		// siafiles in the siadir by the name of the siafile. It is not
+		// bubbled.
+		//
 		// Size is the total amount of data stored in the siafiles of the siadir
 		//
 		// StuckHealth is the health of the most in need siafile in the siadir,
@@ -61,6 +76,7 @@ type (
 		AggregateHealth              float64   `json:"aggregatehealth"`
 		AggregateLastHealthCheckTime time.Time `json:"aggregatelasthealthchecktime"`
 		AggregateMinRedundancy       float64   `json:"aggregateminredundancy"`
+		AggregateMinRepairThreshold  float64   `json:"aggregateminrepairthreshold"`
 		AggregateModTime             time.Time `json:"aggregatemodtime"`
 		AggregateNumFiles            uint64    `json:"aggregatenumfiles"`
 		AggregateNumStuckChunks      uint64    `json:"aggregatenumstuckchunks"`
@@ -73,23 +89,39 @@ type (
 
 		// The following fields are information specific to the siadir that is not
 		// an aggregate of the entire sub directory tree
-		Health              float64     `json:"health"`
-		LastHealthCheckTime time.Time   `json:"lasthealthchecktime"`
-		MinRedundancy       float64     `json:"minredundancy"`
-		Mode                os.FileMode `json:"mode"`
-		ModTime             time.Time   `json:"modtime"`
-		NumFiles            uint64      `json:"numfiles"`
-		NumStuckChunks      uint64      `json:"numstuckchunks"`
-		NumSubDirs          uint64      `json:"numsubdirs"`
-		RemoteHealth        float64     `json:"remotehealth"`
-		RepairSize          uint64      `json:"repairsize"`
-		Size                uint64      `json:"size"`
-		StuckHealth         float64     `json:"stuckhealth"`
-		StuckSize           uint64      `json:"stucksize"`
+		Health              float64                `json:"health"`
+		LastHealthCheckTime time.Time              `json:"lasthealthchecktime"`
+		MinRedundancy       float64                `json:"minredundancy"`
+		Mode                os.FileMode            `json:"mode"`
+		ModTime             time.Time              `json:"modtime"`
+		NumFiles            uint64                 `json:"numfiles"`
+		NumStuckChunks      uint64                 `json:"numstuckchunks"`
+		NumSubDirs          uint64                 `json:"numsubdirs"`
+		Policy              modules.DirPolicy      `json:"policy"`
+		Quota               modules.DirQuota       `json:"quota"`
+		QuotaUsage          modules.DirQuotaUsage  `json:"quotausage"`
+		RemoteHealth        float64                `json:"remotehealth"`
+		RepairSize          uint64                 `json:"repairsize"`
+		ScrubResults        map[string]ScrubResult `json:"scrubresults,omitempty"`
+		Size                uint64                 `json:"size"`
+		StuckHealth         float64                `json:"stuckhealth"`
+		StuckSize           uint64                 `json:"stucksize"`
 
 		// Version is the used version of the header file.
 		Version string `json:"version"`
 	}
+
+	// ScrubResult is the result of the most recent scrub of a siafile.
+	ScrubResult struct {
+		// UID is the UID of the scrubbed siafile. It is used to tell whether
+		// the result belongs to the siafile with the same name.
+		UID string `json:"uid"`
+
+		LastScrubTime    time.Time `json:"lastscrubtime"`
+		PiecesChecked    uint64    `json:"pieceschecked"`
+		PiecesFailed     uint64    `json:"piecesfailed"`
+		PiecesUnverified uint64    `json:"piecesunverified"`
+	}
 )
 
 // mdPath returns the path of the SiaDir's metadata on disk.
@@ -56,6 +56,9 @@ func equalMetadatas(md, md2 Metadata) error {
 	if md.AggregateMinRedundancy != md2.AggregateMinRedundancy {
 		return fmt.Errorf("AggregateMinRedundancy not equal, %v and %v", md.AggregateMinRedundancy, md2.AggregateMinRedundancy)
 	}
+	if md.AggregateMinRepairThreshold != md2.AggregateMinRepairThreshold {
+		return fmt.Errorf("AggregateMinRepairThreshold not equal, %v and %v", md.AggregateMinRepairThreshold, md2.AggregateMinRepairThreshold)
+	}
 	if md.AggregateModTime != md2.AggregateModTime {
 		return fmt.Errorf("AggregateModTimes not equal, %v and %v", md.AggregateModTime, md2.AggregateModTime)
 	}
@@ -141,6 +144,7 @@ func randomMetadata() Metadata {
 		AggregateHealth:              float64(fastrand.Intn(100)),
 		AggregateLastHealthCheckTime: time.Now(),
 		AggregateMinRedundancy:       float64(fastrand.Intn(100)),
+		AggregateMinRepairThreshold:  float64(fastrand.Intn(100)),
 		AggregateModTime:             time.Now(),
 		AggregateNumFiles:            fastrand.Uint64n(100),
 		AggregateNumStuckChunks:      fastrand.Uint64n(100),
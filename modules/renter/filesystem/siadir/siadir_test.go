@@ -3,11 +3,14 @@ package siadir
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"gitlab.com/NebulousLabs/errors"
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
 )
 
 // TestSiaDir probes the SiaDir subsystem
@@ -20,6 +23,112 @@ func TestSiaDir(t *testing.T) {
 	t.Run("Basic", testSiaDirBasic)
 	t.Run("Delete", testSiaDirDelete)
 	t.Run("UpdatedMetadata", testUpdateMetadata)
+	t.Run("ScrubResults", testScrubResults)
+	t.Run("Quota", testQuota)
+}
+
+// testScrubResults tests setting the scrub results of a SiaDir.
+func testScrubResults(t *testing.T) {
+	siaDir, err := newTestDir(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result1 := ScrubResult{
+		UID:           "uid1",
+		LastScrubTime: time.Unix(1600000000, 0).UTC(),
+		PiecesChecked: 30,
+		PiecesFailed:  1,
+	}
+	result2 := ScrubResult{
+		UID:              "uid2",
+		LastScrubTime:    time.Unix(1600000100, 0).UTC(),
+		PiecesChecked:    10,
+		PiecesUnverified: 2,
+	}
+	if err := siaDir.SetScrubResult("file1", result1); err != nil {
+		t.Fatal(err)
+	}
+	md := siaDir.Metadata()
+	if err := siaDir.SetScrubResult("file2", result2); err != nil {
+		t.Fatal(err)
+	}
+	// Previously returned metadata isn't modified.
+	if len(md.ScrubResults) != 1 {
+		t.Fatal("metadata returned earlier was modified")
+	}
+
+	// Bubbling doesn't overwrite the results.
+	if err := siaDir.UpdateBubbledMetadata(randomMetadata()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The results are persisted.
+	siaDir, err = LoadSiaDir(siaDir.path, modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md = siaDir.Metadata()
+	if !reflect.DeepEqual(md.ScrubResults, map[string]ScrubResult{"file1": result1, "file2": result2}) {
+		t.Fatal("unexpected scrub results", md.ScrubResults)
+	}
+
+	// Deleting results ignores names without a result.
+	if err := siaDir.DeleteScrubResults([]string{"file1", "file3"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(md.ScrubResults) != 2 {
+		t.Fatal("metadata returned earlier was modified")
+	}
+	siaDir, err = LoadSiaDir(siaDir.path, modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md = siaDir.Metadata()
+	if !reflect.DeepEqual(md.ScrubResults, map[string]ScrubResult{"file2": result2}) {
+		t.Fatal("unexpected scrub results", md.ScrubResults)
+	}
+}
+
+// testQuota tests setting the quota and quota usage of a SiaDir.
+func testQuota(t *testing.T) {
+	siaDir, err := newTestDir(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	quota := modules.DirQuota{
+		MaxStoredBytes:   1 << 30,
+		MaxUploadSpend:   types.SiacoinPrecision,
+		MaxDownloadSpend: types.SiacoinPrecision.Mul64(2),
+	}
+	usage := modules.DirQuotaUsage{
+		Period:        10,
+		UploadSpend:   types.NewCurrency64(100),
+		DownloadSpend: types.NewCurrency64(200),
+	}
+	if err := siaDir.SetQuota(quota); err != nil {
+		t.Fatal(err)
+	}
+	if err := siaDir.SetQuotaUsage(usage); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bubbling doesn't overwrite the quota.
+	if err := siaDir.UpdateBubbledMetadata(randomMetadata()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The quota is persisted.
+	siaDir, err = LoadSiaDir(siaDir.path, modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := siaDir.Metadata()
+	if !reflect.DeepEqual(md.Quota, quota) {
+		t.Fatal("unexpected quota", md.Quota)
+	}
+	if !reflect.DeepEqual(md.QuotaUsage, usage) {
+		t.Fatal("unexpected quota usage", md.QuotaUsage)
+	}
 }
 
 // testSiaDirBasic tests the basic functionality of the siadir
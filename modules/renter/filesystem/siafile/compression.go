@@ -0,0 +1,146 @@
+package siafile
+
+import (
+	"fmt"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+)
+
+var (
+	// ErrCompressionIndexIncomplete is returned when trying to read a
+	// compressed file before its upload is complete.
+	ErrCompressionIndexIncomplete = errors.New("compression index of file is incomplete")
+)
+
+type (
+	// CompressionInfo describes how the data of a compressed file is stored.
+	// The uncompressed data is split into frames of FrameSize bytes which are
+	// compressed independently. Index contains the offset of every compressed
+	// frame within the stored data followed by the total size of the stored
+	// data.
+	CompressionInfo struct {
+		Type             modules.CompressionType
+		FrameSize        uint64
+		Index            []uint64
+		UncompressedSize uint64
+	}
+)
+
+// Compressed returns whether the file is compressed.
+func (ci CompressionInfo) Compressed() bool {
+	return ci.Type != modules.CompressionNone
+}
+
+// NumFrames returns the number of compressed frames.
+func (ci CompressionInfo) NumFrames() uint64 {
+	if len(ci.Index) == 0 {
+		return 0
+	}
+	return uint64(len(ci.Index) - 1)
+}
+
+// Frame returns the offset and length of a compressed frame within the
+// stored data.
+func (ci CompressionInfo) Frame(frameIndex uint64) (offset, length uint64) {
+	return ci.Index[frameIndex], ci.Index[frameIndex+1] - ci.Index[frameIndex]
+}
+
+// Compression returns the compression info of the file.
+func (sf *SiaFile) Compression() CompressionInfo {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.compressionInfo()
+}
+
+// SetCompressionType marks the file as compressed. It can only be called
+// before any data was added to the file.
+func (sf *SiaFile) SetCompressionType(ct modules.CompressionType) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't set compression type of deleted file")
+	}
+	if sf.staticMetadata.FileSize > 0 {
+		return errors.New("can't set compression type of non-empty file")
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+
+	sf.staticMetadata.CompressionType = ct
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// SetCompressionIndex sets the compression index and the uncompressed size of
+// a compressed file. It is called once the compressed data was uploaded.
+func (sf *SiaFile) SetCompressionIndex(index []uint64, uncompressedSize uint64) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't set compression index of deleted file")
+	}
+	if sf.staticMetadata.CompressionType == modules.CompressionNone {
+		return errors.New("can't set compression index of uncompressed file")
+	}
+	// Sanity check the index.
+	frameSize := sf.staticChunkSize()
+	numFrames := uncompressedSize / frameSize
+	if uncompressedSize%frameSize != 0 {
+		numFrames++
+	}
+	if uint64(len(index)) != numFrames+1 {
+		return fmt.Errorf("expected %v entries in compression index but got %v", numFrames+1, len(index))
+	}
+	for i := 1; i < len(index); i++ {
+		if index[i] < index[i-1] {
+			return errors.New("compression index is not sorted")
+		}
+	}
+	if index[0] != 0 || index[len(index)-1] != uint64(sf.staticMetadata.FileSize) {
+		return fmt.Errorf("compression index doesn't cover the file's %v bytes", sf.staticMetadata.FileSize)
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+
+	sf.staticMetadata.CompressionIndex = append([]uint64(nil), index...)
+	sf.staticMetadata.UncompressedSize = uncompressedSize
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// compressionInfo returns the compression info of the file.
+func (sf *SiaFile) compressionInfo() CompressionInfo {
+	return CompressionInfo{
+		Type:             sf.staticMetadata.CompressionType,
+		FrameSize:        sf.staticChunkSize(),
+		Index:            append([]uint64(nil), sf.staticMetadata.CompressionIndex...),
+		UncompressedSize: sf.staticMetadata.UncompressedSize,
+	}
+}
+
+// Compression returns the compression info of the file.
+func (s *Snapshot) Compression() CompressionInfo {
+	return s.staticCompression
+}
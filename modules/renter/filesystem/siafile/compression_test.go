@@ -0,0 +1,102 @@
+package siafile
+
+import (
+	"reflect"
+	"testing"
+
+	"go.sia.tech/siad/modules"
+)
+
+// TestCompression tests setting the compression type and index of a file.
+func TestCompression(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create an empty file.
+	siaFilePath, _, source, rc, sk, _, _, fileMode := newTestFileParams(1, false)
+	wal, _ := newTestWAL()
+	sf, err := New(siaFilePath, source, wal, rc, sk, 0, fileMode, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Compression().Compressed() {
+		t.Fatal("new file shouldn't be compressed")
+	}
+
+	// The index can't be set for an uncompressed file.
+	if err := sf.SetCompressionIndex([]uint64{0}, 0); err == nil {
+		t.Fatal("shouldn't be able to set index of uncompressed file")
+	}
+	if err := sf.SetCompressionType(modules.CompressionGzip); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add the compressed data of 3 frames to the file.
+	chunkSize := sf.ChunkSize()
+	if err := sf.GrowNumChunks(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetFileSize(chunkSize + 100); err != nil {
+		t.Fatal(err)
+	}
+	// The type can't be changed once the file contains data.
+	if err := sf.SetCompressionType(modules.CompressionDeflate); err == nil {
+		t.Fatal("shouldn't be able to change type of non-empty file")
+	}
+
+	// Invalid indices should be rejected.
+	uncompressedSize := 3 * chunkSize
+	invalid := [][]uint64{
+		{0, 50, chunkSize + 100},             // wrong number of frames
+		{0, chunkSize, 50, chunkSize + 100},  // not sorted
+		{0, 50, chunkSize, chunkSize + 99},   // doesn't cover the file
+		{10, 50, chunkSize, chunkSize + 100}, // doesn't start at 0
+	}
+	for _, index := range invalid {
+		if err := sf.SetCompressionIndex(index, uncompressedSize); err == nil {
+			t.Fatal("invalid index was accepted", index)
+		}
+	}
+	if sf.Compression().Index != nil {
+		t.Fatal("invalid index was set")
+	}
+
+	// Set a valid index.
+	index := []uint64{0, 50, chunkSize, chunkSize + 100}
+	if err := sf.SetCompressionIndex(index, uncompressedSize); err != nil {
+		t.Fatal(err)
+	}
+	expected := CompressionInfo{
+		Type:             modules.CompressionGzip,
+		FrameSize:        chunkSize,
+		Index:            index,
+		UncompressedSize: uncompressedSize,
+	}
+	if ci := sf.Compression(); !reflect.DeepEqual(ci, expected) {
+		t.Fatal("wrong compression info", ci)
+	}
+	if ci := sf.Compression(); ci.NumFrames() != 3 {
+		t.Fatal("wrong number of frames", ci.NumFrames())
+	}
+	if offset, length := sf.Compression().Frame(1); offset != 50 || length != chunkSize-50 {
+		t.Fatal("wrong frame", offset, length)
+	}
+
+	// The compression info should be persisted and part of snapshots.
+	sf, err = LoadSiaFile(sf.SiaFilePath(), wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci := sf.Compression(); !reflect.DeepEqual(ci, expected) {
+		t.Fatal("compression info wasn't persisted", ci)
+	}
+	snap, err := sf.Snapshot(modules.RandomSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci := snap.Compression(); !reflect.DeepEqual(ci, expected) {
+		t.Fatal("wrong compression info in snapshot", ci)
+	}
+}
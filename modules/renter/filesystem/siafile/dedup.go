@@ -0,0 +1,350 @@
+package siafile
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// Deduplicated chunks are encrypted with a key which is derived from the
+// content of the chunk instead of the file's masterkey. That way the same data
+// uploaded to two different files results in the same logical chunk which can
+// be referenced by both files. The ID of such a chunk is stored within the
+// ExtensionInfo of the chunk. The first byte of the ExtensionInfo indicates the
+// type of the extension and the remaining 15 bytes contain the DedupID.
+//
+// The data of a deduplicated file is split into blocks at content-defined
+// boundaries. Every block is stored in its own chunk, followed by zero padding
+// if it is shorter than the chunk size. The offsets of the blocks within the
+// file's data are stored in the metadata once the upload is complete.
+const (
+	// extensionTypeDedup is the extension type of a chunk which was
+	// deduplicated.
+	extensionTypeDedup byte = 1
+)
+
+var (
+	// dedupIDSpecifier is the specifier used when computing the DedupID of a
+	// chunk.
+	dedupIDSpecifier = types.NewSpecifier("DedupID")
+
+	// dedupKeySpecifier is the specifier used when deriving the cipher key of
+	// a deduplicated chunk from its DedupID.
+	dedupKeySpecifier = types.NewSpecifier("DedupKey")
+
+	// ErrDedupPartialChunk is returned when trying to deduplicate a partial
+	// chunk.
+	ErrDedupPartialChunk = errors.New("partial chunks can't be deduplicated")
+
+	// ErrDedupOffsetsIncomplete is returned when trying to read a
+	// deduplicated file before its upload is complete.
+	ErrDedupOffsetsIncomplete = errors.New("dedup offsets of file are incomplete")
+)
+
+type (
+	// DedupInfo describes how the data of a deduplicated file is stored. The
+	// chunk at index i contains the block of the file's data between
+	// Offsets[i] and Offsets[i+1]. The last entry of Offsets is the size of
+	// the file's data.
+	DedupInfo struct {
+		Deduplicated bool
+		ChunkSize    uint64
+		Offsets      []uint64
+	}
+)
+
+// NumBlocks returns the number of blocks of the file.
+func (di DedupInfo) NumBlocks() uint64 {
+	if len(di.Offsets) == 0 {
+		return 0
+	}
+	return uint64(len(di.Offsets) - 1)
+}
+
+// Size returns the size of the file's data without the padding of the chunks.
+func (di DedupInfo) Size() uint64 {
+	if len(di.Offsets) == 0 {
+		return 0
+	}
+	return di.Offsets[len(di.Offsets)-1]
+}
+
+// Block returns the offset and length of a block within the file's data.
+func (di DedupInfo) Block(blockIndex uint64) (offset, length uint64) {
+	return di.Offsets[blockIndex], di.Offsets[blockIndex+1] - di.Offsets[blockIndex]
+}
+
+// BlockIndex returns the index of the block which contains the byte at the
+// provided offset of the file's data.
+func (di DedupInfo) BlockIndex(offset uint64) uint64 {
+	return uint64(sort.Search(int(di.NumBlocks()), func(i int) bool {
+		return di.Offsets[i+1] > offset
+	}))
+}
+
+// StoredOffset translates an offset within the file's data into the offset of
+// the same byte within the stored data.
+func (di DedupInfo) StoredOffset(offset uint64) uint64 {
+	blockIndex := di.BlockIndex(offset)
+	return blockIndex*di.ChunkSize + offset - di.Offsets[blockIndex]
+}
+
+// StoredRange returns the range of the stored data which contains the range
+// [offset, offset+length) of the file's data.
+func (di DedupInfo) StoredRange(offset, length uint64) (storedOffset, storedLength uint64) {
+	if length == 0 {
+		return 0, 0
+	}
+	storedOffset = di.StoredOffset(offset)
+	return storedOffset, di.StoredOffset(offset+length-1) + 1 - storedOffset
+}
+
+// DedupID is the identifier of a deduplicated chunk. It is derived from the
+// plaintext of the chunk and is used as the entropy for the chunk's cipher key.
+type DedupID [15]byte
+
+// NewDedupID computes the DedupID of a chunk. The secret prevents a third party
+// who knows the plaintext of a chunk from learning the key of the chunk. The
+// erasure code, piece size and cipher type are part of the ID since a chunk can
+// only be shared between files which encode and encrypt it the same way.
+func NewDedupID(secret []byte, ec modules.ErasureCoder, pieceSize uint64, ct crypto.CipherType, data []byte) DedupID {
+	var id DedupID
+	h := crypto.HashAll(dedupIDSpecifier, secret, ec.Identifier(), pieceSize, ct, data)
+	copy(id[:], h[:])
+	return id
+}
+
+// String implements the fmt.Stringer interface.
+func (id DedupID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id DedupID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (id *DedupID) UnmarshalText(b []byte) error {
+	if hex.DecodedLen(len(b)) != len(id) {
+		return errors.New("DedupID has wrong length")
+	}
+	_, err := hex.Decode(id[:], b)
+	return err
+}
+
+// DedupCipherKey derives the cipher key of a deduplicated chunk from its ID.
+func DedupCipherKey(ct crypto.CipherType, id DedupID) (crypto.CipherKey, error) {
+	keySize := len(crypto.GenerateSiaKey(ct).Key())
+	entropy := make([]byte, 0, keySize+crypto.HashSize)
+	for i := uint64(0); len(entropy) < keySize; i++ {
+		h := crypto.HashAll(dedupKeySpecifier, id, i)
+		entropy = append(entropy, h[:]...)
+	}
+	return crypto.NewSiaKey(ct, entropy[:keySize])
+}
+
+// dedupID returns the DedupID of the chunk and whether the chunk was
+// deduplicated.
+func (c chunk) dedupID() (id DedupID, ok bool) {
+	if c.ExtensionInfo[0] != extensionTypeDedup {
+		return DedupID{}, false
+	}
+	copy(id[:], c.ExtensionInfo[1:])
+	return id, true
+}
+
+// dedupID returns the DedupID of the chunk and whether the chunk was
+// deduplicated.
+func (c Chunk) dedupID() (id DedupID, ok bool) {
+	return chunk{ExtensionInfo: c.extensionInfo}.dedupID()
+}
+
+// chunkCipherKey returns the key and the chunk index which need to be used to
+// derive the piece keys of a chunk.
+func chunkCipherKey(masterKey crypto.CipherKey, chunkIndex uint64, id DedupID, dedup bool) (crypto.CipherKey, uint64, error) {
+	if !dedup {
+		return masterKey, chunkIndex, nil
+	}
+	// Deduplicated chunks always use index 0 for deriving the piece keys to
+	// make sure the same chunk results in the same pieces independent of its
+	// position within a file.
+	key, err := DedupCipherKey(masterKey.Type(), id)
+	return key, 0, err
+}
+
+// ChunkCipherKey returns the key and the chunk index which need to be used to
+// derive the piece keys of the chunk at the given index.
+func (sf *SiaFile) ChunkCipherKey(chunkIndex uint64) (crypto.CipherKey, uint64, error) {
+	id, dedup, err := sf.ChunkDedupID(chunkIndex)
+	if err != nil {
+		return nil, 0, err
+	}
+	return chunkCipherKey(sf.staticMasterKey(), chunkIndex, id, dedup)
+}
+
+// ChunkDedupID returns the DedupID of the chunk at the given index and whether
+// the chunk was deduplicated at all.
+func (sf *SiaFile) ChunkDedupID(chunkIndex uint64) (DedupID, bool, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if _, ok := sf.isIncludedPartialChunk(chunkIndex); ok || sf.isIncompletePartialChunk(chunkIndex) {
+		return DedupID{}, false, nil
+	}
+	chunk, err := sf.chunk(int(chunkIndex))
+	if err != nil {
+		return DedupID{}, false, errors.AddContext(err, "failed to read chunk")
+	}
+	id, ok := chunk.dedupID()
+	return id, ok, nil
+}
+
+// DedupIDs returns the DedupIDs of all the deduplicated chunks of the file.
+func (sf *SiaFile) DedupIDs() (ids []DedupID, err error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	err = sf.iterateChunksReadonly(func(chunk chunk) error {
+		if id, ok := chunk.dedupID(); ok {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	return
+}
+
+// SetChunkDedupID marks the chunk at the given index as deduplicated. This
+// needs to happen before any pieces are uploaded for the chunk since it changes
+// the key used for encrypting the chunk.
+func (sf *SiaFile) SetChunkDedupID(chunkIndex uint64, id DedupID) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if _, ok := sf.isIncludedPartialChunk(chunkIndex); ok || sf.isIncompletePartialChunk(chunkIndex) {
+		return ErrDedupPartialChunk
+	}
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't call SetChunkDedupID on deleted file")
+	}
+	chunk, err := sf.chunk(int(chunkIndex))
+	if err != nil {
+		return err
+	}
+	chunk.ExtensionInfo[0] = extensionTypeDedup
+	copy(chunk.ExtensionInfo[1:], id[:])
+	return sf.createAndApplyTransaction(sf.saveChunkUpdate(chunk))
+}
+
+// Dedup returns the dedup info of the file.
+func (sf *SiaFile) Dedup() DedupInfo {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.dedupInfo()
+}
+
+// SetDeduplicated marks the file as deduplicated. It can only be called before
+// any data was added to the file.
+func (sf *SiaFile) SetDeduplicated() (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't mark deleted file as deduplicated")
+	}
+	if sf.staticMetadata.FileSize > 0 {
+		return errors.New("can't mark non-empty file as deduplicated")
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+
+	sf.staticMetadata.Deduplicated = true
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// SetDedupOffsets sets the offsets of the blocks of a deduplicated file. It is
+// called once all the blocks were added to the file.
+func (sf *SiaFile) SetDedupOffsets(offsets []uint64) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't set dedup offsets of deleted file")
+	}
+	if !sf.staticMetadata.Deduplicated {
+		return errors.New("can't set dedup offsets of file which isn't deduplicated")
+	}
+	// Sanity check the offsets. Every chunk contains a non-empty block which
+	// fits into the chunk and the last block determines the file size.
+	chunkSize := sf.staticChunkSize()
+	if len(offsets) != sf.numChunks+1 {
+		return fmt.Errorf("expected %v dedup offsets but got %v", sf.numChunks+1, len(offsets))
+	}
+	if offsets[0] != 0 {
+		return errors.New("dedup offsets don't start at 0")
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] <= offsets[i-1] || offsets[i]-offsets[i-1] > chunkSize {
+			return fmt.Errorf("block %v has invalid size", i-1)
+		}
+	}
+	if sf.numChunks > 0 {
+		lastBlockSize := offsets[sf.numChunks] - offsets[sf.numChunks-1]
+		if uint64(sf.staticMetadata.FileSize) != uint64(sf.numChunks-1)*chunkSize+lastBlockSize {
+			return fmt.Errorf("dedup offsets don't match the file's %v bytes", sf.staticMetadata.FileSize)
+		}
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+
+	sf.staticMetadata.DedupOffsets = append([]uint64(nil), offsets...)
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// dedupInfo returns the dedup info of the file.
+func (sf *SiaFile) dedupInfo() DedupInfo {
+	var offsets []uint64
+	if sf.staticMetadata.DedupOffsets != nil {
+		offsets = append([]uint64(nil), sf.staticMetadata.DedupOffsets...)
+	}
+	return DedupInfo{
+		Deduplicated: sf.staticMetadata.Deduplicated,
+		ChunkSize:    sf.staticChunkSize(),
+		Offsets:      offsets,
+	}
+}
+
+// Dedup returns the dedup info of the file.
+func (s *Snapshot) Dedup() DedupInfo {
+	return s.staticDedup
+}
+
+// ChunkCipherKey returns the key and the chunk index which need to be used to
+// derive the piece keys of the chunk at the given index.
+func (s *Snapshot) ChunkCipherKey(chunkIndex uint64) (crypto.CipherKey, uint64, error) {
+	id, dedup := s.staticChunks[chunkIndex].dedupID()
+	return chunkCipherKey(s.staticMasterKey, chunkIndex, id, dedup)
+}
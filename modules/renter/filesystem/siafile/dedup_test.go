@@ -0,0 +1,210 @@
+package siafile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+)
+
+// TestChunkDedupID tests setting the DedupID of a chunk and the keys used to
+// encrypt deduplicated chunks.
+func TestChunkDedupID(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	sf, wal, _ := newBlankTestFileAndWAL(2)
+	data := fastrand.Bytes(100)
+	id := NewDedupID(fastrand.Bytes(32), sf.ErasureCode(), sf.PieceSize(), sf.MasterKey().Type(), data)
+
+	// By default the masterkey and the chunk's index should be used.
+	key, keyIndex, err := sf.ChunkCipherKey(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key.Key(), sf.MasterKey().Key()) || keyIndex != 1 {
+		t.Fatal("wrong key for regular chunk")
+	}
+
+	// Mark the chunk as deduplicated.
+	if err := sf.SetChunkDedupID(1, id); err != nil {
+		t.Fatal(err)
+	}
+	expectedKey, err := DedupCipherKey(sf.MasterKey().Type(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKey := func(key crypto.CipherKey, keyIndex uint64) {
+		t.Helper()
+		if !bytes.Equal(key.Key(), expectedKey.Key()) || keyIndex != 0 {
+			t.Fatal("wrong key for deduplicated chunk")
+		}
+	}
+	key, keyIndex, err = sf.ChunkCipherKey(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKey(key, keyIndex)
+
+	// The id should be persisted and the other chunk shouldn't be affected.
+	sf, err = LoadSiaFile(sf.SiaFilePath(), wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loadedID, ok, err := sf.ChunkDedupID(1); err != nil || !ok || loadedID != id {
+		t.Fatal("dedup id wasn't persisted", err, ok)
+	}
+	if _, ok, err := sf.ChunkDedupID(0); err != nil || ok {
+		t.Fatal("chunk 0 shouldn't be deduplicated", err, ok)
+	}
+	ids, err := sf.DedupIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != id {
+		t.Fatal("wrong dedup ids", ids)
+	}
+
+	// A snapshot should use the same key.
+	snap, err := sf.Snapshot(modules.RandomSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, keyIndex, err = snap.ChunkCipherKey(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKey(key, keyIndex)
+	key, keyIndex, err = snap.ChunkCipherKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key.Key(), sf.MasterKey().Key()) || keyIndex != 0 {
+		t.Fatal("wrong key for regular chunk")
+	}
+}
+
+// TestDedupOffsets tests marking a file as deduplicated and setting the offsets
+// of its blocks.
+func TestDedupOffsets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create an empty file.
+	siaFilePath, _, source, rc, sk, _, _, fileMode := newTestFileParams(1, false)
+	wal, _ := newTestWAL()
+	sf, err := New(siaFilePath, source, wal, rc, sk, 0, fileMode, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Dedup().Deduplicated {
+		t.Fatal("new file shouldn't be deduplicated")
+	}
+
+	// The offsets can't be set for a file which isn't deduplicated.
+	if err := sf.SetDedupOffsets([]uint64{0}); err == nil {
+		t.Fatal("shouldn't be able to set offsets of regular file")
+	}
+	if err := sf.SetDeduplicated(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add 3 chunks to the file. The last block is 100 bytes long.
+	chunkSize := sf.ChunkSize()
+	if err := sf.GrowNumChunks(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetFileSize(2*chunkSize + 100); err != nil {
+		t.Fatal(err)
+	}
+	// The file can't be marked once it contains data.
+	if err := sf.SetDeduplicated(); err == nil {
+		t.Fatal("shouldn't be able to mark non-empty file")
+	}
+
+	// Invalid offsets should be rejected.
+	invalid := [][]uint64{
+		{0, 50, 150},     // wrong number of blocks
+		{0, 50, 50, 150}, // empty block
+		{0, chunkSize + 1, chunkSize + 2, chunkSize + 102}, // block too large
+		{0, 50, 60, 150},  // wrong last block
+		{10, 50, 60, 160}, // doesn't start at 0
+	}
+	for _, offsets := range invalid {
+		if err := sf.SetDedupOffsets(offsets); err == nil {
+			t.Fatal("invalid offsets were accepted", offsets)
+		}
+	}
+	if sf.Dedup().Offsets != nil {
+		t.Fatal("invalid offsets were set")
+	}
+
+	// Set valid offsets.
+	offsets := []uint64{0, 50, chunkSize, chunkSize + 100}
+	if err := sf.SetDedupOffsets(offsets); err != nil {
+		t.Fatal(err)
+	}
+	expected := DedupInfo{
+		Deduplicated: true,
+		ChunkSize:    chunkSize,
+		Offsets:      offsets,
+	}
+	if di := sf.Dedup(); !reflect.DeepEqual(di, expected) {
+		t.Fatal("wrong dedup info", di)
+	}
+	di := sf.Dedup()
+	if di.NumBlocks() != 3 || di.Size() != chunkSize+100 {
+		t.Fatal("wrong number of blocks or size", di.NumBlocks(), di.Size())
+	}
+	if offset, length := di.Block(1); offset != 50 || length != chunkSize-50 {
+		t.Fatal("wrong block", offset, length)
+	}
+
+	// Offsets within the file's data are translated into offsets within the
+	// padded chunks.
+	tests := []struct {
+		offset, stored uint64
+	}{
+		{0, 0},
+		{49, 49},
+		{50, chunkSize},
+		{chunkSize - 1, 2*chunkSize - 51},
+		{chunkSize, 2 * chunkSize},
+		{chunkSize + 99, 2*chunkSize + 99},
+	}
+	for _, test := range tests {
+		if stored := di.StoredOffset(test.offset); stored != test.stored {
+			t.Fatalf("offset %v: expected stored offset %v but got %v", test.offset, test.stored, stored)
+		}
+	}
+	if offset, length := di.StoredRange(40, 20); offset != 40 || length != chunkSize-30 {
+		t.Fatal("wrong stored range", offset, length)
+	}
+	if offset, length := di.StoredRange(0, di.Size()); offset != 0 || length != 2*chunkSize+100 {
+		t.Fatal("wrong stored range", offset, length)
+	}
+
+	// The dedup info should be persisted and part of snapshots.
+	sf, err = LoadSiaFile(sf.SiaFilePath(), wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di := sf.Dedup(); !reflect.DeepEqual(di, expected) {
+		t.Fatal("dedup info wasn't persisted", di)
+	}
+	snap, err := sf.Snapshot(modules.RandomSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di := snap.Dedup(); !reflect.DeepEqual(di, expected) {
+		t.Fatal("wrong dedup info in snapshot", di)
+	}
+}
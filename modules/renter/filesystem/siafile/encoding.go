@@ -49,8 +49,16 @@ func marshalErasureCoder(ec modules.ErasureCoder) ([4]byte, [8]byte) {
 	ecType := [4]byte(ec.Type())
 	// Read params from ec.
 	ecParams := [8]byte{}
-	binary.LittleEndian.PutUint32(ecParams[:4], uint32(ec.MinPieces()))
-	binary.LittleEndian.PutUint32(ecParams[4:], uint32(ec.NumPieces()-ec.MinPieces()))
+	binary.LittleEndian.PutUint32(ecParams[:4], uint32(ec.DataPieces()))
+	binary.LittleEndian.PutUint32(ecParams[4:], uint32(ec.NumPieces()-ec.DataPieces()))
+	// The locally repairable code also needs the number of local groups. Since
+	// the number of pieces is limited to 16 bits, the parity pieces are split
+	// into local groups and global parity pieces of 2 bytes each.
+	if lrc, ok := ec.(*modules.LRCode); ok {
+		localGroups := lrc.LocalGroups()
+		binary.LittleEndian.PutUint16(ecParams[4:6], uint16(localGroups))
+		binary.LittleEndian.PutUint16(ecParams[6:], uint16(ec.NumPieces()-ec.DataPieces()-localGroups))
+	}
 	return ecType, ecParams
 }
 
@@ -148,6 +156,12 @@ func unmarshalErasureCoder(ecType [4]byte, ecParams [8]byte) (modules.ErasureCod
 		return modules.NewRSCode(dataPieces, parityPieces)
 	case modules.ECReedSolomonSubShards64:
 		return modules.NewRSSubCode(dataPieces, parityPieces, 64)
+	case modules.ECReedSolomonGF16:
+		return modules.NewRSGF16Code(dataPieces, parityPieces)
+	case modules.ECLocallyRepairable:
+		localGroups := int(binary.LittleEndian.Uint16(ecParams[4:6]))
+		globalParity := int(binary.LittleEndian.Uint16(ecParams[6:]))
+		return modules.NewLRCode(dataPieces, localGroups, globalParity)
 	default:
 		return nil, errors.New("unknown erasure code type")
 	}
@@ -82,6 +82,40 @@ func TestMarshalUnmarshalErasureCoder(t *testing.T) {
 	}
 }
 
+// TestMarshalUnmarshalErasureCoderGF16 tests marshaling and unmarshaling a
+// GF(2^16) ErasureCoder with more pieces than GF(2^8) supports.
+func TestMarshalUnmarshalErasureCoderGF16(t *testing.T) {
+	rc, err := modules.NewRSGF16Code(64, 436)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecType, ecParams := marshalErasureCoder(rc)
+	rc2, err := unmarshalErasureCoder(ecType, ecParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Identifier() != rc2.Identifier() {
+		t.Fatalf("expected identifier %v but was %v", rc.Identifier(), rc2.Identifier())
+	}
+}
+
+// TestMarshalUnmarshalErasureCoderLRC tests marshaling and unmarshaling a
+// locally repairable ErasureCoder.
+func TestMarshalUnmarshalErasureCoderLRC(t *testing.T) {
+	rc, err := modules.NewLRCode(12, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecType, ecParams := marshalErasureCoder(rc)
+	rc2, err := unmarshalErasureCoder(ecType, ecParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Identifier() != rc2.Identifier() {
+		t.Fatalf("expected identifier %v but was %v", rc.Identifier(), rc2.Identifier())
+	}
+}
+
 // TestMarshalUnmarshalMetadata tests marshaling and unmarshaling the metadata
 // of a SiaFile.
 func TestMarshalUnmarshalMetadata(t *testing.T) {
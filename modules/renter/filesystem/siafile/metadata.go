@@ -60,6 +60,29 @@ type (
 		PartialChunks       []PartialChunkInfo `json:"partialchunks"`       // information about the partial chunk.
 		HasPartialChunk     bool               `json:"haspartialchunk"`     // indicates whether this file is supposed to have a partial chunk or not
 
+		// Fields for compression. The uncompressed data is split into frames
+		// of ChunkSize bytes which are compressed independently. The stored
+		// data is the concatenation of the compressed frames.
+		//
+		// CompressionIndex contains the offset of every compressed frame
+		// within the stored data followed by the total size of the stored
+		// data. It is nil until the upload of a compressed file is complete.
+		//
+		// UncompressedSize is the size of the file before compression.
+		CompressionType  modules.CompressionType `json:"compressiontype"`
+		CompressionIndex []uint64                `json:"compressionindex"`
+		UncompressedSize uint64                  `json:"uncompressedsize"`
+
+		// Fields for deduplication. The data of a deduplicated file is split
+		// into blocks at content-defined boundaries and every chunk contains a
+		// single block followed by zero padding.
+		//
+		// DedupOffsets contains the offset of every block within the file's
+		// data followed by the size of the data. It is nil until the upload of
+		// a deduplicated file is complete.
+		Deduplicated bool     `json:"deduplicated"`
+		DedupOffsets []uint64 `json:"dedupoffsets"`
+
 		// The following fields are the usual unix timestamps of files.
 		ModTime    time.Time `json:"modtime"`    // time of last content modification
 		ChangeTime time.Time `json:"changetime"` // time of last metadata modification
@@ -311,6 +334,9 @@ func (md Metadata) backup() (b Metadata) {
 	b.LocalPath = md.LocalPath
 	b.DisablePartialChunk = md.DisablePartialChunk
 	b.HasPartialChunk = md.HasPartialChunk
+	b.CompressionType = md.CompressionType
+	b.UncompressedSize = md.UncompressedSize
+	b.Deduplicated = md.Deduplicated
 	b.ModTime = md.ModTime
 	b.ChangeTime = md.ChangeTime
 	b.AccessTime = md.AccessTime
@@ -346,6 +372,18 @@ func (md Metadata) backup() (b Metadata) {
 		b.PartialChunks = make([]PartialChunkInfo, len(md.PartialChunks), cap(md.PartialChunks))
 		copy(b.PartialChunks, md.PartialChunks)
 	}
+	if md.CompressionIndex == nil {
+		b.CompressionIndex = nil
+	} else {
+		b.CompressionIndex = make([]uint64, len(md.CompressionIndex), cap(md.CompressionIndex))
+		copy(b.CompressionIndex, md.CompressionIndex)
+	}
+	if md.DedupOffsets == nil {
+		b.DedupOffsets = nil
+	} else {
+		b.DedupOffsets = make([]uint64, len(md.DedupOffsets), cap(md.DedupOffsets))
+		copy(b.DedupOffsets, md.DedupOffsets)
+	}
 	// If the backup was successful it should match the original.
 	if build.Release == "testing" && !md.equals(b) {
 		fmt.Println("md:\n", md)
@@ -363,6 +401,11 @@ func (md *Metadata) restore(b Metadata) {
 	md.DisablePartialChunk = b.DisablePartialChunk
 	md.PartialChunks = b.PartialChunks
 	md.HasPartialChunk = b.HasPartialChunk
+	md.CompressionType = b.CompressionType
+	md.CompressionIndex = b.CompressionIndex
+	md.UncompressedSize = b.UncompressedSize
+	md.Deduplicated = b.Deduplicated
+	md.DedupOffsets = b.DedupOffsets
 	md.ModTime = b.ModTime
 	md.ChangeTime = b.ChangeTime
 	md.AccessTime = b.AccessTime
@@ -581,7 +624,7 @@ func (sf *SiaFile) numStuckChunks() uint64 {
 
 // staticChunkSize returns the size of a single chunk of the file.
 func (sf *SiaFile) staticChunkSize() uint64 {
-	return sf.staticMetadata.StaticPieceSize * uint64(sf.staticMetadata.staticErasureCode.MinPieces())
+	return sf.staticMetadata.StaticPieceSize * uint64(sf.staticMetadata.staticErasureCode.DataPieces())
 }
 
 // staticMasterKey returns the masterkey used to encrypt the file.
@@ -94,6 +94,9 @@ type (
 	// Chunk is an exported chunk. It contains exported pieces.
 	Chunk struct {
 		Pieces [][]Piece
+
+		// extensionInfo is a copy of the chunk's ExtensionInfo.
+		extensionInfo [16]byte
 	}
 
 	// piece represents a single piece of a chunk on disk
@@ -197,7 +200,7 @@ func New(siaFilePath, source string, wal *writeaheadlog.WAL, erasureCode modules
 	minPieces := erasureCode.MinPieces()
 	numPieces := erasureCode.NumPieces()
 	zeroHealth := float64(1 + minPieces/(numPieces-minPieces))
-	repairSize := fileSize * uint64(numPieces/minPieces)
+	repairSize := fileSize * uint64(numPieces/erasureCode.DataPieces())
 	file := &SiaFile{
 		staticMetadata: Metadata{
 			AccessTime:              currentTime,
@@ -403,16 +406,22 @@ func (sf *SiaFile) AddPiece(pk types.SiaPublicKey, chunkIndex, pieceIndex uint64
 	if pieceIndex >= uint64(len(chunk.Pieces)) {
 		return fmt.Errorf("pieceIndex %v out of bounds (%v)", pieceIndex, len(chunk.Pieces))
 	}
-	// Add the piece to the chunk.
+	// Add the piece to the chunk. Repairs upload a piece which is already
+	// stored on other hosts, only the first piece of a piece index adds
+	// content to the file.
+	contentChanged := len(chunk.Pieces[pieceIndex]) == 0
 	chunk.Pieces[pieceIndex] = append(chunk.Pieces[pieceIndex], piece{
 		HostTableOffset: uint32(tableIndex),
 		MerkleRoot:      merkleRoot,
 	})
 
-	// Update the AccessTime, ChangeTime and ModTime.
+	// Update the AccessTime and ChangeTime. The ModTime is only updated if the
+	// content of the file changed.
 	sf.staticMetadata.AccessTime = time.Now()
 	sf.staticMetadata.ChangeTime = sf.staticMetadata.AccessTime
-	sf.staticMetadata.ModTime = sf.staticMetadata.AccessTime
+	if contentChanged {
+		sf.staticMetadata.ModTime = sf.staticMetadata.AccessTime
+	}
 
 	// Defrag the chunk if necessary.
 	chunkSize := marshaledChunkSize(chunk.numPieces())
@@ -444,6 +453,74 @@ func (sf *SiaFile) AddPiece(pk types.SiaPublicKey, chunkIndex, pieceIndex uint64
 	return sf.createAndApplyTransaction(append(updates, chunkUpdate)...)
 }
 
+// RemovePiece removes a piece with the given merkle root, which is stored on
+// the host with the given public key, from the file. This is used to drop
+// pieces which a host can no longer prove to be storing so that the chunk gets
+// repaired. Removing a piece that doesn't exist is a no-op.
+func (sf *SiaFile) RemovePiece(pk types.SiaPublicKey, chunkIndex, pieceIndex uint64, merkleRoot crypto.Hash) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't remove piece from deleted file")
+	}
+	if sf.isIncompletePartialChunk(chunkIndex) {
+		return errors.New("can't remove piece from incomplete partial chunk")
+	}
+	// Backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+
+	// Update cache.
+	defer sf.uploadProgressAndBytes()
+
+	// Handle piece being removed from the partial chunk.
+	if cci, ok := sf.isIncludedPartialChunk(chunkIndex); ok {
+		return sf.partialsSiaFile.RemovePiece(pk, cci.Index, pieceIndex, merkleRoot)
+	}
+	// Check if the chunkIndex is valid.
+	if chunkIndex >= uint64(sf.numChunks) {
+		return fmt.Errorf("chunkIndex %v out of bounds (%v)", chunkIndex, sf.numChunks)
+	}
+	// Get the chunk from disk.
+	chunk, err := sf.chunk(int(chunkIndex))
+	if err != nil {
+		return errors.AddContext(err, "failed to get chunk")
+	}
+	// Check if the pieceIndex is valid.
+	if pieceIndex >= uint64(len(chunk.Pieces)) {
+		return fmt.Errorf("pieceIndex %v out of bounds (%v)", pieceIndex, len(chunk.Pieces))
+	}
+	// Remove the piece from the chunk.
+	var pieces []piece
+	for _, p := range chunk.Pieces[pieceIndex] {
+		if p.MerkleRoot == merkleRoot && int(p.HostTableOffset) < len(sf.pubKeyTable) &&
+			sf.pubKeyTable[p.HostTableOffset].PublicKey.Equals(pk) {
+			continue
+		}
+		pieces = append(pieces, p)
+	}
+	if len(pieces) == len(chunk.Pieces[pieceIndex]) {
+		return nil // nothing to remove
+	}
+	chunk.Pieces[pieceIndex] = pieces
+
+	// Update the ChangeTime. The ModTime is not updated since the content of
+	// the file didn't change.
+	sf.staticMetadata.ChangeTime = time.Now()
+
+	// Update the file atomically.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	chunkUpdate := sf.saveChunkUpdate(chunk)
+	return sf.createAndApplyTransaction(append(updates, chunkUpdate)...)
+}
+
 // chunkHealth returns the health and user health of the chunk which is defined
 // as the percent of parity pieces remaining. When calculating the user health
 // we assume that an incomplete partial chunk has full health. For the regular
@@ -800,6 +800,74 @@ func TestPruneHosts(t *testing.T) {
 	}
 }
 
+// TestRemovePiece tests removing pieces from a SiaFile.
+func TestRemovePiece(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	sf, wal, _ := newBlankTestFileAndWAL(2)
+
+	// Add the same piece to two hosts.
+	var root crypto.Hash
+	fastrand.Read(root[:])
+	pk1 := types.SiaPublicKey{Key: fastrand.Bytes(crypto.PublicKeySize)}
+	pk2 := types.SiaPublicKey{Key: fastrand.Bytes(crypto.PublicKeySize)}
+	if err := sf.AddPiece(pk1, 0, 1, root); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.AddPiece(pk2, 0, 1, root); err != nil {
+		t.Fatal(err)
+	}
+	modTime := sf.ModTime()
+
+	// Removing a piece that doesn't exist is a no-op.
+	var otherRoot crypto.Hash
+	fastrand.Read(otherRoot[:])
+	if err := sf.RemovePiece(pk1, 0, 1, otherRoot); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.RemovePiece(pk1, 0, 0, root); err != nil {
+		t.Fatal(err)
+	}
+	// Out of bounds indices return an error.
+	if err := sf.RemovePiece(pk1, sf.NumChunks(), 1, root); err == nil {
+		t.Fatal("expected error for out of bounds chunk")
+	}
+	if err := sf.RemovePiece(pk1, 0, uint64(sf.ErasureCode().NumPieces()), root); err == nil {
+		t.Fatal("expected error for out of bounds piece")
+	}
+
+	// Remove the piece of the first host.
+	if err := sf.RemovePiece(pk1, 0, 1, root); err != nil {
+		t.Fatal(err)
+	}
+	pieces, err := sf.Pieces(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces[1]) != 1 || !pieces[1][0].HostPubKey.Equals(pk2) {
+		t.Fatal("wrong pieces after removal", pieces[1])
+	}
+	if !sf.ModTime().Equal(modTime) {
+		t.Fatal("ModTime shouldn't change")
+	}
+
+	// The change is persisted.
+	sf, err = LoadSiaFile(sf.siaFilePath, wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pieces, err = sf.Pieces(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces[1]) != 1 || !pieces[1][0].HostPubKey.Equals(pk2) {
+		t.Fatal("wrong pieces after reload", pieces[1])
+	}
+}
+
 // TestNumPieces tests the chunk's numPieces method.
 func TestNumPieces(t *testing.T) {
 	// create a random chunk.
@@ -19,6 +19,8 @@ type (
 	// representation of a siafile which only exists in memory.
 	Snapshot struct {
 		staticChunks          []Chunk
+		staticCompression     CompressionInfo
+		staticDedup           DedupInfo
 		staticFileSize        int64
 		staticPieceSize       uint64
 		staticErasureCode     modules.ErasureCoder
@@ -110,7 +112,7 @@ func (s *Snapshot) ChunkIndexByOffset(offset uint64) (chunkIndex uint64, off uin
 
 // ChunkSize returns the size of a single chunk of the file.
 func (s *Snapshot) ChunkSize() uint64 {
-	return s.staticPieceSize * uint64(s.staticErasureCode.MinPieces())
+	return s.staticPieceSize * uint64(s.staticErasureCode.DataPieces())
 }
 
 // PartialChunks returns the snapshot's PartialChunks.
@@ -270,7 +272,8 @@ func (sf *SiaFile) readlockSnapshot(sp modules.SiaPath, chunks []chunk) (*Snapsh
 			}
 		}
 		exportedChunks = append(exportedChunks, Chunk{
-			Pieces: pieces,
+			Pieces:        pieces,
+			extensionInfo: chunk.ExtensionInfo,
 		})
 	}
 	// Get non-static metadata fields under lock.
@@ -283,6 +286,8 @@ func (sf *SiaFile) readlockSnapshot(sp modules.SiaPath, chunks []chunk) (*Snapsh
 
 	return &Snapshot{
 		staticChunks:          exportedChunks,
+		staticCompression:     sf.compressionInfo(),
+		staticDedup:           sf.dedupInfo(),
 		staticPartialChunks:   pcs,
 		staticHasPartialChunk: hasPartial,
 		staticFileSize:        fileSize,
@@ -6,7 +6,9 @@ package renter
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"math"
+	"os"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -14,6 +16,7 @@ import (
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"gitlab.com/NebulousLabs/errors"
+	"go.sia.tech/siad/crypto"
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/modules/renter/filesystem"
 )
@@ -51,25 +54,49 @@ type fuseDirnode struct {
 //
 // NodeStatfser is necessary to provide information about the filesystem that
 // contains the directory.
+//
+// NodeCreater, NodeMkdirer, NodeRenamer, NodeRmdirer and NodeUnlinker are
+// necessary to modify the directory tree when the filesystem is not mounted
+// read-only.
 var _ = (fs.NodeAccesser)((*fuseDirnode)(nil))
+var _ = (fs.NodeCreater)((*fuseDirnode)(nil))
 var _ = (fs.NodeFlusher)((*fuseDirnode)(nil))
 var _ = (fs.NodeGetattrer)((*fuseDirnode)(nil))
 var _ = (fs.NodeLookuper)((*fuseDirnode)(nil))
+var _ = (fs.NodeMkdirer)((*fuseDirnode)(nil))
 var _ = (fs.NodeReaddirer)((*fuseDirnode)(nil))
+var _ = (fs.NodeRenamer)((*fuseDirnode)(nil))
+var _ = (fs.NodeRmdirer)((*fuseDirnode)(nil))
 var _ = (fs.NodeStatfser)((*fuseDirnode)(nil))
+var _ = (fs.NodeUnlinker)((*fuseDirnode)(nil))
 
 // fuseFilenode is a fuse node for the fs package that covers a siafile.
 //
 // Data is fetched using a download streamer. This download streamer needs to be
 // closed when the filehandle is released.
+//
+// Writes are not sent to the network directly. Instead the file is copied into
+// a local staging file when it is opened for writing, all writes are applied to
+// that staging file and the whole file is uploaded again through
+// UploadStreamFromReader when the file is flushed. Since the upload replaces
+// the siafile, the fileNode is not static.
+//
+// attrMu protects the fileNode and the staging fields which are accessed by
+// Getattr. Getattr can't use 'mu' since 'mu' is held for the duration of reads
+// and uploads. Updating those fields requires holding both 'mu' and 'attrMu'.
 type fuseFilenode struct {
 	atomicClosed uint32
 
 	fs.Inode
 	staticFilesystem *fuseFS
-	staticFileNode   *filesystem.FileNode
-	stream           modules.Streamer
-	mu               sync.Mutex
+
+	fileNode *filesystem.FileNode
+	staging  *os.File
+	attrMu   sync.Mutex
+
+	stagingDirty bool
+	stream       modules.Streamer
+	mu           sync.Mutex
 }
 
 // Ensure the file nodes satisfy the required interfaces.
@@ -88,18 +115,32 @@ type fuseFilenode struct {
 //
 // NodeStatfser is necessary to provide information about the filesystem that
 // contains the file.
+//
+// NodeReleaser is necessary for cleaning up the staging file once the last
+// handle of a written file is closed.
+//
+// NodeSetattrer is necessary for truncating files and changing their mode.
+//
+// NodeWriter is necessary for writing files.
 var _ = (fs.NodeAccesser)((*fuseFilenode)(nil))
 var _ = (fs.NodeFlusher)((*fuseFilenode)(nil))
 var _ = (fs.NodeGetattrer)((*fuseFilenode)(nil))
 var _ = (fs.NodeOpener)((*fuseFilenode)(nil))
 var _ = (fs.NodeReader)((*fuseFilenode)(nil))
+var _ = (fs.NodeReleaser)((*fuseFilenode)(nil))
+var _ = (fs.NodeSetattrer)((*fuseFilenode)(nil))
 var _ = (fs.NodeStatfser)((*fuseFilenode)(nil))
+var _ = (fs.NodeWriter)((*fuseFilenode)(nil))
 
 // fuseRoot is the root directory for a mounted fuse filesystem.
 type fuseFS struct {
 	options modules.MountOptions
 	root    *fuseDirnode
 
+	// stagingDir is the local directory which holds the staging files of
+	// files that are currently open for writing.
+	stagingDir string
+
 	renter *Renter
 	server *fuse.Server
 }
@@ -108,12 +149,22 @@ type fuseFS struct {
 func errToStatus(err error) syscall.Errno {
 	if err == nil {
 		return syscall.F_OK
-	} else if errors.IsOSNotExist(err) {
+	} else if errors.IsOSNotExist(err) || errors.Contains(err, filesystem.ErrNotExist) {
 		return syscall.ENOENT
+	} else if errors.Contains(err, filesystem.ErrExists) {
+		return syscall.EEXIST
+	} else if errors.Contains(err, filesystem.ErrDeleteFileIsDir) {
+		return syscall.EISDIR
 	}
 	return syscall.EIO
 }
 
+// isWriteFlags returns true if the open flags provided by fuse indicate that
+// the file is opened for writing.
+func isWriteFlags(flags uint32) bool {
+	return flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+}
+
 // Access reports whether a directory can be accessed by the caller.
 func (fdn *fuseDirnode) Access(ctx context.Context, mask uint32) syscall.Errno {
 	// TODO: parse the mask and return a more correct value instead of always
@@ -138,14 +189,27 @@ func (fdn *fuseDirnode) Flush(ctx context.Context, fh fs.FileHandle) syscall.Err
 	return errToStatus(err)
 }
 
-// Flush is called when a file is being closed.
+// Flush is called when a file is being closed. If the file has been written
+// to, the staging file is uploaded to the network before returning so that
+// the error can be reported to the caller of close.
 func (ffn *fuseFilenode) Flush(ctx context.Context, fh fs.FileHandle) syscall.Errno {
+	ffn.mu.Lock()
+	defer ffn.mu.Unlock()
+
+	// Upload the staged data if the file was modified.
+	if ffn.stagingDirty {
+		err := ffn.uploadStaging()
+		if err != nil {
+			siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
+			ffn.staticFilesystem.renter.log.Printf("error when uploading fuse file %v: %v", siaPath, err)
+			return errToStatus(err)
+		}
+	}
+
 	swapped := atomic.CompareAndSwapUint32(&ffn.atomicClosed, 0, 1)
 	if !swapped {
 		return errToStatus(nil)
 	}
-	ffn.mu.Lock()
-	defer ffn.mu.Unlock()
 
 	// If a stream was opened for the file, the stream must now be closed.
 	var streamErr error
@@ -153,19 +217,86 @@ func (ffn *fuseFilenode) Flush(ctx context.Context, fh fs.FileHandle) syscall.Er
 		// Need to 'nil' out the stream once 'Flush' has been called because it
 		// can be called multiple times.
 		streamErr = ffn.stream.Close()
+		ffn.stream = nil
 	}
 
 	// Check all of the errors.
-	closeErr := ffn.staticFileNode.Close()
+	fileNode := ffn.managedFileNode()
+	closeErr := fileNode.Close()
 	err := errors.Compose(streamErr, closeErr)
 	if err != nil {
-		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.staticFileNode)
+		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(fileNode)
 		ffn.staticFilesystem.renter.log.Printf("error when flushing fuse file %v: %v", siaPath, err)
 		return errToStatus(err)
 	}
 	return errToStatus(nil)
 }
 
+// Release is called when the last handle to a file is closed. Any staging file
+// is removed at this point, a modified staging file was already uploaded by
+// Flush.
+func (ffn *fuseFilenode) Release(ctx context.Context, fh fs.FileHandle) syscall.Errno {
+	ffn.mu.Lock()
+	defer ffn.mu.Unlock()
+	if ffn.stagingDirty {
+		// Flush failed to upload the file. There is no way to report the
+		// error anymore, so at least log that the changes are lost.
+		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
+		ffn.staticFilesystem.renter.log.Printf("discarding unflushed changes to fuse file %v", siaPath)
+	}
+	return errToStatus(ffn.closeStaging())
+}
+
+// newDirInode creates a new inode for the provided dir node and sets the
+// critical entry out values.
+func (fdn *fuseDirnode) newDirInode(ctx context.Context, dirNode *filesystem.DirNode, out *fuse.EntryOut) (*fs.Inode, error) {
+	dirInfo, err := fdn.staticFilesystem.renter.staticFileSystem.DirNodeInfo(dirNode)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to fetch info from dir")
+	}
+	dirnode := &fuseDirnode{
+		staticDirNode:    dirNode,
+		staticFilesystem: fdn.staticFilesystem,
+	}
+	attrs := fs.StableAttr{
+		Ino:  dirInfo.UID,
+		Mode: fuse.S_IFDIR,
+	}
+	out.Ino = dirInfo.UID
+	out.Mode = uint32(dirInfo.Mode())
+	return fdn.NewInode(ctx, dirnode, attrs), nil
+}
+
+// newFileInode creates a new inode for the provided file node and sets the
+// critical entry out values.
+func (fdn *fuseDirnode) newFileInode(ctx context.Context, fileNode *filesystem.FileNode, out *fuse.EntryOut) (*fs.Inode, *fuseFilenode, error) {
+	fileInfo, err := fdn.staticFilesystem.renter.staticFileSystem.FileNodeInfo(fileNode)
+	if err != nil {
+		return nil, nil, errors.AddContext(err, "unable to fetch info from file")
+	}
+	filenode := &fuseFilenode{
+		staticFilesystem: fdn.staticFilesystem,
+		fileNode:         fileNode,
+	}
+	attrs := fs.StableAttr{
+		Ino:  fileInfo.UID,
+		Mode: fuse.S_IFREG,
+	}
+
+	// Set the crticial entry out values.
+	//
+	// TODO: Set more of these, there are like 20 of them.
+	out.Ino = fileInfo.UID
+	out.Size = fileInfo.Filesize
+	out.Mode = uint32(fileInfo.Mode())
+	return fdn.NewInode(ctx, filenode, attrs), filenode, nil
+}
+
+// childSiaPath returns the SiaPath of the child with the provided name.
+func (fdn *fuseDirnode) childSiaPath(name string) (modules.SiaPath, error) {
+	return fdn.staticFilesystem.renter.staticFileSystem.DirSiaPath(fdn.staticDirNode).Join(name)
+}
+
 // Lookup is a directory call that returns the file in the directory associated
 // with the provided name. When a file browser is opening folders with lots of
 // files, this method can be called thousands of times concurrently in a single
@@ -173,30 +304,13 @@ func (ffn *fuseFilenode) Flush(ctx context.Context, fh fs.FileHandle) syscall.Er
 func (fdn *fuseDirnode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	fileNode, fileErr := fdn.staticDirNode.File(name)
 	if fileErr == nil {
-		fileInfo, err := fdn.staticFilesystem.renter.staticFileSystem.FileNodeInfo(fileNode)
+		// Convert the file to an inode.
+		inode, _, err := fdn.newFileInode(ctx, fileNode, out)
 		if err != nil {
 			siaPath := fdn.staticFilesystem.renter.staticFileSystem.DirSiaPath(fdn.staticDirNode)
 			fdn.staticFilesystem.renter.log.Printf("Unable to fetch fileinfo on file %v from dir %v: %v", name, siaPath, err)
-			return nil, errToStatus(err)
-		}
-		// Convert the file to an inode.
-		filenode := &fuseFilenode{
-			staticFilesystem: fdn.staticFilesystem,
-			staticFileNode:   fileNode,
-		}
-		attrs := fs.StableAttr{
-			Ino:  fileInfo.UID,
-			Mode: fuse.S_IFREG,
+			return nil, errToStatus(errors.Compose(err, fileNode.Close()))
 		}
-
-		// Set the crticial entry out values.
-		//
-		// TODO: Set more of these, there are like 20 of them.
-		out.Ino = fileInfo.UID
-		out.Size = fileInfo.Filesize
-		out.Mode = uint32(fileInfo.Mode())
-
-		inode := fdn.NewInode(ctx, filenode, attrs)
 		return inode, errToStatus(nil)
 	}
 
@@ -206,27 +320,207 @@ func (fdn *fuseDirnode) Lookup(ctx context.Context, name string, out *fuse.Entry
 		fdn.staticFilesystem.renter.log.Printf("Unable to perform lookup on %v in dir %v; file err %v :: dir err %v", name, siaPath, fileErr, dirErr)
 		return nil, errToStatus(dirErr)
 	}
-	dirInfo, err := fdn.staticFilesystem.renter.staticFileSystem.DirNodeInfo(childDir)
+
+	// We found the directory we want, convert to an inode.
+	inode, err := fdn.newDirInode(ctx, childDir, out)
 	if err != nil {
 		fdn.staticFilesystem.renter.log.Printf("Unable to fetch info from childDir: %v", err)
-		return nil, errToStatus(err)
+		return nil, errToStatus(errors.Compose(err, childDir.Close()))
 	}
+	return inode, errToStatus(nil)
+}
 
-	// We found the directory we want, convert to an inode.
-	dirnode := &fuseDirnode{
-		staticDirNode:    childDir,
-		staticFilesystem: fdn.staticFilesystem,
+// Create creates a new, empty file in the directory and opens it for writing.
+// The data written to the file is uploaded once the file is flushed.
+func (fdn *fuseDirnode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	if fdn.staticFilesystem.options.ReadOnly {
+		return nil, nil, 0, syscall.EROFS
 	}
-	attrs := fs.StableAttr{
-		Ino:  dirInfo.UID,
-		Mode: fuse.S_IFDIR,
+	r := fdn.staticFilesystem.renter
+	siaPath, err := fdn.childSiaPath(name)
+	if err != nil {
+		return nil, nil, 0, syscall.EINVAL
+	}
+
+	// Create an empty siafile right away to make sure the file shows up in
+	// the directory before the first flush.
+	policy, err := r.managedFilePolicy(siaPath)
+	if err != nil {
+		r.log.Printf("Unable to get policy for fuse file %v: %v", siaPath, err)
+		return nil, nil, 0, errToStatus(err)
+	}
+	ec, err := policy.ErasureCoder()
+	if err != nil {
+		r.log.Printf("Unable to create erasure coder for fuse file %v: %v", siaPath, err)
+		return nil, nil, 0, errToStatus(err)
+	}
+	cipherKey := crypto.GenerateSiaKey(policy.Cipher())
+	fileMode := os.FileMode(mode) & os.ModePerm
+	err = r.staticFileSystem.NewSiaFile(siaPath, "", ec, cipherKey, 0, fileMode, false)
+	if err != nil {
+		r.log.Printf("Unable to create fuse file %v: %v", siaPath, err)
+		return nil, nil, 0, errToStatus(err)
+	}
+	fileNode, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		r.log.Printf("Unable to open newly created fuse file %v: %v", siaPath, err)
+		return nil, nil, 0, errToStatus(err)
+	}
+	inode, filenode, err := fdn.newFileInode(ctx, fileNode, out)
+	if err != nil {
+		r.log.Printf("Unable to create inode for fuse file %v: %v", siaPath, err)
+		return nil, nil, 0, errToStatus(errors.Compose(err, fileNode.Close()))
+	}
+
+	// Prepare the staging file. The file is new so there is nothing to copy.
+	filenode.mu.Lock()
+	err = filenode.openStaging(true)
+	filenode.mu.Unlock()
+	if err != nil {
+		r.log.Printf("Unable to create staging file for fuse file %v: %v", siaPath, err)
+		return nil, nil, 0, errToStatus(err)
+	}
+
+	// Bubble the parent to reflect the new file.
+	dirSiaPath := fdn.staticFilesystem.renter.staticFileSystem.DirSiaPath(fdn.staticDirNode)
+	_ = r.staticBubbleScheduler.callQueueBubble(dirSiaPath)
+	return inode, filenode, 0, errToStatus(nil)
+}
+
+// Mkdir creates a new directory.
+func (fdn *fuseDirnode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if fdn.staticFilesystem.options.ReadOnly {
+		return nil, syscall.EROFS
+	}
+	r := fdn.staticFilesystem.renter
+	siaPath, err := fdn.childSiaPath(name)
+	if err != nil {
+		return nil, syscall.EINVAL
+	}
+	err = r.CreateDir(siaPath, os.FileMode(mode)&os.ModePerm)
+	if err != nil {
+		r.log.Printf("Unable to create fuse dir %v: %v", siaPath, err)
+		return nil, errToStatus(err)
+	}
+	dirNode, err := r.staticFileSystem.OpenSiaDir(siaPath)
+	if err != nil {
+		r.log.Printf("Unable to open newly created fuse dir %v: %v", siaPath, err)
+		return nil, errToStatus(err)
+	}
+	inode, err := fdn.newDirInode(ctx, dirNode, out)
+	if err != nil {
+		r.log.Printf("Unable to create inode for fuse dir %v: %v", siaPath, err)
+		return nil, errToStatus(errors.Compose(err, dirNode.Close()))
 	}
-	out.Ino = dirInfo.UID
-	out.Mode = uint32(dirInfo.Mode())
-	inode := fdn.NewInode(ctx, dirnode, attrs)
 	return inode, errToStatus(nil)
 }
 
+// Rename moves the file or directory with the provided name to newName within
+// newParent. Like rename(2), an existing file at the destination is replaced.
+func (fdn *fuseDirnode) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if fdn.staticFilesystem.options.ReadOnly {
+		return syscall.EROFS
+	}
+	// RENAME_EXCHANGE and RENAME_NOREPLACE are not supported.
+	if flags != 0 {
+		return syscall.ENOTSUP
+	}
+	newParentDir, ok := newParent.(*fuseDirnode)
+	if !ok {
+		return syscall.EXDEV
+	}
+	r := fdn.staticFilesystem.renter
+	oldSiaPath, err := fdn.childSiaPath(name)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	newSiaPath, err := newParentDir.childSiaPath(newName)
+	if err != nil {
+		return syscall.EINVAL
+	}
+
+	// Check if the source is a file or a directory.
+	isFile, err := r.staticFileSystem.FileExists(oldSiaPath)
+	if err != nil {
+		return errToStatus(err)
+	}
+	if !isFile {
+		err = r.RenameDir(oldSiaPath, newSiaPath)
+		if err != nil {
+			r.log.Printf("Unable to rename fuse dir %v to %v: %v", oldSiaPath, newSiaPath, err)
+		}
+		return errToStatus(err)
+	}
+
+	// Replace an existing file at the destination.
+	exists, err := r.staticFileSystem.FileExists(newSiaPath)
+	if err != nil {
+		return errToStatus(err)
+	}
+	if exists {
+		err = r.managedArchiveFile(newSiaPath)
+		if err != nil {
+			r.log.Printf("Unable to replace fuse file %v: %v", newSiaPath, err)
+			return errToStatus(err)
+		}
+	}
+	err = r.RenameFile(oldSiaPath, newSiaPath)
+	if err != nil {
+		r.log.Printf("Unable to rename fuse file %v to %v: %v", oldSiaPath, newSiaPath, err)
+	}
+	return errToStatus(err)
+}
+
+// Rmdir removes an empty directory.
+func (fdn *fuseDirnode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if fdn.staticFilesystem.options.ReadOnly {
+		return syscall.EROFS
+	}
+	r := fdn.staticFilesystem.renter
+	siaPath, err := fdn.childSiaPath(name)
+	if err != nil {
+		return syscall.EINVAL
+	}
+
+	// Like rmdir(2), only empty directories can be removed.
+	dirNode, err := r.staticFileSystem.OpenSiaDir(siaPath)
+	if err != nil {
+		return errToStatus(err)
+	}
+	fileinfos, dirinfos, err := r.staticFileSystem.CachedListOnNode(dirNode)
+	err = errors.Compose(err, dirNode.Close())
+	if err != nil {
+		r.log.Printf("Unable to list fuse dir %v: %v", siaPath, err)
+		return errToStatus(err)
+	}
+	// The first directory is always the directory itself.
+	if len(fileinfos) > 0 || len(dirinfos) > 1 {
+		return syscall.ENOTEMPTY
+	}
+	err = r.DeleteDir(siaPath)
+	if err != nil {
+		r.log.Printf("Unable to delete fuse dir %v: %v", siaPath, err)
+	}
+	return errToStatus(err)
+}
+
+// Unlink deletes a file.
+func (fdn *fuseDirnode) Unlink(ctx context.Context, name string) syscall.Errno {
+	if fdn.staticFilesystem.options.ReadOnly {
+		return syscall.EROFS
+	}
+	r := fdn.staticFilesystem.renter
+	siaPath, err := fdn.childSiaPath(name)
+	if err != nil {
+		return syscall.EINVAL
+	}
+	err = r.DeleteFile(siaPath)
+	if err != nil {
+		r.log.Printf("Unable to delete fuse file %v: %v", siaPath, err)
+	}
+	return errToStatus(err)
+}
+
 // Getattr returns the attributes of a fuse dir.
 func (fdn *fuseDirnode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	dirInfo, err := fdn.staticFilesystem.renter.staticFileSystem.DirNodeInfo(fdn.staticDirNode)
@@ -245,7 +539,7 @@ func (fdn *fuseDirnode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse
 // Getattr should try to minimize lock contention and should run very quickly if
 // possible.
 func (ffn *fuseFilenode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
-	fileInfo, err := ffn.staticFilesystem.renter.staticFileSystem.FileNodeInfo(ffn.staticFileNode)
+	fileInfo, err := ffn.staticFilesystem.renter.staticFileSystem.FileNodeInfo(ffn.managedFileNode())
 	if err != nil {
 		ffn.staticFilesystem.renter.log.Printf("Unable to fetch info from file: %v", err)
 	}
@@ -253,10 +547,42 @@ func (ffn *fuseFilenode) Getattr(ctx context.Context, fh fs.FileHandle, out *fus
 	out.Size = fileInfo.Filesize
 	out.Mode = uint32(fileInfo.Mode()) | syscall.S_IFREG
 	out.Ino = fileInfo.UID
+
+	// If the file is being written to, the size of the staging file is the
+	// size the caller expects to see.
+	if size, staged := ffn.managedStagingSize(); staged {
+		out.Size = size
+	}
 	return errToStatus(nil)
 }
 
-// Open will open a streamer for the file.
+// Setattr sets the attributes of a fuse file. Only changing the size and the
+// mode of a file is supported.
+func (ffn *fuseFilenode) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	mode, setMode := in.GetMode()
+	size, setSize := in.GetSize()
+	if (setMode || setSize) && ffn.staticFilesystem.options.ReadOnly {
+		return syscall.EROFS
+	}
+	if setMode {
+		err := ffn.managedFileNode().SetMode(os.FileMode(mode) & os.ModePerm)
+		if err != nil {
+			ffn.staticFilesystem.renter.log.Printf("Unable to set mode of fuse file: %v", err)
+			return errToStatus(err)
+		}
+	}
+	if setSize {
+		err := ffn.managedTruncate(int64(size))
+		if err != nil {
+			ffn.staticFilesystem.renter.log.Printf("Unable to truncate fuse file: %v", err)
+			return errToStatus(err)
+		}
+	}
+	return ffn.Getattr(ctx, fh, out)
+}
+
+// Open will open a streamer for the file. If the file is opened for writing, a
+// staging file is prepared instead.
 //
 // TODO: Currently 'Open' returns '0' for the fuseFlags. I was unable to figure
 // out from the documentation what the flags are supposed to represent. So far,
@@ -265,12 +591,27 @@ func (ffn *fuseFilenode) Open(ctx context.Context, flags uint32) (fs.FileHandle,
 	ffn.mu.Lock()
 	defer ffn.mu.Unlock()
 
-	stream, err := ffn.staticFilesystem.renter.StreamerByNode(ffn.staticFileNode, false)
+	siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
+	if isWriteFlags(flags) {
+		if ffn.staticFilesystem.options.ReadOnly {
+			return nil, 0, syscall.EROFS
+		}
+		err := ffn.openStaging(flags&syscall.O_TRUNC != 0)
+		if err != nil {
+			ffn.staticFilesystem.renter.log.Printf("Unable to open staging file for file %v: %v", siaPath, err)
+			return nil, 0, errToStatus(err)
+		}
+		return ffn, 0, errToStatus(nil)
+	}
+
+	stream, err := ffn.staticFilesystem.renter.StreamerByNode(ffn.managedFileNode(), false)
 	if err != nil {
-		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.staticFileNode)
 		ffn.staticFilesystem.renter.log.Printf("Unable to get stream for file %v: %v", siaPath, err)
 		return nil, 0, errToStatus(err)
 	}
+	if ffn.stream != nil {
+		_ = ffn.stream.Close()
+	}
 	ffn.stream = stream
 	return ffn, 0, errToStatus(nil)
 }
@@ -285,9 +626,33 @@ func (ffn *fuseFilenode) Read(ctx context.Context, f fs.FileHandle, dest []byte,
 	ffn.mu.Lock()
 	defer ffn.mu.Unlock()
 
+	// If the file is being written to, the staging file contains the most
+	// recent data.
+	if ffn.staging != nil {
+		n, err := ffn.staging.ReadAt(dest, offset)
+		if err != nil && err != io.EOF {
+			siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
+			ffn.staticFilesystem.renter.log.Printf("Error reading staging file from offset %v during call to Read in file %s: %v", offset, siaPath.String(), err)
+			return nil, errToStatus(err)
+		}
+		return fuse.ReadResultData(dest[:n]), errToStatus(nil)
+	}
+
+	// The stream might have been closed by a previous Flush or the file might
+	// have been uploaded since it was opened.
+	if ffn.stream == nil {
+		stream, err := ffn.staticFilesystem.renter.StreamerByNode(ffn.managedFileNode(), false)
+		if err != nil {
+			siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
+			ffn.staticFilesystem.renter.log.Printf("Unable to get stream for file %v: %v", siaPath, err)
+			return nil, errToStatus(err)
+		}
+		ffn.stream = stream
+	}
+
 	_, err := ffn.stream.Seek(offset, io.SeekStart)
 	if err != nil {
-		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.staticFileNode)
+		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
 		ffn.staticFilesystem.renter.log.Printf("Error seeking to offset %v during call to Read in file %s: %v", offset, siaPath.String(), err)
 		return nil, errToStatus(err)
 	}
@@ -298,7 +663,7 @@ func (ffn *fuseFilenode) Read(ctx context.Context, f fs.FileHandle, dest []byte,
 	// often dropping parts of the tail of the file.
 	n, err := io.ReadFull(ffn.stream, dest)
 	if err != nil && !errors.Contains(err, io.EOF) && err != io.ErrUnexpectedEOF {
-		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.staticFileNode)
+		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
 		ffn.staticFilesystem.renter.log.Printf("Error reading from offset %v during call to Read in file %s: %v", offset, siaPath.String(), err)
 		return nil, errToStatus(err)
 	}
@@ -307,6 +672,184 @@ func (ffn *fuseFilenode) Read(ctx context.Context, f fs.FileHandle, dest []byte,
 	return fuse.ReadResultData(dest[:n]), errToStatus(nil)
 }
 
+// Write will write data to the staging file of the file. The data is uploaded
+// when the file is flushed.
+func (ffn *fuseFilenode) Write(ctx context.Context, f fs.FileHandle, data []byte, offset int64) (uint32, syscall.Errno) {
+	if ffn.staticFilesystem.options.ReadOnly {
+		return 0, syscall.EROFS
+	}
+	ffn.mu.Lock()
+	defer ffn.mu.Unlock()
+
+	// The staging file is opened in Open and Create. If it doesn't exist, the
+	// file wasn't opened for writing.
+	if ffn.staging == nil {
+		return 0, syscall.EBADF
+	}
+	n, err := ffn.staging.WriteAt(data, offset)
+	if n > 0 {
+		ffn.stagingDirty = true
+	}
+	if err != nil {
+		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
+		ffn.staticFilesystem.renter.log.Printf("Error writing to staging file at offset %v during call to Write in file %s: %v", offset, siaPath.String(), err)
+		return uint32(n), errToStatus(err)
+	}
+	return uint32(n), errToStatus(nil)
+}
+
+// managedFileNode returns the siafile node that currently backs the fuse file.
+func (ffn *fuseFilenode) managedFileNode() *filesystem.FileNode {
+	ffn.attrMu.Lock()
+	defer ffn.attrMu.Unlock()
+	return ffn.fileNode
+}
+
+// managedStagingSize returns the size of the staging file and whether or not
+// the file is currently staged.
+func (ffn *fuseFilenode) managedStagingSize() (uint64, bool) {
+	ffn.attrMu.Lock()
+	defer ffn.attrMu.Unlock()
+	if ffn.staging == nil {
+		return 0, false
+	}
+	fi, err := ffn.staging.Stat()
+	if err != nil {
+		return 0, false
+	}
+	return uint64(fi.Size()), true
+}
+
+// managedTruncate truncates the file to the provided size.
+func (ffn *fuseFilenode) managedTruncate(size int64) error {
+	ffn.mu.Lock()
+	defer ffn.mu.Unlock()
+	closeAfter := ffn.staging == nil
+	// There is no need to copy the existing data if everything is truncated.
+	err := ffn.openStaging(size == 0)
+	if err != nil {
+		return errors.AddContext(err, "unable to open staging file")
+	}
+	err = ffn.staging.Truncate(size)
+	if err != nil {
+		return errors.AddContext(err, "unable to truncate staging file")
+	}
+	ffn.stagingDirty = true
+
+	// If the file wasn't open for writing, e.g. truncate(2) was called on a
+	// path, the change needs to be uploaded right away.
+	if !closeAfter {
+		return nil
+	}
+	err = ffn.uploadStaging()
+	return errors.Compose(err, ffn.closeStaging())
+}
+
+// closeStaging closes and removes the staging file.
+func (ffn *fuseFilenode) closeStaging() error {
+	if ffn.staging == nil {
+		return nil
+	}
+	ffn.attrMu.Lock()
+	f := ffn.staging
+	ffn.staging = nil
+	ffn.attrMu.Unlock()
+	ffn.stagingDirty = false
+	return errors.Compose(f.Close(), os.Remove(f.Name()))
+}
+
+// openStaging makes sure that a staging file exists for the file. If truncate
+// is false, the current contents of the file are downloaded into the staging
+// file.
+func (ffn *fuseFilenode) openStaging(truncate bool) (err error) {
+	if ffn.staging != nil {
+		if truncate {
+			ffn.stagingDirty = true
+			return ffn.staging.Truncate(0)
+		}
+		return nil
+	}
+	f, err := ioutil.TempFile(ffn.staticFilesystem.stagingDir, "staging-")
+	if err != nil {
+		return errors.AddContext(err, "unable to create staging file")
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Compose(err, f.Close(), os.Remove(f.Name()))
+		}
+	}()
+
+	// Copy the existing data if necessary.
+	fileNode := ffn.managedFileNode()
+	if truncate {
+		ffn.stagingDirty = fileNode.Size() > 0
+	} else if fileNode.Size() > 0 {
+		stream, err := ffn.staticFilesystem.renter.StreamerByNode(fileNode, false)
+		if err != nil {
+			return errors.AddContext(err, "unable to open stream to populate staging file")
+		}
+		_, err = io.Copy(f, stream)
+		err = errors.Compose(err, stream.Close())
+		if err != nil {
+			return errors.AddContext(err, "unable to populate staging file")
+		}
+	}
+	ffn.attrMu.Lock()
+	ffn.staging = f
+	ffn.attrMu.Unlock()
+	return nil
+}
+
+// uploadStaging uploads the staging file, replacing the siafile of the fuse
+// file. The erasure code and cipher type of the replaced siafile are kept.
+func (ffn *fuseFilenode) uploadStaging() error {
+	r := ffn.staticFilesystem.renter
+	oldNode := ffn.managedFileNode()
+	siaPath := r.staticFileSystem.FileSiaPath(oldNode)
+
+	_, err := ffn.staging.Seek(0, io.SeekStart)
+	if err != nil {
+		return errors.AddContext(err, "unable to seek to the start of the staging file")
+	}
+	up := modules.FileUploadParams{
+		SiaPath:     siaPath,
+		ErasureCode: oldNode.ErasureCode(),
+		Force:       true,
+		CipherType:  oldNode.MasterKey().Type(),
+		Compression: oldNode.Compression().Type,
+	}
+	err = r.UploadStreamFromReader(up, ffn.staging)
+	if err != nil {
+		return errors.AddContext(err, "unable to upload staging file")
+	}
+
+	// Swap the siafile node for the new one.
+	newNode, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to open uploaded file")
+	}
+	err = newNode.SetMode(oldNode.Mode())
+	if err != nil {
+		r.log.Printf("Unable to restore mode of fuse file %v: %v", siaPath, err)
+	}
+	ffn.attrMu.Lock()
+	ffn.fileNode = newNode
+	ffn.attrMu.Unlock()
+	ffn.stagingDirty = false
+
+	// The old node is closed here unless it has been closed by a previous
+	// flush already. The new node is closed by a future flush.
+	var closeErr error
+	if atomic.SwapUint32(&ffn.atomicClosed, 0) == 0 {
+		closeErr = oldNode.Close()
+	}
+	if ffn.stream != nil {
+		closeErr = errors.Compose(closeErr, ffn.stream.Close())
+		ffn.stream = nil
+	}
+	return closeErr
+}
+
 // Readdir will return a dirstream that can be used to look at all of the files
 // in the directory.
 func (fdn *fuseDirnode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
@@ -402,7 +945,7 @@ func (fdn *fuseDirnode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall
 func (ffn *fuseFilenode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
 	err := ffn.staticFilesystem.setStatfsOut(out)
 	if err != nil {
-		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.staticFileNode)
+		siaPath := ffn.staticFilesystem.renter.staticFileSystem.FileSiaPath(ffn.managedFileNode())
 		ffn.staticFilesystem.renter.log.Printf("Error fetching statfs for fuse file %v: %v", siaPath, err)
 		return errToStatus(err)
 	}
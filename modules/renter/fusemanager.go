@@ -5,6 +5,8 @@ package renter
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/hanwen/go-fuse/v2/fs"
@@ -13,6 +15,12 @@ import (
 	"go.sia.tech/siad/modules"
 )
 
+const (
+	// fuseStagingDir is the name of the directory within the renter's persist
+	// dir that holds the staging files of files being written through fuse.
+	fuseStagingDir = "fusestaging"
+)
+
 var errNothingMounted = errors.New("nothing mounted at that path")
 
 // A fuseManager manages mounted fuse filesystems.
@@ -31,6 +39,13 @@ func newFuseManager(r *Renter) renterFuseManager {
 		renter:      r,
 	}
 
+	// Staging files are only valid while a file is open, so any leftover
+	// staging files from a previous run can be removed.
+	stagingDir := filepath.Join(r.persistDir, fuseStagingDir)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		r.log.Printf("Unable to remove fuse staging dir %v: %v", stagingDir, err)
+	}
+
 	// Close the fuse manager on shutdown.
 	r.tg.OnStop(func() error {
 		return fm.managedCloseFuseManager()
@@ -99,9 +114,13 @@ func (fm *fuseManager) Mount(mountPoint string, sp modules.SiaPath, opts modules
 		}
 	}()
 
-	// Enforce read-only requirements for now.
+	// Make sure the staging dir for writes exists.
+	stagingDir := filepath.Join(fm.renter.persistDir, fuseStagingDir)
 	if !opts.ReadOnly {
-		return errors.New("fuse must be mounted as read-only")
+		err = os.MkdirAll(stagingDir, modules.DefaultDirPerm)
+		if err != nil {
+			return errors.AddContext(err, "unable to create the fuse staging dir")
+		}
 	}
 
 	// Get the mountpoint's root from the filesystem.
@@ -111,7 +130,8 @@ func (fm *fuseManager) Mount(mountPoint string, sp modules.SiaPath, opts modules
 	}
 	// Create the fuse filesystem object.
 	filesystem := &fuseFS{
-		options: opts,
+		options:    opts,
+		stagingDir: stagingDir,
 
 		renter: fm.renter,
 	}
@@ -132,12 +152,17 @@ func (fm *fuseManager) Mount(mountPoint string, sp modules.SiaPath, opts modules
 		return errors.New("there is already a sia fuse system mounted at " + mountPoint)
 	}
 
-	// Mount the filesystem.
+	// Mount the filesystem. The kernel is told about read-only mounts as well
+	// so that writes are rejected before they reach the fuse nodes.
+	mountOpts := fuse.MountOptions{
+		AllowOther: opts.AllowOther,
+		// Debug: true,
+	}
+	if opts.ReadOnly {
+		mountOpts.Options = append(mountOpts.Options, "ro")
+	}
 	server, err := fs.Mount(mountPoint, filesystem.root, &fs.Options{
-		MountOptions: fuse.MountOptions{
-			AllowOther: opts.AllowOther,
-			// Debug: true,
-		},
+		MountOptions: mountOpts,
 	})
 	if err != nil {
 		return errors.AddContext(err, "error calling mount")
@@ -232,6 +232,7 @@ func (r *Renter) callCalculateDirectoryMetadata(siaPath modules.SiaPath) (siadir
 			metadata.AggregateRepairSize += dirMetadata.AggregateRepairSize
 			metadata.AggregateSize += dirMetadata.AggregateSize
 			metadata.AggregateStuckSize += dirMetadata.AggregateStuckSize
+			metadata.AggregateMinRepairThreshold = minRepairThreshold(metadata.AggregateMinRepairThreshold, dirMetadata.AggregateMinRepairThreshold)
 
 			// Add 1 to the AggregateNumSubDirs to account for this subdirectory.
 			metadata.AggregateNumSubDirs++
@@ -58,6 +58,9 @@ type (
 	persistence struct {
 		MaxDownloadSpeed int64
 		MaxUploadSpeed   int64
+		MaxFileVersions  uint64
+		PriorityWeights  modules.PriorityWeights
+		QuotasSet        bool
 		UploadedBackups  []modules.UploadedBackup
 		SyncedContracts  []types.FileContractID
 	}
@@ -108,6 +111,12 @@ func (r *Renter) managedLoadSettings() error {
 		return err
 	}
 
+	// Use the default priority weights if none were persisted yet.
+	if r.persist.PriorityWeights == (modules.PriorityWeights{}) {
+		r.persist.PriorityWeights = modules.DefaultPriorityWeights
+	}
+	r.managedSetPriorityWeights(r.persist.PriorityWeights)
+
 	// Set the bandwidth limits on the contractor, which was already initialized
 	// without bandwidth limits.
 	return r.setBandwidthLimits(r.persist.MaxDownloadSpeed, r.persist.MaxUploadSpeed)
@@ -179,6 +188,23 @@ func (r *Renter) managedInitPersist() error {
 	r.wal = wal
 	r.staticFileSystem = fs
 
+	// Start with the lowest repair threshold of the root's previous bubble.
+	rootMD, err := r.managedDirectoryMetadata(modules.RootSiaPath())
+	if err != nil {
+		return errors.AddContext(err, "failed to read the metadata of the root dir")
+	}
+	r.managedSetMinRepairThreshold(rootMD.AggregateMinRepairThreshold)
+
+	// Load the dedup index and make sure it is saved on shutdown.
+	r.staticDedupIndex, err = filesystem.NewDedupIndex(filepath.Join(r.persistDir, dedupIndexFile))
+	if err != nil {
+		return err
+	}
+	err = r.tg.AfterStop(r.staticDedupIndex.Save)
+	if err != nil {
+		return err
+	}
+
 	// Load the prior persistence structures.
 	if err := r.managedLoadSettings(); err != nil {
 		return errors.AddContext(err, "failed to load renter's persistence structrue")
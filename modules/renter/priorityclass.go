@@ -0,0 +1,193 @@
+package renter
+
+import (
+	"container/heap"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+)
+
+// priorityclass.go shares the upload and download capacity of the renter
+// between the priority classes. Both the upload heap and the download heap
+// keep a separate heap for every class and use a priorityClassScheduler to
+// decide which heap the next chunk is popped from.
+//
+// The scheduler uses stride scheduling. Every class has a pass value and the
+// class with the lowest pass is selected next. Once selected, the pass of the
+// class grows by the amount of memory the chunk needs divided by the weight
+// of the class. As long as multiple classes have chunks queued, every class
+// receives a share of the memory, and with it the workers, that is
+// proportional to its weight. Since the upload and download loops block until
+// the memory of a chunk is available, the order of the chunks is what
+// determines the share of the capacity.
+//
+// A class which has no chunks queued can't save up capacity for later. When
+// it receives new chunks, its pass is moved forward to the pass of the most
+// recently selected class.
+
+type (
+	// priorityClassScheduler decides which priority class receives the next
+	// share of capacity. It is not thread safe and needs to be protected by
+	// the lock of the heap it belongs to.
+	priorityClassScheduler struct {
+		weights modules.PriorityWeights
+		pass    [modules.NumPriorityClasses]float64
+		vtime   float64
+
+		// scheduledBytes is the total amount of memory charged to each
+		// class.
+		scheduledBytes [modules.NumPriorityClasses]uint64
+	}
+
+	// uploadPriorityHeap contains an uploadChunkHeap for every priority
+	// class.
+	uploadPriorityHeap struct {
+		heaps     [modules.NumPriorityClasses]uploadChunkHeap
+		scheduler priorityClassScheduler
+	}
+
+	// downloadPriorityHeap contains a downloadChunkHeap for every priority
+	// class.
+	downloadPriorityHeap struct {
+		heaps     [modules.NumPriorityClasses]downloadChunkHeap
+		scheduler priorityClassScheduler
+	}
+)
+
+// charge charges the class for the amount of memory it was scheduled. Work
+// which bypasses the heaps, like streamed uploads, is charged as well since it
+// uses the same workers.
+func (pcs *priorityClassScheduler) charge(pc modules.PriorityClass, amount uint64) {
+	weight := pcs.weights.Weight(pc)
+	if weight == 0 {
+		weight = 1
+	}
+	if pcs.pass[pc] < pcs.vtime {
+		pcs.pass[pc] = pcs.vtime
+	}
+	pcs.pass[pc] += float64(amount) / float64(weight)
+	pcs.scheduledBytes[pc] += amount
+}
+
+// selectClass returns the class which should receive the next share of
+// capacity out of the classes which have queued work. 'false' is returned if
+// no class has queued work.
+func (pcs *priorityClassScheduler) selectClass(hasWork func(modules.PriorityClass) bool) (modules.PriorityClass, bool) {
+	var selected modules.PriorityClass
+	found := false
+	// Classes are checked from the most to the least urgent one to resolve
+	// ties in favor of the more urgent class.
+	for _, pc := range modules.PriorityClasses() {
+		if !hasWork(pc) {
+			continue
+		}
+		if pcs.pass[pc] < pcs.vtime {
+			pcs.pass[pc] = pcs.vtime
+		}
+		if !found || pcs.pass[pc] < pcs.pass[selected] {
+			selected = pc
+			found = true
+		}
+	}
+	if found {
+		pcs.vtime = pcs.pass[selected]
+	}
+	return selected, found
+}
+
+// Len returns the number of chunks in all the heaps.
+func (uph *uploadPriorityHeap) Len() int {
+	var n int
+	for _, h := range uph.heaps {
+		n += h.Len()
+	}
+	return n
+}
+
+// pop removes the next chunk from the heaps. 'nil' is returned if all of the
+// heaps are empty.
+func (uph *uploadPriorityHeap) pop() *unfinishedUploadChunk {
+	pc, ok := uph.scheduler.selectClass(func(pc modules.PriorityClass) bool {
+		return uph.heaps[pc].Len() > 0
+	})
+	if !ok {
+		return nil
+	}
+	uuc := heap.Pop(&uph.heaps[pc]).(*unfinishedUploadChunk)
+	uph.scheduler.charge(pc, uuc.staticMemoryNeeded)
+	return uuc
+}
+
+// push adds a chunk to the heap of its priority class.
+func (uph *uploadPriorityHeap) push(uuc *unfinishedUploadChunk) {
+	heap.Push(&uph.heaps[uuc.staticPriorityClass], uuc)
+}
+
+// removeByID removes the chunk with the corresponding uploadChunkID from the
+// heap of its priority class.
+func (uph *uploadPriorityHeap) removeByID(uuc *unfinishedUploadChunk) {
+	h := &uph.heaps[uuc.staticPriorityClass]
+	h.removeByID(uuc)
+	heap.Init(h)
+}
+
+// reset resets all of the heaps.
+func (uph *uploadPriorityHeap) reset() (err error) {
+	for i := range uph.heaps {
+		err = errors.Compose(err, uph.heaps[i].reset())
+	}
+	return err
+}
+
+// Len returns the number of chunks in all the heaps.
+func (dph *downloadPriorityHeap) Len() int {
+	var n int
+	for _, h := range dph.heaps {
+		n += h.Len()
+	}
+	return n
+}
+
+// pop removes the next chunk from the heaps. 'nil' is returned if all of the
+// heaps are empty.
+func (dph *downloadPriorityHeap) pop() *unfinishedDownloadChunk {
+	pc, ok := dph.scheduler.selectClass(func(pc modules.PriorityClass) bool {
+		return dph.heaps[pc].Len() > 0
+	})
+	if !ok {
+		return nil
+	}
+	udc := heap.Pop(&dph.heaps[pc]).(*unfinishedDownloadChunk)
+	dph.scheduler.charge(pc, udc.staticMemoryRequired())
+	return udc
+}
+
+// push adds a chunk to the heap of its priority class.
+func (dph *downloadPriorityHeap) push(udc *unfinishedDownloadChunk) {
+	heap.Push(&dph.heaps[udc.staticPriorityClass], udc)
+}
+
+// managedSetPriorityWeights updates the weights used to share the upload,
+// repair and download capacity between the priority classes.
+func (r *Renter) managedSetPriorityWeights(weights modules.PriorityWeights) {
+	r.uploadHeap.mu.Lock()
+	r.uploadHeap.heap.scheduler.weights = weights
+	r.uploadHeap.mu.Unlock()
+
+	r.downloadHeapMu.Lock()
+	r.downloadHeap.scheduler.weights = weights
+	r.downloadHeapMu.Unlock()
+
+	r.staticUploadChunkDistributionQueue.callSetPriorityWeights(weights)
+}
+
+// UploadPriorityClasses returns the status of the renter's uploads and
+// repairs for every priority class.
+func (r *Renter) UploadPriorityClasses() ([]modules.PriorityClassStatus, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+	return r.uploadHeap.managedPriorityClasses(), nil
+}
@@ -0,0 +1,124 @@
+package renter
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/modules"
+)
+
+// TestPriorityClassScheduler tests that the scheduler shares the capacity
+// between the priority classes according to their weights.
+func TestPriorityClassScheduler(t *testing.T) {
+	t.Parallel()
+
+	pcs := priorityClassScheduler{
+		weights: modules.PriorityWeights{Background: 1, User: 2, Interactive: 8},
+	}
+	hasWork := map[modules.PriorityClass]bool{}
+	selectClasses := func(n int, amount uint64) map[modules.PriorityClass]int {
+		selected := make(map[modules.PriorityClass]int)
+		for i := 0; i < n; i++ {
+			pc, ok := pcs.selectClass(func(pc modules.PriorityClass) bool { return hasWork[pc] })
+			if !ok {
+				t.Fatal("no class selected")
+			}
+			pcs.charge(pc, amount)
+			selected[pc]++
+		}
+		return selected
+	}
+
+	// Without work no class is selected.
+	if _, ok := pcs.selectClass(func(modules.PriorityClass) bool { return false }); ok {
+		t.Fatal("class selected without work")
+	}
+
+	// With work in every class, every class should be selected according to
+	// its weight.
+	for _, pc := range modules.PriorityClasses() {
+		hasWork[pc] = true
+	}
+	selected := selectClasses(1100, 10)
+	if selected[modules.PriorityClassInteractive] != 800 || selected[modules.PriorityClassUser] != 200 || selected[modules.PriorityClassBackground] != 100 {
+		t.Fatal("wrong shares", selected)
+	}
+
+	// Only the background class has work for a while. Once the interactive
+	// class has work again, it shouldn't be able to use the capacity it didn't
+	// use in the meantime.
+	hasWork[modules.PriorityClassInteractive] = false
+	hasWork[modules.PriorityClassUser] = false
+	selected = selectClasses(100, 10)
+	if selected[modules.PriorityClassBackground] != 100 {
+		t.Fatal("background class should get all the capacity", selected)
+	}
+	hasWork[modules.PriorityClassInteractive] = true
+	selected = selectClasses(90, 10)
+	if n := selected[modules.PriorityClassInteractive]; n < 79 || n > 81 {
+		t.Fatal("wrong shares after idling", selected)
+	}
+
+	// Work which bypasses the heaps, like streams, counts towards the share
+	// of its class.
+	scheduled := pcs.scheduledBytes[modules.PriorityClassInteractive]
+	for i := 0; i < 8; i++ {
+		pcs.charge(modules.PriorityClassInteractive, 10)
+	}
+	pc, _ := pcs.selectClass(func(pc modules.PriorityClass) bool { return hasWork[pc] })
+	if pc != modules.PriorityClassBackground {
+		t.Fatal("expected background class after charging the interactive class", pc)
+	}
+	if pcs.scheduledBytes[modules.PriorityClassInteractive] != scheduled+80 {
+		t.Fatal("wrong number of scheduled bytes", pcs.scheduledBytes)
+	}
+}
+
+// TestUploadPriorityHeap tests pushing, popping and removing chunks from an
+// uploadPriorityHeap.
+func TestUploadPriorityHeap(t *testing.T) {
+	t.Parallel()
+
+	var uph uploadPriorityHeap
+	uph.scheduler.weights = modules.PriorityWeights{Background: 1, User: 1, Interactive: 1}
+	chunk := func(index uint64, class modules.PriorityClass, health float64) *unfinishedUploadChunk {
+		return &unfinishedUploadChunk{
+			id:                  uploadChunkID{index: index},
+			health:              health,
+			staticMemoryNeeded:  10,
+			staticPriorityClass: class,
+		}
+	}
+	background := []*unfinishedUploadChunk{
+		chunk(0, modules.PriorityClassBackground, 1.5),
+		chunk(1, modules.PriorityClassBackground, 2),
+		chunk(2, modules.PriorityClassBackground, 1),
+	}
+	interactive := []*unfinishedUploadChunk{
+		chunk(3, modules.PriorityClassInteractive, 0),
+		chunk(4, modules.PriorityClassInteractive, 0.5),
+	}
+	for _, uuc := range append(background, interactive...) {
+		uph.push(uuc)
+	}
+	if uph.Len() != 5 {
+		t.Fatal("wrong length", uph.Len())
+	}
+
+	// Remove a background chunk.
+	uph.removeByID(background[0])
+	if uph.Len() != 4 || uph.heaps[modules.PriorityClassBackground].Len() != 2 {
+		t.Fatal("chunk wasn't removed")
+	}
+
+	// With equal weights, the classes should take turns starting with the
+	// more urgent class. Within a class the chunks are sorted by health.
+	expected := []*unfinishedUploadChunk{interactive[1], background[1], interactive[0], background[2]}
+	for i, uuc := range expected {
+		if popped := uph.pop(); popped != uuc {
+			t.Fatalf("%v: wrong chunk popped %v", i, popped.id)
+		}
+	}
+	if uph.pop() != nil {
+		t.Fatal("heap should be empty")
+	}
+}
@@ -315,7 +315,7 @@ func (pdc *projectDownloadChunk) fail(err error) {
 func (pdc *projectDownloadChunk) finalize() {
 	// Determine the amount of bytes the EC will need to skip from the recovered
 	// data when returning the data.
-	skipLength := pdc.offsetInChunk % (crypto.SegmentSize * uint64(pdc.workerSet.staticErasureCoder.MinPieces()))
+	skipLength := pdc.offsetInChunk % (crypto.SegmentSize * uint64(pdc.workerSet.staticErasureCoder.DataPieces()))
 
 	// Create a skipwriter that ensures we're recovering at the offset
 	buf := bytes.NewBuffer(nil)
@@ -527,20 +527,20 @@ func getPieceOffsetAndLen(ec modules.ErasureCoder, offset, length uint64) (piece
 	// This is mathematically equivalent to rounding down the chunk size to
 	// the nearest chunk segment size and then dividing by the number of
 	// pieces.
-	pieceOffset = offset / uint64(ec.MinPieces())
+	pieceOffset = offset / uint64(ec.DataPieces())
 	pieceOffset = pieceOffset / pieceSegmentSize
 	pieceOffset = pieceOffset * pieceSegmentSize
 
 	// Determine the length that needs to be downloaded. This is done by
 	// determining the offset that the download needs to reach, and then
 	// subtracting the pieceOffset from the termination offset.
-	chunkSegmentSize := pieceSegmentSize * uint64(ec.MinPieces())
+	chunkSegmentSize := pieceSegmentSize * uint64(ec.DataPieces())
 	chunkTerminationOffset := offset + length
 	overflow := chunkTerminationOffset % chunkSegmentSize
 	if overflow != 0 {
 		chunkTerminationOffset += chunkSegmentSize - overflow
 	}
-	pieceTerminationOffset := chunkTerminationOffset / uint64(ec.MinPieces())
+	pieceTerminationOffset := chunkTerminationOffset / uint64(ec.DataPieces())
 	pieceLength = pieceTerminationOffset - pieceOffset
 	return pieceOffset, pieceLength
 }
@@ -0,0 +1,210 @@
+package renter
+
+// registrysubscriptions.go allows for subscribing to registry entries on the
+// renter level. The renter subscribes to the entries on all of its workers and
+// forwards the updates the workers receive from their hosts to the
+// subscribers. Since multiple hosts notify the renter about the same update,
+// only updates with a higher revision than the last forwarded one are passed
+// on.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+var (
+	// registrySubscriptionTimeout is the amount of time a worker has to
+	// establish a subscription before it is skipped.
+	registrySubscriptionTimeout = build.Select(build.Var{
+		Dev:      30 * time.Second,
+		Standard: time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+)
+
+type (
+	// registrySubscriptions keeps track of the subscribers to registry
+	// entries.
+	registrySubscriptions struct {
+		entries map[modules.RegistryEntryID]*registrySubscriptionEntry
+		nextID  uint64
+		mu      sync.Mutex
+	}
+
+	// registrySubscriptionEntry contains the subscribers of a single registry
+	// entry and the latest value that was forwarded to them.
+	registrySubscriptionEntry struct {
+		latest      *modules.SignedRegistryValue
+		subscribers map[uint64]chan modules.SignedRegistryValue
+	}
+)
+
+// newRegistrySubscriptions creates a new registrySubscriptions object.
+func newRegistrySubscriptions() *registrySubscriptions {
+	return &registrySubscriptions{
+		entries: make(map[modules.RegistryEntryID]*registrySubscriptionEntry),
+	}
+}
+
+// callNotify forwards an update of a registry entry to its subscribers if the
+// revision is higher than the revision of the latest known value. Every
+// subscriber's channel only buffers the latest value, so slow subscribers
+// skip intermediate revisions instead of blocking the workers.
+func (rs *registrySubscriptions) callNotify(spk types.SiaPublicKey, srv modules.SignedRegistryValue) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	entry, exists := rs.entries[modules.DeriveRegistryEntryID(spk, srv.Tweak)]
+	if !exists {
+		return
+	}
+	if entry.latest != nil && entry.latest.Revision >= srv.Revision {
+		return
+	}
+	entry.latest = &srv
+	for _, c := range entry.subscribers {
+		sendLatestRegistryValue(c, srv)
+	}
+}
+
+// callSubscribe adds a new subscriber for the entry with the given public key
+// and tweak. It returns the subscriber's id and channel.
+func (rs *registrySubscriptions) callSubscribe(spk types.SiaPublicKey, tweak crypto.Hash) (uint64, chan modules.SignedRegistryValue) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	sid := modules.DeriveRegistryEntryID(spk, tweak)
+	entry, exists := rs.entries[sid]
+	if !exists {
+		entry = &registrySubscriptionEntry{
+			subscribers: make(map[uint64]chan modules.SignedRegistryValue),
+		}
+		rs.entries[sid] = entry
+	}
+	id := rs.nextID
+	rs.nextID++
+	c := make(chan modules.SignedRegistryValue, 1)
+	entry.subscribers[id] = c
+
+	// Send the latest known value right away.
+	if entry.latest != nil {
+		c <- *entry.latest
+	}
+	return id, c
+}
+
+// callUnsubscribe removes the subscriber with the given id and closes its
+// channel. If the entry doesn't have any subscribers left, it is removed,
+// unsubscribeWorkers is called and true is returned. unsubscribeWorkers is
+// called while holding the lock to make sure that it doesn't race with a new
+// subscriber of the same entry.
+func (rs *registrySubscriptions) callUnsubscribe(spk types.SiaPublicKey, tweak crypto.Hash, id uint64, unsubscribeWorkers func()) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	sid := modules.DeriveRegistryEntryID(spk, tweak)
+	entry, exists := rs.entries[sid]
+	if !exists {
+		return false
+	}
+	c, exists := entry.subscribers[id]
+	if !exists {
+		return false
+	}
+	delete(entry.subscribers, id)
+	close(c)
+	if len(entry.subscribers) > 0 {
+		return false
+	}
+	delete(rs.entries, sid)
+	if unsubscribeWorkers != nil {
+		unsubscribeWorkers()
+	}
+	return true
+}
+
+// sendLatestRegistryValue sends a value on a channel with a buffer of 1. If
+// the buffer is full, the buffered value is replaced. The caller needs to be
+// the only sender on the channel.
+func sendLatestRegistryValue(c chan modules.SignedRegistryValue, srv modules.SignedRegistryValue) {
+	select {
+	case c <- srv:
+		return
+	default:
+	}
+	select {
+	case <-c:
+	default:
+	}
+	c <- srv
+}
+
+// SubscribeRegistry subscribes to updates of the registry entry with the given
+// public key and tweak on all workers. Every update with a higher revision
+// than the previous one is sent on the returned channel. The returned function
+// unsubscribes from the entry and closes the channel. It needs to be called
+// once the caller is done.
+func (r *Renter) SubscribeRegistry(spk types.SiaPublicKey, tweak crypto.Hash) (<-chan modules.SignedRegistryValue, func(), error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, nil, err
+	}
+	defer r.tg.Done()
+
+	rs := r.staticRegistrySubscriptions
+	id, c := rs.callSubscribe(spk, tweak)
+	req := modules.RPCRegistrySubscriptionRequest{
+		PubKey: spk,
+		Tweak:  tweak,
+	}
+
+	// unsubscribe cancels the subscriptions which are still in progress and
+	// waits for them to return before unsubscribing. Otherwise a subscription
+	// which completes after the workers were unsubscribed would stay active.
+	subscribeCtx, cancelSubscribe := context.WithCancel(r.tg.StopCtx())
+	var wg sync.WaitGroup
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			cancelSubscribe()
+			wg.Wait()
+			// If the last subscriber is gone, unsubscribe on the workers.
+			rs.callUnsubscribe(spk, tweak, id, func() {
+				for _, w := range r.staticWorkerPool.callWorkers() {
+					w.Unsubscribe(req)
+				}
+			})
+		})
+	}
+
+	// Subscribe on all workers in the background. Subscribing on a worker
+	// that is already subscribed is a no-op which returns the initial value.
+	for _, w := range r.staticWorkerPool.callWorkers() {
+		w := w
+		wg.Add(1)
+		err := r.tg.Launch(func() {
+			defer wg.Done()
+			if subscribeCtx.Err() != nil {
+				return // already unsubscribed
+			}
+			ctx, cancel := context.WithTimeout(subscribeCtx, registrySubscriptionTimeout)
+			defer cancel()
+			notifications, err := w.Subscribe(ctx, req)
+			if err != nil {
+				r.log.Debugf("Worker %v failed to subscribe to registry entry: %v", w.staticHostPubKeyStr, err)
+				return
+			}
+			for _, n := range notifications {
+				rs.callNotify(n.PubKey, n.Entry)
+			}
+		})
+		if err != nil {
+			wg.Done()
+			unsubscribe()
+			return nil, nil, err
+		}
+	}
+	return c, unsubscribe, nil
+}
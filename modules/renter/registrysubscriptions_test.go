@@ -0,0 +1,145 @@
+package renter
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/fastrand"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+// TestRegistrySubscriptions is a unit test for the registrySubscriptions
+// object.
+func TestRegistrySubscriptions(t *testing.T) {
+	t.Parallel()
+
+	rs := newRegistrySubscriptions()
+	sk, pk := crypto.GenerateKeyPair()
+	spk := types.Ed25519PublicKey(pk)
+	var tweak crypto.Hash
+	fastrand.Read(tweak[:])
+
+	// signedValue creates a signed value for the tweak with the given
+	// revision.
+	signedValue := func(revision uint64) modules.SignedRegistryValue {
+		return modules.NewRegistryValue(tweak, fastrand.Bytes(10), revision, modules.RegistryTypeWithoutPubkey).Sign(sk)
+	}
+
+	// Notifying without subscribers is a no-op.
+	rs.callNotify(spk, signedValue(1))
+	if len(rs.entries) != 0 {
+		t.Fatal("entry shouldn't exist")
+	}
+
+	// Subscribe twice.
+	id1, c1 := rs.callSubscribe(spk, tweak)
+	id2, c2 := rs.callSubscribe(spk, tweak)
+	if id1 == id2 {
+		t.Fatal("ids should be unique")
+	}
+
+	// Notify both subscribers.
+	srv := signedValue(2)
+	rs.callNotify(spk, srv)
+	for _, c := range []chan modules.SignedRegistryValue{c1, c2} {
+		select {
+		case v := <-c:
+			if v.Revision != srv.Revision {
+				t.Fatal("wrong revision", v.Revision)
+			}
+		default:
+			t.Fatal("no value received")
+		}
+	}
+
+	// Outdated revisions are ignored.
+	rs.callNotify(spk, signedValue(2))
+	rs.callNotify(spk, signedValue(1))
+	if len(c1) != 0 || len(c2) != 0 {
+		t.Fatal("outdated revision shouldn't be forwarded")
+	}
+
+	// A slow subscriber only receives the latest value.
+	rs.callNotify(spk, signedValue(3))
+	rs.callNotify(spk, signedValue(4))
+	if v := <-c1; v.Revision != 4 {
+		t.Fatal("expected latest revision", v.Revision)
+	}
+	<-c2
+
+	// A new subscriber receives the latest value right away.
+	id3, c3 := rs.callSubscribe(spk, tweak)
+	if v := <-c3; v.Revision != 4 {
+		t.Fatal("expected latest revision", v.Revision)
+	}
+
+	// Unsubscribe all of them. Only the last one removes the entry and
+	// unsubscribes the workers.
+	var workerUnsubscribes int
+	unsubscribeWorkers := func() { workerUnsubscribes++ }
+	if rs.callUnsubscribe(spk, tweak, id1, unsubscribeWorkers) || rs.callUnsubscribe(spk, tweak, id2, unsubscribeWorkers) {
+		t.Fatal("entry shouldn't be removed yet")
+	}
+	if rs.callUnsubscribe(spk, tweak, id2, unsubscribeWorkers) {
+		t.Fatal("unsubscribing twice should be a no-op")
+	}
+	if !rs.callUnsubscribe(spk, tweak, id3, unsubscribeWorkers) {
+		t.Fatal("entry should be removed")
+	}
+	if workerUnsubscribes != 1 {
+		t.Fatal("workers should be unsubscribed once but were unsubscribed", workerUnsubscribes)
+	}
+	if len(rs.entries) != 0 {
+		t.Fatal("entry should be removed")
+	}
+	// The channels should be closed.
+	for _, c := range []chan modules.SignedRegistryValue{c1, c2, c3} {
+		if _, ok := <-c; ok {
+			t.Fatal("channel should be closed")
+		}
+	}
+}
+
+// TestSubscribeRegistryUnsubscribeInProgress checks that unsubscribing right
+// after subscribing leaves no subscription on the workers, even if the
+// workers' subscriptions were still in progress.
+func TestSubscribeRegistryUnsubscribeInProgress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	wt, err := newWorkerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	var sids []modules.RegistryEntryID
+	for i := 0; i < 100; i++ {
+		srv, spk, _ := randomRegistryValue()
+		_, unsubscribe, err := wt.rt.renter.SubscribeRegistry(spk, srv.Tweak)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unsubscribe()
+		sids = append(sids, modules.DeriveRegistryEntryID(spk, srv.Tweak))
+	}
+
+	// Give subscriptions which weren't cancelled time to complete.
+	time.Sleep(time.Second)
+	subInfo := wt.worker.staticSubscriptionInfo
+	subInfo.mu.Lock()
+	defer subInfo.mu.Unlock()
+	for _, sid := range sids {
+		if sub, exists := subInfo.subscriptions[sid]; exists && sub.subscribe {
+			t.Fatal("worker is still subscribed after unsubscribing")
+		}
+	}
+}
@@ -186,9 +186,9 @@ type cachedUtilities struct {
 type Renter struct {
 	// Download management. The heap has a separate mutex because it is always
 	// accessed in isolation.
-	downloadHeapMu sync.Mutex         // Used to protect the downloadHeap.
-	downloadHeap   *downloadChunkHeap // A heap of priority-sorted chunks to download.
-	newDownloads   chan struct{}      // Used to notify download loop that new downloads are available.
+	downloadHeapMu sync.Mutex            // Used to protect the downloadHeap.
+	downloadHeap   *downloadPriorityHeap // A heap of priority-sorted chunks to download for every priority class.
+	newDownloads   chan struct{}         // Used to notify download loop that new downloads are available.
 
 	// Download history. The history list has its own mutex because it is always
 	// accessed in isolation.
@@ -222,9 +222,32 @@ type Renter struct {
 	statsChan chan struct{}
 	statsMu   sync.Mutex
 
+	// versionsMu serializes operations which change the prior versions of
+	// files.
+	versionsMu sync.Mutex
+
+	// minRepairThreshold is the lowest repair threshold of any directory
+	// policy as of the last bubble of the root directory. It is used by the
+	// repair loop to decide when the filesystem is healthy enough to stop
+	// looking for chunks to repair.
+	minRepairThreshold   float64
+	minRepairThresholdMu sync.Mutex
+
+	// quotaMu serializes checking and charging the quotas of directories.
+	quotaMu sync.Mutex
+
 	// read registry stats
 	staticRRS *readRegistryStats
 
+	// registry subscriptions
+	staticRegistrySubscriptions *registrySubscriptions
+
+	// staticDownloadPersister persists the progress of resumable downloads.
+	staticDownloadPersister *downloadPersister
+
+	// staticScrubber keeps track of the status of the scrub.
+	staticScrubber *scrubber
+
 	// Memory management
 	//
 	// registryMemoryManager is used for updating registry entries and reading
@@ -255,6 +278,7 @@ type Renter struct {
 	repairLog                          *persist.Logger
 	staticAccountManager               *accountManager
 	staticAlerter                      *modules.GenericAlerter
+	staticDedupIndex                   *filesystem.DedupIndex
 	staticFileSystem                   *filesystem.FileSystem
 	staticFuseManager                  renterFuseManager
 	staticStreamBufferSet              *streamBufferSet
@@ -604,6 +628,13 @@ func (r *Renter) SetSettings(s modules.RenterSettings) error {
 	if s.MaxDownloadSpeed < 0 || s.MaxUploadSpeed < 0 {
 		return errors.New("bandwidth limits cannot be negative")
 	}
+	// Unset priority weights are replaced by the defaults.
+	if s.PriorityWeights == (modules.PriorityWeights{}) {
+		s.PriorityWeights = modules.DefaultPriorityWeights
+	}
+	if err := s.PriorityWeights.Validate(); err != nil {
+		return err
+	}
 
 	// Set allowance.
 	err := r.hostContractor.SetAllowance(s.Allowance)
@@ -624,11 +655,14 @@ func (r *Renter) SetSettings(s modules.RenterSettings) error {
 	id := r.mu.Lock()
 	r.persist.MaxDownloadSpeed = s.MaxDownloadSpeed
 	r.persist.MaxUploadSpeed = s.MaxUploadSpeed
+	r.persist.MaxFileVersions = s.MaxFileVersions
+	r.persist.PriorityWeights = s.PriorityWeights
 	err = r.saveSync()
 	r.mu.Unlock(id)
 	if err != nil {
 		return err
 	}
+	r.managedSetPriorityWeights(s.PriorityWeights)
 
 	// Update the worker pool so that the changes are immediately apparent to
 	// users.
@@ -817,11 +851,17 @@ func (r *Renter) Settings() (modules.RenterSettings, error) {
 		return modules.RenterSettings{}, errors.AddContext(err, "error getting IPViolationsCheck:")
 	}
 	paused, endTime := r.uploadHeap.managedPauseStatus()
+	id := r.mu.RLock()
+	maxFileVersions := r.persist.MaxFileVersions
+	priorityWeights := r.persist.PriorityWeights
+	r.mu.RUnlock(id)
 	return modules.RenterSettings{
 		Allowance:        r.hostContractor.Allowance(),
 		IPViolationCheck: enabled,
 		MaxDownloadSpeed: download,
+		MaxFileVersions:  maxFileVersions,
 		MaxUploadSpeed:   upload,
+		PriorityWeights:  priorityWeights,
 		UploadsStatus: modules.UploadsStatus{
 			Paused:       paused,
 			PauseEndTime: endTime,
@@ -892,7 +932,7 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 		// preferable to the alternative, where in rare cases the download heap
 		// will miss work altogether.
 		newDownloads: make(chan struct{}, 1),
-		downloadHeap: new(downloadChunkHeap),
+		downloadHeap: new(downloadPriorityHeap),
 
 		uploadHeap: uploadHeap{
 			repairingChunks:   make(map[uploadChunkID]*unfinishedUploadChunk),
@@ -929,6 +969,8 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 	r.staticStreamBufferSet = newStreamBufferSet(&r.tg)
 	r.staticUploadChunkDistributionQueue = newUploadChunkDistributionQueue(r)
 	r.staticRRS = newReadRegistryStats(ReadRegistryBackgroundTimeout, readRegistryStatsInterval, readRegistryStatsDecay, readRegistryStatsPercentile)
+	r.staticRegistrySubscriptions = newRegistrySubscriptions()
+	r.staticScrubber = &scrubber{}
 	close(r.uploadHeap.pauseChan)
 
 	// Seed the rrs.
@@ -978,6 +1020,10 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 	if err != nil {
 		return nil, err
 	}
+	r.staticDownloadPersister, err = newDownloadPersister(r.persistDir)
+	if err != nil {
+		return nil, err
+	}
 
 	// After persist is initialized, create the worker pool.
 	r.staticWorkerPool = r.newWorkerPool()
@@ -1035,6 +1081,7 @@ func renterAsyncStartup(r *Renter, cs modules.ConsensusSet) error {
 	// consensus set.
 	// Spin up the workers for the work pool.
 	go r.threadedDownloadLoop()
+	go r.threadedResumeDownloads()
 	if !r.deps.Disrupt("DisableRepairAndHealthLoops") {
 		go r.threadedUploadAndRepair()
 		go r.threadedStuckFileLoop()
@@ -130,7 +130,7 @@ func (r *Renter) managedAddStuckChunksToHeap(siaPath modules.SiaPath, hosts map[
 
 	// Build unfinished stuck chunks
 	var allErrors error
-	unfinishedStuckChunks := r.managedBuildUnfinishedChunks(sf, hosts, targetStuckChunks, offline, goodForRenew, r.repairMemoryManager)
+	unfinishedStuckChunks := r.managedBuildUnfinishedChunks(sf, hosts, targetStuckChunks, modules.PriorityClassBackground, offline, goodForRenew, r.repairMemoryManager)
 	defer func() {
 		// Close out remaining file entries
 		for _, chunk := range unfinishedStuckChunks {
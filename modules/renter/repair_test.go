@@ -70,6 +70,10 @@ func equalBubbledAggregateMetadata(md1, md2 siadir.Metadata, delta time.Duration
 	if md1.AggregateMinRedundancy != md2.AggregateMinRedundancy {
 		return fmt.Errorf("AggregateMinRedundancy not equal, %v and %v", md1.AggregateMinRedundancy, md2.AggregateMinRedundancy)
 	}
+	// Check AggregateMinRepairThreshold
+	if md1.AggregateMinRepairThreshold != md2.AggregateMinRepairThreshold {
+		return fmt.Errorf("AggregateMinRepairThreshold not equal, %v and %v", md1.AggregateMinRepairThreshold, md2.AggregateMinRepairThreshold)
+	}
 	// Check AggregateModTime
 	if !timeEquals(md2.AggregateModTime, md1.AggregateModTime, delta) {
 		return fmt.Errorf("AggregateModTime not equal %v and %v (%v)", md1.AggregateModTime, md2.AggregateModTime, delta)
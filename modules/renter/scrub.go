@@ -0,0 +1,606 @@
+package renter
+
+// scrub.go contains the logic for verifying the integrity of uploaded files.
+// A scrub walks the files within a directory and checks every piece of every
+// file on the hosts which store it. The hosts are first asked whether they
+// still have the pieces using a HasSector program. Then a random segment of
+// every available piece is read together with a range proof which is verified
+// against the Merkle root stored in the siafile. Hosts which support the
+// ProveSegment instruction are instead challenged to prove a segment chosen by
+// a random seed for a whole batch of pieces within a single program.
+//
+// Pieces which are missing on their host or which fail verification are
+// removed from the siafile and the affected chunks are added to the repair
+// heap. Pieces which can't be checked, e.g. because the host is offline, are
+// counted as unverified and left alone since the regular health checks take
+// care of them. The result of every scrubbed file is stored in the metadata of
+// its directory. Results of files which were deleted, renamed or replaced are
+// removed when their directory is scrubbed or its results are requested.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+	"go.sia.tech/siad/modules/renter/filesystem/siadir"
+	"go.sia.tech/siad/types"
+)
+
+const (
+	// scrubHasSectorBatchSize is the maximum number of sector roots which are
+	// checked with a single HasSector job.
+	scrubHasSectorBatchSize = 100
+
+	// scrubProveSegmentBatchSize is the maximum number of pieces which are
+	// proven with a single ProveSegment job.
+	scrubProveSegmentBatchSize = 20
+)
+
+var (
+	// scrubJobTimeout is the amount of time a single job of a scrub has to
+	// complete before the piece is considered unverified.
+	scrubJobTimeout = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: 5 * time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
+	// errScrubCancelled is the error of a scrub which was cancelled before it
+	// finished.
+	errScrubCancelled = errors.New("scrub was cancelled")
+
+	// errScrubInProgress is returned if a scrub is started while another one
+	// is running.
+	errScrubInProgress = errors.New("a scrub is already in progress")
+
+	// errNoScrubInProgress is returned if there is no scrub to cancel.
+	errNoScrubInProgress = errors.New("no scrub in progress")
+)
+
+// The possible outcomes of checking a piece.
+const (
+	pieceUnverified scrubPieceStatus = iota
+	pieceVerified
+	pieceFailed
+)
+
+type (
+	// scrubber keeps track of the status of the renter's scrub.
+	scrubber struct {
+		cancel context.CancelFunc
+		status modules.ScrubStatus
+		mu     sync.Mutex
+	}
+
+	// scrubPiece is a piece of a file which is checked during a scrub.
+	scrubPiece struct {
+		chunkIndex uint64
+		pieceIndex uint64
+		root       crypto.Hash
+		status     scrubPieceStatus
+	}
+
+	// scrubPieceStatus is the outcome of checking a piece.
+	scrubPieceStatus int
+)
+
+// callCancel cancels the running scrub.
+func (s *scrubber) callCancel() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.status.Running {
+		return errNoScrubInProgress
+	}
+	s.cancel()
+	return nil
+}
+
+// callFileScrubbed adds the result of a scrubbed file to the status.
+func (s *scrubber) callFileScrubbed(result siadir.ScrubResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.FilesScrubbed++
+	if result.PiecesFailed > 0 {
+		s.status.FilesFailed++
+	}
+	s.status.PiecesChecked += result.PiecesChecked
+	s.status.PiecesFailed += result.PiecesFailed
+	s.status.PiecesUnverified += result.PiecesUnverified
+}
+
+// callFinish marks the running scrub as finished.
+func (s *scrubber) callFinish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancel()
+	s.cancel = nil
+	s.status.Running = false
+	s.status.EndTime = time.Now()
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+}
+
+// callStart resets the status for a new scrub of the given path. An error is
+// returned if a scrub is already running.
+func (s *scrubber) callStart(siaPath modules.SiaPath, cancel context.CancelFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.Running {
+		return errScrubInProgress
+	}
+	s.cancel = cancel
+	s.status = modules.ScrubStatus{
+		SiaPath:   siaPath,
+		Running:   true,
+		StartTime: time.Now(),
+	}
+	return nil
+}
+
+// callStatus returns the status of the current or last scrub.
+func (s *scrubber) callStatus() modules.ScrubStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// randomSegmentOffset returns the offset of a random segment within a sector.
+func randomSegmentOffset() uint64 {
+	return fastrand.Uint64n(modules.SectorSize/crypto.SegmentSize) * crypto.SegmentSize
+}
+
+// Scrub starts a scrub of the file or of the files within the directory at the
+// given path.
+func (r *Renter) Scrub(siaPath modules.SiaPath) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	isFile, err := r.staticFileSystem.FileExists(siaPath)
+	if err != nil {
+		return errors.AddContext(err, "failed to check if file exists")
+	}
+	if !isFile {
+		isDir, err := r.staticFileSystem.DirExists(siaPath)
+		if err != nil {
+			return errors.AddContext(err, "failed to check if dir exists")
+		}
+		if !isDir {
+			return filesystem.ErrNotExist
+		}
+	}
+
+	ctx, cancel := context.WithCancel(r.tg.StopCtx())
+	if err := r.staticScrubber.callStart(siaPath, cancel); err != nil {
+		cancel()
+		return err
+	}
+	err = r.tg.Launch(func() {
+		r.threadedScrub(ctx, siaPath, isFile)
+	})
+	if err != nil {
+		r.staticScrubber.callFinish(err)
+		return err
+	}
+	return nil
+}
+
+// ScrubCancel cancels the running scrub.
+func (r *Renter) ScrubCancel() error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.staticScrubber.callCancel()
+}
+
+// ScrubResults returns the results of the last scrubs of the files within the
+// directory at the given path. Files which were never scrubbed or which were
+// replaced since their last scrub are omitted.
+func (r *Renter) ScrubResults(siaPath modules.SiaPath, recursive bool) ([]modules.FileScrubResult, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+
+	dirs, err := r.managedScrubDirs(siaPath, recursive)
+	if err != nil {
+		return nil, err
+	}
+	var results []modules.FileScrubResult
+	for _, dir := range dirs {
+		dirResults, err := r.managedDirScrubResults(dir)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to get scrub results of "+dir.String())
+		}
+		results = append(results, dirResults...)
+	}
+	return results, nil
+}
+
+// ScrubStatus returns the status of the current or last scrub.
+func (r *Renter) ScrubStatus() modules.ScrubStatus {
+	return r.staticScrubber.callStatus()
+}
+
+// managedScrubDirs returns the directory at the given path and, if recursive
+// is true, all of its subdirectories.
+func (r *Renter) managedScrubDirs(siaPath modules.SiaPath, recursive bool) ([]modules.SiaPath, error) {
+	dirs := []modules.SiaPath{siaPath}
+	if !recursive {
+		return dirs, nil
+	}
+	var mu sync.Mutex
+	err := r.staticFileSystem.CachedList(siaPath, true, func(modules.FileInfo) {}, func(di modules.DirectoryInfo) {
+		if di.SiaPath.Equals(siaPath) {
+			return
+		}
+		mu.Lock()
+		dirs = append(dirs, di.SiaPath)
+		mu.Unlock()
+	})
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to list directories")
+	}
+	return dirs, nil
+}
+
+// managedDirScrubResults returns the scrub results of the files within a
+// directory. The results of files which were deleted or replaced are removed
+// from the directory's metadata.
+func (r *Renter) managedDirScrubResults(siaPath modules.SiaPath) (_ []modules.FileScrubResult, err error) {
+	dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Compose(err, dir.Close())
+	}()
+	md, err := dir.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []modules.FileScrubResult
+	var stale []string
+	for name, result := range md.ScrubResults {
+		fileSiaPath, err := siaPath.Join(name)
+		if err != nil {
+			return nil, err
+		}
+		// Remove the result if the file was deleted or replaced.
+		entry, err := r.staticFileSystem.OpenSiaFile(fileSiaPath)
+		if errors.Contains(err, filesystem.ErrNotExist) {
+			stale = append(stale, name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		uid := entry.UID()
+		if err := entry.Close(); err != nil {
+			return nil, err
+		}
+		if string(uid) != result.UID {
+			stale = append(stale, name)
+			continue
+		}
+		results = append(results, modules.FileScrubResult{
+			SiaPath:          fileSiaPath,
+			LastScrubTime:    result.LastScrubTime,
+			PiecesChecked:    result.PiecesChecked,
+			PiecesFailed:     result.PiecesFailed,
+			PiecesUnverified: result.PiecesUnverified,
+		})
+	}
+	if err := dir.DeleteScrubResults(stale); err != nil {
+		return nil, errors.AddContext(err, "failed to delete stale scrub results")
+	}
+	return results, nil
+}
+
+// managedCheckHostPieces checks the pieces stored on a host and sets their
+// status.
+func (r *Renter) managedCheckHostPieces(ctx context.Context, hostKey types.SiaPublicKey, pieces []*scrubPiece) {
+	w, err := r.staticWorkerPool.callWorker(hostKey)
+	if err != nil {
+		return // all pieces remain unverified
+	}
+	// Hosts which support the ProveSegment instruction can prove their
+	// pieces in batches.
+	if build.VersionCmp(w.staticCache().staticHostVersion, minProveSegmentVersion) >= 0 {
+		r.managedProveHostPieces(ctx, w, pieces)
+		return
+	}
+	for len(pieces) > 0 {
+		batch := pieces
+		if len(batch) > scrubHasSectorBatchSize {
+			batch = batch[:scrubHasSectorBatchSize]
+		}
+		pieces = pieces[len(batch):]
+
+		// Check that the host has the pieces.
+		roots := make([]crypto.Hash, 0, len(batch))
+		for _, piece := range batch {
+			roots = append(roots, piece.root)
+		}
+		availables, err := r.managedScrubHasSector(ctx, w, roots)
+		if err != nil {
+			continue
+		}
+
+		// Read a random segment of every available piece.
+		for i, piece := range batch {
+			if !availables[i] {
+				piece.status = pieceFailed
+				continue
+			}
+			readCtx, cancel := context.WithTimeout(ctx, scrubJobTimeout)
+			_, err := w.ReadSectorLowPrio(readCtx, categoryRepairDownload, piece.root, randomSegmentOffset(), crypto.SegmentSize)
+			cancel()
+			if errors.Contains(err, errProofVerificationFailed) {
+				piece.status = pieceFailed
+			} else if err == nil {
+				piece.status = pieceVerified
+			}
+		}
+	}
+}
+
+// managedProveHostPieces challenges the worker's host to prove that it stores
+// the pieces and sets their status.
+func (r *Renter) managedProveHostPieces(ctx context.Context, w *worker, pieces []*scrubPiece) {
+	for len(pieces) > 0 {
+		batch := pieces
+		if len(batch) > scrubProveSegmentBatchSize {
+			batch = batch[:scrubProveSegmentBatchSize]
+		}
+		pieces = pieces[len(batch):]
+
+		roots := make([]crypto.Hash, 0, len(batch))
+		for _, piece := range batch {
+			roots = append(roots, piece.root)
+		}
+		var seed crypto.Hash
+		fastrand.Read(seed[:])
+		proveCtx, cancel := context.WithTimeout(ctx, scrubJobTimeout)
+		proven, err := w.ProveSegments(proveCtx, seed, roots)
+		cancel()
+		if err != nil {
+			continue
+		}
+		for i, piece := range batch {
+			if proven[i] {
+				piece.status = pieceVerified
+			} else {
+				piece.status = pieceFailed
+			}
+		}
+	}
+}
+
+// managedScrubHasSector checks whether the worker's host has the sectors with
+// the given roots.
+func (r *Renter) managedScrubHasSector(ctx context.Context, w *worker, roots []crypto.Hash) ([]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, scrubJobTimeout)
+	defer cancel()
+	responseChan := make(chan *jobHasSectorResponse)
+	jhs := w.newJobHasSector(ctx, responseChan, roots...)
+	if !w.staticJobHasSectorQueue.callAdd(jhs) {
+		return nil, errors.New("worker unavailable")
+	}
+	var resp *jobHasSectorResponse
+	select {
+	case <-ctx.Done():
+		return nil, errors.New("HasSector interrupted")
+	case resp = <-responseChan:
+	}
+	if resp.staticErr != nil {
+		return nil, resp.staticErr
+	}
+	if len(resp.staticAvailables) != len(roots) {
+		return nil, fmt.Errorf("expected %v availables but got %v", len(roots), len(resp.staticAvailables))
+	}
+	return resp.staticAvailables, nil
+}
+
+// managedQueueScrubRepairs adds the chunks with failed pieces to the repair
+// heap.
+func (r *Renter) managedQueueScrubRepairs(entry *filesystem.FileNode, chunks map[uint64]struct{}) error {
+	hosts := r.managedRefreshHostsAndWorkers()
+	offline, goodForRenew, _ := r.managedContractUtilityMaps()
+	pks := make(map[string]types.SiaPublicKey)
+	for _, pk := range entry.HostPublicKeys() {
+		pks[string(pk.Key)] = pk
+	}
+	var errs error
+	for chunkIndex := range chunks {
+		uuc, err := r.managedBuildUnfinishedChunk(entry, chunkIndex, hosts, pks, memoryPriorityLow, modules.PriorityClassBackground, offline, goodForRenew, r.repairMemoryManager)
+		if err != nil {
+			errs = errors.Compose(errs, err)
+			continue
+		}
+		pushed, err := r.managedPushChunkForRepair(uuc, chunkTypeLocalChunk)
+		if err != nil || !pushed {
+			// The chunk might already be in the heap.
+			errs = errors.Compose(errs, err, uuc.fileEntry.Close())
+		}
+	}
+	select {
+	case r.uploadHeap.repairNeeded <- struct{}{}:
+	default:
+	}
+	return errs
+}
+
+// managedScrubFile checks all the pieces of a file, removes the ones which
+// failed verification and queues the affected chunks for repair.
+func (r *Renter) managedScrubFile(ctx context.Context, siaPath modules.SiaPath) (err error) {
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Compose(err, entry.Close())
+	}()
+
+	// Group the pieces by host.
+	hostKeys := make(map[string]types.SiaPublicKey)
+	hostPieces := make(map[string][]*scrubPiece)
+	for chunkIndex := uint64(0); chunkIndex < entry.NumChunks(); chunkIndex++ {
+		pieces, err := entry.Pieces(chunkIndex)
+		if err != nil {
+			return errors.AddContext(err, "failed to get pieces")
+		}
+		for pieceIndex, pieceSet := range pieces {
+			for _, piece := range pieceSet {
+				hk := piece.HostPubKey.String()
+				hostKeys[hk] = piece.HostPubKey
+				hostPieces[hk] = append(hostPieces[hk], &scrubPiece{
+					chunkIndex: chunkIndex,
+					pieceIndex: uint64(pieceIndex),
+					root:       piece.MerkleRoot,
+				})
+			}
+		}
+	}
+
+	// Check the pieces of every host in parallel.
+	var wg sync.WaitGroup
+	for hk, pieces := range hostPieces {
+		wg.Add(1)
+		go func(hostKey types.SiaPublicKey, pieces []*scrubPiece) {
+			defer wg.Done()
+			r.managedCheckHostPieces(ctx, hostKey, pieces)
+		}(hostKeys[hk], pieces)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return errScrubCancelled
+	}
+
+	// Remove the failed pieces.
+	result := siadir.ScrubResult{
+		UID:           string(entry.UID()),
+		LastScrubTime: time.Now(),
+	}
+	failedChunks := make(map[uint64]struct{})
+	for hk, pieces := range hostPieces {
+		for _, piece := range pieces {
+			result.PiecesChecked++
+			switch piece.status {
+			case pieceUnverified:
+				result.PiecesUnverified++
+				continue
+			case pieceVerified:
+				continue
+			}
+			result.PiecesFailed++
+			failedChunks[piece.chunkIndex] = struct{}{}
+			err := entry.RemovePiece(hostKeys[hk], piece.chunkIndex, piece.pieceIndex, piece.root)
+			if err != nil {
+				return errors.AddContext(err, "failed to remove piece")
+			}
+		}
+	}
+	if len(failedChunks) > 0 {
+		r.log.Printf("Scrub of %v found %v bad pieces in %v chunks", siaPath, result.PiecesFailed, len(failedChunks))
+		if err := r.managedQueueScrubRepairs(entry, failedChunks); err != nil {
+			r.repairLog.Println("WARN: failed to queue chunks of scrubbed file for repair:", err)
+		}
+	}
+
+	// Record the result.
+	if result.PiecesFailed > 0 {
+		r.staticAlerter.RegisterAlert(modules.AlertIDSiafileScrubFailed(result.UID), AlertMSGSiafileScrubFailed,
+			AlertCauseSiafileScrubFailed(siaPath, result.PiecesFailed, result.PiecesChecked), modules.SeverityWarning)
+	} else {
+		r.staticAlerter.UnregisterAlert(modules.AlertIDSiafileScrubFailed(result.UID))
+	}
+	r.staticScrubber.callFileScrubbed(result)
+	dirSiaPath, err := siaPath.Dir()
+	if err != nil {
+		return err
+	}
+	dir, err := r.staticFileSystem.OpenSiaDir(dirSiaPath)
+	if err != nil {
+		return errors.AddContext(err, "failed to open dir")
+	}
+	err = errors.Compose(dir.SetScrubResult(siaPath.Name(), result), dir.Close())
+	if err != nil {
+		return errors.AddContext(err, "failed to save scrub result")
+	}
+
+	// The health of the file changed if pieces were removed.
+	if result.PiecesFailed > 0 {
+		_ = r.staticBubbleScheduler.callQueueBubble(dirSiaPath)
+	}
+	return nil
+}
+
+// threadedScrub scrubs the file or the files within the directory at the given
+// path.
+func (r *Renter) threadedScrub(ctx context.Context, siaPath modules.SiaPath, isFile bool) {
+	siaPaths := []modules.SiaPath{siaPath}
+	dirSiaPath := siaPath
+	if isFile {
+		var err error
+		dirSiaPath, err = siaPath.Dir()
+		if err != nil {
+			r.staticScrubber.callFinish(err)
+			return
+		}
+	} else {
+		var err error
+		siaPaths, err = r.managedDirFiles(siaPath)
+		if err != nil {
+			r.staticScrubber.callFinish(errors.AddContext(err, "failed to list files"))
+			return
+		}
+	}
+
+	var errs error
+	for _, sp := range siaPaths {
+		select {
+		case <-ctx.Done():
+			r.staticScrubber.callFinish(errScrubCancelled)
+			return
+		default:
+		}
+		err := r.managedScrubFile(ctx, sp)
+		if errors.Contains(err, errScrubCancelled) {
+			r.staticScrubber.callFinish(errScrubCancelled)
+			return
+		}
+		if errors.Contains(err, filesystem.ErrNotExist) {
+			continue // the file was deleted in the meantime
+		}
+		if err != nil {
+			r.log.Printf("Failed to scrub %v: %v", sp, err)
+			errs = errors.Compose(errs, errors.AddContext(err, "failed to scrub "+sp.String()))
+		}
+	}
+
+	// Remove the results of files which no longer exist from the scrubbed
+	// directories.
+	dirs, err := r.managedScrubDirs(dirSiaPath, !isFile)
+	for _, dir := range dirs {
+		_, pruneErr := r.managedDirScrubResults(dir)
+		err = errors.Compose(err, pruneErr)
+	}
+	if err != nil {
+		r.log.Printf("Failed to remove stale scrub results of %v: %v", dirSiaPath, err)
+		errs = errors.Compose(errs, errors.AddContext(err, "failed to remove stale scrub results"))
+	}
+	r.staticScrubber.callFinish(errs)
+}
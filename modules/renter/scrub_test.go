@@ -0,0 +1,161 @@
+package renter
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siadir"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/siatest/dependencies"
+)
+
+// TestScrubber is a unit test for the scrubber's status tracking.
+func TestScrubber(t *testing.T) {
+	t.Parallel()
+
+	var s scrubber
+	if err := s.callCancel(); !errors.Contains(err, errNoScrubInProgress) {
+		t.Fatal("expected errNoScrubInProgress but got", err)
+	}
+
+	// Start a scrub.
+	ctx, cancel := context.WithCancel(context.Background())
+	siaPath := modules.RandomSiaPath()
+	if err := s.callStart(siaPath, cancel); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.callStart(siaPath, cancel); !errors.Contains(err, errScrubInProgress) {
+		t.Fatal("expected errScrubInProgress but got", err)
+	}
+	status := s.callStatus()
+	if !status.Running || !status.SiaPath.Equals(siaPath) || status.StartTime.IsZero() {
+		t.Fatal("unexpected status", status)
+	}
+
+	// Add some results.
+	s.callFileScrubbed(siadir.ScrubResult{PiecesChecked: 10})
+	s.callFileScrubbed(siadir.ScrubResult{PiecesChecked: 20, PiecesFailed: 2, PiecesUnverified: 3})
+	status = s.callStatus()
+	if status.FilesScrubbed != 2 || status.FilesFailed != 1 || status.PiecesChecked != 30 ||
+		status.PiecesFailed != 2 || status.PiecesUnverified != 3 {
+		t.Fatal("unexpected status", status)
+	}
+
+	// Cancel the scrub.
+	if err := s.callCancel(); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context should be cancelled")
+	}
+	s.callFinish(errScrubCancelled)
+	status = s.callStatus()
+	if status.Running || status.EndTime.IsZero() || status.Error != errScrubCancelled.Error() {
+		t.Fatal("unexpected status", status)
+	}
+
+	// A new scrub resets the status.
+	if err := s.callStart(siaPath, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	status = s.callStatus()
+	if status.FilesScrubbed != 0 || status.Error != "" || !status.EndTime.IsZero() {
+		t.Fatal("status wasn't reset", status)
+	}
+}
+
+// TestDirScrubResults checks that the results of deleted and replaced files
+// are removed when the results of a directory are requested.
+func TestDirScrubResults(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Create a dir with two files.
+	dirSiaPath := newSiaPath("dir")
+	if err := r.CreateDir(dirSiaPath, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+	rsc, _ := modules.NewRSCode(1, 1)
+	uid := func(name string) string {
+		t.Helper()
+		siaPath := newSiaPath("dir/" + name)
+		err := r.staticFileSystem.NewSiaFile(siaPath, "", rsc, crypto.GenerateSiaKey(crypto.RandomCipherType()), modules.SectorSize, persist.DefaultDiskPermissionsTest, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := entry.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		return string(entry.UID())
+	}
+	fileUID := uid("file")
+	_ = uid("replaced")
+
+	// Store results for both files and for a deleted file. The result of the
+	// replaced file belongs to a previous file with the same name.
+	dir, err := r.staticFileSystem.OpenSiaDir(dirSiaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]siadir.ScrubResult{
+		"file":     {UID: fileUID, PiecesChecked: 2},
+		"replaced": {UID: "old", PiecesChecked: 2},
+		"deleted":  {UID: "deleted", PiecesChecked: 2},
+	}
+	for name, result := range results {
+		if err := dir.SetScrubResult(name, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the result of the existing file is returned and kept.
+	frs, err := r.managedDirScrubResults(dirSiaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frs) != 1 || !frs[0].SiaPath.Equals(newSiaPath("dir/file")) {
+		t.Fatal("unexpected results", frs)
+	}
+	md, err := dir.Metadata()
+	if err := errors.Compose(err, dir.Close()); err != nil {
+		t.Fatal(err)
+	}
+	if len(md.ScrubResults) != 1 || md.ScrubResults["file"] != results["file"] {
+		t.Fatal("stale results weren't removed", md.ScrubResults)
+	}
+}
+
+// TestRandomSegmentOffset checks that random segment offsets are aligned and
+// within a sector.
+func TestRandomSegmentOffset(t *testing.T) {
+	t.Parallel()
+	for i := 0; i < 1000; i++ {
+		offset := randomSegmentOffset()
+		if offset%crypto.SegmentSize != 0 || offset+crypto.SegmentSize > modules.SectorSize {
+			t.Fatal("invalid offset", offset)
+		}
+	}
+}
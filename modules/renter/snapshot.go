@@ -29,7 +29,12 @@ var (
 
 	// snapshotTableSpecifier is the specifier used to identify a snapshot entry
 	// table stored in a sector.
-	snapshotTableSpecifier = types.NewSpecifier("SnapshotTable")
+	snapshotTableSpecifier = types.NewSpecifier("SnapshotTableV2")
+
+	// snapshotTableSpecifierV1 is the specifier used to identify a snapshot
+	// entry table which was stored before the entries contained the siapath of
+	// directory backups.
+	snapshotTableSpecifierV1 = types.NewSpecifier("SnapshotTable")
 )
 
 var (
@@ -56,8 +61,62 @@ type snapshotEntry struct {
 	Name         [96]byte
 	UID          [16]byte
 	CreationDate types.Timestamp
-	Size         uint64         // size of snapshot .sia file
-	DataSectors  [4]crypto.Hash // pointers to sectors containing snapshot .sia file
+	Size         uint64          // size of snapshot .sia file
+	DataSectors  [4]crypto.Hash  // pointers to sectors containing snapshot .sia file
+	SiaPath      modules.SiaPath // directory of a directory backup, empty for full backups
+}
+
+// snapshotEntryV1 is an entry within a snapshot table identified by
+// snapshotTableSpecifierV1.
+type snapshotEntryV1 struct {
+	Name         [96]byte
+	UID          [16]byte
+	CreationDate types.Timestamp
+	Size         uint64
+	DataSectors  [4]crypto.Hash
+}
+
+// uploadedBackup returns the metadata of the snapshot the entry points to.
+func (se snapshotEntry) uploadedBackup() modules.UploadedBackup {
+	return modules.UploadedBackup{
+		Name:           string(bytes.TrimRight(se.Name[:], types.RuneToString(0))),
+		UID:            se.UID,
+		CreationDate:   se.CreationDate,
+		Size:           se.Size,
+		UploadProgress: 100,
+		SiaPath:        se.SiaPath,
+	}
+}
+
+// unmarshalSnapshotTable unmarshals a decrypted snapshot table. Tables which
+// were stored before the entries contained a siapath are converted to the
+// current version.
+func unmarshalSnapshotTable(table []byte) ([]snapshotEntry, error) {
+	var entryTable []snapshotEntry
+	switch {
+	case bytes.Equal(table[:16], snapshotTableSpecifier[:]):
+		if err := encoding.Unmarshal(table[16:], &entryTable); err != nil {
+			return nil, errors.AddContext(err, "error unmarshaling the entry table")
+		}
+	case bytes.Equal(table[:16], snapshotTableSpecifierV1[:]):
+		var entryTableV1 []snapshotEntryV1
+		if err := encoding.Unmarshal(table[16:], &entryTableV1); err != nil {
+			return nil, errors.AddContext(err, "error unmarshaling the v1 entry table")
+		}
+		entryTable = make([]snapshotEntry, 0, len(entryTableV1))
+		for _, e := range entryTableV1 {
+			entryTable = append(entryTable, snapshotEntry{
+				Name:         e.Name,
+				UID:          e.UID,
+				CreationDate: e.CreationDate,
+				Size:         e.Size,
+				DataSectors:  e.DataSectors,
+			})
+		}
+	default:
+		return nil, errors.New("sector doesn't contain an entry table")
+	}
+	return entryTable, nil
 }
 
 // calcSnapshotUploadProgress calculates the upload progress of a snapshot.
@@ -113,12 +172,28 @@ func (r *Renter) UploadBackup(src, name string) error {
 		return err
 	}
 	defer r.tg.Done()
-	return r.managedUploadBackup(src, name)
+	return r.managedUploadBackup(src, name, modules.SiaPath{})
+}
+
+// UploadDirBackup uploads a backup of a single directory which was created
+// with CreateDirBackup as a snapshot. The directory is recorded in the
+// snapshot's metadata.
+func (r *Renter) UploadDirBackup(src, name string, siaPath modules.SiaPath) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if siaPath.IsEmpty() {
+		return errors.New("directory of backup not specified")
+	}
+	return r.managedUploadBackup(src, name, siaPath)
 }
 
 // managedUploadBackup creates a backup of the renter which is uploaded to the
-// sia network as a snapshot and can be retrieved using only the seed.
-func (r *Renter) managedUploadBackup(src, name string) error {
+// sia network as a snapshot and can be retrieved using only the seed. If the
+// backup only contains a single directory, siaPath is the path of that
+// directory.
+func (r *Renter) managedUploadBackup(src, name string, siaPath modules.SiaPath) error {
 	if len(name) > 96 {
 		return errors.New("name is too long")
 	}
@@ -177,6 +252,7 @@ func (r *Renter) managedUploadBackup(src, name string) error {
 		CreationDate:   types.CurrentTimestamp(),
 		Size:           0,
 		UploadProgress: 0,
+		SiaPath:        siaPath,
 	}
 	fastrand.Read(meta.UID[:])
 	if err := r.managedSaveSnapshot(meta); err != nil {
@@ -248,7 +324,10 @@ func (r *Renter) DownloadBackup(dst string, name string) (err error) {
 	if err != nil {
 		return err
 	}
-	s := r.managedStreamer(snap, false)
+	s, err := r.managedStreamer(snap, false)
+	if err != nil {
+		return err
+	}
 	_, err = io.Copy(dstFile, s)
 	return errors.Compose(err, s.Close())
 }
@@ -288,15 +367,18 @@ func (r *Renter) managedSaveSnapshot(meta modules.UploadedBackup) error {
 	// number of snapshots, so if we exceed that number, we kick out the oldest
 	// snapshot. We check the size by encoding a slice of snapshotEntrys and
 	// removing elements from the slice until the encoded size fits on the host.
-	entryTable := make([]snapshotEntry, len(r.persist.UploadedBackups))
-	for len(encoding.Marshal(entryTable)) > int(modules.SectorSize) {
-		entryTable = entryTable[1:]
-	}
 	// Sort by CreationDate (youngest-to-oldest) and remove excess elements from
 	// the end.
 	sort.Slice(r.persist.UploadedBackups, func(i, j int) bool {
 		return r.persist.UploadedBackups[i].CreationDate > r.persist.UploadedBackups[j].CreationDate
 	})
+	entryTable := make([]snapshotEntry, len(r.persist.UploadedBackups))
+	for i, ub := range r.persist.UploadedBackups {
+		entryTable[i].SiaPath = ub.SiaPath
+	}
+	for len(encoding.Marshal(entryTable)) > int(modules.SectorSize) {
+		entryTable = entryTable[:len(entryTable)-1]
+	}
 	r.persist.UploadedBackups = r.persist.UploadedBackups[:len(entryTable)]
 	// Save the result.
 	if err := r.saveSync(); err != nil {
@@ -341,7 +423,7 @@ func (r *Renter) managedDownloadSnapshotTable(host *worker) ([]snapshotEntry, er
 	// decrypt the table
 	c, _ := crypto.NewSiaKey(crypto.TypeThreefish, secret[:])
 	encTable, err := c.DecryptBytesInPlace(tableSector, 0)
-	if err != nil || !bytes.Equal(encTable[:16], snapshotTableSpecifier[:]) {
+	if err != nil || (!bytes.Equal(encTable[:16], snapshotTableSpecifier[:]) && !bytes.Equal(encTable[:16], snapshotTableSpecifierV1[:])) {
 		// either the first sector was not an entry table, or it got corrupted
 		// somehow; either way, it's not retrievable, so we'll treat this as
 		// equivalent to having no entry table at all. This is not an error; it
@@ -349,11 +431,7 @@ func (r *Renter) managedDownloadSnapshotTable(host *worker) ([]snapshotEntry, er
 		// table.
 		return nil, errors.AddContext(err, "error decrypting bytes")
 	}
-
-	if err := encoding.Unmarshal(encTable[16:], &entryTable); err != nil {
-		return nil, errors.AddContext(err, "error unmarshaling the entry table")
-	}
-	return entryTable, nil
+	return unmarshalSnapshotTable(encTable)
 }
 
 // managedUploadSnapshot uploads a snapshot .sia file to all hosts.
@@ -513,13 +591,7 @@ func (r *Renter) managedDownloadSnapshot(uid [16]byte) (ub modules.UploadedBacku
 					break
 				}
 			}
-			ub = modules.UploadedBackup{
-				Name:           string(bytes.TrimRight(entry.Name[:], types.RuneToString(0))),
-				UID:            entry.UID,
-				CreationDate:   entry.CreationDate,
-				Size:           entry.Size,
-				UploadProgress: 100,
-			}
+			ub = entry.uploadedBackup()
 			return nil
 		}()
 		if err != nil {
@@ -548,13 +620,7 @@ func (r *Renter) threadedSynchronizeSnapshots() {
 		}
 		for _, e := range entryTable {
 			if _, ok := known[e.UID]; !ok {
-				unknown = append(unknown, modules.UploadedBackup{
-					Name:           string(bytes.TrimRight(e.Name[:], types.RuneToString(0))),
-					UID:            e.UID,
-					CreationDate:   e.CreationDate,
-					Size:           e.Size,
-					UploadProgress: 100,
-				})
+				unknown = append(unknown, e.uploadedBackup())
 			}
 			delete(missingMap, e.UID)
 		}
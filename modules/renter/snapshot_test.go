@@ -0,0 +1,72 @@
+package renter
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/fastrand"
+	"go.sia.tech/siad/modules"
+)
+
+// TestUnmarshalSnapshotTable checks that both the current and the v1 snapshot
+// tables can be unmarshaled.
+func TestUnmarshalSnapshotTable(t *testing.T) {
+	t.Parallel()
+
+	// table returns a snapshot table sector with the provided specifier and
+	// entries.
+	table := func(specifier []byte, entries interface{}) []byte {
+		sector := make([]byte, modules.SectorSize)
+		copy(sector[:16], specifier)
+		copy(sector[16:], encoding.Marshal(entries))
+		return sector
+	}
+
+	// Create a table with a full backup and a directory backup.
+	siaPath, err := modules.NewSiaPath("foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]snapshotEntry, 2)
+	for i := range entries {
+		copy(entries[i].Name[:], "backup")
+		fastrand.Read(entries[i].UID[:])
+		entries[i].Size = fastrand.Uint64n(1000)
+	}
+	entries[1].SiaPath = siaPath
+	entryTable, err := unmarshalSnapshotTable(table(snapshotTableSpecifier[:], entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entryTable) != 2 || entryTable[0] != entries[0] || entryTable[1] != entries[1] {
+		t.Fatal("wrong entry table", entryTable)
+	}
+	if ub := entryTable[1].uploadedBackup(); ub.Name != "backup" || !ub.SiaPath.Equals(siaPath) {
+		t.Fatal("wrong uploaded backup", ub)
+	}
+
+	// v1 tables don't contain siapaths.
+	entriesV1 := make([]snapshotEntryV1, len(entries))
+	for i, e := range entries {
+		entriesV1[i] = snapshotEntryV1{
+			Name:         e.Name,
+			UID:          e.UID,
+			CreationDate: e.CreationDate,
+			Size:         e.Size,
+			DataSectors:  e.DataSectors,
+		}
+	}
+	entryTable, err = unmarshalSnapshotTable(table(snapshotTableSpecifierV1[:], entriesV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries[1].SiaPath = modules.SiaPath{}
+	if len(entryTable) != 2 || entryTable[0] != entries[0] || entryTable[1] != entries[1] {
+		t.Fatal("wrong v1 entry table", entryTable)
+	}
+
+	// Sectors without a table can't be unmarshaled.
+	if _, err := unmarshalSnapshotTable(fastrand.Bytes(int(modules.SectorSize))); err == nil {
+		t.Fatal("expected unmarshaling random data to fail")
+	}
+}
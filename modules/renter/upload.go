@@ -33,6 +33,16 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	}
 	defer r.tg.Done()
 
+	// Chunks can only be deduplicated while the data is streamed.
+	if up.Dedup {
+		return errDedupNotStreamed
+	}
+	// Compressed data doesn't match the chunks of the local file which would
+	// prevent the repair loop from using it.
+	if up.Compression != modules.CompressionNone {
+		return errCompressionNotStreamed
+	}
+
 	// Check if the file is a directory.
 	sourceInfo, err := os.Stat(up.Source)
 	if err != nil {
@@ -52,17 +62,17 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 		return errors.AddContext(err, "unable to close file after checking permissions")
 	}
 
-	// Delete existing file if overwrite flag is set. Ignore ErrUnknownPath.
-	if up.Force {
-		err := r.DeleteFile(up.SiaPath)
-		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
-			return errors.AddContext(err, "unable to delete existing file")
-		}
+	// Fill in any missing upload params with the defaults of the directory's
+	// policy.
+	policy, err := r.managedFilePolicy(up.SiaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to get policy of directory")
 	}
-
-	// Fill in any missing upload params with sensible defaults.
 	if up.ErasureCode == nil {
-		up.ErasureCode = modules.NewRSSubCodeDefault()
+		up.ErasureCode, err = policy.ErasureCoder()
+		if err != nil {
+			return errors.AddContext(err, "unable to create erasure coder from policy")
+		}
 	}
 
 	// Check that we have contracts to upload to. We need at least data +
@@ -82,11 +92,26 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 		return err
 	}
 
+	// Make sure that the upload doesn't exceed the quotas of the directory
+	// and its parents.
+	charge := r.managedUploadQuotaCharge(uint64(sourceInfo.Size()), up.ErasureCode)
+	if err := r.managedChargeQuotas(dirSiaPath, charge, true); err != nil {
+		return err
+	}
+
+	// Archive existing file if overwrite flag is set. Ignore ErrUnknownPath.
+	if up.Force {
+		err := r.managedArchiveFile(up.SiaPath)
+		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+			return errors.AddContext(err, "unable to delete existing file")
+		}
+	}
+
 	// Determine what type of encryption key to use. If no cipher type has been
-	// set, the default renter type will be used.
+	// set, the type of the directory's policy will be used.
 	var ct crypto.CipherType
 	if up.CipherType == ct {
-		up.CipherType = crypto.TypeDefaultRenter
+		up.CipherType = policy.Cipher()
 	}
 	// Generate a key using the cipher type.
 	cipherKey := crypto.GenerateSiaKey(up.CipherType)
@@ -121,7 +146,7 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	nilMap := make(map[string]bool)
 	// Send the upload to the repair loop.
 	hosts := r.managedRefreshHostsAndWorkers()
-	r.callBuildAndPushChunks([]*filesystem.FileNode{entry}, hosts, targetUnstuckChunks, nilMap, nilMap)
+	r.callBuildAndPushChunks([]*filesystem.FileNode{entry}, hosts, targetUnstuckChunks, modules.PriorityClassInteractive, nilMap, nilMap)
 	select {
 	case r.uploadHeap.newUploads <- struct{}{}:
 	default:
@@ -45,9 +45,15 @@ type unfinishedUploadChunk struct {
 	staticMemoryManager *memoryManager
 
 	// Static cached fields.
-	staticIndex    uint64
-	staticSiaPath  string
-	staticPriority bool // indicates if the chunk should get access to priority memory
+	staticCipherKey crypto.CipherKey // key used for encrypting the pieces of the chunk
+	staticIndex     uint64
+	staticKeyIndex  uint64 // index used for deriving the keys of the pieces
+	staticSiaPath   string
+	staticPriority  bool // indicates if the chunk should get access to priority memory
+
+	// staticPriorityClass is the class used to share the upload capacity
+	// with the chunks of other classes.
+	staticPriorityClass modules.PriorityClass
 
 	// The logical data is the data that is presented to the user when the user
 	// requests the chunk. The physical data is all of the pieces that get
@@ -220,7 +226,7 @@ func (uc *unfinishedUploadChunk) chunkComplete() bool {
 // readDataPieces reads dataPieces from a io.Reader and stores them in a
 // [][]byte ready to be encoded using an ErasureCoder.
 func readDataPieces(r io.Reader, ec modules.ErasureCoder, pieceSize uint64) ([][]byte, uint64, error) {
-	dataPieces := make([][]byte, ec.MinPieces())
+	dataPieces := make([][]byte, ec.DataPieces())
 	var total uint64
 	for i := range dataPieces {
 		dataPieces[i] = make([]byte, pieceSize)
@@ -236,11 +242,12 @@ func readDataPieces(r io.Reader, ec modules.ErasureCoder, pieceSize uint64) ([][
 // padAndEncryptPiece will add padding to a unfinishedUploadChunk's piece at
 // index i and then encrypt it.
 func (uc *unfinishedUploadChunk) padAndEncryptPiece(i int) {
-	padAndEncryptPiece(uc.staticIndex, uint64(i), uc.logicalChunkData, uc.fileEntry.MasterKey())
+	padAndEncryptPiece(uc.staticKeyIndex, uint64(i), uc.logicalChunkData, uc.staticCipherKey)
 }
 
-// padAndEncryptPiece will add padding to a piece and then encrypt it.
-func padAndEncryptPiece(chunkIndex, pieceIndex uint64, logicalChunkData [][]byte, masterKey crypto.CipherKey) {
+// padAndEncryptPiece will add padding to a piece and then encrypt it. The
+// keyIndex is usually the index of the chunk within the file.
+func padAndEncryptPiece(keyIndex, pieceIndex uint64, logicalChunkData [][]byte, masterKey crypto.CipherKey) {
 	// If the piece is not a full sector, pad it with empty bytes. The padding
 	// is done before applying encryption, meaning the data fed to the host does
 	// not have a bunch of zeroes in it.
@@ -254,7 +261,7 @@ func padAndEncryptPiece(chunkIndex, pieceIndex uint64, logicalChunkData [][]byte
 		logicalChunkData[pieceIndex] = append(logicalChunkData[pieceIndex], make([]byte, short)...)
 	}
 	// Encrypt the piece.
-	key := masterKey.Derive(chunkIndex, pieceIndex)
+	key := masterKey.Derive(keyIndex, pieceIndex)
 	// TODO: Switch this to perform in-place encryption.
 	logicalChunkData[pieceIndex] = key.EncryptBytes(logicalChunkData[pieceIndex])
 }
@@ -263,6 +270,16 @@ func padAndEncryptPiece(chunkIndex, pieceIndex uint64, logicalChunkData [][]byte
 // download to the renter's downloader, and then using the data that gets
 // returned.
 func (r *Renter) managedDownloadLogicalChunkData(chunk *unfinishedUploadChunk) error {
+	// If the erasure code of the chunk is locally repairable, try to only
+	// fetch the local groups of the missing pieces.
+	if indices, ok := localRepairPieces(chunk.fileEntry.ErasureCode(), chunk.pieceUsage); ok {
+		err := r.managedRepairLocalGroups(chunk, indices)
+		if err == nil {
+			return nil
+		}
+		r.repairLog.Printf("local repair of chunk %v of %s failed, falling back to a full download: %v", chunk.staticIndex, chunk.staticSiaPath, err)
+	}
+
 	//  Determine what the download length should be. Normally it is just the
 	//  chunk size, but if this is the last chunk we need to download less
 	//  because the file is not that large.
@@ -296,6 +313,7 @@ func (r *Renter) managedDownloadLogicalChunkData(chunk *unfinishedUploadChunk) e
 		offset:        uint64(chunk.offset),
 		overdrive:     0, // No need to rush the latency on repair downloads.
 		priority:      0, // Repair downloads are completely de-prioritized.
+		priorityClass: chunk.staticPriorityClass,
 
 		staticMemoryManager:    chunk.staticMemoryManager, // Same memory manager as upload chunk
 		staticSpendingCategory: categoryRepairDownload,
@@ -334,22 +352,26 @@ func (r *Renter) managedDownloadLogicalChunkData(chunk *unfinishedUploadChunk) e
 	if err != nil {
 		return errors.AddContext(err, "unable to reconstruct the data downloaded from the network during repair")
 	}
-	// Loop through the pieces and encrypt any that are needed, while dropping
-	// any pieces that are not needed.
+	chunk.padAndEncryptMissingPieces()
+	return nil
+}
+
+// padAndEncryptMissingPieces loops through the pieces and encrypts any that
+// are needed, while dropping any pieces that are not needed.
+func (uc *unfinishedUploadChunk) padAndEncryptMissingPieces() {
 	var wg sync.WaitGroup
-	for i := 0; i < len(chunk.pieceUsage); i++ {
-		if chunk.pieceUsage[i] {
-			chunk.logicalChunkData[i] = nil
+	for i := 0; i < len(uc.pieceUsage); i++ {
+		if uc.pieceUsage[i] {
+			uc.logicalChunkData[i] = nil
 			continue
 		}
 		wg.Add(1)
 		go func(i int) {
-			chunk.padAndEncryptPiece(i)
+			uc.padAndEncryptPiece(i)
 			wg.Done()
 		}(i)
 	}
 	wg.Wait()
-	return nil
 }
 
 // threadedFetchAndRepairChunk will fetch the logical data for a chunk, create
@@ -363,7 +385,7 @@ func (r *Renter) threadedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
 
 	// Calculate the amount of memory needed for erasure coding. This will need
 	// to be released if there's an error before erasure coding is complete.
-	erasureCodingMemory := chunk.fileEntry.PieceSize() * uint64(chunk.fileEntry.ErasureCode().MinPieces())
+	erasureCodingMemory := chunk.fileEntry.PieceSize() * uint64(chunk.fileEntry.ErasureCode().DataPieces())
 
 	// Calculate the amount of memory to release due to already completed
 	// pieces. This memory gets released during encryption, but needs to be
@@ -4,6 +4,8 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"go.sia.tech/siad/modules"
 )
 
 // uploadchunkdistributionqueue.go creates a queue for distributing upload
@@ -13,6 +15,11 @@ import (
 // amount of throughput we will bump low priority work in to the priority work
 // queue if too much priority work gets scheduled while the low priority work is
 // waiting.
+//
+// Background repairs go into the low priority lane unless their memory was
+// requested with priority. Chunks of the interactive and user priority classes
+// always go into the priority lane. The ratio at which low priority work is
+// bumped follows the priority weights of the renter.
 
 const (
 	// uploadChunkDistirbutionBackoff dictates the amount of time that the
@@ -38,6 +45,9 @@ const (
 	// users upload to a fresh node that has very little need of repair traffic.
 	// Increasing the lowPriorityMinThroughput will increase the total amount of
 	// data that a node can maintain at the cost of latency for new uploads.
+	//
+	// This is the default which matches the default priority weights. Once the
+	// renter loaded its settings, the multiplier is derived from the weights.
 	lowPriorityMinThroughputMultiplier = 10 // 10%
 
 	// workerUploadBusyThreshold is the number of jobs a worker needs to have to
@@ -59,9 +69,10 @@ const (
 type uploadChunkDistributionQueue struct {
 	processThreadRunning bool
 
-	priorityBuildup uint64
-	priorityLane    *ucdqFifo
-	lowPriorityLane *ucdqFifo
+	priorityBuildup       uint64
+	priorityLane          *ucdqFifo
+	lowPriorityLane       *ucdqFifo
+	lowPriorityMultiplier uint64
 
 	mu           sync.Mutex
 	staticRenter *Renter
@@ -75,8 +86,9 @@ type ucdqFifo struct {
 // newUploadChunkDistributionQueue will initialize a ucdq for the renter.
 func newUploadChunkDistributionQueue(r *Renter) *uploadChunkDistributionQueue {
 	return &uploadChunkDistributionQueue{
-		priorityLane:    newUCDQfifo(),
-		lowPriorityLane: newUCDQfifo(),
+		priorityLane:          newUCDQfifo(),
+		lowPriorityLane:       newUCDQfifo(),
+		lowPriorityMultiplier: lowPriorityMinThroughputMultiplier,
 
 		staticRenter: r,
 	}
@@ -101,7 +113,7 @@ func (u *ucdqFifo) Pop() *unfinishedUploadChunk {
 
 // callAddUploadChunk will add an unfinished upload chunk to the queue. The
 // chunk will be put into a lane based on whether the memory was requested with
-// priority or not.
+// priority or not and on its priority class.
 func (ucdq *uploadChunkDistributionQueue) callAddUploadChunk(uc *unfinishedUploadChunk) {
 	// We need to hold a lock for the whole process of adding an upload chunk.
 	ucdq.mu.Lock()
@@ -122,7 +134,7 @@ func (ucdq *uploadChunkDistributionQueue) callAddUploadChunk(uc *unfinishedUploa
 	}()
 
 	// If the chunk is not a priority chunk, put it in the low priority lane.
-	if !uc.staticPriority {
+	if !uc.staticPriority && uc.staticPriorityClass == modules.PriorityClassBackground {
 		ucdq.lowPriorityLane.PushBack(uc)
 		return
 	}
@@ -149,7 +161,7 @@ func (ucdq *uploadChunkDistributionQueue) callAddUploadChunk(uc *unfinishedUploa
 	// to justify bumping them.
 	for x := ucdq.lowPriorityLane.Pop(); x != nil; x = ucdq.lowPriorityLane.Pop() {
 		// If there is buildup, add the item.
-		needed := x.staticMemoryNeeded * lowPriorityMinThroughputMultiplier
+		needed := x.staticMemoryNeeded * ucdq.lowPriorityMultiplier
 		if ucdq.priorityBuildup >= needed {
 			ucdq.priorityBuildup -= needed
 			ucdq.priorityLane.PushBack(x)
@@ -166,6 +178,22 @@ func (ucdq *uploadChunkDistributionQueue) callAddUploadChunk(uc *unfinishedUploa
 	}
 }
 
+// callSetPriorityWeights derives the ratio at which low priority chunks are
+// bumped into the priority lane from the priority weights. The low priority
+// lane holds the background class, the priority lane the other classes.
+func (ucdq *uploadChunkDistributionQueue) callSetPriorityWeights(weights modules.PriorityWeights) {
+	multiplier := uint64(lowPriorityMinThroughputMultiplier)
+	if weights.Background > 0 {
+		multiplier = (weights.Interactive + weights.User) / weights.Background
+	}
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	ucdq.mu.Lock()
+	ucdq.lowPriorityMultiplier = multiplier
+	ucdq.mu.Unlock()
+}
+
 // threadedProcessQueue serializes the processing of chunks in the distribution
 // queue. If there are priority chunks, it'll handle those first, and then if
 // there are no chunks in the priority lane it'll handle things in the low
@@ -248,7 +276,7 @@ func (ucdq *uploadChunkDistributionQueue) threadedProcessQueue() {
 			// from the low priority lane, we need to subtract from the priority
 			// buildup as the low priority lane has made progress.
 			ucdq.mu.Lock()
-			needed := nextUC.staticMemoryNeeded * lowPriorityMinThroughputMultiplier
+			needed := nextUC.staticMemoryNeeded * ucdq.lowPriorityMultiplier
 			if ucdq.priorityBuildup < needed {
 				ucdq.priorityBuildup = 0
 			} else {
@@ -0,0 +1,124 @@
+package renter
+
+// uploadchunklocalrepair.go contains the logic for repairing the chunks of
+// files which use a locally repairable erasure code. Instead of downloading
+// MinPieces pieces to reconstruct the whole chunk, only the pieces of the
+// local groups of the missing pieces are fetched from the hosts.
+
+import (
+	"fmt"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+)
+
+// localRepairPieces returns the indices of the pieces which need to be
+// downloaded to repair the missing pieces of a chunk using their local groups.
+// A piece is missing if it is not marked in pieceUsage. If the chunk can't be
+// repaired locally or if a local repair requires as many pieces as a regular
+// repair, false is returned.
+func localRepairPieces(ec modules.ErasureCoder, pieceUsage []bool) ([]int, bool) {
+	lrc, ok := ec.(modules.LocallyRepairableCoder)
+	if !ok {
+		return nil, false
+	}
+	needed := make(map[int]struct{})
+	var indices []int
+	for pieceIndex, available := range pieceUsage {
+		if available {
+			continue
+		}
+		group := lrc.LocalRepairGroup(pieceIndex)
+		if group == nil {
+			return nil, false
+		}
+		for _, j := range group {
+			// All of the pieces in the group need to be available.
+			if !pieceUsage[j] {
+				return nil, false
+			}
+			if _, exists := needed[j]; !exists {
+				needed[j] = struct{}{}
+				indices = append(indices, j)
+			}
+		}
+	}
+	if len(indices) == 0 || len(indices) >= ec.MinPieces() {
+		return nil, false
+	}
+	return indices, true
+}
+
+// managedRepairLocalGroups fetches the pieces at the provided indices from
+// the hosts and uses them to repair the missing pieces of the chunk.
+func (r *Renter) managedRepairLocalGroups(chunk *unfinishedUploadChunk, indices []int) error {
+	lrc, ok := chunk.fileEntry.ErasureCode().(modules.LocallyRepairableCoder)
+	if !ok {
+		return errors.New("erasure code is not locally repairable")
+	}
+	pieces, err := chunk.fileEntry.Pieces(chunk.staticIndex)
+	if err != nil {
+		return errors.AddContext(err, "failed to get pieces of chunk")
+	}
+
+	// The length of the read needs to be a multiple of the segment size.
+	pieceSize := chunk.fileEntry.PieceSize()
+	fetchLength := pieceSize
+	if mod := fetchLength % crypto.SegmentSize; mod != 0 {
+		fetchLength += crypto.SegmentSize - mod
+	}
+
+	// Fetch the pieces in parallel. Every piece is tried on all hosts which
+	// store it until one of them succeeds.
+	logicalChunkData := make([][]byte, lrc.NumPieces())
+	fetchErrs := make([]error, len(indices))
+	var wg sync.WaitGroup
+	for i, pieceIndex := range indices {
+		wg.Add(1)
+		go func(i, pieceIndex int) {
+			defer wg.Done()
+			for _, piece := range pieces[pieceIndex] {
+				w, err := r.staticWorkerPool.callWorker(piece.HostPubKey)
+				if err != nil {
+					fetchErrs[i] = errors.Compose(fetchErrs[i], err)
+					continue
+				}
+				data, err := w.ReadSectorLowPrio(r.tg.StopCtx(), categoryRepairDownload, piece.MerkleRoot, 0, fetchLength)
+				if err != nil {
+					fetchErrs[i] = errors.Compose(fetchErrs[i], err)
+					continue
+				}
+				key := chunk.staticCipherKey.Derive(chunk.staticKeyIndex, uint64(pieceIndex))
+				decryptedPiece, err := key.DecryptBytesInPlace(data, 0)
+				if err != nil {
+					fetchErrs[i] = errors.Compose(fetchErrs[i], err)
+					continue
+				}
+				logicalChunkData[pieceIndex] = decryptedPiece[:pieceSize]
+				fetchErrs[i] = nil
+				return
+			}
+			fetchErrs[i] = errors.Compose(fmt.Errorf("unable to fetch piece %v", pieceIndex), fetchErrs[i])
+		}(i, pieceIndex)
+	}
+	wg.Wait()
+	if err := errors.Compose(fetchErrs...); err != nil {
+		return errors.AddContext(err, "failed to fetch the pieces of the local groups")
+	}
+
+	// Repair the missing pieces.
+	for pieceIndex, available := range chunk.pieceUsage {
+		if available {
+			continue
+		}
+		if err := lrc.RepairLocal(logicalChunkData, pieceIndex); err != nil {
+			return errors.AddContext(err, fmt.Sprintf("failed to repair piece %v", pieceIndex))
+		}
+	}
+	chunk.logicalChunkData = logicalChunkData
+	chunk.padAndEncryptMissingPieces()
+	return nil
+}
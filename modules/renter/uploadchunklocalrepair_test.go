@@ -0,0 +1,78 @@
+package renter
+
+import (
+	"testing"
+
+	"go.sia.tech/siad/modules"
+)
+
+// TestLocalRepairPieces is a unit test for localRepairPieces.
+func TestLocalRepairPieces(t *testing.T) {
+	t.Parallel()
+
+	// 10 data pieces in 3 groups of sizes 4, 3 and 3, followed by 3 local
+	// parity pieces and 2 global parity pieces.
+	lrc, err := modules.NewLRCode(10, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// usage returns a pieceUsage slice with the provided pieces missing.
+	usage := func(missing ...int) []bool {
+		pu := make([]bool, lrc.NumPieces())
+		for i := range pu {
+			pu[i] = true
+		}
+		for _, i := range missing {
+			pu[i] = false
+		}
+		return pu
+	}
+
+	// A missing data piece only requires the rest of its group.
+	indices, ok := localRepairPieces(lrc, usage(5))
+	if !ok || len(indices) != 3 {
+		t.Fatal("unexpected result", indices, ok)
+	}
+	// A missing local parity piece requires the data of its group.
+	indices, ok = localRepairPieces(lrc, usage(10))
+	if !ok || len(indices) != 4 {
+		t.Fatal("unexpected result", indices, ok)
+	}
+	// One missing piece in two groups.
+	indices, ok = localRepairPieces(lrc, usage(0, 9))
+	if !ok || len(indices) != 7 {
+		t.Fatal("unexpected result", indices, ok)
+	}
+	// Two missing pieces in the same group can't be repaired locally.
+	if _, ok := localRepairPieces(lrc, usage(0, 1)); ok {
+		t.Fatal("expected local repair to be impossible")
+	}
+	// Global parity pieces can't be repaired locally.
+	if _, ok := localRepairPieces(lrc, usage(13)); ok {
+		t.Fatal("expected local repair to be impossible")
+	}
+	// A missing piece in every group still requires fewer pieces than a
+	// regular repair.
+	indices, ok = localRepairPieces(lrc, usage(0, 4, 7))
+	if !ok || len(indices) != 10 {
+		t.Fatal("unexpected result", indices, ok)
+	}
+	// If a local repair needs as many pieces as a regular repair, it's not
+	// worth it. With a single group of 4 data pieces and 2 global parity
+	// pieces, a regular repair requires 4 pieces.
+	single, err := modules.NewLRCode(4, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := localRepairPieces(single, []bool{false, true, true, true, true, true, true}); ok {
+		t.Fatal("expected local repair to be skipped")
+	}
+	// Nothing to repair.
+	if _, ok := localRepairPieces(lrc, usage()); ok {
+		t.Fatal("expected no local repair")
+	}
+	// Reed-Solomon codes aren't locally repairable.
+	if _, ok := localRepairPieces(modules.NewRSSubCodeDefault(), make([]bool, modules.RenterDefaultDataPieces+modules.RenterDefaultParityPieces)); ok {
+		t.Fatal("expected RS code to not be locally repairable")
+	}
+}
@@ -186,7 +186,11 @@ func (uch *uploadChunkHeap) reset() (err error) {
 // uploadHeap contains a priority-sorted heap of all the chunks being uploaded
 // to the renter, along with some metadata.
 type uploadHeap struct {
-	heap uploadChunkHeap
+	heap uploadPriorityHeap
+
+	// finishedChunks is the number of chunks of every priority class which
+	// were removed from the repairingChunks map since the renter started.
+	finishedChunks [modules.NumPriorityClasses]uint64
 
 	// heapChunks is a map containing all the chunks that are currently in the
 	// heap. Chunks are added and removed from the map when chunks are pushed
@@ -268,10 +272,33 @@ func (uh *uploadHeap) managedMarkRepairDone(uuc *unfinishedUploadChunk) {
 	existingUUC, ok := uh.repairingChunks[uuc.id]
 	if ok && existingUUC == uuc {
 		delete(uh.repairingChunks, uuc.id)
+		uh.finishedChunks[uuc.staticPriorityClass]++
 	}
 	//	build.Critical("Chunk is not in the repair map, this means it was removed prematurely or was never added")
 }
 
+// managedPriorityClasses returns the status of every priority class.
+func (uh *uploadHeap) managedPriorityClasses() []modules.PriorityClassStatus {
+	uh.mu.Lock()
+	defer uh.mu.Unlock()
+	var active [modules.NumPriorityClasses]uint64
+	for _, chunk := range uh.repairingChunks {
+		active[chunk.staticPriorityClass]++
+	}
+	var classes []modules.PriorityClassStatus
+	for _, pc := range modules.PriorityClasses() {
+		classes = append(classes, modules.PriorityClassStatus{
+			Class:          pc,
+			Weight:         uh.heap.scheduler.weights.Weight(pc),
+			QueuedChunks:   uint64(uh.heap.heaps[pc].Len()),
+			ActiveChunks:   active[pc],
+			FinishedChunks: uh.finishedChunks[pc],
+			ScheduledBytes: uh.heap.scheduler.scheduledBytes[pc],
+		})
+	}
+	return classes
+}
+
 // managedNumStuckChunks returns total number of stuck chunks in the heap and
 // the number of stuck chunks that were added at random as opposed to being
 // added due to a recently successful file repair
@@ -342,11 +369,11 @@ func (uh *uploadHeap) managedPush(uuc *unfinishedUploadChunk, ct chunkType) bool
 	// Add the chunk to the heap
 	if canAddStuckChunk {
 		uh.stuckHeapChunks[uuc.id] = uuc
-		heap.Push(&uh.heap, uuc)
+		uh.heap.push(uuc)
 		return true
 	} else if canAddUnstuckChunk {
 		uh.unstuckHeapChunks[uuc.id] = uuc
-		heap.Push(&uh.heap, uuc)
+		uh.heap.push(uuc)
 		return true
 	} else if ct == chunkTypeStreamChunk && !existsRepairing {
 		// Make sure the chunk is removed from unstuck and stuck maps
@@ -358,8 +385,10 @@ func (uh *uploadHeap) managedPush(uuc *unfinishedUploadChunk, ct chunkType) bool
 			uh.heap.removeByID(uuc)
 		}
 
-		// Add to the repair map
+		// Add to the repair map and charge the chunk's priority class since
+		// it is sent to the workers right away.
 		uh.repairingChunks[uuc.id] = uuc
+		uh.heap.scheduler.charge(uuc.staticPriorityClass, uuc.staticMemoryNeeded)
 		return true
 	}
 	return false
@@ -368,8 +397,8 @@ func (uh *uploadHeap) managedPush(uuc *unfinishedUploadChunk, ct chunkType) bool
 // managedPop will pull a chunk off of the upload heap and return it.
 func (uh *uploadHeap) managedPop() (uc *unfinishedUploadChunk) {
 	uh.mu.Lock()
-	if len(uh.heap) > 0 {
-		uc = heap.Pop(&uh.heap).(*unfinishedUploadChunk)
+	uc = uh.heap.pop()
+	if uc != nil {
 		delete(uh.unstuckHeapChunks, uc.id)
 		delete(uh.stuckHeapChunks, uc.id)
 		if _, exists := uh.repairingChunks[uc.id]; exists {
@@ -510,7 +539,7 @@ func (r *Renter) ResumeRepairsAndUploads() error {
 }
 
 // managedBuildUnfinishedChunk will pull out a single unfinished chunk of a file.
-func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIndex uint64, hosts map[string]struct{}, hostPublicKeys map[string]types.SiaPublicKey, priority bool, offline, goodForRenew map[string]bool, mm *memoryManager) (*unfinishedUploadChunk, error) {
+func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIndex uint64, hosts map[string]struct{}, hostPublicKeys map[string]types.SiaPublicKey, priority bool, class modules.PriorityClass, offline, goodForRenew map[string]bool, mm *memoryManager) (*unfinishedUploadChunk, error) {
 	// Copy entry
 	entryCopy := entry.Copy()
 	stuck, err := entry.StuckChunkByIndex(chunkIndex)
@@ -518,6 +547,12 @@ func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIn
 		r.log.Println("WARN: unable to get 'stuck' status:", err)
 		return nil, errors.AddContext(err, "unable to get 'stuck' status")
 	}
+	// Deduplicated chunks are not encrypted with the file's masterkey.
+	key, keyIndex, err := entry.ChunkCipherKey(chunkIndex)
+	if err != nil {
+		r.log.Println("WARN: unable to get cipher key of chunk:", err)
+		return nil, errors.AddContext(err, "unable to get cipher key of chunk")
+	}
 	_, err = os.Stat(entryCopy.LocalPath())
 	onDisk := err == nil
 	uuc := &unfinishedUploadChunk{
@@ -533,8 +568,12 @@ func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIn
 		onDisk:         onDisk,
 		staticPriority: priority,
 
-		staticIndex:   chunkIndex,
-		staticSiaPath: entryCopy.SiaFilePath(),
+		staticPriorityClass: class,
+
+		staticCipherKey: key,
+		staticIndex:     chunkIndex,
+		staticKeyIndex:  keyIndex,
+		staticSiaPath:   entryCopy.SiaFilePath(),
 
 		staticMemoryManager: mm,
 
@@ -544,7 +583,7 @@ func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIn
 		// TODO: Currently we request memory for all of the pieces as well
 		// as the minimum pieces, but we perhaps don't need to request all
 		// of that.
-		staticMemoryNeeded:  entry.PieceSize()*uint64(entry.ErasureCode().NumPieces()+entry.ErasureCode().MinPieces()) + uint64(entry.ErasureCode().NumPieces())*entry.MasterKey().Type().Overhead(),
+		staticMemoryNeeded:  entry.PieceSize()*uint64(entry.ErasureCode().NumPieces()+entry.ErasureCode().DataPieces()) + uint64(entry.ErasureCode().NumPieces())*entry.MasterKey().Type().Overhead(),
 		staticMinimumPieces: entry.ErasureCode().MinPieces(),
 		staticPiecesNeeded:  entry.ErasureCode().NumPieces(),
 		stuck:               stuck,
@@ -624,7 +663,7 @@ func (r *Renter) managedBuildUnfinishedChunk(entry *filesystem.FileNode, chunkIn
 // they are done and so cannot share a SiaFileSetEntry as the first chunk to
 // finish would then close the Entry and consequentially impact the remaining
 // chunks.
-func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts map[string]struct{}, target repairTarget, offline, goodForRenew map[string]bool, mm *memoryManager) []*unfinishedUploadChunk {
+func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts map[string]struct{}, target repairTarget, class modules.PriorityClass, offline, goodForRenew map[string]bool, mm *memoryManager) []*unfinishedUploadChunk {
 	// If we don't have enough workers for the file, don't repair it right now.
 	minPieces := entry.ErasureCode().MinPieces()
 	r.staticWorkerPool.mu.RLock()
@@ -670,6 +709,14 @@ func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts
 		return nil
 	}
 
+	// Get the policy of the file's directory to determine which chunks need
+	// repair.
+	policy, err := r.managedFilePolicy(r.staticFileSystem.FileSiaPath(entry))
+	if err != nil {
+		r.log.Println("WARN: unable to get policy of file, using defaults:", err)
+		policy = modules.DirPolicy{}
+	}
+
 	// Build a map of host public keys. We assume that all entrys are the same.
 	pks := make(map[string]types.SiaPublicKey)
 	for _, pk := range entry.HostPublicKeys() {
@@ -685,7 +732,7 @@ func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts
 		}
 
 		// Create unfinishedUploadChunk
-		chunk, err := r.managedBuildUnfinishedChunk(entry, uint64(index), hosts, pks, memoryPriorityLow, offline, goodForRenew, mm)
+		chunk, err := r.managedBuildUnfinishedChunk(entry, uint64(index), hosts, pks, memoryPriorityLow, class, offline, goodForRenew, mm)
 		if err != nil {
 			r.log.Debugln("Error when building an unfinished chunk:", err)
 			continue
@@ -710,7 +757,7 @@ func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts
 		// it is likely that we can not read the file in which case it can not
 		// be used for repair.
 		repairable := chunk.health <= 1 || chunk.onDisk
-		needsRepair := modules.NeedsRepair(chunk.health)
+		needsRepair := policy.NeedsRepair(chunk.health)
 
 		if r.deps.Disrupt("AddUnrepairableChunks") && needsRepair {
 			incompleteChunks = append(incompleteChunks, chunk)
@@ -806,13 +853,23 @@ func (r *Renter) managedAddChunksToHeap(hosts map[string]struct{}) (*uniqueRefre
 			return siaPaths, nil
 		}
 
-		// If the directory that was just popped does not need to be repaired then
-		// return
+		// If the directory that was just popped does not need to be repaired
+		// according to any directory's policy then return. If it only doesn't
+		// need to be repaired according to its own policy, continue with the
+		// next directory.
 		heapHealth, _ := dir.managedHeapHealth()
-		if !modules.NeedsRepair(heapHealth) {
+		if heapHealth < r.managedMinRepairThreshold() {
 			r.repairLog.Debugln("no more chunks added to the upload heap because directory popped is healthy")
 			return siaPaths, nil
 		}
+		policy, err := r.managedDirPolicy(dir.staticSiaPath)
+		if err != nil {
+			r.repairLog.Println("WARN: unable to get policy of directory:", err)
+			continue
+		}
+		if !policy.NeedsRepair(heapHealth) {
+			continue
+		}
 
 		// Add chunks from the directory to the uploadHeap.
 		r.managedBuildChunkHeap(dir.staticSiaPath, hosts, targetUnstuckChunks, offline, goodForRenew)
@@ -851,7 +908,7 @@ func (r *Renter) managedBuildAndPushRandomChunk(siaPath modules.SiaPath, hosts m
 	offline, goodForRenew, _ := r.managedContractUtilityMaps()
 
 	// Build the unfinished stuck chunks from the file
-	unfinishedUploadChunks := r.managedBuildUnfinishedChunks(file, hosts, target, offline, goodForRenew, mm)
+	unfinishedUploadChunks := r.managedBuildUnfinishedChunks(file, hosts, target, modules.PriorityClassBackground, offline, goodForRenew, mm)
 
 	// Sanity check that there are stuck chunks
 	if len(unfinishedUploadChunks) == 0 {
@@ -889,7 +946,7 @@ func (r *Renter) managedBuildAndPushRandomChunk(siaPath modules.SiaPath, hosts m
 //
 // NOTE: the files submitted to this function should all be from the same
 // directory
-func (r *Renter) callBuildAndPushChunks(files []*filesystem.FileNode, hosts map[string]struct{}, target repairTarget, offline, goodForRenew map[string]bool) {
+func (r *Renter) callBuildAndPushChunks(files []*filesystem.FileNode, hosts map[string]struct{}, target repairTarget, class modules.PriorityClass, offline, goodForRenew map[string]bool) {
 	// Sanity check that at least one file was provided
 	if len(files) == 0 {
 		build.Critical("callBuildAndPushChunks called without providing any files")
@@ -939,7 +996,7 @@ func (r *Renter) callBuildAndPushChunks(files []*filesystem.FileNode, hosts map[
 		}
 
 		// Build unfinished chunks from file and add them to the temp heap.
-		unfinishedUploadChunks := r.managedBuildUnfinishedChunks(file, hosts, target, offline, goodForRenew, r.repairMemoryManager)
+		unfinishedUploadChunks := r.managedBuildUnfinishedChunks(file, hosts, target, class, offline, goodForRenew, r.repairMemoryManager)
 		for i := 0; i < len(unfinishedUploadChunks); i++ {
 			chunk := unfinishedUploadChunks[i]
 			// Skip adding this chunk if it is already in the upload heap.
@@ -1139,6 +1196,12 @@ func (r *Renter) managedBuildChunkHeap(dirSiaPath modules.SiaPath, hosts map[str
 		r.log.Println("WARN: could not read directory:", err)
 		return
 	}
+	// Get the policy of the directory to determine which files need repair.
+	policy, err := r.managedDirPolicy(dirSiaPath)
+	if err != nil {
+		r.log.Println("WARN: could not get policy of directory:", err)
+		return
+	}
 	// Build files from fileinfos
 	var files []*filesystem.FileNode
 	for _, fi := range fileinfos {
@@ -1177,7 +1240,7 @@ func (r *Renter) managedBuildChunkHeap(dirSiaPath modules.SiaPath, hosts map[str
 		// information updated by bubble this cached health is accurate enough
 		// to use in order to determine if a file has any chunks that need
 		// repair
-		ignore := file.NumChunks() == file.NumStuckChunks() || !modules.NeedsRepair(file.Metadata().CachedHealth)
+		ignore := file.NumChunks() == file.NumStuckChunks() || !policy.NeedsRepair(file.Metadata().CachedHealth)
 		if target == targetUnstuckChunks && ignore {
 			err = file.Close()
 			if err != nil {
@@ -1233,12 +1296,12 @@ func (r *Renter) managedBuildChunkHeap(dirSiaPath modules.SiaPath, hosts map[str
 	switch target {
 	case targetBackupChunks:
 		r.log.Debugln("Attempting to add backup chunks to heap")
-		r.callBuildAndPushChunks(files, hosts, target, offline, goodForRenew)
+		r.callBuildAndPushChunks(files, hosts, target, modules.PriorityClassBackground, offline, goodForRenew)
 	case targetStuckChunks:
 		r.log.Println("stuck repair target used incorrectly")
 	case targetUnstuckChunks:
 		r.log.Debugln("Attempting to add chunks to heap")
-		r.callBuildAndPushChunks(files, hosts, target, offline, goodForRenew)
+		r.callBuildAndPushChunks(files, hosts, target, modules.PriorityClassBackground, offline, goodForRenew)
 	default:
 		r.log.Println("WARN: repair target not recognized", target)
 	}
@@ -1353,7 +1416,7 @@ func (r *Renter) managedRepairLoop() error {
 	// directory heap is in good health, ie does not need to be repaired, and
 	// there are no more chunks that could be added to the heap.
 	dirHeapHealth, _ := r.directoryHeap.managedPeekHealth()
-	smallRepair := !modules.NeedsRepair(dirHeapHealth)
+	smallRepair := dirHeapHealth < r.managedMinRepairThreshold()
 
 	// Limit the amount of time spent in each iteration of the repair loop so
 	// that changes to the directory heap take effect sooner rather than later.
@@ -1558,7 +1621,7 @@ func (r *Renter) threadedUploadAndRepair() {
 		// heap is empty, there is no work to do and the thread should block
 		// until there is work to do.
 		dirHeapHealth, _ := r.directoryHeap.managedPeekHealth()
-		if r.uploadHeap.managedLen() == 0 && !modules.NeedsRepair(dirHeapHealth) {
+		if r.uploadHeap.managedLen() == 0 && dirHeapHealth < r.managedMinRepairThreshold() {
 			// TODO: This has a tiny window where it might be dumping out chunks
 			// that need health, if the upload call is appending to the
 			// directory heap because there is a new upload.
@@ -1,6 +1,7 @@
 package renter
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"sync"
@@ -137,23 +138,64 @@ func (r *Renter) UploadStreamFromReader(up modules.FileUploadParams, reader io.R
 // SiaFile for the upload.
 func (r *Renter) managedInitUploadStream(up modules.FileUploadParams) (*filesystem.FileNode, error) {
 	siaPath, ec, force, repair, cipherType := up.SiaPath, up.ErasureCode, up.Force, up.Repair, up.CipherType
-	// Check if ec was set. If not use defaults.
-	var err error
+	// Check if ec was set. If not use the defaults of the directory's policy.
+	policy, err := r.managedFilePolicy(siaPath)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to get policy of directory")
+	}
 	if ec == nil && !repair {
-		ec = modules.NewRSSubCodeDefault()
+		ec, err = policy.ErasureCoder()
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to create erasure coder from policy")
+		}
 		up.ErasureCode = ec
 	} else if ec != nil && repair {
 		return nil, errors.New("can't provide erasure code settings when doing repairs")
 	}
+	if cipherType == crypto.TypeInvalid {
+		cipherType = policy.Cipher()
+	}
 
 	// Make sure that force and repair aren't both set.
 	if force && repair {
 		return nil, errors.New("'force' and 'repair' can't both be set")
 	}
+	// Deduplicated chunks can't be repaired from a stream since the chunk's
+	// key is derived from its content and they can't be combined into partial
+	// chunks.
+	if up.Dedup && repair {
+		return nil, errDedupRepair
+	}
+	if up.Dedup {
+		up.DisablePartialChunk = true
+	}
+	if up.Dedup && up.Compression != modules.CompressionNone {
+		return nil, errDedupCompression
+	}
+	// Repairs use the compression of the existing file.
+	if up.Compression != modules.CompressionNone && repair {
+		return nil, errCompressionRepair
+	}
+	if up.Compression.String() == "" {
+		return nil, modules.ErrUnknownCompressionType
+	}
+
+	// Make sure that none of the directory's quotas is reached yet. The size
+	// of the upload isn't known upfront so it is charged once all the data
+	// was read.
+	if !repair {
+		dirSiaPath, err := siaPath.Dir()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.managedChargeQuotas(dirSiaPath, quotaCharge{}, true); err != nil {
+			return nil, err
+		}
+	}
 
-	// Delete existing file if overwrite flag is set. Ignore ErrUnknownPath.
+	// Archive existing file if overwrite flag is set. Ignore ErrUnknownPath.
 	if force {
-		err := r.DeleteFile(siaPath)
+		err := r.managedArchiveFile(siaPath)
 		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
 			return nil, err
 		}
@@ -164,6 +206,9 @@ func (r *Renter) managedInitUploadStream(up modules.FileUploadParams) (*filesyst
 		if err != nil {
 			return nil, err
 		}
+		if entry.Dedup().Deduplicated {
+			return nil, errors.Compose(errDedupRepair, entry.Close())
+		}
 		return entry, nil
 	}
 	// Check that we have contracts to upload to. We need at least data +
@@ -188,7 +233,23 @@ func (r *Renter) managedInitUploadStream(up modules.FileUploadParams) (*filesyst
 	if err != nil {
 		return nil, err
 	}
-	return r.staticFileSystem.OpenSiaFile(siaPath)
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return nil, err
+	}
+	// Mark the file as compressed before adding any data to it.
+	if up.Compression != modules.CompressionNone {
+		if err := entry.SetCompressionType(up.Compression); err != nil {
+			return nil, errors.Compose(err, entry.Close())
+		}
+	}
+	// Mark the file as deduplicated before adding any data to it.
+	if up.Dedup {
+		if err := entry.SetDeduplicated(); err != nil {
+			return nil, errors.Compose(err, entry.Close())
+		}
+	}
+	return entry, nil
 }
 
 // callUploadStreamFromReader reads from the provided reader until io.EOF is
@@ -218,10 +279,29 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 		}
 	}()
 
+	// Compress the data of compressed files. The compression index is set
+	// once all of the data was read.
+	var cr *compressReader
+	if compression := fileNode.Compression(); compression.Compressed() {
+		cr = newCompressReader(reader, compression.Type, compression.FrameSize)
+		reader = cr
+	}
+
 	// Check if stream has at least one byte. No need to upload empty data.
 	peek := []byte{0}
 	_, err = io.ReadFull(reader, peek)
 	if errors.Contains(err, io.EOF) || errors.Contains(err, io.ErrUnexpectedEOF) {
+		if cr != nil && !up.Repair {
+			index, size := cr.Index()
+			if err := fileNode.SetCompressionIndex(index, size); err != nil {
+				return nil, errors.AddContext(err, "failed to set compression index")
+			}
+		}
+		if up.Dedup {
+			if err := fileNode.SetDedupOffsets([]uint64{0}); err != nil {
+				return nil, errors.AddContext(err, "failed to set dedup offsets")
+			}
+		}
 		return fileNode, nil
 	} else if err != nil {
 		return nil, err
@@ -236,6 +316,13 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 	// Get the most recent workers.
 	hosts := r.managedRefreshHostsAndWorkers()
 
+	// New uploads are interactive while repairs from a stream were requested
+	// by the user.
+	class := modules.PriorityClassInteractive
+	if up.Repair {
+		class = modules.PriorityClassUser
+	}
+
 	// Check if we currently have enough workers for the specified redundancy.
 	minWorkers := fileNode.ErasureCode().MinPieces()
 	r.staticWorkerPool.mu.RLock()
@@ -249,6 +336,12 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 	// shards. A shard will signal completion after reading the input but
 	// before the upload is done.
 	var chunks []*unfinishedUploadChunk
+	var ds *dedupStream
+	var chunker *cdcChunker
+	if up.Dedup {
+		ds = newDedupStream()
+		chunker = newCDCChunker(io.MultiReader(bytes.NewReader(peek), reader), fileNode.ChunkSize())
+	}
 	for chunkIndex := uint64(0); ; chunkIndex++ {
 		// Disrupt the upload by closing the reader and simulating losing
 		// connectivity during the upload.
@@ -264,15 +357,43 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 			return nil, err
 		}
 
+		// Deduplicated chunks contain a single block which is read upfront to
+		// compute its id. If the block was already uploaded, only the file
+		// size is adjusted.
+		source, shardPeek := reader, peek
+		var more bool
+		if ds != nil {
+			var data []byte
+			data, more, err = chunker.Next()
+			if err != nil {
+				return nil, errors.AddContext(err, "unable to read block from stream")
+			}
+			upload, err := r.managedDedupStreamChunk(fileNode, ds, chunkIndex, data)
+			if err != nil {
+				return nil, err
+			}
+			if !upload {
+				adjustedSize := fileNode.Size() - fileNode.ChunkSize() + uint64(len(data))
+				if err := fileNode.SetFileSize(adjustedSize); err != nil {
+					return nil, errors.AddContext(err, "failed to adjust FileSize")
+				}
+				if !more {
+					break
+				}
+				continue
+			}
+			source, shardPeek = bytes.NewReader(data), nil
+		}
+
 		// Start the chunk upload.
 		offline, goodForRenew, _ := r.managedContractUtilityMaps()
-		uuc, err := r.managedBuildUnfinishedChunk(fileNode, chunkIndex, hosts, pks, memoryPriorityHigh, offline, goodForRenew, r.userUploadMemoryManager)
+		uuc, err := r.managedBuildUnfinishedChunk(fileNode, chunkIndex, hosts, pks, memoryPriorityHigh, class, offline, goodForRenew, r.userUploadMemoryManager)
 		if err != nil {
 			return nil, errors.AddContext(err, "unable to fetch chunk for stream")
 		}
 
 		// Create a new shard set it to be the source reader of the chunk.
-		ss := NewStreamShard(reader, peek)
+		ss := NewStreamShard(source, shardPeek)
 		uuc.sourceReader = ss
 
 		// Check if the chunk needs any work or if we can skip it.
@@ -308,6 +429,17 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 		case <-ss.signalChan:
 		}
 
+		// The shard of a deduplicated chunk only contains a single block.
+		if ds != nil {
+			if _, err := ss.Result(); err != nil && !errors.Contains(err, io.EOF) {
+				return nil, err
+			}
+			if !more {
+				break
+			}
+			continue
+		}
+
 		// If an io.EOF error occurred or less than chunkSize was read, we are
 		// done. Otherwise we report the error.
 		if _, err := ss.Result(); errors.Contains(err, io.EOF) {
@@ -341,6 +473,43 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 		}
 	}
 
+	// Set the compression index now that all of the data was read. Repairs
+	// don't change the compressed data so the index stays the same.
+	if cr != nil && !up.Repair {
+		index, size := cr.Index()
+		if err := fileNode.SetCompressionIndex(index, size); err != nil {
+			return nil, errors.AddContext(err, "failed to set compression index")
+		}
+	}
+	if ds != nil {
+		if err := fileNode.SetDedupOffsets(ds.offsets); err != nil {
+			return nil, errors.AddContext(err, "failed to set dedup offsets")
+		}
+	}
+
+	// Count the upload against the quotas of the file's directories.
+	if !up.Repair {
+		dirSiaPath, err := up.SiaPath.Dir()
+		if err != nil {
+			return nil, err
+		}
+		charge := r.managedUploadQuotaCharge(fileNode.Size(), fileNode.ErasureCode())
+		if err := r.managedChargeQuotas(dirSiaPath, charge, false); err != nil {
+			r.log.Println("WARN: failed to charge quotas for upload:", err)
+		}
+	}
+
+	// Add the uploaded chunks to the dedup index and keep it updated until the
+	// chunks are fully uploaded.
+	if ds != nil {
+		if err := r.managedRegisterDedupStream(fileNode, ds); err != nil {
+			return nil, errors.AddContext(err, "failed to register deduplicated chunks")
+		}
+		if len(chunks) > 0 {
+			go r.threadedUpdateDedupStream(fileNode.Copy(), ds, chunks)
+		}
+	}
+
 	// Disrupt to force an error and ensure the fileNode is being closed
 	// correctly.
 	if r.deps.Disrupt("failUploadStreamFromReader") {
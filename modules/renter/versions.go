@@ -0,0 +1,357 @@
+package renter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+)
+
+// File Versions Overview:
+// If MaxFileVersions is set in the renter's settings, overwriting a file with
+// the Force flag doesn't delete the existing siafile. Instead it is moved to
+// VersionsFolder/<dir>/<name>.<version> where <dir> is the directory of the
+// file and <version> is a number which starts at 1 and grows with every
+// overwrite. Since versions are files, the versions of a file never end up in
+// the versions directory of a directory with the same name. The versions are regular siafiles
+// which means that they are repaired like every other file and their sectors
+// stay in the renter's contracts. Once a file has more than MaxFileVersions
+// versions, the oldest ones are deleted.
+//
+// Deleting or renaming a file or directory also deletes or renames the
+// versions of the files within it.
+
+var (
+	// errUnknownFileVersion is returned when trying to restore a version of a
+	// file which doesn't exist.
+	errUnknownFileVersion = errors.New("unknown file version")
+)
+
+// FileVersions returns the prior versions of a file sorted from oldest to
+// newest.
+func (r *Renter) FileVersions(siaPath modules.SiaPath) ([]modules.FileVersion, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+	return r.managedFileVersions(siaPath)
+}
+
+// RestoreFileVersion replaces a file with one of its prior versions. The
+// replaced file is kept as the newest version of the file.
+func (r *Renter) RestoreFileVersion(siaPath modules.SiaPath, version uint64) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+
+	// Make sure the version exists.
+	versionSiaPath, err := fileVersionSiaPath(siaPath, version)
+	if err != nil {
+		return err
+	}
+	exists, err := r.staticFileSystem.FileExists(versionSiaPath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errUnknownFileVersion
+	}
+
+	// Keep the current file as a new version. This happens even if versioning
+	// is disabled to avoid losing data.
+	_, err = r.managedAddVersion(siaPath)
+	if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+		return errors.AddContext(err, "unable to keep current file as version")
+	}
+	// Move the version in its place.
+	err = r.managedRenameFile(versionSiaPath, siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to restore version")
+	}
+	return r.managedPruneVersions(siaPath)
+}
+
+// managedArchiveFile removes a file which is about to be overwritten. If
+// versioning is enabled, the file is kept as a prior version. Otherwise it is
+// deleted.
+func (r *Renter) managedArchiveFile(siaPath modules.SiaPath) error {
+	id := r.mu.RLock()
+	maxVersions := r.persist.MaxFileVersions
+	r.mu.RUnlock(id)
+	if maxVersions == 0 || isVersionSiaPath(siaPath) {
+		return r.managedDeleteFile(siaPath)
+	}
+
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+	_, err := r.managedAddVersion(siaPath)
+	if err != nil {
+		return err
+	}
+	return r.managedPruneVersions(siaPath)
+}
+
+// managedReplaceFile renames a file to the siapath of another file and
+// archives the replaced file. The replaced file is moved to the versions
+// folder first, even if versioning is disabled, so it can be moved back if the
+// rename fails.
+func (r *Renter) managedReplaceFile(siaPath, replacedSiaPath modules.SiaPath) error {
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+
+	versionSiaPath, err := r.managedAddVersion(replacedSiaPath)
+	if errors.Contains(err, filesystem.ErrNotExist) {
+		return r.managedRenameFile(siaPath, replacedSiaPath)
+	} else if err != nil {
+		return errors.AddContext(err, "unable to archive replaced file")
+	}
+	err = r.managedRenameFile(siaPath, replacedSiaPath)
+	if err != nil {
+		restoreErr := r.managedRenameFile(versionSiaPath, replacedSiaPath)
+		return errors.Compose(err, errors.AddContext(restoreErr, "unable to restore replaced file"))
+	}
+
+	// Drop the replaced file if it isn't kept as a version.
+	id := r.mu.RLock()
+	maxVersions := r.persist.MaxFileVersions
+	r.mu.RUnlock(id)
+	if maxVersions == 0 || isVersionSiaPath(replacedSiaPath) {
+		return r.managedDeleteFile(versionSiaPath)
+	}
+	return r.managedPruneVersions(replacedSiaPath)
+}
+
+// managedAddVersion moves a file to the versions folder as its newest version
+// and returns the path of the version. The caller must hold versionsMu.
+func (r *Renter) managedAddVersion(siaPath modules.SiaPath) (modules.SiaPath, error) {
+	exists, err := r.staticFileSystem.FileExists(siaPath)
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	if !exists {
+		return modules.SiaPath{}, filesystem.ErrNotExist
+	}
+	versions, err := r.managedFileVersions(siaPath)
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	next := uint64(1)
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+	versionSiaPath, err := fileVersionSiaPath(siaPath, next)
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	return versionSiaPath, r.managedRenameFile(siaPath, versionSiaPath)
+}
+
+// managedFileVersions returns the versions of a file sorted from oldest to
+// newest. The caller must hold versionsMu.
+func (r *Renter) managedFileVersions(siaPath modules.SiaPath) ([]modules.FileVersion, error) {
+	dir, err := siaPath.Dir()
+	if err != nil {
+		return nil, err
+	}
+	versionsSiaPath, err := versionsDirSiaPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := r.staticFileSystem.DirExists(versionsSiaPath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+	var mu sync.Mutex
+	var versions []modules.FileVersion
+	err = r.staticFileSystem.CachedList(versionsSiaPath, false, func(fi modules.FileInfo) {
+		version, ok := parseFileVersion(siaPath.Name(), fi.SiaPath.Name())
+		if !ok {
+			return // not a version of the file
+		}
+		mu.Lock()
+		versions = append(versions, modules.FileVersion{
+			Version:          version,
+			Filesize:         fi.Filesize,
+			ModificationTime: fi.ModificationTime,
+			Redundancy:       fi.Redundancy,
+			SiaPath:          fi.SiaPath,
+		})
+		mu.Unlock()
+	}, func(modules.DirectoryInfo) {})
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to list versions")
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+// managedPruneVersions deletes the oldest versions of a file until no more
+// than MaxFileVersions versions are left. Nothing is deleted if versioning is
+// disabled. The caller must hold versionsMu.
+func (r *Renter) managedPruneVersions(siaPath modules.SiaPath) error {
+	id := r.mu.RLock()
+	maxVersions := r.persist.MaxFileVersions
+	r.mu.RUnlock(id)
+	if maxVersions == 0 {
+		return nil
+	}
+	versions, err := r.managedFileVersions(siaPath)
+	if err != nil {
+		return err
+	}
+	for uint64(len(versions)) > maxVersions {
+		err = r.managedDeleteFile(versions[0].SiaPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to delete old version")
+		}
+		versions = versions[1:]
+	}
+	return nil
+}
+
+// managedDeleteFileVersions deletes the versions of a file.
+func (r *Renter) managedDeleteFileVersions(siaPath modules.SiaPath) error {
+	if isVersionSiaPath(siaPath) {
+		return nil
+	}
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+	versions, err := r.managedFileVersions(siaPath)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		err = r.managedDeleteFile(v.SiaPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to delete version")
+		}
+	}
+	return nil
+}
+
+// managedDeleteDirVersions deletes the versions of the files within a
+// directory.
+func (r *Renter) managedDeleteDirVersions(siaPath modules.SiaPath) error {
+	if isVersionSiaPath(siaPath) {
+		return nil
+	}
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+	versionsSiaPath, err := versionsDirSiaPath(siaPath)
+	if err != nil {
+		return err
+	}
+	exists, err := r.staticFileSystem.DirExists(versionsSiaPath)
+	if err != nil || !exists {
+		return err
+	}
+	return errors.AddContext(r.managedDeleteDir(versionsSiaPath), "unable to delete versions")
+}
+
+// managedRenameFileVersions moves the versions of a file to match the new path
+// of the file.
+func (r *Renter) managedRenameFileVersions(oldSiaPath, newSiaPath modules.SiaPath) error {
+	if isVersionSiaPath(oldSiaPath) || isVersionSiaPath(newSiaPath) {
+		return nil
+	}
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+	versions, err := r.managedFileVersions(oldSiaPath)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		versionSiaPath, err := fileVersionSiaPath(newSiaPath, v.Version)
+		if err != nil {
+			return err
+		}
+		err = r.managedRenameFile(v.SiaPath, versionSiaPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to move version")
+		}
+	}
+	return nil
+}
+
+// managedRenameDirVersions moves the versions of the files within a directory
+// to match the new path of the directory.
+func (r *Renter) managedRenameDirVersions(oldSiaPath, newSiaPath modules.SiaPath) error {
+	if isVersionSiaPath(oldSiaPath) || isVersionSiaPath(newSiaPath) {
+		return nil
+	}
+	r.versionsMu.Lock()
+	defer r.versionsMu.Unlock()
+	oldVersionsSiaPath, err := versionsDirSiaPath(oldSiaPath)
+	if err != nil {
+		return err
+	}
+	newVersionsSiaPath, err := versionsDirSiaPath(newSiaPath)
+	if err != nil {
+		return err
+	}
+	exists, err := r.staticFileSystem.DirExists(oldVersionsSiaPath)
+	if err != nil || !exists {
+		return err
+	}
+	return errors.AddContext(r.staticFileSystem.RenameDir(oldVersionsSiaPath, newVersionsSiaPath), "unable to move versions")
+}
+
+// versionsDirSiaPath returns the path of the directory which contains the
+// versions of the files within the directory at siaPath.
+func versionsDirSiaPath(siaPath modules.SiaPath) (modules.SiaPath, error) {
+	if siaPath.IsRoot() {
+		return modules.VersionsFolder, nil
+	}
+	return modules.VersionsFolder.Join(siaPath.String())
+}
+
+// fileVersionSiaPath returns the path of a specific version of a file. The
+// versions of a file are stored next to each other as <name>.<version> in the
+// versions directory of the file's parent. Since the versions are files, they
+// can't collide with the versions directory of a directory with the same name
+// as the file.
+func fileVersionSiaPath(siaPath modules.SiaPath, version uint64) (modules.SiaPath, error) {
+	dir, err := siaPath.Dir()
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	versionsSiaPath, err := versionsDirSiaPath(dir)
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	return versionsSiaPath.Join(siaPath.Name() + "." + strconv.FormatUint(version, 10))
+}
+
+// parseFileVersion returns the version of the file with the provided name if
+// versionName is the name of one of its versions.
+func parseFileVersion(name, versionName string) (uint64, bool) {
+	if !strings.HasPrefix(versionName, name+".") {
+		return 0, false
+	}
+	suffix := strings.TrimPrefix(versionName, name+".")
+	version, err := strconv.ParseUint(suffix, 10, 64)
+	if err != nil || strconv.FormatUint(version, 10) != suffix {
+		return 0, false
+	}
+	return version, true
+}
+
+// isVersionSiaPath returns whether a path is within the versions folder.
+func isVersionSiaPath(siaPath modules.SiaPath) bool {
+	return siaPath.Equals(modules.VersionsFolder) || strings.HasPrefix(siaPath.String(), modules.VersionsFolder.String()+"/")
+}
@@ -0,0 +1,272 @@
+package renter
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem/siafile"
+	"go.sia.tech/siad/siatest/dependencies"
+)
+
+// TestFileVersions tests keeping, restoring, renaming and deleting the prior
+// versions of a file.
+func TestFileVersions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+
+	// Keep up to 2 versions.
+	id := r.mu.Lock()
+	r.persist.MaxFileVersions = 2
+	r.mu.Unlock(id)
+
+	// createFile creates a file at the given path and returns its UID.
+	createFile := func(sp modules.SiaPath) siafile.SiafileUID {
+		entry, err := r.createRenterTestFile(sp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid := entry.UID()
+		if err := entry.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	// fileUID returns the UID of the file at the given path.
+	fileUID := func(sp modules.SiaPath) siafile.SiafileUID {
+		entry, err := r.staticFileSystem.OpenSiaFile(sp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid := entry.UID()
+		if err := entry.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return uid
+	}
+	// checkVersions checks that the file has the expected versions.
+	checkVersions := func(sp modules.SiaPath, expected ...uint64) {
+		versions, err := r.FileVersions(sp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != len(expected) {
+			t.Fatalf("expected %v versions but got %v", len(expected), len(versions))
+		}
+		for i, v := range versions {
+			if v.Version != expected[i] {
+				t.Fatalf("expected version %v but got %v", expected[i], v.Version)
+			}
+		}
+	}
+
+	// Overwrite a file 3 times. Only the last 2 versions should be kept.
+	siaPath := newSiaPath("dir/file")
+	checkVersions(siaPath)
+	var uids []siafile.SiafileUID
+	for i := 0; i < 4; i++ {
+		if i > 0 {
+			if err := r.managedArchiveFile(siaPath); err != nil {
+				t.Fatal(err)
+			}
+		}
+		uids = append(uids, createFile(siaPath))
+	}
+	checkVersions(siaPath, 2, 3)
+	versions, err := r.FileVersions(siaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileUID(versions[0].SiaPath) != uids[1] || fileUID(versions[1].SiaPath) != uids[2] {
+		t.Fatal("versions contain the wrong files")
+	}
+
+	// Restore version 2. The current file should become version 4 and the
+	// oldest version should be pruned.
+	if err := r.RestoreFileVersion(siaPath, 2); err != nil {
+		t.Fatal(err)
+	}
+	if fileUID(siaPath) != uids[1] {
+		t.Fatal("wrong file was restored")
+	}
+	checkVersions(siaPath, 3, 4)
+	if err := r.RestoreFileVersion(siaPath, 2); !errors.Contains(err, errUnknownFileVersion) {
+		t.Fatal("expected errUnknownFileVersion", err)
+	}
+
+	// Renaming the parent dir and the file should move the versions.
+	newDirSiaPath := newSiaPath("newdir")
+	if err := r.RenameDir(newSiaPath("dir"), newDirSiaPath); err != nil {
+		t.Fatal(err)
+	}
+	siaPath = newSiaPath("newdir/file")
+	checkVersions(siaPath, 3, 4)
+	renamedSiaPath := newSiaPath("newdir/newfile")
+	if err := r.RenameFile(siaPath, renamedSiaPath); err != nil {
+		t.Fatal(err)
+	}
+	checkVersions(siaPath)
+	checkVersions(renamedSiaPath, 3, 4)
+	siaPath = renamedSiaPath
+
+	// Replacing the file should archive it like overwriting it.
+	tempSiaPath := newSiaPath("newdir/temp")
+	uid := createFile(tempSiaPath)
+	if err := r.ReplaceFile(tempSiaPath, siaPath); err != nil {
+		t.Fatal(err)
+	}
+	if fileUID(siaPath) != uid {
+		t.Fatal("file wasn't replaced")
+	}
+	if _, err := r.File(tempSiaPath); err == nil {
+		t.Fatal("replacing file still exists")
+	}
+	checkVersions(siaPath, 4, 5)
+	checkVersions(tempSiaPath)
+
+	// If the rename fails, the replaced file stays in place.
+	if err := r.ReplaceFile(tempSiaPath, siaPath); err == nil {
+		t.Fatal("expected replacing with a missing file to fail")
+	}
+	if fileUID(siaPath) != uid {
+		t.Fatal("replaced file wasn't restored")
+	}
+	checkVersions(siaPath, 4, 5)
+
+	// With versioning disabled, replacing and overwriting delete the file
+	// without touching the existing versions.
+	id = r.mu.Lock()
+	r.persist.MaxFileVersions = 0
+	r.mu.Unlock(id)
+	if err := r.ReplaceFile(tempSiaPath, siaPath); err == nil {
+		t.Fatal("expected replacing with a missing file to fail")
+	}
+	if fileUID(siaPath) != uid {
+		t.Fatal("replaced file wasn't restored")
+	}
+	checkVersions(siaPath, 4, 5)
+	uid = createFile(tempSiaPath)
+	if err := r.ReplaceFile(tempSiaPath, siaPath); err != nil {
+		t.Fatal(err)
+	}
+	if fileUID(siaPath) != uid {
+		t.Fatal("file wasn't replaced")
+	}
+	checkVersions(siaPath, 4, 5)
+	if err := r.managedArchiveFile(siaPath); err != nil {
+		t.Fatal(err)
+	}
+	checkVersions(siaPath, 4, 5)
+
+	// Deleting the file deletes its versions.
+	createFile(siaPath)
+	if err := r.DeleteFile(siaPath); err != nil {
+		t.Fatal(err)
+	}
+	checkVersions(siaPath)
+}
+
+// TestFileVersionsSharedName checks that the versions of a file and the
+// versions of the files within a directory with the same name are kept apart.
+func TestFileVersionsSharedName(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := rt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := rt.renter
+	id := r.mu.Lock()
+	r.persist.MaxFileVersions = 2
+	r.mu.Unlock(id)
+
+	// overwrite creates a file at the given path twice, which leaves one
+	// version of the file.
+	overwrite := func(sp modules.SiaPath) {
+		for i := 0; i < 2; i++ {
+			if i > 0 {
+				if err := r.managedArchiveFile(sp); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entry, err := r.createRenterTestFile(sp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := entry.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// numVersions returns the number of versions of the file.
+	numVersions := func(sp modules.SiaPath) int {
+		versions, err := r.FileVersions(sp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(versions)
+	}
+
+	// Keep 2 versions of the file "x" without a current file, like a forced
+	// upload which failed after archiving the existing file. Then create the
+	// file "y" within the dir "x".
+	file := newSiaPath("x")
+	overwrite(file)
+	if err := r.managedArchiveFile(file); err != nil {
+		t.Fatal(err)
+	}
+	overwrite(newSiaPath("x/y"))
+	if numVersions(file) != 2 || numVersions(newSiaPath("x/y")) != 1 {
+		t.Fatal("wrong number of versions", numVersions(file), numVersions(newSiaPath("x/y")))
+	}
+
+	// Renaming the dir doesn't move the versions of the file.
+	if err := r.RenameDir(newSiaPath("x"), newSiaPath("v")); err != nil {
+		t.Fatal(err)
+	}
+	if numVersions(file) != 2 || numVersions(newSiaPath("x/y")) != 0 || numVersions(newSiaPath("v/y")) != 1 {
+		t.Fatal("wrong number of versions after renaming the dir", numVersions(file), numVersions(newSiaPath("v/y")))
+	}
+
+	// Deleting the dir doesn't delete the versions of the file.
+	if err := r.RenameDir(newSiaPath("v"), newSiaPath("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteDir(newSiaPath("x")); err != nil {
+		t.Fatal(err)
+	}
+	if numVersions(file) != 2 || numVersions(newSiaPath("x/y")) != 0 {
+		t.Fatal("wrong number of versions after deleting the dir", numVersions(file), numVersions(newSiaPath("x/y")))
+	}
+
+	// Deleting the file doesn't delete the versions of the dir's files.
+	overwrite(newSiaPath("x/y"))
+	if err := r.RenameDir(newSiaPath("x"), newSiaPath("v")); err != nil {
+		t.Fatal(err)
+	}
+	overwrite(file)
+	if err := r.DeleteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if numVersions(file) != 0 || numVersions(newSiaPath("v/y")) != 1 {
+		t.Fatal("wrong number of versions after deleting the file", numVersions(file), numVersions(newSiaPath("v/y")))
+	}
+}
@@ -35,6 +35,10 @@ const (
 	// host to support the registry.
 	minRegistryVersion = "1.5.1"
 
+	// minProveSegmentVersion defines the minimum version that is required for
+	// a host to support the ProveSegment instruction.
+	minProveSegmentVersion = "1.5.10"
+
 	// registryCacheSize is the cache size used by a single worker for the
 	// registry cache.
 	registryCacheSize = 1 << 20 // 1 MiB
@@ -83,6 +87,7 @@ type (
 		staticJobHasSectorQueue        *jobHasSectorQueue
 		staticJobReadQueue             *jobReadQueue
 		staticJobLowPrioReadQueue      *jobReadQueue
+		staticJobProveSegmentQueue     *jobProveSegmentQueue
 		staticJobReadRegistryQueue     *jobReadRegistryQueue
 		staticJobRenewQueue            *jobRenewQueue
 		staticJobUpdateRegistryQueue   *jobUpdateRegistryQueue
@@ -260,6 +265,7 @@ func (r *Renter) newWorker(hostPubKey types.SiaPublicKey) (*worker, error) {
 	w.initJobHasSectorQueue()
 	w.initJobReadQueue()
 	w.initJobLowPrioReadQueue()
+	w.initJobProveSegmentQueue()
 	w.initJobRenewQueue()
 	w.initJobDownloadSnapshotQueue()
 	w.initJobReadRegistryQueue()
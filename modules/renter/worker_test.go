@@ -197,6 +197,7 @@ func TestManagedAsyncReady(t *testing.T) {
 	w.initJobLowPrioReadQueue()
 	w.initJobReadRegistryQueue()
 	w.initJobUpdateRegistryQueue()
+	w.initJobProveSegmentQueue()
 
 	timeInFuture := time.Now().Add(time.Hour)
 	timeInPast := time.Now().Add(-time.Hour)
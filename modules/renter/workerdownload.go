@@ -33,7 +33,7 @@ func segmentsForRecovery(chunkFetchOffset, chunkFetchLength uint64, rs modules.E
 	}
 	// Else we need to figure out what segments of the piece we need to
 	// download for the recovered data to contain the data we want.
-	recoveredSegmentSize := uint64(rs.MinPieces()) * segmentSize
+	recoveredSegmentSize := uint64(rs.DataPieces()) * segmentSize
 	// Calculate the offset of the download.
 	startSegment := chunkFetchOffset / recoveredSegmentSize
 	// Calculate the length of the download.
@@ -151,7 +151,7 @@ func (w *worker) threadedPerformDownloadChunkJob(udc *unfinishedDownloadChunk) {
 	// a large overdrive. It shouldn't be a bottleneck though since bandwidth
 	// is usually a lot more scarce than CPU processing power.
 	pieceIndex := udc.staticChunkMap[w.staticHostPubKey.String()].index
-	key := udc.masterKey.Derive(udc.staticChunkIndex, pieceIndex)
+	key := udc.masterKey.Derive(udc.staticKeyIndex, pieceIndex)
 	decryptedPiece, err := key.DecryptBytesInPlace(pieceData, uint64(fetchOffset/crypto.SegmentSize))
 	if err != nil {
 		w.renter.log.Debugln("worker failed to decrypt piece:", err)
@@ -1,13 +1,11 @@
 package renter
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 
 	"gitlab.com/NebulousLabs/errors"
 	"go.sia.tech/siad/modules"
-	"go.sia.tech/siad/types"
 )
 
 const (
@@ -200,13 +198,7 @@ func (w *worker) FetchBackups(ctx context.Context) ([]modules.UploadedBackup, er
 	// Format the response and return the response to the requester.
 	uploadedBackups := make([]modules.UploadedBackup, len(snapshots))
 	for i, e := range snapshots {
-		uploadedBackups[i] = modules.UploadedBackup{
-			Name:           string(bytes.TrimRight(e.Name[:], types.RuneToString(0))),
-			UID:            e.UID,
-			CreationDate:   e.CreationDate,
-			Size:           e.Size,
-			UploadProgress: 100,
-		}
+		uploadedBackups[i] = e.uploadedBackup()
 	}
 	return uploadedBackups, nil
 }
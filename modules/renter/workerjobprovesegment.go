@@ -0,0 +1,230 @@
+package renter
+
+import (
+	"context"
+	"time"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+const (
+	// jobProveSegmentPerformanceDecay defines how much the average performance
+	// is decayed each time a new datapoint is added. The jobs use an
+	// exponential weighted average.
+	jobProveSegmentPerformanceDecay = 0.9
+)
+
+type (
+	// jobProveSegment contains information about a proveSegment query. The
+	// host is challenged to prove that it stores a segment of every sector.
+	// The segment is determined by the seed and the root of the sector.
+	jobProveSegment struct {
+		staticSectors []crypto.Hash
+		staticSeed    crypto.Hash
+
+		staticResponseChan chan *jobProveSegmentResponse
+
+		*jobGeneric
+	}
+
+	// jobProveSegmentQueue is a list of proveSegment queries that have been
+	// assigned to the worker.
+	jobProveSegmentQueue struct {
+		// These variables contain an exponential weighted average of the
+		// worker's recent performance for jobProveSegmentQueue.
+		weightedJobTime float64
+
+		*jobGenericQueue
+	}
+
+	// jobProveSegmentResponse contains the result of a proveSegment query.
+	jobProveSegmentResponse struct {
+		// staticProven indicates for every sector whether the host returned
+		// the challenged segment together with a valid proof.
+		staticProven []bool
+		staticErr    error
+
+		staticWorker *worker
+	}
+)
+
+// newJobProveSegment is a helper method to create a new ProveSegment job.
+func (w *worker) newJobProveSegment(ctx context.Context, responseChan chan *jobProveSegmentResponse, seed crypto.Hash, roots ...crypto.Hash) *jobProveSegment {
+	return &jobProveSegment{
+		staticSectors:      roots,
+		staticSeed:         seed,
+		staticResponseChan: responseChan,
+		jobGeneric:         newJobGeneric(ctx, w.staticJobProveSegmentQueue, nil),
+	}
+}
+
+// callDiscard will discard a job, sending the provided error.
+func (j *jobProveSegment) callDiscard(err error) {
+	w := j.staticQueue.staticWorker()
+	errLaunch := w.renter.tg.Launch(func() {
+		response := &jobProveSegmentResponse{
+			staticErr: errors.Extend(err, ErrJobDiscarded),
+
+			staticWorker: w,
+		}
+		select {
+		case j.staticResponseChan <- response:
+		case <-j.staticCtx.Done():
+		case <-w.renter.tg.StopChan():
+		}
+	})
+	if errLaunch != nil {
+		w.renter.log.Print("callDiscard: launch failed", err)
+	}
+}
+
+// callExecute will run the prove segment job.
+func (j *jobProveSegment) callExecute() {
+	start := time.Now()
+	w := j.staticQueue.staticWorker()
+	proven, err := j.managedProveSegments()
+	jobTime := time.Since(start)
+
+	// Send the response.
+	response := &jobProveSegmentResponse{
+		staticProven: proven,
+		staticErr:    err,
+		staticWorker: w,
+	}
+	err2 := w.renter.tg.Launch(func() {
+		select {
+		case j.staticResponseChan <- response:
+		case <-j.staticCtx.Done():
+		case <-w.renter.tg.StopChan():
+		}
+	})
+	if err2 != nil {
+		w.renter.log.Println("callExececute: launch failed", err)
+	}
+
+	// Report success or failure to the queue.
+	if err != nil {
+		j.staticQueue.callReportFailure(err)
+		return
+	}
+	j.staticQueue.callReportSuccess()
+
+	// Job was a success, update the performance stats on the queue.
+	jq := j.staticQueue.(*jobProveSegmentQueue)
+	jq.mu.Lock()
+	jq.weightedJobTime = expMovingAvg(jq.weightedJobTime, float64(jobTime), jobProveSegmentPerformanceDecay)
+	jq.mu.Unlock()
+}
+
+// callExpectedBandwidth returns the bandwidth that is expected to be consumed
+// by the job.
+func (j *jobProveSegment) callExpectedBandwidth() (ul, dl uint64) {
+	// sanity check
+	if len(j.staticSectors) == 0 {
+		build.Critical("expected bandwidth requested for a job that has no staticSectors set")
+	}
+	return proveSegmentJobExpectedBandwidth(len(j.staticSectors))
+}
+
+// managedProveSegments challenges the host to prove that it stores the job's
+// sectors and verifies the returned proofs.
+func (j *jobProveSegment) managedProveSegments() ([]bool, error) {
+	w := j.staticQueue.staticWorker()
+	// Create the program.
+	pt := w.staticPriceTable().staticPriceTable
+	pb := modules.NewProgramBuilder(&pt, 0) // 0 duration since ProveSegment doesn't depend on it.
+	for _, sector := range j.staticSectors {
+		pb.AddProveSegmentInstruction(sector, j.staticSeed)
+	}
+	program, programData := pb.Program()
+	cost, _, _ := pb.Cost(true)
+
+	// take into account bandwidth costs
+	ulBandwidth, dlBandwidth := j.callExpectedBandwidth()
+	bandwidthCost := modules.MDMBandwidthCost(pt, ulBandwidth, dlBandwidth)
+	cost = cost.Add(bandwidthCost)
+
+	// Execute the program and parse the responses.
+	responses, _, err := w.managedExecuteProgram(program, programData, types.FileContractID{}, categoryRepairDownload, cost)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to execute program for prove segment job")
+	}
+	if len(responses) != len(program) {
+		return nil, errors.New("received invalid number of responses but no error")
+	}
+	proven := make([]bool, 0, len(responses))
+	for i, resp := range responses {
+		if resp.Error != nil {
+			return nil, errors.AddContext(resp.Error, "Output error")
+		}
+		// An empty output means that the host doesn't have the sector.
+		root := j.staticSectors[i]
+		index := int(modules.ProveSegmentIndex(j.staticSeed, root))
+		ok := uint64(len(resp.Output)) == crypto.SegmentSize &&
+			crypto.VerifyRangeProof(resp.Output, resp.Proof, index, index+1, root)
+		proven = append(proven, ok)
+	}
+	return proven, nil
+}
+
+// initJobProveSegmentQueue will init the queue for the prove segment jobs.
+func (w *worker) initJobProveSegmentQueue() {
+	// Sanity check that there is no existing job queue.
+	if w.staticJobProveSegmentQueue != nil {
+		w.renter.log.Critical("incorret call on initJobProveSegmentQueue")
+		return
+	}
+
+	w.staticJobProveSegmentQueue = &jobProveSegmentQueue{
+		jobGenericQueue: newJobGenericQueue(w),
+	}
+}
+
+// ProveSegments challenges the worker's host to prove that it stores the
+// sectors with the given roots. For every root the returned slice indicates
+// whether the host provided a valid proof.
+func (w *worker) ProveSegments(ctx context.Context, seed crypto.Hash, roots []crypto.Hash) ([]bool, error) {
+	proveSegmentRespChan := make(chan *jobProveSegmentResponse)
+	jps := w.newJobProveSegment(ctx, proveSegmentRespChan, seed, roots...)
+
+	// Add the job to the queue.
+	if !w.staticJobProveSegmentQueue.callAdd(jps) {
+		return nil, errors.New("worker unavailable")
+	}
+
+	// Wait for the response.
+	var resp *jobProveSegmentResponse
+	select {
+	case <-ctx.Done():
+		return nil, errors.New("ProveSegments interrupted")
+	case resp = <-proveSegmentRespChan:
+	}
+	if resp.staticErr != nil {
+		return nil, resp.staticErr
+	}
+	if len(resp.staticProven) != len(roots) {
+		return nil, errors.New("received invalid number of proofs but no error")
+	}
+	return resp.staticProven, nil
+}
+
+// proveSegmentJobExpectedBandwidth is a helper function that returns the
+// expected bandwidth consumption of a prove segment job. This helper function
+// enables getting at the expected bandwidth without having to instantiate a
+// job.
+func proveSegmentJobExpectedBandwidth(numRoots int) (ul, dl uint64) {
+	// Every instruction uploads a root, a seed and the instruction itself
+	// which fits about 10 instructions into a frame. Every response contains
+	// a segment and a proof of up to 16 hashes which only fits 2 responses
+	// into a frame.
+	ulFrames := (numRoots + 9) / 10
+	dlFrames := (numRoots + 1) / 2
+	ul = uint64(ethernetMTU * ulFrames)
+	dl = uint64(ethernetMTU * dlFrames)
+	return
+}
@@ -255,6 +255,7 @@ func (r *Renter) managedUploadSnapshotHost(meta modules.UploadedBackup, dotSia [
 		UID:          meta.UID,
 		CreationDate: meta.CreationDate,
 		Size:         meta.Size,
+		SiaPath:      meta.SiaPath,
 	}
 	for j, piece := range sectors {
 		root, err := host.Upload(piece)
@@ -247,6 +247,14 @@ func (w *worker) externTryLaunchAsyncJob() bool {
 			return true
 		}
 	}
+	// Check if prove segment jobs are supported.
+	if build.VersionCmp(cache.staticHostVersion, minProveSegmentVersion) >= 0 {
+		job = w.staticJobProveSegmentQueue.callNext()
+		if job != nil {
+			w.externLaunchAsyncJob(job)
+			return true
+		}
+	}
 	job = w.staticJobReadQueue.callNext()
 	if job != nil {
 		w.externLaunchAsyncJob(job)
@@ -285,6 +293,7 @@ func (w *worker) managedDiscardAsyncJobs(err error) {
 	w.staticJobReadRegistryQueue.callDiscardAll(err)
 	w.staticJobReadQueue.callDiscardAll(err)
 	w.staticJobLowPrioReadQueue.callDiscardAll(err)
+	w.staticJobProveSegmentQueue.callDiscardAll(err)
 }
 
 // threadedWorkLoop is a perpetual loop run by the worker that accepts new jobs
@@ -302,6 +311,7 @@ func (w *worker) threadedWorkLoop() {
 	defer w.managedKillUploading()
 	defer w.staticJobLowPrioReadQueue.callKill()
 	defer w.staticJobHasSectorQueue.callKill()
+	defer w.staticJobProveSegmentQueue.callKill()
 	defer w.staticJobUpdateRegistryQueue.callKill()
 	defer w.staticJobReadQueue.callKill()
 	defer w.staticJobDownloadSnapshotQueue.callKill()
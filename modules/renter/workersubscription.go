@@ -212,8 +212,9 @@ func (nh *notificationHandler) managedHandleRegistryEntry(stream siamux.Stream,
 		return fmt.Errorf("subscription not found")
 	}
 
-	// Update the subscription.
+	// Update the subscription and notify the renter's subscribers.
 	sub.latestRV = &sneu.Entry
+	w.renter.staticRegistrySubscriptions.callNotify(sneu.PubKey, sneu.Entry)
 	return nil
 }
 
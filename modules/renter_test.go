@@ -282,3 +282,137 @@ func BenchmarkSliceCryptoHashSave(b *testing.B) {
 		}
 	}
 }
+
+// TestDirPolicy tests inheriting, validating and marshaling DirPolicy.
+func TestDirPolicy(t *testing.T) {
+	t.Parallel()
+
+	parent := DirPolicy{
+		DataPieces:      10,
+		ParityPieces:    40,
+		CipherType:      crypto.TypeXChaCha20,
+		RepairThreshold: 0.1,
+	}
+
+	// An empty policy inherits everything.
+	if p := (DirPolicy{}).Inherit(parent); p != parent {
+		t.Fatal("empty policy didn't inherit parent", p)
+	}
+	// Set fields are kept.
+	child := DirPolicy{DataPieces: 10, ParityPieces: 20}
+	expected := DirPolicy{
+		DataPieces:      10,
+		ParityPieces:    20,
+		CipherType:      crypto.TypeXChaCha20,
+		RepairThreshold: 0.1,
+	}
+	if p := child.Inherit(parent); p != expected {
+		t.Fatal("wrong inherited policy", p)
+	}
+
+	// Check the defaults of an empty policy.
+	var empty DirPolicy
+	if empty.Cipher() != crypto.TypeDefaultRenter {
+		t.Fatal("wrong default cipher type", empty.Cipher())
+	}
+	ec, err := empty.ErasureCoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.MinPieces() != RenterDefaultDataPieces || ec.NumPieces() != RenterDefaultDataPieces+RenterDefaultParityPieces {
+		t.Fatal("wrong default erasure coder", ec.MinPieces(), ec.NumPieces())
+	}
+	if empty.NeedsRepair(RepairThreshold-0.01) || !empty.NeedsRepair(RepairThreshold) {
+		t.Fatal("empty policy should use the global repair threshold")
+	}
+	if parent.NeedsRepair(0.09) || !parent.NeedsRepair(0.1) {
+		t.Fatal("policy should use its own repair threshold")
+	}
+	ec, err = parent.ErasureCoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.MinPieces() != 10 || ec.NumPieces() != 50 {
+		t.Fatal("wrong erasure coder", ec.MinPieces(), ec.NumPieces())
+	}
+
+	// Check validation.
+	if err := parent.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []DirPolicy{
+		{DataPieces: 10},
+		{ParityPieces: 10},
+		{DataPieces: -1, ParityPieces: -1},
+		{CipherType: crypto.CipherType{1}},
+		{RepairThreshold: -0.1},
+		{RepairThreshold: 1.1},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Fatal("invalid policy was accepted", p)
+		}
+	}
+
+	// Check the JSON encoding.
+	b, err := json.Marshal(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p DirPolicy
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != parent {
+		t.Fatal("policy doesn't match after unmarshaling", p)
+	}
+	b, err = json.Marshal(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != empty {
+		t.Fatal("empty policy doesn't match after unmarshaling", p)
+	}
+}
+
+// TestPriorityClass tests the string and JSON encoding of priority classes and
+// the validation of priority weights.
+func TestPriorityClass(t *testing.T) {
+	for _, pc := range PriorityClasses() {
+		var pc2 PriorityClass
+		if err := pc2.FromString(pc.String()); err != nil || pc2 != pc {
+			t.Fatal("class doesn't match after parsing", pc, pc2, err)
+		}
+		b, err := json.Marshal(PriorityClassStatus{Class: pc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pcs PriorityClassStatus
+		if err := json.Unmarshal(b, &pcs); err != nil {
+			t.Fatal(err)
+		}
+		if pcs.Class != pc {
+			t.Fatal("class doesn't match after unmarshaling", pcs.Class, pc)
+		}
+	}
+	var pc PriorityClass
+	if err := pc.FromString("unknown"); err == nil {
+		t.Fatal("unknown class was parsed")
+	}
+
+	// The default weights are valid and every class needs a weight.
+	if err := DefaultPriorityWeights.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	weights := DefaultPriorityWeights
+	weights.User = 0
+	if err := weights.Validate(); err == nil {
+		t.Fatal("weight of 0 was accepted")
+	}
+	if DefaultPriorityWeights.Weight(PriorityClassInteractive) != DefaultPriorityWeights.Interactive {
+		t.Fatal("wrong weight")
+	}
+}
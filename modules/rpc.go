@@ -126,6 +126,10 @@ type RPCPriceTable struct {
 	// Cost values specific to the HasSector command.
 	HasSectorBaseCost types.Currency `json:"hassectorbasecost"`
 
+	// ProveSegmentBaseCost is the cost of proving that the host stores a
+	// random segment of a sector.
+	ProveSegmentBaseCost types.Currency `json:"provesegmentbasecost"`
+
 	// Cost values specific to the Read instruction.
 	ReadBaseCost   types.Currency `json:"readbasecost"`
 	ReadLengthCost types.Currency `json:"readlengthcost"`
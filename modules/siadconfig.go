@@ -2,7 +2,9 @@ package modules
 
 import (
 	"errors"
+	"net/url"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"gitlab.com/NebulousLabs/ratelimit"
@@ -19,10 +21,31 @@ type (
 		WriteBPS           int64  `json:"writebps"`
 		PacketSize         uint64 `json:"packetsize"`
 
+		// API listener related fields. They are used if the corresponding
+		// siad flags are not set.
+		APITLSCertFile     string `json:"apitlscertfile"`
+		APITLSKeyFile      string `json:"apitlskeyfile"`
+		APITLSClientCAFile string `json:"apitlsclientcafile"`
+		APIUnixSocket      string `json:"apiunixsocket"`
+		APIUnixSocketMode  uint32 `json:"apiunixsocketmode"`
+
+		// Webhooks are the endpoints siad posts events to.
+		Webhooks []Webhook `json:"webhooks"`
+
+		// APITokens are the scoped tokens which grant access to the API.
+		APITokens []APIToken `json:"apitokens"`
+
 		// path of config on disk.
 		path string
 		mu   sync.Mutex
 	}
+
+	// Webhook is an endpoint which siad posts events to. If Events is empty,
+	// all events are posted. Otherwise only the events of the listed types.
+	Webhook struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
 )
 
 var (
@@ -37,6 +60,10 @@ var (
 
 	// ConfigName is the name of the config file on disk
 	ConfigName = "siad.config"
+
+	// ErrUnknownWebhook is returned when removing a webhook which isn't
+	// configured.
+	ErrUnknownWebhook = errors.New("webhook not found")
 )
 
 // SetRatelimit sets the ratelimit related fields in the config and persists it
@@ -59,6 +86,74 @@ func (cfg *SiadConfig) SetRatelimit(readBPS, writeBPS int64) error {
 	return cfg.save()
 }
 
+// AddWebhook adds a webhook to the config and persists it to disk. An existing
+// webhook with the same URL is replaced.
+func (cfg *SiadConfig) AddWebhook(webhook Webhook) error {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	u, err := url.Parse(webhook.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("webhook url needs to be an http or https url")
+	}
+	webhooks := make([]Webhook, 0, len(cfg.Webhooks)+1)
+	for _, wh := range cfg.Webhooks {
+		if wh.URL != webhook.URL {
+			webhooks = append(webhooks, wh)
+		}
+	}
+	cfg.Webhooks = append(webhooks, webhook)
+	return cfg.save()
+}
+
+// RemoveWebhook removes the webhook with the given URL from the config and
+// persists it to disk.
+func (cfg *SiadConfig) RemoveWebhook(webhookURL string) error {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	webhooks := make([]Webhook, 0, len(cfg.Webhooks))
+	for _, wh := range cfg.Webhooks {
+		if wh.URL != webhookURL {
+			webhooks = append(webhooks, wh)
+		}
+	}
+	if len(webhooks) == len(cfg.Webhooks) {
+		return ErrUnknownWebhook
+	}
+	cfg.Webhooks = webhooks
+	return cfg.save()
+}
+
+// Dir returns the directory which contains the config.
+func (cfg *SiadConfig) Dir() string {
+	return filepath.Dir(cfg.path)
+}
+
+// WebhookList returns a copy of the configured webhooks.
+func (cfg *SiadConfig) WebhookList() []Webhook {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	webhooks := make([]Webhook, len(cfg.Webhooks))
+	copy(webhooks, cfg.Webhooks)
+	return webhooks
+}
+
+// Wants returns whether an event of the given type should be posted to the
+// webhook.
+func (wh Webhook) Wants(eventType string) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, et := range wh.Events {
+		if et == eventType {
+			return true
+		}
+	}
+	return false
+}
+
 // save saves the config to disk.
 func (cfg *SiadConfig) save() error {
 	return persist.SaveJSON(configMetadata, cfg, cfg.path)
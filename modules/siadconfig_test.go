@@ -97,3 +97,61 @@ func checkWriteBPS(sc *SiadConfig, writeBPS int64) error {
 	}
 	return nil
 }
+
+// TestSiadConfigWebhooks tests adding and removing webhooks.
+func TestSiadConfigWebhooks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testDir := build.TempDir("siadconfig", t.Name())
+	if err := os.MkdirAll(testDir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(testDir, ConfigName)
+	sc, err := NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid urls are rejected.
+	if err := sc.AddWebhook(Webhook{URL: "ftp://example.com"}); err == nil {
+		t.Fatal("expected error for non-http url")
+	}
+
+	// Add two webhooks and replace the first one.
+	wh1 := Webhook{URL: "http://localhost:8000/events"}
+	wh2 := Webhook{URL: "https://example.com/hook", Events: []string{"alert.registered"}}
+	for _, wh := range []Webhook{wh1, wh2, wh1} {
+		if err := sc.AddWebhook(wh); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if webhooks := sc.WebhookList(); len(webhooks) != 2 {
+		t.Fatal("expected 2 webhooks but got", len(webhooks))
+	}
+	if !wh1.Wants("alert.registered") || !wh2.Wants("alert.registered") || wh2.Wants("alert.unregistered") {
+		t.Fatal("webhooks want the wrong events")
+	}
+
+	// The webhooks are persisted.
+	sc, err = NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhooks := sc.WebhookList(); len(webhooks) != 2 {
+		t.Fatal("expected 2 webhooks after reload but got", len(webhooks))
+	}
+
+	// Remove a webhook.
+	if err := sc.RemoveWebhook(wh1.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.RemoveWebhook(wh1.URL); err != ErrUnknownWebhook {
+		t.Fatal("expected ErrUnknownWebhook but got", err)
+	}
+	if webhooks := sc.WebhookList(); len(webhooks) != 1 || webhooks[0].URL != wh2.URL {
+		t.Fatal("wrong webhooks after removal", webhooks)
+	}
+}
@@ -55,6 +55,10 @@ var (
 
 	// UserFolder is the Sia folder that is used to store the renter's siafiles.
 	UserFolder = NewGlobalSiaPath("/home/user")
+
+	// VersionsFolder is the Sia folder where the renter keeps the prior
+	// versions of overwritten siafiles.
+	VersionsFolder = NewGlobalSiaPath("/versions")
 )
 
 type (
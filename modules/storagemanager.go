@@ -1,6 +1,8 @@
 package modules
 
 import (
+	"time"
+
 	"go.sia.tech/siad/crypto"
 )
 
@@ -14,6 +16,18 @@ const (
 	StorageManagerDir = "storagemanager"
 )
 
+const (
+	// StorageTierFast is the tier of storage folders on fast storage, e.g.
+	// SSDs. The contract manager moves frequently read sectors to fast storage
+	// folders.
+	StorageTierFast = "fast"
+
+	// StorageTierSlow is the tier of storage folders on slow storage, e.g.
+	// HDDs. It is the default tier of new storage folders and new sectors are
+	// preferably placed in slow storage folders.
+	StorageTierSlow = "slow"
+)
+
 type (
 	// StorageFolderMetadata contains metadata about a storage folder that is
 	// tracked by the storage folder manager.
@@ -22,6 +36,7 @@ type (
 		CapacityRemaining uint64 `json:"capacityremaining"` // bytes
 		Index             uint16 `json:"index"`
 		Path              string `json:"path"`
+		Tier              string `json:"tier"`
 
 		// Below are statistics about the filesystem. FailedReads and
 		// FailedWrites are only incremented if the filesystem is returning
@@ -42,6 +57,40 @@ type (
 		ProgressDenominator uint64
 	}
 
+	// StorageFolderScrubStatus contains the progress of scrubbing a storage
+	// folder. Scrubbing reads every sector of the folder and compares the
+	// Merkle root of its data with the root it was stored under.
+	StorageFolderScrubStatus struct {
+		Index uint16 `json:"index"`
+		Path  string `json:"path"`
+
+		// ScrubbedSectors is the number of sectors which were scrubbed in the
+		// current or last scrub of the folder and TotalSectors is the number
+		// of sectors in the folder when the scrub started.
+		ScrubbedSectors uint64 `json:"scrubbedsectors"`
+		TotalSectors    uint64 `json:"totalsectors"`
+
+		// CorruptSectors is the number of sectors which were found to be
+		// corrupt or unreadable in the current or last scrub of the folder.
+		CorruptSectors uint64 `json:"corruptsectors"`
+	}
+
+	// StorageScrubStatus contains the status of the storage manager's
+	// scrubber.
+	StorageScrubStatus struct {
+		// Running indicates whether a scrub is currently in progress.
+		Running bool `json:"running"`
+
+		// LastStarted and LastCompleted are the times the last scrub started
+		// and completed since the storage manager was started. NextScrub is
+		// the time the next scrub is scheduled for.
+		LastStarted   time.Time `json:"laststarted"`
+		LastCompleted time.Time `json:"lastcompleted"`
+		NextScrub     time.Time `json:"nextscrub"`
+
+		Folders []StorageFolderScrubStatus `json:"folders"`
+	}
+
 	// A StorageManager is responsible for managing storage folders and
 	// sectors. Sectors are the base unit of storage that gets moved between
 	// renters and hosts, and primarily is stored on the hosts.
@@ -129,6 +178,15 @@ type (
 		// that data will be lost.
 		ResizeStorageFolder(index uint16, newSize uint64, force bool) error
 
+		// ScrubStatus returns the status of the background scrubber which
+		// periodically verifies the integrity of all stored sectors.
+		ScrubStatus() StorageScrubStatus
+
+		// SetStorageFolderTier sets the tier of a storage folder. Frequently
+		// read sectors are moved to storage folders of the fast tier in the
+		// background.
+		SetStorageFolderTier(index uint16, tier string) error
+
 		// StorageFolders will return a list of storage folders tracked by the
 		// manager.
 		StorageFolders() []StorageFolderMetadata
@@ -129,6 +129,7 @@ type (
 		Shutdown          func() error
 		siadConfig        *modules.SiadConfig
 
+		staticEvents    *eventManager
 		staticStartTime time.Time
 
 		staticDeps modules.Dependencies
@@ -183,6 +184,13 @@ func (api *API) SetModules(acc modules.Accounting, cs modules.ConsensusSet, e mo
 	}
 	api.modulesSet = true
 	api.buildHTTPRoutes()
+	api.staticEvents.callStart()
+}
+
+// Close stops the background threads of the API. It doesn't close the
+// modules.
+func (api *API) Close() error {
+	return api.staticEvents.Close()
 }
 
 // StartTime returns the time at which the API started
@@ -222,9 +230,15 @@ func NewCustom(cfg *modules.SiadConfig, requiredUserAgent string, requiredPasswo
 		staticStartTime: time.Now(),
 	}
 
+	api.staticEvents = newEventManager(api)
+
 	// Register API handlers
 	api.buildHTTPRoutes()
 
+	// Start emitting events if the modules were provided.
+	if cs != nil || g != nil || h != nil || r != nil || w != nil {
+		api.staticEvents.callStart()
+	}
 	return api
 }
 
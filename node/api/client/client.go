@@ -2,10 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 
@@ -30,10 +34,22 @@ type (
 		// Password must match the password of the siad server.
 		Password string
 
+		// APIToken is an API token created by the siad server. If set, it is
+		// used instead of the password.
+		APIToken string
+
 		// UserAgent must match the User-Agent required by the siad server. If not
 		// set, it defaults to "Sia-Agent".
 		UserAgent string
 
+		// UnixSocket is the path of the siad server's unix socket. If set, the
+		// client connects to the socket instead of Address.
+		UnixSocket string
+
+		// TLSConfig enables TLS for connections to Address if set. It is
+		// ignored when connecting to a unix socket.
+		TLSConfig *tls.Config
+
 		// CheckRedirect is an optional handler to be called if the request
 		// receives a redirect status code.
 		// For more see https://golang.org/pkg/net/http/#Client
@@ -79,8 +95,7 @@ func (uc *UnsafeClient) GetWithHeaders(resource string, headers http.Header) (*h
 		}
 	}
 
-	httpClient := http.Client{CheckRedirect: uc.CheckRedirect}
-	return httpClient.Do(req)
+	return uc.httpClient().Do(req)
 }
 
 // New creates a new Client using the provided address. The password will be set
@@ -107,10 +122,72 @@ func DefaultOptions() (Options, error) {
 	}, nil
 }
 
+// NewTLSConfig creates a TLS config for connecting to a siad server which
+// serves its API over TLS. caFile is the path to the PEM encoded CA
+// certificates the server's certificate is verified against. If empty, the
+// system's CAs are used. certFile and keyFile are the paths to the PEM encoded
+// client certificate and key in case the server requires one.
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to read CA file")
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA file doesn't contain any certificates")
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to load client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// httpClient returns the http.Client which is used to send requests to the
+// siad server.
+func (c *Client) httpClient() *http.Client {
+	client := &http.Client{CheckRedirect: c.CheckRedirect}
+	if c.UnixSocket == "" && c.TLSConfig == nil {
+		return client
+	}
+	// The transport is not shared between requests, so don't keep idle
+	// connections around.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	transport.TLSClientConfig = c.TLSConfig
+	if c.UnixSocket != "" {
+		socket := c.UnixSocket
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+	client.Transport = transport
+	return client
+}
+
 // NewRequest constructs a request to the siad HTTP API, setting the correct
-// User-Agent and Basic Auth. The resource path must begin with /.
+// User-Agent and Basic Auth or API token. The resource path must begin with /.
 func (c *Client) NewRequest(method, resource string, body io.Reader) (*http.Request, error) {
-	url := "http://" + c.Address + resource
+	scheme, host := "http://", c.Address
+	if c.UnixSocket != "" {
+		// The host is only used for the Host header when connecting to a unix
+		// socket.
+		if host == "" {
+			host = "localhost"
+		}
+	} else if c.TLSConfig != nil {
+		scheme = "https://"
+	}
+	url := scheme + host + resource
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
@@ -120,7 +197,9 @@ func (c *Client) NewRequest(method, resource string, body io.Reader) (*http.Requ
 		agent = "Sia-Agent"
 	}
 	req.Header.Set("User-Agent", agent)
-	if c.Password != "" {
+	if c.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIToken)
+	} else if c.Password != "" {
 		req.SetBasicAuth("", c.Password)
 	}
 	return req, nil
@@ -173,7 +252,7 @@ func (c *Client) getReaderResponse(resource string) (http.Header, io.ReadCloser,
 	if err != nil {
 		return nil, nil, errors.AddContext(err, "failed to construct GET request")
 	}
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
+	httpClient := c.httpClient()
 	res, err := httpClient.Do(req)
 	if err != nil {
 		return nil, nil, errors.AddContext(err, "GET request failed")
@@ -211,7 +290,7 @@ func (c *Client) getRawPartialResponse(resource string, from, to uint64) ([]byte
 	}
 	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d", from, to-1))
 
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
+	httpClient := c.httpClient()
 	res, err := httpClient.Do(req)
 	if err != nil {
 		return nil, errors.AddContext(err, "GET request failed")
@@ -268,7 +347,7 @@ func (c *Client) head(resource string) (int, http.Header, error) {
 	if err != nil {
 		return 0, nil, errors.AddContext(err, "failed to construct HEAD request")
 	}
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
+	httpClient := c.httpClient()
 	res, err := httpClient.Do(req)
 	if err != nil {
 		return 0, nil, errors.AddContext(err, "HEAD request failed")
@@ -302,7 +381,7 @@ func (c *Client) postRawResponseWithHeaders(resource string, body io.Reader, hea
 		}
 	}
 
-	httpClient := http.Client{CheckRedirect: c.CheckRedirect}
+	httpClient := c.httpClient()
 	res, err := httpClient.Do(req)
 	if err != nil {
 		return http.Header{}, nil, errors.AddContext(err, "POST request failed")
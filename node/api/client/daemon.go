@@ -1,12 +1,26 @@
 package client
 
 import (
+	"bufio"
+	"encoding/json"
+	"io"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/node/api"
 )
 
+type (
+	// EventSubscription is an open subscription to the events of siad.
+	EventSubscription struct {
+		body    io.ReadCloser
+		scanner *bufio.Scanner
+	}
+)
+
 // DaemonGlobalRateLimitPost uses the /daemon/settings endpoint to change the
 // siad's bandwidth rate limit. downloadSpeed and uploadSpeed are interpreted
 // as bytes/second.
@@ -74,3 +88,116 @@ func (c *Client) DaemonUpdatePost() (err error) {
 	err = c.post("/daemon/update", "", nil)
 	return
 }
+
+// MetricsGet requests the /metrics resource and returns the metrics in the
+// Prometheus text exposition format.
+func (c *Client) MetricsGet() ([]byte, error) {
+	_, metrics, err := c.getRawResponse("/metrics")
+	return metrics, err
+}
+
+// DaemonEventsSubscribe uses the /daemon/events endpoint to subscribe to the
+// events of the given types. If no types are provided, all events are
+// received. The subscription needs to be closed by the caller.
+func (c *Client) DaemonEventsSubscribe(eventTypes []string) (*EventSubscription, error) {
+	values := url.Values{}
+	values.Set("types", strings.Join(eventTypes, ","))
+	_, body, err := c.getReaderResponse("/daemon/events?" + values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return &EventSubscription{
+		body:    body,
+		scanner: bufio.NewScanner(body),
+	}, nil
+}
+
+// Next blocks until the next event is received. The data of the event is
+// returned as raw json since its type depends on the type of the event.
+func (es *EventSubscription) Next() (api.Event, json.RawMessage, error) {
+	for es.scanner.Scan() {
+		line := es.scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			// Skip ids, event types, heartbeats and empty lines.
+			continue
+		}
+		var e struct {
+			api.Event
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+			return api.Event{}, nil, err
+		}
+		e.Event.Data = e.Data
+		return e.Event, e.Data, nil
+	}
+	if err := es.scanner.Err(); err != nil {
+		return api.Event{}, nil, err
+	}
+	return api.Event{}, nil, io.EOF
+}
+
+// Close closes the subscription.
+func (es *EventSubscription) Close() error {
+	return es.body.Close()
+}
+
+// DaemonWebhooksGet requests the /daemon/webhooks resource.
+func (c *Client) DaemonWebhooksGet() (dwg api.DaemonWebhooksGET, err error) {
+	err = c.get("/daemon/webhooks", &dwg)
+	return
+}
+
+// DaemonWebhooksAddPost uses the /daemon/webhooks endpoint to add a webhook
+// which receives the events of the given types. If no types are provided, the
+// webhook receives all events.
+func (c *Client) DaemonWebhooksAddPost(webhook modules.Webhook) (err error) {
+	values := url.Values{}
+	values.Set("action", "add")
+	values.Set("url", webhook.URL)
+	values.Set("events", strings.Join(webhook.Events, ","))
+	err = c.post("/daemon/webhooks", values.Encode(), nil)
+	return
+}
+
+// DaemonWebhooksRemovePost uses the /daemon/webhooks endpoint to remove a
+// webhook.
+func (c *Client) DaemonWebhooksRemovePost(webhookURL string) (err error) {
+	values := url.Values{}
+	values.Set("action", "remove")
+	values.Set("url", webhookURL)
+	err = c.post("/daemon/webhooks", values.Encode(), nil)
+	return
+}
+
+// DaemonTokensGet uses the /daemon/tokens endpoint to request the API tokens of
+// the daemon.
+func (c *Client) DaemonTokensGet() (dtg api.DaemonTokensGET, err error) {
+	err = c.get("/daemon/tokens", &dtg)
+	return
+}
+
+// DaemonTokensCreatePost uses the /daemon/tokens endpoint to create an API
+// token with the given scopes. A validity of 0 creates a token which never
+// expires.
+func (c *Client) DaemonTokensCreatePost(name string, scopes []string, validity time.Duration) (dtp api.DaemonTokensPOST, err error) {
+	values := url.Values{}
+	values.Set("action", "create")
+	values.Set("name", name)
+	values.Set("scopes", strings.Join(scopes, ","))
+	if validity != 0 {
+		values.Set("validity", validity.String())
+	}
+	err = c.post("/daemon/tokens", values.Encode(), &dtp)
+	return
+}
+
+// DaemonTokensRemovePost uses the /daemon/tokens endpoint to remove an API
+// token.
+func (c *Client) DaemonTokensRemovePost(id string) (err error) {
+	values := url.Values{}
+	values.Set("action", "remove")
+	values.Set("id", id)
+	err = c.post("/daemon/tokens", values.Encode(), nil)
+	return
+}
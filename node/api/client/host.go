@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"go.sia.tech/siad/crypto"
 	"go.sia.tech/siad/modules"
@@ -68,6 +69,15 @@ const (
 	// HostParamCustomRegistryPath is the locataion of the host's registry on
 	// disk.
 	HostParamCustomRegistryPath = HostParam("customregistrypath")
+	// HostParamMaxConcurrentPrograms is the maximum number of programs an
+	// ephemeral account can execute concurrently.
+	HostParamMaxConcurrentPrograms = HostParam("maxconcurrentprograms")
+	// HostParamPeerBandwidthLimit is the bandwidth limit per peer in bytes per
+	// second.
+	HostParamPeerBandwidthLimit = HostParam("peerbandwidthlimit")
+	// HostParamPeerRPCRateLimit is the maximum number of RPCs per second per
+	// peer, renter and ephemeral account.
+	HostParamPeerRPCRateLimit = HostParam("peerrpcratelimit")
 )
 
 // HostAnnouncePost uses the /host/announce endpoint to announce the host to
@@ -91,6 +101,66 @@ func (c *Client) HostContractInfoGet() (cg api.ContractInfoGET, err error) {
 	return
 }
 
+// storageObligationQueryValues encodes the storage obligation query as query
+// string parameters of the /host/contracts endpoint.
+func storageObligationQueryValues(query modules.HostStorageObligationQuery) url.Values {
+	values := url.Values{}
+	if len(query.Statuses) > 0 {
+		values.Set("status", strings.Join(query.Statuses, ","))
+	}
+	if query.MinExpirationHeight != 0 {
+		values.Set("minexpirationheight", fmt.Sprint(query.MinExpirationHeight))
+	}
+	if query.MaxExpirationHeight != 0 {
+		values.Set("maxexpirationheight", fmt.Sprint(query.MaxExpirationHeight))
+	}
+	if query.MinNegotiationHeight != 0 {
+		values.Set("minnegotiationheight", fmt.Sprint(query.MinNegotiationHeight))
+	}
+	if query.MaxNegotiationHeight != 0 {
+		values.Set("maxnegotiationheight", fmt.Sprint(query.MaxNegotiationHeight))
+	}
+	if !query.MinRevenue.IsZero() {
+		values.Set("minrevenue", query.MinRevenue.String())
+	}
+	if !query.MaxRevenue.IsZero() {
+		values.Set("maxrevenue", query.MaxRevenue.String())
+	}
+	if query.ProofOutcome != "" {
+		values.Set("proofoutcome", query.ProofOutcome)
+	}
+	if query.SortBy != "" {
+		values.Set("sortby", query.SortBy)
+	}
+	if query.SortDesc {
+		values.Set("sortorder", "desc")
+	}
+	if query.Offset != 0 {
+		values.Set("offset", fmt.Sprint(query.Offset))
+	}
+	if query.Limit != 0 {
+		values.Set("limit", fmt.Sprint(query.Limit))
+	}
+	return values
+}
+
+// HostContractInfoQueryGet uses the /host/contracts endpoint to get the page of
+// contracts on the host matching the query.
+func (c *Client) HostContractInfoQueryGet(query modules.HostStorageObligationQuery) (cg api.ContractInfoGET, err error) {
+	err = c.get("/host/contracts?"+storageObligationQueryValues(query).Encode(), &cg)
+	return
+}
+
+// HostContractsExportGet uses the /host/contracts endpoint to export the
+// contracts on the host matching the query in the given format, either "csv"
+// or "json".
+func (c *Client) HostContractsExportGet(query modules.HostStorageObligationQuery, format string) ([]byte, error) {
+	values := storageObligationQueryValues(query)
+	values.Set("format", format)
+	_, data, err := c.getRawResponse("/host/contracts?" + values.Encode())
+	return data, err
+}
+
 // HostContractGet uses the /host/contracts/:id endpoint to get information
 // about a contract on the host.
 func (c *Client) HostContractGet(obligationID types.FileContractID) (cg api.HostContractGET, err error) {
@@ -123,6 +193,21 @@ func (c *Client) HostBandwidthGet() (gbg api.GatewayBandwidthGET, err error) {
 	return
 }
 
+// HostPricingGet requests the /host/pricing endpoint.
+func (c *Client) HostPricingGet() (hpg api.HostPricingGET, err error) {
+	err = c.get("/host/pricing", &hpg)
+	return
+}
+
+// HostPricingPost uses the /host/pricing endpoint to change a field of the
+// host's pricing policy to a certain value.
+func (c *Client) HostPricingPost(field string, value interface{}) (err error) {
+	values := url.Values{}
+	values.Set(field, fmt.Sprint(value))
+	err = c.post("/host/pricing", values.Encode(), nil)
+	return
+}
+
 // HostStorageFoldersAddPost uses the /host/storage/folders/add api endpoint to
 // add a storage folder to a host
 func (c *Client) HostStorageFoldersAddPost(path string, size uint64) (err error) {
@@ -153,12 +238,28 @@ func (c *Client) HostStorageFoldersResizePost(path string, size uint64) (err err
 	return
 }
 
+// HostStorageFoldersTierPost uses the /host/storage/folders/tier api endpoint
+// to set the tier of an existing storage folder.
+func (c *Client) HostStorageFoldersTierPost(path, tier string) (err error) {
+	values := url.Values{}
+	values.Set("path", path)
+	values.Set("tier", tier)
+	err = c.post("/host/storage/folders/tier", values.Encode(), nil)
+	return
+}
+
 // HostStorageGet requests the /host/storage endpoint.
 func (c *Client) HostStorageGet() (sg api.StorageGET, err error) {
 	err = c.get("/host/storage", &sg)
 	return
 }
 
+// HostStorageScrubGet requests the /host/storage/scrub endpoint.
+func (c *Client) HostStorageScrubGet() (ssg api.StorageScrubGET, err error) {
+	err = c.get("/host/storage/scrub", &ssg)
+	return
+}
+
 // HostStorageSectorsDeletePost uses the /host/storage/sectors/delete endpoint
 // to delete a sector from the host.
 func (c *Client) HostStorageSectorsDeletePost(root crypto.Hash) (err error) {
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/node/api"
+	"go.sia.tech/siad/types"
+)
+
+type (
+	// RegistrySubscription is an open subscription to a registry entry.
+	RegistrySubscription struct {
+		body io.ReadCloser
+		dec  *json.Decoder
+	}
+)
+
+// registryValueFromGET converts a RegistryHandlerGET response into a
+// SignedRegistryValue.
+func registryValueFromGET(dataKey crypto.Hash, rhg api.RegistryHandlerGET) (modules.SignedRegistryValue, error) {
+	data, err := hex.DecodeString(rhg.Data)
+	if err != nil {
+		return modules.SignedRegistryValue{}, errors.AddContext(err, "failed to decode data")
+	}
+	sigBytes, err := hex.DecodeString(rhg.Signature)
+	if err != nil {
+		return modules.SignedRegistryValue{}, errors.AddContext(err, "failed to decode signature")
+	}
+	var sig crypto.Signature
+	if len(sigBytes) != len(sig) {
+		return modules.SignedRegistryValue{}, fmt.Errorf("unexpected signature length %v", len(sigBytes))
+	}
+	copy(sig[:], sigBytes)
+	return modules.NewSignedRegistryValue(dataKey, data, rhg.Revision, sig, rhg.Type), nil
+}
+
+// RegistryRead uses the /renter/registry endpoint to read a registry value.
+func (c *Client) RegistryRead(spk types.SiaPublicKey, dataKey crypto.Hash) (modules.SignedRegistryValue, error) {
+	return c.RegistryReadWithTimeout(spk, dataKey, 0)
+}
+
+// RegistryReadWithTimeout uses the /renter/registry endpoint to read a
+// registry value. A timeout of 0 uses the renter's default timeout.
+func (c *Client) RegistryReadWithTimeout(spk types.SiaPublicKey, dataKey crypto.Hash, timeout time.Duration) (modules.SignedRegistryValue, error) {
+	values := url.Values{}
+	values.Set("publickey", spk.String())
+	values.Set("datakey", dataKey.String())
+	if timeout > 0 {
+		values.Set("timeout", fmt.Sprint(int(timeout.Seconds())))
+	}
+	var rhg api.RegistryHandlerGET
+	err := c.get("/renter/registry?"+values.Encode(), &rhg)
+	if err != nil {
+		return modules.SignedRegistryValue{}, err
+	}
+	srv, err := registryValueFromGET(dataKey, rhg)
+	if err != nil {
+		return modules.SignedRegistryValue{}, err
+	}
+	return srv, srv.Verify(spk.ToPublicKey())
+}
+
+// RegistryUpdate uses the /renter/registry endpoint to update a registry
+// value.
+func (c *Client) RegistryUpdate(spk types.SiaPublicKey, srv modules.SignedRegistryValue) error {
+	data, err := json.Marshal(api.RegistryHandlerRequestPOST{
+		PublicKey: spk,
+		DataKey:   srv.Tweak,
+		Revision:  srv.Revision,
+		Signature: hex.EncodeToString(srv.Signature[:]),
+		Data:      hex.EncodeToString(srv.Data),
+		Type:      srv.Type,
+	})
+	if err != nil {
+		return err
+	}
+	return c.post("/renter/registry", string(data), nil)
+}
+
+// RegistrySubscribe uses the /renter/registry/subscribe endpoint to subscribe
+// to updates of a registry entry. The subscription needs to be closed by the
+// caller.
+func (c *Client) RegistrySubscribe(spk types.SiaPublicKey, dataKey crypto.Hash) (*RegistrySubscription, error) {
+	values := url.Values{}
+	values.Set("publickey", spk.String())
+	values.Set("datakey", dataKey.String())
+	_, body, err := c.getReaderResponse("/renter/registry/subscribe?" + values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return &RegistrySubscription{
+		body: body,
+		dec:  json.NewDecoder(body),
+	}, nil
+}
+
+// Next blocks until the next update of the registry entry is received.
+func (rs *RegistrySubscription) Next() (types.SiaPublicKey, modules.SignedRegistryValue, error) {
+	var update api.RegistrySubscriptionUpdate
+	if err := rs.dec.Decode(&update); err != nil {
+		return types.SiaPublicKey{}, modules.SignedRegistryValue{}, err
+	}
+	srv, err := registryValueFromGET(update.DataKey, update.RegistryHandlerGET)
+	if err != nil {
+		return types.SiaPublicKey{}, modules.SignedRegistryValue{}, err
+	}
+	return update.PublicKey, srv, srv.Verify(update.PublicKey.ToPublicKey())
+}
+
+// Close closes the subscription.
+func (rs *RegistrySubscription) Close() error {
+	return rs.body.Close()
+}
@@ -12,6 +12,7 @@ import (
 
 	"gitlab.com/NebulousLabs/errors"
 
+	"go.sia.tech/siad/crypto"
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/node/api"
 	"go.sia.tech/siad/types"
@@ -289,6 +290,24 @@ func (c *Client) RenterDownloadGet(siaPath modules.SiaPath, destination string,
 	return modules.DownloadID(h.Get("ID")), nil
 }
 
+// RenterDownloadResumableGet uses the /renter/download endpoint to start an
+// async resumable download of a file to a destination on disk.
+func (c *Client) RenterDownloadResumableGet(siaPath modules.SiaPath, destination string, offset, length uint64, root bool) (modules.DownloadID, error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("destination", destination)
+	values.Set("offset", fmt.Sprint(offset))
+	values.Set("length", fmt.Sprint(length))
+	values.Set("async", "true")
+	values.Set("resumable", "true")
+	values.Set("root", fmt.Sprint(root))
+	h, _, err := c.getRawResponse(fmt.Sprintf("/renter/download/%s?%s", sp, values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	return modules.DownloadID(h.Get("ID")), nil
+}
+
 // RenterDownloadInfoGet uses the /renter/downloadinfo endpoint to fetch
 // information about a download from the history.
 func (c *Client) RenterDownloadInfoGet(uid modules.DownloadID) (di api.DownloadInfo, err error) {
@@ -311,6 +330,16 @@ func (c *Client) RenterBackupsOnHost(host types.SiaPublicKey) (ubs api.RenterBac
 	return
 }
 
+// RenterDirBackups lists the backups of a single directory the renter has
+// uploaded to hosts.
+func (c *Client) RenterDirBackups(siaPath modules.SiaPath, root bool) (ubs api.RenterBackupsGET, err error) {
+	values := url.Values{}
+	values.Set("siapath", siaPath.String())
+	values.Set("root", fmt.Sprint(root))
+	err = c.get("/renter/backups?"+values.Encode(), &ubs)
+	return
+}
+
 // RenterCreateBackupPost creates a backup of the SiaFiles of the renter and
 // uploads it to hosts.
 func (c *Client) RenterCreateBackupPost(name string) (err error) {
@@ -320,6 +349,28 @@ func (c *Client) RenterCreateBackupPost(name string) (err error) {
 	return
 }
 
+// RenterCreateDirBackupPost creates a backup of the SiaFiles within a single
+// directory and uploads it to hosts.
+func (c *Client) RenterCreateDirBackupPost(name string, siaPath modules.SiaPath, root bool) (err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("siapath", siaPath.String())
+	values.Set("root", fmt.Sprint(root))
+	err = c.post("/renter/backups/create", values.Encode(), nil)
+	return
+}
+
+// RenterRecoverDirBackupPost downloads the specified backup and restores it
+// to the target directory.
+func (c *Client) RenterRecoverDirBackupPost(name string, target modules.SiaPath, root bool) (err error) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("siapath", target.String())
+	values.Set("root", fmt.Sprint(root))
+	err = c.post("/renter/backups/restore", values.Encode(), nil)
+	return
+}
+
 // RenterRecoverBackupPost downloads and restores the specified backup.
 func (c *Client) RenterRecoverBackupPost(name string) (err error) {
 	values := url.Values{}
@@ -400,6 +451,13 @@ func (c *Client) RenterClearDownloadsRangePost(after, before time.Time) (err err
 	return
 }
 
+// RenterDownloadsResumePost requests the /renter/downloads/resume resource to
+// resume the persisted downloads.
+func (c *Client) RenterDownloadsResumePost() (err error) {
+	err = c.post("/renter/downloads/resume", "", nil)
+	return
+}
+
 // RenterDownloadsGet requests the /renter/downloads resource
 func (c *Client) RenterDownloadsGet() (rdq api.RenterDownloadQueue, err error) {
 	err = c.get("/renter/downloads", &rdq)
@@ -445,6 +503,25 @@ func (c *Client) RenterFileGet(siaPath modules.SiaPath) (rf api.RenterFile, err
 	return
 }
 
+// RenterFileVersionsGet uses the /renter/file/:siapath/versions endpoint to
+// query the prior versions of a file.
+func (c *Client) RenterFileVersionsGet(siaPath modules.SiaPath, root bool) (rfv api.RenterFileVersions, err error) {
+	sp := escapeSiaPath(siaPath)
+	err = c.get(fmt.Sprintf("/renter/file/%s/versions?root=%v", sp, root), &rfv)
+	return
+}
+
+// RenterFileVersionRestorePost uses the /renter/file/:siapath/versions
+// endpoint to replace a file with one of its prior versions.
+func (c *Client) RenterFileVersionRestorePost(siaPath modules.SiaPath, version uint64, root bool) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("version", strconv.FormatUint(version, 10))
+	values.Set("root", fmt.Sprint(root))
+	err = c.post(fmt.Sprintf("/renter/file/%s/versions", sp), values.Encode(), nil)
+	return
+}
+
 // RenterFilesGet requests the /renter/files resource.
 func (c *Client) RenterFilesGet(cached bool) (rf api.RenterFiles, err error) {
 	err = c.get("/renter/files?cached="+fmt.Sprint(cached), &rf)
@@ -497,6 +574,26 @@ func (c *Client) RenterRateLimitPost(readBPS, writeBPS int64) (err error) {
 	return
 }
 
+// RenterMaxFileVersionsPost uses the /renter endpoint to change the number of
+// prior versions the renter keeps of overwritten files.
+func (c *Client) RenterMaxFileVersionsPost(maxVersions uint64) (err error) {
+	values := url.Values{}
+	values.Set("maxfileversions", strconv.FormatUint(maxVersions, 10))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
+
+// RenterPriorityWeightsPost uses the /renter endpoint to set the weights the
+// renter uses to share its capacity between the priority classes.
+func (c *Client) RenterPriorityWeightsPost(weights modules.PriorityWeights) (err error) {
+	values := url.Values{}
+	values.Set("backgroundweight", strconv.FormatUint(weights.Background, 10))
+	values.Set("userweight", strconv.FormatUint(weights.User, 10))
+	values.Set("interactiveweight", strconv.FormatUint(weights.Interactive, 10))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
+
 // RenterRenamePost uses the /renter/rename/:siapath endpoint to rename a file.
 func (c *Client) RenterRenamePost(siaPathOld, siaPathNew modules.SiaPath, root bool) (err error) {
 	spo := escapeSiaPath(siaPathOld)
@@ -608,6 +705,34 @@ func (c *Client) RenterUploadStreamPost(r io.Reader, siaPath modules.SiaPath, da
 	return err
 }
 
+// RenterUploadStreamDedupPost uploads data using a stream and deduplicates its
+// chunks.
+func (c *Client) RenterUploadStreamDedupPost(r io.Reader, siaPath modules.SiaPath, dataPieces, parityPieces uint64, force bool) error {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("datapieces", strconv.FormatUint(dataPieces, 10))
+	values.Set("paritypieces", strconv.FormatUint(parityPieces, 10))
+	values.Set("force", strconv.FormatBool(force))
+	values.Set("dedup", strconv.FormatBool(true))
+	values.Set("stream", strconv.FormatBool(true))
+	_, _, err := c.postRawResponse(fmt.Sprintf("/renter/uploadstream/%s?%s", sp, values.Encode()), r)
+	return err
+}
+
+// RenterUploadStreamCompressedPost uploads data using a stream and compresses
+// it with the provided compression type.
+func (c *Client) RenterUploadStreamCompressedPost(r io.Reader, siaPath modules.SiaPath, dataPieces, parityPieces uint64, force bool, compression modules.CompressionType) error {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("datapieces", strconv.FormatUint(dataPieces, 10))
+	values.Set("paritypieces", strconv.FormatUint(parityPieces, 10))
+	values.Set("force", strconv.FormatBool(force))
+	values.Set("compression", compression.String())
+	values.Set("stream", strconv.FormatBool(true))
+	_, _, err := c.postRawResponse(fmt.Sprintf("/renter/uploadstream/%s?%s", sp, values.Encode()), r)
+	return err
+}
+
 // RenterUploadStreamRepairPost a siafile using a stream. If the data provided
 // by r is not the same as the previously uploaded data, the data will be
 // corrupted.
@@ -661,6 +786,45 @@ func (c *Client) RenterDirRenamePost(siaPath, newSiaPath modules.SiaPath) (err e
 	return
 }
 
+// RenterDirSetPolicyPost uses the /renter/dir/ endpoint to set the policy of
+// a directory.
+func (c *Client) RenterDirSetPolicyPost(siaPath modules.SiaPath, policy modules.DirPolicy) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("action", "setpolicy")
+	if policy.DataPieces != 0 || policy.ParityPieces != 0 {
+		values.Set("datapieces", strconv.Itoa(policy.DataPieces))
+		values.Set("paritypieces", strconv.Itoa(policy.ParityPieces))
+	}
+	if policy.CipherType != crypto.TypeInvalid {
+		values.Set("ciphertype", policy.CipherType.String())
+	}
+	if policy.RepairThreshold != 0 {
+		values.Set("repairthreshold", strconv.FormatFloat(policy.RepairThreshold, 'f', -1, 64))
+	}
+	err = c.post(fmt.Sprintf("/renter/dir/%s", sp), values.Encode(), nil)
+	return
+}
+
+// RenterDirSetQuotaPost uses the /renter/dir/ endpoint to set the quota of a
+// directory.
+func (c *Client) RenterDirSetQuotaPost(siaPath modules.SiaPath, quota modules.DirQuota) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("action", "setquota")
+	if quota.MaxStoredBytes != 0 {
+		values.Set("maxstoredbytes", strconv.FormatUint(quota.MaxStoredBytes, 10))
+	}
+	if !quota.MaxUploadSpend.IsZero() {
+		values.Set("maxuploadspend", quota.MaxUploadSpend.String())
+	}
+	if !quota.MaxDownloadSpend.IsZero() {
+		values.Set("maxdownloadspend", quota.MaxDownloadSpend.String())
+	}
+	err = c.post(fmt.Sprintf("/renter/dir/%s", sp), values.Encode(), nil)
+	return
+}
+
 // RenterDirRootGet uses the /renter/dir/ endpoint to query a directory,
 // starting from the root path.
 func (c *Client) RenterDirRootGet(siaPath modules.SiaPath) (rd api.RenterDirectory, err error) {
@@ -676,6 +840,38 @@ func (c *Client) RenterDirGet(siaPath modules.SiaPath) (rd api.RenterDirectory,
 	return
 }
 
+// RenterScrubGet requests the /renter/scrub resource to get the status of the
+// current or last scrub.
+func (c *Client) RenterScrubGet() (ss modules.ScrubStatus, err error) {
+	err = c.get("/renter/scrub", &ss)
+	return
+}
+
+// RenterScrubCancelPost uses the /renter/scrub/cancel endpoint to cancel the
+// running scrub.
+func (c *Client) RenterScrubCancelPost() (err error) {
+	err = c.post("/renter/scrub/cancel", "", nil)
+	return
+}
+
+// RenterScrubResultsGet uses the /renter/scrub/results/:siapath endpoint to
+// get the results of the last scrubs of the files within a directory.
+func (c *Client) RenterScrubResultsGet(siaPath modules.SiaPath, recursive, root bool) (rsr api.RenterScrubResults, err error) {
+	sp := escapeSiaPath(siaPath)
+	err = c.get(fmt.Sprintf("/renter/scrub/results/%s?recursive=%v&root=%v", sp, recursive, root), &rsr)
+	return
+}
+
+// RenterScrubStartPost uses the /renter/scrub/start endpoint to start a scrub
+// of a file or of the files within a directory.
+func (c *Client) RenterScrubStartPost(siaPath modules.SiaPath, root bool) (err error) {
+	values := url.Values{}
+	values.Set("siapath", siaPath.String())
+	values.Set("root", fmt.Sprint(root))
+	err = c.post("/renter/scrub/start", values.Encode(), nil)
+	return
+}
+
 // RenterValidateSiaPathPost uses the /renter/validatesiapath endpoint to
 // validate a potential siapath
 //
@@ -733,6 +929,13 @@ func (c *Client) RenterFuseUnmount(mount string) (err error) {
 	return
 }
 
+// RenterUploadsGet uses the /renter/uploads endpoint to get the status of the
+// renter's uploads and repairs.
+func (c *Client) RenterUploadsGet() (rug api.RenterUploadsGet, err error) {
+	err = c.get("/renter/uploads", &rug)
+	return
+}
+
 // RenterUploadsPausePost uses the /renter/uploads/pause endpoint to pause the
 // renter's uploads and repairs
 func (c *Client) RenterUploadsPausePost(duration time.Duration) (err error) {
@@ -0,0 +1,932 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/threadgroup"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/wallet"
+	"go.sia.tech/siad/persist"
+	"go.sia.tech/siad/types"
+)
+
+// Event Overview:
+// The modules don't push state changes, so the eventManager polls them in
+// regular intervals and compares their state to the state of the previous
+// poll. Every difference results in a typed event which is sent to the
+// subscribers of /daemon/events and posted to the webhooks configured in the
+// SiadConfig. A module is only polled if there is a subscriber or webhook for
+// one of its event types. The first poll after that only establishes the
+// state to compare against. Listing the renter's files is expensive, so they
+// are polled in a separate thread with a much longer interval than the other
+// modules. Webhook deliveries which fail are logged to the events log next to
+// the siad config.
+
+// The following consts are the types of the events emitted by the
+// eventManager.
+const (
+	// EventTypeAlertRegistered and EventTypeAlertUnregistered are emitted
+	// when a module registers or unregisters an alert.
+	EventTypeAlertRegistered   = "alert.registered"
+	EventTypeAlertUnregistered = "alert.unregistered"
+
+	// EventTypeContractFormed, EventTypeContractRenewed and
+	// EventTypeContractExpired are emitted when the renter forms a contract,
+	// renews a contract or a contract is no longer active.
+	EventTypeContractFormed  = "contract.formed"
+	EventTypeContractRenewed = "contract.renewed"
+	EventTypeContractExpired = "contract.expired"
+
+	// EventTypeDownloadCompleted and EventTypeDownloadFailed are emitted when
+	// a download finishes.
+	EventTypeDownloadCompleted = "download.completed"
+	EventTypeDownloadFailed    = "download.failed"
+
+	// EventTypeFileHealthChanged is emitted when the health status of an
+	// uploaded file changes.
+	EventTypeFileHealthChanged = "file.healthchanged"
+
+	// EventTypeObligationStatusChanged is emitted when the host creates a
+	// storage obligation or the status of an obligation changes.
+	EventTypeObligationStatusChanged = "obligation.statuschanged"
+
+	// EventTypeUploadCompleted is emitted when a file is fully uploaded.
+	EventTypeUploadCompleted = "upload.completed"
+
+	// EventTypeWalletIncoming is emitted when a confirmed transaction sends
+	// siacoins to the wallet.
+	EventTypeWalletIncoming = "wallet.incoming"
+)
+
+// The following consts are the health statuses of a file reported by
+// EventTypeFileHealthChanged.
+const (
+	// FileHealthStatusHealthy means that the file doesn't need to be
+	// repaired.
+	FileHealthStatusHealthy = "healthy"
+
+	// FileHealthStatusDegraded means that the file's health dropped below the
+	// repair threshold.
+	FileHealthStatusDegraded = "degraded"
+
+	// FileHealthStatusAtRisk means that the file can't be recovered from the
+	// hosts alone anymore but it can still be repaired from the local copy.
+	FileHealthStatusAtRisk = "atrisk"
+
+	// FileHealthStatusUnrecoverable means that the file can't be recovered.
+	FileHealthStatusUnrecoverable = "unrecoverable"
+)
+
+const (
+	// eventsLogFile is the name of the file failed webhook deliveries are
+	// logged to.
+	eventsLogFile = "events.log"
+
+	// eventSubscriberBufferSize is the number of events buffered for a
+	// subscriber. A subscriber which falls further behind is disconnected.
+	eventSubscriberBufferSize = 1000
+
+	// webhookQueueSize is the number of batches of events which are queued
+	// for the webhooks. If the queue is full, new batches are dropped.
+	webhookQueueSize = 100
+)
+
+var (
+	// eventPollInterval is the interval in which the eventManager polls the
+	// modules for changes.
+	eventPollInterval = build.Select(build.Var{
+		Standard: 10 * time.Second,
+		Dev:      5 * time.Second,
+		Testing:  100 * time.Millisecond,
+	}).(time.Duration)
+
+	// eventFilePollInterval is the interval in which the eventManager polls
+	// the renter's files for changes.
+	eventFilePollInterval = build.Select(build.Var{
+		Standard: 10 * time.Minute,
+		Dev:      time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// eventHeartbeatInterval is the interval in which a comment is sent to
+	// the subscribers of /daemon/events to keep the connection alive.
+	eventHeartbeatInterval = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// webhookTimeout is the timeout for posting events to a webhook.
+	webhookTimeout = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      10 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// eventTypes are all the event types emitted by the eventManager.
+	eventTypes = []string{
+		EventTypeAlertRegistered,
+		EventTypeAlertUnregistered,
+		EventTypeContractExpired,
+		EventTypeContractFormed,
+		EventTypeContractRenewed,
+		EventTypeDownloadCompleted,
+		EventTypeDownloadFailed,
+		EventTypeFileHealthChanged,
+		EventTypeObligationStatusChanged,
+		EventTypeUploadCompleted,
+		EventTypeWalletIncoming,
+	}
+)
+
+type (
+	// Event is a state change of a module. The type of Data depends on the
+	// Type of the event.
+	Event struct {
+		ID   uint64      `json:"id"`
+		Type string      `json:"type"`
+		Time time.Time   `json:"time"`
+		Data interface{} `json:"data"`
+	}
+
+	// ContractEvent is the data of the contract events.
+	ContractEvent struct {
+		ID            types.FileContractID  `json:"id"`
+		HostPublicKey types.SiaPublicKey    `json:"hostpublickey"`
+		StartHeight   types.BlockHeight     `json:"startheight"`
+		EndHeight     types.BlockHeight     `json:"endheight"`
+		RenewedFrom   *types.FileContractID `json:"renewedfrom,omitempty"`
+	}
+
+	// DownloadEvent is the data of the download events.
+	DownloadEvent struct {
+		SiaPath     modules.SiaPath `json:"siapath"`
+		Destination string          `json:"destination"`
+		Error       string          `json:"error,omitempty"`
+	}
+
+	// FileHealthEvent is the data of EventTypeFileHealthChanged.
+	FileHealthEvent struct {
+		SiaPath     modules.SiaPath `json:"siapath"`
+		Health      float64         `json:"health"`
+		MaxHealth   float64         `json:"maxhealth"`
+		Stuck       bool            `json:"stuck"`
+		OldStatus   string          `json:"oldstatus"`
+		Status      string          `json:"status"`
+		Recoverable bool            `json:"recoverable"`
+	}
+
+	// ObligationEvent is the data of EventTypeObligationStatusChanged. The
+	// OldStatus of a new obligation is empty.
+	ObligationEvent struct {
+		ID        types.FileContractID `json:"id"`
+		OldStatus string               `json:"oldstatus"`
+		Status    string               `json:"status"`
+	}
+
+	// UploadEvent is the data of EventTypeUploadCompleted.
+	UploadEvent struct {
+		SiaPath modules.SiaPath `json:"siapath"`
+		Size    uint64          `json:"size"`
+	}
+
+	// WalletIncomingEvent is the data of EventTypeWalletIncoming.
+	WalletIncomingEvent struct {
+		TransactionID      types.TransactionID `json:"transactionid"`
+		ConfirmationHeight types.BlockHeight   `json:"confirmationheight"`
+		Value              types.Currency      `json:"value"`
+	}
+
+	// DaemonWebhooksGET contains the webhooks configured in the siad config.
+	DaemonWebhooksGET struct {
+		Webhooks []modules.Webhook `json:"webhooks"`
+	}
+
+	// WebhookPayload is the body of the requests posted to webhooks.
+	WebhookPayload struct {
+		Events []Event `json:"events"`
+	}
+
+	// eventSubscriber is a subscriber of /daemon/events.
+	eventSubscriber struct {
+		events chan Event
+		types  map[string]struct{}
+	}
+
+	// eventFileState is the state of a file during the previous poll.
+	eventFileState struct {
+		uploaded bool
+		status   string
+	}
+
+	// eventState is the state of the modules during the previous poll. A nil
+	// map means that the corresponding module wasn't polled. The files are
+	// only accessed by threadedPollFiles, everything else only by
+	// threadedPoll.
+	eventState struct {
+		alerts      map[modules.Alert]struct{}
+		contracts   map[types.FileContractID]modules.RenterContract
+		downloads   map[string]bool
+		files       map[modules.SiaPath]eventFileState
+		obligations map[types.FileContractID]string
+
+		walletHeight types.BlockHeight
+		walletPolled bool
+	}
+
+	// eventManager polls the modules for state changes and emits them as
+	// events.
+	eventManager struct {
+		staticAPI *API
+
+		nextEventID      uint64
+		nextSubscriberID uint64
+		subscribers      map[uint64]*eventSubscriber
+
+		state eventState
+
+		staticLog          *persist.Logger
+		staticWebhookQueue chan []Event
+
+		started bool
+		mu      sync.Mutex
+		tg      threadgroup.ThreadGroup
+	}
+)
+
+// newEventManager creates a new eventManager for the API. If the events log
+// can't be created, failed webhook deliveries are not logged.
+func newEventManager(api *API) *eventManager {
+	var log *persist.Logger
+	var err error
+	if cfg := api.siadConfig; cfg != nil {
+		log, err = persist.NewFileLogger(filepath.Join(cfg.Dir(), eventsLogFile))
+	}
+	if log == nil {
+		log, err = persist.NewLogger(ioutil.Discard)
+	}
+	if err != nil {
+		build.Critical("failed to create the events logger", err)
+	}
+	return &eventManager{
+		staticAPI:          api,
+		staticLog:          log,
+		subscribers:        make(map[uint64]*eventSubscriber),
+		staticWebhookQueue: make(chan []Event, webhookQueueSize),
+	}
+}
+
+// fileHealthStatus returns the health status of a file.
+func fileHealthStatus(fi modules.FileInfo) string {
+	switch {
+	case !fi.Recoverable:
+		return FileHealthStatusUnrecoverable
+	case fi.MaxHealth > 1:
+		return FileHealthStatusAtRisk
+	case fi.MaxHealth >= modules.RepairThreshold:
+		return FileHealthStatusDegraded
+	default:
+		return FileHealthStatusHealthy
+	}
+}
+
+// parseEventTypes parses a comma separated list of event types.
+func parseEventTypes(str string) ([]string, error) {
+	if str == "" {
+		return nil, nil
+	}
+	var ets []string
+	for _, et := range strings.Split(str, ",") {
+		var known bool
+		for _, t := range eventTypes {
+			known = known || t == et
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event type '%v'", et)
+		}
+		ets = append(ets, et)
+	}
+	return ets, nil
+}
+
+// Close stops the eventManager and disconnects all subscribers.
+func (em *eventManager) Close() error {
+	err := em.tg.Stop()
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	for id, sub := range em.subscribers {
+		delete(em.subscribers, id)
+		close(sub.events)
+	}
+	return errors.Compose(err, em.staticLog.Close())
+}
+
+// callStart starts polling the modules and posting events to the webhooks.
+// Calling it more than once is a no-op.
+func (em *eventManager) callStart() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	if em.started {
+		return
+	}
+	em.started = true
+	go em.threadedPoll()
+	go em.threadedPollFiles()
+	go em.threadedPostWebhooks()
+}
+
+// managedSubscribe subscribes to the events of the given types. If no types
+// are provided, all events are sent to the subscriber. The returned channel is
+// closed when the subscriber falls behind or the eventManager is stopped.
+func (em *eventManager) managedSubscribe(ets []string) (<-chan Event, func(), error) {
+	sub := &eventSubscriber{
+		events: make(chan Event, eventSubscriberBufferSize),
+		types:  make(map[string]struct{}),
+	}
+	for _, et := range ets {
+		sub.types[et] = struct{}{}
+	}
+	em.mu.Lock()
+	select {
+	case <-em.tg.StopChan():
+		em.mu.Unlock()
+		return nil, nil, threadgroup.ErrStopped
+	default:
+	}
+	id := em.nextSubscriberID
+	em.nextSubscriberID++
+	em.subscribers[id] = sub
+	em.mu.Unlock()
+
+	unsubscribe := func() {
+		em.mu.Lock()
+		defer em.mu.Unlock()
+		if _, exists := em.subscribers[id]; exists {
+			delete(em.subscribers, id)
+			close(sub.events)
+		}
+	}
+	return sub.events, unsubscribe, nil
+}
+
+// managedWantedTypes returns the event types which a subscriber or webhook is
+// interested in.
+func (em *eventManager) managedWantedTypes() map[string]bool {
+	wanted := make(map[string]bool)
+	em.mu.Lock()
+	for _, sub := range em.subscribers {
+		for _, et := range eventTypes {
+			_, exists := sub.types[et]
+			wanted[et] = wanted[et] || len(sub.types) == 0 || exists
+		}
+	}
+	em.mu.Unlock()
+	if cfg := em.staticAPI.siadConfig; cfg != nil {
+		for _, wh := range cfg.WebhookList() {
+			for _, et := range eventTypes {
+				wanted[et] = wanted[et] || wh.Wants(et)
+			}
+		}
+	}
+	return wanted
+}
+
+// managedPublish assigns ids to the events and sends them to the subscribers
+// and webhooks.
+func (em *eventManager) managedPublish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	now := time.Now()
+	em.mu.Lock()
+	for i := range events {
+		events[i].ID = em.nextEventID
+		events[i].Time = now
+		em.nextEventID++
+	}
+	for id, sub := range em.subscribers {
+		for _, e := range events {
+			if _, exists := sub.types[e.Type]; len(sub.types) > 0 && !exists {
+				continue
+			}
+			select {
+			case sub.events <- e:
+			default:
+				// The subscriber is too slow. Disconnect it.
+				delete(em.subscribers, id)
+				close(sub.events)
+			}
+			if _, exists := em.subscribers[id]; !exists {
+				break
+			}
+		}
+	}
+	em.mu.Unlock()
+
+	if cfg := em.staticAPI.siadConfig; cfg != nil && len(cfg.WebhookList()) > 0 {
+		select {
+		case em.staticWebhookQueue <- events:
+		default:
+			// The webhooks are too slow. Drop the events.
+		}
+	}
+}
+
+// threadedPoll polls the modules for state changes until the eventManager is
+// stopped.
+func (em *eventManager) threadedPoll() {
+	if err := em.tg.Add(); err != nil {
+		return
+	}
+	defer em.tg.Done()
+	for {
+		select {
+		case <-em.tg.StopChan():
+			return
+		case <-time.After(eventPollInterval):
+		}
+		wanted := em.managedWantedTypes()
+		var events []Event
+		events = append(events, em.alertEvents(wanted)...)
+		events = append(events, em.contractEvents(wanted)...)
+		events = append(events, em.downloadEvents(wanted)...)
+		events = append(events, em.obligationEvents(wanted)...)
+		events = append(events, em.walletEvents(wanted)...)
+		em.managedPublish(events)
+	}
+}
+
+// threadedPollFiles polls the renter's files for state changes until the
+// eventManager is stopped.
+func (em *eventManager) threadedPollFiles() {
+	if err := em.tg.Add(); err != nil {
+		return
+	}
+	defer em.tg.Done()
+	for {
+		select {
+		case <-em.tg.StopChan():
+			return
+		case <-time.After(eventFilePollInterval):
+		}
+		em.managedPublish(em.fileEvents(em.managedWantedTypes()))
+	}
+}
+
+// threadedPostWebhooks posts the published events to the webhooks until the
+// eventManager is stopped.
+func (em *eventManager) threadedPostWebhooks() {
+	if err := em.tg.Add(); err != nil {
+		return
+	}
+	defer em.tg.Done()
+	for {
+		select {
+		case <-em.tg.StopChan():
+			return
+		case events := <-em.staticWebhookQueue:
+			for _, wh := range em.staticAPI.siadConfig.WebhookList() {
+				var payload WebhookPayload
+				for _, e := range events {
+					if wh.Wants(e.Type) {
+						payload.Events = append(payload.Events, e)
+					}
+				}
+				if len(payload.Events) == 0 {
+					continue
+				}
+				// The events are not delivered again.
+				if err := em.managedPostWebhook(wh.URL, payload); err != nil {
+					em.staticLog.Printf("WARN: failed to post %v events to webhook %v: %v", len(payload.Events), wh.URL, err)
+				}
+			}
+		}
+	}
+}
+
+// managedPostWebhook posts a payload to a webhook.
+func (em *eventManager) managedPostWebhook(url string, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-em.tg.StopChan():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Sia-Agent")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %v", resp.Status)
+	}
+	return nil
+}
+
+// alertEvents returns the events for alerts which were registered or
+// unregistered since the previous poll.
+func (em *eventManager) alertEvents(wanted map[string]bool) []Event {
+	if !wanted[EventTypeAlertRegistered] && !wanted[EventTypeAlertUnregistered] {
+		em.state.alerts = nil
+		return nil
+	}
+	alerts := make(map[modules.Alert]struct{})
+	for _, alerter := range em.staticAPI.alerters() {
+		crit, err, warn, info := alerter.Alerts()
+		for _, as := range [][]modules.Alert{crit, err, warn, info} {
+			for _, a := range as {
+				alerts[a] = struct{}{}
+			}
+		}
+	}
+	prev := em.state.alerts
+	em.state.alerts = alerts
+	if prev == nil {
+		return nil
+	}
+	var events []Event
+	for a := range alerts {
+		if _, exists := prev[a]; !exists && wanted[EventTypeAlertRegistered] {
+			events = append(events, Event{Type: EventTypeAlertRegistered, Data: a})
+		}
+	}
+	for a := range prev {
+		if _, exists := alerts[a]; !exists && wanted[EventTypeAlertUnregistered] {
+			events = append(events, Event{Type: EventTypeAlertUnregistered, Data: a})
+		}
+	}
+	return events
+}
+
+// contractEvents returns the events for contracts which were formed, renewed
+// or expired since the previous poll. A new contract with a host whose
+// previous contract stopped being active is considered a renewal.
+func (em *eventManager) contractEvents(wanted map[string]bool) []Event {
+	r := em.staticAPI.renter
+	if r == nil || (!wanted[EventTypeContractFormed] && !wanted[EventTypeContractRenewed] && !wanted[EventTypeContractExpired]) {
+		em.state.contracts = nil
+		return nil
+	}
+	contracts := make(map[types.FileContractID]modules.RenterContract)
+	for _, c := range r.Contracts() {
+		contracts[c.ID] = c
+	}
+	prev := em.state.contracts
+	em.state.contracts = contracts
+	if prev == nil {
+		return nil
+	}
+
+	// Find the contracts which are no longer active.
+	inactive := make(map[string]modules.RenterContract)
+	for id, c := range prev {
+		if _, exists := contracts[id]; !exists {
+			inactive[c.HostPublicKey.String()] = c
+		}
+	}
+	newContractEvent := func(c modules.RenterContract) ContractEvent {
+		return ContractEvent{
+			ID:            c.ID,
+			HostPublicKey: c.HostPublicKey,
+			StartHeight:   c.StartHeight,
+			EndHeight:     c.EndHeight,
+		}
+	}
+	var events []Event
+	for id, c := range contracts {
+		if _, exists := prev[id]; exists {
+			continue
+		}
+		ce := newContractEvent(c)
+		old, renewed := inactive[c.HostPublicKey.String()]
+		if renewed {
+			delete(inactive, c.HostPublicKey.String())
+			ce.RenewedFrom = &old.ID
+			if wanted[EventTypeContractRenewed] {
+				events = append(events, Event{Type: EventTypeContractRenewed, Data: ce})
+			}
+		} else if wanted[EventTypeContractFormed] {
+			events = append(events, Event{Type: EventTypeContractFormed, Data: ce})
+		}
+	}
+	if wanted[EventTypeContractExpired] {
+		for _, c := range inactive {
+			events = append(events, Event{Type: EventTypeContractExpired, Data: newContractEvent(c)})
+		}
+	}
+	return events
+}
+
+// downloadEvents returns the events for downloads which finished since the
+// previous poll.
+func (em *eventManager) downloadEvents(wanted map[string]bool) []Event {
+	r := em.staticAPI.renter
+	if r == nil || (!wanted[EventTypeDownloadCompleted] && !wanted[EventTypeDownloadFailed]) {
+		em.state.downloads = nil
+		return nil
+	}
+	downloads := make(map[string]bool)
+	prev := em.state.downloads
+	var events []Event
+	for _, d := range r.DownloadHistory() {
+		key := fmt.Sprintf("%v:%v:%v", d.SiaPath, d.Destination, d.StartTime.UnixNano())
+		downloads[key] = d.Completed
+		if prev == nil || !d.Completed || prev[key] {
+			continue
+		}
+		de := DownloadEvent{
+			SiaPath:     d.SiaPath,
+			Destination: d.Destination,
+			Error:       d.Error,
+		}
+		if d.Error != "" && wanted[EventTypeDownloadFailed] {
+			events = append(events, Event{Type: EventTypeDownloadFailed, Data: de})
+		} else if d.Error == "" && wanted[EventTypeDownloadCompleted] {
+			events = append(events, Event{Type: EventTypeDownloadCompleted, Data: de})
+		}
+	}
+	em.state.downloads = downloads
+	return events
+}
+
+// fileEvents returns the events for files which finished uploading or whose
+// health status changed since the previous poll. The health status of a file
+// is only reported once it is fully uploaded.
+func (em *eventManager) fileEvents(wanted map[string]bool) []Event {
+	r := em.staticAPI.renter
+	if r == nil || (!wanted[EventTypeUploadCompleted] && !wanted[EventTypeFileHealthChanged]) {
+		em.state.files = nil
+		return nil
+	}
+	files := make(map[modules.SiaPath]eventFileState)
+	var mu sync.Mutex
+	var fis []modules.FileInfo
+	err := r.FileList(modules.RootSiaPath(), true, true, func(fi modules.FileInfo) {
+		mu.Lock()
+		fis = append(fis, fi)
+		mu.Unlock()
+	})
+	if err != nil {
+		// Keep the previous state and try again during the next poll.
+		return nil
+	}
+	prev := em.state.files
+	var events []Event
+	for _, fi := range fis {
+		fs := eventFileState{
+			uploaded: fi.UploadProgress >= 100,
+			status:   fileHealthStatus(fi),
+		}
+		files[fi.SiaPath] = fs
+		if prev == nil || !fs.uploaded {
+			continue
+		}
+		prevFS, exists := prev[fi.SiaPath]
+		if !prevFS.uploaded && wanted[EventTypeUploadCompleted] {
+			events = append(events, Event{Type: EventTypeUploadCompleted, Data: UploadEvent{
+				SiaPath: fi.SiaPath,
+				Size:    fi.Filesize,
+			}})
+		}
+		if exists && prevFS.uploaded && prevFS.status != fs.status && wanted[EventTypeFileHealthChanged] {
+			events = append(events, Event{Type: EventTypeFileHealthChanged, Data: FileHealthEvent{
+				SiaPath:     fi.SiaPath,
+				Health:      fi.Health,
+				MaxHealth:   fi.MaxHealth,
+				Stuck:       fi.Stuck,
+				OldStatus:   prevFS.status,
+				Status:      fs.status,
+				Recoverable: fi.Recoverable,
+			}})
+		}
+	}
+	em.state.files = files
+	return events
+}
+
+// obligationEvents returns the events for storage obligations which were
+// created or whose status changed since the previous poll.
+func (em *eventManager) obligationEvents(wanted map[string]bool) []Event {
+	h := em.staticAPI.host
+	if h == nil || !wanted[EventTypeObligationStatusChanged] {
+		em.state.obligations = nil
+		return nil
+	}
+	obligations := make(map[types.FileContractID]string)
+	prev := em.state.obligations
+	var events []Event
+	for _, so := range h.StorageObligations() {
+		obligations[so.ObligationId] = so.ObligationStatus
+		if prev == nil {
+			continue
+		}
+		if oldStatus, exists := prev[so.ObligationId]; !exists || oldStatus != so.ObligationStatus {
+			events = append(events, Event{Type: EventTypeObligationStatusChanged, Data: ObligationEvent{
+				ID:        so.ObligationId,
+				OldStatus: oldStatus,
+				Status:    so.ObligationStatus,
+			}})
+		}
+	}
+	em.state.obligations = obligations
+	return events
+}
+
+// walletEvents returns the events for transactions which were confirmed since
+// the previous poll and sent siacoins to the wallet.
+func (em *eventManager) walletEvents(wanted map[string]bool) []Event {
+	w := em.staticAPI.wallet
+	if w == nil || !wanted[EventTypeWalletIncoming] {
+		em.state.walletPolled = false
+		return nil
+	}
+	height, err := w.Height()
+	if err != nil {
+		return nil
+	}
+	prevHeight, polled := em.state.walletHeight, em.state.walletPolled
+	if !polled || height <= prevHeight {
+		// Nothing to report on the first poll or after a reorg.
+		em.state.walletHeight, em.state.walletPolled = height, true
+		return nil
+	}
+	pts, err := w.Transactions(prevHeight+1, height)
+	if err != nil {
+		// Try again during the next poll.
+		return nil
+	}
+	em.state.walletHeight = height
+	vts, err := wallet.ComputeValuedTransactions(pts, height)
+	if err != nil {
+		return nil
+	}
+	var events []Event
+	for _, vt := range vts {
+		if vt.ConfirmedIncomingValue.Cmp(vt.ConfirmedOutgoingValue) <= 0 {
+			continue
+		}
+		events = append(events, Event{Type: EventTypeWalletIncoming, Data: WalletIncomingEvent{
+			TransactionID:      vt.TransactionID,
+			ConfirmationHeight: vt.ConfirmationHeight,
+			Value:              vt.ConfirmedIncomingValue.Sub(vt.ConfirmedOutgoingValue),
+		}})
+	}
+	return events
+}
+
+// alerters returns the modules of the API which implement the Alerter
+// interface.
+func (api *API) alerters() []modules.Alerter {
+	var alerters []modules.Alerter
+	if api.gateway != nil {
+		alerters = append(alerters, api.gateway)
+	}
+	if api.cs != nil {
+		alerters = append(alerters, api.cs)
+	}
+	if api.tpool != nil {
+		alerters = append(alerters, api.tpool)
+	}
+	if api.wallet != nil {
+		alerters = append(alerters, api.wallet)
+	}
+	if api.renter != nil {
+		alerters = append(alerters, api.renter)
+	}
+	if api.host != nil {
+		alerters = append(alerters, api.host)
+	}
+	return alerters
+}
+
+// daemonEventsHandlerGET handles the API call to /daemon/events [GET]. It
+// keeps the connection open and sends the events as server-sent events until
+// the client disconnects.
+func (api *API) daemonEventsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	ets, err := parseEventTypes(req.FormValue("types"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, Error{"streaming is not supported by the connection"}, http.StatusInternalServerError)
+		return
+	}
+	events, unsubscribe, err := api.staticEvents.managedSubscribe(ets)
+	if err != nil {
+		WriteError(w, Error{"failed to subscribe to events: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	defer unsubscribe()
+
+	// Send the header right away to signal that the subscription was
+	// established.
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				build.Critical("failed to marshal event", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// daemonWebhooksHandlerGET handles the API call to /daemon/webhooks [GET].
+func (api *API) daemonWebhooksHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, DaemonWebhooksGET{
+		Webhooks: api.siadConfig.WebhookList(),
+	})
+}
+
+// daemonWebhooksHandlerPOST handles the API call to /daemon/webhooks [POST].
+func (api *API) daemonWebhooksHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	webhookURL := req.FormValue("url")
+	if webhookURL == "" {
+		WriteError(w, Error{"url needs to be provided"}, http.StatusBadRequest)
+		return
+	}
+	switch action := req.FormValue("action"); action {
+	case "add":
+		ets, err := parseEventTypes(req.FormValue("events"))
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.siadConfig.AddWebhook(modules.Webhook{
+			URL:    webhookURL,
+			Events: ets,
+		})
+		if err != nil {
+			WriteError(w, Error{"failed to add webhook: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	case "remove":
+		err := api.siadConfig.RemoveWebhook(webhookURL)
+		if errors.Contains(err, modules.ErrUnknownWebhook) {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			WriteError(w, Error{"failed to remove webhook: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	default:
+		WriteError(w, Error{fmt.Sprintf("unknown action '%v'", action)}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/persist"
+)
+
+// TestFileHealthStatus is a unit test for fileHealthStatus.
+func TestFileHealthStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		fi     modules.FileInfo
+		status string
+	}{
+		{modules.FileInfo{Recoverable: true, MaxHealth: 0}, FileHealthStatusHealthy},
+		{modules.FileInfo{Recoverable: true, MaxHealth: modules.RepairThreshold}, FileHealthStatusDegraded},
+		{modules.FileInfo{Recoverable: true, MaxHealth: 1}, FileHealthStatusDegraded},
+		{modules.FileInfo{Recoverable: true, MaxHealth: 1.5}, FileHealthStatusAtRisk},
+		{modules.FileInfo{Recoverable: false, MaxHealth: 1.5}, FileHealthStatusUnrecoverable},
+	}
+	for i, test := range tests {
+		if status := fileHealthStatus(test.fi); status != test.status {
+			t.Errorf("%v: expected %v but got %v", i, test.status, status)
+		}
+	}
+}
+
+// TestParseEventTypes is a unit test for parseEventTypes.
+func TestParseEventTypes(t *testing.T) {
+	t.Parallel()
+
+	if ets, err := parseEventTypes(""); err != nil || ets != nil {
+		t.Fatal("unexpected result", ets, err)
+	}
+	ets, err := parseEventTypes(EventTypeAlertRegistered + "," + EventTypeWalletIncoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ets) != 2 || ets[0] != EventTypeAlertRegistered || ets[1] != EventTypeWalletIncoming {
+		t.Fatal("wrong event types", ets)
+	}
+	if _, err := parseEventTypes("alert.unknown"); err == nil {
+		t.Fatal("expected error for unknown event type")
+	}
+}
+
+// TestEventManager tests publishing events to subscribers and webhooks.
+func TestEventManager(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	testDir := build.TempDir("api", t.Name())
+	if err := os.MkdirAll(testDir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := modules.NewConfig(filepath.Join(testDir, modules.ConfigName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	em := newEventManager(&API{siadConfig: cfg})
+	em.callStart()
+	defer func() {
+		if err := em.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Create a webhook which only receives unregistered alerts.
+	payloads := make(chan WebhookPayload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+	err = cfg.AddWebhook(modules.Webhook{
+		URL:    server.URL,
+		Events: []string{EventTypeAlertUnregistered},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscribe to all events and to registered alerts.
+	all, unsubscribeAll, err := em.managedSubscribe(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeAll()
+	registered, unsubscribeRegistered, err := em.managedSubscribe([]string{EventTypeAlertRegistered})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the wanted event types are polled.
+	wanted := em.managedWantedTypes()
+	for _, et := range eventTypes {
+		if !wanted[et] {
+			t.Fatal("event type should be wanted", et)
+		}
+	}
+
+	// Publish two events.
+	alert := modules.Alert{Module: "test", Msg: "msg", Cause: "cause", Severity: modules.SeverityWarning}
+	em.managedPublish([]Event{
+		{Type: EventTypeAlertRegistered, Data: alert},
+		{Type: EventTypeAlertUnregistered, Data: alert},
+	})
+	for i := uint64(0); i < 2; i++ {
+		if e := <-all; e.ID != i {
+			t.Fatal("wrong event id", e.ID, i)
+		}
+	}
+	if e := <-registered; e.Type != EventTypeAlertRegistered {
+		t.Fatal("wrong event type", e.Type)
+	}
+	select {
+	case e := <-registered:
+		t.Fatal("subscriber received unwanted event", e)
+	default:
+	}
+	select {
+	case payload := <-payloads:
+		if len(payload.Events) != 1 || payload.Events[0].Type != EventTypeAlertUnregistered {
+			t.Fatal("webhook received wrong events", payload.Events)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("webhook didn't receive events")
+	}
+
+	// Unsubscribing closes the channel.
+	unsubscribeRegistered()
+	if _, ok := <-registered; ok {
+		t.Fatal("channel should be closed")
+	}
+
+	// Failed deliveries are logged.
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	err = cfg.AddWebhook(modules.Webhook{URL: failing.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	em.managedPublish([]Event{{Type: EventTypeAlertRegistered, Data: alert}})
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		log, err := ioutil.ReadFile(filepath.Join(testDir, eventsLogFile))
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(log), failing.URL) {
+			return errors.New("failed delivery wasn't logged")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestIntegrationDaemonEvents probes the /daemon/events and /daemon/webhooks
+// endpoints.
+func TestIntegrationDaemonEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Unknown event types are rejected.
+	err = st.stdGetAPI("/daemon/events?types=unknown")
+	if err == nil || !strings.Contains(err.Error(), "unknown event type") {
+		t.Fatal("expected error for unknown event type but got", err)
+	}
+
+	// Subscribe to alerts.
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/daemon/events?types=" + EventTypeAlertRegistered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatal("unexpected content type", ct)
+	}
+
+	// Publish an alert and read it from the stream.
+	alert := modules.Alert{Module: "test", Msg: "msg", Cause: "cause", Severity: modules.SeverityWarning}
+	st.server.api.staticEvents.managedPublish([]Event{{Type: EventTypeAlertRegistered, Data: alert}})
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for scanner.Scan() && scanner.Text() != "" {
+		if strings.HasPrefix(scanner.Text(), ":") {
+			continue // heartbeat
+		}
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id: ") || lines[1] != "event: "+EventTypeAlertRegistered {
+		t.Fatal("unexpected event", lines)
+	}
+	var e struct {
+		Type string        `json:"type"`
+		Data modules.Alert `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != EventTypeAlertRegistered || !e.Data.Equals(alert) {
+		t.Fatal("unexpected event", e)
+	}
+
+	// Add and remove a webhook.
+	values := url.Values{}
+	values.Set("action", "add")
+	values.Set("url", "http://localhost:1234/events")
+	values.Set("events", EventTypeWalletIncoming)
+	if err := st.stdPostAPI("/daemon/webhooks", values); err != nil {
+		t.Fatal(err)
+	}
+	var dwg DaemonWebhooksGET
+	if err := st.getAPI("/daemon/webhooks", &dwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(dwg.Webhooks) != 1 || dwg.Webhooks[0].URL != values.Get("url") || len(dwg.Webhooks[0].Events) != 1 {
+		t.Fatal("unexpected webhooks", dwg.Webhooks)
+	}
+	values.Set("action", "remove")
+	if err := st.stdPostAPI("/daemon/webhooks", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/daemon/webhooks", values); err == nil {
+		t.Fatal("removing an unknown webhook should fail")
+	}
+	if err := st.getAPI("/daemon/webhooks", &dwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(dwg.Webhooks) != 0 {
+		t.Fatal("webhook wasn't removed", dwg.Webhooks)
+	}
+}
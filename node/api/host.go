@@ -1,10 +1,12 @@
 package api
 
 import (
+	"encoding/csv"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -37,8 +39,10 @@ var (
 type (
 	// ContractInfoGET contains the information that is returned after a GET request
 	// to /host/contracts - information for the host about stored obligations.
+	// Total is the number of obligations matching the query before paging.
 	ContractInfoGET struct {
 		Contracts []modules.StorageObligation `json:"contracts"`
+		Total     uint64                      `json:"total"`
 	}
 
 	// HostContractGET contains information about the storage contract returned
@@ -67,12 +71,25 @@ type (
 		ConversionRate float64        `json:"conversionrate"`
 	}
 
+	// HostPricingGET contains the policy of the host's pricing engine and the
+	// most recent price changes it made.
+	HostPricingGET struct {
+		Policy  modules.HostPricingPolicy   `json:"policy"`
+		Changes []modules.HostPricingChange `json:"changes"`
+	}
+
 	// StorageGET contains the information that is returned after a GET request
 	// to /host/storage - a bunch of information about the status of storage
 	// management on the host.
 	StorageGET struct {
 		Folders []modules.StorageFolderMetadata `json:"folders"`
 	}
+
+	// StorageScrubGET contains the status of the host's scrubber, which
+	// periodically verifies the integrity of all stored sectors.
+	StorageScrubGET struct {
+		modules.StorageScrubStatus
+	}
 )
 
 // RegisterRoutesHost is a helper function to register all host routes.
@@ -96,6 +113,12 @@ func RegisterRoutesHost(router *httprouter.Router, h modules.Host, deps modules.
 	router.GET("/host/bandwidth", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		hostBandwidthHandlerGET(h, w, req, ps)
 	})
+	router.GET("/host/pricing", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		hostPricingHandlerGET(h, w, req, ps)
+	})
+	router.POST("/host/pricing", RequirePassword(func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		hostPricingHandlerPOST(h, w, req, ps)
+	}, requiredPassword))
 
 	// Calls pertaining to the storage manager that the host uses.
 	router.GET("/host/storage", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -110,6 +133,12 @@ func RegisterRoutesHost(router *httprouter.Router, h modules.Host, deps modules.
 	router.POST("/host/storage/folders/resize", RequirePassword(func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		storageFoldersResizeHandler(h, w, req, ps)
 	}, requiredPassword))
+	router.POST("/host/storage/folders/tier", RequirePassword(func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		storageFoldersTierHandler(h, w, req, ps)
+	}, requiredPassword))
+	router.GET("/host/storage/scrub", func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		storageScrubHandler(h, w, req, ps)
+	})
 	router.POST("/host/storage/sectors/delete/:merkleroot", RequirePassword(func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		storageSectorsDeleteHandler(h, w, req, ps)
 	}, requiredPassword))
@@ -150,13 +179,96 @@ func hostContractGetHandler(host modules.Host, w http.ResponseWriter, _ *http.Re
 	})
 }
 
+// parseStorageObligationQuery parses the filter, sort and paging parameters
+// of a /host/contracts request.
+func parseStorageObligationQuery(req *http.Request) (modules.HostStorageObligationQuery, error) {
+	var query modules.HostStorageObligationQuery
+	if status := req.FormValue("status"); status != "" {
+		query.Statuses = strings.Split(status, ",")
+	}
+	query.ProofOutcome = req.FormValue("proofoutcome")
+	query.SortBy = req.FormValue("sortby")
+	switch order := req.FormValue("sortorder"); order {
+	case "", "asc":
+	case "desc":
+		query.SortDesc = true
+	default:
+		return modules.HostStorageObligationQuery{}, fmt.Errorf("unknown sort order '%v'", order)
+	}
+	fields := []struct {
+		name  string
+		value interface{}
+	}{
+		{"minexpirationheight", &query.MinExpirationHeight},
+		{"maxexpirationheight", &query.MaxExpirationHeight},
+		{"minnegotiationheight", &query.MinNegotiationHeight},
+		{"maxnegotiationheight", &query.MaxNegotiationHeight},
+		{"minrevenue", &query.MinRevenue},
+		{"maxrevenue", &query.MaxRevenue},
+		{"offset", &query.Offset},
+		{"limit", &query.Limit},
+	}
+	for _, field := range fields {
+		if req.FormValue(field.name) == "" {
+			continue
+		}
+		if _, err := fmt.Sscan(req.FormValue(field.name), field.value); err != nil {
+			return modules.HostStorageObligationQuery{}, fmt.Errorf("unable to parse %v: %v", field.name, err)
+		}
+	}
+	return query, query.Validate()
+}
+
+// writeStorageObligationsCSV writes the storage obligations as CSV with one
+// row per obligation and a breakdown of its revenue. All amounts are in
+// hastings.
+func writeStorageObligationsCSV(w http.ResponseWriter, sos []modules.StorageObligation) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="contracts.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{
+		"obligationid", "obligationstatus", "negotiationheight", "expirationheight", "proofdeadline", "datasize",
+		"contractcost", "potentialstoragerevenue", "potentialuploadrevenue", "potentialdownloadrevenue",
+		"potentialaccountfunding", "potentialrevenue", "lockedcollateral", "riskedcollateral",
+		"transactionfeesadded", "proofconfirmed",
+	})
+	for _, so := range sos {
+		_ = cw.Write([]string{
+			so.ObligationId.String(), so.ObligationStatus, fmt.Sprint(so.NegotiationHeight), fmt.Sprint(so.ExpirationHeight),
+			fmt.Sprint(so.ProofDeadLine), fmt.Sprint(so.DataSize), so.ContractCost.String(), so.PotentialStorageRevenue.String(),
+			so.PotentialUploadRevenue.String(), so.PotentialDownloadRevenue.String(), so.PotentialAccountFunding.String(),
+			so.PotentialRevenue().String(), so.LockedCollateral.String(), so.RiskedCollateral.String(),
+			so.TransactionFeesAdded.String(), fmt.Sprint(so.ProofConfirmed),
+		})
+	}
+	cw.Flush()
+}
+
 // hostContractInfoHandler handles the API call to get the contract information of the host.
 // Information is retrieved via the storage obligations from the host database.
-func hostContractInfoHandler(host modules.Host, w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	cg := ContractInfoGET{
-		Contracts: host.StorageObligations(),
+// The obligations can be filtered, sorted, paged and exported as CSV.
+func hostContractInfoHandler(host modules.Host, w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	query, err := parseStorageObligationQuery(req)
+	if err != nil {
+		WriteError(w, Error{"error parsing query: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	sos, total, err := host.StorageObligationsQuery(query)
+	if err != nil {
+		WriteError(w, Error{"failed to get storage obligations: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	switch format := req.FormValue("format"); format {
+	case "", "json":
+		WriteJSON(w, ContractInfoGET{
+			Contracts: sos,
+			Total:     total,
+		})
+	case "csv":
+		writeStorageObligationsCSV(w, sos)
+	default:
+		WriteError(w, Error{fmt.Sprintf("unknown format '%v'", format)}, http.StatusBadRequest)
 	}
-	WriteJSON(w, cg)
 }
 
 // hostHandlerGET handles GET requests to the /host API endpoint, returning key
@@ -366,6 +478,30 @@ func parseHostSettings(host modules.Host, req *http.Request) (modules.HostIntern
 	if req.FormValue("customregistrypath") != "" {
 		settings.CustomRegistryPath = req.FormValue("customregistrypath")
 	}
+	if req.FormValue("maxconcurrentprograms") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxconcurrentprograms"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxConcurrentPrograms = x
+	}
+	if req.FormValue("peerbandwidthlimit") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("peerbandwidthlimit"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.PeerBandwidthLimit = x
+	}
+	if req.FormValue("peerrpcratelimit") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("peerrpcratelimit"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.PeerRPCRateLimit = x
+	}
 
 	// Validate the RPC, Sector Access, and Download Prices
 	minBaseRPCPrice := settings.MinBaseRPCPrice
@@ -458,6 +594,78 @@ func hostHandlerPOST(host modules.Host, w http.ResponseWriter, req *http.Request
 	WriteSuccess(w)
 }
 
+// parseHostPricingPolicy fetches the host's pricing policy and updates it with
+// the fields set in the request.
+func parseHostPricingPolicy(host modules.Host, req *http.Request) (modules.HostPricingPolicy, error) {
+	policy, _ := host.PricingPolicy()
+
+	if req.FormValue("source") != "" {
+		policy.Source = req.FormValue("source")
+	}
+	if req.FormValue("exchangerate") != "" {
+		policy.ExchangeRate = req.FormValue("exchangerate")
+	}
+	if req.FormValue("updateinterval") != "" {
+		interval, err := time.ParseDuration(req.FormValue("updateinterval"))
+		if err != nil {
+			return modules.HostPricingPolicy{}, fmt.Errorf("unable to parse updateinterval: %v", err)
+		}
+		policy.UpdateInterval = interval
+	}
+	fields := []struct {
+		name  string
+		value interface{}
+	}{
+		{"enabled", &policy.Enabled},
+		{"fiatcontractprice", &policy.FiatContractPrice},
+		{"fiatdownloadprice", &policy.FiatDownloadPrice},
+		{"fiatstorageprice", &policy.FiatStoragePrice},
+		{"fiatuploadprice", &policy.FiatUploadPrice},
+		{"networkpercentile", &policy.NetworkPercentile},
+		{"targetutilization", &policy.TargetUtilization},
+		{"utilizationadjustment", &policy.UtilizationAdjustment},
+		{"collateralratio", &policy.CollateralRatio},
+		{"minstorageprice", &policy.MinStoragePrice},
+		{"maxstorageprice", &policy.MaxStoragePrice},
+	}
+	for _, field := range fields {
+		if req.FormValue(field.name) == "" {
+			continue
+		}
+		if _, err := fmt.Sscan(req.FormValue(field.name), field.value); err != nil {
+			return modules.HostPricingPolicy{}, fmt.Errorf("unable to parse %v: %v", field.name, err)
+		}
+	}
+	return policy, nil
+}
+
+// hostPricingHandlerGET handles GET requests to the /host/pricing API
+// endpoint, which returns the policy of the host's pricing engine and its
+// recent price changes.
+func hostPricingHandlerGET(host modules.Host, w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	policy, changes := host.PricingPolicy()
+	WriteJSON(w, HostPricingGET{
+		Policy:  policy,
+		Changes: changes,
+	})
+}
+
+// hostPricingHandlerPOST handles POST requests to the /host/pricing API
+// endpoint, which updates the policy of the host's pricing engine.
+func hostPricingHandlerPOST(host modules.Host, w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	policy, err := parseHostPricingPolicy(host, req)
+	if err != nil {
+		WriteError(w, Error{"error parsing pricing policy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = host.SetPricingPolicy(policy)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // hostAnnounceHandler handles the API call to get the host to announce itself
 // to the network.
 func hostAnnounceHandler(host modules.Host, w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
@@ -553,6 +761,37 @@ func storageFoldersRemoveHandler(host modules.Host, w http.ResponseWriter, req *
 	WriteSuccess(w)
 }
 
+// storageFoldersTierHandler sets the tier of a storage folder in the storage
+// manager.
+func storageFoldersTierHandler(host modules.Host, w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	folderPath := req.FormValue("path")
+	if folderPath == "" {
+		WriteError(w, Error{"path parameter is required"}, http.StatusBadRequest)
+		return
+	}
+
+	storageFolders := host.StorageFolders()
+	folderIndex, err := folderIndex(folderPath, storageFolders)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	err = host.SetStorageFolderTier(uint16(folderIndex), req.FormValue("tier"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// storageScrubHandler returns the status of the host's scrubber.
+func storageScrubHandler(host modules.Host, w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, StorageScrubGET{
+		StorageScrubStatus: host.ScrubStatus(),
+	})
+}
+
 // storageSectorsDeleteHandler handles the call to delete a sector from the
 // storage manager.
 func storageSectorsDeleteHandler(host modules.Host, w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
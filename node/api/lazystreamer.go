@@ -0,0 +1,105 @@
+package api
+
+import (
+	"io"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+	"go.sia.tech/siad/modules"
+)
+
+var (
+	// errLazyStreamerClosed is returned when reading from a closed
+	// lazyStreamer.
+	errLazyStreamerClosed = errors.New("streamer was closed")
+)
+
+// lazyStreamer is a helper struct that wraps a modules.Streamer which is only
+// opened once data is read from it. Since the size of the stream is known in
+// advance, seeking doesn't require opening the underlying stream. This allows
+// for answering HEAD requests and conditional requests without fetching any
+// data from the hosts.
+//
+// The lazyStreamer is thread safe since http.ServeContent reads multipart
+// ranges from a separate goroutine which might still be running when the
+// handler closes the streamer.
+type lazyStreamer struct {
+	staticOpen func() (modules.Streamer, error)
+	staticSize int64
+
+	closed bool
+	off    int64
+	stream modules.Streamer
+	mu     sync.Mutex
+}
+
+// newLazyStreamer creates a new lazyStreamer for a stream of the given size.
+// open is called to open the underlying stream on the first read.
+func newLazyStreamer(size uint64, open func() (modules.Streamer, error)) *lazyStreamer {
+	return &lazyStreamer{
+		staticOpen: open,
+		staticSize: int64(size),
+	}
+}
+
+// Read implements the io.Reader interface
+func (ls *lazyStreamer) Read(p []byte) (int, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.closed {
+		return 0, errLazyStreamerClosed
+	}
+	if ls.off >= ls.staticSize {
+		return 0, io.EOF
+	}
+	// Open the stream if necessary.
+	if ls.stream == nil {
+		stream, err := ls.staticOpen()
+		if err != nil {
+			return 0, errors.AddContext(err, "failed to open stream")
+		}
+		if _, err := stream.Seek(ls.off, io.SeekStart); err != nil {
+			return 0, errors.Compose(err, stream.Close())
+		}
+		ls.stream = stream
+	}
+	n, err := ls.stream.Read(p)
+	ls.off += int64(n)
+	return n, err
+}
+
+// Seek implements the io.Seeker interface
+func (ls *lazyStreamer) Seek(offset int64, whence int) (int64, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += ls.off
+	case io.SeekEnd:
+		offset += ls.staticSize
+	default:
+		return ls.off, errors.New("invalid value for 'whence' in call to seek")
+	}
+	if offset < 0 {
+		return ls.off, errors.New("cannot seek to negative offset")
+	}
+	if ls.stream != nil {
+		if _, err := ls.stream.Seek(offset, io.SeekStart); err != nil {
+			return ls.off, err
+		}
+	}
+	ls.off = offset
+	return offset, nil
+}
+
+// Close implements the io.Closer interface
+func (ls *lazyStreamer) Close() error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.closed = true
+	if ls.stream == nil {
+		return nil
+	}
+	return ls.stream.Close()
+}
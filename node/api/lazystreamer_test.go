@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/fastrand"
+	"go.sia.tech/siad/modules"
+)
+
+// TestLazyStreamer verifies that the lazy streamer only opens the underlying
+// stream when data is read and returns the right data.
+func TestLazyStreamer(t *testing.T) {
+	data := []byte("Hello, this is some not so random text")
+	opened := 0
+	ls := newLazyStreamer(uint64(len(data)), func() (modules.Streamer, error) {
+		opened++
+		return streamerFromSlice(data), nil
+	})
+
+	// Seeking doesn't open the stream.
+	end, err := ls.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end != int64(len(data)) {
+		t.Fatalf("expected size %v but was %v", len(data), end)
+	}
+	if _, err := ls.Seek(20, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if opened != 0 {
+		t.Fatal("stream shouldn't be opened yet")
+	}
+
+	// Reading opens the stream at the current offset.
+	allData, err := ioutil.ReadAll(ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(allData, []byte("not so random text")) {
+		t.Fatal("unexpected data", string(allData))
+	}
+	// Seeking after opening the stream is forwarded.
+	if _, err := ls.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(ls, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "this" {
+		t.Fatal("unexpected data", string(buf))
+	}
+	if opened != 1 {
+		t.Fatalf("stream should be opened once but was opened %v times", opened)
+	}
+
+	// Reading after closing fails.
+	if err := ls.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.Read(buf); err != errLazyStreamerClosed {
+		t.Fatal("expected errLazyStreamerClosed, got", err)
+	}
+}
+
+// TestLazyStreamerServeContent verifies that serving a lazy streamer over HTTP
+// doesn't open the stream for HEAD requests and conditional requests and that
+// range requests return the right data.
+func TestLazyStreamerServeContent(t *testing.T) {
+	data := fastrand.Bytes(1000)
+	etag := `"abcdef"`
+	modTime := time.Unix(1600000000, 0)
+	opened := 0
+
+	// serve serves the data for the request and returns the response.
+	serve := func(method string, headers map[string]string) *http.Response {
+		req := httptest.NewRequest(method, "/renter/stream/file", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		ls := newLazyStreamer(uint64(len(data)), func() (modules.Streamer, error) {
+			opened++
+			return streamerFromSlice(data), nil
+		})
+		defer ls.Close()
+		rec := httptest.NewRecorder()
+		rec.Header().Set("ETag", etag)
+		rec.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(rec, req, "file", modTime, ls)
+		return rec.Result()
+	}
+
+	// HEAD requests don't fetch any data.
+	resp := serve(http.MethodHead, nil)
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len(data)) {
+		t.Fatal("unexpected response", resp.StatusCode, resp.ContentLength)
+	}
+	resp = serve(http.MethodHead, map[string]string{"Range": "bytes=0-9,20-29"})
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatal("unexpected status", resp.StatusCode)
+	}
+	// Conditional requests for unchanged content don't fetch any data.
+	resp = serve(http.MethodGet, map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatal("unexpected status", resp.StatusCode)
+	}
+	resp = serve(http.MethodGet, map[string]string{"If-Modified-Since": modTime.UTC().Format(http.TimeFormat)})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatal("unexpected status", resp.StatusCode)
+	}
+	if opened != 0 {
+		t.Fatalf("stream shouldn't be opened but was opened %v times", opened)
+	}
+
+	// A single range.
+	resp = serve(http.MethodGet, map[string]string{"Range": "bytes=100-199"})
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[100:200]) {
+		t.Fatal("unexpected response", resp.StatusCode, len(body))
+	}
+	// If-Range with an outdated ETag returns the whole file.
+	resp = serve(http.MethodGet, map[string]string{"Range": "bytes=100-199", "If-Range": `"outdated"`})
+	body, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatal("unexpected response", resp.StatusCode, len(body))
+	}
+	// Multiple ranges.
+	resp = serve(http.MethodGet, map[string]string{"Range": "bytes=0-9,500-509"})
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || mediaType != "multipart/byteranges" {
+		t.Fatal("unexpected response", resp.StatusCode, mediaType)
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for _, expected := range [][]byte{data[:10], data[500:510]} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		partData, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(partData, expected) {
+			t.Fatal("unexpected part data")
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/types"
+)
+
+const (
+	// metricsContentType is the content type of the Prometheus text
+	// exposition format.
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// metricTypeCounter and metricTypeGauge are the types of the exported
+	// metrics.
+	metricTypeCounter = "counter"
+	metricTypeGauge   = "gauge"
+)
+
+// metricsLabelEscaper escapes label values according to the Prometheus text
+// exposition format.
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type (
+	// metricFamily is a set of samples of the same metric which only differ in
+	// their labels.
+	metricFamily struct {
+		name    string
+		help    string
+		typ     string
+		samples []string
+	}
+
+	// metrics collects metric families and writes them in the Prometheus text
+	// exposition format.
+	metrics struct {
+		families []*metricFamily
+		byName   map[string]*metricFamily
+	}
+)
+
+// newMetrics creates an empty set of metrics.
+func newMetrics() *metrics {
+	return &metrics{
+		byName: make(map[string]*metricFamily),
+	}
+}
+
+// add adds a sample to the metric with the given name. The labels are
+// provided as name value pairs. Samples of the same metric are grouped
+// together when writing the metrics.
+func (m *metrics) add(name, typ, help string, value float64, labels ...string) {
+	mf, exists := m.byName[name]
+	if !exists {
+		mf = &metricFamily{
+			name: name,
+			help: help,
+			typ:  typ,
+		}
+		m.byName[name] = mf
+		m.families = append(m.families, mf)
+	}
+	var sample strings.Builder
+	sample.WriteString(name)
+	if len(labels) > 0 {
+		sample.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				sample.WriteString(",")
+			}
+			fmt.Fprintf(&sample, `%s="%s"`, labels[i], metricsLabelEscaper.Replace(labels[i+1]))
+		}
+		sample.WriteString("}")
+	}
+	sample.WriteString(" ")
+	sample.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	mf.samples = append(mf.samples, sample.String())
+}
+
+// addBool adds a gauge which is 1 if b is true and 0 otherwise.
+func (m *metrics) addBool(name, help string, b bool, labels ...string) {
+	var value float64
+	if b {
+		value = 1
+	}
+	m.add(name, metricTypeGauge, help, value, labels...)
+}
+
+// addCurrency adds a gauge for an amount of hastings.
+func (m *metrics) addCurrency(name, help string, c types.Currency, labels ...string) {
+	value, _ := new(big.Float).SetInt(c.Big()).Float64()
+	m.add(name, metricTypeGauge, help, value, labels...)
+}
+
+// Bytes returns the metrics in the Prometheus text exposition format.
+func (m *metrics) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, mf := range m.families {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", mf.name, mf.help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", mf.name, mf.typ)
+		for _, sample := range mf.samples {
+			buf.WriteString(sample)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// metricsHandlerGET handles the API call to /metrics. It exports the metrics
+// of all the modules which are enabled in the Prometheus text exposition
+// format.
+func (api *API) metricsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	m := newMetrics()
+	if api.cs != nil {
+		consensusMetrics(m, api.cs)
+	}
+	if api.gateway != nil {
+		if err := gatewayMetrics(m, api.gateway); err != nil {
+			WriteError(w, Error{"failed to get gateway metrics: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
+	if api.host != nil {
+		hostMetrics(m, api.host)
+	}
+	if api.renter != nil {
+		if err := renterMetrics(m, api.renter); err != nil {
+			WriteError(w, Error{"failed to get renter metrics: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
+	if api.tpool != nil {
+		tpoolMetrics(m, api.tpool)
+	}
+	if api.wallet != nil {
+		if err := walletMetrics(m, api.wallet); err != nil {
+			WriteError(w, Error{"failed to get wallet metrics: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(m.Bytes())
+}
+
+// consensusMetrics adds the metrics of the consensus set.
+func consensusMetrics(m *metrics, cs modules.ConsensusSet) {
+	m.add("siad_consensus_height", metricTypeGauge, "Height of the current block.", float64(cs.Height()))
+	m.addBool("siad_consensus_synced", "Whether the consensus set is synced with the network.", cs.Synced())
+}
+
+// gatewayMetrics adds the metrics of the gateway.
+func gatewayMetrics(m *metrics, g modules.Gateway) error {
+	var inbound, outbound int
+	for _, p := range g.Peers() {
+		if p.Inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+	}
+	const peersHelp = "Number of peers the gateway is connected to."
+	m.add("siad_gateway_peers", metricTypeGauge, peersHelp, float64(inbound), "direction", "inbound")
+	m.add("siad_gateway_peers", metricTypeGauge, peersHelp, float64(outbound), "direction", "outbound")
+	m.addBool("siad_gateway_online", "Whether the gateway is connected to at least one non-local peer.", g.Online())
+
+	upload, download, _, err := g.BandwidthCounters()
+	if err != nil {
+		return err
+	}
+	const bandwidthHelp = "Number of bytes transferred by the gateway since siad started."
+	m.add("siad_gateway_bandwidth_bytes_total", metricTypeCounter, bandwidthHelp, float64(upload), "direction", "upload")
+	m.add("siad_gateway_bandwidth_bytes_total", metricTypeCounter, bandwidthHelp, float64(download), "direction", "download")
+	return nil
+}
+
+// hostMetrics adds the financial metrics and storage folder usage of the host.
+func hostMetrics(m *metrics, h modules.Host) {
+	fm := h.FinancialMetrics()
+	m.add("siad_host_contracts", metricTypeGauge, "Number of active storage obligations of the host.", float64(fm.ContractCount))
+
+	// Revenue which was already earned and revenue which will be earned once
+	// the storage obligations succeed.
+	const revenueHelp = "Revenue of the host in hastings."
+	revenue := []struct {
+		source    string
+		realized  types.Currency
+		potential types.Currency
+	}{
+		{"accountfunding", fm.AccountFunding, fm.PotentialAccountFunding},
+		{"contract", fm.ContractCompensation, fm.PotentialContractCompensation},
+		{"downloadbandwidth", fm.DownloadBandwidthRevenue, fm.PotentialDownloadBandwidthRevenue},
+		{"storage", fm.StorageRevenue, fm.PotentialStorageRevenue},
+		{"uploadbandwidth", fm.UploadBandwidthRevenue, fm.PotentialUploadBandwidthRevenue},
+	}
+	for _, r := range revenue {
+		m.addCurrency("siad_host_revenue_hastings", revenueHelp, r.realized, "source", r.source, "state", "realized")
+		m.addCurrency("siad_host_revenue_hastings", revenueHelp, r.potential, "source", r.source, "state", "potential")
+	}
+	m.addCurrency("siad_host_lost_revenue_hastings", "Revenue the host lost due to failed storage obligations in hastings.", fm.LostRevenue)
+
+	const collateralHelp = "Storage collateral of the host in hastings."
+	m.addCurrency("siad_host_collateral_hastings", collateralHelp, fm.LockedStorageCollateral, "state", "locked")
+	m.addCurrency("siad_host_collateral_hastings", collateralHelp, fm.RiskedStorageCollateral, "state", "risked")
+	m.addCurrency("siad_host_collateral_hastings", collateralHelp, fm.LostStorageCollateral, "state", "lost")
+	m.addCurrency("siad_host_transaction_fee_expenses_hastings", "Transaction fees paid by the host in hastings.", fm.TransactionFeeExpenses)
+
+	// Storage folders.
+	for _, sf := range h.StorageFolders() {
+		m.add("siad_host_storage_folder_capacity_bytes", metricTypeGauge, "Capacity of a storage folder.", float64(sf.Capacity), "path", sf.Path)
+		m.add("siad_host_storage_folder_remaining_bytes", metricTypeGauge, "Remaining capacity of a storage folder.", float64(sf.CapacityRemaining), "path", sf.Path)
+
+		const opsHelp = "Number of reads and writes of a storage folder."
+		m.add("siad_host_storage_folder_operations_total", metricTypeCounter, opsHelp, float64(sf.SuccessfulReads), "path", sf.Path, "op", "read", "result", "success")
+		m.add("siad_host_storage_folder_operations_total", metricTypeCounter, opsHelp, float64(sf.FailedReads), "path", sf.Path, "op", "read", "result", "failure")
+		m.add("siad_host_storage_folder_operations_total", metricTypeCounter, opsHelp, float64(sf.SuccessfulWrites), "path", sf.Path, "op", "write", "result", "success")
+		m.add("siad_host_storage_folder_operations_total", metricTypeCounter, opsHelp, float64(sf.FailedWrites), "path", sf.Path, "op", "write", "result", "failure")
+	}
+}
+
+// renterMetrics adds the metrics of the renter's worker pool, upload heap and
+// memory managers.
+func renterMetrics(m *metrics, r modules.Renter) error {
+	// Worker pool.
+	wps, err := r.WorkerPoolStatus()
+	if err != nil {
+		return err
+	}
+	m.add("siad_renter_workers", metricTypeGauge, "Number of workers in the worker pool.", float64(wps.NumWorkers))
+
+	const cooldownHelp = "Number of workers on cooldown."
+	m.add("siad_renter_workers_on_cooldown", metricTypeGauge, cooldownHelp, float64(wps.TotalDownloadCoolDown), "job", "download")
+	m.add("siad_renter_workers_on_cooldown", metricTypeGauge, cooldownHelp, float64(wps.TotalMaintenanceCoolDown), "job", "maintenance")
+	m.add("siad_renter_workers_on_cooldown", metricTypeGauge, cooldownHelp, float64(wps.TotalUploadCoolDown), "job", "upload")
+	var readRegistryCooldown, updateRegistryCooldown, activePriceTables int
+	queues := make(map[string]uint64)
+	for _, ws := range wps.Workers {
+		if ws.PriceTableStatus.Active {
+			activePriceTables++
+		}
+		if ws.ReadRegistryJobsStatus.OnCooldown {
+			readRegistryCooldown++
+		}
+		if ws.UpdateRegistryJobsStatus.OnCooldown {
+			updateRegistryCooldown++
+		}
+		queues["download"] += uint64(ws.DownloadQueueSize)
+		queues["downloadsnapshot"] += uint64(ws.DownloadSnapshotJobQueueSize)
+		queues["hassector"] += ws.HasSectorJobsStatus.JobQueueSize
+		queues["read"] += ws.ReadJobsStatus.JobQueueSize
+		queues["readregistry"] += ws.ReadRegistryJobsStatus.JobQueueSize
+		queues["updateregistry"] += ws.UpdateRegistryJobsStatus.JobQueueSize
+		queues["upload"] += uint64(ws.UploadQueueSize)
+		queues["uploadsnapshot"] += uint64(ws.UploadSnapshotJobQueueSize)
+	}
+	m.add("siad_renter_workers_on_cooldown", metricTypeGauge, cooldownHelp, float64(readRegistryCooldown), "job", "readregistry")
+	m.add("siad_renter_workers_on_cooldown", metricTypeGauge, cooldownHelp, float64(updateRegistryCooldown), "job", "updateregistry")
+	m.add("siad_renter_workers_price_table_active", metricTypeGauge, "Number of workers with a valid price table.", float64(activePriceTables))
+	for _, job := range []string{"download", "downloadsnapshot", "hassector", "read", "readregistry", "updateregistry", "upload", "uploadsnapshot"} {
+		m.add("siad_renter_worker_job_queue_size", metricTypeGauge, "Number of jobs queued across all workers.", float64(queues[job]), "job", job)
+	}
+
+	// Upload heap. Repairs are scheduled in the background class.
+	classes, err := r.UploadPriorityClasses()
+	if err != nil {
+		return err
+	}
+	for _, pc := range classes {
+		class := pc.Class.String()
+		m.add("siad_renter_upload_heap_chunks", metricTypeGauge, "Number of chunks waiting in the upload heap.", float64(pc.QueuedChunks), "class", class)
+		m.add("siad_renter_upload_active_chunks", metricTypeGauge, "Number of chunks which are being uploaded.", float64(pc.ActiveChunks), "class", class)
+		m.add("siad_renter_upload_finished_chunks_total", metricTypeCounter, "Number of chunks which finished uploading since siad started.", float64(pc.FinishedChunks), "class", class)
+	}
+
+	// Memory managers.
+	ms, err := r.MemoryStatus()
+	if err != nil {
+		return err
+	}
+	managers := []struct {
+		name   string
+		status modules.MemoryManagerStatus
+	}{
+		{"registry", ms.Registry},
+		{"system", ms.System},
+		{"total", ms.MemoryManagerStatus},
+		{"userdownload", ms.UserDownload},
+		{"userupload", ms.UserUpload},
+	}
+	for _, mm := range managers {
+		m.add("siad_renter_memory_available_bytes", metricTypeGauge, "Memory available in a memory manager.", float64(mm.status.Available), "manager", mm.name)
+		m.add("siad_renter_memory_base_bytes", metricTypeGauge, "Base memory of a memory manager.", float64(mm.status.Base), "manager", mm.name)
+		m.add("siad_renter_memory_requested_bytes", metricTypeGauge, "Memory requested from a memory manager which is not available yet.", float64(mm.status.Requested), "manager", mm.name)
+	}
+	return nil
+}
+
+// tpoolMetrics adds the metrics of the transaction pool.
+func tpoolMetrics(m *metrics, tp modules.TransactionPool) {
+	m.add("siad_tpool_transactions", metricTypeGauge, "Number of unconfirmed transactions in the transaction pool.", float64(len(tp.Transactions())))
+	minFee, maxFee := tp.FeeEstimation()
+	const feeHelp = "Recommended transaction fee per byte in hastings."
+	m.addCurrency("siad_tpool_fee_estimate_hastings", feeHelp, minFee, "bound", "min")
+	m.addCurrency("siad_tpool_fee_estimate_hastings", feeHelp, maxFee, "bound", "max")
+}
+
+// walletMetrics adds the metrics of the wallet. The balance is only exported
+// while the wallet is unlocked.
+func walletMetrics(m *metrics, w modules.Wallet) error {
+	unlocked, err := w.Unlocked()
+	if err != nil {
+		return err
+	}
+	m.addBool("siad_wallet_unlocked", "Whether the wallet is unlocked.", unlocked)
+	if !unlocked {
+		return nil
+	}
+	siacoins, _, _, err := w.ConfirmedBalance()
+	if err != nil {
+		return err
+	}
+	m.addCurrency("siad_wallet_confirmed_balance_hastings", "Confirmed siacoin balance of the wallet in hastings.", siacoins)
+	return nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/types"
+)
+
+// TestMetricsBytes is a unit test for writing metrics in the Prometheus text
+// exposition format.
+func TestMetricsBytes(t *testing.T) {
+	t.Parallel()
+
+	m := newMetrics()
+	m.add("siad_a", metricTypeGauge, "A gauge.", 1.5, "label", "x")
+	m.add("siad_b", metricTypeCounter, "A counter.", 2)
+	m.add("siad_a", metricTypeGauge, "A gauge.", 3, "label", "y\"\\\n", "other", "z")
+	m.addBool("siad_c", "A bool.", true)
+	m.addCurrency("siad_d", "A currency.", types.SiacoinPrecision)
+
+	expected := `# HELP siad_a A gauge.
+# TYPE siad_a gauge
+siad_a{label="x"} 1.5
+siad_a{label="y\"\\\n",other="z"} 3
+# HELP siad_b A counter.
+# TYPE siad_b counter
+siad_b 2
+# HELP siad_c A bool.
+# TYPE siad_c gauge
+siad_c 1
+# HELP siad_d A currency.
+# TYPE siad_d gauge
+siad_d 1e+24
+`
+	if got := string(m.Bytes()); got != expected {
+		t.Fatalf("unexpected metrics\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+// TestIntegrationMetricsGET probes the GET call to /metrics.
+func TestIntegrationMetricsGET(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// The endpoint is reachable without the Sia-Agent useragent.
+	req, err := http.NewRequest("GET", "http://"+st.server.listener.Addr().String()+"/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "Prometheus/2.22.0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err := errors.Compose(err, resp.Body.Close()); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status code", resp.StatusCode, string(body))
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != metricsContentType {
+		t.Fatal("unexpected content type", ct)
+	}
+
+	// The metrics of all the modules are exported.
+	metrics := string(body)
+	for _, name := range []string{
+		"siad_consensus_height",
+		"siad_gateway_peers",
+		"siad_host_contracts",
+		"siad_renter_workers",
+		"siad_renter_memory_available_bytes",
+		"siad_tpool_transactions",
+		"siad_wallet_unlocked",
+	} {
+		if !strings.Contains(metrics, "# TYPE "+name+" ") {
+			t.Error("metric is missing", name)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter"
+	"go.sia.tech/siad/types"
+)
+
+type (
+	// RegistryHandlerGET is the response returned by the registryHandlerGET
+	// handler.
+	RegistryHandlerGET struct {
+		Data      string                    `json:"data"`
+		Revision  uint64                    `json:"revision"`
+		Signature string                    `json:"signature"`
+		Type      modules.RegistryEntryType `json:"type"`
+	}
+
+	// RegistryHandlerRequestPOST is the expected format of the json request
+	// for /renter/registry [POST]. The data and signature are hex encoded.
+	RegistryHandlerRequestPOST struct {
+		PublicKey types.SiaPublicKey        `json:"publickey"`
+		DataKey   crypto.Hash               `json:"datakey"`
+		Revision  uint64                    `json:"revision"`
+		Signature string                    `json:"signature"`
+		Data      string                    `json:"data"`
+		Type      modules.RegistryEntryType `json:"type"`
+	}
+
+	// RegistrySubscriptionUpdate is a single update of a registry entry sent
+	// by the /renter/registry/subscribe [GET] endpoint. The updates are
+	// newline delimited json objects.
+	RegistrySubscriptionUpdate struct {
+		PublicKey types.SiaPublicKey `json:"publickey"`
+		DataKey   crypto.Hash        `json:"datakey"`
+		RegistryHandlerGET
+	}
+)
+
+// newRegistryHandlerGET converts a SignedRegistryValue into a
+// RegistryHandlerGET.
+func newRegistryHandlerGET(srv modules.SignedRegistryValue) RegistryHandlerGET {
+	return RegistryHandlerGET{
+		Data:      hex.EncodeToString(srv.Data),
+		Revision:  srv.Revision,
+		Signature: hex.EncodeToString(srv.Signature[:]),
+		Type:      srv.Type,
+	}
+}
+
+// parseRegistryEntryID parses the publickey and datakey query string
+// parameters which identify a registry entry.
+func parseRegistryEntryID(req *http.Request) (types.SiaPublicKey, crypto.Hash, error) {
+	var spk types.SiaPublicKey
+	err := spk.LoadString(req.FormValue("publickey"))
+	if err != nil {
+		return types.SiaPublicKey{}, crypto.Hash{}, errors.AddContext(err, "unable to parse publickey param")
+	}
+	var dataKey crypto.Hash
+	err = dataKey.LoadString(req.FormValue("datakey"))
+	if err != nil {
+		return types.SiaPublicKey{}, crypto.Hash{}, errors.AddContext(err, "unable to parse datakey param")
+	}
+	return spk, dataKey, nil
+}
+
+// renterRegistryHandlerGET handles the API call to /renter/registry [GET].
+func (api *API) renterRegistryHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	spk, dataKey, err := parseRegistryEntryID(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Parse the timeout.
+	timeout := renter.MaxRegistryReadTimeout
+	if timeoutStr := req.FormValue("timeout"); timeoutStr != "" {
+		timeoutInt, err := strconv.Atoi(timeoutStr)
+		if err != nil {
+			WriteError(w, Error{"unable to parse timeout param: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if timeoutInt < 1 || time.Duration(timeoutInt)*time.Second > renter.MaxRegistryReadTimeout {
+			WriteError(w, Error{fmt.Sprintf("timeout has to be between 1 and %v seconds", int(renter.MaxRegistryReadTimeout.Seconds()))}, http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(timeoutInt) * time.Second
+	}
+
+	srv, err := api.renter.ReadRegistry(spk, dataKey, timeout)
+	if errors.Contains(err, renter.ErrRegistryEntryNotFound) ||
+		errors.Contains(err, renter.ErrRegistryLookupTimeout) {
+		WriteError(w, Error{err.Error()}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		WriteError(w, Error{fmt.Sprintf("failed to read registry: %v", err)}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, newRegistryHandlerGET(srv))
+}
+
+// renterRegistryHandlerPOST handles the API call to /renter/registry [POST].
+func (api *API) renterRegistryHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var rhp RegistryHandlerRequestPOST
+	err := json.NewDecoder(req.Body).Decode(&rhp)
+	if err != nil {
+		WriteError(w, Error{"Failed to decode request: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	data, err := hex.DecodeString(rhp.Data)
+	if err != nil {
+		WriteError(w, Error{"unable to decode data: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if len(data) > modules.RegistryDataSize {
+		WriteError(w, Error{fmt.Sprintf("data can't be larger than %v bytes", modules.RegistryDataSize)}, http.StatusBadRequest)
+		return
+	}
+	sigBytes, err := hex.DecodeString(rhp.Signature)
+	if err != nil {
+		WriteError(w, Error{"unable to decode signature: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var sig crypto.Signature
+	if len(sigBytes) != len(sig) {
+		WriteError(w, Error{fmt.Sprintf("signature needs to be %v bytes", len(sig))}, http.StatusBadRequest)
+		return
+	}
+	copy(sig[:], sigBytes)
+
+	// Entries without a type default to entries without a pubkey.
+	entryType := rhp.Type
+	if entryType == modules.RegistryTypeInvalid {
+		entryType = modules.RegistryTypeWithoutPubkey
+	}
+	srv := modules.NewSignedRegistryValue(rhp.DataKey, data, rhp.Revision, sig, entryType)
+
+	// Verify the signature before sending the entry to the hosts.
+	if err := srv.Verify(rhp.PublicKey.ToPublicKey()); err != nil {
+		WriteError(w, Error{"invalid signature: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.renter.UpdateRegistry(rhp.PublicKey, srv, renter.DefaultRegistryUpdateTimeout)
+	if err != nil {
+		WriteError(w, Error{"Unable to update the registry: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterRegistrySubscribeHandlerGET handles the API call to
+// /renter/registry/subscribe [GET]. It keeps the connection open and writes
+// every update of the entry as a newline delimited json object until the
+// client disconnects.
+func (api *API) renterRegistrySubscribeHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	spk, dataKey, err := parseRegistryEntryID(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, Error{"streaming is not supported by the connection"}, http.StatusInternalServerError)
+		return
+	}
+	updates, unsubscribe, err := api.renter.SubscribeRegistry(spk, dataKey)
+	if err != nil {
+		WriteError(w, Error{"failed to subscribe to registry entry: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	defer unsubscribe()
+
+	// Send the header right away to signal that the subscription was
+	// established.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case srv, ok := <-updates:
+			if !ok {
+				return
+			}
+			err := enc.Encode(RegistrySubscriptionUpdate{
+				PublicKey:          spk,
+				DataKey:            dataKey,
+				RegistryHandlerGET: newRegistryHandlerGET(srv),
+			})
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
@@ -27,6 +28,13 @@ import (
 	"go.sia.tech/siad/types"
 )
 
+const (
+	// fileVersionsSuffix is the suffix of the /renter/file/*siapath/versions
+	// endpoints. The router doesn't support routes which continue after the
+	// siapath so it is stripped from the siapath by the file handlers.
+	fileVersionsSuffix = "/versions"
+)
+
 var (
 	// requiredHosts specifies the minimum number of hosts that must be set in
 	// the renter settings for the renter settings to be valid. This minimum is
@@ -162,6 +170,10 @@ type (
 	RenterDirectory struct {
 		Directories []modules.DirectoryInfo `json:"directories"`
 		Files       []modules.FileInfo      `json:"files"`
+
+		// Policy is the effective policy of the directory which includes the
+		// fields inherited from its parent directories.
+		Policy modules.DirPolicy `json:"policy"`
 	}
 
 	// RenterDownloadQueue contains the renter's download queue.
@@ -174,6 +186,11 @@ type (
 		File modules.FileInfo `json:"file"`
 	}
 
+	// RenterFileVersions lists the prior versions of the file queried.
+	RenterFileVersions struct {
+		Versions []modules.FileVersion `json:"versions"`
+	}
+
 	// RenterFiles lists the files known to the renter.
 	RenterFiles struct {
 		Files []modules.FileInfo `json:"files"`
@@ -212,6 +229,7 @@ type (
 		CreationDate   types.Timestamp `json:"creationdate"`
 		Size           uint64          `json:"size"`
 		UploadProgress float64         `json:"uploadprogress"`
+		SiaPath        modules.SiaPath `json:"siapath"`
 	}
 
 	// RenterBackupsGET lists the renter's uploaded backups, as well as the
@@ -238,6 +256,16 @@ type (
 		ParityPieces int `json:"paritypieces"`
 	}
 
+	// RenterUploadsGet contains the status of the renter's uploads and
+	// repairs.
+	RenterUploadsGet struct {
+		modules.UploadsStatus
+
+		// PriorityClasses contains the status of every priority class from
+		// the most to the least urgent one.
+		PriorityClasses []modules.PriorityClassStatus `json:"priorityclasses"`
+	}
+
 	// DownloadInfo contains all client-facing information of a file.
 	DownloadInfo struct {
 		Destination     string          `json:"destination"`     // The destination of the download.
@@ -245,6 +273,7 @@ type (
 		Filesize        uint64          `json:"filesize"`        // DEPRECATED. Same as 'Length'.
 		Length          uint64          `json:"length"`          // The length requested for the download.
 		Offset          uint64          `json:"offset"`          // The offset within the siafile requested for the download.
+		Resumable       bool            `json:"resumable"`       // Whether the download is resumed after a restart.
 		SiaPath         modules.SiaPath `json:"siapath"`         // The siapath of the file used for the download.
 
 		Completed            bool      `json:"completed"`            // Whether or not the download has completed.
@@ -416,14 +445,25 @@ outer:
 		}
 	}
 
-	rups := make([]RenterUploadedBackup, len(backups))
-	for i, b := range backups {
-		rups[i] = RenterUploadedBackup{
+	// if requested, only return the backups of a specific directory
+	siaPath, filter, err := scanBackupSiaPath(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	rups := make([]RenterUploadedBackup, 0, len(backups))
+	for _, b := range backups {
+		if filter && !b.SiaPath.Equals(siaPath) {
+			continue
+		}
+		rups = append(rups, RenterUploadedBackup{
 			Name:           b.Name,
 			CreationDate:   b.CreationDate,
 			Size:           b.Size,
 			UploadProgress: b.UploadProgress,
-		}
+			SiaPath:        b.SiaPath,
+		})
 	}
 	WriteJSON(w, RenterBackupsGET{
 		Backups:       rups,
@@ -467,13 +507,29 @@ func (api *API) renterBackupsCreateHandlerPOST(w http.ResponseWriter, req *http.
 	// Derive the secret and wipe it afterwards.
 	secret := crypto.HashAll(rs, modules.BackupKeySpecifier)
 	defer fastrand.Read(secret[:])
+	// Check if only a single directory should be backed up.
+	siaPath, dirBackup, err := scanBackupSiaPath(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
 	// Create the backup.
-	if err := api.renter.CreateBackup(backupPath, secret[:32]); err != nil {
+	if dirBackup {
+		err = api.renter.CreateDirBackup(backupPath, siaPath, secret[:32])
+	} else {
+		err = api.renter.CreateBackup(backupPath, secret[:32])
+	}
+	if err != nil {
 		WriteError(w, Error{"failed to create backup: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
 	// Upload the backup.
-	if err := api.renter.UploadBackup(backupPath, name); err != nil {
+	if dirBackup {
+		err = api.renter.UploadDirBackup(backupPath, name, siaPath)
+	} else {
+		err = api.renter.UploadBackup(backupPath, name)
+	}
+	if err != nil {
 		WriteError(w, Error{"failed to upload backup: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
@@ -488,6 +544,26 @@ func (api *API) renterBackupsRestoreHandlerGET(w http.ResponseWriter, req *http.
 		WriteError(w, Error{"name not specified"}, http.StatusBadRequest)
 		return
 	}
+	// Backups of a single directory are restored to their original directory
+	// unless a different target was specified.
+	target, dirRestore, err := scanBackupSiaPath(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if !dirRestore {
+		backups, _, err := api.renter.UploadedBackups()
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		for _, b := range backups {
+			if b.Name == name && !b.SiaPath.IsEmpty() {
+				target, dirRestore = b.SiaPath, true
+				break
+			}
+		}
+	}
 	// Write the backup to a temporary file and delete it after loading.
 	tmpDir, err := ioutil.TempDir("", "sia-backup")
 	if err != nil {
@@ -515,13 +591,44 @@ func (api *API) renterBackupsRestoreHandlerGET(w http.ResponseWriter, req *http.
 	secret := crypto.HashAll(rs, modules.BackupKeySpecifier)
 	defer fastrand.Read(secret[:])
 	// Load the backup.
-	if err := api.renter.LoadBackup(backupPath, secret[:32]); err != nil {
+	if dirRestore {
+		err = api.renter.LoadDirBackup(backupPath, secret[:32], target)
+	} else {
+		err = api.renter.LoadBackup(backupPath, secret[:32])
+	}
+	if err != nil {
 		WriteError(w, Error{"failed to load backup: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
 	WriteSuccess(w)
 }
 
+// scanBackupSiaPath parses the optional siapath of a directory backup from a
+// request. The siapath is relative to the user's home directory unless the
+// root flag is set. The returned bool indicates whether a siapath was
+// specified.
+func scanBackupSiaPath(req *http.Request) (modules.SiaPath, bool, error) {
+	siaPathStr := req.FormValue("siapath")
+	if siaPathStr == "" {
+		return modules.SiaPath{}, false, nil
+	}
+	siaPath, err := modules.NewSiaPath(siaPathStr)
+	if err != nil {
+		return modules.SiaPath{}, false, errors.AddContext(err, "unable to parse siapath")
+	}
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		return modules.SiaPath{}, false, err
+	}
+	if !root {
+		siaPath, err = rebaseInputSiaPath(siaPath)
+		if err != nil {
+			return modules.SiaPath{}, false, err
+		}
+	}
+	return siaPath, true, nil
+}
+
 // renterBackupHandlerPOST handles the API calls to /renter/backup
 func (api *API) renterBackupHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	// Check that destination was specified.
@@ -590,8 +697,10 @@ func (api *API) renterLoadBackupHandlerPOST(w http.ResponseWriter, req *http.Req
 
 // parseErasureCodingParameters parses the supplied string values and creates
 // an erasure coder. If values haven't been supplied it will fill in sane
-// defaults.
-func parseErasureCodingParameters(strDataPieces, strParityPieces string) (modules.ErasureCoder, error) {
+// defaults. If a number of local groups is supplied, a locally repairable code
+// is created which uses one parity piece per local group and the remaining
+// parity pieces as global parity.
+func parseErasureCodingParameters(strDataPieces, strParityPieces, strLocalGroups string) (modules.ErasureCoder, error) {
 	// Parse data and parity pieces
 	dataPieces, parityPieces, err := ParseDataAndParityPieces(strDataPieces, strParityPieces)
 	if err != nil {
@@ -600,6 +709,9 @@ func parseErasureCodingParameters(strDataPieces, strParityPieces string) (module
 
 	// Check if data and parity pieces were set
 	if dataPieces == 0 && parityPieces == 0 {
+		if strLocalGroups != "" {
+			return nil, errors.New("can't specify 'localgroups' without 'datapieces' and 'paritypieces'")
+		}
 		return nil, nil
 	}
 
@@ -615,8 +727,82 @@ func parseErasureCodingParameters(strDataPieces, strParityPieces string) (module
 		return nil, err
 	}
 
-	// Create the erasure coder.
-	return modules.NewRSSubCode(dataPieces, parityPieces, crypto.SegmentSize)
+	// Create a locally repairable code if local groups were specified.
+	if strLocalGroups != "" {
+		var localGroups int
+		_, err = fmt.Sscan(strLocalGroups, &localGroups)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to read parameter 'localgroups'")
+		}
+		if localGroups <= 0 || localGroups > parityPieces {
+			return nil, fmt.Errorf("'localgroups' needs to be between 1 and the number of parity pieces %v", parityPieces)
+		}
+		return modules.NewLRCode(dataPieces, localGroups, parityPieces-localGroups)
+	}
+
+	// Create the erasure coder. Codes which are too wide for GF(2^8) are
+	// created over GF(2^16).
+	return modules.NewPartialEncodingRSCode(dataPieces, parityPieces)
+}
+
+// parseDirPolicy parses the policy of a directory from a request. Parameters
+// which are not provided are left unset which means that they are inherited
+// from the parent directory.
+func parseDirPolicy(req *http.Request) (modules.DirPolicy, error) {
+	var policy modules.DirPolicy
+	// Parse the erasure coding parameters and make sure they are accepted
+	// for uploads.
+	dataPiecesStr, parityPiecesStr := req.FormValue("datapieces"), req.FormValue("paritypieces")
+	if _, err := parseErasureCodingParameters(dataPiecesStr, parityPiecesStr, ""); err != nil {
+		return modules.DirPolicy{}, err
+	}
+	dataPieces, parityPieces, err := ParseDataAndParityPieces(dataPiecesStr, parityPiecesStr)
+	if err != nil {
+		return modules.DirPolicy{}, err
+	}
+	policy.DataPieces, policy.ParityPieces = dataPieces, parityPieces
+	// Parse the cipher type.
+	if ct := req.FormValue("ciphertype"); ct != "" {
+		if err := policy.CipherType.FromString(ct); err != nil {
+			return modules.DirPolicy{}, errors.AddContext(err, "unable to parse 'ciphertype'")
+		}
+	}
+	// Parse the repair threshold.
+	if rt := req.FormValue("repairthreshold"); rt != "" {
+		policy.RepairThreshold, err = strconv.ParseFloat(rt, 64)
+		if err != nil {
+			return modules.DirPolicy{}, errors.AddContext(err, "unable to parse 'repairthreshold'")
+		}
+	}
+	return policy, policy.Validate()
+}
+
+// parseDirQuota parses the quota of a directory from a request. Limits which
+// are not provided are not enforced.
+func parseDirQuota(req *http.Request) (modules.DirQuota, error) {
+	var quota modules.DirQuota
+	if msb := req.FormValue("maxstoredbytes"); msb != "" {
+		var err error
+		quota.MaxStoredBytes, err = strconv.ParseUint(msb, 10, 64)
+		if err != nil {
+			return modules.DirQuota{}, errors.AddContext(err, "unable to parse 'maxstoredbytes'")
+		}
+	}
+	if mus := req.FormValue("maxuploadspend"); mus != "" {
+		spend, ok := scanAmount(mus)
+		if !ok {
+			return modules.DirQuota{}, errors.New("unable to parse 'maxuploadspend'")
+		}
+		quota.MaxUploadSpend = spend
+	}
+	if mds := req.FormValue("maxdownloadspend"); mds != "" {
+		spend, ok := scanAmount(mds)
+		if !ok {
+			return modules.DirQuota{}, errors.New("unable to parse 'maxdownloadspend'")
+		}
+		quota.MaxDownloadSpend = spend
+	}
+	return quota, nil
 }
 
 // ParseDataAndParityPieces parse the numeric values for dataPieces and
@@ -944,6 +1130,38 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 		}
 		settings.MaxUploadSpeed = uploadSpeed
 	}
+	// Scan the number of file versions to keep. (optional parameter)
+	if v := req.FormValue("maxfileversions"); v != "" {
+		var maxFileVersions uint64
+		if _, err := fmt.Sscan(v, &maxFileVersions); err != nil {
+			WriteError(w, Error{"unable to parse maxfileversions: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.MaxFileVersions = maxFileVersions
+	}
+	// Scan the priority weights. (optional parameters)
+	weights := []struct {
+		param  string
+		weight *uint64
+	}{
+		{"backgroundweight", &settings.PriorityWeights.Background},
+		{"userweight", &settings.PriorityWeights.User},
+		{"interactiveweight", &settings.PriorityWeights.Interactive},
+	}
+	for _, pw := range weights {
+		v := req.FormValue(pw.param)
+		if v == "" {
+			continue
+		}
+		if _, err := fmt.Sscan(v, pw.weight); err != nil {
+			WriteError(w, Error{"unable to parse " + pw.param + ": " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := settings.PriorityWeights.Validate(); err != nil {
+		WriteError(w, Error{"invalid priority weights: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
 
 	// Scan the checkforipviolation flag.
 	if ipc := req.FormValue("checkforipviolation"); ipc != "" {
@@ -1258,6 +1476,17 @@ func (api *API) renterClearDownloadsHandler(w http.ResponseWriter, req *http.Req
 	WriteSuccess(w)
 }
 
+// renterDownloadsResumeHandlerPOST handles the API call to
+// /renter/downloads/resume.
+func (api *API) renterDownloadsResumeHandlerPOST(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	err := api.renter.ResumeDownloads()
+	if err != nil {
+		WriteError(w, Error{"failed to resume downloads: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterContractorChurnStatus handles the API call to request the churn status
 // from the renter's contractor.
 func (api *API) renterContractorChurnStatus(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
@@ -1288,6 +1517,7 @@ func (api *API) renterDownloadsHandler(w http.ResponseWriter, req *http.Request,
 			Filesize:        di.Length,
 			Length:          di.Length,
 			Offset:          di.Offset,
+			Resumable:       di.Resumable,
 			SiaPath:         di.SiaPath,
 
 			Completed:            di.Completed,
@@ -1324,6 +1554,7 @@ func (api *API) renterDownloadByUIDHandlerGET(w http.ResponseWriter, _ *http.Req
 		Filesize:        di.Length,
 		Length:          di.Length,
 		Offset:          di.Offset,
+		Resumable:       di.Resumable,
 		SiaPath:         di.SiaPath,
 
 		Completed:            di.Completed,
@@ -1470,6 +1701,11 @@ func (api *API) renterRenameHandler(w http.ResponseWriter, req *http.Request, ps
 
 // renterFileHandler handles GET requests to the /renter/file/:siapath API endpoint.
 func (api *API) renterFileHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	// Check if the user wants the versions of the file instead.
+	if strings.HasSuffix(ps.ByName("siapath"), fileVersionsSuffix) {
+		api.renterFileVersionsHandlerGET(w, req, strings.TrimSuffix(ps.ByName("siapath"), fileVersionsSuffix))
+		return
+	}
 	// Determine the siapath that the user wants to get the file from.
 	siaPath, err := modules.NewSiaPath(ps.ByName("siapath"))
 	if err != nil {
@@ -1517,6 +1753,11 @@ func (api *API) renterFileHandlerGET(w http.ResponseWriter, req *http.Request, p
 
 // renterFileHandler handles POST requests to the /renter/file/:siapath API endpoint.
 func (api *API) renterFileHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	// Check if the user wants to restore a version of the file instead.
+	if strings.HasSuffix(ps.ByName("siapath"), fileVersionsSuffix) {
+		api.renterFileVersionsHandlerPOST(w, req, strings.TrimSuffix(ps.ByName("siapath"), fileVersionsSuffix))
+		return
+	}
 	newTrackingPath := req.FormValue("trackingpath")
 	stuck := req.FormValue("stuck")
 	root, err := scanBool(req.FormValue("root"))
@@ -1558,6 +1799,68 @@ func (api *API) renterFileHandlerPOST(w http.ResponseWriter, req *http.Request,
 	WriteSuccess(w)
 }
 
+// renterFileVersionsHandlerGET handles GET requests to the
+// /renter/file/:siapath/versions API endpoint.
+func (api *API) renterFileVersionsHandlerGET(w http.ResponseWriter, req *http.Request, siaPathStr string) {
+	siaPath, err := modules.NewSiaPath(siaPathStr)
+	if err != nil {
+		WriteError(w, Error{"unable to parse siapath: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if !root {
+		siaPath, err = rebaseInputSiaPath(siaPath)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	versions, err := api.renter.FileVersions(siaPath)
+	if err != nil {
+		WriteError(w, Error{"unable to get file versions: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterFileVersions{
+		Versions: versions,
+	})
+}
+
+// renterFileVersionsHandlerPOST handles POST requests to the
+// /renter/file/:siapath/versions API endpoint.
+func (api *API) renterFileVersionsHandlerPOST(w http.ResponseWriter, req *http.Request, siaPathStr string) {
+	siaPath, err := modules.NewSiaPath(siaPathStr)
+	if err != nil {
+		WriteError(w, Error{"unable to parse siapath: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if !root {
+		siaPath, err = rebaseInputSiaPath(siaPath)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	version, err := strconv.ParseUint(req.FormValue("version"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"unable to parse version: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.RestoreFileVersion(siaPath, version); err != nil {
+		WriteError(w, Error{"unable to restore file version: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterFilesHandler handles the API call to list all of the files.
 func (api *API) renterFilesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	var c bool
@@ -1809,6 +2112,10 @@ func parseDownloadParameters(w http.ResponseWriter, req *http.Request, ps httpro
 	// disk if available.
 	disablelocalfetchparam := req.FormValue("disablelocalfetch")
 
+	// resumableparam determines whether the download is resumed after a
+	// restart.
+	resumableparam := req.FormValue("resumable")
+
 	// Parse the offset and length parameters.
 	var offset, length uint64
 	if len(offsetparam) > 0 {
@@ -1862,6 +2169,13 @@ func parseDownloadParameters(w http.ResponseWriter, req *http.Request, ps httpro
 			return modules.RenterDownloadParameters{}, errors.AddContext(err, "error parsing the disablelocalfetch flag")
 		}
 	}
+	var resumable bool
+	if resumableparam != "" {
+		resumable, err = scanBool(resumableparam)
+		if err != nil {
+			return modules.RenterDownloadParameters{}, errors.AddContext(err, "error parsing the resumable flag")
+		}
+	}
 
 	dp := modules.RenterDownloadParameters{
 		Destination:      destination,
@@ -1869,6 +2183,7 @@ func parseDownloadParameters(w http.ResponseWriter, req *http.Request, ps httpro
 		Async:            async,
 		Length:           length,
 		Offset:           offset,
+		Resumable:        resumable,
 		SiaPath:          siaPath,
 	}
 	if httpresp {
@@ -1908,16 +2223,34 @@ func (api *API) renterStreamHandler(w http.ResponseWriter, req *http.Request, ps
 			return
 		}
 	}
-	fileName, streamer, err := api.renter.Streamer(siaPath, disableLocalFetch)
+	info, err := api.renter.StreamInfo(siaPath)
 	if err != nil {
 		WriteError(w, Error{fmt.Sprintf("failed to create download streamer: %v", err)},
 			http.StatusInternalServerError)
 		return
 	}
+
+	// The streamer is only created once data is read. That way HEAD requests
+	// and conditional requests for unchanged content don't fetch any data
+	// from the hosts. http.ServeContent handles ranges, multipart ranges and
+	// the conditional headers using the ETag and the modification time.
+	streamer := newLazyStreamer(info.Size, func() (modules.Streamer, error) {
+		_, s, err := api.renter.Streamer(siaPath, disableLocalFetch)
+		return s, err
+	})
 	defer func() {
 		_ = streamer.Close()
 	}()
-	http.ServeContent(w, req, fileName, time.Time{}, streamer)
+	w.Header().Set("ETag", `"`+info.ETag+`"`)
+
+	// Set the content type to prevent http.ServeContent from sniffing it by
+	// reading the beginning of the file.
+	contentType := mime.TypeByExtension(filepath.Ext(info.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, req, info.Name, info.ModTime, streamer)
 }
 
 // renterUploadHandler handles the API call to upload a file.
@@ -1940,7 +2273,7 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 		}
 	}
 	// Parse the erasure coder.
-	ec, err := parseErasureCodingParameters(req.FormValue("datapieces"), req.FormValue("paritypieces"))
+	ec, err := parseErasureCodingParameters(req.FormValue("datapieces"), req.FormValue("paritypieces"), req.FormValue("localgroups"))
 	if err != nil {
 		WriteError(w, Error{"unable to parse erasure code settings: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -1963,9 +2296,6 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 		ErasureCode:         ec,
 		Force:               force,
 		DisablePartialChunk: true, // TODO: remove this
-
-		// NOTE: can make this an optional param.
-		CipherType: crypto.TypeDefaultRenter,
 	})
 	if err != nil {
 		WriteError(w, Error{"upload failed: " + err.Error()}, http.StatusInternalServerError)
@@ -2005,6 +2335,25 @@ func (api *API) renterUploadReadyHandler(w http.ResponseWriter, req *http.Reques
 	})
 }
 
+// renterUploadsHandler handles the API call to get the status of the renter's
+// uploads and repairs.
+func (api *API) renterUploadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	settings, err := api.renter.Settings()
+	if err != nil {
+		WriteError(w, Error{"failed to get renter settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	classes, err := api.renter.UploadPriorityClasses()
+	if err != nil {
+		WriteError(w, Error{"failed to get priority classes: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterUploadsGet{
+		UploadsStatus:   settings.UploadsStatus,
+		PriorityClasses: classes,
+	})
+}
+
 // renterUploadsPauseHandler handles the api call to pause the renter's uploads,
 // this includes repairs
 func (api *API) renterUploadsPauseHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
@@ -2066,8 +2415,33 @@ func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Reque
 			return
 		}
 	}
+	// Check whether the chunks of the file should be deduplicated
+	dedup := false
+	if d := queryForm.Get("dedup"); d != "" {
+		dedup, err = strconv.ParseBool(d)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'dedup' parameter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if dedup && repair {
+		WriteError(w, Error{"can't deduplicate chunks when doing a repair"}, http.StatusBadRequest)
+		return
+	}
+	// Check whether the data of the file should be compressed.
+	compression := modules.CompressionNone
+	if c := queryForm.Get("compression"); c != "" {
+		if err := compression.FromString(c); err != nil {
+			WriteError(w, Error{"unable to parse 'compression' parameter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if compression != modules.CompressionNone && repair {
+		WriteError(w, Error{"can't provide compression settings when doing a repair"}, http.StatusBadRequest)
+		return
+	}
 	// Parse the erasure coder.
-	ec, err := parseErasureCodingParameters(queryForm.Get("datapieces"), queryForm.Get("paritypieces"))
+	ec, err := parseErasureCodingParameters(queryForm.Get("datapieces"), queryForm.Get("paritypieces"), queryForm.Get("localgroups"))
 	if err != nil && !repair {
 		WriteError(w, Error{"unable to parse erasure code settings: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -2093,9 +2467,8 @@ func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Reque
 		ErasureCode: ec,
 		Force:       force,
 		Repair:      repair,
-
-		// NOTE: can make this an optional param.
-		CipherType: crypto.TypeDefaultRenter,
+		Dedup:       dedup,
+		Compression: compression,
 	}
 	err = api.renter.UploadStreamFromReader(up, req.Body)
 	if err != nil {
@@ -2181,9 +2554,16 @@ func (api *API) renterDirHandlerGET(w http.ResponseWriter, req *http.Request, ps
 		}
 	}
 
+	policy, err := api.renter.DirPolicy(siaPath)
+	if err != nil {
+		WriteError(w, Error{"failed to get directory policy: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
 	WriteJSON(w, RenterDirectory{
 		Directories: directories,
 		Files:       files,
+		Policy:      policy,
 	})
 	return
 }
@@ -2266,6 +2646,34 @@ func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, p
 		WriteSuccess(w)
 		return
 	}
+	if action == "setpolicy" {
+		policy, err := parseDirPolicy(req)
+		if err != nil {
+			WriteError(w, Error{"failed to parse policy: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.renter.SetDirPolicy(siaPath, policy)
+		if err != nil {
+			WriteError(w, Error{"failed to set directory policy: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
+	if action == "setquota" {
+		quota, err := parseDirQuota(req)
+		if err != nil {
+			WriteError(w, Error{"failed to parse quota: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.renter.SetDirQuota(siaPath, quota)
+		if err != nil {
+			WriteError(w, Error{"failed to set directory quota: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
 
 	// Report that no calls were made
 	WriteError(w, Error{"no calls were made, please check your submission and try again"}, http.StatusInternalServerError)
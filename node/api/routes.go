@@ -35,15 +35,23 @@ func (api *API) buildHTTPRoutes() {
 	// Daemon API Calls
 	router.GET("/daemon/alerts", api.daemonAlertsHandlerGET)
 	router.GET("/daemon/constants", api.daemonConstantsHandler)
+	router.GET("/daemon/events", RequirePassword(api.daemonEventsHandlerGET, requiredPassword))
 	router.GET("/daemon/settings", api.daemonSettingsHandlerGET)
 	router.POST("/daemon/settings", api.daemonSettingsHandlerPOST)
 	router.GET("/daemon/stack", api.daemonStackHandlerGET)
 	router.POST("/daemon/startprofile", api.daemonStartProfileHandlerPOST)
 	router.GET("/daemon/stop", RequirePassword(api.daemonStopHandler, requiredPassword))
 	router.POST("/daemon/stopprofile", api.daemonStopProfileHandlerPOST)
+	router.GET("/daemon/tokens", RequirePassword(api.daemonTokensHandlerGET, requiredPassword))
+	router.POST("/daemon/tokens", RequirePassword(api.daemonTokensHandlerPOST, requiredPassword))
 	router.GET("/daemon/update", api.daemonUpdateHandlerGET)
 	router.POST("/daemon/update", api.daemonUpdateHandlerPOST)
 	router.GET("/daemon/version", api.daemonVersionHandler)
+	router.GET("/daemon/webhooks", RequirePassword(api.daemonWebhooksHandlerGET, requiredPassword))
+	router.POST("/daemon/webhooks", RequirePassword(api.daemonWebhooksHandlerPOST, requiredPassword))
+
+	// Metrics API Calls
+	router.GET("/metrics", RequirePassword(api.metricsHandlerGET, requiredPassword))
 
 	// Consensus API Calls
 	if api.cs != nil {
@@ -91,12 +99,20 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/renter/downloadinfo/*uid", api.renterDownloadByUIDHandlerGET)
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
 		router.POST("/renter/downloads/clear", RequirePassword(api.renterClearDownloadsHandler, requiredPassword))
+		router.POST("/renter/downloads/resume", RequirePassword(api.renterDownloadsResumeHandlerPOST, requiredPassword))
 		router.GET("/renter/files", api.renterFilesHandler)
 		router.GET("/renter/file/*siapath", api.renterFileHandlerGET)
 		router.POST("/renter/file/*siapath", RequirePassword(api.renterFileHandlerPOST, requiredPassword))
 		router.GET("/renter/prices", api.renterPricesHandler)
 		router.POST("/renter/recoveryscan", RequirePassword(api.renterRecoveryScanHandlerPOST, requiredPassword))
 		router.GET("/renter/recoveryscan", api.renterRecoveryScanHandlerGET)
+		router.GET("/renter/registry", api.renterRegistryHandlerGET)
+		router.POST("/renter/registry", RequirePassword(api.renterRegistryHandlerPOST, requiredPassword))
+		router.GET("/renter/registry/subscribe", RequirePassword(api.renterRegistrySubscribeHandlerGET, requiredPassword))
+		router.GET("/renter/scrub", api.renterScrubHandlerGET)
+		router.POST("/renter/scrub/cancel", RequirePassword(api.renterScrubCancelHandlerPOST, requiredPassword))
+		router.GET("/renter/scrub/results/*siapath", api.renterScrubResultsHandlerGET)
+		router.POST("/renter/scrub/start", RequirePassword(api.renterScrubStartHandlerPOST, requiredPassword))
 		router.GET("/renter/fuse", api.renterFuseHandlerGET)
 		router.POST("/renter/fuse/mount", RequirePassword(api.renterFuseMountHandlerPOST, requiredPassword))
 		router.POST("/renter/fuse/unmount", RequirePassword(api.renterFuseUnmountHandlerPOST, requiredPassword))
@@ -107,8 +123,10 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/renter/downloadasync/*siapath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
 		router.POST("/renter/rename/*siapath", RequirePassword(api.renterRenameHandler, requiredPassword))
 		router.GET("/renter/stream/*siapath", api.renterStreamHandler)
+		router.HEAD("/renter/stream/*siapath", api.renterStreamHandler)
 		router.POST("/renter/upload/*siapath", RequirePassword(api.renterUploadHandler, requiredPassword))
 		router.GET("/renter/uploadready", api.renterUploadReadyHandler)
+		router.GET("/renter/uploads", api.renterUploadsHandler)
 		router.POST("/renter/uploads/pause", RequirePassword(api.renterUploadsPauseHandler, requiredPassword))
 		router.POST("/renter/uploads/resume", RequirePassword(api.renterUploadsResumeHandler, requiredPassword))
 		router.POST("/renter/uploadstream/*siapath", RequirePassword(api.renterUploadStreamHandler, requiredPassword))
@@ -148,7 +166,7 @@ func (api *API) buildHTTPRoutes() {
 
 	// Apply UserAgent middleware and return the Router
 	api.routerMu.Lock()
-	api.router = timeoutHandler(RequireUserAgent(router, requiredUserAgent), httpServerTimeout)
+	api.router = timeoutHandler(RequireUserAgent(api.requireToken(router), requiredUserAgent), httpServerTimeout)
 	api.routerMu.Unlock()
 	return
 }
@@ -180,13 +198,18 @@ func RequireUserAgent(h http.Handler, ua string) http.Handler {
 
 // RequirePassword is middleware that requires a request to authenticate with a
 // password using HTTP basic auth. Usernames are ignored. Empty passwords
-// indicate no authentication is required.
+// indicate no authentication is required. Requests which were authenticated
+// with an API token of the right scope don't need the password.
 func RequirePassword(h httprouter.Handle, password string) httprouter.Handle {
 	// An empty password is equivalent to no password.
 	if password == "" {
 		return h
 	}
 	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		if tokenAuthenticated(req) {
+			h(w, req, ps)
+			return
+		}
 		_, pass, ok := req.BasicAuth()
 		if !ok || pass != password {
 			w.Header().Set("WWW-Authenticate", "Basic realm=\"SiaAPI\"")
@@ -197,7 +220,9 @@ func RequirePassword(h httprouter.Handle, password string) httprouter.Handle {
 	}
 }
 
-// isUnrestricted checks if a request may bypass the useragent check.
+// isUnrestricted checks if a request may bypass the useragent check. The
+// /metrics endpoint is unrestricted since monitoring systems like Prometheus
+// can't set a custom useragent. It still requires the API password.
 func isUnrestricted(req *http.Request) bool {
-	return strings.HasPrefix(req.URL.Path, "/renter/stream/")
+	return strings.HasPrefix(req.URL.Path, "/renter/stream/") || req.URL.Path == "/metrics"
 }
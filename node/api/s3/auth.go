@@ -0,0 +1,317 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// signV4Algorithm is the only signing algorithm supported by the gateway.
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+
+	// iso8601Format is the format of the X-Amz-Date header.
+	iso8601Format = "20060102T150405Z"
+
+	// maxClockSkew is the maximum difference between the time a request was
+	// signed and the time it was received.
+	maxClockSkew = 15 * time.Minute
+
+	// maxPresignExpiry is the maximum lifetime of a presigned URL.
+	maxPresignExpiry = 7 * 24 * time.Hour
+
+	// unsignedPayload is the payload hash used by clients which don't sign
+	// the request body.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+type (
+	// signature contains the parsed components of a V4 signature, taken
+	// either from the Authorization header or from the query string of a
+	// presigned URL.
+	signature struct {
+		accessKey     string
+		date          string
+		region        string
+		service       string
+		signedHeaders []string
+		signature     string
+		amzDate       time.Time
+		payloadHash   string
+	}
+
+	// verifyingReader wraps a request body and checks that its sha256 hash
+	// matches the hash the client signed once the body was read completely.
+	verifyingReader struct {
+		io.ReadCloser
+		h        hash.Hash
+		expected []byte
+		mismatch bool
+	}
+)
+
+// Read implements io.Reader. If the end of the body was reached and the hash
+// doesn't match, errBadDigest is returned instead of io.EOF.
+func (vr *verifyingReader) Read(b []byte) (int, error) {
+	n, err := vr.ReadCloser.Read(b)
+	_, _ = vr.h.Write(b[:n])
+	if err == io.EOF && !bytes.Equal(vr.h.Sum(nil), vr.expected) {
+		vr.mismatch = true
+		return n, errBadDigest
+	}
+	return n, err
+}
+
+// authenticate verifies the signature of the request. On success it returns
+// the body which should be used by the handlers. If the client signed the
+// payload, the returned body verifies the payload hash while it's being read.
+func (g *Gateway) authenticate(req *http.Request) (io.ReadCloser, error) {
+	var sig signature
+	var err error
+	presigned := req.URL.Query().Get("X-Amz-Algorithm") != ""
+	if presigned {
+		sig, err = parsePresignedSignature(req.URL.Query())
+	} else {
+		sig, err = parseHeaderSignature(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sig.accessKey != g.staticAccessKey {
+		return nil, errInvalidAccessKeyID
+	}
+	expected := signRequest(req, sig, g.staticSecretKey, presigned)
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return nil, errSignatureDoesNotMatch
+	}
+
+	// Presigned requests and unsigned payloads don't need to be verified.
+	if presigned || sig.payloadHash == unsignedPayload {
+		return req.Body, nil
+	}
+	expectedHash, err := hex.DecodeString(sig.payloadHash)
+	if err != nil || len(expectedHash) != sha256.Size {
+		return nil, errUnsupportedContentSHA256
+	}
+	return &verifyingReader{
+		ReadCloser: req.Body,
+		h:          sha256.New(),
+		expected:   expectedHash,
+	}, nil
+}
+
+// parseHeaderSignature parses the signature of a request which was signed
+// using the Authorization header.
+func parseHeaderSignature(req *http.Request) (signature, error) {
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return signature{}, errAccessDenied
+	}
+	if !strings.HasPrefix(auth, signV4Algorithm+" ") {
+		return signature{}, errAuthorizationHeader
+	}
+	var sig signature
+	var credential, signedHeaders string
+	for _, field := range strings.Split(strings.TrimPrefix(auth, signV4Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return signature{}, errAuthorizationHeader
+		}
+		switch kv[0] {
+		case "Credential":
+			credential = kv[1]
+		case "SignedHeaders":
+			signedHeaders = kv[1]
+		case "Signature":
+			sig.signature = kv[1]
+		}
+	}
+	if err := sig.parseCredential(credential); err != nil {
+		return signature{}, err
+	}
+	if signedHeaders == "" || sig.signature == "" {
+		return signature{}, errAuthorizationHeader
+	}
+	sig.signedHeaders = strings.Split(signedHeaders, ";")
+
+	// Check the date of the request.
+	amzDate := req.Header.Get("X-Amz-Date")
+	if amzDate == "" {
+		return signature{}, errAccessDenied
+	}
+	t, err := time.Parse(iso8601Format, amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, sig.date) {
+		return signature{}, errAuthorizationHeader
+	}
+	if skew := time.Since(t); skew > maxClockSkew || skew < -maxClockSkew {
+		return signature{}, errRequestTimeTooSkewed
+	}
+	sig.amzDate = t
+
+	// The payload hash is required for header based authentication.
+	sig.payloadHash = req.Header.Get("X-Amz-Content-Sha256")
+	if sig.payloadHash == "" {
+		return signature{}, errAccessDenied
+	}
+	return sig, nil
+}
+
+// parsePresignedSignature parses the signature of a presigned URL.
+func parsePresignedSignature(query url.Values) (signature, error) {
+	if query.Get("X-Amz-Algorithm") != signV4Algorithm {
+		return signature{}, errAuthorizationHeader
+	}
+	var sig signature
+	if err := sig.parseCredential(query.Get("X-Amz-Credential")); err != nil {
+		return signature{}, err
+	}
+	sig.signature = query.Get("X-Amz-Signature")
+	signedHeaders := query.Get("X-Amz-SignedHeaders")
+	if sig.signature == "" || signedHeaders == "" {
+		return signature{}, errAuthorizationHeader
+	}
+	sig.signedHeaders = strings.Split(signedHeaders, ";")
+
+	amzDate := query.Get("X-Amz-Date")
+	t, err := time.Parse(iso8601Format, amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, sig.date) {
+		return signature{}, errAuthorizationHeader
+	}
+	expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+	if err != nil || expires < 0 || time.Duration(expires)*time.Second > maxPresignExpiry {
+		return signature{}, errInvalidArgument
+	}
+	if time.Now().After(t.Add(time.Duration(expires) * time.Second)) {
+		return signature{}, errExpiredPresignRequest
+	}
+	if time.Until(t) > maxClockSkew {
+		return signature{}, errRequestTimeTooSkewed
+	}
+	sig.amzDate = t
+	sig.payloadHash = unsignedPayload
+	return sig, nil
+}
+
+// parseCredential parses a credential of the form
+// <access-key>/<date>/<region>/<service>/aws4_request.
+func (sig *signature) parseCredential(credential string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return errAuthorizationHeader
+	}
+	sig.accessKey = parts[0]
+	sig.date = parts[1]
+	sig.region = parts[2]
+	sig.service = parts[3]
+	return nil
+}
+
+// scope returns the credential scope of the signature.
+func (sig signature) scope() string {
+	return strings.Join([]string{sig.date, sig.region, sig.service, "aws4_request"}, "/")
+}
+
+// signRequest computes the hex encoded V4 signature of a request.
+func signRequest(req *http.Request, sig signature, secretKey string, presigned bool) string {
+	stringToSign := strings.Join([]string{
+		signV4Algorithm,
+		sig.amzDate.Format(iso8601Format),
+		sig.scope(),
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest(req, sig, presigned)))),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), sig.date)
+	key = hmacSHA256(key, sig.region)
+	key = hmacSHA256(key, sig.service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalRequest builds the canonical form of a request as described by the
+// V4 signing specification.
+func canonicalRequest(req *http.Request, sig signature, presigned bool) string {
+	// Build the canonical query string. The signature itself is not part of
+	// the canonical query of a presigned URL.
+	query := req.URL.Query()
+	if presigned {
+		query.Del("X-Amz-Signature")
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			params = append(params, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+
+	// Build the canonical headers.
+	var headers strings.Builder
+	for _, h := range sig.signedHeaders {
+		var value string
+		if h == "host" {
+			value = req.Host
+		} else {
+			value = strings.Join(req.Header[http.CanonicalHeaderKey(h)], ",")
+		}
+		headers.WriteString(h)
+		headers.WriteByte(':')
+		headers.WriteString(strings.Join(strings.Fields(value), " "))
+		headers.WriteByte('\n')
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		strings.Join(params, "&"),
+		headers.String(),
+		strings.Join(sig.signedHeaders, ";"),
+		sig.payloadHash,
+	}, "\n")
+}
+
+// uriEncode encodes a string according to the rules of the V4 signing
+// specification. All characters except for the unreserved ones are percent
+// encoded. The slash is only encoded if encodeSlash is true.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			sb.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+	}
+	return sb.String()
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of data using the provided key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sha256Sum returns the sha256 hash of data.
+func sha256Sum(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
@@ -0,0 +1,348 @@
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+)
+
+// bucketNameRegexp matches the names which can be used for new buckets. Buckets
+// which were created outside of the gateway may have other names.
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// managedBucketDir returns the information about the directory backing a
+// bucket. errNoSuchBucket is returned if the directory doesn't exist.
+func (g *Gateway) managedBucketDir(bucket string) (modules.DirectoryInfo, error) {
+	bucketPath, err := bucketSiaPath(bucket)
+	if err != nil {
+		return modules.DirectoryInfo{}, err
+	}
+	dis, err := g.staticRenter.DirList(bucketPath)
+	if errors.Contains(err, filesystem.ErrNotExist) {
+		return modules.DirectoryInfo{}, errNoSuchBucket
+	} else if err != nil {
+		return modules.DirectoryInfo{}, err
+	}
+	for _, di := range dis {
+		if di.SiaPath.Equals(bucketPath) {
+			return di, nil
+		}
+	}
+	return modules.DirectoryInfo{}, errNoSuchBucket
+}
+
+// listBucketsHandler handles ListBuckets requests.
+func (g *Gateway) listBucketsHandler(w http.ResponseWriter, req *http.Request) {
+	dis, err := g.staticRenter.DirList(modules.UserFolder)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	resp := listAllMyBucketsResult{
+		Xmlns: xmlNamespace,
+		Owner: owner{ID: "sia", DisplayName: "sia"},
+	}
+	for _, di := range dis {
+		if di.SiaPath.Equals(modules.UserFolder) {
+			continue
+		}
+		resp.Buckets = append(resp.Buckets, bucketInfo{
+			Name:         di.SiaPath.Name(),
+			CreationDate: formatTime(di.MostRecentModTime),
+		})
+	}
+	sort.Slice(resp.Buckets, func(i, j int) bool {
+		return resp.Buckets[i].Name < resp.Buckets[j].Name
+	})
+	writeXML(w, http.StatusOK, resp)
+}
+
+// createBucketHandler handles CreateBucket requests.
+func (g *Gateway) createBucketHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	if !bucketNameRegexp.MatchString(bucket) {
+		writeError(w, req, errInvalidBucketName)
+		return
+	}
+	bucketPath, err := bucketSiaPath(bucket)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	err = g.staticRenter.CreateDir(bucketPath, modules.DefaultDirPerm)
+	if errors.Contains(err, filesystem.ErrExists) {
+		writeError(w, req, errBucketAlreadyOwnedByYou)
+		return
+	} else if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+}
+
+// headBucketHandler handles HeadBucket requests.
+func (g *Gateway) headBucketHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteBucketHandler handles DeleteBucket requests. Only empty buckets can be
+// deleted.
+func (g *Gateway) deleteBucketHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	di, err := g.managedBucketDir(bucket)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if di.AggregateNumFiles > 0 || di.NumSubDirs > 0 {
+		writeError(w, req, errBucketNotEmpty)
+		return
+	}
+	if err := g.staticRenter.DeleteDir(di.SiaPath); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBucketLocationHandler handles GetBucketLocation requests. The renter
+// doesn't have regions so the default location is always returned.
+func (g *Gateway) getBucketLocationHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	writeXML(w, http.StatusOK, locationConstraint{Xmlns: xmlNamespace})
+}
+
+// listObjectsHandler handles both ListObjects and ListObjectsV2 requests.
+func (g *Gateway) listObjectsHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	query := req.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	encodingType := query.Get("encoding-type")
+	if encodingType != "" && encodingType != "url" {
+		writeError(w, req, errInvalidArgument)
+		return
+	}
+	maxKeys := maxListKeys
+	if mk := query.Get("max-keys"); mk != "" {
+		n, err := strconv.Atoi(mk)
+		if err != nil || n < 0 {
+			writeError(w, req, errInvalidArgument)
+			return
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	// Figure out where to start the listing.
+	var marker string
+	if v2 {
+		marker = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			b, err := base64.URLEncoding.DecodeString(token)
+			if err != nil {
+				writeError(w, req, errInvalidArgument)
+				return
+			}
+			marker = string(b)
+		}
+	} else {
+		marker = query.Get("marker")
+	}
+
+	objects, err := g.managedListObjects(bucket, prefix)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	contents, prefixes, truncated, next := paginateObjects(objects, prefix, delimiter, marker, maxKeys)
+
+	// Build the response.
+	encode := func(s string) string {
+		if encodingType == "url" {
+			return uriEncode(s, false)
+		}
+		return s
+	}
+	resp := listBucketResult{
+		Xmlns:        xmlNamespace,
+		Name:         bucket,
+		Prefix:       encode(prefix),
+		Delimiter:    encode(delimiter),
+		EncodingType: encodingType,
+		MaxKeys:      maxKeys,
+		IsTruncated:  truncated,
+	}
+	for _, fi := range contents {
+		resp.Contents = append(resp.Contents, objectInfo{
+			Key:          encode(fi.key),
+			LastModified: formatTime(fi.ModificationTime),
+			ETag:         etag(fi.FileInfo),
+			Size:         fi.Filesize,
+			StorageClass: "STANDARD",
+		})
+	}
+	for _, cp := range prefixes {
+		resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix{Prefix: encode(cp)})
+	}
+	if v2 {
+		resp.KeyCount = len(contents) + len(prefixes)
+		resp.ContinuationToken = query.Get("continuation-token")
+		resp.StartAfter = encode(query.Get("start-after"))
+		if truncated {
+			resp.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(next))
+		}
+	} else {
+		resp.Marker = encode(marker)
+		if truncated && delimiter != "" {
+			resp.NextMarker = encode(next)
+		}
+	}
+	writeXML(w, http.StatusOK, resp)
+}
+
+// bucketObject is a file within a bucket together with its object key.
+type bucketObject struct {
+	modules.FileInfo
+	key string
+}
+
+// managedListObjects returns all the objects within a bucket which start with
+// the provided prefix, sorted by their keys. Only the directory which contains
+// the prefix is listed.
+func (g *Gateway) managedListObjects(bucket, prefix string) ([]bucketObject, error) {
+	di, err := g.managedBucketDir(bucket)
+	if err != nil {
+		return nil, err
+	}
+	listPath := di.SiaPath
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		listPath, err = di.SiaPath.Join(prefix[:i])
+		if err != nil {
+			// A prefix which isn't a valid siapath can't match any objects.
+			return nil, nil
+		}
+	}
+
+	var mu sync.Mutex
+	var objects []bucketObject
+	err = g.staticRenter.FileList(listPath, true, true, func(fi modules.FileInfo) {
+		key := strings.TrimPrefix(fi.SiaPath.String(), di.SiaPath.String()+"/")
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(fi.SiaPath.Name(), tempUploadPrefix) {
+			return
+		}
+		mu.Lock()
+		objects = append(objects, bucketObject{FileInfo: fi, key: key})
+		mu.Unlock()
+	})
+	if errors.Contains(err, filesystem.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].key < objects[j].key
+	})
+	return objects, nil
+}
+
+// paginateObjects applies the delimiter, marker and maxKeys of a listing to a
+// sorted slice of objects. It returns the objects and common prefixes of the
+// page, whether there are more results and the marker for the next page.
+func paginateObjects(objects []bucketObject, prefix, delimiter, marker string, maxKeys int) (contents []bucketObject, prefixes []string, truncated bool, next string) {
+	skip := func(key string) bool {
+		if !strings.HasPrefix(key, prefix) || key <= marker {
+			return true
+		}
+		// If the marker is a common prefix, all of the keys which share it
+		// were already returned.
+		return delimiter != "" && strings.HasSuffix(marker, delimiter) && strings.HasPrefix(key, marker)
+	}
+	for _, obj := range objects {
+		if skip(obj.key) {
+			continue
+		}
+		entry := obj.key
+		isPrefix := false
+		if delimiter != "" {
+			if i := strings.Index(obj.key[len(prefix):], delimiter); i >= 0 {
+				entry = obj.key[:len(prefix)+i+len(delimiter)]
+				isPrefix = true
+			}
+		}
+		if isPrefix && len(prefixes) > 0 && prefixes[len(prefixes)-1] == entry {
+			continue
+		}
+		if len(contents)+len(prefixes) == maxKeys {
+			truncated = maxKeys > 0
+			break
+		}
+		if isPrefix {
+			prefixes = append(prefixes, entry)
+		} else {
+			contents = append(contents, obj)
+		}
+		next = entry
+	}
+	return
+}
+
+// deleteObjectsHandler handles DeleteObjects requests.
+func (g *Gateway) deleteObjectsHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	var dr deleteRequest
+	if err := decodeXML(req.Body, &dr); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if len(dr.Objects) > maxDeleteObjects {
+		writeError(w, req, errMalformedXML)
+		return
+	}
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	resp := deleteResult{Xmlns: xmlNamespace}
+	for _, obj := range dr.Objects {
+		err := g.managedDeleteObject(bucket, obj.Key)
+		if ae, ok := err.(*apiError); ok {
+			resp.Errors = append(resp.Errors, deleteError{Key: obj.Key, Code: ae.Code, Message: ae.Message})
+		} else if err != nil {
+			resp.Errors = append(resp.Errors, deleteError{Key: obj.Key, Code: "InternalError", Message: err.Error()})
+		} else if !dr.Quiet {
+			resp.Deleted = append(resp.Deleted, deletedObject{Key: obj.Key})
+		}
+	}
+	writeXML(w, http.StatusOK, resp)
+}
+
+// decodeXML decodes an XML request body into v.
+func decodeXML(body io.Reader, v interface{}) error {
+	b, err := ioutil.ReadAll(io.LimitReader(body, maxXMLBodySize))
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(b, v); err != nil {
+		return errMalformedXML
+	}
+	return nil
+}
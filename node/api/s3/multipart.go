@@ -0,0 +1,357 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/modules"
+)
+
+const (
+	// maxPartNumber is the highest part number of a multipart upload.
+	maxPartNumber = 10000
+
+	// maxListParts is the maximum number of parts returned by a single
+	// ListParts request.
+	maxListParts = 1000
+)
+
+type (
+	// multipartUpload is an unfinished multipart upload. Its parts are
+	// stored on disk in a directory named after the upload ID.
+	multipartUpload struct {
+		staticID        string
+		staticBucket    string
+		staticKey       string
+		staticInitiated time.Time
+		parts           map[int]uploadedPart
+	}
+
+	// uploadedPart is a part of a multipart upload which was staged on disk.
+	uploadedPart struct {
+		etag    string
+		size    int64
+		modTime time.Time
+	}
+)
+
+// partPath returns the path of the file containing a staged part.
+func (g *Gateway) partPath(uploadID string, partNumber int) string {
+	return filepath.Join(g.staticMultipartDir, uploadID, strconv.Itoa(partNumber))
+}
+
+// managedMultipartUpload returns the multipart upload with the provided ID.
+// The upload must belong to the provided bucket and key.
+func (g *Gateway) managedMultipartUpload(uploadID, bucket, key string) (*multipartUpload, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	mu, exists := g.uploads[uploadID]
+	if !exists || mu.staticBucket != bucket || mu.staticKey != key {
+		return nil, errNoSuchUpload
+	}
+	return mu, nil
+}
+
+// createMultipartUploadHandler handles CreateMultipartUpload requests.
+func (g *Gateway) createMultipartUploadHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	if _, err := objectSiaPath(bucket, key); err != nil || strings.HasSuffix(key, "/") {
+		writeError(w, req, errInvalidKey)
+		return
+	}
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	id := hex.EncodeToString(fastrand.Bytes(16))
+	if err := os.MkdirAll(filepath.Join(g.staticMultipartDir, id), modules.DefaultDirPerm); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	g.mu.Lock()
+	g.uploads[id] = &multipartUpload{
+		staticID:        id,
+		staticBucket:    bucket,
+		staticKey:       key,
+		staticInitiated: time.Now(),
+		parts:           make(map[int]uploadedPart),
+	}
+	g.mu.Unlock()
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Xmlns:    xmlNamespace,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: id,
+	})
+}
+
+// uploadPartHandler handles UploadPart requests. The part is written to a
+// temporary file first so that a failed upload doesn't corrupt a previously
+// uploaded version of the same part.
+func (g *Gateway) uploadPartHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	if req.Header.Get("X-Amz-Copy-Source") != "" {
+		writeError(w, req, errNotImplemented)
+		return
+	}
+	query := req.URL.Query()
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		writeError(w, req, errInvalidArgument)
+		return
+	}
+	mu, err := g.managedMultipartUpload(query.Get("uploadId"), bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Join(g.staticMultipartDir, mu.staticID), "part")
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	h := md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), req.Body)
+	err = errors.Compose(err, tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), g.partPath(mu.staticID, partNumber))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		if vr, ok := req.Body.(*verifyingReader); ok && vr.mismatch {
+			err = errBadDigest
+		}
+		writeError(w, req, err)
+		return
+	}
+
+	part := uploadedPart{
+		etag:    `"` + hex.EncodeToString(h.Sum(nil)) + `"`,
+		size:    size,
+		modTime: time.Now(),
+	}
+	g.mu.Lock()
+	mu.parts[partNumber] = part
+	g.mu.Unlock()
+	w.Header().Set("ETag", part.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// completeMultipartUploadHandler handles CompleteMultipartUpload requests. The
+// listed parts are concatenated and uploaded to the renter as a single file.
+func (g *Gateway) completeMultipartUploadHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	var cmu completeMultipartUpload
+	if err := decodeXML(req.Body, &cmu); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if len(cmu.Parts) == 0 {
+		writeError(w, req, errMalformedXML)
+		return
+	}
+	uploadID := req.URL.Query().Get("uploadId")
+	mu, err := g.managedMultipartUpload(uploadID, bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	sp, err := objectSiaPath(bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	// Check the requested parts and remove the upload from the map to prevent
+	// it from being completed twice.
+	g.mu.Lock()
+	for i, p := range cmu.Parts {
+		if i > 0 && p.PartNumber <= cmu.Parts[i-1].PartNumber {
+			g.mu.Unlock()
+			writeError(w, req, errInvalidPartOrder)
+			return
+		}
+		part, exists := mu.parts[p.PartNumber]
+		if !exists || strings.Trim(part.etag, `"`) != strings.Trim(p.ETag, `"`) {
+			g.mu.Unlock()
+			writeError(w, req, errInvalidPart)
+			return
+		}
+	}
+	if _, exists := g.uploads[uploadID]; !exists {
+		g.mu.Unlock()
+		writeError(w, req, errNoSuchUpload)
+		return
+	}
+	delete(g.uploads, uploadID)
+	g.mu.Unlock()
+
+	// Open the parts and upload them.
+	var readers []io.Reader
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, p := range cmu.Parts {
+		f, err := os.Open(g.partPath(uploadID, p.PartNumber))
+		if err != nil {
+			g.managedRestoreUpload(mu)
+			writeError(w, req, err)
+			return
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+	}
+	fi, err := g.managedUpload(sp, io.MultiReader(readers...))
+	if err != nil {
+		g.managedRestoreUpload(mu)
+		writeError(w, req, err)
+		return
+	}
+	if err := os.RemoveAll(filepath.Join(g.staticMultipartDir, uploadID)); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Xmlns:    xmlNamespace,
+		Location: "/" + bucket + "/" + key,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     etag(fi),
+	})
+}
+
+// managedRestoreUpload adds an upload back to the map of unfinished uploads
+// after it failed to complete.
+func (g *Gateway) managedRestoreUpload(mu *multipartUpload) {
+	g.mu.Lock()
+	g.uploads[mu.staticID] = mu
+	g.mu.Unlock()
+}
+
+// abortMultipartUploadHandler handles AbortMultipartUpload requests.
+func (g *Gateway) abortMultipartUploadHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	uploadID := req.URL.Query().Get("uploadId")
+	if _, err := g.managedMultipartUpload(uploadID, bucket, key); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	g.mu.Lock()
+	delete(g.uploads, uploadID)
+	g.mu.Unlock()
+	if err := os.RemoveAll(filepath.Join(g.staticMultipartDir, uploadID)); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listPartsHandler handles ListParts requests.
+func (g *Gateway) listPartsHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	query := req.URL.Query()
+	uploadID := query.Get("uploadId")
+	maxParts := maxListParts
+	if mp := query.Get("max-parts"); mp != "" {
+		n, err := strconv.Atoi(mp)
+		if err != nil || n < 0 {
+			writeError(w, req, errInvalidArgument)
+			return
+		}
+		if n < maxParts {
+			maxParts = n
+		}
+	}
+	var marker int
+	if pm := query.Get("part-number-marker"); pm != "" {
+		n, err := strconv.Atoi(pm)
+		if err != nil || n < 0 {
+			writeError(w, req, errInvalidArgument)
+			return
+		}
+		marker = n
+	}
+	mu, err := g.managedMultipartUpload(uploadID, bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	resp := listPartsResult{
+		Xmlns:            xmlNamespace,
+		Bucket:           bucket,
+		Key:              key,
+		UploadID:         uploadID,
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+	}
+	g.mu.Lock()
+	var partNumbers []int
+	for n := range mu.parts {
+		if n > marker {
+			partNumbers = append(partNumbers, n)
+		}
+	}
+	sort.Ints(partNumbers)
+	for _, n := range partNumbers {
+		if len(resp.Parts) == maxParts {
+			resp.IsTruncated = true
+			break
+		}
+		part := mu.parts[n]
+		resp.Parts = append(resp.Parts, partInfo{
+			PartNumber:   n,
+			LastModified: formatTime(part.modTime),
+			ETag:         part.etag,
+			Size:         part.size,
+		})
+		resp.NextPartNumberMarker = n
+	}
+	g.mu.Unlock()
+	writeXML(w, http.StatusOK, resp)
+}
+
+// listMultipartUploadsHandler handles ListMultipartUploads requests.
+func (g *Gateway) listMultipartUploadsHandler(w http.ResponseWriter, req *http.Request, bucket string) {
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	prefix := req.URL.Query().Get("prefix")
+	resp := listMultipartUploadsResult{
+		Xmlns:      xmlNamespace,
+		Bucket:     bucket,
+		Prefix:     prefix,
+		MaxUploads: maxListParts,
+	}
+	g.mu.Lock()
+	for _, mu := range g.uploads {
+		if mu.staticBucket != bucket || !strings.HasPrefix(mu.staticKey, prefix) {
+			continue
+		}
+		resp.Uploads = append(resp.Uploads, uploadInfo{
+			Key:       mu.staticKey,
+			UploadID:  mu.staticID,
+			Initiated: formatTime(mu.staticInitiated),
+		})
+	}
+	g.mu.Unlock()
+	sort.Slice(resp.Uploads, func(i, j int) bool {
+		if resp.Uploads[i].Key != resp.Uploads[j].Key {
+			return resp.Uploads[i].Key < resp.Uploads[j].Key
+		}
+		return resp.Uploads[i].Initiated < resp.Uploads[j].Initiated
+	})
+	writeXML(w, http.StatusOK, resp)
+}
@@ -0,0 +1,225 @@
+package s3
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/crypto"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+)
+
+const (
+	// tempUploadPrefix is the prefix of the name of the file an object is
+	// uploaded to before it replaces the object. Files with this prefix are
+	// not listed as objects.
+	tempUploadPrefix = ".s3upload-"
+)
+
+// etag returns the quoted ETag of a file. Since the renter doesn't store an
+// MD5 of the file's content, the ETag is derived from the file's UID and
+// modification time which change whenever the file is replaced.
+func etag(fi modules.FileInfo) string {
+	return fmt.Sprintf(`"%x"`, crypto.HashAll(fi.UID, fi.ModificationTime.UnixNano()))
+}
+
+// contentType returns the content type of an object based on its key.
+func contentType(key string) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// setObjectHeaders sets the headers which describe an object.
+func setObjectHeaders(w http.ResponseWriter, key string, fi modules.FileInfo) {
+	w.Header().Set("ETag", etag(fi))
+	w.Header().Set("Last-Modified", fi.ModificationTime.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Type", contentType(key))
+	w.Header().Set("Accept-Ranges", "bytes")
+}
+
+// managedObject returns the information about the file which backs an object.
+func (g *Gateway) managedObject(bucket, key string) (modules.FileInfo, error) {
+	if strings.HasSuffix(key, "/") {
+		return modules.FileInfo{}, errNoSuchKey
+	}
+	sp, err := objectSiaPath(bucket, key)
+	if err != nil {
+		return modules.FileInfo{}, err
+	}
+	fi, err := g.staticRenter.File(sp)
+	if errors.Contains(err, filesystem.ErrNotExist) {
+		// Distinguish between a missing bucket and a missing key.
+		if _, err := g.managedBucketDir(bucket); err != nil {
+			return modules.FileInfo{}, err
+		}
+		return modules.FileInfo{}, errNoSuchKey
+	} else if err != nil {
+		return modules.FileInfo{}, err
+	}
+	return fi, nil
+}
+
+// getObjectHandler handles GetObject requests. Range and conditional requests
+// are handled by http.ServeContent.
+func (g *Gateway) getObjectHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	fi, err := g.managedObject(bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	_, streamer, err := g.staticRenter.Streamer(fi.SiaPath, false)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	defer func() {
+		_ = streamer.Close()
+	}()
+	setObjectHeaders(w, key, fi)
+	http.ServeContent(w, req, key, fi.ModificationTime, streamer)
+}
+
+// headObjectHandler handles HeadObject requests.
+func (g *Gateway) headObjectHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	fi, err := g.managedObject(bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	setObjectHeaders(w, key, fi)
+	w.Header().Set("Content-Length", strconv.FormatUint(fi.Filesize, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// putObjectHandler handles PutObject requests. Existing objects are replaced.
+// Keys which end with a slash create a directory since that's how S3 clients
+// represent empty folders.
+func (g *Gateway) putObjectHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	if req.Header.Get("X-Amz-Copy-Source") != "" {
+		writeError(w, req, errNotImplemented)
+		return
+	}
+	sp, err := objectSiaPath(bucket, key)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if strings.HasSuffix(key, "/") {
+		if req.ContentLength != 0 {
+			writeError(w, req, errInvalidKey)
+			return
+		}
+		err = g.staticRenter.CreateDir(sp, modules.DefaultDirPerm)
+		if err != nil && !errors.Contains(err, filesystem.ErrExists) {
+			writeError(w, req, err)
+			return
+		}
+		w.Header().Set("ETag", etag(modules.FileInfo{}))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	fi, err := g.managedUpload(sp, req.Body)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.Header().Set("ETag", etag(fi))
+	w.WriteHeader(http.StatusOK)
+}
+
+// tempUploadSiaPath returns a random siapath next to the provided siapath
+// which an object can be uploaded to before it replaces the file at the
+// provided siapath.
+func tempUploadSiaPath(sp modules.SiaPath) (modules.SiaPath, error) {
+	dir, err := sp.Dir()
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	return dir.Join(tempUploadPrefix + hex.EncodeToString(fastrand.Bytes(16)))
+}
+
+// managedUpload uploads the data read from r to the provided siapath,
+// replacing any existing file. The data is uploaded to a temporary siapath
+// first which only replaces the existing file once the upload succeeded. That
+// way a failed upload doesn't affect the existing file.
+func (g *Gateway) managedUpload(sp modules.SiaPath, r io.Reader) (modules.FileInfo, error) {
+	tempSiaPath, err := tempUploadSiaPath(sp)
+	if err != nil {
+		return modules.FileInfo{}, err
+	}
+	vr, verified := r.(*verifyingReader)
+	err = g.staticRenter.UploadStreamFromReader(modules.FileUploadParams{
+		SiaPath:    tempSiaPath,
+		CipherType: crypto.TypeDefaultRenter,
+	}, r)
+	if err != nil {
+		_ = g.staticRenter.DeleteFile(tempSiaPath)
+		if verified && vr.mismatch {
+			return modules.FileInfo{}, errBadDigest
+		}
+		return modules.FileInfo{}, err
+	}
+	if err := g.staticRenter.ReplaceFile(tempSiaPath, sp); err != nil {
+		_ = g.staticRenter.DeleteFile(tempSiaPath)
+		return modules.FileInfo{}, errors.AddContext(err, "unable to replace object with uploaded file")
+	}
+	return g.staticRenter.File(sp)
+}
+
+// deleteObjectHandler handles DeleteObject requests. Deleting an object which
+// doesn't exist is not an error.
+func (g *Gateway) deleteObjectHandler(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	if _, err := g.managedBucketDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if err := g.managedDeleteObject(bucket, key); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// managedDeleteObject deletes the file which backs an object. Keys which end
+// with a slash delete the matching directory if it is empty.
+func (g *Gateway) managedDeleteObject(bucket, key string) error {
+	sp, err := objectSiaPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(key, "/") {
+		err = g.staticRenter.DeleteFile(sp)
+		if errors.Contains(err, filesystem.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	dis, err := g.staticRenter.DirList(sp)
+	if errors.Contains(err, filesystem.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, di := range dis {
+		if di.SiaPath.Equals(sp) && di.AggregateNumFiles == 0 && di.NumSubDirs == 0 {
+			return g.staticRenter.DeleteDir(sp)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+// Package s3 implements a gateway which exposes the renter's filesystem
+// through a subset of the Amazon S3 REST API. Buckets are mapped to the
+// top-level directories of the renter's user folder and object keys are mapped
+// to the siapaths of the files within those directories.
+//
+// Only path-style requests (http://host/bucket/key) are supported. Requests are
+// authenticated using AWS Signature Version 4, either through the
+// Authorization header or through a presigned URL.
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+)
+
+const (
+	// maxDeleteObjects is the maximum number of keys which can be deleted in
+	// a single DeleteObjects request.
+	maxDeleteObjects = 1000
+
+	// maxListKeys is the maximum number of keys returned by a single
+	// ListObjects request.
+	maxListKeys = 1000
+
+	// maxXMLBodySize is the maximum size of an XML request body.
+	maxXMLBodySize = 1 << 20
+
+	// xmlNamespace is the namespace of all S3 response documents.
+	xmlNamespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+)
+
+var (
+	// errNoCredentials is returned by New if the gateway is created without
+	// an access key or secret key.
+	errNoCredentials = errors.New("the s3 gateway requires both an access key and a secret key")
+)
+
+// Gateway is an http.Handler which translates S3 requests into calls to the
+// renter.
+type Gateway struct {
+	staticAccessKey string
+	staticSecretKey string
+	staticRenter    modules.Renter
+
+	// staticMultipartDir is the directory in which the parts of unfinished
+	// multipart uploads are staged before they are uploaded to the renter.
+	staticMultipartDir string
+	uploads            map[string]*multipartUpload
+	mu                 sync.Mutex
+}
+
+// New creates a new S3 gateway for the provided renter. The multipartDir is
+// used to stage the parts of multipart uploads. Any parts left over from a
+// previous run are removed since the uploads they belong to can't be completed
+// anymore.
+func New(r modules.Renter, accessKey, secretKey, multipartDir string) (*Gateway, error) {
+	if accessKey == "" || secretKey == "" {
+		return nil, errNoCredentials
+	}
+	if err := os.RemoveAll(multipartDir); err != nil {
+		return nil, errors.AddContext(err, "unable to remove stale multipart uploads")
+	}
+	if err := os.MkdirAll(multipartDir, modules.DefaultDirPerm); err != nil {
+		return nil, errors.AddContext(err, "unable to create multipart directory")
+	}
+	return &Gateway{
+		staticAccessKey:    accessKey,
+		staticSecretKey:    secretKey,
+		staticRenter:       r,
+		staticMultipartDir: multipartDir,
+		uploads:            make(map[string]*multipartUpload),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler. It authenticates the request and routes
+// it to the handler for the requested operation.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := g.authenticate(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	req.Body = body
+
+	bucket, key := splitPath(req.URL.Path)
+	query := req.URL.Query()
+	switch {
+	case bucket == "":
+		if req.Method != http.MethodGet {
+			writeError(w, req, errMethodNotAllowed)
+			return
+		}
+		g.listBucketsHandler(w, req)
+	case key == "":
+		g.serveBucket(w, req, bucket, query)
+	default:
+		g.serveObject(w, req, bucket, key, query)
+	}
+}
+
+// serveBucket routes a request which targets a bucket.
+func (g *Gateway) serveBucket(w http.ResponseWriter, req *http.Request, bucket string, query map[string][]string) {
+	_, uploads := query["uploads"]
+	_, del := query["delete"]
+	_, location := query["location"]
+	switch {
+	case req.Method == http.MethodGet && location:
+		g.getBucketLocationHandler(w, req, bucket)
+	case req.Method == http.MethodGet && uploads:
+		g.listMultipartUploadsHandler(w, req, bucket)
+	case req.Method == http.MethodPost && del:
+		g.deleteObjectsHandler(w, req, bucket)
+	case hasSubresource(query):
+		writeError(w, req, errNotImplemented)
+	case req.Method == http.MethodGet:
+		g.listObjectsHandler(w, req, bucket)
+	case req.Method == http.MethodHead:
+		g.headBucketHandler(w, req, bucket)
+	case req.Method == http.MethodPut:
+		g.createBucketHandler(w, req, bucket)
+	case req.Method == http.MethodDelete:
+		g.deleteBucketHandler(w, req, bucket)
+	default:
+		writeError(w, req, errMethodNotAllowed)
+	}
+}
+
+// serveObject routes a request which targets an object.
+func (g *Gateway) serveObject(w http.ResponseWriter, req *http.Request, bucket, key string, query map[string][]string) {
+	_, uploads := query["uploads"]
+	_, uploadID := query["uploadId"]
+	switch {
+	case req.Method == http.MethodPost && uploads:
+		g.createMultipartUploadHandler(w, req, bucket, key)
+	case req.Method == http.MethodPut && uploadID:
+		g.uploadPartHandler(w, req, bucket, key)
+	case req.Method == http.MethodPost && uploadID:
+		g.completeMultipartUploadHandler(w, req, bucket, key)
+	case req.Method == http.MethodDelete && uploadID:
+		g.abortMultipartUploadHandler(w, req, bucket, key)
+	case req.Method == http.MethodGet && uploadID:
+		g.listPartsHandler(w, req, bucket, key)
+	case hasSubresource(query):
+		writeError(w, req, errNotImplemented)
+	case req.Method == http.MethodGet:
+		g.getObjectHandler(w, req, bucket, key)
+	case req.Method == http.MethodHead:
+		g.headObjectHandler(w, req, bucket, key)
+	case req.Method == http.MethodPut:
+		g.putObjectHandler(w, req, bucket, key)
+	case req.Method == http.MethodDelete:
+		g.deleteObjectHandler(w, req, bucket, key)
+	default:
+		writeError(w, req, errMethodNotAllowed)
+	}
+}
+
+// hasSubresource returns true if the query contains a subresource which isn't
+// supported by the gateway, e.g. ?acl or ?versioning.
+func hasSubresource(query map[string][]string) bool {
+	for _, sub := range []string{"acl", "cors", "lifecycle", "policy", "tagging", "versioning", "versions", "website", "encryption", "replication", "logging", "notification", "object-lock", "retention", "legal-hold", "torrent", "restore", "select"} {
+		if _, exists := query[sub]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits the path of a path-style request into the bucket and the
+// object key.
+func splitPath(path string) (bucket, key string) {
+	path = strings.TrimPrefix(path, "/")
+	i := strings.Index(path, "/")
+	if i == -1 {
+		return path, ""
+	}
+	return path[:i], path[i+1:]
+}
+
+// bucketSiaPath returns the siapath of the directory which backs a bucket.
+func bucketSiaPath(bucket string) (modules.SiaPath, error) {
+	if bucket == "" || strings.Contains(bucket, "/") {
+		return modules.SiaPath{}, errInvalidBucketName
+	}
+	sp, err := modules.UserFolder.Join(bucket)
+	if err != nil {
+		return modules.SiaPath{}, errInvalidBucketName
+	}
+	return sp, nil
+}
+
+// objectSiaPath returns the siapath of the file which backs an object.
+func objectSiaPath(bucket, key string) (modules.SiaPath, error) {
+	bucketPath, err := bucketSiaPath(bucket)
+	if err != nil {
+		return modules.SiaPath{}, err
+	}
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "//") {
+		return modules.SiaPath{}, errInvalidKey
+	}
+	sp, err := bucketPath.Join(strings.TrimSuffix(key, "/"))
+	if err != nil {
+		return modules.SiaPath{}, errInvalidKey
+	}
+	return sp, nil
+}
+
+// writeXML writes an XML response with the provided status code.
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		build.Critical("failed to encode s3 response:", err)
+	}
+}
+
+// writeError writes an S3 error response. Errors which are not an *apiError
+// are translated into the matching S3 error, falling back to InternalError.
+func writeError(w http.ResponseWriter, req *http.Request, err error) {
+	ae, ok := err.(*apiError)
+	if !ok {
+		switch {
+		case errors.Contains(err, filesystem.ErrNotExist):
+			ae = errNoSuchKey
+		case errors.Contains(err, filesystem.ErrExists):
+			ae = errBucketAlreadyOwnedByYou
+		default:
+			ae = &apiError{
+				Code:    "InternalError",
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+	resp := errorResponse{
+		Code:     ae.Code,
+		Message:  ae.Message,
+		Resource: req.URL.Path,
+	}
+	if req.Method == http.MethodHead {
+		// HEAD responses can't have a body.
+		w.WriteHeader(ae.Status)
+		return
+	}
+	writeXML(w, ae.Status, resp)
+}
+
+// apiError is an error which maps to an S3 error code.
+type apiError struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+// Error implements the error interface.
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+// The S3 errors returned by the gateway.
+var (
+	errAccessDenied             = &apiError{"AccessDenied", "Access Denied", http.StatusForbidden}
+	errAuthorizationHeader      = &apiError{"AuthorizationHeaderMalformed", "The authorization header is malformed", http.StatusBadRequest}
+	errBadDigest                = &apiError{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed", http.StatusBadRequest}
+	errBucketAlreadyOwnedByYou  = &apiError{"BucketAlreadyOwnedByYou", "The bucket you tried to create already exists", http.StatusConflict}
+	errBucketNotEmpty           = &apiError{"BucketNotEmpty", "The bucket you tried to delete is not empty", http.StatusConflict}
+	errExpiredPresignRequest    = &apiError{"AccessDenied", "Request has expired", http.StatusForbidden}
+	errInvalidAccessKeyID       = &apiError{"InvalidAccessKeyId", "The access key ID you provided does not exist in our records", http.StatusForbidden}
+	errInvalidArgument          = &apiError{"InvalidArgument", "Invalid argument", http.StatusBadRequest}
+	errInvalidBucketName        = &apiError{"InvalidBucketName", "The specified bucket is not valid", http.StatusBadRequest}
+	errInvalidKey               = &apiError{"InvalidArgument", "The specified key is not valid", http.StatusBadRequest}
+	errInvalidPart              = &apiError{"InvalidPart", "One or more of the specified parts could not be found", http.StatusBadRequest}
+	errInvalidPartOrder         = &apiError{"InvalidPartOrder", "The list of parts was not in ascending order", http.StatusBadRequest}
+	errMalformedXML             = &apiError{"MalformedXML", "The XML you provided was not well-formed", http.StatusBadRequest}
+	errMethodNotAllowed         = &apiError{"MethodNotAllowed", "The specified method is not allowed against this resource", http.StatusMethodNotAllowed}
+	errMissingContentLength     = &apiError{"MissingContentLength", "You must provide the Content-Length HTTP header", http.StatusLengthRequired}
+	errNoSuchBucket             = &apiError{"NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound}
+	errNoSuchKey                = &apiError{"NoSuchKey", "The specified key does not exist", http.StatusNotFound}
+	errNoSuchUpload             = &apiError{"NoSuchUpload", "The specified multipart upload does not exist", http.StatusNotFound}
+	errNotImplemented           = &apiError{"NotImplemented", "A header or parameter you provided implies functionality that is not implemented", http.StatusNotImplemented}
+	errRequestTimeTooSkewed     = &apiError{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large", http.StatusForbidden}
+	errSignatureDoesNotMatch    = &apiError{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided", http.StatusForbidden}
+	errUnsupportedContentSHA256 = &apiError{"NotImplemented", "Streaming payload signatures are not supported, use UNSIGNED-PAYLOAD or a hex encoded payload hash", http.StatusNotImplemented}
+)
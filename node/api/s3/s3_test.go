@@ -0,0 +1,665 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/modules/renter/filesystem"
+)
+
+type (
+	// mockRenter is an in-memory implementation of the parts of
+	// modules.Renter which are used by the gateway.
+	mockRenter struct {
+		modules.Renter
+
+		dirs  map[string]struct{}
+		files map[string]modules.FileInfo
+		data  map[string][]byte
+		mu    sync.Mutex
+	}
+
+	// mockStreamer is a modules.Streamer for in-memory data.
+	mockStreamer struct {
+		*bytes.Reader
+	}
+)
+
+// Close implements io.Closer.
+func (ms mockStreamer) Close() error { return nil }
+
+// newMockRenter creates a mockRenter which contains the user folder.
+func newMockRenter() *mockRenter {
+	return &mockRenter{
+		dirs: map[string]struct{}{
+			"":          {},
+			"home":      {},
+			"home/user": {},
+		},
+		files: make(map[string]modules.FileInfo),
+		data:  make(map[string][]byte),
+	}
+}
+
+// dirExists returns whether a directory exists. The lock must be held.
+func (mr *mockRenter) dirExists(sp modules.SiaPath) bool {
+	_, exists := mr.dirs[sp.String()]
+	return exists
+}
+
+// CreateDir implements modules.Renter.
+func (mr *mockRenter) CreateDir(sp modules.SiaPath, _ os.FileMode) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if mr.dirExists(sp) {
+		return filesystem.ErrExists
+	}
+	for dir := sp; !dir.IsRoot(); dir, _ = dir.Dir() {
+		mr.dirs[dir.String()] = struct{}{}
+	}
+	return nil
+}
+
+// DeleteDir implements modules.Renter.
+func (mr *mockRenter) DeleteDir(sp modules.SiaPath) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if !mr.dirExists(sp) {
+		return filesystem.ErrNotExist
+	}
+	delete(mr.dirs, sp.String())
+	return nil
+}
+
+// DirList implements modules.Renter.
+func (mr *mockRenter) DirList(sp modules.SiaPath) ([]modules.DirectoryInfo, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if !mr.dirExists(sp) {
+		return nil, filesystem.ErrNotExist
+	}
+	self := modules.DirectoryInfo{SiaPath: sp}
+	var dis []modules.DirectoryInfo
+	for dir := range mr.dirs {
+		dirPath := modules.SiaPath{Path: dir}
+		if parent, err := dirPath.Dir(); err == nil && parent.Equals(sp) && dir != "" {
+			dis = append(dis, modules.DirectoryInfo{SiaPath: dirPath})
+			self.NumSubDirs++
+		}
+	}
+	for path := range mr.files {
+		if strings.HasPrefix(path, sp.String()+"/") {
+			self.AggregateNumFiles++
+		}
+	}
+	return append([]modules.DirectoryInfo{self}, dis...), nil
+}
+
+// DeleteFile implements modules.Renter.
+func (mr *mockRenter) DeleteFile(sp modules.SiaPath) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if _, exists := mr.files[sp.String()]; !exists {
+		return filesystem.ErrNotExist
+	}
+	delete(mr.files, sp.String())
+	delete(mr.data, sp.String())
+	return nil
+}
+
+// File implements modules.Renter.
+func (mr *mockRenter) File(sp modules.SiaPath) (modules.FileInfo, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	fi, exists := mr.files[sp.String()]
+	if !exists {
+		return modules.FileInfo{}, errors.AddContext(filesystem.ErrNotExist, "unable to get the fileinfo from the filesystem")
+	}
+	return fi, nil
+}
+
+// FileList implements modules.Renter.
+func (mr *mockRenter) FileList(sp modules.SiaPath, recursive, _ bool, flf modules.FileListFunc) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if !mr.dirExists(sp) {
+		return filesystem.ErrNotExist
+	}
+	for path, fi := range mr.files {
+		if !strings.HasPrefix(path, sp.String()+"/") {
+			continue
+		}
+		if dir, _ := fi.SiaPath.Dir(); !recursive && !dir.Equals(sp) {
+			continue
+		}
+		flf(fi)
+	}
+	return nil
+}
+
+// Streamer implements modules.Renter.
+func (mr *mockRenter) Streamer(sp modules.SiaPath, _ bool) (string, modules.Streamer, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	data, exists := mr.data[sp.String()]
+	if !exists {
+		return "", nil, filesystem.ErrNotExist
+	}
+	return sp.Name(), mockStreamer{bytes.NewReader(data)}, nil
+}
+
+// ReplaceFile implements modules.Renter.
+func (mr *mockRenter) ReplaceFile(sp, replaced modules.SiaPath) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	fi, exists := mr.files[sp.String()]
+	if !exists {
+		return filesystem.ErrNotExist
+	}
+	fi.SiaPath = replaced
+	mr.files[replaced.String()] = fi
+	mr.data[replaced.String()] = mr.data[sp.String()]
+	delete(mr.files, sp.String())
+	delete(mr.data, sp.String())
+	return nil
+}
+
+// UploadStreamFromReader implements modules.Renter. Like the renter, it leaves
+// the partially uploaded file behind if reading from the reader fails.
+func (mr *mockRenter) UploadStreamFromReader(up modules.FileUploadParams, r io.Reader) error {
+	data, readErr := ioutil.ReadAll(r)
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if _, exists := mr.files[up.SiaPath.String()]; exists && !up.Force {
+		return filesystem.ErrExists
+	}
+	dir, _ := up.SiaPath.Dir()
+	for ; !dir.IsRoot(); dir, _ = dir.Dir() {
+		mr.dirs[dir.String()] = struct{}{}
+	}
+	mr.files[up.SiaPath.String()] = modules.FileInfo{
+		SiaPath:          up.SiaPath,
+		Filesize:         uint64(len(data)),
+		ModificationTime: time.Now(),
+		UID:              fastrand.Uint64n(1 << 62),
+	}
+	mr.data[up.SiaPath.String()] = data
+	return errors.AddContext(readErr, "unable to stream an upload from a reader")
+}
+
+// testClient is a minimal S3 client for testing the gateway.
+type testClient struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+// newTestGateway creates a gateway backed by a mockRenter and a client for it.
+func newTestGateway(t *testing.T) (*testClient, *mockRenter) {
+	mr := newMockRenter()
+	g, err := New(mr, testAccessKey, testSecretKey, filepath.Join(build.TempDir("s3", t.Name()), "multipart"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(g)
+	t.Cleanup(server.Close)
+	return &testClient{t: t, server: server}, mr
+}
+
+// do performs a signed request and returns the response status, headers and
+// body.
+func (c *testClient) do(method, path string, body []byte, headers map[string]string) (int, http.Header, []byte) {
+	req, err := http.NewRequest(method, c.server.URL+path, bytes.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	signTestRequest(req, hex.EncodeToString(sha256Sum(body)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, b
+}
+
+// doXML performs a signed request, checks the status code and decodes the
+// XML response into v.
+func (c *testClient) doXML(method, path string, body []byte, status int, v interface{}) {
+	code, _, b := c.do(method, path, body, nil)
+	if code != status {
+		c.t.Fatalf("%v %v: expected status %v but got %v: %s", method, path, status, code, b)
+	}
+	if v == nil {
+		return
+	}
+	if err := xml.Unmarshal(b, v); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// errorCode performs a signed request and returns the S3 error code of the
+// response.
+func (c *testClient) errorCode(method, path string, body []byte) string {
+	_, _, b := c.do(method, path, body, nil)
+	var er errorResponse
+	if err := xml.Unmarshal(b, &er); err != nil {
+		c.t.Fatalf("%v %v: expected an error response but got %s", method, path, b)
+	}
+	return er.Code
+}
+
+// TestGatewayBuckets tests creating, listing and deleting buckets.
+func TestGatewayBuckets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	c, _ := newTestGateway(t)
+
+	// Create two buckets.
+	c.doXML(http.MethodPut, "/bucket1", nil, http.StatusOK, nil)
+	c.doXML(http.MethodPut, "/bucket2", nil, http.StatusOK, nil)
+	if code := c.errorCode(http.MethodPut, "/bucket1", nil); code != errBucketAlreadyOwnedByYou.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	if code := c.errorCode(http.MethodPut, "/Invalid_Bucket", nil); code != errInvalidBucketName.Code {
+		t.Fatal("unexpected error code", code)
+	}
+
+	// List them.
+	var lb listAllMyBucketsResult
+	c.doXML(http.MethodGet, "/", nil, http.StatusOK, &lb)
+	if len(lb.Buckets) != 2 || lb.Buckets[0].Name != "bucket1" || lb.Buckets[1].Name != "bucket2" {
+		t.Fatal("unexpected buckets", lb.Buckets)
+	}
+
+	// Head them.
+	if code, _, _ := c.do(http.MethodHead, "/bucket1", nil, nil); code != http.StatusOK {
+		t.Fatal("unexpected status", code)
+	}
+	if code, _, _ := c.do(http.MethodHead, "/bucket3", nil, nil); code != http.StatusNotFound {
+		t.Fatal("unexpected status", code)
+	}
+
+	// A bucket with an object can't be deleted.
+	c.doXML(http.MethodPut, "/bucket1/object", []byte("data"), http.StatusOK, nil)
+	if code := c.errorCode(http.MethodDelete, "/bucket1", nil); code != errBucketNotEmpty.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	c.doXML(http.MethodDelete, "/bucket1/object", nil, http.StatusNoContent, nil)
+	c.doXML(http.MethodDelete, "/bucket1", nil, http.StatusNoContent, nil)
+	if code := c.errorCode(http.MethodDelete, "/bucket1", nil); code != errNoSuchBucket.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	lb = listAllMyBucketsResult{}
+	c.doXML(http.MethodGet, "/", nil, http.StatusOK, &lb)
+	if len(lb.Buckets) != 1 || lb.Buckets[0].Name != "bucket2" {
+		t.Fatal("unexpected buckets", lb.Buckets)
+	}
+}
+
+// TestGatewayObjects tests uploading, downloading and deleting objects.
+func TestGatewayObjects(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	c, mr := newTestGateway(t)
+	c.doXML(http.MethodPut, "/bucket", nil, http.StatusOK, nil)
+
+	// Upload an object and check that it ended up in the bucket's directory.
+	data := fastrand.Bytes(1000)
+	code, header, b := c.do(http.MethodPut, "/bucket/dir/file.txt", data, nil)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status %v: %s", code, b)
+	}
+	etag := header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+	if _, exists := mr.data["home/user/bucket/dir/file.txt"]; !exists {
+		t.Fatal("object wasn't uploaded to the expected siapath")
+	}
+
+	// Download it.
+	code, header, b = c.do(http.MethodGet, "/bucket/dir/file.txt", nil, nil)
+	if code != http.StatusOK || !bytes.Equal(b, data) {
+		t.Fatal("unexpected response", code)
+	}
+	if header.Get("ETag") != etag || header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatal("unexpected headers", header)
+	}
+
+	// Download a range.
+	code, header, b = c.do(http.MethodGet, "/bucket/dir/file.txt", nil, map[string]string{"Range": "bytes=100-199"})
+	if code != http.StatusPartialContent || !bytes.Equal(b, data[100:200]) {
+		t.Fatal("unexpected response", code)
+	}
+	if cr := header.Get("Content-Range"); cr != "bytes 100-199/1000" {
+		t.Fatal("unexpected Content-Range", cr)
+	}
+
+	// Conditional requests should use the ETag.
+	code, _, _ = c.do(http.MethodGet, "/bucket/dir/file.txt", nil, map[string]string{"If-None-Match": etag})
+	if code != http.StatusNotModified {
+		t.Fatal("unexpected status", code)
+	}
+
+	// Head the object.
+	code, header, _ = c.do(http.MethodHead, "/bucket/dir/file.txt", nil, nil)
+	if code != http.StatusOK || header.Get("Content-Length") != "1000" || header.Get("ETag") != etag {
+		t.Fatal("unexpected response", code, header)
+	}
+
+	// Overwrite the object. The ETag should change.
+	code, header, _ = c.do(http.MethodPut, "/bucket/dir/file.txt", data[:10], nil)
+	if code != http.StatusOK || header.Get("ETag") == etag {
+		t.Fatal("unexpected response", code, header)
+	}
+	_, _, b = c.do(http.MethodGet, "/bucket/dir/file.txt", nil, nil)
+	if !bytes.Equal(b, data[:10]) {
+		t.Fatal("object wasn't overwritten")
+	}
+
+	// Missing objects and buckets.
+	if code := c.errorCode(http.MethodGet, "/bucket/missing", nil); code != errNoSuchKey.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	if code := c.errorCode(http.MethodGet, "/missing/key", nil); code != errNoSuchBucket.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	if code := c.errorCode(http.MethodPut, "/missing/key", data); code != errNoSuchBucket.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	if code := c.errorCode(http.MethodGet, "/bucket/a/../b", nil); code != errInvalidKey.Code {
+		t.Fatal("unexpected error code", code)
+	}
+
+	// A body which doesn't match the signed hash should be rejected. An
+	// existing object with the same key isn't affected.
+	c.doXML(http.MethodPut, "/bucket/corrupt", data[:100], http.StatusOK, nil)
+	req, err := http.NewRequest(http.MethodPut, c.server.URL+"/bucket/corrupt", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signTestRequest(req, hex.EncodeToString(sha256Sum(data[1:])))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != errBadDigest.Status {
+		t.Fatal("unexpected status", resp.StatusCode)
+	}
+	if !bytes.Equal(mr.data["home/user/bucket/corrupt"], data[:100]) {
+		t.Fatal("existing object was changed by corrupt upload")
+	}
+	for path := range mr.files {
+		if strings.Contains(path, tempUploadPrefix) {
+			t.Fatal("corrupt upload wasn't removed", path)
+		}
+	}
+
+	// Delete the object. Deleting it again is not an error.
+	c.doXML(http.MethodDelete, "/bucket/dir/file.txt", nil, http.StatusNoContent, nil)
+	c.doXML(http.MethodDelete, "/bucket/dir/file.txt", nil, http.StatusNoContent, nil)
+	if code := c.errorCode(http.MethodGet, "/bucket/dir/file.txt", nil); code != errNoSuchKey.Code {
+		t.Fatal("unexpected error code", code)
+	}
+
+	// Folder markers create directories.
+	c.doXML(http.MethodPut, "/bucket/folder/", nil, http.StatusOK, nil)
+	if _, exists := mr.dirs["home/user/bucket/folder"]; !exists {
+		t.Fatal("folder wasn't created")
+	}
+	c.doXML(http.MethodDelete, "/bucket/folder/", nil, http.StatusNoContent, nil)
+	if _, exists := mr.dirs["home/user/bucket/folder"]; exists {
+		t.Fatal("folder wasn't deleted")
+	}
+
+	// Copying objects isn't supported.
+	code, _, _ = c.do(http.MethodPut, "/bucket/copy", nil, map[string]string{"X-Amz-Copy-Source": "/bucket/dir/file.txt"})
+	if code != http.StatusNotImplemented {
+		t.Fatal("unexpected status", code)
+	}
+}
+
+// TestGatewayListObjects tests listing objects with prefixes, delimiters and
+// pagination.
+func TestGatewayListObjects(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	c, mr := newTestGateway(t)
+	c.doXML(http.MethodPut, "/bucket", nil, http.StatusOK, nil)
+	keys := []string{"a", "b/1", "b/2", "b/c/3", "d"}
+	for _, key := range keys {
+		c.doXML(http.MethodPut, "/bucket/"+key, []byte(key), http.StatusOK, nil)
+	}
+	// Files of uploads in progress aren't listed.
+	tempSiaPath, err := modules.NewSiaPath("home/user/bucket/b/" + tempUploadPrefix + "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mr.UploadStreamFromReader(modules.FileUploadParams{SiaPath: tempSiaPath}, bytes.NewReader(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// List everything.
+	var lr listBucketResult
+	c.doXML(http.MethodGet, "/bucket?list-type=2", nil, http.StatusOK, &lr)
+	if lr.KeyCount != len(keys) || lr.IsTruncated {
+		t.Fatal("unexpected listing", lr)
+	}
+	for i, obj := range lr.Contents {
+		if obj.Key != keys[i] || obj.Size != uint64(len(keys[i])) {
+			t.Fatal("unexpected object", obj)
+		}
+	}
+
+	// List the top level.
+	lr = listBucketResult{}
+	c.doXML(http.MethodGet, "/bucket?list-type=2&delimiter=%2F", nil, http.StatusOK, &lr)
+	if len(lr.Contents) != 2 || lr.Contents[0].Key != "a" || lr.Contents[1].Key != "d" {
+		t.Fatal("unexpected contents", lr.Contents)
+	}
+	if len(lr.CommonPrefixes) != 1 || lr.CommonPrefixes[0].Prefix != "b/" {
+		t.Fatal("unexpected prefixes", lr.CommonPrefixes)
+	}
+
+	// List a prefix.
+	lr = listBucketResult{}
+	c.doXML(http.MethodGet, "/bucket?list-type=2&delimiter=%2F&prefix=b%2F", nil, http.StatusOK, &lr)
+	if len(lr.Contents) != 2 || lr.Contents[0].Key != "b/1" || lr.Contents[1].Key != "b/2" {
+		t.Fatal("unexpected contents", lr.Contents)
+	}
+	if len(lr.CommonPrefixes) != 1 || lr.CommonPrefixes[0].Prefix != "b/c/" {
+		t.Fatal("unexpected prefixes", lr.CommonPrefixes)
+	}
+
+	// A prefix which doesn't exist is empty.
+	lr = listBucketResult{}
+	c.doXML(http.MethodGet, "/bucket?list-type=2&prefix=x%2Fy", nil, http.StatusOK, &lr)
+	if len(lr.Contents) != 0 {
+		t.Fatal("unexpected contents", lr.Contents)
+	}
+
+	// Paginate through the top level, one entry at a time.
+	var entries []string
+	token := ""
+	for {
+		lr = listBucketResult{}
+		path := "/bucket?list-type=2&delimiter=%2F&max-keys=1"
+		if token != "" {
+			path += "&continuation-token=" + token
+		}
+		c.doXML(http.MethodGet, path, nil, http.StatusOK, &lr)
+		for _, obj := range lr.Contents {
+			entries = append(entries, obj.Key)
+		}
+		for _, cp := range lr.CommonPrefixes {
+			entries = append(entries, cp.Prefix)
+		}
+		if !lr.IsTruncated {
+			break
+		}
+		token = lr.NextContinuationToken
+	}
+	if strings.Join(entries, ",") != "a,b/,d" {
+		t.Fatal("unexpected entries", entries)
+	}
+
+	// ListObjects V1 with a marker.
+	lr = listBucketResult{}
+	c.doXML(http.MethodGet, "/bucket?marker=b%2F2", nil, http.StatusOK, &lr)
+	if len(lr.Contents) != 2 || lr.Contents[0].Key != "b/c/3" || lr.Contents[1].Key != "d" {
+		t.Fatal("unexpected contents", lr.Contents)
+	}
+
+	// Delete multiple objects at once.
+	var dr deleteResult
+	c.doXML(http.MethodPost, "/bucket?delete", []byte(`<Delete><Object><Key>a</Key></Object><Object><Key>b/1</Key></Object></Delete>`), http.StatusOK, &dr)
+	if len(dr.Deleted) != 2 || len(dr.Errors) != 0 {
+		t.Fatal("unexpected delete result", dr)
+	}
+	lr = listBucketResult{}
+	c.doXML(http.MethodGet, "/bucket?list-type=2", nil, http.StatusOK, &lr)
+	if lr.KeyCount != len(keys)-2 {
+		t.Fatal("unexpected listing", lr)
+	}
+}
+
+// TestGatewayMultipart tests multipart uploads.
+func TestGatewayMultipart(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	c, _ := newTestGateway(t)
+	c.doXML(http.MethodPut, "/bucket", nil, http.StatusOK, nil)
+
+	// Create an upload.
+	var imu initiateMultipartUploadResult
+	c.doXML(http.MethodPost, "/bucket/large?uploads", nil, http.StatusOK, &imu)
+	if imu.UploadID == "" || imu.Key != "large" {
+		t.Fatal("unexpected result", imu)
+	}
+	var lmu listMultipartUploadsResult
+	c.doXML(http.MethodGet, "/bucket?uploads", nil, http.StatusOK, &lmu)
+	if len(lmu.Uploads) != 1 || lmu.Uploads[0].UploadID != imu.UploadID {
+		t.Fatal("unexpected uploads", lmu.Uploads)
+	}
+
+	// Upload three parts out of order and overwrite one of them.
+	parts := [][]byte{fastrand.Bytes(100), fastrand.Bytes(200), fastrand.Bytes(50)}
+	etags := make([]string, len(parts))
+	uploadPart := func(n int, data []byte) string {
+		code, header, b := c.do(http.MethodPut, "/bucket/large?partNumber="+string(rune('0'+n))+"&uploadId="+imu.UploadID, data, nil)
+		if code != http.StatusOK {
+			t.Fatalf("unexpected status %v: %s", code, b)
+		}
+		return header.Get("ETag")
+	}
+	for _, i := range []int{2, 0, 1} {
+		etags[i] = uploadPart(i+1, fastrand.Bytes(10))
+		etags[i] = uploadPart(i+1, parts[i])
+	}
+	var lp listPartsResult
+	c.doXML(http.MethodGet, "/bucket/large?uploadId="+imu.UploadID, nil, http.StatusOK, &lp)
+	if len(lp.Parts) != len(parts) {
+		t.Fatal("unexpected parts", lp.Parts)
+	}
+	for i, p := range lp.Parts {
+		if p.PartNumber != i+1 || p.ETag != etags[i] || p.Size != int64(len(parts[i])) {
+			t.Fatal("unexpected part", p)
+		}
+	}
+
+	// Completing with a wrong ETag or order should fail.
+	completeBody := func(order []int, etags []string) []byte {
+		var cmu completeMultipartUpload
+		for _, i := range order {
+			cmu.Parts = append(cmu.Parts, completePart{PartNumber: i + 1, ETag: etags[i]})
+		}
+		b, err := xml.Marshal(cmu)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	path := "/bucket/large?uploadId=" + imu.UploadID
+	if code := c.errorCode(http.MethodPost, path, completeBody([]int{1, 0, 2}, etags)); code != errInvalidPartOrder.Code {
+		t.Fatal("unexpected error code", code)
+	}
+	if code := c.errorCode(http.MethodPost, path, completeBody([]int{0, 1, 2}, []string{etags[1], etags[1], etags[2]})); code != errInvalidPart.Code {
+		t.Fatal("unexpected error code", code)
+	}
+
+	// Complete the upload and download the object.
+	var cmr completeMultipartUploadResult
+	c.doXML(http.MethodPost, path, completeBody([]int{0, 1, 2}, etags), http.StatusOK, &cmr)
+	_, _, b := c.do(http.MethodGet, "/bucket/large", nil, nil)
+	if !bytes.Equal(b, bytes.Join(parts, nil)) {
+		t.Fatal("object doesn't match the uploaded parts")
+	}
+
+	// The upload is gone.
+	if code := c.errorCode(http.MethodPost, path, completeBody([]int{0, 1, 2}, etags)); code != errNoSuchUpload.Code {
+		t.Fatal("unexpected error code", code)
+	}
+
+	// Abort another upload.
+	imu = initiateMultipartUploadResult{}
+	c.doXML(http.MethodPost, "/bucket/aborted?uploads", nil, http.StatusOK, &imu)
+	c.doXML(http.MethodDelete, "/bucket/aborted?uploadId="+imu.UploadID, nil, http.StatusNoContent, nil)
+	if code := c.errorCode(http.MethodGet, "/bucket/aborted?uploadId="+imu.UploadID, nil); code != errNoSuchUpload.Code {
+		t.Fatal("unexpected error code", code)
+	}
+}
+
+// TestPaginateObjects is a unit test for paginateObjects.
+func TestPaginateObjects(t *testing.T) {
+	var objects []bucketObject
+	for _, key := range []string{"a/1", "a/2", "b", "c/1", "c/d/2"} {
+		objects = append(objects, bucketObject{key: key})
+	}
+	contents, prefixes, truncated, next := paginateObjects(objects, "", "/", "", 2)
+	if len(contents) != 1 || contents[0].key != "b" || strings.Join(prefixes, ",") != "a/" || !truncated || next != "b" {
+		t.Fatal("unexpected page", contents, prefixes, truncated, next)
+	}
+	contents, prefixes, truncated, _ = paginateObjects(objects, "", "/", next, 2)
+	if len(contents) != 0 || strings.Join(prefixes, ",") != "c/" || truncated {
+		t.Fatal("unexpected page", contents, prefixes, truncated)
+	}
+	contents, prefixes, truncated, _ = paginateObjects(objects, "c/", "/", "", 10)
+	if len(contents) != 1 || contents[0].key != "c/1" || strings.Join(prefixes, ",") != "c/d/" || truncated {
+		t.Fatal("unexpected page", contents, prefixes, truncated)
+	}
+	contents, prefixes, truncated, _ = paginateObjects(objects, "", "", "a/2", 0)
+	if len(contents) != 0 || len(prefixes) != 0 || truncated {
+		t.Fatal("unexpected page", contents, prefixes, truncated)
+	}
+}
@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// timeFormat is the format used for timestamps within XML documents.
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+type (
+	// errorResponse is the body of an error response.
+	errorResponse struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Message  string   `xml:"Message"`
+		Resource string   `xml:"Resource"`
+	}
+
+	// owner is the owner of a bucket. The gateway only has a single user.
+	owner struct {
+		ID          string `xml:"ID"`
+		DisplayName string `xml:"DisplayName"`
+	}
+
+	// bucketInfo describes a single bucket.
+	bucketInfo struct {
+		Name         string `xml:"Name"`
+		CreationDate string `xml:"CreationDate"`
+	}
+
+	// listAllMyBucketsResult is the response of a ListBuckets request.
+	listAllMyBucketsResult struct {
+		XMLName xml.Name     `xml:"ListAllMyBucketsResult"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		Owner   owner        `xml:"Owner"`
+		Buckets []bucketInfo `xml:"Buckets>Bucket"`
+	}
+
+	// locationConstraint is the response of a GetBucketLocation request.
+	locationConstraint struct {
+		XMLName  xml.Name `xml:"LocationConstraint"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Location string   `xml:",chardata"`
+	}
+
+	// objectInfo describes a single object within a listing.
+	objectInfo struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         uint64 `xml:"Size"`
+		StorageClass string `xml:"StorageClass"`
+	}
+
+	// commonPrefix is a prefix which groups keys in a delimited listing.
+	commonPrefix struct {
+		Prefix string `xml:"Prefix"`
+	}
+
+	// listBucketResult is the response of both ListObjects and ListObjectsV2
+	// requests. The V1 and V2 specific fields are omitted if they are empty.
+	listBucketResult struct {
+		XMLName               xml.Name       `xml:"ListBucketResult"`
+		Xmlns                 string         `xml:"xmlns,attr"`
+		Name                  string         `xml:"Name"`
+		Prefix                string         `xml:"Prefix"`
+		Delimiter             string         `xml:"Delimiter,omitempty"`
+		EncodingType          string         `xml:"EncodingType,omitempty"`
+		MaxKeys               int            `xml:"MaxKeys"`
+		IsTruncated           bool           `xml:"IsTruncated"`
+		Marker                string         `xml:"Marker,omitempty"`
+		NextMarker            string         `xml:"NextMarker,omitempty"`
+		KeyCount              int            `xml:"KeyCount,omitempty"`
+		ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+		NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+		StartAfter            string         `xml:"StartAfter,omitempty"`
+		Contents              []objectInfo   `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+	}
+
+	// deleteRequest is the body of a DeleteObjects request.
+	deleteRequest struct {
+		XMLName xml.Name `xml:"Delete"`
+		Quiet   bool     `xml:"Quiet"`
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+
+	// deletedObject is an object which was deleted by a DeleteObjects
+	// request.
+	deletedObject struct {
+		Key string `xml:"Key"`
+	}
+
+	// deleteError is an object which couldn't be deleted by a DeleteObjects
+	// request.
+	deleteError struct {
+		Key     string `xml:"Key"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+
+	// deleteResult is the response of a DeleteObjects request.
+	deleteResult struct {
+		XMLName xml.Name        `xml:"DeleteResult"`
+		Xmlns   string          `xml:"xmlns,attr"`
+		Deleted []deletedObject `xml:"Deleted"`
+		Errors  []deleteError   `xml:"Error"`
+	}
+
+	// initiateMultipartUploadResult is the response of a
+	// CreateMultipartUpload request.
+	initiateMultipartUploadResult struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}
+
+	// completePart is a part referenced by a CompleteMultipartUpload request.
+	completePart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+
+	// completeMultipartUpload is the body of a CompleteMultipartUpload
+	// request.
+	completeMultipartUpload struct {
+		XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+		Parts   []completePart `xml:"Part"`
+	}
+
+	// completeMultipartUploadResult is the response of a
+	// CompleteMultipartUpload request.
+	completeMultipartUploadResult struct {
+		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Location string   `xml:"Location"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		ETag     string   `xml:"ETag"`
+	}
+
+	// partInfo describes a single part of a multipart upload.
+	partInfo struct {
+		PartNumber   int    `xml:"PartNumber"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int64  `xml:"Size"`
+	}
+
+	// listPartsResult is the response of a ListParts request.
+	listPartsResult struct {
+		XMLName              xml.Name   `xml:"ListPartsResult"`
+		Xmlns                string     `xml:"xmlns,attr"`
+		Bucket               string     `xml:"Bucket"`
+		Key                  string     `xml:"Key"`
+		UploadID             string     `xml:"UploadId"`
+		PartNumberMarker     int        `xml:"PartNumberMarker"`
+		NextPartNumberMarker int        `xml:"NextPartNumberMarker"`
+		MaxParts             int        `xml:"MaxParts"`
+		IsTruncated          bool       `xml:"IsTruncated"`
+		Parts                []partInfo `xml:"Part"`
+	}
+
+	// uploadInfo describes a single unfinished multipart upload.
+	uploadInfo struct {
+		Key       string `xml:"Key"`
+		UploadID  string `xml:"UploadId"`
+		Initiated string `xml:"Initiated"`
+	}
+
+	// listMultipartUploadsResult is the response of a ListMultipartUploads
+	// request.
+	listMultipartUploadsResult struct {
+		XMLName     xml.Name     `xml:"ListMultipartUploadsResult"`
+		Xmlns       string       `xml:"xmlns,attr"`
+		Bucket      string       `xml:"Bucket"`
+		Prefix      string       `xml:"Prefix"`
+		MaxUploads  int          `xml:"MaxUploads"`
+		IsTruncated bool         `xml:"IsTruncated"`
+		Uploads     []uploadInfo `xml:"Upload"`
+	}
+)
+
+// formatTime formats a timestamp for an XML document.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
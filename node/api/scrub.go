@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"go.sia.tech/siad/modules"
+)
+
+type (
+	// RenterScrubResults lists the results of the last scrubs of the files
+	// within a directory.
+	RenterScrubResults struct {
+		Results []modules.FileScrubResult `json:"results"`
+	}
+)
+
+// parseScrubSiaPath parses the siapath of a scrub request. An empty siapath
+// refers to the root directory. Unless the root flag is set, the siapath is
+// rebased to the user folder.
+func parseScrubSiaPath(req *http.Request, str string) (modules.SiaPath, bool, error) {
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		return modules.SiaPath{}, false, err
+	}
+	siaPath := modules.RootSiaPath()
+	if str != "" && str != "/" {
+		siaPath, err = modules.NewSiaPath(str)
+		if err != nil {
+			return modules.SiaPath{}, false, err
+		}
+	}
+	if !root {
+		siaPath, err = rebaseInputSiaPath(siaPath)
+		if err != nil {
+			return modules.SiaPath{}, false, err
+		}
+	}
+	return siaPath, root, nil
+}
+
+// renterScrubHandlerGET handles the API call to /renter/scrub [GET].
+func (api *API) renterScrubHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.renter.ScrubStatus())
+}
+
+// renterScrubCancelHandlerPOST handles the API call to /renter/scrub/cancel
+// [POST].
+func (api *API) renterScrubCancelHandlerPOST(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	err := api.renter.ScrubCancel()
+	if err != nil {
+		WriteError(w, Error{"failed to cancel scrub: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterScrubResultsHandlerGET handles the API call to
+// /renter/scrub/results/*siapath [GET].
+func (api *API) renterScrubResultsHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siaPath, root, err := parseScrubSiaPath(req, ps.ByName("siapath"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var recursive bool
+	if recursiveStr := req.FormValue("recursive"); recursiveStr != "" {
+		recursive, err = strconv.ParseBool(recursiveStr)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'recursive' arg: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	results, err := api.renter.ScrubResults(siaPath, recursive)
+	if err != nil {
+		WriteError(w, Error{"failed to get scrub results: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Trim the user folder off of the siapaths.
+	if !root {
+		for i := range results {
+			results[i].SiaPath, err = results[i].SiaPath.Rebase(modules.UserFolder, modules.RootSiaPath())
+			if err != nil {
+				WriteError(w, Error{err.Error()}, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	WriteJSON(w, RenterScrubResults{
+		Results: results,
+	})
+}
+
+// renterScrubStartHandlerPOST handles the API call to /renter/scrub/start
+// [POST].
+func (api *API) renterScrubStartHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	siaPath, _, err := parseScrubSiaPath(req, req.FormValue("siapath"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.renter.Scrub(siaPath)
+	if err != nil {
+		WriteError(w, Error{"failed to start scrub: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
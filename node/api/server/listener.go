@@ -0,0 +1,205 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+)
+
+// defaultUnixSocketMode are the permissions of the API's unix socket if no
+// other permissions are specified. Only the user running siad can connect to
+// it.
+const defaultUnixSocketMode os.FileMode = 0600
+
+// ListenerOptions configure the listeners of the API server in addition to the
+// API address.
+type ListenerOptions struct {
+	// TLSCertFile and TLSKeyFile are the paths to the PEM encoded certificate
+	// and key which enable TLS on the API address.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSClientCAFile is the path to PEM encoded CA certificates. If set,
+	// clients need to present a certificate signed by one of them.
+	TLSClientCAFile string
+
+	// UnixSocket is the path of a unix domain socket the API listens on in
+	// addition to the API address. Access to it is controlled by its file
+	// permissions which are set to UnixSocketMode or 0600 if unset.
+	UnixSocket     string
+	UnixSocketMode os.FileMode
+}
+
+// withConfigDefaults returns the options with all unset fields set to the
+// values from the siad config.
+func (opts ListenerOptions) withConfigDefaults(cfg *modules.SiadConfig) ListenerOptions {
+	if opts.TLSCertFile == "" && opts.TLSKeyFile == "" {
+		opts.TLSCertFile = cfg.APITLSCertFile
+		opts.TLSKeyFile = cfg.APITLSKeyFile
+	}
+	if opts.TLSClientCAFile == "" {
+		opts.TLSClientCAFile = cfg.APITLSClientCAFile
+	}
+	if opts.UnixSocket == "" {
+		opts.UnixSocket = cfg.APIUnixSocket
+	}
+	if opts.UnixSocketMode == 0 {
+		opts.UnixSocketMode = os.FileMode(cfg.APIUnixSocketMode)
+	}
+	if opts.UnixSocketMode == 0 {
+		opts.UnixSocketMode = defaultUnixSocketMode
+	}
+	return opts
+}
+
+// tlsConfig returns the TLS config for the API address or nil if TLS is
+// disabled.
+func (opts ListenerOptions) tlsConfig() (*tls.Config, error) {
+	if opts.TLSCertFile == "" && opts.TLSKeyFile == "" {
+		if opts.TLSClientCAFile != "" {
+			return nil, errors.New("client certificate authentication requires a TLS certificate and key")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(opts.TLSCertFile, opts.TLSKeyFile)
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to load TLS certificate")
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if opts.TLSClientCAFile != "" {
+		pem, err := ioutil.ReadFile(opts.TLSClientCAFile)
+		if err != nil {
+			return nil, errors.AddContext(err, "failed to read TLS client CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("TLS client CA file doesn't contain any certificates")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// listen creates the listeners of the API server. The API address is skipped if
+// it is empty, but at least one listener is required.
+func (opts ListenerOptions) listen(addr string) (_ []net.Listener, err error) {
+	var listeners []net.Listener
+	defer func() {
+		if err != nil {
+			for _, l := range listeners {
+				err = errors.Compose(err, l.Close())
+			}
+		}
+	}()
+	if addr == "" && opts.UnixSocket == "" {
+		return nil, errors.New("the API needs either an address or a unix socket to listen on")
+	}
+
+	// Listen on the API address.
+	if addr != "" {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		listeners = append(listeners, l)
+	}
+
+	// Listen on the unix socket.
+	if opts.UnixSocket != "" {
+		l, err := listenUnix(opts.UnixSocket, opts.UnixSocketMode)
+		if err != nil {
+			return listeners, errors.AddContext(err, "failed to listen on unix socket")
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// unixListener is a listener on a unix socket which removes the socket when it
+// is closed.
+type unixListener struct {
+	*net.UnixListener
+	staticPath string
+}
+
+// Addr returns the address of the socket. The socket was moved after the
+// listener was created, so the listener's own address is outdated.
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.staticPath, Net: "unix"}
+}
+
+// Close closes the listener and removes its socket.
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	if rmErr := os.Remove(l.staticPath); rmErr != nil && !os.IsNotExist(rmErr) {
+		err = errors.Compose(err, rmErr)
+	}
+	return err
+}
+
+// removeStaleUnixSocket removes the socket at path if no other process is
+// listening on it anymore. Other files are never removed.
+func removeStaleUnixSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return errors.New("a file which is not a socket already exists at the socket path")
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		return errors.Compose(errors.New("another process is listening on the socket"), conn.Close())
+	}
+	return errors.AddContext(os.Remove(path), "failed to remove stale socket")
+}
+
+// listenUnix listens on a unix socket at path with the provided permissions.
+// The socket is created in a directory which is only accessible by the user
+// running siad and only moved to path once its permissions are set. That way
+// it is never accessible with the permissions of the umask.
+func listenUnix(path string, mode os.FileMode) (_ net.Listener, err error) {
+	if err := removeStaleUnixSocket(path); err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".siad-socket-")
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to create socket directory")
+	}
+	defer func() {
+		err = errors.Compose(err, os.RemoveAll(dir))
+	}()
+	tmpPath := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	ul := l.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return nil, errors.Compose(errors.AddContext(err, "failed to set socket permissions"), ul.Close())
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, errors.Compose(errors.AddContext(err, "failed to move socket"), ul.Close())
+	}
+	return &unixListener{UnixListener: ul, staticPath: path}, nil
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/siad/build"
+	"go.sia.tech/siad/modules"
+	"go.sia.tech/siad/node/api"
+	"go.sia.tech/siad/node/api/client"
+	"go.sia.tech/siad/persist"
+)
+
+// writeTestCert creates a certificate for localhost signed by the parent
+// certificate and writes it and its key to dir. If parent is nil, the
+// certificate is a self-signed CA.
+func writeTestCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPEM, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// serveTestAPI serves a minimal /daemon/version endpoint on the listeners and
+// returns a function to shut the server down.
+func serveTestAPI(t *testing.T, listeners []net.Listener) func() {
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			api.WriteJSON(w, api.DaemonVersionGet{Version: "1.0.0"})
+		}),
+	}
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			_ = srv.Serve(l)
+		}(l)
+	}
+	return func() {
+		if err := srv.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestListenerOptionsTLS tests serving the API over TLS with client
+// certificate authentication.
+func TestListenerOptionsTLS(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("server", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	ca, caKey := writeTestCert(t, dir, "ca", nil, nil)
+	writeTestCert(t, dir, "server", ca, caKey)
+	writeTestCert(t, dir, "client", ca, caKey)
+
+	// A client CA without a certificate is rejected.
+	opts := ListenerOptions{TLSClientCAFile: filepath.Join(dir, "ca.crt")}
+	if _, err := opts.listen("127.0.0.1:0"); err == nil {
+		t.Fatal("expected error for client CA without certificate")
+	}
+
+	opts = ListenerOptions{
+		TLSCertFile:     filepath.Join(dir, "server.crt"),
+		TLSKeyFile:      filepath.Join(dir, "server.key"),
+		TLSClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	listeners, err := opts.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serveTestAPI(t, listeners)()
+	addr := listeners[0].Addr().String()
+
+	// Connecting with a client certificate works.
+	tlsConfig, err := client.NewTLSConfig(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(client.Options{Address: addr, TLSConfig: tlsConfig})
+	if dvg, err := c.DaemonVersionGet(); err != nil || dvg.Version != "1.0.0" {
+		t.Fatal("unexpected response", dvg, err)
+	}
+
+	// Connecting without a client certificate or without TLS fails.
+	tlsConfig, err = client.NewTLSConfig(filepath.Join(dir, "ca.crt"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = client.New(client.Options{Address: addr, TLSConfig: tlsConfig})
+	if _, err := c.DaemonVersionGet(); err == nil {
+		t.Fatal("expected error without client certificate")
+	}
+	c = client.New(client.Options{Address: addr})
+	if _, err := c.DaemonVersionGet(); err == nil {
+		t.Fatal("expected error without TLS")
+	}
+}
+
+// TestListenerOptionsUnixSocket tests serving the API on a unix socket.
+func TestListenerOptionsUnixSocket(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("server", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "siad.sock")
+
+	// At least one listener is required.
+	if _, err := (ListenerOptions{}).listen(""); err == nil {
+		t.Fatal("expected error without listeners")
+	}
+
+	// Files which are not sockets are never removed.
+	if err := ioutil.WriteFile(socket, []byte("data"), persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	opts := ListenerOptions{UnixSocket: socket}.withConfigDefaults(&modules.SiadConfig{})
+	if _, err := opts.listen(""); err == nil {
+		t.Fatal("expected error for existing file")
+	}
+	if err := os.Remove(socket); err != nil {
+		t.Fatal(err)
+	}
+
+	// Listen on the socket only.
+	listeners, err := opts.listen("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serveTestAPI(t, listeners)()
+	if len(listeners) != 1 {
+		t.Fatal("expected a single listener but got", len(listeners))
+	}
+	fi, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != defaultUnixSocketMode {
+		t.Fatalf("expected permissions %v but got %v", defaultUnixSocketMode, fi.Mode().Perm())
+	}
+
+	if addr := listeners[0].Addr().String(); addr != socket {
+		t.Fatalf("expected address %v but got %v", socket, addr)
+	}
+	if fis, err := ioutil.ReadDir(dir); err != nil || len(fis) != 1 {
+		t.Fatal("expected only the socket in the directory", len(fis), err)
+	}
+
+	c := client.New(client.Options{UnixSocket: socket})
+	if dvg, err := c.DaemonVersionGet(); err != nil || dvg.Version != "1.0.0" {
+		t.Fatal("unexpected response", dvg, err)
+	}
+
+	// A socket another process is listening on isn't removed.
+	if _, err := opts.listen(""); err == nil {
+		t.Fatal("expected error for socket in use")
+	}
+	if _, err := c.DaemonVersionGet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestListenUnixStaleSocket checks that a stale socket is replaced and that the
+// socket is removed once the listener is closed.
+func TestListenUnixStaleSocket(t *testing.T) {
+	t.Parallel()
+
+	dir := build.TempDir("server", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "siad.sock")
+
+	// Leave a stale socket behind.
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(socket); err != nil {
+		t.Fatal("stale socket doesn't exist", err)
+	}
+
+	// Listening replaces the stale socket.
+	l, err = listenUnix(socket, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Fatal("wrong permissions", fi.Mode().Perm())
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing the listener removes the socket.
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(socket); !os.IsNotExist(err) {
+		t.Fatal("socket wasn't removed", err)
+	}
+}
+
+// TestListenerOptionsConfigDefaults is a unit test for withConfigDefaults.
+func TestListenerOptionsConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	cfg := &modules.SiadConfig{
+		APITLSCertFile:    "cfg.crt",
+		APITLSKeyFile:     "cfg.key",
+		APIUnixSocket:     "cfg.sock",
+		APIUnixSocketMode: 0660,
+	}
+	opts := ListenerOptions{}.withConfigDefaults(cfg)
+	if opts.TLSCertFile != "cfg.crt" || opts.TLSKeyFile != "cfg.key" || opts.UnixSocket != "cfg.sock" || opts.UnixSocketMode != 0660 {
+		t.Fatal("config values weren't used", opts)
+	}
+	opts = ListenerOptions{TLSCertFile: "flag.crt", TLSKeyFile: "flag.key", UnixSocket: "flag.sock"}.withConfigDefaults(&modules.SiadConfig{})
+	if opts.TLSCertFile != "flag.crt" || opts.TLSKeyFile != "flag.key" || opts.UnixSocket != "flag.sock" || opts.UnixSocketMode != defaultUnixSocketMode {
+		t.Fatal("options were overwritten", opts)
+	}
+}
@@ -22,15 +22,20 @@ import (
 	"go.sia.tech/siad/modules"
 	"go.sia.tech/siad/node"
 	"go.sia.tech/siad/node/api"
+	"go.sia.tech/siad/node/api/s3"
 	"go.sia.tech/siad/types"
 )
 
+// s3MultipartDir is the directory within the renter's directory in which the
+// S3 gateway stages the parts of multipart uploads.
+const s3MultipartDir = "s3multipart"
+
 // A Server is a collection of siad modules that can be communicated with over
 // an http api.
 type Server struct {
 	api               *api.API
 	apiServer         *http.Server
-	listener          net.Listener
+	listeners         []net.Listener
 	node              *node.Node
 	requiredUserAgent string
 	Dir               string
@@ -38,21 +43,36 @@ type Server struct {
 	serveChan chan struct{}
 	serveErr  error
 
+	// s3Server and s3Listener serve the optional S3 gateway. They are nil
+	// unless ServeS3 was called.
+	s3Server   *http.Server
+	s3Listener net.Listener
+
 	closeChan chan struct{}
 
 	closeMu sync.Mutex
 }
 
-// serve listens for and handles API calls. It is a blocking function.
+// serve listens for and handles API calls on all listeners. It is a blocking
+// function.
 func (srv *Server) serve() error {
-	// The server will run until an error is encountered or the listener is
+	// The server will run until an error is encountered or the listeners are
 	// closed, via either the Close method or by signal handling.  Closing the
-	// listener will result in the benign error handled below.
-	err := srv.apiServer.Serve(srv.listener)
-	if err != nil && !strings.HasSuffix(err.Error(), "use of closed network connection") {
-		return err
+	// listeners will result in the benign error handled below.
+	var wg sync.WaitGroup
+	errs := make([]error, len(srv.listeners))
+	for i, listener := range srv.listeners {
+		wg.Add(1)
+		go func(i int, listener net.Listener) {
+			defer wg.Done()
+			err := srv.apiServer.Serve(listener)
+			if err != nil && !strings.HasSuffix(err.Error(), "use of closed network connection") {
+				errs[i] = err
+			}
+		}(i, listener)
 	}
-	return nil
+	wg.Wait()
+	return errors.Compose(errs...)
 }
 
 // Close closes the Server's listener, causing the HTTP server to shut down.
@@ -60,13 +80,20 @@ func (srv *Server) Close() error {
 	defer close(srv.closeChan)
 	srv.closeMu.Lock()
 	defer srv.closeMu.Unlock()
+	// Stop emitting events. This also disconnects the subscribers of
+	// /daemon/events which would otherwise block the shutdown.
+	err := srv.api.Close()
 	// Stop accepting API requests.
-	err := srv.apiServer.Shutdown(context.Background())
+	err = errors.Compose(err, srv.apiServer.Shutdown(context.Background()))
 	// Wait for serve() to return and capture its error.
 	<-srv.serveChan
 	if !errors.Contains(srv.serveErr, http.ErrServerClosed) {
 		err = errors.Compose(err, srv.serveErr)
 	}
+	// Stop accepting S3 requests.
+	if srv.s3Server != nil {
+		err = errors.Compose(err, srv.s3Server.Shutdown(context.Background()))
+	}
 	// Shutdown modules.
 	if srv.node != nil {
 		err = errors.Compose(err, srv.node.Close())
@@ -79,9 +106,57 @@ func (srv *Server) WaitClose() {
 	<-srv.closeChan
 }
 
-// APIAddress returns the underlying node's api address
+// APIAddress returns the underlying node's api address. If the API only listens
+// on a unix socket, the path of the socket is returned.
 func (srv *Server) APIAddress() string {
-	return srv.listener.Addr().String()
+	return srv.listeners[0].Addr().String()
+}
+
+// S3Address returns the address of the S3 gateway or an empty string if the
+// gateway is not running.
+func (srv *Server) S3Address() string {
+	srv.closeMu.Lock()
+	defer srv.closeMu.Unlock()
+	if srv.s3Listener == nil {
+		return ""
+	}
+	return srv.s3Listener.Addr().String()
+}
+
+// ServeS3 starts an S3 gateway in front of the node's renter on the provided
+// address. Requests to the gateway are authenticated using the provided access
+// key and secret key. The gateway is shut down when the server is closed.
+func (srv *Server) ServeS3(addr, accessKey, secretKey string) error {
+	srv.closeMu.Lock()
+	defer srv.closeMu.Unlock()
+	if srv.node == nil || srv.node.Renter == nil {
+		return errors.New("can't serve the s3 gateway for a non-renter node")
+	}
+	if srv.s3Server != nil {
+		return errors.New("s3 gateway is already running")
+	}
+	multipartDir := filepath.Join(srv.Dir, modules.RenterDir, s3MultipartDir)
+	gateway, err := s3.New(srv.node.Renter, accessKey, secretKey, multipartDir)
+	if err != nil {
+		return errors.AddContext(err, "failed to create s3 gateway")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.AddContext(err, "failed to listen on s3 address")
+	}
+	srv.s3Listener = listener
+	srv.s3Server = &http.Server{
+		Handler:           gateway,
+		ReadHeaderTimeout: time.Minute * 2,
+		IdleTimeout:       time.Minute * 5,
+	}
+	go func() {
+		err := srv.s3Server.Serve(listener)
+		if err != nil && !errors.Contains(err, http.ErrServerClosed) {
+			fmt.Println("s3 gateway stopped unexpectedly:", err)
+		}
+	}()
+	return nil
 }
 
 // GatewayAddress returns the underlying node's gateway address
@@ -156,24 +231,31 @@ func (srv *Server) Unlock(password string) error {
 // authentication sends passwords in plaintext and should therefore only be
 // used if the APIaddr is localhost.
 func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, <-chan error) {
+	return NewAsyncWithOptions(APIaddr, ListenerOptions{}, requiredUserAgent, requiredPassword, nodeParams, loadStartTime)
+}
+
+// NewAsyncWithOptions creates a new API server like NewAsync but allows for
+// configuring TLS and a unix socket for the API. Options which are not set
+// are taken from the siad config. An empty APIaddr disables listening on TCP.
+func NewAsyncWithOptions(APIaddr string, opts ListenerOptions, requiredUserAgent string, requiredPassword string, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, <-chan error) {
 	c := make(chan error, 1)
 	defer close(c)
 
 	var errChan <-chan error
 	var n *node.Node
 	s, err := func() (*Server, error) {
-		// Create the server listener.
-		listener, err := net.Listen("tcp", APIaddr)
-		if err != nil {
-			return nil, err
-		}
-
 		// Load the config file.
 		cfg, err := modules.NewConfig(filepath.Join(nodeParams.Dir, modules.ConfigName))
 		if err != nil {
 			return nil, errors.AddContext(err, "failed to load siad config")
 		}
 
+		// Create the server listeners.
+		listeners, err := opts.withConfigDefaults(cfg).listen(APIaddr)
+		if err != nil {
+			return nil, err
+		}
+
 		// Create the api for the server.
 		api := api.New(cfg, requiredUserAgent, requiredPassword, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 		srv := &Server{
@@ -200,7 +282,7 @@ func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string,
 			},
 			closeChan:         make(chan struct{}),
 			serveChan:         make(chan struct{}),
-			listener:          listener,
+			listeners:         listeners,
 			requiredUserAgent: requiredUserAgent,
 			Dir:               nodeParams.Dir,
 		}
@@ -255,8 +337,15 @@ func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string,
 // authentication sends passwords in plaintext and should therefore only be
 // used if the APIaddr is localhost.
 func New(APIaddr string, requiredUserAgent string, requiredPassword string, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, error) {
+	return NewWithOptions(APIaddr, ListenerOptions{}, requiredUserAgent, requiredPassword, nodeParams, loadStartTime)
+}
+
+// NewWithOptions creates a new API server like New but allows for configuring
+// TLS and a unix socket for the API. Options which are not set are taken from
+// the siad config. An empty APIaddr disables listening on TCP.
+func NewWithOptions(APIaddr string, opts ListenerOptions, requiredUserAgent string, requiredPassword string, nodeParams node.NodeParams, loadStartTime time.Time) (*Server, error) {
 	// Wait for the node to be done loading.
-	srv, errChan := NewAsync(APIaddr, requiredUserAgent, requiredPassword, nodeParams, loadStartTime)
+	srv, errChan := NewAsyncWithOptions(APIaddr, opts, requiredUserAgent, requiredPassword, nodeParams, loadStartTime)
 	if err := <-errChan; err != nil {
 		// Error occurred during async load. Close all modules.
 		if build.Release == "standard" {
@@ -57,7 +57,8 @@ func (srv *Server) panicClose() {
 
 // Close closes the Server's listener, causing the HTTP server to shut down.
 func (srv *Server) Close() error {
-	err := srv.listener.Close()
+	err := srv.api.Close()
+	err = errors.Extend(err, srv.listener.Close())
 	err = errors.Extend(err, srv.tg.Stop())
 
 	// Safely close each module.
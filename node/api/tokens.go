@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"gitlab.com/NebulousLabs/errors"
+
+	"go.sia.tech/siad/modules"
+)
+
+// API tokens are an alternative to the API password. They are passed in the
+// 'Authorization: Bearer <token>' header and grant access to the routes of
+// their scopes. A route requires the read scope of its module for GET requests
+// and the write scope for all other requests. Routes which are protected by the
+// API password can be accessed with a token of the right scope instead.
+
+type (
+	// DaemonToken is the API representation of an API token. It never contains
+	// the token itself.
+	DaemonToken struct {
+		ID      string    `json:"id"`
+		Name    string    `json:"name"`
+		Scopes  []string  `json:"scopes"`
+		Expiry  time.Time `json:"expiry"`
+		Expired bool      `json:"expired"`
+	}
+
+	// DaemonTokensGET contains the API tokens of the daemon.
+	DaemonTokensGET struct {
+		Tokens []DaemonToken `json:"tokens"`
+	}
+
+	// DaemonTokensPOST contains a newly created API token. This is the only
+	// time the token is revealed.
+	DaemonTokensPOST struct {
+		ID     string    `json:"id"`
+		Token  string    `json:"token"`
+		Expiry time.Time `json:"expiry"`
+	}
+
+	// tokenAuthKey is the context key under which the id of the API token
+	// which authenticated a request is stored.
+	tokenAuthKey struct{}
+)
+
+var (
+	// tokenScopeModules maps the first segment of a route to the module whose
+	// scopes grant access to the route.
+	tokenScopeModules = map[string]string{
+		"accounting": "accounting",
+		"consensus":  "consensus",
+		"daemon":     "daemon",
+		"explorer":   "explorer",
+		"gateway":    "gateway",
+		"host":       "host",
+		"hostdb":     "renter",
+		"metrics":    "daemon",
+		"miner":      "miner",
+		"renter":     "renter",
+		"tpool":      "tpool",
+		"wallet":     "wallet",
+	}
+
+	// tokenWriteRoutes are the prefixes of GET routes which require the write
+	// scope of their module since they change state, spend money or reveal
+	// secrets.
+	tokenWriteRoutes = []string{
+		"/daemon/stop",
+		"/miner/start",
+		"/miner/stop",
+		"/renter/download/",
+		"/renter/downloadasync/",
+		"/renter/registry/subscribe",
+		"/renter/stream/",
+		"/wallet/backup",
+		"/wallet/seeds",
+		"/wallet/verifypassword",
+	}
+
+	// tokenForbiddenRoutes are the prefixes of routes which can't be accessed
+	// with an API token at all.
+	tokenForbiddenRoutes = []string{
+		"/daemon/tokens",
+	}
+)
+
+// requiredTokenScope returns the scope an API token needs to access the route.
+// The returned bool is false if the route can't be accessed with a token.
+func requiredTokenScope(method, path string) (string, bool) {
+	for _, prefix := range tokenForbiddenRoutes {
+		if strings.HasPrefix(path, prefix) {
+			return "", false
+		}
+	}
+	segment := strings.Split(strings.TrimPrefix(path, "/"), "/")[0]
+	module, exists := tokenScopeModules[segment]
+	if !exists {
+		return "", false
+	}
+	write := method != http.MethodGet && method != http.MethodHead
+	for _, prefix := range tokenWriteRoutes {
+		write = write || strings.HasPrefix(path, prefix)
+	}
+	return modules.APITokenScope(module, write), true
+}
+
+// tokenAuthenticated returns whether the request was authenticated with an API
+// token.
+func tokenAuthenticated(req *http.Request) bool {
+	_, ok := req.Context().Value(tokenAuthKey{}).(string)
+	return ok
+}
+
+// requireToken is middleware that authenticates requests which carry an API
+// token. Requests with an invalid token or a token which lacks the scope of the
+// route are rejected. Requests without a token are passed on unchanged.
+func (api *API) requireToken(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			h.ServeHTTP(w, req)
+			return
+		}
+		t, err := modules.APIToken{}, modules.ErrInvalidAPIToken
+		if api.siadConfig != nil {
+			t, err = api.siadConfig.APITokenLookup(strings.TrimPrefix(auth, "Bearer "))
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer realm=\"SiaAPI\"")
+			WriteError(w, Error{"API authentication failed: " + err.Error()}, http.StatusUnauthorized)
+			return
+		}
+		scope, ok := requiredTokenScope(req.Method, req.URL.Path)
+		if !ok {
+			WriteError(w, Error{"route can't be accessed with an API token"}, http.StatusForbidden)
+			return
+		}
+		if !t.HasScope(scope) {
+			WriteError(w, Error{fmt.Sprintf("API token lacks the required scope '%v'", scope)}, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), tokenAuthKey{}, t.ID)))
+	})
+}
+
+// daemonTokensHandlerGET handles the API call to /daemon/tokens [GET].
+func (api *API) daemonTokensHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	now := time.Now()
+	tokens := make([]DaemonToken, 0)
+	for _, t := range api.siadConfig.APITokenList() {
+		tokens = append(tokens, DaemonToken{
+			ID:      t.ID,
+			Name:    t.Name,
+			Scopes:  t.Scopes,
+			Expiry:  t.Expiry,
+			Expired: t.Expired(now),
+		})
+	}
+	WriteJSON(w, DaemonTokensGET{
+		Tokens: tokens,
+	})
+}
+
+// daemonTokensHandlerPOST handles the API call to /daemon/tokens [POST].
+func (api *API) daemonTokensHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	switch action := req.FormValue("action"); action {
+	case "create":
+		var scopes []string
+		if s := req.FormValue("scopes"); s != "" {
+			scopes = strings.Split(s, ",")
+		}
+		var expiry time.Time
+		if v := req.FormValue("validity"); v != "" {
+			validity, err := time.ParseDuration(v)
+			if err != nil {
+				WriteError(w, Error{"unable to parse validity: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			expiry = time.Now().Add(validity)
+		}
+		token, t, err := api.siadConfig.AddAPIToken(req.FormValue("name"), scopes, expiry)
+		if err != nil {
+			WriteError(w, Error{"failed to create API token: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		WriteJSON(w, DaemonTokensPOST{
+			ID:     t.ID,
+			Token:  token,
+			Expiry: t.Expiry,
+		})
+	case "remove":
+		err := api.siadConfig.RemoveAPIToken(req.FormValue("id"))
+		if errors.Contains(err, modules.ErrUnknownAPIToken) {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			WriteError(w, Error{"failed to remove API token: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		WriteSuccess(w)
+	default:
+		WriteError(w, Error{fmt.Sprintf("unknown action '%v'", action)}, http.StatusBadRequest)
+	}
+}
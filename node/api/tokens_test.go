@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestRequiredTokenScope is a unit test for requiredTokenScope.
+func TestRequiredTokenScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		method string
+		path   string
+		scope  string
+		ok     bool
+	}{
+		{http.MethodGet, "/renter/files", "renter:read", true},
+		{http.MethodPost, "/renter/upload/foo", "renter:write", true},
+		{http.MethodGet, "/renter/stream/foo", "renter:write", true},
+		{http.MethodGet, "/renter/registry/subscribe", "renter:write", true},
+		{http.MethodGet, "/hostdb/active", "renter:read", true},
+		{http.MethodGet, "/wallet", "wallet:read", true},
+		{http.MethodGet, "/wallet/seeds", "wallet:spend", true},
+		{http.MethodPost, "/wallet/siacoins", "wallet:spend", true},
+		{http.MethodPost, "/host/announce", "host:admin", true},
+		{http.MethodGet, "/daemon/stop", "daemon:admin", true},
+		{http.MethodGet, "/metrics", "daemon:read", true},
+		{http.MethodGet, "/daemon/tokens", "", false},
+		{http.MethodPost, "/daemon/tokens", "", false},
+		{http.MethodGet, "/unknown", "", false},
+	}
+	for _, test := range tests {
+		scope, ok := requiredTokenScope(test.method, test.path)
+		if scope != test.scope || ok != test.ok {
+			t.Errorf("%v %v: expected (%v, %v) but got (%v, %v)", test.method, test.path, test.scope, test.ok, scope, ok)
+		}
+	}
+}
+
+// TestAPITokens tests authenticating with scoped API tokens on a server which
+// requires a password.
+func TestAPITokens(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createAuthenticatedServerTester(t.Name(), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	baseURL := "http://" + st.server.listener.Addr().String()
+
+	// do is a helper to make a request authenticated with a token.
+	do := func(method, path, token string) int {
+		req, err := http.NewRequest(method, baseURL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", "Sia-Agent")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// Tokens can't be created with a token or without the password.
+	resp, err := HttpPOST(baseURL+"/daemon/tokens", "action=create&scopes=renter:write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("unauthenticated token creation succeeded", resp.StatusCode)
+	}
+
+	// Create an upload token.
+	values := url.Values{}
+	values.Set("action", "create")
+	values.Set("name", "uploader")
+	values.Set("scopes", "renter:write,wallet:read")
+	resp, err = HttpPOSTAuthenticated(baseURL+"/daemon/tokens", values.Encode(), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dtp DaemonTokensPOST
+	err = json.NewDecoder(resp.Body).Decode(&dtp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if dtp.Token == "" || dtp.ID == "" || !dtp.Expiry.IsZero() {
+		t.Fatal("unexpected token", dtp)
+	}
+
+	// The token is valid for routes of its scopes.
+	if code := do(http.MethodGet, "/renter", dtp.Token); non2xx(code) {
+		t.Fatal("token should grant read access to the renter", code)
+	}
+	if code := do(http.MethodPost, "/renter/uploads/pause", dtp.Token); code == http.StatusUnauthorized || code == http.StatusForbidden {
+		t.Fatal("token should grant write access to the renter", code)
+	}
+	if code := do(http.MethodGet, "/wallet", dtp.Token); non2xx(code) {
+		t.Fatal("token should grant read access to the wallet", code)
+	}
+
+	// But not for other routes.
+	if code := do(http.MethodGet, "/wallet/seeds", dtp.Token); code != http.StatusForbidden {
+		t.Fatal("token shouldn't reveal the wallet seeds", code)
+	}
+	if code := do(http.MethodPost, "/wallet/siacoins", dtp.Token); code != http.StatusForbidden {
+		t.Fatal("token shouldn't be able to spend", code)
+	}
+	if code := do(http.MethodPost, "/host/announce", dtp.Token); code != http.StatusForbidden {
+		t.Fatal("token shouldn't grant access to the host", code)
+	}
+	if code := do(http.MethodGet, "/daemon/tokens", dtp.Token); code != http.StatusForbidden {
+		t.Fatal("token shouldn't grant access to the tokens", code)
+	}
+	if code := do(http.MethodGet, "/renter", "invalid"); code != http.StatusUnauthorized {
+		t.Fatal("invalid token should be rejected", code)
+	}
+
+	// Expired tokens are rejected.
+	token, _, err := st.server.api.siadConfig.AddAPIToken("expiring", []string{"renter:read"}, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := do(http.MethodGet, "/renter", token); non2xx(code) {
+		t.Fatal("token should be valid", code)
+	}
+	time.Sleep(time.Second)
+	if code := do(http.MethodGet, "/renter", token); code != http.StatusUnauthorized {
+		t.Fatal("expired token should be rejected", code)
+	}
+
+	// List the tokens. The tokens themselves are never returned.
+	resp, err = HttpGETAuthenticated(baseURL+"/daemon/tokens", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dtg DaemonTokensGET
+	err = json.NewDecoder(resp.Body).Decode(&dtg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(dtg.Tokens) != 2 || dtg.Tokens[0].Name != "uploader" || dtg.Tokens[0].Expired || !dtg.Tokens[1].Expired {
+		t.Fatal("unexpected tokens", dtg.Tokens)
+	}
+
+	// Remove the upload token.
+	resp, err = HttpPOSTAuthenticated(baseURL+"/daemon/tokens", "action=remove&id="+dtp.ID, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if non2xx(resp.StatusCode) {
+		t.Fatal("failed to remove token", resp.StatusCode)
+	}
+	if code := do(http.MethodGet, "/renter", dtp.Token); code != http.StatusUnauthorized {
+		t.Fatal("removed token should be rejected", code)
+	}
+	resp, err = HttpPOSTAuthenticated(baseURL+"/daemon/tokens", "action=remove&id="+dtp.ID, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("removing an unknown token should fail", resp.StatusCode)
+	}
+}
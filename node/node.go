@@ -525,6 +525,12 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 		return nil, errChan
 	}
 
+	// Let the host's pricing engine observe the network through the renter's
+	// hostdb.
+	if h != nil && r != nil {
+		h.SetPricingHostDB(r)
+	}
+
 	// Accounting.
 	acc, err := func() (modules.Accounting, error) {
 		if params.CreateAccounting && params.Accounting != nil {
@@ -402,6 +402,45 @@ func TestHostContracts(t *testing.T) {
 	if mpo.Cmp(prevMissPayout) != 1 {
 		t.Fatalf("missed payout should be more than old missed payout %v %v", mpo, prevMissPayout)
 	}
+
+	// Filter the contracts.
+	hc, err = hostNode.HostContractInfoQueryGet(modules.HostStorageObligationQuery{
+		Statuses:     []string{"unresolved"},
+		ProofOutcome: modules.ProofOutcomePending,
+		Limit:        1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 1 || hc.Total == 0 {
+		t.Fatal("expected an unresolved contract", len(hc.Contracts), hc.Total)
+	}
+	hc, err = hostNode.HostContractInfoQueryGet(modules.HostStorageObligationQuery{
+		Statuses: []string{"succeeded"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 0 || hc.Total != 0 {
+		t.Fatal("expected no succeeded contracts", len(hc.Contracts), hc.Total)
+	}
+
+	// Export the contracts as CSV.
+	hc, err = hostNode.HostContractInfoGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := hostNode.HostContractsExportGet(modules.HostStorageObligationQuery{}, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(hc.Contracts)+1 {
+		t.Fatalf("expected %v lines but got %v", len(hc.Contracts)+1, len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "obligationid,") || !strings.Contains(lines[1], "obligationUnresolved") {
+		t.Fatal("unexpected export", string(data))
+	}
 }
 
 // TestHostContract confirms that the host contract endpoint returns the expected values
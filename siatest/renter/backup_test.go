@@ -595,6 +595,212 @@ func TestRemoteBackup(t *testing.T) {
 	}
 }
 
+// TestRemoteDirBackup tests creating remote backups of a single directory and
+// restoring them to their original directory and to a different directory.
+func TestRemoteDirBackup(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a testgroup.
+	// Need 5 hosts to address an NDF with the snapshot upload code.
+	groupParams := siatest.GroupParams{
+		Hosts:   5,
+		Miners:  1,
+		Renters: 1,
+	}
+	testDir := renterTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal("Failed to create group: ", err)
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Upload a file to two different dirs.
+	r := tg.Renters()[0]
+	var rfs []*siatest.RemoteFile
+	for _, name := range []string{"dirA", "dirB"} {
+		dir, err := r.FilesDir().CreateDir(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lf, err := dir.NewFile(int(20e3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rf, err := r.UploadBlocking(lf, 2, 1, false)
+		if err != nil {
+			t.Fatal("Failed to upload a file for testing: ", err)
+		}
+		rfs = append(rfs, rf)
+	}
+	dirA, err := rfs[0].SiaPath().Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirB, err := rfs[1].SiaPath().Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a backup of the first dir and wait for it to upload.
+	if err := r.RenterCreateDirBackupPost("foo", dirA, false); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(60, time.Second, func() error {
+		ubs, err := r.RenterDirBackups(dirA, false)
+		if err != nil {
+			return err
+		}
+		if len(ubs.Backups) != 1 {
+			return fmt.Errorf("expected 1 backup but got %v", len(ubs.Backups))
+		}
+		if ub := ubs.Backups[0]; ub.UploadProgress != 100 {
+			return fmt.Errorf("backup not uploaded: %v", ub.UploadProgress)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The backup shouldn't be listed for the other dir.
+	ubs, err := r.RenterDirBackups(dirB, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ubs.Backups) != 0 {
+		t.Fatal("expected no backups of the other dir", ubs.Backups)
+	}
+	// Backing up a dir which doesn't exist should fail.
+	if err := r.RenterCreateDirBackupPost("bar", modules.RandomSiaPath(), false); err == nil {
+		t.Fatal("shouldn't be able to back up a dir that doesn't exist")
+	}
+
+	// Restoring the backup to its original dir should fail as long as the dir
+	// exists.
+	if err := r.RenterRecoverBackupPost("foo"); err == nil || !strings.Contains(err.Error(), filesystem.ErrExists.Error()) {
+		t.Fatal("expected restore to fail", err)
+	}
+	// Restore the backup to a different dir.
+	target, err := modules.NewSiaPath("restored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RenterRecoverDirBackupPost("foo", target, false); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := target.Join(rfs[0].SiaPath().Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RenterFileGet(restored); err != nil {
+		t.Fatal(err)
+	}
+	// Only the backed up dir should have been restored.
+	rd, err := r.RenterDirGet(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Files) != 1 || len(rd.Directories) != 1 {
+		t.Fatalf("expected 1 file and no subdirs but got %v files and %v dirs", len(rd.Files), len(rd.Directories)-1)
+	}
+
+	// Delete the original dir and restore the backup to it.
+	if err := r.RenterDirDeletePost(dirA); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RenterRecoverBackupPost("foo"); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		_, _, err := r.DownloadToDisk(rfs[0], false)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The other dir should be untouched.
+	if _, _, err := r.DownloadToDisk(rfs[1], false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete the renter entirely and create a new renter with the same seed.
+	wsg, err := r.WalletSeedsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.RemoveNode(r); err != nil {
+		t.Fatal(err)
+	}
+	renterParams := node.Renter(filepath.Join(testDir, "renter"))
+	renterParams.PrimarySeed = wsg.PrimarySeed
+	nodes, err := tg.AddNodes(renterParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = nodes[0]
+
+	// The backup synced from the hosts should still be a backup of the first
+	// dir.
+	dirARoot, err := dirA.Rebase(modules.RootSiaPath(), modules.UserFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(60, time.Second, func() error {
+		ubs, err := r.RenterDirBackups(dirA, false)
+		if err != nil {
+			return err
+		}
+		if len(ubs.Backups) != 1 {
+			return fmt.Errorf("expected 1 backup but got %v", len(ubs.Backups))
+		}
+		if ub := ubs.Backups[0]; ub.Name != "foo" || !ub.SiaPath.Equals(dirARoot) {
+			return fmt.Errorf("unexpected backup %v", ub)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contracts, err := r.RenterContractsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range contracts.ActiveContracts {
+		backups, err := r.RenterBackupsOnHost(c.HostPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backups.Backups) != 1 || !backups.Backups[0].SiaPath.Equals(dirARoot) {
+			t.Fatal("unexpected backups on host", backups.Backups)
+		}
+	}
+
+	// Restoring the synced backup should restore the first dir.
+	if err := r.RenterRecoverBackupPost("foo"); err != nil {
+		t.Fatal(err)
+	}
+	rd, err = r.RenterDirGet(dirA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Files) != 1 {
+		t.Fatalf("expected 1 file but got %v", len(rd.Files))
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		_, _, err := r.DownloadToDisk(rfs[0], false)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestBackupRenew tests that a backup can be restored after a set of contract
 // has been renewed.
 func TestBackupRenew(t *testing.T) {
@@ -1164,3 +1164,171 @@ func TestFuse(t *testing.T) {
 		err = r.RenterFuseUnmount(unmount)
 	}
 }
+
+// TestFuseReadWrite tests writing to the renter's Fuse filesystem when it is
+// not mounted read-only.
+func TestFuseReadWrite(t *testing.T) {
+	if !build.VLONG {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a testgroup.
+	groupParams := siatest.GroupParams{
+		Hosts:   2,
+		Miners:  1,
+		Renters: 1,
+	}
+	testDir := fuseTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal("Failed to create group: ", err)
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := tg.Renters()[0]
+
+	// Mount the root in read-write mode.
+	opts := modules.MountOptions{
+		ReadOnly: false,
+	}
+	mountpoint := filepath.Join(testDir, "mount")
+	err = os.MkdirAll(mountpoint, persist.DefaultDiskPermissionsTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.RenterFuseMount(mountpoint, modules.RootSiaPath(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a dir and write a file into it.
+	dirPath := filepath.Join(mountpoint, "dir")
+	err = os.Mkdir(dirPath, persist.DefaultDiskPermissionsTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(int(modules.SectorSize) + 100)
+	filePath := filepath.Join(dirPath, "file")
+	err = ioutil.WriteFile(filePath, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file should exist in the renter.
+	dirSiaPath, err := modules.NewSiaPath("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileSiaPath, err := dirSiaPath.Join("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf, err := r.RenterFileGet(fileSiaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rf.File.Filesize != uint64(len(data)) {
+		t.Fatalf("wrong filesize: %v != %v", rf.File.Filesize, len(data))
+	}
+
+	// Append to the file.
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appended := fastrand.Bytes(100)
+	_, err = f.Write(appended)
+	if err != nil {
+		t.Fatal(errors.Compose(err, f.Close()))
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, appended...)
+
+	// Rename the file.
+	renamedPath := filepath.Join(mountpoint, "renamed")
+	err = os.Rename(filePath, renamedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamedSiaPath, err := modules.NewSiaPath("renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.RenterFileGet(fileSiaPath)
+	if err == nil {
+		t.Fatal("file should have been renamed")
+	}
+
+	// Remount the filesystem and verify the data through a fresh mount.
+	err = r.RenterFuseUnmount(mountpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.RenterFuseMount(mountpoint, modules.RootSiaPath(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readData, err := ioutil.ReadFile(renamedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readData, data) {
+		t.Fatal("data read through fuse doesn't match written data")
+	}
+
+	// Truncate the file.
+	err = os.Truncate(renamedPath, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf, err = r.RenterFileGet(renamedSiaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rf.File.Filesize != 10 {
+		t.Fatalf("wrong filesize after truncate: %v", rf.File.Filesize)
+	}
+
+	// Delete the file and the dir.
+	err = os.Remove(renamedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.RenterFileGet(renamedSiaPath)
+	if err == nil {
+		t.Fatal("file should have been deleted")
+	}
+	err = os.Remove(dirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.RenterDirGet(dirSiaPath)
+	if err == nil {
+		t.Fatal("dir should have been deleted")
+	}
+
+	// Remount read-only. Writes should fail.
+	err = r.RenterFuseUnmount(mountpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.RenterFuseMount(mountpoint, modules.RootSiaPath(), modules.MountOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(mountpoint, "readonly"), data, 0644)
+	if err == nil {
+		t.Fatal("writing to a read-only mount should fail")
+	}
+	err = r.RenterFuseUnmount(mountpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -181,6 +181,11 @@ func TestRenterStream(t *testing.T) {
 		{Name: "TestStreamLargeFile", Test: testStreamLargeFile},
 		{Name: "TestStreamRepair", Test: testStreamRepair},
 		{Name: "TestUploadStreaming", Test: testUploadStreaming},
+		{Name: "TestUploadStreamingDedup", Test: testUploadStreamingDedup},
+		{Name: "TestUploadStreamingCompressed", Test: testUploadStreamingCompressed},
+		{Name: "TestUploadStreamingDirPolicy", Test: testUploadStreamingDirPolicy},
+		{Name: "TestUploadStreamingPriorityClasses", Test: testUploadStreamingPriorityClasses},
+		{Name: "TestUploadStreamingVersions", Test: testUploadStreamingVersions},
 		{Name: "TestUploadStreamingWithBadDeps", Test: testUploadStreamingWithBadDeps},
 	}
 
@@ -344,6 +349,468 @@ func testUploadStreaming(t *testing.T, tg *siatest.TestGroup) {
 	}
 }
 
+// testUploadStreamingDedup uploads the same data twice with deduplication
+// enabled and checks that the second upload doesn't store any new sectors. It
+// then uploads the data with a few bytes inserted at the beginning and checks
+// that most of the blocks are reused.
+func testUploadStreamingDedup(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+	parityPieces := uint64(len(tg.Hosts()) - 1)
+
+	// contractSize returns the total size of the renter's contracts.
+	contractSize := func() (uint64, error) {
+		rc, err := r.RenterContractsGet()
+		if err != nil {
+			return 0, err
+		}
+		var size uint64
+		for _, c := range rc.ActiveContracts {
+			size += c.Size
+		}
+		return size, nil
+	}
+	// waitForUpload waits for a file to reach full redundancy.
+	waitForUpload := func(siaPath modules.SiaPath, size int) error {
+		return build.Retry(100, 600*time.Millisecond, func() error {
+			rfg, err := r.RenterFileGet(siaPath)
+			if err != nil {
+				return err
+			}
+			if rfg.File.Redundancy < float64(len(tg.Hosts())) {
+				return fmt.Errorf("expected redundancy %v but was %v", len(tg.Hosts()), rfg.File.Redundancy)
+			}
+			if rfg.File.Filesize != uint64(size) {
+				return fmt.Errorf("expected size %v but was %v", size, rfg.File.Filesize)
+			}
+			return nil
+		})
+	}
+	// checkDownload downloads a file and compares it to the expected data.
+	checkDownload := func(siaPath modules.SiaPath, data []byte) {
+		_, downloadedData, err := r.RenterDownloadHTTPResponseGet(siaPath, 0, uint64(len(data)), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, downloadedData) {
+			t.Fatal("Downloaded data doesn't match uploaded data")
+		}
+	}
+
+	// Create data which consists of two identical parts followed by a few
+	// more chunks of random data.
+	chunk := fastrand.Bytes(int(modules.SectorSize))
+	data := append(append(append([]byte{}, chunk...), chunk...), fastrand.Bytes(int(8*modules.SectorSize)+100)...)
+
+	// Upload the data.
+	siaPath1, siaPath2, siaPath3 := modules.RandomSiaPath(), modules.RandomSiaPath(), modules.RandomSiaPath()
+	sizeInitial, err := contractSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.RenterUploadStreamDedupPost(bytes.NewReader(data), siaPath1, 1, parityPieces, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForUpload(siaPath1, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(siaPath1, data)
+	sizeBefore, err := contractSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upload the same data again. No new sectors should be stored.
+	err = r.RenterUploadStreamDedupPost(bytes.NewReader(data), siaPath2, 1, parityPieces, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForUpload(siaPath2, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	sizeAfter, err := contractSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sizeAfter != sizeBefore {
+		t.Fatalf("expected contract size to stay %v but was %v", sizeBefore, sizeAfter)
+	}
+
+	// Upload the data with a few bytes inserted at the beginning. The block
+	// boundaries line up again after the insertion, so most of the blocks are
+	// reused.
+	edited := append(fastrand.Bytes(10), data...)
+	err = r.RenterUploadStreamDedupPost(bytes.NewReader(edited), siaPath3, 1, parityPieces, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForUpload(siaPath3, len(edited)); err != nil {
+		t.Fatal(err)
+	}
+	sizeEdited, err := contractSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added, full := sizeEdited-sizeAfter, sizeBefore-sizeInitial; added > full/2 {
+		t.Fatalf("edited upload added %v bytes to the contracts, a full upload added %v", added, full)
+	}
+	checkDownload(siaPath3, edited)
+
+	// Download a range which spans multiple blocks.
+	offset, length := uint64(len(edited)/3), uint64(len(edited)/3)
+	_, downloadedData, err := r.RenterDownloadHTTPResponseGet(siaPath3, offset, length, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(edited[offset:offset+length], downloadedData) {
+		t.Fatal("Downloaded range doesn't match uploaded data")
+	}
+
+	// Delete the first file. The second one should still be downloadable.
+	if err := r.RenterFileDeletePost(siaPath1); err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(siaPath2, data)
+}
+
+// testUploadStreamingDirPolicy tests that streamed uploads without erasure
+// coding parameters use the policy of their directory.
+func testUploadStreamingDirPolicy(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+	parityPieces := len(tg.Hosts()) - 1
+
+	// Create a dir with a policy and a subdir which inherits it.
+	dir := modules.RandomSiaPath()
+	subDir, err := dir.Join("sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RenterDirCreatePost(subDir); err != nil {
+		t.Fatal(err)
+	}
+	policy := modules.DirPolicy{
+		DataPieces:      1,
+		ParityPieces:    parityPieces,
+		CipherType:      crypto.TypeXChaCha20,
+		RepairThreshold: 0.2,
+	}
+	if err := r.RenterDirSetPolicyPost(dir, policy); err != nil {
+		t.Fatal(err)
+	}
+	// Invalid policies should be rejected.
+	if err := r.RenterDirSetPolicyPost(dir, modules.DirPolicy{RepairThreshold: 2}); err == nil {
+		t.Fatal("invalid policy was accepted")
+	}
+	rd, err := r.RenterDirGet(subDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd.Directories[0].Policy != (modules.DirPolicy{}) {
+		t.Fatal("subdir shouldn't have its own policy", rd.Directories[0].Policy)
+	}
+	if rd.Policy != policy {
+		t.Fatal("wrong effective policy", rd.Policy)
+	}
+
+	// Upload a file to the subdir without specifying erasure coding
+	// parameters.
+	siaPath, err := subDir.Join("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(int(modules.SectorSize))
+	err = r.RenterUploadStreamPost(bytes.NewReader(data), siaPath, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file should use the cipher type of the policy and reach the
+	// redundancy of a 1-of-N erasure code.
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		rf, err := r.RenterFileGet(siaPath)
+		if err != nil {
+			return err
+		}
+		if rf.File.CipherType != crypto.TypeXChaCha20.String() {
+			t.Fatal("wrong cipher type", rf.File.CipherType)
+		}
+		if expected := float64(1 + parityPieces); rf.File.Redundancy != expected {
+			return fmt.Errorf("expected redundancy %v but got %v", expected, rf.File.Redundancy)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testUploadStreamingPriorityClasses tests that streamed uploads show up in the
+// stats of the interactive priority class and that the priority weights can be
+// changed.
+func testUploadStreamingPriorityClasses(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+
+	// The classes should be listed from the most to the least urgent one
+	// with the default weights.
+	rug, err := r.RenterUploadsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rug.PriorityClasses) != modules.NumPriorityClasses {
+		t.Fatal("wrong number of priority classes", len(rug.PriorityClasses))
+	}
+	for i, pc := range modules.PriorityClasses() {
+		if rug.PriorityClasses[i].Class != pc {
+			t.Fatal("wrong class", rug.PriorityClasses[i].Class, pc)
+		}
+		if rug.PriorityClasses[i].Weight != modules.DefaultPriorityWeights.Weight(pc) {
+			t.Fatal("wrong weight", rug.PriorityClasses[i])
+		}
+	}
+	before := rug.PriorityClasses[0]
+
+	// Upload a file. Its chunk should count towards the interactive class.
+	data := fastrand.Bytes(int(modules.SectorSize))
+	siaPath := modules.RandomSiaPath()
+	err = r.RenterUploadStreamPost(bytes.NewReader(data), siaPath, 1, uint64(len(tg.Hosts())-1), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		rug, err := r.RenterUploadsGet()
+		if err != nil {
+			return err
+		}
+		interactive := rug.PriorityClasses[0]
+		if interactive.FinishedChunks <= before.FinishedChunks || interactive.ScheduledBytes <= before.ScheduledBytes {
+			return fmt.Errorf("chunk wasn't counted %v %v", interactive, before)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the weights.
+	weights := modules.PriorityWeights{Background: 2, User: 3, Interactive: 5}
+	if err := r.RenterPriorityWeightsPost(weights); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := r.RenterPriorityWeightsPost(modules.DefaultPriorityWeights); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	rg, err := r.RenterGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rg.Settings.PriorityWeights != weights {
+		t.Fatal("weights weren't set", rg.Settings.PriorityWeights)
+	}
+	rug, err = r.RenterUploadsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pc := range modules.PriorityClasses() {
+		if rug.PriorityClasses[i].Weight != weights.Weight(pc) {
+			t.Fatal("wrong weight", rug.PriorityClasses[i])
+		}
+	}
+
+	// A weight of 0 should be rejected.
+	if err := r.RenterPriorityWeightsPost(modules.PriorityWeights{Background: 0, User: 1, Interactive: 1}); err == nil {
+		t.Fatal("weight of 0 was accepted")
+	}
+}
+
+// testUploadStreamingVersions overwrites a file using the upload streaming API
+// and makes sure that prior versions of the file are kept and can be
+// restored.
+func testUploadStreamingVersions(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+	parityPieces := uint64(len(tg.Hosts()) - 1)
+
+	// Keep a single prior version.
+	err := r.RenterMaxFileVersionsPost(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := r.RenterMaxFileVersionsPost(0); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	rg, err := r.RenterGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rg.Settings.MaxFileVersions != 1 {
+		t.Fatal("wrong max file versions", rg.Settings.MaxFileVersions)
+	}
+
+	// Upload the same file 3 times.
+	siaPath := modules.RandomSiaPath()
+	var datas [][]byte
+	for i := 0; i < 3; i++ {
+		data := fastrand.Bytes(int(modules.SectorSize) + i)
+		err = r.RenterUploadStreamPost(bytes.NewReader(data), siaPath, 1, parityPieces, i > 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		datas = append(datas, data)
+	}
+
+	// Only the second upload should be kept as a version.
+	rfv, err := r.RenterFileVersionsGet(siaPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rfv.Versions) != 1 || rfv.Versions[0].Version != 2 {
+		t.Fatal("unexpected versions", rfv.Versions)
+	}
+	if rfv.Versions[0].Filesize != uint64(len(datas[1])) {
+		t.Fatal("wrong size of version", rfv.Versions[0].Filesize)
+	}
+
+	// Restore the version and check that the data of the second upload is
+	// downloaded.
+	err = r.RenterFileVersionRestorePost(siaPath, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, downloadedData, err := r.RenterDownloadHTTPResponseGet(siaPath, 0, uint64(len(datas[1])), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(datas[1], downloadedData) {
+		t.Fatal("Downloaded data doesn't match restored version")
+	}
+
+	// The third upload should now be the only version.
+	rfv, err = r.RenterFileVersionsGet(siaPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rfv.Versions) != 1 || rfv.Versions[0].Version != 3 {
+		t.Fatal("unexpected versions", rfv.Versions)
+	}
+	versionSiaPath := rfv.Versions[0].SiaPath
+	_, downloadedData, err = r.RenterDownloadHTTPResponseGet(versionSiaPath, 0, uint64(len(datas[2])), true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(datas[2], downloadedData) {
+		t.Fatal("Downloaded data doesn't match version")
+	}
+
+	// Deleting the file deletes the versions.
+	err = r.RenterFileDeletePost(siaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rfv, err = r.RenterFileVersionsGet(siaPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rfv.Versions) != 0 {
+		t.Fatal("versions weren't deleted", rfv.Versions)
+	}
+}
+
+// testUploadStreamingCompressed uploads compressed data using the upload
+// streaming API and makes sure that downloads and streams return the
+// uncompressed data.
+func testUploadStreamingCompressed(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+	parityPieces := uint64(len(tg.Hosts()) - 1)
+
+	// Create compressible data which spans multiple frames.
+	size := 5*int(modules.SectorSize) + 100
+	data := bytes.Repeat(fastrand.Bytes(64), size/64+1)[:size]
+
+	// Upload the data.
+	siaPath := modules.RandomSiaPath()
+	err := r.RenterUploadStreamCompressedPost(bytes.NewReader(data), siaPath, 1, parityPieces, false, modules.CompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 600*time.Millisecond, func() error {
+		rfg, err := r.RenterFileGet(siaPath)
+		if err != nil {
+			return err
+		}
+		if rfg.File.Redundancy < float64(len(tg.Hosts())) {
+			return fmt.Errorf("expected redundancy %v but was %v", len(tg.Hosts()), rfg.File.Redundancy)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file should report the uncompressed size while storing less data.
+	rfg, err := r.RenterFileGet(siaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rfg.File.Filesize != uint64(size) {
+		t.Fatalf("expected size %v but was %v", size, rfg.File.Filesize)
+	}
+	if rfg.File.Compression != modules.CompressionGzip.String() {
+		t.Fatal("wrong compression", rfg.File.Compression)
+	}
+	if rfg.File.UploadedBytes >= uint64(size)*uint64(len(tg.Hosts())) {
+		t.Fatal("data wasn't compressed", rfg.File.UploadedBytes)
+	}
+
+	// Download the whole file and a range which spans multiple frames.
+	_, downloadedData, err := r.RenterDownloadHTTPResponseGet(siaPath, 0, uint64(size), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, downloadedData) {
+		t.Fatal("Downloaded data doesn't match uploaded data")
+	}
+	offset, length := uint64(modules.SectorSize)-10, 2*uint64(modules.SectorSize)
+	_, downloadedData, err = r.RenterDownloadHTTPResponseGet(siaPath, offset, length, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[offset:offset+length], downloadedData) {
+		t.Fatal("Downloaded range doesn't match uploaded data")
+	}
+
+	// Download a range to disk.
+	dst := filepath.Join(r.FilesDir().Path(), "compressed.dat")
+	_, err = r.RenterDownloadGet(siaPath, dst, offset, length, false, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloadedData, err = ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[offset:offset+length], downloadedData) {
+		t.Fatal("Downloaded file doesn't match uploaded data")
+	}
+
+	// Stream the whole file and a range at the end of the file.
+	streamedData, err := r.RenterStreamGet(siaPath, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, streamedData) {
+		t.Fatal("Streamed data doesn't match uploaded data")
+	}
+	streamedData, err = r.RenterStreamPartialGet(siaPath, uint64(size-200), uint64(size), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[size-200:], streamedData) {
+		t.Fatal("Streamed range doesn't match uploaded data")
+	}
+}
+
 // testUploadStreamingWithBadDeps uploads random data using the upload streaming
 // API, depending on a disrupt to cause a failure. This is a regression test
 // that would have caused a production build panic.
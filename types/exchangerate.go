@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"math/big"
 	"regexp"
+	"strconv"
 )
 
 type (
@@ -92,3 +93,28 @@ func (r *ExchangeRate) ApplyAndFormat(c Currency) string {
 	result = fmt.Sprintf("~ %s %s", result, r.staticSymbol)
 	return result
 }
+
+// Symbol returns the symbol of the exchange rate's currency.
+func (r *ExchangeRate) Symbol() string {
+	return r.staticSymbol
+}
+
+// ToCurrency converts an amount of the exchange rate's currency to siacoins.
+// Assumes that amount cannot be negative.
+func (r *ExchangeRate) ToCurrency(amount float64) Currency {
+	// Use the shortest decimal representations of the amount and rate to
+	// avoid binary rounding errors.
+	amountRat, ok := new(big.Rat).SetString(strconv.FormatFloat(amount, 'f', -1, 64))
+	if !ok {
+		return ZeroCurrency
+	}
+	asRatio, ok := new(big.Rat).SetString(r.staticValue.Text('f', -1))
+	if !ok {
+		return ZeroCurrency
+	}
+	precisionRat := new(big.Rat).SetInt(SiacoinPrecision.Big())
+
+	// calculate (amountRat * precisionRat) / asRatio
+	resultRat := new(big.Rat).Quo(new(big.Rat).Mul(amountRat, precisionRat), asRatio)
+	return NewCurrency(new(big.Int).Quo(resultRat.Num(), resultRat.Denom()))
+}
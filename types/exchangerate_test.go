@@ -122,3 +122,27 @@ func TestApplyAndFormat(t *testing.T) {
 		}
 	}
 }
+
+// TestToCurrency checks that amounts are correctly converted to siacoins.
+func TestToCurrency(t *testing.T) {
+	tests := []struct {
+		rate   string
+		amount float64
+		result Currency
+	}{
+		{"1 USD", 0, ZeroCurrency},
+		{"1 USD", 1, SiacoinPrecision},
+		{"0.5 USD", 1, SiacoinPrecision.Mul64(2)},
+		{"0.01 EUR", 2.5, SiacoinPrecision.Mul64(250)},
+		{"2 USD", 0.001, SiacoinPrecision.Div64(2000)},
+	}
+	for _, test := range tests {
+		rate, err := ParseExchangeRate(test.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := rate.ToCurrency(test.amount); !result.Equals(test.result) {
+			t.Errorf("%v.ToCurrency(%v): expected %v, got %v", test.rate, test.amount, test.result, result)
+		}
+	}
+}